@@ -0,0 +1,14 @@
+// Package circuit holds the gnark circuits of the light clients: the sync committee circuits, which
+// prove that a header was signed by the sync committee of its period, and the circuits proving facts
+// about a header, its BeaconState, its block body or its execution block.
+//
+// The latter do not verify any signature: their public HeaderRoot, or StateRoot for the circuits
+// reading a BeaconState only, is expected to be already trusted by the consumer, e.g. the HeaderRoot
+// of a verified Eth2ScSignatureCircuit proof or the attested header of a verified sync committee
+// update. The header fields behind a HeaderRoot are private inputs, bound to it by hash_tree_root.
+//
+// Likewise, the public BlockHash of the circuits reading an execution block is expected to be an
+// execution block hash already trusted by the consumer, e.g. one recorded by Eth2LightClient from the
+// attested header of a verified sync committee update. The RLP encoded header is a private input,
+// bound to BlockHash by keccak256 (see verifyExecutionHeader).
+package circuit
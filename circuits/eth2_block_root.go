@@ -0,0 +1,81 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+)
+
+// blockRootsDepth is log2(SLOTS_PER_HISTORICAL_ROOT), the depth of the block_roots vector
+const blockRootsDepth = 13
+
+// Eth2BlockRootCircuit proves the root of a recent block through the block_roots ring buffer of a
+// BeaconState, for blocks within the last SLOTS_PER_HISTORICAL_ROOT (8192) slots of the state, so that
+// BlockRoot is proven to be the canonical block at Slot without the historical_summaries machinery.
+//
+// This circuit:
+// 1. Requires StateSlot - 8192 <= Slot < StateSlot, so the ring buffer entry was not overwritten
+// 2. Verifies BlockRoot is block_roots[Slot % 8192] in StateRoot via an SSZ Merkle proof
+type Eth2BlockRootCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params BlockRootParams `gnark:"-"`
+
+	// Merkle branch of block_roots[Slot % 8192] in the BeaconState (private input),
+	// 13 levels inside block_roots followed by Params.BlockRootsGIndex().Depth() state levels
+	Branch [][32]uints.U8
+
+	// Public inputs
+	StateRoot [32]uints.U8      `gnark:",public"` // root of the BeaconState holding the ring buffer
+	StateSlot frontend.Variable `gnark:",public"` // slot of that BeaconState
+	BlockRoot [32]uints.U8      `gnark:",public"` // proven block root
+	Slot      frontend.Variable `gnark:",public"` // slot of the proven block
+}
+
+// BlockRootParams holds the compile-time parameters of Eth2BlockRootCircuit
+type BlockRootParams struct {
+	// BlockRootsGIndex is the generalized index of block_roots in the BeaconState of the target fork.
+	// Zero means Electra/Fulu (69).
+	BlockRootsGIndex types.GIndex
+}
+
+// StateBlockRootsGIndex returns BlockRootsGIndex, defaulting to the Electra/Fulu layout
+func (p BlockRootParams) StateBlockRootsGIndex() types.GIndex {
+	if p.BlockRootsGIndex == 0 {
+		return types.BlockRootsGIndexElectra
+	}
+	return p.BlockRootsGIndex
+}
+
+// NewEth2BlockRootCircuit allocates a circuit (or witness) for the given params
+func NewEth2BlockRootCircuit(params BlockRootParams) *Eth2BlockRootCircuit {
+	return &Eth2BlockRootCircuit{
+		Params: params,
+		Branch: make([][32]uints.U8, blockRootsDepth+params.StateBlockRootsGIndex().Depth()),
+	}
+}
+
+// Define implements the circuit constraints
+func (c *Eth2BlockRootCircuit) Define(api frontend.API) error {
+	statePath := c.Params.StateBlockRootsGIndex().PathBits()
+	if len(c.Branch) != blockRootsDepth+len(statePath) {
+		return fmt.Errorf("branch length %d does not match depth %d", len(c.Branch), blockRootsDepth+len(statePath))
+	}
+
+	// Step 1: 0 <= StateSlot - Slot - 1 < 8192, and both slots fit in 64 bits
+	api.ToBinary(c.StateSlot, 64)
+	slotBits := api.ToBinary(c.Slot, 64)
+	api.ToBinary(api.Sub(c.StateSlot, c.Slot, 1), blockRootsDepth)
+
+	// Step 2: the lower 13 levels follow Slot % 8192, the upper levels the block_roots field gindex
+	current, err := gadgets.SSZBranchRootAt(api, c.BlockRoot, c.Branch[:blockRootsDepth], slotBits[:blockRootsDepth])
+	if err != nil {
+		return err
+	}
+	if err := gadgets.VerifySSZBranch(api, current, c.Branch[blockRootsDepth:], c.Params.StateBlockRootsGIndex(), c.StateRoot); err != nil {
+		return err
+	}
+	return nil
+}
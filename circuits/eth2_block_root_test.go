@@ -0,0 +1,55 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func newBlockRootAssignment(params BlockRootParams, stateRoot, blockRoot zrntcommon.Root, branch []zrntcommon.Root, stateSlot, slot uint64) *Eth2BlockRootCircuit {
+	w := NewEth2BlockRootCircuit(params)
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(stateRoot[:]))
+	w.BlockRoot = [32]uints.U8(uints.NewU8Array(blockRoot[:]))
+	w.StateSlot = stateSlot
+	w.Slot = slot
+	for i := range branch {
+		w.Branch[i] = [32]uints.U8(uints.NewU8Array(branch[i][:]))
+	}
+	return w
+}
+
+func TestEth2BlockRootCircuit(t *testing.T) {
+	params := BlockRootParams{}
+	stateSlot := uint64(9052234)
+	slot := stateSlot - 8000
+	blockRoot := zrntcommon.Root{0xaa, 0xbb}
+
+	branch := make([]zrntcommon.Root, 13+6)
+	for i := range branch {
+		branch[i][31] = byte(i + 1)
+	}
+	gindex, err := types.BlockRootGIndex(types.BlockRootsGIndexElectra, slot)
+	require.NoError(t, err)
+	stateRoot, err := types.ComputeSSZBranchRoot(blockRoot, branch, gindex)
+	require.NoError(t, err)
+	require.NoError(t, types.VerifyBlockRoot(stateRoot, blockRoot, branch, types.BlockRootsGIndexElectra, stateSlot, slot))
+
+	circuit := NewEth2BlockRootCircuit(params)
+	err = gnark_test.IsSolved(circuit, newBlockRootAssignment(params, stateRoot, blockRoot, branch, stateSlot, slot), ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	// same ring buffer index one period earlier: the entry has been overwritten since
+	err = gnark_test.IsSolved(circuit, newBlockRootAssignment(params, stateRoot, blockRoot, branch, stateSlot, slot-8192), ecc.BN254.ScalarField())
+	require.Error(t, err)
+	// a slot at or after the state slot is not in the ring buffer yet
+	err = gnark_test.IsSolved(circuit, newBlockRootAssignment(params, stateRoot, blockRoot, branch, slot, slot), ecc.BN254.ScalarField())
+	require.Error(t, err)
+	// another slot maps to another index
+	err = gnark_test.IsSolved(circuit, newBlockRootAssignment(params, stateRoot, blockRoot, branch, stateSlot, slot+1), ecc.BN254.ScalarField())
+	require.Error(t, err)
+}
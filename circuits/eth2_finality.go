@@ -0,0 +1,140 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// Eth2FinalityCircuit proves the finalized header of an attested header, on either side of the Electra
+// fork with the same proving key: finalized_checkpoint.root is 6 levels deep in the BeaconState before
+// Electra (gindex 105) and 7 since (gindex 169), the Electra state tree having the pre-Electra one as
+// its left half. The branch is witnessed padded to 7 and the public Fork selects its depth.
+//
+// Fork is expected to be checked by the consumer against AttestedSlot and the Electra fork epoch of the
+// chain during the transition.
+//
+// This circuit:
+// 1. Computes the root of the attested header and requires it to be HeaderRoot, its slot AttestedSlot
+// 2. Computes the root of the finalized header and requires it to be FinalizedHeaderRoot, its slot FinalizedSlot
+// 3. Verifies FinalizedHeaderRoot is finalized_checkpoint.root in the StateRoot of the attested header
+// via the SSZ Merkle proof of the layout selected by Fork
+type Eth2FinalityCircuit struct {
+	// BeaconBlockHeader fields of the attested header (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// BeaconBlockHeader fields of the finalized header (private inputs), but its public slot
+	FinalizedProposerIndex frontend.Variable // uint64
+	FinalizedParentRoot    [32]uints.U8      // bytes32
+	FinalizedStateRoot     [32]uints.U8      // bytes32
+	FinalizedBodyRoot      [32]uints.U8      // bytes32
+
+	// Merkle branch of finalized_checkpoint.root in StateRoot (private input), zero padded to the
+	// types.FinalizedRootGIndexElectra.Depth() levels of Electra
+	FinalityBranch [][32]uints.U8
+
+	// Public inputs
+	HeaderRoot          [32]uints.U8      `gnark:",public"` // root of the trusted attested header
+	AttestedSlot        frontend.Variable `gnark:",public"` // slot of the attested header
+	Fork                frontend.Variable `gnark:",public"` // state layout: 0 before Electra, 1 since
+	FinalizedHeaderRoot [32]uints.U8      `gnark:",public"` // root of the finalized header
+	FinalizedSlot       frontend.Variable `gnark:",public"` // slot of the finalized header
+}
+
+// NewEth2FinalityCircuit allocates a circuit (or witness)
+func NewEth2FinalityCircuit() *Eth2FinalityCircuit {
+	return &Eth2FinalityCircuit{
+		FinalityBranch: make([][32]uints.U8, types.FinalizedRootGIndexElectra.Depth()),
+	}
+}
+
+// NewEth2FinalityAssignment builds the witness proving finalized is the finalized header of attested,
+// whose state has the layout of fork (the version of a light client update), with the finality branch
+// of that layout.
+func NewEth2FinalityAssignment(fork string, attested, finalized *zrntcommon.BeaconBlockHeader, branch []zrntcommon.Root) (*Eth2FinalityCircuit, error) {
+	gindex, err := types.FinalizedRootGIndexForFork(fork)
+	if err != nil {
+		return nil, err
+	}
+	if len(branch) != gindex.Depth() {
+		return nil, fmt.Errorf("branch length %d does not match depth %d of %s", len(branch), gindex.Depth(), fork)
+	}
+	finalizedRoot := finalized.HashTreeRoot(tree.GetHashFn())
+	if !types.VerifySSZBranch(attested.StateRoot, finalizedRoot, branch, gindex) {
+		return nil, fmt.Errorf("finalized header %v is not finalized in state %v", finalizedRoot, attested.StateRoot)
+	}
+
+	w := NewEth2FinalityCircuit()
+	headerRoot := attested.HashTreeRoot(tree.GetHashFn())
+	w.Slot = uint64(attested.Slot)
+	w.ProposerIndex = uint64(attested.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(attested.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(attested.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(attested.BodyRoot[:]))
+	w.FinalizedProposerIndex = uint64(finalized.ProposerIndex)
+	w.FinalizedParentRoot = [32]uints.U8(uints.NewU8Array(finalized.ParentRoot[:]))
+	w.FinalizedStateRoot = [32]uints.U8(uints.NewU8Array(finalized.StateRoot[:]))
+	w.FinalizedBodyRoot = [32]uints.U8(uints.NewU8Array(finalized.BodyRoot[:]))
+	for i := range w.FinalityBranch {
+		var node zrntcommon.Root
+		if i < len(branch) {
+			node = branch[i]
+		}
+		w.FinalityBranch[i] = [32]uints.U8(uints.NewU8Array(node[:]))
+	}
+	w.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	w.AttestedSlot = uint64(attested.Slot)
+	w.Fork = 0
+	if gindex == types.FinalizedRootGIndexElectra {
+		w.Fork = 1
+	}
+	w.FinalizedHeaderRoot = [32]uints.U8(uints.NewU8Array(finalizedRoot[:]))
+	w.FinalizedSlot = uint64(finalized.Slot)
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2FinalityCircuit) Define(api frontend.API) error {
+	if len(c.FinalityBranch) != types.FinalizedRootGIndexElectra.Depth() {
+		return fmt.Errorf("branch length %d does not match depth %d", len(c.FinalityBranch), types.FinalizedRootGIndexElectra.Depth())
+	}
+
+	// Step 1: the trusted attested header
+	attested := &Eth2ScUpdateCircuit{
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+	}
+	gadgets.AssertChunksEqual(api, attested.computeBlockRoot(api), c.HeaderRoot)
+	api.AssertIsEqual(c.AttestedSlot, c.Slot)
+
+	// Step 2: the finalized header
+	finalized := &Eth2ScUpdateCircuit{
+		Slot:          c.FinalizedSlot,
+		ProposerIndex: c.FinalizedProposerIndex,
+		ParentRoot:    c.FinalizedParentRoot,
+		StateRoot:     c.FinalizedStateRoot,
+		BodyRoot:      c.FinalizedBodyRoot,
+	}
+	gadgets.AssertChunksEqual(api, finalized.computeBlockRoot(api), c.FinalizedHeaderRoot)
+
+	// Step 3: finalized_checkpoint.root in the state of the attested header, 6 or 7 levels deep
+	root, err := gadgets.SSZBranchRootEither(api, c.FinalizedHeaderRoot, c.FinalityBranch,
+		types.FinalizedRootGIndexAltair, types.FinalizedRootGIndexElectra, c.Fork)
+	if err != nil {
+		return fmt.Errorf("finality branch: %w", err)
+	}
+	gadgets.AssertChunksEqual(api, root, c.StateRoot)
+	return nil
+}
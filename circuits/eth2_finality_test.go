@@ -0,0 +1,63 @@
+package circuit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2FinalityCircuit(t *testing.T) {
+	var update types.LightClientUpdate
+	data, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &update))
+	attested := &update.Data.AttestedHeader.Beacon
+	finalized := &update.Data.FinalizedHeader.Beacon
+	require.Len(t, update.Data.FinalityBranch, 7)
+
+	circuit := NewEth2FinalityCircuit()
+
+	// a Fulu update, 7 levels
+	fulu, err := NewEth2FinalityAssignment(update.Version, attested, finalized, update.Data.FinalityBranch)
+	require.NoError(t, err)
+	require.Equal(t, 1, fulu.Fork)
+	require.NoError(t, gnark_test.IsSolved(circuit, fulu, ecc.BN254.ScalarField()))
+
+	// a Deneb state of the same finalized header, 6 levels, with the same circuit
+	denebBranch := update.Data.FinalityBranch[:6]
+	denebAttested := *attested
+	denebAttested.StateRoot, err = types.ComputeSSZBranchRoot(finalized.HashTreeRoot(tree.GetHashFn()), denebBranch, types.FinalizedRootGIndexAltair)
+	require.NoError(t, err)
+	deneb, err := NewEth2FinalityAssignment("deneb", &denebAttested, finalized, denebBranch)
+	require.NoError(t, err)
+	require.Equal(t, 0, deneb.Fork)
+	require.NoError(t, gnark_test.IsSolved(circuit, deneb, ecc.BN254.ScalarField()))
+
+	// the public Fork selects the layout
+	fulu.Fork = 0
+	require.Error(t, gnark_test.IsSolved(circuit, fulu, ecc.BN254.ScalarField()))
+	deneb.Fork = 1
+	require.Error(t, gnark_test.IsSolved(circuit, deneb, ecc.BN254.ScalarField()))
+	deneb.Fork = 2
+	require.Error(t, gnark_test.IsSolved(circuit, deneb, ecc.BN254.ScalarField()))
+
+	// another finalized slot
+	fulu, err = NewEth2FinalityAssignment(update.Version, attested, finalized, update.Data.FinalityBranch)
+	require.NoError(t, err)
+	fulu.FinalizedSlot = uint64(finalized.Slot) + 1
+	require.Error(t, gnark_test.IsSolved(circuit, fulu, ecc.BN254.ScalarField()))
+
+	// the branch must be the one of the fork
+	_, err = NewEth2FinalityAssignment("deneb", attested, finalized, update.Data.FinalityBranch)
+	require.Error(t, err)
+	_, err = NewEth2FinalityAssignment(update.Version, attested, &zrntcommon.BeaconBlockHeader{Slot: finalized.Slot}, update.Data.FinalityBranch)
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// Eth2HistoricalBlockCircuit proves the root of a block older than the block_roots ring buffer through
+// the historical_summaries list of a recent BeaconState, so that facts about any block since Capella can
+// be proven against a recent header.
+//
+// This circuit:
+// 1. Computes the root of the BeaconBlockHeader and requires it to be HeaderRoot
+// 2. Requires StartPeriod <= Slot / 8192 < HeaderSlot / 8192, so the period of Slot was summarized
+// 3. Verifies BlockRoot is block_roots[Slot % 8192] of historical_summaries[Slot / 8192 - StartPeriod].block_summary_root
+// 4. Verifies that summary is included in the StateRoot of the header via an SSZ Merkle proof
+//
+// StartPeriod is a public input, so the same compiled circuit and proving key serve every network;
+// the consumer pins the period of CAPELLA_FORK_EPOCH of its network (758 on mainnet).
+type Eth2HistoricalBlockCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params HistoricalBlockParams `gnark:"-"`
+
+	// BeaconBlockHeader fields of the trusted header (private inputs)
+	HeaderSlot    frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Merkle branch of the block root in the BeaconState (private input): 13 levels inside the summarized
+	// block_roots, 1 inside the summary, 24 inside the list data, the length mix-in and
+	// Params.StateHistoricalSummariesGIndex().Depth() state levels
+	Branch [][32]uints.U8
+
+	// Public inputs
+	HeaderRoot  [32]uints.U8      `gnark:",public"` // root of the trusted header
+	StartPeriod frontend.Variable `gnark:",public"` // period summarized by historical_summaries[0]
+	BlockRoot   [32]uints.U8      `gnark:",public"` // proven block root
+	Slot        frontend.Variable `gnark:",public"` // slot of the proven block
+}
+
+// HistoricalBlockParams holds the compile-time parameters of Eth2HistoricalBlockCircuit
+type HistoricalBlockParams struct {
+	// HistoricalSummariesGIndex is the generalized index of historical_summaries in the BeaconState of
+	// the target fork. Zero means Electra/Fulu (91).
+	HistoricalSummariesGIndex types.GIndex
+}
+
+// StateHistoricalSummariesGIndex returns HistoricalSummariesGIndex, defaulting to the Electra/Fulu layout
+func (p HistoricalBlockParams) StateHistoricalSummariesGIndex() types.GIndex {
+	if p.HistoricalSummariesGIndex == 0 {
+		return types.HistoricalSummariesGIndexElectra
+	}
+	return p.HistoricalSummariesGIndex
+}
+
+// historicalBranchDepth is the depth of the block root under historical_summaries
+const historicalBranchDepth = blockRootsDepth + 1 + types.HistoricalSummariesDepth + 1
+
+// NewEth2HistoricalBlockCircuit allocates a circuit (or witness) for the given params
+func NewEth2HistoricalBlockCircuit(params HistoricalBlockParams) *Eth2HistoricalBlockCircuit {
+	return &Eth2HistoricalBlockCircuit{
+		Params: params,
+		Branch: make([][32]uints.U8, historicalBranchDepth+params.StateHistoricalSummariesGIndex().Depth()),
+	}
+}
+
+// NewEth2HistoricalBlockAssignment builds the witness proving proof.Leaf as the root of the block at
+// slot, proof being generated by types.HistoricalBlockRootProof against the state of header.
+func NewEth2HistoricalBlockAssignment(params HistoricalBlockParams, header *zrntcommon.BeaconBlockHeader, proof *types.SSZProof, startPeriod, slot uint64) (*Eth2HistoricalBlockCircuit, error) {
+	w := NewEth2HistoricalBlockCircuit(params)
+	if len(proof.Branch) != len(w.Branch) {
+		return nil, fmt.Errorf("branch length %d does not match depth %d", len(proof.Branch), len(w.Branch))
+	}
+	if err := types.VerifyHistoricalBlockRoot(header.StateRoot, proof.Leaf, proof.Branch,
+		params.StateHistoricalSummariesGIndex(), startPeriod, uint64(header.Slot), slot); err != nil {
+		return nil, err
+	}
+
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	w.HeaderSlot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	for i := range proof.Branch {
+		w.Branch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+	w.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	w.StartPeriod = startPeriod
+	w.BlockRoot = [32]uints.U8(uints.NewU8Array(proof.Leaf[:]))
+	w.Slot = slot
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2HistoricalBlockCircuit) Define(api frontend.API) error {
+	statePath := c.Params.StateHistoricalSummariesGIndex().PathBits()
+	if len(c.Branch) != historicalBranchDepth+len(statePath) {
+		return fmt.Errorf("branch length %d does not match depth %d", len(c.Branch), historicalBranchDepth+len(statePath))
+	}
+
+	// Step 1: the trusted header
+	sc := &Eth2ScUpdateCircuit{
+		Slot:          c.HeaderSlot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+	}
+	headerRoot := sc.computeBlockRoot(api)
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(headerRoot[i].Val, c.HeaderRoot[i].Val)
+	}
+
+	// Step 2: 0 <= HeaderSlot / 8192 - Slot / 8192 - 1, and the summary index fits in the list
+	slotBits := api.ToBinary(c.Slot, 64)
+	headerSlotBits := api.ToBinary(c.HeaderSlot, 64)
+	period := api.FromBinary(slotBits[blockRootsDepth:]...)
+	headerPeriod := api.FromBinary(headerSlotBits[blockRootsDepth:]...)
+	api.ToBinary(api.Sub(headerPeriod, period, 1), 64-blockRootsDepth)
+	summaryBits := api.ToBinary(api.Sub(period, c.StartPeriod), types.HistoricalSummariesDepth)
+
+	// Step 3: the entry in block_roots follows Slot % 8192, block_summary_root is the left field of the
+	// summary, the summary follows its index and the list data is left of the length mix-in
+	path := make([]frontend.Variable, 0, historicalBranchDepth)
+	path = append(path, slotBits[:blockRootsDepth]...)
+	path = append(path, 0)
+	path = append(path, summaryBits...)
+	path = append(path, 0)
+	current, err := gadgets.SSZBranchRootAt(api, c.BlockRoot, c.Branch[:historicalBranchDepth], path)
+	if err != nil {
+		return err
+	}
+
+	// Step 4: historical_summaries in the state
+	return gadgets.VerifySSZBranch(api, current, c.Branch[historicalBranchDepth:], c.Params.StateHistoricalSummariesGIndex(), c.StateRoot)
+}
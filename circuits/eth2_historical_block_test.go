@@ -0,0 +1,63 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2HistoricalBlockCircuit(t *testing.T) {
+	params := HistoricalBlockParams{}
+	startPeriod := uint64(758)
+	slot := (startPeriod+40)*types.SlotsPerHistoricalRoot + 4321
+	blockRoot := zrntcommon.Root{0xaa, 0xbb}
+
+	branch := make([]zrntcommon.Root, historicalBranchDepth+6)
+	for i := range branch {
+		branch[i][31] = byte(i + 1)
+	}
+	gindex, err := types.HistoricalBlockRootGIndex(types.HistoricalSummariesGIndexElectra, 40, slot)
+	require.NoError(t, err)
+	stateRoot, err := types.ComputeSSZBranchRoot(blockRoot, branch, gindex)
+	require.NoError(t, err)
+	header := &zrntcommon.BeaconBlockHeader{
+		Slot:          zrntcommon.Slot(slot + 100*types.SlotsPerHistoricalRoot),
+		ProposerIndex: 12345,
+		ParentRoot:    zrntcommon.Root{0x01},
+		StateRoot:     stateRoot,
+		BodyRoot:      zrntcommon.Root{0x02},
+	}
+	proof := &types.SSZProof{GIndex: gindex, Leaf: blockRoot, Branch: branch}
+
+	circuit := NewEth2HistoricalBlockCircuit(params)
+	witness, err := NewEth2HistoricalBlockAssignment(params, header, proof, startPeriod, slot)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// another slot of the same period maps to another block_roots entry
+	witness.Slot = slot + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	// another start period maps to another summary
+	witness.Slot = slot
+	witness.StartPeriod = startPeriod + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the period of the header is not summarized yet in its state
+	header.Slot = zrntcommon.Slot(slot + 1)
+	_, err = NewEth2HistoricalBlockAssignment(params, header, proof, startPeriod, slot)
+	require.Error(t, err)
+	header.Slot = zrntcommon.Slot(slot + types.SlotsPerHistoricalRoot)
+	witness, err = NewEth2HistoricalBlockAssignment(params, header, proof, startPeriod, slot)
+	require.NoError(t, err)
+	header.Slot = zrntcommon.Slot(slot + 1)
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	witness.HeaderSlot = slot + 1
+	witness.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+}
@@ -1,32 +1,213 @@
 package circuit
 
 import (
+	"fmt"
+
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
 	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/selector"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
 )
 
+// receiptHeadBytes is the length of the head of a receipt holding its type, list prefix, status and
+// cumulativeGasUsed
+const receiptHeadBytes = 14
+
+// Eth2ReceiptProofCircuit proves the outcome of a transaction of a beacon block: the status and the
+// cumulativeGasUsed of its receipt, read from the execution_payload.receipts_root of the block, so that
+// a bridge only needing "did transaction TxIndex succeed" does not decode logs on-chain.
+//
+// This circuit:
+// 1. Computes the root of the BeaconBlockHeader and requires it to be HeaderRoot
+// 2. Verifies ReceiptsRoot is execution_payload.receipts_root in the BodyRoot of the header via an
+// SSZ Merkle proof, whose depth depends on the fork (see ReceiptProofParams)
+// 3. Verifies ReceiptProof is the path of rlp(TxIndex) in the receipts trie of ReceiptsRoot
+// 4. Decodes [type ||] rlp([status, cumulativeGasUsed, ...]) and exposes Status and CumulativeGasUsed
+//
+// Only post-Byzantium receipts, whose first field is the status rather than a state root, are accepted.
 type Eth2ReceiptProofCircuit struct {
-	// BeaconBlockHeader fields (private inputs)
+	// Compile-time parameters (not part of the witness)
+	Params ReceiptProofParams `gnark:"-"`
+
+	// BeaconBlockHeader fields of the trusted header (private inputs)
 	Slot          frontend.Variable // uint64
 	ProposerIndex frontend.Variable // uint64
 	ParentRoot    [32]uints.U8      // bytes32
 	StateRoot     [32]uints.U8      // bytes32
 	BodyRoot      [32]uints.U8      // bytes32
 
-	// Sync committee data (private inputs)
-	ScPubKeys     [512]sw_bls12381.G1Affine // 512 sync committee public keys
-	ScBits        [512]frontend.Variable    // Bit array indicating which validators signed (0 or 1)
-	AggregatedSig sw_bls12381.G2Affine
+	// Merkle branch of receipts_root in the BeaconBlockBody (private input): the
+	// Params.PayloadReceiptsRootGIndex().Depth() payload levels followed by the 4 body levels
+	ReceiptsRootBranch [][32]uints.U8
+
+	// Proof of the receipt in the receipts trie (private input)
+	ReceiptProof MPTProof
 
-	ExeHeaderRootBranch [4][32]uints.U8
-	ExeHeaderRoot       [32]uints.U8
-	ReceiptsRootBranch  [4][32]uints.U8
-	ReceiptsRoot        [32]uints.U8
-	ReceiptRLPBranch    [4][32]uints.U8
-	ReceiptRLP          [32]uints.U8
+	// Public inputs
+	HeaderRoot        [32]uints.U8      `gnark:",public"` // root of the trusted header
+	ReceiptsRoot      [32]uints.U8      `gnark:",public"` // receipts_root of its execution payload
+	TxIndex           frontend.Variable `gnark:",public"` // index of the transaction in the block
+	Status            frontend.Variable `gnark:",public"` // 1 if the transaction succeeded, 0 if it reverted
+	CumulativeGasUsed frontend.Variable `gnark:",public"` // gas used by the block up to and including the transaction
 }
 
+// ReceiptProofParams holds the compile-time parameters of Eth2ReceiptProofCircuit
+type ReceiptProofParams struct {
+	// ReceiptsRootGIndex is the generalized index of receipts_root in the ExecutionPayload of the
+	// target fork, see types.ExecutionReceiptsRootGIndexForFork. Zero means Deneb .. Fulu (35).
+	ReceiptsRootGIndex types.GIndex
+	// Receipt bounds the receipt proof, with the defaults of EventProofParams.Receipt
+	Receipt MPTParams
+}
+
+// NewReceiptProofParams returns the params of the given fork
+func NewReceiptProofParams(fork string) (ReceiptProofParams, error) {
+	gindex, err := types.ExecutionReceiptsRootGIndexForFork(fork)
+	if err != nil {
+		return ReceiptProofParams{}, err
+	}
+	return ReceiptProofParams{ReceiptsRootGIndex: gindex}, nil
+}
+
+// PayloadReceiptsRootGIndex returns ReceiptsRootGIndex, defaulting to the Deneb .. Fulu layout
+func (p ReceiptProofParams) PayloadReceiptsRootGIndex() types.GIndex {
+	if p.ReceiptsRootGIndex == 0 {
+		return types.ExecutionReceiptsRootGIndex
+	}
+	return p.ReceiptsRootGIndex
+}
+
+// ReceiptParams returns the bounds of the receipt proof, see EventProofParams.ReceiptParams
+func (p ReceiptProofParams) ReceiptParams() MPTParams {
+	return EventProofParams{Receipt: p.Receipt}.ReceiptParams()
+}
+
+// BodyReceiptsRootGIndex returns the generalized index of execution_payload.receipts_root in the BeaconBlockBody
+func (p ReceiptProofParams) BodyReceiptsRootGIndex() types.GIndex {
+	return types.GIndex(uint64(types.ExecutionPayloadGIndex)<<uint(p.PayloadReceiptsRootGIndex().Depth()) |
+		p.PayloadReceiptsRootGIndex().Index())
+}
+
+// NewEth2ReceiptProofCircuit allocates a circuit (or witness) for the given params
+func NewEth2ReceiptProofCircuit(params ReceiptProofParams) *Eth2ReceiptProofCircuit {
+	return &Eth2ReceiptProofCircuit{
+		Params:             params,
+		ReceiptsRootBranch: make([][32]uints.U8, params.BodyReceiptsRootGIndex().Depth()),
+		ReceiptProof:       NewMPTProof(params.ReceiptParams()),
+	}
+}
+
+// NewEth2ReceiptProofAssignment builds the witness proving the receipt of transaction txIndex, given all the
+// receipts of the block of header and the proof of their receipts_root (proof.Leaf) against header.BodyRoot,
+// e.g. generated by types.BeaconBlockBodyProof.
+func NewEth2ReceiptProofAssignment(params ReceiptProofParams, header *zrntcommon.BeaconBlockHeader, proof *types.SSZProof, receipts gethtypes.Receipts, txIndex int) (*Eth2ReceiptProofCircuit, error) {
+	w := NewEth2ReceiptProofCircuit(params)
+	if len(proof.Branch) != len(w.ReceiptsRootBranch) {
+		return nil, fmt.Errorf("branch length %d does not match depth %d", len(proof.Branch), len(w.ReceiptsRootBranch))
+	}
+	if !types.VerifySSZBranch(header.BodyRoot, proof.Leaf, proof.Branch, params.BodyReceiptsRootGIndex()) {
+		return nil, fmt.Errorf("receipts_root branch does not match body root %v", header.BodyRoot)
+	}
+	if txIndex < 0 || txIndex >= len(receipts) || txIndex >= 1<<maxTxIndexBits {
+		return nil, fmt.Errorf("transaction %d not in block (%d receipts)", txIndex, len(receipts))
+	}
+	if root := gethtypes.DeriveSha(receipts, trie.NewStackTrie(nil)); root != gethcommon.Hash(proof.Leaf) {
+		return nil, fmt.Errorf("receipts root is %v, the block commits to %v", root, proof.Leaf)
+	}
+	receipt := receipts[txIndex]
+	if len(receipt.PostState) != 0 {
+		return nil, fmt.Errorf("receipt %d is a pre-Byzantium receipt without status", txIndex)
+	}
+	nodes, err := ReceiptProof(receipts, txIndex)
+	if err != nil {
+		return nil, err
+	}
+	if w.ReceiptProof, err = AssignMPTProof(params.ReceiptParams(), nodes); err != nil {
+		return nil, fmt.Errorf("receipt proof: %w", err)
+	}
+
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	w.Slot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	for i := range proof.Branch {
+		w.ReceiptsRootBranch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+	w.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	w.ReceiptsRoot = [32]uints.U8(uints.NewU8Array(proof.Leaf[:]))
+	w.TxIndex = txIndex
+	w.Status = receipt.Status
+	w.CumulativeGasUsed = receipt.CumulativeGasUsed
+	return w, nil
+}
+
+// Define implements the circuit constraints
 func (c *Eth2ReceiptProofCircuit) Define(api frontend.API) error {
+	gindex := c.Params.BodyReceiptsRootGIndex()
+	if len(c.ReceiptsRootBranch) != gindex.Depth() {
+		return fmt.Errorf("branch length %d does not match depth %d", len(c.ReceiptsRootBranch), gindex.Depth())
+	}
+
+	// Step 1: the trusted header
+	sc := &Eth2ScUpdateCircuit{
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+	}
+	headerRoot := sc.computeBlockRoot(api)
+	gadgets.AssertChunksEqual(api, headerRoot, c.HeaderRoot)
+
+	// Step 2: receipts_root in the body of the header
+	if err := gadgets.VerifySSZBranch(api, c.ReceiptsRoot, c.ReceiptsRootBranch, gindex, c.BodyRoot); err != nil {
+		return fmt.Errorf("receipts root verification failed: %w", err)
+	}
+
+	// Step 3: the receipt is in the receipts trie, at key rlp(TxIndex)
+	key, keyLen := rlpTxIndex(api, c.TxIndex)
+	receipt, receiptLen, err := verifyMPTProof(api, c.Params.ReceiptParams(), c.ReceiptsRoot, key, keyLen, &c.ReceiptProof)
+	if err != nil {
+		return fmt.Errorf("receipt proof: %w", err)
+	}
+
+	// Step 4: [type ||] rlp([status, cumulativeGasUsed, logsBloom, logs]). The type byte, the list prefix
+	// (at most 3 bytes), the status (1) and cumulativeGasUsed (at most 9) fit in the first 14 bytes.
+	head := shiftBytes(api, receipt, 0, receiptHeadBytes)
+	byteAt := func(off frontend.Variable) frontend.Variable {
+		return selector.Mux(api, off, head...)
+	}
+	typed := api.Sub(1, api.ToBinary(receipt[0], 8)[7])
+	list := decodeRLPList(api, byteAt, typed)
+	api.AssertIsEqual(list.valid, 1)
+	api.AssertIsEqual(list.end, receiptLen)
+
+	// status is 0x80 (failure) or 0x01 (success), a post-state root is 32 bytes long
+	status := decodeRLPString(api, byteAt, list.off)
+	api.AssertIsEqual(status.valid, 1)
+	api.AssertIsBoolean(status.len)
+	api.AssertIsEqual(c.Status, api.Mul(status.len, byteAt(status.off)))
+	api.AssertIsBoolean(c.Status)
+
+	// cumulativeGasUsed is a big endian uint64 of at most 8 bytes
+	gas := decodeRLPString(api, byteAt, status.end)
+	api.AssertIsEqual(gas.valid, 1)
+	api.AssertIsLessOrEqual(gas.len, 8)
+	gasBytes := shiftBytes(api, head, gas.off, 8)
+	var cumulativeGasUsed frontend.Variable = 0
+	var inGas frontend.Variable = 1
+	for i := range gasBytes {
+		inGas = api.Sub(inGas, api.IsZero(api.Sub(gas.len, i)))
+		cumulativeGasUsed = api.Select(inGas, api.Add(api.Mul(cumulativeGasUsed, 256), gasBytes[i]), cumulativeGasUsed)
+	}
+	api.AssertIsEqual(c.CumulativeGasUsed, cumulativeGasUsed)
 	return nil
 }
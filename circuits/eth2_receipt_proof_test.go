@@ -0,0 +1,106 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ReceiptProofCircuit(t *testing.T) {
+	_, receipts := newTestReceipts(t)
+	receipts[128].Status = gethtypes.ReceiptStatusFailed
+
+	spec := configs.Mainnet
+	var block electra.BeaconBlock
+	block.Slot = 12345678
+	block.ProposerIndex = 4321
+	block.ParentRoot = zrntcommon.Root{0x01}
+	block.StateRoot = zrntcommon.Root{0x02}
+	block.Body.SyncAggregate.SyncCommitteeBits = make(altair.SyncCommitteeBits, spec.SYNC_COMMITTEE_SIZE/8)
+	block.Body.ExecutionPayload.ReceiptsRoot = zrntcommon.Root(gethtypes.DeriveSha(receipts, trie.NewStackTrie(nil)))
+	block.Body.ExecutionPayload.BlockNumber = 22000000
+	header := block.Header(spec)
+
+	params, err := NewReceiptProofParams("fulu")
+	require.NoError(t, err)
+	params.Receipt = MPTParams{MaxDepth: 5, MaxValueBytes: 512}
+	require.Equal(t, types.GIndex(803), params.BodyReceiptsRootGIndex())
+	require.Equal(t, ReceiptProofParams{}.BodyReceiptsRootGIndex(), params.BodyReceiptsRootGIndex())
+	proof, err := types.BeaconBlockBodyProof(spec, &block.Body, params.BodyReceiptsRootGIndex())
+	require.NoError(t, err)
+
+	circuit := NewEth2ReceiptProofCircuit(params)
+	require.Len(t, circuit.ReceiptsRootBranch, 9)
+	// a legacy receipt, a failed and a successful typed one (rlp keys 0x80, 0x81 0x80 and 0x81 0x81)
+	for _, txIndex := range []int{0, 128, 129} {
+		witness, err := NewEth2ReceiptProofAssignment(params, header, proof, receipts, txIndex)
+		require.NoError(t, err)
+		require.Equal(t, receipts[txIndex].Status, witness.Status)
+		require.Equal(t, receipts[txIndex].CumulativeGasUsed, witness.CumulativeGasUsed)
+		require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), txIndex)
+	}
+
+	witness, err := NewEth2ReceiptProofAssignment(params, header, proof, receipts, 128)
+	require.NoError(t, err)
+	// a reverted transaction can't be claimed successful
+	witness.Status = gethtypes.ReceiptStatusSuccessful
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness.Status = gethtypes.ReceiptStatusFailed
+	witness.CumulativeGasUsed = receipts[128].CumulativeGasUsed - 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	// nor claimed for another transaction
+	witness.CumulativeGasUsed = receipts[128].CumulativeGasUsed
+	witness.TxIndex = 127
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// another receipts root is not in the body
+	witness, err = NewEth2ReceiptProofAssignment(params, header, proof, receipts, 129)
+	require.NoError(t, err)
+	witness.ReceiptsRoot[0] = uints.NewU8(witness.ReceiptsRoot[0].Val.(uint8) ^ 1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// another header does not have the trusted root
+	witness, err = NewEth2ReceiptProofAssignment(params, header, proof, receipts, 129)
+	require.NoError(t, err)
+	witness.Slot = uint64(header.Slot) + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the receipts must be those of the block
+	_, err = NewEth2ReceiptProofAssignment(params, header, proof, receipts[:129], 0)
+	require.Error(t, err)
+	_, err = NewEth2ReceiptProofAssignment(params, header, proof, receipts, 130)
+	require.Error(t, err)
+
+	// a branch of the Capella layout has one payload level less
+	capellaParams, err := NewReceiptProofParams("capella")
+	require.NoError(t, err)
+	capellaParams.Receipt = params.Receipt
+	require.Equal(t, types.GIndex(403), capellaParams.BodyReceiptsRootGIndex())
+	_, err = NewEth2ReceiptProofAssignment(capellaParams, header, proof, receipts, 129)
+	require.Error(t, err)
+
+	branch := make([]zrntcommon.Root, 8)
+	for i := range branch {
+		branch[i][31] = byte(i + 1)
+	}
+	header.BodyRoot, err = types.ComputeSSZBranchRoot(proof.Leaf, branch, capellaParams.BodyReceiptsRootGIndex())
+	require.NoError(t, err)
+	capellaProof := &types.SSZProof{GIndex: capellaParams.BodyReceiptsRootGIndex(), Leaf: proof.Leaf, Branch: branch}
+	witness, err = NewEth2ReceiptProofAssignment(capellaParams, header, capellaProof, receipts, 129)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(NewEth2ReceiptProofCircuit(capellaParams), witness, ecc.BN254.ScalarField()))
+
+	// Altair payloads do not exist
+	_, err = NewReceiptProofParams("altair")
+	require.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package circuit
+
+import (
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+)
+
+// Eth2ScUpdateAggPubKeyCircuit is Eth2ScUpdateCircuit with the aggregated pubkey of the participants as
+// a public input instead of the committee: it proves that AggregatedPubKey signed the attested header,
+// whose state has NextScRoot as next_sync_committee.
+//
+// The committee is neither witnessed nor hashed, so its witness only needs the participating pubkeys
+// decompressed, and the circuit has no per member constraint. In exchange the consumer must check that
+// AggregatedPubKey is the sum of the pubkeys of the participants of the trusted committee, and that
+// they exceed 2/3 of it.
+//
+// This circuit:
+// 1. Requires AggregatedPubKey, in G1 and not at infinity, to be the compressed encoding of the witnessed aggregate
+// 2. Computes the header root and its signing root with Domain
+// 3. Verifies the BLS signature of the aggregate over the signing root
+// 4. Verifies next_sync_committee is included in StateRoot via SSZ Merkle proof
+// 5. Binds the public AttestedSlot and Period to the header slot
+// 6. Verifies the execution block_hash and block_number are included in BodyRoot
+type Eth2ScUpdateAggPubKeyCircuit struct {
+	// Compile-time parameters (not part of the witness), ScPubKeysHashMode, ScPubKeysCheck and
+	// PublicStateRoot are not used
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Aggregated pubkey of the participants and their signature (private inputs)
+	AggregatedPoint sw_bls12381.G1Affine
+	AggregatedSig   sw_bls12381.G2Affine
+
+	// Merkle branches, as in Eth2ScUpdateCircuit
+	NextScBranch          [][32]uints.U8
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8
+
+	// Public inputs
+	AggregatedPubKey [48]uints.U8      `gnark:",public"` // compressed aggregated pubkey of the participants
+	NextScRoot       [32]uints.U8      `gnark:",public"` // SSZ root of next_sync_committee
+	Period           frontend.Variable `gnark:",public"` // sync committee period of the attested header
+	Domain           [32]uints.U8      `gnark:",public"` // signing domain
+	ExecBlockHash    [32]uints.U8      `gnark:",public"` // execution_payload.block_hash of the attested header
+	ExecBlockNumber  frontend.Variable `gnark:",public"` // execution_payload.block_number of the attested header
+	AttestedSlot     frontend.Variable `gnark:",public"` // slot of the attested header
+}
+
+// NewEth2ScUpdateAggPubKeyCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScUpdateAggPubKeyCircuit(params CircuitParams) *Eth2ScUpdateAggPubKeyCircuit {
+	return &Eth2ScUpdateAggPubKeyCircuit{
+		Params:       params,
+		NextScBranch: make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+	}
+}
+
+// AggPubKeyAssignment extracts the Eth2ScUpdateAggPubKeyCircuit witness from an Eth2ScUpdateCircuit
+// witness, whose committee (ScPubKeys, ScBits and ScPubKeysHash) is left out for aggregate, the sum of
+// its participating pubkeys (see types.AggregatePublicKeys). The committee may thus be left unassigned.
+func (c *Eth2ScUpdateCircuit) AggPubKeyAssignment(aggregate bls12381.G1Affine) *Eth2ScUpdateAggPubKeyCircuit {
+	compressed := aggregate.Bytes()
+	return &Eth2ScUpdateAggPubKeyCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		AggregatedPoint:       sw_bls12381.NewG1Affine(aggregate),
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		AggregatedPubKey:      [48]uints.U8(uints.NewU8Array(compressed[:])),
+		NextScRoot:            c.NextScRoot,
+		Period:                c.Period,
+		Domain:                c.Domain,
+		ExecBlockHash:         c.ExecBlockHash,
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+	}
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScUpdateAggPubKeyCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		NextScRoot:            c.NextScRoot,
+		Period:                c.Period,
+		Domain:                c.Domain,
+		ExecBlockHash:         c.ExecBlockHash,
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+	}
+
+	// Step 1: the aggregated pubkey. verifyBLSSignature asserts it in G1, which gnark holds of (0, 0),
+	// so infinity, the aggregate of no participant, is rejected here
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+	if err != nil {
+		return fmt.Errorf("new emulated field: %w", err)
+	}
+	api.AssertIsEqual(isInfinity(api, fp, &c.AggregatedPoint), 0)
+	compressed := sc.serializeG1Compressed(api, fp, &c.AggregatedPoint)
+	for i := range compressed {
+		api.AssertIsEqual(compressed[i].Val, c.AggregatedPubKey[i].Val)
+	}
+
+	// Step 2: the attested header
+	blockRoot := sc.computeBlockRoot(api)
+	signingRoot := sc.computeSigningRoot(api, blockRoot)
+
+	// Step 3: the signature
+	signingRootG2, err := hash2curve.HashToG2(api, signingRoot[:], []byte(hash2curve.EthSignatureDST))
+	if err != nil {
+		return fmt.Errorf("hash-to-curve failed: %w", err)
+	}
+	if err := sc.verifyBLSSignature(api, &c.AggregatedPoint, signingRootG2); err != nil {
+		return fmt.Errorf("BLS signature verification failed: %w", err)
+	}
+
+	// Step 4: next_sync_committee in its state
+	if err := sc.verifyNextSyncCommitteeMerkleProof(api); err != nil {
+		return fmt.Errorf("next_sync_committee Merkle proof verification failed: %w", err)
+	}
+
+	// Step 5: the slot and period
+	sc.verifyPeriod(api)
+
+	// Step 6: the execution payload
+	if err := sc.verifyExecBlockHashMerkleProof(api); err != nil {
+		return fmt.Errorf("execution block hash Merkle proof verification failed: %w", err)
+	}
+	if err := sc.verifyExecBlockNumberMerkleProof(api); err != nil {
+		return fmt.Errorf("execution block number Merkle proof verification failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package circuit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ScUpdateAggPubKeyCircuit(t *testing.T) {
+	var updates [2]types.LightClientUpdate
+	for i, name := range []string{"data/sc-update-1104.json", "data/sc-update-1105.json"} {
+		data, err := os.ReadFile(filepath.Join(rootDir, name))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &updates[i]))
+	}
+	committee, update := &updates[0].Data.NextSyncCommittee, &updates[1]
+
+	params := CircuitParams{}
+	header := &update.Data.AttestedHeader.Beacon
+	bits := types.ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits)
+	var signature bls12381.G2Affine
+	_, err := signature.SetBytes(update.Data.SyncAggregate.SyncCommitteeSignature[:])
+	require.NoError(t, err)
+
+	// the update witness, its committee left unassigned
+	full := NewEth2ScUpdateCircuit(params)
+	full.Slot, full.AttestedSlot = uint64(header.Slot), uint64(header.Slot)
+	full.Period = uint64(header.Slot) >> SlotsPerPeriodLog2
+	full.ProposerIndex = uint64(header.ProposerIndex)
+	full.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	full.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	full.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	domain := params.SigningDomain()
+	full.Domain = [32]uints.U8(uints.NewU8Array(domain[:]))
+	full.AggregatedSig = sw_bls12381.NewG2Affine(signature)
+	assignNextSyncCommitteeToWitness(update, full)
+	require.NoError(t, assignExecutionToWitness(update, full))
+
+	aggregate, _, err := types.AggregatePublicKeys(committee.Pubkeys, bits)
+	require.NoError(t, err)
+	circuit := NewEth2ScUpdateAggPubKeyCircuit(params)
+	require.NoError(t, gnark_test.IsSolved(circuit, full.AggPubKeyAssignment(aggregate), ecc.BN254.ScalarField()))
+
+	// the aggregate of the participants but one
+	for i := range bits {
+		if bits[i] {
+			bits[i] = false
+			break
+		}
+	}
+	partial, _, err := types.AggregatePublicKeys(committee.Pubkeys, bits)
+	require.NoError(t, err)
+	require.Error(t, gnark_test.IsSolved(circuit, full.AggPubKeyAssignment(partial), ecc.BN254.ScalarField()))
+
+	// a public aggregate other than the witnessed one, here its negation
+	witness := full.AggPubKeyAssignment(aggregate)
+	var negated bls12381.G1Affine
+	negated.Neg(&aggregate)
+	compressed := negated.Bytes()
+	witness.AggregatedPubKey = [48]uints.U8(uints.NewU8Array(compressed[:]))
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+}
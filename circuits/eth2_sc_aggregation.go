@@ -0,0 +1,367 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+const (
+	// SyncCommitteeSize is the number of pubkeys in the sync committees the aggregation circuits chain,
+	// the one of the mainnet and gnosis presets: they reject the params of another preset
+	SyncCommitteeSize = 512
+	// SyncCommitteeBytes is the size of a serialized SyncCommittee: 512 pubkeys || aggregate pubkey
+	SyncCommitteeBytes = (SyncCommitteeSize + 1) * 48
+)
+
+// Layout of the public inputs of Eth2ScUpdateCircuit (one field element per byte),
+// in struct field order: ScPubKeysHash, NextScRoot, Period, Domain, ExecBlockHash, ExecBlockNumber, AttestedSlot.
+const (
+	innerScPubKeysHashOffset   = 0
+	innerNextScRootOffset      = 32
+	innerPeriodOffset          = 64
+	innerDomainOffset          = 65
+	innerExecBlockHashOffset   = 97
+	innerExecBlockNumberOffset = 129
+	innerAttestedSlotOffset    = 130
+	innerNbPublicInputs        = 131
+)
+
+// AggregationParams holds the compile-time parameters of Eth2ScAggregationCircuit
+type AggregationParams struct {
+	// NbProofs is the number of consecutive Eth2ScUpdateCircuit proofs verified by the circuit
+	NbProofs int
+	// Inner are the params the inner Eth2ScUpdateCircuit was compiled with
+	Inner CircuitParams
+}
+
+type (
+	innerG1El   = sw_bn254.G1Affine
+	innerG2El   = sw_bn254.G2Affine
+	innerGtEl   = sw_bn254.GTEl
+	innerField  = sw_bn254.ScalarField
+	innerProof  = stdgroth16.Proof[innerG1El, innerG2El]
+	innerVK     = stdgroth16.VerifyingKey[innerG1El, innerG2El, innerGtEl]
+	innerPublic = stdgroth16.Witness[innerField]
+)
+
+// Eth2ScAggregationCircuit recursively verifies NbProofs consecutive Eth2ScUpdateCircuit proofs
+// and exposes only the two ends of the sync committee chain, so that a light client that is
+// many periods behind can catch up with a single on-chain verification.
+//
+// For every inner proof i, the circuit:
+// 1. Verifies the Groth16 proof against the inner verifying key, embedded as a constant
+// 2. Requires its Period to be InitialPeriod + i and its Domain to be the public Domain
+// 3. Recomputes the SSZ root of Committees[i] and requires it to be the proved NextScRoot
+// 4. Hashes the pubkeys of Committees[i] (with the inner ScPubKeysHashMode) and requires it to be
+// the ScPubKeysHash of proof i+1, or FinalScPubKeysHash for the last proof
+//
+// The ScPubKeysHash of the first proof must be InitialScPubKeysHash.
+//
+// Inner proofs must be generated with AggregationProverOptions instead of the
+// Solidity-friendly sha256 hash-to-field option.
+type Eth2ScAggregationCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params  AggregationParams `gnark:"-"`
+	InnerVK innerVK           `gnark:"-"`
+
+	// Inner proofs and their public inputs (private inputs)
+	Proofs    []innerProof
+	Witnesses []innerPublic
+
+	// Committees[i] is the serialized next_sync_committee proved by Proofs[i] (private input)
+	Committees [][SyncCommitteeBytes]uints.U8
+
+	// Public inputs
+	InitialScPubKeysHash [32]uints.U8      `gnark:",public"` // committee trusted by the light client
+	FinalScPubKeysHash   [32]uints.U8      `gnark:",public"` // committee after the last update
+	InitialPeriod        frontend.Variable `gnark:",public"` // period of the first update
+	Domain               [32]uints.U8      `gnark:",public"` // signing domain of all updates
+}
+
+// NewEth2ScAggregationCircuit allocates the aggregation circuit for compilation.
+// innerCcs and innerVK are the constraint system and verifying key of the inner Eth2ScUpdateCircuit.
+func NewEth2ScAggregationCircuit(params AggregationParams, innerCcs constraint.ConstraintSystem, vk groth16.VerifyingKey) (*Eth2ScAggregationCircuit, error) {
+	if params.NbProofs < 1 {
+		return nil, fmt.Errorf("aggregation needs at least one proof, got %d", params.NbProofs)
+	}
+	if n := params.Inner.SyncCommitteeSize(); n != SyncCommitteeSize {
+		return nil, fmt.Errorf("aggregation chains committees of %d members, the %s preset has %d", SyncCommitteeSize, params.Inner.Preset, n)
+	}
+	if nb := innerCcs.GetNbPublicVariables() - 1; nb != innerNbPublicInputs {
+		return nil, fmt.Errorf("inner circuit has %d public inputs, expected %d", nb, innerNbPublicInputs)
+	}
+	circuitVK, err := stdgroth16.ValueOfVerifyingKeyFixed[innerG1El, innerG2El, innerGtEl](vk)
+	if err != nil {
+		return nil, fmt.Errorf("inner verifying key: %w", err)
+	}
+
+	c := &Eth2ScAggregationCircuit{
+		Params:     params,
+		InnerVK:    circuitVK,
+		Proofs:     make([]innerProof, params.NbProofs),
+		Witnesses:  make([]innerPublic, params.NbProofs),
+		Committees: make([][SyncCommitteeBytes]uints.U8, params.NbProofs),
+	}
+	for i := 0; i < params.NbProofs; i++ {
+		c.Proofs[i] = stdgroth16.PlaceholderProof[innerG1El, innerG2El](innerCcs)
+		c.Witnesses[i] = stdgroth16.PlaceholderWitness[innerField](innerCcs)
+	}
+	return c, nil
+}
+
+// NewEth2ScAggregationAssignment builds the witness of the aggregation circuit from consecutive inner
+// proofs, their public witnesses and the next sync committees they prove.
+func NewEth2ScAggregationAssignment(
+	params AggregationParams,
+	proofs []groth16.Proof,
+	publicWitnesses []witness.Witness,
+	committees []*zrntcommon.SyncCommittee,
+	initialScPubKeysHash [32]byte,
+	finalScPubKeysHash [32]byte,
+	initialPeriod uint64,
+	domain [32]byte,
+) (*Eth2ScAggregationCircuit, error) {
+	c := &Eth2ScAggregationCircuit{
+		Params:               params,
+		InitialScPubKeysHash: [32]uints.U8(uints.NewU8Array(initialScPubKeysHash[:])),
+		FinalScPubKeysHash:   [32]uints.U8(uints.NewU8Array(finalScPubKeysHash[:])),
+		InitialPeriod:        initialPeriod,
+		Domain:               [32]uints.U8(uints.NewU8Array(domain[:])),
+	}
+	var err error
+	c.Proofs, c.Witnesses, c.Committees, err = valueOfInnerProofs[innerField, innerG1El, innerG2El](params, proofs, publicWitnesses, committees)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// valueOfInnerProofs converts the inner proofs, their public witnesses and the next sync committees
+// they prove to the witness of an aggregation circuit
+func valueOfInnerProofs[FR emulated.FieldParams, G1El algebra.G1ElementT, G2El algebra.G2ElementT](
+	params AggregationParams,
+	proofs []groth16.Proof,
+	publicWitnesses []witness.Witness,
+	committees []*zrntcommon.SyncCommittee,
+) ([]stdgroth16.Proof[G1El, G2El], []stdgroth16.Witness[FR], [][SyncCommitteeBytes]uints.U8, error) {
+	if len(proofs) != params.NbProofs || len(publicWitnesses) != params.NbProofs || len(committees) != params.NbProofs {
+		return nil, nil, nil, fmt.Errorf("expected %d proofs, witnesses and committees, got %d, %d and %d",
+			params.NbProofs, len(proofs), len(publicWitnesses), len(committees))
+	}
+	circuitProofs := make([]stdgroth16.Proof[G1El, G2El], params.NbProofs)
+	circuitWitnesses := make([]stdgroth16.Witness[FR], params.NbProofs)
+	circuitCommittees := make([][SyncCommitteeBytes]uints.U8, params.NbProofs)
+	for i := 0; i < params.NbProofs; i++ {
+		var err error
+		if circuitProofs[i], err = stdgroth16.ValueOfProof[G1El, G2El](proofs[i]); err != nil {
+			return nil, nil, nil, fmt.Errorf("proof %d: %w", i, err)
+		}
+		if circuitWitnesses[i], err = stdgroth16.ValueOfWitness[FR](publicWitnesses[i]); err != nil {
+			return nil, nil, nil, fmt.Errorf("witness %d: %w", i, err)
+		}
+		committee, err := SerializeSyncCommittee(committees[i])
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("committee %d: %w", i, err)
+		}
+		circuitCommittees[i] = [SyncCommitteeBytes]uints.U8(uints.NewU8Array(committee[:]))
+	}
+	return circuitProofs, circuitWitnesses, circuitCommittees, nil
+}
+
+// SerializeSyncCommittee returns the SSZ serialization of a sync committee: 512 pubkeys || aggregate pubkey
+func SerializeSyncCommittee(sc *zrntcommon.SyncCommittee) ([SyncCommitteeBytes]byte, error) {
+	var out [SyncCommitteeBytes]byte
+	if len(sc.Pubkeys) != SyncCommitteeSize {
+		return out, fmt.Errorf("expected %d pubkeys, got %d", SyncCommitteeSize, len(sc.Pubkeys))
+	}
+	for i, pk := range sc.Pubkeys {
+		copy(out[i*48:], pk[:])
+	}
+	copy(out[SyncCommitteeSize*48:], sc.AggregatePubkey[:])
+	return out, nil
+}
+
+// AggregationProverOptions returns the prover options inner Eth2ScUpdateCircuit proofs must be
+// generated with to be verifiable by Eth2ScAggregationCircuit.
+func AggregationProverOptions() backend.ProverOption {
+	return stdgroth16.GetNativeProverOptions(ecc.BN254.ScalarField(), ecc.BN254.ScalarField())
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScAggregationCircuit) Define(api frontend.API) error {
+	if len(c.Proofs) != c.Params.NbProofs || len(c.Witnesses) != c.Params.NbProofs || len(c.Committees) != c.Params.NbProofs {
+		return fmt.Errorf("circuit is not allocated for %d proofs", c.Params.NbProofs)
+	}
+
+	verifier, err := stdgroth16.NewVerifier[innerField, innerG1El, innerG2El, innerGtEl](api)
+	if err != nil {
+		return fmt.Errorf("new verifier: %w", err)
+	}
+	fr, err := emulated.NewField[innerField](api)
+	if err != nil {
+		return fmt.Errorf("new emulated field: %w", err)
+	}
+
+	publics := make([][]frontend.Variable, c.Params.NbProofs)
+	for i := 0; i < c.Params.NbProofs; i++ {
+		// Step 1: Verify the inner proof
+		if err := verifier.AssertProof(c.InnerVK, c.Proofs[i], c.Witnesses[i], stdgroth16.WithCompleteArithmetic()); err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+		publics[i] = innerPublicInputs(api, fr, c.Witnesses[i])
+	}
+	return assertCommitteeChain(api, c.Params.Inner.ScPubKeysHashMode, publics, c.Committees,
+		c.InitialScPubKeysHash, c.FinalScPubKeysHash, c.InitialPeriod, c.Domain)
+}
+
+// assertCommitteeChain applies steps 2 to 4 of Eth2ScAggregationCircuit to the public inputs of
+// consecutive inner proofs and the committees they hand over
+func assertCommitteeChain(
+	api frontend.API,
+	mode types.ScPubKeysHashMode,
+	publics [][]frontend.Variable,
+	committees [][SyncCommitteeBytes]uints.U8,
+	initialScPubKeysHash, finalScPubKeysHash [32]uints.U8,
+	initialPeriod frontend.Variable,
+	domain [32]uints.U8,
+) error {
+	scPubKeysHash := initialScPubKeysHash
+	for i, public := range publics {
+		// Step 2: Consecutive periods on the same domain
+		api.AssertIsEqual(public[innerPeriodOffset], api.Add(initialPeriod, i))
+		for j := 0; j < 32; j++ {
+			api.AssertIsEqual(public[innerDomainOffset+j], domain[j].Val)
+		}
+
+		// The proof must be made by the committee handed over by the previous proof
+		for j := 0; j < 32; j++ {
+			api.AssertIsEqual(public[innerScPubKeysHashOffset+j], scPubKeysHash[j].Val)
+		}
+
+		// Step 3: Committees[i] is the proved next_sync_committee
+		root, err := syncCommitteeRoot(api, committees[i][:])
+		if err != nil {
+			return fmt.Errorf("committee %d: %w", i, err)
+		}
+		for j := 0; j < 32; j++ {
+			api.AssertIsEqual(public[innerNextScRootOffset+j], root[j].Val)
+		}
+
+		// Step 4: Hand over the commitment to the next committee
+		scPubKeysHash, err = committeePubKeysHash(api, mode, committees[i][:])
+		if err != nil {
+			return fmt.Errorf("committee %d: %w", i, err)
+		}
+	}
+
+	for j := 0; j < 32; j++ {
+		api.AssertIsEqual(scPubKeysHash[j].Val, finalScPubKeysHash[j].Val)
+	}
+	return nil
+}
+
+// innerPublicInputs converts the emulated public inputs of an inner proof to native variables.
+// The conversion is exact as long as the inner scalar field is not larger than the native one: the
+// inner and outer circuits share the BN254 scalar field, and the BLS12-377 scalar field is smaller
+// than the BW6-761 one.
+func innerPublicInputs[FR emulated.FieldParams](api frontend.API, fr *emulated.Field[FR], w stdgroth16.Witness[FR]) []frontend.Variable {
+	public := make([]frontend.Variable, len(w.Public))
+	for i := range w.Public {
+		public[i] = api.FromBinary(fr.ToBitsCanonical(&w.Public[i])...)
+	}
+	return public
+}
+
+// syncCommitteeRoot computes hash_tree_root(SyncCommittee) of a serialized sync committee:
+// hash(merkleize(pubkeys), hash_tree_root(aggregate_pubkey)), each pubkey being padded to two chunks.
+// The committee size is derived from the length and must be a power of two.
+func syncCommitteeRoot(api frontend.API, committee []uints.U8) ([32]uints.U8, error) {
+	size, err := syncCommitteeSizeOf(committee)
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+
+	pubkeyRoot := func(pk []uints.U8) ([32]uints.U8, error) {
+		return sha256Sum(api, pk, uints.NewU8Array(make([]byte, 16)))
+	}
+
+	level := make([][32]uints.U8, size)
+	for i := range level {
+		if level[i], err = pubkeyRoot(committee[i*48 : (i+1)*48]); err != nil {
+			return [32]uints.U8{}, err
+		}
+	}
+	for len(level) > 1 {
+		next := make([][32]uints.U8, len(level)/2)
+		for i := range next {
+			if next[i], err = sha256Sum(api, level[2*i][:], level[2*i+1][:]); err != nil {
+				return [32]uints.U8{}, err
+			}
+		}
+		level = next
+	}
+
+	aggregateRoot, err := pubkeyRoot(committee[size*48:])
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+	return sha256Sum(api, level[0][:], aggregateRoot[:])
+}
+
+// committeePubKeysHash computes the ScPubKeysHash commitment from the compressed pubkeys of a
+// serialized sync committee. In truncated mode the 16 least significant bytes of X are the last
+// 16 bytes of each compressed pubkey; in full mode the compressed pubkeys are hashed as is.
+func committeePubKeysHash(api frontend.API, mode types.ScPubKeysHashMode, committee []uints.U8) ([32]uints.U8, error) {
+	size, err := syncCommitteeSizeOf(committee)
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+
+	var chunks [][]uints.U8
+	switch mode {
+	case types.ScPubKeysHashTruncated:
+		for i := 0; i < size; i++ {
+			chunks = append(chunks, committee[i*48+32:(i+1)*48])
+		}
+	case types.ScPubKeysHashFull:
+		chunks = append(chunks, committee[:size*48])
+	default:
+		return [32]uints.U8{}, fmt.Errorf("unsupported sync committee pubkeys hash mode: %v", mode)
+	}
+	return sha256Sum(api, chunks...)
+}
+
+// syncCommitteeSizeOf returns the number of pubkeys of a serialized sync committee (pubkeys || aggregate pubkey)
+func syncCommitteeSizeOf(committee []uints.U8) (int, error) {
+	size := len(committee)/48 - 1
+	if len(committee)%48 != 0 || size < 1 || size&(size-1) != 0 {
+		return 0, fmt.Errorf("invalid serialized sync committee length %d", len(committee))
+	}
+	return size, nil
+}
+
+// sha256Sum hashes the concatenation of the given byte slices
+func sha256Sum(api frontend.API, chunks ...[]uints.U8) ([32]uints.U8, error) {
+	hasher, err := sha2.New(api)
+	if err != nil {
+		return [32]uints.U8{}, fmt.Errorf("failed to create SHA2 hasher: %w", err)
+	}
+	for _, chunk := range chunks {
+		hasher.Write(chunk)
+	}
+	return [32]uints.U8(hasher.Sum()), nil
+}
@@ -0,0 +1,86 @@
+package circuit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// committeeLinkCircuit checks the committee hand-over of the aggregation circuit in isolation
+type committeeLinkCircuit struct {
+	Committee     []uints.U8
+	Root          [32]uints.U8 `gnark:",public"`
+	ScPubKeysHash [32]uints.U8 `gnark:",public"`
+}
+
+func (c *committeeLinkCircuit) Define(api frontend.API) error {
+	root, err := syncCommitteeRoot(api, c.Committee[:])
+	if err != nil {
+		return err
+	}
+	hash, err := committeePubKeysHash(api, types.ScPubKeysHashTruncated, c.Committee[:])
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(root[i].Val, c.Root[i].Val)
+		api.AssertIsEqual(hash[i].Val, c.ScPubKeysHash[i].Val)
+	}
+	return nil
+}
+
+func TestEth2ScAggregationCircuit_CommitteeLink(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	// a minimal preset committee (32 members) built from real pubkeys keeps the test engine small
+	spec := configs.Minimal
+	committee := zrntcommon.SyncCommittee{
+		Pubkeys:         update.Data.NextSyncCommittee.Pubkeys[:spec.SYNC_COMMITTEE_SIZE],
+		AggregatePubkey: update.Data.NextSyncCommittee.AggregatePubkey,
+	}
+	var serialized []byte
+	for _, pk := range committee.Pubkeys {
+		serialized = append(serialized, pk[:]...)
+	}
+	serialized = append(serialized, committee.AggregatePubkey[:]...)
+
+	root := committee.HashTreeRoot(spec, tree.GetHashFn())
+	pubkeys := make([]bls12381.G1Affine, len(committee.Pubkeys))
+	for i := range pubkeys {
+		_, err := pubkeys[i].SetBytes(committee.Pubkeys[i][:])
+		require.NoError(t, err)
+	}
+	hash := types.ComputeScPubKeysHash(pubkeys)
+
+	circuit := &committeeLinkCircuit{Committee: make([]uints.U8, len(serialized))}
+	assignment := &committeeLinkCircuit{
+		Committee:     uints.NewU8Array(serialized),
+		Root:          [32]uints.U8(uints.NewU8Array(root[:])),
+		ScPubKeysHash: [32]uints.U8(uints.NewU8Array(hash[:])),
+	}
+	require.NoError(t, gnark_test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+
+	// a committee with a single flipped pubkey byte must not match
+	serialized[100] ^= 1
+	assignment.Committee = uints.NewU8Array(serialized)
+	require.Error(t, gnark_test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+
+	// the chained committees are of SyncCommitteeSize members, another preset is rejected
+	_, err = NewEth2ScAggregationCircuit(AggregationParams{NbProofs: 1, Inner: CircuitParams{Preset: types.PresetMinimal}}, nil, nil)
+	require.ErrorContains(t, err, "minimal")
+}
@@ -0,0 +1,298 @@
+package circuit
+
+import (
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// Eth2ScBatchSignatureCircuit is Eth2ScSignatureCircuit for the sync aggregates of Params.BatchSize()
+// headers of one period: it proves that the sync committee committed to by ScPubKeysHash signed each of
+// the headers whose roots are HeaderRoots, for relayers attesting every block rather than one per period.
+//
+// The committee is verified once for the batch, and the signatures are checked as one BLS aggregate
+// signature over distinct messages (the headers have increasing slots):
+//
+//	e(G1, AggregatedSig) == ∏ e(aggregate pubkey of the participants of header k, H(signing root k))
+//
+// AggregatedSig being the sum of the sync aggregate signatures, so the batch costs one signature check
+// and one final exponentiation. With Params.SharedBits, every header has the same participants and the
+// aggregate pubkey is computed once, the product collapsing to e(aggregate pubkey, ∑ H(signing root k)).
+//
+// This circuit:
+// 1. Verifies the sync committee pubkeys hash and checks the pubkeys are in G1, once
+// 2. Aggregates the public keys of the participants of each header (of the batch with Params.SharedBits)
+// 3. Computes the root of each header and requires it to be HeaderRoots[k]
+// 4. Binds AttestedSlots[k] and Period to the slot of each header, the slots strictly increasing
+// 5. Verifies the aggregate signature over the signing roots of the headers
+type Eth2ScBatchSignatureCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params BatchParams `gnark:"-"`
+
+	// BeaconBlockHeaders (private inputs), Params.BatchSize() long
+	Headers []BatchHeader
+
+	// Sync committee data (private inputs): the pubkeys, Params.Inner.SyncCommitteeSize() long, the
+	// participation of each header (of the batch with Params.SharedBits) and the sum of the signatures
+	ScPubKeys     []sw_bls12381.G1Affine
+	ScBits        [][]frontend.Variable
+	AggregatedSig sw_bls12381.G2Affine
+
+	// Public inputs
+	ScPubKeysHash [32]uints.U8        `gnark:",public"` // SHA2 hash to sync committee pubkeys
+	Period        frontend.Variable   `gnark:",public"` // sync committee period of the headers
+	Domain        [32]uints.U8        `gnark:",public"` // signing domain
+	AttestedSlots []frontend.Variable `gnark:",public"` // slot of each header
+	HeaderRoots   [][32]uints.U8      `gnark:",public"` // root of each header
+}
+
+// BatchHeader holds the fields of a BeaconBlockHeader
+type BatchHeader struct {
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+}
+
+// BatchParams holds the compile-time parameters of Eth2ScBatchSignatureCircuit
+type BatchParams struct {
+	// Inner are the params of the committee (preset, pubkeys hash mode and checks), NextScGIndex is not used
+	Inner CircuitParams
+	// NbHeaders is the number of headers of a batch (default 4)
+	NbHeaders int
+	// SharedBits requires every header of the batch to have the same participants
+	SharedBits bool
+}
+
+// BatchSize returns NbHeaders, defaulting to 4
+func (p BatchParams) BatchSize() int {
+	if p.NbHeaders == 0 {
+		return 4
+	}
+	return p.NbHeaders
+}
+
+// NbBits returns the number of participation vectors: 1 with SharedBits, BatchSize() otherwise
+func (p BatchParams) NbBits() int {
+	if p.SharedBits {
+		return 1
+	}
+	return p.BatchSize()
+}
+
+// BatchAttestation is a sync aggregate of a header of the batch
+type BatchAttestation struct {
+	Header    *zrntcommon.BeaconBlockHeader
+	Bits      []bool
+	Signature bls12381.G2Affine
+}
+
+// NewEth2ScBatchSignatureCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScBatchSignatureCircuit(params BatchParams) *Eth2ScBatchSignatureCircuit {
+	c := &Eth2ScBatchSignatureCircuit{
+		Params:        params,
+		Headers:       make([]BatchHeader, params.BatchSize()),
+		ScPubKeys:     make([]sw_bls12381.G1Affine, params.Inner.SyncCommitteeSize()),
+		ScBits:        make([][]frontend.Variable, params.NbBits()),
+		AttestedSlots: make([]frontend.Variable, params.BatchSize()),
+		HeaderRoots:   make([][32]uints.U8, params.BatchSize()),
+	}
+	for i := range c.ScBits {
+		c.ScBits[i] = make([]frontend.Variable, params.Inner.SyncCommitteeSize())
+	}
+	return c
+}
+
+// NewEth2ScBatchSignatureAssignment builds the witness of the attestations of the committee of pubkeys,
+// ordered by slot and all in the same period. The signatures, e.g. decoded by bls12381.G2Affine.SetBytes,
+// are summed into AggregatedSig.
+func NewEth2ScBatchSignatureAssignment(params BatchParams, pubkeys []bls12381.G1Affine, attestations []BatchAttestation) (*Eth2ScBatchSignatureCircuit, error) {
+	w := NewEth2ScBatchSignatureCircuit(params)
+	if len(pubkeys) != len(w.ScPubKeys) {
+		return nil, fmt.Errorf("expected %d pubkeys, got %d", len(w.ScPubKeys), len(pubkeys))
+	}
+	if len(attestations) != params.BatchSize() {
+		return nil, fmt.Errorf("expected %d attestations, got %d", params.BatchSize(), len(attestations))
+	}
+
+	period := params.Inner.Preset.Period(uint64(attestations[0].Header.Slot))
+	var aggregatedSig bls12381.G2Affine
+	for k, a := range attestations {
+		if len(a.Bits) != len(w.ScPubKeys) {
+			return nil, fmt.Errorf("attestation %d: expected %d bits, got %d", k, len(w.ScPubKeys), len(a.Bits))
+		}
+		if k > 0 && a.Header.Slot <= attestations[k-1].Header.Slot {
+			return nil, fmt.Errorf("attestation %d: slot %d does not follow %d", k, a.Header.Slot, attestations[k-1].Header.Slot)
+		}
+		if p := params.Inner.Preset.Period(uint64(a.Header.Slot)); p != period {
+			return nil, fmt.Errorf("attestation %d: period %d, expected %d", k, p, period)
+		}
+		bits := k
+		if params.SharedBits {
+			bits = 0
+			for i := range a.Bits {
+				if a.Bits[i] != attestations[0].Bits[i] {
+					return nil, fmt.Errorf("attestation %d: participation differs from the first attestation", k)
+				}
+			}
+		}
+		for i, bit := range a.Bits {
+			if bit {
+				w.ScBits[bits][i] = 1
+			} else {
+				w.ScBits[bits][i] = 0
+			}
+		}
+		aggregatedSig.Add(&aggregatedSig, &a.Signature)
+
+		headerRoot := a.Header.HashTreeRoot(tree.GetHashFn())
+		w.Headers[k] = BatchHeader{
+			Slot:          uint64(a.Header.Slot),
+			ProposerIndex: uint64(a.Header.ProposerIndex),
+			ParentRoot:    [32]uints.U8(uints.NewU8Array(a.Header.ParentRoot[:])),
+			StateRoot:     [32]uints.U8(uints.NewU8Array(a.Header.StateRoot[:])),
+			BodyRoot:      [32]uints.U8(uints.NewU8Array(a.Header.BodyRoot[:])),
+		}
+		w.AttestedSlots[k] = uint64(a.Header.Slot)
+		w.HeaderRoots[k] = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	}
+
+	for i := range pubkeys {
+		w.ScPubKeys[i] = sw_bls12381.NewG1Affine(pubkeys[i])
+	}
+	w.AggregatedSig = sw_bls12381.NewG2Affine(aggregatedSig)
+	scPubKeysHash := types.ComputeScPubKeysHashWithMode(pubkeys, params.Inner.ScPubKeysHashMode)
+	w.ScPubKeysHash = [32]uints.U8(uints.NewU8Array(scPubKeysHash[:]))
+	w.Period = period
+	domain := params.Inner.SigningDomain()
+	w.Domain = [32]uints.U8(uints.NewU8Array(domain[:]))
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScBatchSignatureCircuit) Define(api frontend.API) error {
+	n, size := c.Params.Inner.SyncCommitteeSize(), c.Params.BatchSize()
+	if len(c.Headers) != size || len(c.AttestedSlots) != size || len(c.HeaderRoots) != size {
+		return fmt.Errorf("%d headers, %d slots and %d roots for a batch of %d", len(c.Headers), len(c.AttestedSlots), len(c.HeaderRoots), size)
+	}
+	if len(c.ScBits) != c.Params.NbBits() {
+		return fmt.Errorf("%d participation vectors, expected %d", len(c.ScBits), c.Params.NbBits())
+	}
+	for _, bits := range c.ScBits {
+		if len(bits) != n {
+			return fmt.Errorf("%d bits for a sync committee of %d", len(bits), n)
+		}
+	}
+
+	// Step 1: the committee
+	sc := &Eth2ScUpdateCircuit{
+		Params:        c.Params.Inner,
+		ScPubKeys:     c.ScPubKeys,
+		ScBits:        c.ScBits[0],
+		ScPubKeysHash: c.ScPubKeysHash,
+	}
+	if err := sc.verifyScPubKeysHash(api); err != nil {
+		return fmt.Errorf("sync committee pubkeys hash verification failed: %w", err)
+	}
+	infinity, err := sc.scPubKeysInfinity(api)
+	if err != nil {
+		return err
+	}
+	if err := sc.assertScPubKeysOnG1(api, infinity); err != nil {
+		return fmt.Errorf("sync committee pubkeys check failed: %w", err)
+	}
+
+	// Step 2: the participants
+	pairing, err := sw_bls12381.NewPairing(api)
+	if err != nil {
+		return fmt.Errorf("failed to create pairing: %w", err)
+	}
+	pubKeys := make([]*sw_bls12381.G1Affine, len(c.ScBits))
+	for j := range c.ScBits {
+		sc.ScBits = c.ScBits[j]
+		if pubKeys[j], err = sc.aggregatePubKeys(api, infinity); err != nil {
+			return fmt.Errorf("public key aggregation failed: %w", err)
+		}
+		pairing.AssertIsOnG1(pubKeys[j])
+	}
+
+	// Steps 3 and 4: the headers, their slots and signing roots
+	messages := make([]*sw_bls12381.G2Affine, size)
+	for k := range c.Headers {
+		h := &Eth2ScUpdateCircuit{
+			Params:        c.Params.Inner,
+			Slot:          c.Headers[k].Slot,
+			ProposerIndex: c.Headers[k].ProposerIndex,
+			ParentRoot:    c.Headers[k].ParentRoot,
+			StateRoot:     c.Headers[k].StateRoot,
+			BodyRoot:      c.Headers[k].BodyRoot,
+			Period:        c.Period,
+			Domain:        c.Domain,
+			AttestedSlot:  c.AttestedSlots[k],
+		}
+		blockRoot := h.computeBlockRoot(api)
+		gadgets.AssertChunksEqual(api, blockRoot, c.HeaderRoots[k])
+		h.verifyPeriod(api)
+		if k > 0 {
+			// distinct headers, so the aggregate signature is over distinct messages
+			api.AssertIsLessOrEqual(api.Add(c.AttestedSlots[k-1], 1), c.AttestedSlots[k])
+		}
+
+		signingRoot := h.computeSigningRoot(api, blockRoot)
+		if messages[k], err = hash2curve.HashToG2(api, signingRoot[:], []byte(hash2curve.EthSignatureDST)); err != nil {
+			return fmt.Errorf("hash-to-curve failed: %w", err)
+		}
+		pairing.AssertIsOnG2(messages[k])
+	}
+
+	// Step 5: e(pubKeys[k], messages[k]) * ... * e(-G1, AggregatedSig) == 1
+	if err := assertSignatureOnG2(api, c.Params.Inner.SigCheck, &c.AggregatedSig); err != nil {
+		return err
+	}
+	if c.Params.SharedBits {
+		g2, err := sw_bls12381.NewG2(api)
+		if err != nil {
+			return fmt.Errorf("failed to create G2: %w", err)
+		}
+		sum := messages[0]
+		for _, m := range messages[1:] {
+			sum = g2.AddUnified(sum, m)
+		}
+		messages = []*sw_bls12381.G2Affine{sum}
+	}
+	curve, err := sw_emulated.New[sw_bls12381.BaseField, sw_bls12381.ScalarField](api, sw_emulated.GetBLS12381Params())
+	if err != nil {
+		return fmt.Errorf("failed to create curve: %w", err)
+	}
+	if err := assertPairingProduct(pairing,
+		append(pubKeys, curve.Neg(curve.Generator())),
+		append(messages, &c.AggregatedSig),
+	); err != nil {
+		return fmt.Errorf("pairing check failed: %w", err)
+	}
+	return nil
+}
+
+// assertPairingProduct asserts ∏ e(P[i], Q[i]) == 1. PairingCheck is used for two pairs, larger products
+// go through Pair: the final exponentiation hint of PairingCheck only reads the first two pairs.
+func assertPairingProduct(pairing *sw_bls12381.Pairing, P []*sw_bls12381.G1Affine, Q []*sw_bls12381.G2Affine) error {
+	if len(P) <= 2 {
+		return pairing.PairingCheck(P, Q)
+	}
+	res, err := pairing.Pair(P, Q)
+	if err != nil {
+		return err
+	}
+	pairing.AssertIsEqual(res, pairing.Ext12.One())
+	return nil
+}
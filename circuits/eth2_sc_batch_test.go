@@ -0,0 +1,109 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchAttestations signs headers of the given slots with the members of the committee of secret keys
+// selected by bits[k] (bits[0] for every header if len(bits) == 1)
+func newBatchAttestations(t *testing.T, params BatchParams, secrets []*big.Int, slots []uint64, bits [][]bool) []BatchAttestation {
+	domain := params.Inner.SigningDomain()
+	attestations := make([]BatchAttestation, len(slots))
+	for k, slot := range slots {
+		header := &zrntcommon.BeaconBlockHeader{
+			Slot:          zrntcommon.Slot(slot),
+			ProposerIndex: zrntcommon.ValidatorIndex(k + 1),
+			ParentRoot:    zrntcommon.Root{byte(k), 0x01},
+			StateRoot:     zrntcommon.Root{byte(k), 0x02},
+			BodyRoot:      zrntcommon.Root{byte(k), 0x03},
+		}
+		signingRoot := zrntcommon.ComputeSigningRoot(header.HashTreeRoot(tree.GetHashFn()), zrntcommon.BLSDomain(domain))
+		message, err := bls12381.HashToG2(signingRoot[:], []byte(hash2curve.EthSignatureDST))
+		require.NoError(t, err)
+
+		b := bits[0]
+		if len(bits) > 1 {
+			b = bits[k]
+		}
+		sk := new(big.Int)
+		for i, bit := range b {
+			if bit {
+				sk.Add(sk, secrets[i])
+			}
+		}
+		attestations[k] = BatchAttestation{Header: header, Bits: b}
+		attestations[k].Signature.ScalarMultiplication(&message, sk)
+	}
+	return attestations
+}
+
+func TestEth2ScBatchSignatureCircuit(t *testing.T) {
+	inner := CircuitParams{Preset: types.PresetMinimal, ScPubKeysCheck: PubKeyCheckNone}
+	n := inner.SyncCommitteeSize()
+	_, _, g1, _ := bls12381.Generators()
+	secrets := make([]*big.Int, n)
+	pubkeys := make([]bls12381.G1Affine, n)
+	for i := range secrets {
+		secrets[i] = big.NewInt(int64(1000 + 7*i))
+		pubkeys[i].ScalarMultiplication(&g1, secrets[i])
+	}
+	all, some := make([]bool, n), make([]bool, n)
+	for i := range all {
+		all[i] = true
+		some[i] = i%3 != 0
+	}
+	period := inner.Preset.Period(1000)
+	slots := []uint64{1000, 1003}
+	require.Equal(t, period, inner.Preset.Period(slots[1]))
+
+	for _, shared := range []bool{false, true} {
+		params := BatchParams{Inner: inner, NbHeaders: 2, SharedBits: shared}
+		bits := [][]bool{all, some}
+		if shared {
+			bits = [][]bool{some}
+		}
+		attestations := newBatchAttestations(t, params, secrets, slots, bits)
+		witness, err := NewEth2ScBatchSignatureAssignment(params, pubkeys, attestations)
+		require.NoError(t, err)
+		require.Len(t, witness.ScBits, params.NbBits())
+		circuit := NewEth2ScBatchSignatureCircuit(params)
+		require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), shared)
+
+		// a header root not signed by the committee
+		witness.HeaderRoots[1] = witness.HeaderRoots[0]
+		require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), shared)
+	}
+
+	params := BatchParams{Inner: inner, NbHeaders: 2}
+	// the signature of a header by fewer members than claimed
+	attestations := newBatchAttestations(t, params, secrets, slots, [][]bool{all, some})
+	attestations[1].Bits = all
+	witness, err := NewEth2ScBatchSignatureAssignment(params, pubkeys, attestations)
+	require.NoError(t, err)
+	require.Error(t, gnark_test.IsSolved(NewEth2ScBatchSignatureCircuit(params), witness, ecc.BN254.ScalarField()))
+
+	// the slots must increase within one period, and the participants be shared with SharedBits
+	attestations = newBatchAttestations(t, params, secrets, []uint64{1003, 1000}, [][]bool{all})
+	_, err = NewEth2ScBatchSignatureAssignment(params, pubkeys, attestations)
+	require.Error(t, err)
+	nextPeriod := (period + 1) << uint(inner.SlotsPerPeriodLog2())
+	attestations = newBatchAttestations(t, params, secrets, []uint64{1000, nextPeriod}, [][]bool{all})
+	_, err = NewEth2ScBatchSignatureAssignment(params, pubkeys, attestations)
+	require.Error(t, err)
+	params.SharedBits = true
+	attestations = newBatchAttestations(t, params, secrets, slots, [][]bool{all, some})
+	_, err = NewEth2ScBatchSignatureAssignment(params, pubkeys, attestations)
+	require.Error(t, err)
+	_, err = NewEth2ScBatchSignatureAssignment(params, pubkeys, attestations[:1])
+	require.Error(t, err)
+}
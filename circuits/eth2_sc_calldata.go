@@ -0,0 +1,115 @@
+package circuit
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+)
+
+// Eth2ScUpdateCalldataCircuit is Eth2ScUpdateCircuit with its public inputs in the order of
+// types.CalldataPublicInputs: the values stored by the verifier contract first, then the values of the
+// update calldata, each input being one half of a calldata word. The sync committee bits stay private.
+//
+// Like with Eth2ScUpdatePackedCircuit, each word is decomposed into bits in-circuit, which range checks
+// it to 128 bits and gives the bytes the update circuit constrains.
+type Eth2ScUpdateCalldataCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Sync committee data (private inputs)
+	ScPubKeys     []sw_bls12381.G1Affine // Params.SyncCommitteeSize() long
+	ScBits        []frontend.Variable
+	AggregatedSig sw_bls12381.G2Affine
+
+	// Merkle branches (private inputs), as in Eth2ScUpdateCircuit
+	NextScBranch          [][32]uints.U8
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8
+
+	// Public inputs stored by the contract, 32 bytes values as two big-endian 128 bits words (hi, lo)
+	ScPubKeysHash [2]frontend.Variable `gnark:",public"`
+	Domain        [2]frontend.Variable `gnark:",public"`
+	Period        frontend.Variable    `gnark:",public"`
+	// Public inputs read from the calldata
+	AttestedSlot    frontend.Variable    `gnark:",public"`
+	NextScRoot      [2]frontend.Variable `gnark:",public"`
+	ExecBlockHash   [2]frontend.Variable `gnark:",public"`
+	ExecBlockNumber frontend.Variable    `gnark:",public"`
+}
+
+// NewEth2ScUpdateCalldataCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScUpdateCalldataCircuit(params CircuitParams) *Eth2ScUpdateCalldataCircuit {
+	return &Eth2ScUpdateCalldataCircuit{
+		Params:       params,
+		ScPubKeys:    make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		ScBits:       make([]frontend.Variable, params.SyncCommitteeSize()),
+		NextScBranch: make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+	}
+}
+
+// CalldataAssignment converts a full Eth2ScUpdateCircuit witness into the Eth2ScUpdateCalldataCircuit one,
+// together with its public values
+func (c *Eth2ScUpdateCircuit) CalldataAssignment() (*Eth2ScUpdateCalldataCircuit, *types.CalldataPublicInputs, error) {
+	values, err := c.publicValues()
+	if err != nil {
+		return nil, nil, err
+	}
+	public := types.NewCalldataPublicInputs(values)
+	w := &Eth2ScUpdateCalldataCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		ScBits:                c.ScBits,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+	}
+	inputs := public.Encode()
+	publics := []*frontend.Variable{
+		&w.ScPubKeysHash[0], &w.ScPubKeysHash[1], &w.Domain[0], &w.Domain[1], &w.Period,
+		&w.AttestedSlot, &w.NextScRoot[0], &w.NextScRoot[1], &w.ExecBlockHash[0], &w.ExecBlockHash[1], &w.ExecBlockNumber,
+	}
+	for i := range publics {
+		*publics[i] = inputs[i]
+	}
+	return w, public, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScUpdateCalldataCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		ScBits:                c.ScBits,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		ScPubKeysHash:         unpackBytes32(api, c.ScPubKeysHash),
+		NextScRoot:            unpackBytes32(api, c.NextScRoot),
+		Period:                c.Period,
+		Domain:                unpackBytes32(api, c.Domain),
+		ExecBlockHash:         unpackBytes32(api, c.ExecBlockHash),
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+	}
+	return sc.Define(api)
+}
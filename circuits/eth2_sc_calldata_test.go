@@ -0,0 +1,38 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ScUpdateCalldataCircuit_PublicWitness(t *testing.T) {
+	assignment, public := newPublicAssignment(t)
+	for i := range assignment.ScBits {
+		assignment.ScBits[i] = 1
+	}
+	calldata, calldataPublic, err := assignment.CalldataAssignment()
+	require.NoError(t, err)
+	require.Equal(t, public.scPubKeysHash, calldataPublic.ScPubKeysHash)
+	require.Equal(t, DOMAIN, calldataPublic.Domain)
+	require.Equal(t, public.period, calldataPublic.Period)
+	require.Equal(t, assignment.AttestedSlot, calldataPublic.AttestedSlot)
+	require.Equal(t, public.nextScRoot, calldataPublic.NextScRoot)
+	require.Equal(t, public.execBlockHash, calldataPublic.ExecBlockHash)
+	require.Equal(t, public.execBlockNumber, calldataPublic.ExecBlockNumber)
+
+	w, err := frontend.NewWitness(calldata, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	vector, ok := w.Vector().(fr.Vector)
+	require.True(t, ok)
+	inputs := calldataPublic.Encode()
+	require.Len(t, vector, types.CalldataNbPublicInputs)
+	for i := range inputs {
+		require.Equal(t, inputs[i], vector[i].BigInt(new(big.Int)), "public input %d", i)
+	}
+}
@@ -0,0 +1,184 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Eth2ScUpdateChainedCircuit is Eth2ScUpdateCircuit which also outputs NextScPubKeysHash, the
+// ScPubKeysHash commitment (in Params.ScPubKeysHashMode) of the next sync committee.
+//
+// The next committee is witnessed as its SSZ SyncCommittee, whose hash_tree_root is required to be
+// NextScRoot, so the light client stores NextScPubKeysHash and checks the ScPubKeysHash of the next
+// period's proof against it, chaining periods without the relayer telling it the commitment.
+//
+// The public inputs are those of Eth2ScUpdateCircuit followed by the 32 bytes of NextScPubKeysHash.
+type Eth2ScUpdateChainedCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Sync committee data (private inputs)
+	ScPubKeys     []sw_bls12381.G1Affine // Params.SyncCommitteeSize() long
+	ScBits        []frontend.Variable
+	AggregatedSig sw_bls12381.G2Affine
+
+	// Merkle branches (private inputs), as in Eth2ScUpdateCircuit
+	NextScBranch          [][32]uints.U8
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8
+
+	// next_sync_committee (private inputs): the compressed pubkeys, Params.SyncCommitteeSize() long,
+	// and the aggregate pubkey
+	NextScPubKeys         [][48]uints.U8
+	NextScAggregatePubKey [48]uints.U8
+
+	// Public inputs of Eth2ScUpdateCircuit
+	ScPubKeysHash   [32]uints.U8      `gnark:",public"`
+	NextScRoot      [32]uints.U8      `gnark:",public"`
+	Period          frontend.Variable `gnark:",public"`
+	Domain          [32]uints.U8      `gnark:",public"`
+	ExecBlockHash   [32]uints.U8      `gnark:",public"`
+	ExecBlockNumber frontend.Variable `gnark:",public"`
+	AttestedSlot    frontend.Variable `gnark:",public"`
+
+	NextScPubKeysHash [32]uints.U8 `gnark:",public"` // ScPubKeysHash of next_sync_committee
+}
+
+// NewEth2ScUpdateChainedCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScUpdateChainedCircuit(params CircuitParams) *Eth2ScUpdateChainedCircuit {
+	return &Eth2ScUpdateChainedCircuit{
+		Params:        params,
+		ScPubKeys:     make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		ScBits:        make([]frontend.Variable, params.SyncCommitteeSize()),
+		NextScBranch:  make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+		NextScPubKeys: make([][48]uints.U8, params.SyncCommitteeSize()),
+	}
+}
+
+// ChainedAssignment converts a full Eth2ScUpdateCircuit witness into the Eth2ScUpdateChainedCircuit one,
+// next being the next_sync_committee whose root is NextScRoot
+func (c *Eth2ScUpdateCircuit) ChainedAssignment(next *zrntcommon.SyncCommittee) (*Eth2ScUpdateChainedCircuit, error) {
+	if len(next.Pubkeys) != c.Params.SyncCommitteeSize() {
+		return nil, fmt.Errorf("expected %d next pubkeys, got %d", c.Params.SyncCommitteeSize(), len(next.Pubkeys))
+	}
+	var nextScRoot [32]byte
+	if err := assignedBytes(nextScRoot[:], c.NextScRoot[:]); err != nil {
+		return nil, fmt.Errorf("next sync committee root: %w", err)
+	}
+	if root := types.SyncCommitteeRoot(next); root != nextScRoot {
+		return nil, fmt.Errorf("next sync committee root is %v, the witness commits to %x", root, nextScRoot)
+	}
+	nextHash := types.ComputeSyncCommitteePubKeysHash(next.Pubkeys, c.Params.ScPubKeysHashMode)
+
+	w := &Eth2ScUpdateChainedCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		ScBits:                c.ScBits,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		NextScPubKeys:         make([][48]uints.U8, len(next.Pubkeys)),
+		NextScAggregatePubKey: [48]uints.U8(uints.NewU8Array(next.AggregatePubkey[:])),
+		ScPubKeysHash:         c.ScPubKeysHash,
+		NextScRoot:            c.NextScRoot,
+		Period:                c.Period,
+		Domain:                c.Domain,
+		ExecBlockHash:         c.ExecBlockHash,
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+		NextScPubKeysHash:     [32]uints.U8(uints.NewU8Array(nextHash[:])),
+	}
+	for i := range next.Pubkeys {
+		w.NextScPubKeys[i] = [48]uints.U8(uints.NewU8Array(next.Pubkeys[i][:]))
+	}
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScUpdateChainedCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		ScBits:                c.ScBits,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		ScPubKeysHash:         c.ScPubKeysHash,
+		NextScRoot:            c.NextScRoot,
+		Period:                c.Period,
+		Domain:                c.Domain,
+		ExecBlockHash:         c.ExecBlockHash,
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+	}
+	if err := sc.Define(api); err != nil {
+		return err
+	}
+	if err := sc.verifyNextScPubKeysHash(api, c.NextScPubKeys, c.NextScAggregatePubKey, c.NextScPubKeysHash); err != nil {
+		return fmt.Errorf("next sync committee pubkeys hash verification failed: %w", err)
+	}
+	return nil
+}
+
+// verifyNextScPubKeysHash requires the SyncCommittee of pubkeys and aggregatePubKey to be NextScRoot,
+// and nextScPubKeysHash to be its ScPubKeysHash commitment.
+//
+// The commitment is computed from the compressed pubkeys, as types.ComputeSyncCommitteePubKeysHash does:
+// in the truncated mode the low 16 bytes of X are the last 16 bytes of the compressed form, and in the
+// full mode it is the compressed form itself, so the next period's circuit, serializing its witnessed
+// G1 points, recomputes the same commitment.
+func (c *Eth2ScUpdateCircuit) verifyNextScPubKeysHash(api frontend.API, pubkeys [][48]uints.U8, aggregatePubKey [48]uints.U8, nextScPubKeysHash [32]uints.U8) error {
+	if n := c.Params.SyncCommitteeSize(); len(pubkeys) != n {
+		return fmt.Errorf("%d next pubkeys for a sync committee of %d", len(pubkeys), n)
+	}
+
+	// the committee contents are bound to NextScRoot in-circuit
+	root, err := gadgets.SyncCommitteeRoot(api, pubkeys, aggregatePubKey)
+	if err != nil {
+		return fmt.Errorf("next sync committee root: %w", err)
+	}
+	gadgets.AssertChunksEqual(api, root, c.NextScRoot)
+
+	hasher, err := sha2.New(api)
+	if err != nil {
+		return fmt.Errorf("failed to create SHA2 hasher: %w", err)
+	}
+	for i := range pubkeys {
+		switch c.Params.ScPubKeysHashMode {
+		case types.ScPubKeysHashTruncated:
+			hasher.Write(pubkeys[i][32:])
+		case types.ScPubKeysHashFull:
+			hasher.Write(pubkeys[i][:])
+		default:
+			return fmt.Errorf("unsupported sync committee pubkeys hash mode: %v", c.Params.ScPubKeysHashMode)
+		}
+	}
+	gadgets.AssertChunksEqual(api, [32]uints.U8(hasher.Sum()), nextScPubKeysHash)
+	return nil
+}
@@ -0,0 +1,92 @@
+package circuit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// nextScPubKeysHashCircuit checks verifyNextScPubKeysHash in isolation
+type nextScPubKeysHashCircuit struct {
+	Params CircuitParams `gnark:"-"`
+
+	NextScPubKeys         [][48]uints.U8
+	NextScAggregatePubKey [48]uints.U8
+	NextScRoot            [32]uints.U8 `gnark:",public"`
+	NextScPubKeysHash     [32]uints.U8 `gnark:",public"`
+}
+
+func (c *nextScPubKeysHashCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{Params: c.Params, NextScRoot: c.NextScRoot}
+	return sc.verifyNextScPubKeysHash(api, c.NextScPubKeys, c.NextScAggregatePubKey, c.NextScPubKeysHash)
+}
+
+func TestEth2ScUpdateChainedCircuit_NextScPubKeysHash(t *testing.T) {
+	var update types.LightClientUpdate
+	data, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &update))
+	next := &update.Data.NextSyncCommittee
+	require.Equal(t, next.HashTreeRoot(configs.Mainnet, tree.GetHashFn()), types.SyncCommitteeRoot(next))
+
+	// the commitment of the compressed pubkeys is the one of the points the next circuit witnesses
+	pubkeys := make([]bls12381.G1Affine, len(next.Pubkeys))
+	for i := range next.Pubkeys {
+		_, err := pubkeys[i].SetBytes(next.Pubkeys[i][:])
+		require.NoError(t, err)
+	}
+	for _, mode := range []types.ScPubKeysHashMode{types.ScPubKeysHashTruncated, types.ScPubKeysHashFull} {
+		require.Equal(t, types.ComputeScPubKeysHashWithMode(pubkeys, mode), types.ComputeSyncCommitteePubKeysHash(next.Pubkeys, mode))
+	}
+
+	// the witness is only built for the committee of NextScRoot
+	assignment, _ := newPublicAssignment(t)
+	chained, err := assignment.ChainedAssignment(next)
+	require.NoError(t, err)
+	nextHash := types.ComputeScPubKeysHash(pubkeys)
+	require.Equal(t, [32]uints.U8(uints.NewU8Array(nextHash[:])), chained.NextScPubKeysHash)
+	other := &zrntcommon.SyncCommittee{Pubkeys: append([]zrntcommon.BLSPubkey{}, next.Pubkeys...), AggregatePubkey: next.AggregatePubkey}
+	other.Pubkeys[0], other.Pubkeys[1] = other.Pubkeys[1], other.Pubkeys[0]
+	_, err = assignment.ChainedAssignment(other)
+	require.Error(t, err)
+
+	// in-circuit, on a minimal committee
+	for _, mode := range []types.ScPubKeysHashMode{types.ScPubKeysHashTruncated, types.ScPubKeysHashFull} {
+		params := CircuitParams{Preset: types.PresetMinimal, ScPubKeysHashMode: mode}
+		committee := &zrntcommon.SyncCommittee{Pubkeys: next.Pubkeys[:32], AggregatePubkey: next.AggregatePubkey}
+		root := types.SyncCommitteeRoot(committee)
+		hash := types.ComputeSyncCommitteePubKeysHash(committee.Pubkeys, mode)
+		witness := &nextScPubKeysHashCircuit{
+			NextScPubKeys:         make([][48]uints.U8, 32),
+			NextScAggregatePubKey: [48]uints.U8(uints.NewU8Array(committee.AggregatePubkey[:])),
+			NextScRoot:            [32]uints.U8(uints.NewU8Array(root[:])),
+			NextScPubKeysHash:     [32]uints.U8(uints.NewU8Array(hash[:])),
+		}
+		for i := range committee.Pubkeys {
+			witness.NextScPubKeys[i] = [48]uints.U8(uints.NewU8Array(committee.Pubkeys[i][:]))
+		}
+		circuit := &nextScPubKeysHashCircuit{Params: params, NextScPubKeys: make([][48]uints.U8, 32)}
+		require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+		// a pubkey which is not in NextScRoot
+		witness.NextScPubKeys[31][0] = uints.NewU8(committee.Pubkeys[31][0] ^ 1)
+		require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+		witness.NextScPubKeys[31] = [48]uints.U8(uints.NewU8Array(committee.Pubkeys[31][:]))
+
+		// a commitment which is not the one of the committee
+		witness.NextScPubKeysHash[0] = uints.NewU8(hash[0] ^ 1)
+		require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	}
+}
@@ -0,0 +1,185 @@
+package circuit
+
+import (
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Eth2ScHandoverCircuit proves the hand-over of the light client from one sync committee to the next,
+// with only their commitments as public inputs: the committee committed to by PrevScPubKeysHash signed a
+// header whose state has a next_sync_committee committed to by NewScPubKeysHash (both in
+// Params.ScPubKeysHashMode), so the verifier contract is a plain PrevScPubKeysHash -> NewScPubKeysHash
+// transition function.
+//
+// The header, the period and NextScRoot stay private. The signing domain is not an input but
+// Params.SigningDomain(), a constant of the compiled circuit: a fork changing the domain needs a new setup.
+// A header attested at the last slot of the previous period has the signing committee as its
+// next_sync_committee, its hand-over being PrevScPubKeysHash to itself.
+//
+// This circuit:
+// 1. Verifies the sync committee pubkeys hash (PrevScPubKeysHash) and checks the pubkeys are in G1
+// 2. Aggregates the public keys of the participants (ScBits)
+// 3. Verifies the BLS signature of the aggregated public key over the signing root of the header
+// 4. Verifies NextScRoot is next_sync_committee in the StateRoot of the header via SSZ Merkle proof
+// 5. Requires the SyncCommittee of NextScPubKeys to be NextScRoot, and NewScPubKeysHash its commitment
+type Eth2ScHandoverCircuit struct {
+	// Compile-time parameters (not part of the witness), the execution branches are not used
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields of the attested header (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Sync committee data (private inputs)
+	ScPubKeys     []sw_bls12381.G1Affine // Params.SyncCommitteeSize() long
+	ScBits        []frontend.Variable
+	AggregatedSig sw_bls12381.G2Affine
+
+	// next_sync_committee (private inputs): its root, Merkle branch in StateRoot, compressed pubkeys,
+	// Params.SyncCommitteeSize() long, and aggregate pubkey
+	NextScRoot            [32]uints.U8
+	NextScBranch          [][32]uints.U8
+	NextScPubKeys         [][48]uints.U8
+	NextScAggregatePubKey [48]uints.U8
+
+	// Public inputs
+	PrevScPubKeysHash [32]uints.U8 `gnark:",public"` // ScPubKeysHash of the signing sync committee
+	NewScPubKeysHash  [32]uints.U8 `gnark:",public"` // ScPubKeysHash of its next_sync_committee
+}
+
+// NewEth2ScHandoverCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScHandoverCircuit(params CircuitParams) *Eth2ScHandoverCircuit {
+	return &Eth2ScHandoverCircuit{
+		Params:        params,
+		ScPubKeys:     make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		ScBits:        make([]frontend.Variable, params.SyncCommitteeSize()),
+		NextScBranch:  make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+		NextScPubKeys: make([][48]uints.U8, params.SyncCommitteeSize()),
+	}
+}
+
+// NewEth2ScHandoverAssignment builds the witness of header, signed with signature by the members of the
+// committee of pubkeys selected by bits, whose state has next as next_sync_committee with the given branch
+func NewEth2ScHandoverAssignment(
+	params CircuitParams,
+	header *zrntcommon.BeaconBlockHeader,
+	pubkeys []bls12381.G1Affine,
+	bits []bool,
+	signature bls12381.G2Affine,
+	next *zrntcommon.SyncCommittee,
+	branch []zrntcommon.Root,
+) (*Eth2ScHandoverCircuit, error) {
+	w := NewEth2ScHandoverCircuit(params)
+	if len(pubkeys) != len(w.ScPubKeys) || len(bits) != len(w.ScBits) {
+		return nil, fmt.Errorf("expected %d pubkeys and bits, got %d and %d", len(w.ScPubKeys), len(pubkeys), len(bits))
+	}
+	if len(next.Pubkeys) != len(w.NextScPubKeys) {
+		return nil, fmt.Errorf("expected %d next pubkeys, got %d", len(w.NextScPubKeys), len(next.Pubkeys))
+	}
+	if len(branch) != len(w.NextScBranch) {
+		return nil, fmt.Errorf("branch length %d does not match depth %d", len(branch), len(w.NextScBranch))
+	}
+	nextScRoot := types.SyncCommitteeRoot(next)
+	if !types.VerifySSZBranch(header.StateRoot, nextScRoot, branch, params.NextSyncCommitteeGIndex()) {
+		return nil, fmt.Errorf("next_sync_committee %v is not in state %v", nextScRoot, header.StateRoot)
+	}
+
+	w.Slot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	for i := range pubkeys {
+		w.ScPubKeys[i] = sw_bls12381.NewG1Affine(pubkeys[i])
+		if bits[i] {
+			w.ScBits[i] = 1
+		} else {
+			w.ScBits[i] = 0
+		}
+	}
+	w.AggregatedSig = sw_bls12381.NewG2Affine(signature)
+	w.NextScRoot = [32]uints.U8(uints.NewU8Array(nextScRoot[:]))
+	for i := range branch {
+		w.NextScBranch[i] = [32]uints.U8(uints.NewU8Array(branch[i][:]))
+	}
+	for i := range next.Pubkeys {
+		w.NextScPubKeys[i] = [48]uints.U8(uints.NewU8Array(next.Pubkeys[i][:]))
+	}
+	w.NextScAggregatePubKey = [48]uints.U8(uints.NewU8Array(next.AggregatePubkey[:]))
+
+	prevHash := types.ComputeScPubKeysHashWithMode(pubkeys, params.ScPubKeysHashMode)
+	newHash := types.ComputeSyncCommitteePubKeysHash(next.Pubkeys, params.ScPubKeysHashMode)
+	w.PrevScPubKeysHash = [32]uints.U8(uints.NewU8Array(prevHash[:]))
+	w.NewScPubKeysHash = [32]uints.U8(uints.NewU8Array(newHash[:]))
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScHandoverCircuit) Define(api frontend.API) error {
+	domain := c.Params.SigningDomain()
+	sc := &Eth2ScUpdateCircuit{
+		Params:        c.Params,
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+		ScPubKeys:     c.ScPubKeys,
+		ScBits:        c.ScBits,
+		AggregatedSig: c.AggregatedSig,
+		NextScBranch:  c.NextScBranch,
+		ScPubKeysHash: c.PrevScPubKeysHash,
+		NextScRoot:    c.NextScRoot,
+		Domain:        [32]uints.U8(uints.NewU8Array(domain[:])),
+	}
+
+	// Step 1: the signing committee
+	if err := sc.verifyScPubKeysHash(api); err != nil {
+		return fmt.Errorf("sync committee pubkeys hash verification failed: %w", err)
+	}
+	infinity, err := sc.scPubKeysInfinity(api)
+	if err != nil {
+		return err
+	}
+	if err := sc.assertScPubKeysOnG1(api, infinity); err != nil {
+		return fmt.Errorf("sync committee pubkeys check failed: %w", err)
+	}
+
+	// Step 2: the participants
+	aggregatedPubKey, err := sc.aggregatePubKeys(api, infinity)
+	if err != nil {
+		return fmt.Errorf("public key aggregation failed: %w", err)
+	}
+
+	// Step 3: the signature of the attested header
+	blockRoot := sc.computeBlockRoot(api)
+	signingRoot := sc.computeSigningRoot(api, blockRoot)
+	signingRootG2, err := hash2curve.HashToG2(api, signingRoot[:], []byte(hash2curve.EthSignatureDST))
+	if err != nil {
+		return fmt.Errorf("hash-to-curve failed: %w", err)
+	}
+	if err := sc.verifyBLSSignature(api, aggregatedPubKey, signingRootG2); err != nil {
+		return fmt.Errorf("BLS signature verification failed: %w", err)
+	}
+
+	// Step 4: next_sync_committee in its state
+	if err := sc.verifyNextSyncCommitteeMerkleProof(api); err != nil {
+		return fmt.Errorf("next_sync_committee Merkle proof verification failed: %w", err)
+	}
+
+	// Step 5: the commitment to the next committee
+	if err := sc.verifyNextScPubKeysHash(api, c.NextScPubKeys, c.NextScAggregatePubKey, c.NewScPubKeysHash); err != nil {
+		return fmt.Errorf("next sync committee pubkeys hash verification failed: %w", err)
+	}
+	return nil
+}
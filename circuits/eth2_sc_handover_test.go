@@ -0,0 +1,107 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ScHandoverCircuit(t *testing.T) {
+	params := CircuitParams{Preset: types.PresetMinimal, ScPubKeysCheck: PubKeyCheckNone}
+	n := params.SyncCommitteeSize()
+	_, _, g1, _ := bls12381.Generators()
+
+	// the signing committee and its next committee
+	secrets := make([]*big.Int, n)
+	pubkeys := make([]bls12381.G1Affine, n)
+	next := &zrntcommon.SyncCommittee{Pubkeys: make([]zrntcommon.BLSPubkey, n)}
+	var nextAggregate bls12381.G1Affine
+	for i := range secrets {
+		secrets[i] = big.NewInt(int64(1000 + 7*i))
+		pubkeys[i].ScalarMultiplication(&g1, secrets[i])
+		var pk bls12381.G1Affine
+		pk.ScalarMultiplication(&g1, big.NewInt(int64(5000+11*i)))
+		next.Pubkeys[i] = pk.Bytes()
+		nextAggregate.Add(&nextAggregate, &pk)
+	}
+	next.AggregatePubkey = nextAggregate.Bytes()
+
+	// a state holding next as next_sync_committee
+	branch := make([]zrntcommon.Root, params.NextSyncCommitteeGIndex().Depth())
+	for i := range branch {
+		branch[i][0] = byte(i + 1)
+	}
+	stateRoot, err := types.ComputeSSZBranchRoot(types.SyncCommitteeRoot(next), branch, params.NextSyncCommitteeGIndex())
+	require.NoError(t, err)
+	header := &zrntcommon.BeaconBlockHeader{
+		Slot:          1000,
+		ProposerIndex: 7,
+		ParentRoot:    zrntcommon.Root{0x01},
+		StateRoot:     stateRoot,
+		BodyRoot:      zrntcommon.Root{0x03},
+	}
+
+	// signed by two thirds of the committee
+	domain := params.SigningDomain()
+	signingRoot := zrntcommon.ComputeSigningRoot(header.HashTreeRoot(tree.GetHashFn()), zrntcommon.BLSDomain(domain))
+	message, err := bls12381.HashToG2(signingRoot[:], []byte(hash2curve.EthSignatureDST))
+	require.NoError(t, err)
+	bits := make([]bool, n)
+	sk := new(big.Int)
+	for i := range bits {
+		if bits[i] = i%3 != 0; bits[i] {
+			sk.Add(sk, secrets[i])
+		}
+	}
+	var signature bls12381.G2Affine
+	signature.ScalarMultiplication(&message, sk)
+
+	witness, err := NewEth2ScHandoverAssignment(params, header, pubkeys, bits, signature, next, branch)
+	require.NoError(t, err)
+	prevHash := types.ComputeScPubKeysHashWithMode(pubkeys, params.ScPubKeysHashMode)
+	require.Equal(t, [32]uints.U8(uints.NewU8Array(prevHash[:])), witness.PrevScPubKeysHash)
+	circuit := NewEth2ScHandoverCircuit(params)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the hand-over to another committee
+	bad, err := NewEth2ScHandoverAssignment(params, header, pubkeys, bits, signature, next, branch)
+	require.NoError(t, err)
+	bad.NewScPubKeysHash[0] = uints.NewU8(bad.NewScPubKeysHash[0].Val.(uint8) ^ 1)
+	require.Error(t, gnark_test.IsSolved(circuit, bad, ecc.BN254.ScalarField()))
+
+	// from another committee
+	bad, err = NewEth2ScHandoverAssignment(params, header, pubkeys, bits, signature, next, branch)
+	require.NoError(t, err)
+	bad.PrevScPubKeysHash[0] = uints.NewU8(bad.PrevScPubKeysHash[0].Val.(uint8) ^ 1)
+	require.Error(t, gnark_test.IsSolved(circuit, bad, ecc.BN254.ScalarField()))
+
+	// with more participants than signed
+	allBits := make([]bool, n)
+	for i := range allBits {
+		allBits[i] = true
+	}
+	bad, err = NewEth2ScHandoverAssignment(params, header, pubkeys, allBits, signature, next, branch)
+	require.NoError(t, err)
+	require.Error(t, gnark_test.IsSolved(circuit, bad, ecc.BN254.ScalarField()))
+
+	// signed for another domain: the domain is a constant of the circuit
+	other := params
+	other.Domain = [32]byte{0x07}
+	require.Error(t, gnark_test.IsSolved(NewEth2ScHandoverCircuit(other), witness, ecc.BN254.ScalarField()))
+
+	// the next committee must be the one of the state
+	_, err = NewEth2ScHandoverAssignment(params, header, pubkeys, bits, signature, &zrntcommon.SyncCommittee{
+		Pubkeys:         append([]zrntcommon.BLSPubkey{next.Pubkeys[1], next.Pubkeys[0]}, next.Pubkeys[2:]...),
+		AggregatePubkey: next.AggregatePubkey,
+	}, branch)
+	require.Error(t, err)
+}
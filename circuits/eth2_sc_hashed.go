@@ -0,0 +1,176 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+)
+
+// Eth2ScUpdateHashedCircuit is Eth2ScUpdateCircuit with a single public input, InputsHash, the
+// SHA-256 of all its public values (types.PackedPublicInputs.HashedPreimage) truncated to 253 bits.
+//
+// The public values are private inputs here: the verifier contract receives them as calldata,
+// recomputes the hash with its sha256 precompile (see types.PackedPublicInputs.InputsHash) and
+// verifies the proof against this single scalar.
+type Eth2ScUpdateHashedCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Sync committee data (private inputs)
+	ScPubKeys     []sw_bls12381.G1Affine // Params.SyncCommitteeSize() long
+	ScBits        []frontend.Variable
+	AggregatedSig sw_bls12381.G2Affine
+
+	// Merkle branches (private inputs), as in Eth2ScUpdateCircuit
+	NextScBranch          [][32]uints.U8
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8
+
+	// The public values of Eth2ScUpdateCircuit, as private inputs
+	ScPubKeysHash   [32]uints.U8
+	NextScRoot      [32]uints.U8
+	Period          frontend.Variable
+	Domain          [32]uints.U8
+	ExecBlockHash   [32]uints.U8
+	ExecBlockNumber frontend.Variable
+	AttestedSlot    frontend.Variable
+
+	// InputsHash is the only public input, sha256(preimage) with its 3 most significant bits cleared
+	InputsHash frontend.Variable `gnark:",public"`
+}
+
+// NewEth2ScUpdateHashedCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScUpdateHashedCircuit(params CircuitParams) *Eth2ScUpdateHashedCircuit {
+	return &Eth2ScUpdateHashedCircuit{
+		Params:       params,
+		ScPubKeys:    make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		ScBits:       make([]frontend.Variable, params.SyncCommitteeSize()),
+		NextScBranch: make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+	}
+}
+
+// HashedAssignment converts a full Eth2ScUpdateCircuit witness into the Eth2ScUpdateHashedCircuit one,
+// together with the public values the contract hashes
+func (c *Eth2ScUpdateCircuit) HashedAssignment() (*Eth2ScUpdateHashedCircuit, *types.PackedPublicInputs, error) {
+	public, err := c.publicValues()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Eth2ScUpdateHashedCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		ScBits:                c.ScBits,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		ScPubKeysHash:         c.ScPubKeysHash,
+		NextScRoot:            c.NextScRoot,
+		Period:                c.Period,
+		Domain:                c.Domain,
+		ExecBlockHash:         c.ExecBlockHash,
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+		InputsHash:            public.InputsHash(),
+	}, public, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScUpdateHashedCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		ScBits:                c.ScBits,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		ScPubKeysHash:         c.ScPubKeysHash,
+		NextScRoot:            c.NextScRoot,
+		Period:                c.Period,
+		Domain:                c.Domain,
+		ExecBlockHash:         c.ExecBlockHash,
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+	}
+	if err := sc.Define(api); err != nil {
+		return err
+	}
+	inputsHash, err := hashPublicValues(api, sc)
+	if err != nil {
+		return fmt.Errorf("public values hash: %w", err)
+	}
+	api.AssertIsEqual(inputsHash, c.InputsHash)
+	return nil
+}
+
+// hashPublicValues computes types.PackedPublicInputs.InputsHash over the public values of sc
+func hashPublicValues(api frontend.API, sc *Eth2ScUpdateCircuit) (frontend.Variable, error) {
+	h, err := sha2.New(api)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(sc.ScPubKeysHash[:])
+	h.Write(sc.NextScRoot[:])
+	h.Write(uint64Bytes(api, sc.Period))
+	h.Write(sc.Domain[:])
+	h.Write(sc.ExecBlockHash[:])
+	h.Write(uint64Bytes(api, sc.ExecBlockNumber))
+	h.Write(uint64Bytes(api, sc.AttestedSlot))
+
+	// sync_committee_bits, SSZ serialized and zero padded to 64 bytes
+	var bits [8]frontend.Variable
+	scBits := make([]uints.U8, 64)
+	for i := range scBits {
+		if 8*i >= len(sc.ScBits) {
+			scBits[i] = uints.NewU8(0)
+			continue
+		}
+		for j := range bits {
+			bits[j] = sc.ScBits[8*i+j]
+			api.AssertIsBoolean(bits[j])
+		}
+		scBits[i] = uints.U8{Val: api.FromBinary(bits[:]...)}
+	}
+	h.Write(scBits)
+	digest := h.Sum()
+
+	// the big-endian digest without the 3 most significant bits is below the BN254 scalar field modulus
+	top := api.ToBinary(digest[0].Val, 8)
+	res := api.FromBinary(top[:5]...)
+	for _, b := range digest[1:] {
+		res = api.Add(api.Mul(res, 256), b.Val)
+	}
+	return res, nil
+}
+
+// uint64Bytes returns the big-endian bytes of a 64 bits value, range checking it
+func uint64Bytes(api frontend.API, v frontend.Variable) []uints.U8 {
+	bits := api.ToBinary(v, 64)
+	out := make([]uints.U8, 8)
+	for j := range out {
+		out[j] = uints.U8{Val: api.FromBinary(bits[8*(7-j) : 8*(8-j)]...)}
+	}
+	return out
+}
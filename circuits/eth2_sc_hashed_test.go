@@ -0,0 +1,83 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+// publicValuesHashCircuit checks hashPublicValues against types.PackedPublicInputs.InputsHash
+type publicValuesHashCircuit struct {
+	ScPubKeysHash   [32]uints.U8
+	NextScRoot      [32]uints.U8
+	Period          frontend.Variable
+	Domain          [32]uints.U8
+	ExecBlockHash   [32]uints.U8
+	ExecBlockNumber frontend.Variable
+	AttestedSlot    frontend.Variable
+	ScBits          []frontend.Variable
+
+	InputsHash frontend.Variable `gnark:",public"`
+}
+
+func (c *publicValuesHashCircuit) Define(api frontend.API) error {
+	h, err := hashPublicValues(api, &Eth2ScUpdateCircuit{
+		ScPubKeysHash:   c.ScPubKeysHash,
+		NextScRoot:      c.NextScRoot,
+		Period:          c.Period,
+		Domain:          c.Domain,
+		ExecBlockHash:   c.ExecBlockHash,
+		ExecBlockNumber: c.ExecBlockNumber,
+		AttestedSlot:    c.AttestedSlot,
+		ScBits:          c.ScBits,
+	})
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(h, c.InputsHash)
+	return nil
+}
+
+func TestEth2ScUpdateHashedCircuit_InputsHash(t *testing.T) {
+	assignment, _ := newPublicAssignment(t)
+	for i := range assignment.ScBits {
+		assignment.ScBits[i] = (i / 3) % 2
+	}
+	hashed, public, err := assignment.HashedAssignment()
+	require.NoError(t, err)
+	require.Equal(t, public.InputsHash(), hashed.InputsHash)
+
+	// the only public input
+	w, err := frontend.NewWitness(hashed, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	vector, ok := w.Vector().(fr.Vector)
+	require.True(t, ok)
+	require.Len(t, vector, 1)
+	require.Equal(t, public.InputsHash(), vector[0].BigInt(new(big.Int)))
+
+	witness := &publicValuesHashCircuit{
+		ScPubKeysHash:   assignment.ScPubKeysHash,
+		NextScRoot:      assignment.NextScRoot,
+		Period:          assignment.Period,
+		Domain:          assignment.Domain,
+		ExecBlockHash:   assignment.ExecBlockHash,
+		ExecBlockNumber: assignment.ExecBlockNumber,
+		AttestedSlot:    assignment.AttestedSlot,
+		ScBits:          append([]frontend.Variable{}, assignment.ScBits...),
+		InputsHash:      hashed.InputsHash,
+	}
+	require.NoError(t, gnark_test.IsSolved(&publicValuesHashCircuit{ScBits: make([]frontend.Variable, SyncCommitteeSize)}, witness, ecc.BN254.ScalarField()))
+
+	witness.ScBits[511] = 1 - assignment.ScBits[511].(int)
+	require.Error(t, gnark_test.IsSolved(&publicValuesHashCircuit{ScBits: make([]frontend.Variable, SyncCommitteeSize)}, witness, ecc.BN254.ScalarField()))
+	witness.ScBits[511] = assignment.ScBits[511]
+
+	witness.AttestedSlot = public.AttestedSlot + 1
+	require.Error(t, gnark_test.IsSolved(&publicValuesHashCircuit{ScBits: make([]frontend.Variable, SyncCommitteeSize)}, witness, ecc.BN254.ScalarField()))
+}
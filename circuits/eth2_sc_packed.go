@@ -0,0 +1,231 @@
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+)
+
+// Eth2ScUpdatePackedCircuit is Eth2ScUpdateCircuit with its public inputs packed into
+// types.PackedNbPublicInputs field elements instead of one per byte, see types.PackedPublicInputs.
+//
+// The sync committee bits are public as well, so the verifier contract counts the participants
+// itself for the 2/3 check instead of trusting the relayer.
+//
+// Each packed word is decomposed into bits in-circuit, which range checks it to 128 bits and gives the
+// bytes (or the bits) the update circuit constrains.
+type Eth2ScUpdatePackedCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Sync committee data (private inputs)
+	ScPubKeys     []sw_bls12381.G1Affine // Params.SyncCommitteeSize() long
+	AggregatedSig sw_bls12381.G2Affine
+
+	// Merkle branches (private inputs), as in Eth2ScUpdateCircuit
+	NextScBranch          [][32]uints.U8
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8
+
+	// Public inputs, 32 bytes values as two big-endian 128 bits words (hi, lo)
+	ScPubKeysHash   [2]frontend.Variable `gnark:",public"`
+	NextScRoot      [2]frontend.Variable `gnark:",public"`
+	Period          frontend.Variable    `gnark:",public"`
+	Domain          [2]frontend.Variable `gnark:",public"`
+	ExecBlockHash   [2]frontend.Variable `gnark:",public"`
+	ExecBlockNumber frontend.Variable    `gnark:",public"`
+	AttestedSlot    frontend.Variable    `gnark:",public"`
+	ScBits          [4]frontend.Variable `gnark:",public"` // sync_committee_bits, zero padded to 512 bits, as four big-endian 128 bits words
+}
+
+// NewEth2ScUpdatePackedCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScUpdatePackedCircuit(params CircuitParams) *Eth2ScUpdatePackedCircuit {
+	return &Eth2ScUpdatePackedCircuit{
+		Params:       params,
+		ScPubKeys:    make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		NextScBranch: make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+	}
+}
+
+// PackedAssignment converts a full Eth2ScUpdateCircuit witness into the Eth2ScUpdatePackedCircuit one,
+// together with its public values
+func (c *Eth2ScUpdateCircuit) PackedAssignment() (*Eth2ScUpdatePackedCircuit, *types.PackedPublicInputs, error) {
+	public, err := c.publicValues()
+	if err != nil {
+		return nil, nil, err
+	}
+	w := &Eth2ScUpdatePackedCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+	}
+	inputs := public.Encode()
+	publics := []*frontend.Variable{
+		&w.ScPubKeysHash[0], &w.ScPubKeysHash[1], &w.NextScRoot[0], &w.NextScRoot[1], &w.Period,
+		&w.Domain[0], &w.Domain[1], &w.ExecBlockHash[0], &w.ExecBlockHash[1], &w.ExecBlockNumber, &w.AttestedSlot,
+		&w.ScBits[0], &w.ScBits[1], &w.ScBits[2], &w.ScBits[3],
+	}
+	for i := range publics {
+		*publics[i] = inputs[i]
+	}
+	return w, public, nil
+}
+
+// publicValues reads the public values, and the sync committee bits, of an assigned witness
+func (c *Eth2ScUpdateCircuit) publicValues() (*types.PackedPublicInputs, error) {
+	public := &types.PackedPublicInputs{}
+	var err error
+	for _, f := range []struct {
+		dst []byte
+		src []uints.U8
+	}{
+		{public.ScPubKeysHash[:], c.ScPubKeysHash[:]},
+		{public.NextScRoot[:], c.NextScRoot[:]},
+		{public.Domain[:], c.Domain[:]},
+		{public.ExecBlockHash[:], c.ExecBlockHash[:]},
+	} {
+		if err = assignedBytes(f.dst, f.src); err != nil {
+			return nil, err
+		}
+	}
+	if public.Period, err = assignedUint64(c.Period); err != nil {
+		return nil, fmt.Errorf("period: %w", err)
+	}
+	if public.ExecBlockNumber, err = assignedUint64(c.ExecBlockNumber); err != nil {
+		return nil, fmt.Errorf("execution block number: %w", err)
+	}
+	if public.AttestedSlot, err = assignedUint64(c.AttestedSlot); err != nil {
+		return nil, fmt.Errorf("attested slot: %w", err)
+	}
+	bits := make([]bool, len(c.ScBits))
+	for i := range c.ScBits {
+		bit, err := assignedUint64(c.ScBits[i])
+		if err != nil || bit > 1 {
+			return nil, fmt.Errorf("sync committee bit %d is not a bit", i)
+		}
+		bits[i] = bit == 1
+	}
+	public.ScBits = types.SyncCommitteeBitsBytes(bits)
+	return public, nil
+}
+
+// assignedBytes copies the values of an assigned byte array
+func assignedBytes(dst []byte, src []uints.U8) error {
+	for i := range src {
+		v, err := assignedUint64(src[i].Val)
+		if err != nil || v > 0xff {
+			return fmt.Errorf("byte %d is not assigned a byte", i)
+		}
+		dst[i] = byte(v)
+	}
+	return nil
+}
+
+// assignedUint64 returns the value of an assigned variable
+func assignedUint64(v frontend.Variable) (uint64, error) {
+	switch v := v.(type) {
+	case uint8:
+		return uint64(v), nil
+	case int:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case uint64:
+		return v, nil
+	case *big.Int:
+		if v.IsUint64() {
+			return v.Uint64(), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported assignment %v (%T)", v, v)
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScUpdatePackedCircuit) Define(api frontend.API) error {
+	scBits, err := unpackScBits(api, c.ScBits, c.Params.SyncCommitteeSize())
+	if err != nil {
+		return err
+	}
+	sc := &Eth2ScUpdateCircuit{
+		Params:                c.Params,
+		Slot:                  c.Slot,
+		ProposerIndex:         c.ProposerIndex,
+		ParentRoot:            c.ParentRoot,
+		StateRoot:             c.StateRoot,
+		BodyRoot:              c.BodyRoot,
+		ScPubKeys:             c.ScPubKeys,
+		AggregatedSig:         c.AggregatedSig,
+		NextScBranch:          c.NextScBranch,
+		ExecBlockHashBranch:   c.ExecBlockHashBranch,
+		ExecBlockNumberBranch: c.ExecBlockNumberBranch,
+		ScPubKeysHash:         unpackBytes32(api, c.ScPubKeysHash),
+		NextScRoot:            unpackBytes32(api, c.NextScRoot),
+		Period:                c.Period,
+		Domain:                unpackBytes32(api, c.Domain),
+		ExecBlockHash:         unpackBytes32(api, c.ExecBlockHash),
+		ExecBlockNumber:       c.ExecBlockNumber,
+		AttestedSlot:          c.AttestedSlot,
+		ScBits:                scBits,
+	}
+	return sc.Define(api)
+}
+
+// unpackWord decomposes a big-endian 128 bits word into its bytes
+func unpackWord(api frontend.API, word frontend.Variable) [types.PackedWordBytes]uints.U8 {
+	const n = types.PackedWordBytes
+	bits := api.ToBinary(word, 8*n)
+	var out [n]uints.U8
+	for j := range out {
+		out[j] = uints.U8{Val: api.FromBinary(bits[8*(n-1-j) : 8*(n-j)]...)}
+	}
+	return out
+}
+
+// unpackBytes32 is the inverse of types.PackWords for a 32 bytes value
+func unpackBytes32(api frontend.API, words [2]frontend.Variable) [32]uints.U8 {
+	var out [32]uints.U8
+	hi, lo := unpackWord(api, words[0]), unpackWord(api, words[1])
+	copy(out[:16], hi[:])
+	copy(out[16:], lo[:])
+	return out
+}
+
+// unpackScBits returns the bits of a sync committee of size members, the preset's, from the packed SSZ
+// Bitvector[512]. The bits past the committee (32 members on minimal) are padding, asserted zero.
+func unpackScBits(api frontend.API, words [4]frontend.Variable, size int) ([]frontend.Variable, error) {
+	const n = types.PackedWordBytes
+	out := make([]frontend.Variable, 8*n*len(words))
+	if size > len(out) {
+		return nil, fmt.Errorf("a sync committee of %d members does not fit the %d packed bits", size, len(out))
+	}
+	for k := range words {
+		bits := api.ToBinary(words[k], 8*n)
+		for i := 0; i < 8*n; i++ {
+			// bit i of the word is bit i%8 of its byte n-1-i/8, i.e. of byte n*(k+1)-1-i/8 of the bitvector
+			out[8*(n*(k+1)-1-i/8)+i%8] = bits[i]
+		}
+	}
+	for _, bit := range out[size:] {
+		api.AssertIsEqual(bit, 0)
+	}
+	return out[:size], nil
+}
@@ -0,0 +1,122 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+// unpackCircuit checks the unpacking of Eth2ScUpdatePackedCircuit against the native packing
+type unpackCircuit struct {
+	Size       int                  `gnark:"-"`
+	Hash       [2]frontend.Variable `gnark:",public"`
+	ScBits     [4]frontend.Variable `gnark:",public"`
+	HashBytes  [32]uints.U8
+	ScBitsBits [512]frontend.Variable
+}
+
+func (c *unpackCircuit) Define(api frontend.API) error {
+	hash := unpackBytes32(api, c.Hash)
+	for i := range hash {
+		api.AssertIsEqual(hash[i].Val, c.HashBytes[i].Val)
+	}
+	bits, err := unpackScBits(api, c.ScBits, c.Size)
+	if err != nil {
+		return err
+	}
+	for i := range bits {
+		api.AssertIsEqual(bits[i], c.ScBitsBits[i])
+	}
+	return nil
+}
+
+func TestEth2ScUpdatePackedCircuit_Unpack(t *testing.T) {
+	var hash [32]byte
+	for i := range hash {
+		hash[i] = byte(7*i + 3)
+	}
+	bits := make([]bool, 512)
+	for i := range bits {
+		bits[i] = i%5 == 1 || i > 500
+	}
+	scBits := types.SyncCommitteeBitsBytes(bits)
+
+	hashWords, err := types.PackWords(hash[:])
+	require.NoError(t, err)
+	bitsWords, err := types.PackWords(scBits[:])
+	require.NoError(t, err)
+	witness := &unpackCircuit{HashBytes: [32]uints.U8(uints.NewU8Array(hash[:]))}
+	for i := range witness.Hash {
+		witness.Hash[i] = hashWords[i]
+	}
+	for i := range witness.ScBits {
+		witness.ScBits[i] = bitsWords[i]
+	}
+	for i := range bits {
+		witness.ScBitsBits[i] = 0
+		if bits[i] {
+			witness.ScBitsBits[i] = 1
+		}
+	}
+	require.NoError(t, gnark_test.IsSolved(&unpackCircuit{Size: 512}, witness, ecc.BN254.ScalarField()))
+
+	witness.ScBitsBits[501] = 0
+	require.Error(t, gnark_test.IsSolved(&unpackCircuit{Size: 512}, witness, ecc.BN254.ScalarField()))
+	witness.ScBitsBits[501] = 1
+
+	// words of more than 128 bits are rejected, even with the right low bits
+	witness.Hash[1] = new(big.Int).Add(hashWords[1], new(big.Int).Lsh(big.NewInt(1), 128))
+	require.Error(t, gnark_test.IsSolved(&unpackCircuit{Size: 512}, witness, ecc.BN254.ScalarField()))
+	witness.Hash[1] = hashWords[1]
+
+	// a minimal committee only takes the first 32 bits, the others must be zero padding
+	require.Error(t, gnark_test.IsSolved(&unpackCircuit{Size: 32}, witness, ecc.BN254.ScalarField()))
+	minimal := types.SyncCommitteeBitsBytes(bits[:32])
+	minimalWords, err := types.PackWords(minimal[:])
+	require.NoError(t, err)
+	for i := range witness.ScBits {
+		witness.ScBits[i] = minimalWords[i]
+	}
+	require.NoError(t, gnark_test.IsSolved(&unpackCircuit{Size: 32}, witness, ecc.BN254.ScalarField()))
+
+	// a committee larger than the packed bits is rejected at compile time
+	_, err = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &unpackCircuit{Size: 513})
+	require.Error(t, err)
+}
+
+func TestEth2ScUpdatePackedCircuit_PublicWitness(t *testing.T) {
+	assignment, public := newPublicAssignment(t)
+	for i := range assignment.ScBits {
+		assignment.ScBits[i] = i % 2
+	}
+	packed, packedPublic, err := assignment.PackedAssignment()
+	require.NoError(t, err)
+	require.Equal(t, public.scPubKeysHash, packedPublic.ScPubKeysHash)
+	require.Equal(t, public.nextScRoot, packedPublic.NextScRoot)
+	require.Equal(t, DOMAIN, packedPublic.Domain)
+	require.Equal(t, public.execBlockHash, packedPublic.ExecBlockHash)
+	require.Equal(t, public.period, packedPublic.Period)
+	require.Equal(t, 256, packedPublic.ParticipantCount())
+
+	w, err := frontend.NewWitness(packed, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	vector, ok := w.Vector().(fr.Vector)
+	require.True(t, ok)
+	inputs := packedPublic.Encode()
+	require.Len(t, vector, types.PackedNbPublicInputs)
+	for i := range inputs {
+		require.Equal(t, inputs[i], vector[i].BigInt(new(big.Int)), "public input %d", i)
+	}
+
+	assignment.ScBits[3] = 2
+	_, _, err = assignment.PackedAssignment()
+	require.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/profile"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+)
+
+// Eth2ScUpdateSteps are the steps of Eth2ScUpdateCircuit.Define, in order, as profiled by ProfileEth2ScUpdateCircuit
+var Eth2ScUpdateSteps = []string{
+	"sc-pubkeys-hash", // verifyScPubKeysHash
+	"pubkeys-g1",      // scPubKeysInfinity and assertScPubKeysOnG1
+	"aggregation",     // aggregatePubKeys
+	"signing-root",    // computeBlockRoot and computeSigningRoot
+	"hash-to-curve",   // hash2curve.HashToG2
+	"pairing",         // verifyBLSSignature
+	"merkle-proofs",   // next_sync_committee, block_hash and block_number branches
+	"period",          // verifyPeriod
+}
+
+// StepConstraints is the number of constraints of one step of Eth2ScUpdateCircuit
+type StepConstraints struct {
+	Step          string
+	NbConstraints int
+}
+
+// Eth2ScProfile reports the constraints of Eth2ScUpdateCircuit per step.
+//
+// Every step is compiled alone, with the outputs of the previous steps as witnesses, so that the
+// deferred checks of the emulated arithmetic and of the lookups are counted in the step that
+// needs them. The steps do not share these checks, so their sum exceeds NbConstraints.
+type Eth2ScProfile struct {
+	NbConstraints int
+	Steps         []StepConstraints
+}
+
+func (p *Eth2ScProfile) String() string {
+	var sb strings.Builder
+	sum := 0
+	for _, s := range p.Steps {
+		sum += s.NbConstraints
+	}
+	for _, s := range p.Steps {
+		fmt.Fprintf(&sb, "%-16s %12d %6.2f%%\n", s.Step, s.NbConstraints, 100*float64(s.NbConstraints)/float64(max(sum, 1)))
+	}
+	fmt.Fprintf(&sb, "%-16s %12d\n", "sum of steps", sum)
+	if p.NbConstraints > 0 {
+		fmt.Fprintf(&sb, "%-16s %12d\n", "circuit", p.NbConstraints)
+	}
+	return sb.String()
+}
+
+// eth2ScStepCircuit runs one step of Eth2ScUpdateCircuit, the outputs of the previous steps being witnessed
+type eth2ScStepCircuit struct {
+	Step string `gnark:"-"`
+
+	Update           Eth2ScUpdateCircuit
+	Infinity         []frontend.Variable
+	AggregatedPubKey sw_bls12381.G1Affine
+	SigningRoot      [32]uints.U8
+	SigningRootG2    sw_bls12381.G2Affine
+}
+
+// Define implements the constraints of Step
+func (c *eth2ScStepCircuit) Define(api frontend.API) error {
+	sc := &c.Update
+	switch c.Step {
+	case "sc-pubkeys-hash":
+		return sc.verifyScPubKeysHash(api)
+	case "pubkeys-g1":
+		infinity, err := sc.scPubKeysInfinity(api)
+		if err != nil {
+			return err
+		}
+		return sc.assertScPubKeysOnG1(api, infinity)
+	case "aggregation":
+		for i := range c.Infinity {
+			api.AssertIsBoolean(c.Infinity[i])
+		}
+		_, err := sc.aggregatePubKeys(api, c.Infinity)
+		return err
+	case "signing-root":
+		signingRoot := sc.computeSigningRoot(api, sc.computeBlockRoot(api))
+		for i := range signingRoot {
+			api.AssertIsEqual(signingRoot[i].Val, c.SigningRoot[i].Val)
+		}
+		return nil
+	case "hash-to-curve":
+		_, err := hash2curve.HashToG2(api, c.SigningRoot[:], []byte(hash2curve.EthSignatureDST))
+		return err
+	case "pairing":
+		return sc.verifyBLSSignature(api, &c.AggregatedPubKey, &c.SigningRootG2)
+	case "merkle-proofs":
+		if err := sc.verifyNextSyncCommitteeMerkleProof(api); err != nil {
+			return err
+		}
+		if err := sc.verifyExecBlockHashMerkleProof(api); err != nil {
+			return err
+		}
+		return sc.verifyExecBlockNumberMerkleProof(api)
+	case "period":
+		sc.verifyPeriod(api)
+		return nil
+	}
+	return fmt.Errorf("unknown Eth2ScUpdateCircuit step %q", c.Step)
+}
+
+// ProfileEth2ScUpdateSteps compiles the given steps of Eth2ScUpdateCircuit (all of Eth2ScUpdateSteps if none)
+// and returns their number of constraints
+func ProfileEth2ScUpdateSteps(params CircuitParams, field *big.Int, newBuilder frontend.NewBuilder, steps ...string) ([]StepConstraints, error) {
+	if len(steps) == 0 {
+		steps = Eth2ScUpdateSteps
+	}
+	out := make([]StepConstraints, 0, len(steps))
+	for _, step := range steps {
+		c := &eth2ScStepCircuit{Step: step, Update: *NewEth2ScUpdateCircuit(params), Infinity: make([]frontend.Variable, params.SyncCommitteeSize())}
+		ccs, err := frontend.Compile(field, newBuilder, c, frontend.IgnoreUnconstrainedInputs())
+		if err != nil {
+			return nil, fmt.Errorf("compile step %s: %w", step, err)
+		}
+		out = append(out, StepConstraints{Step: step, NbConstraints: ccs.GetNbConstraints()})
+	}
+	return out, nil
+}
+
+// ProfileEth2ScUpdateCircuit compiles Eth2ScUpdateCircuit under gnark's profiler, writing the pprof profile
+// to pprofPath unless it is empty, and each of its steps alone.
+//
+// The profile is read with `go tool pprof -top <pprofPath>`; its call stacks are truncated and the deferred
+// checks are attributed to the compiler, hence the per step compilations.
+func ProfileEth2ScUpdateCircuit(params CircuitParams, field *big.Int, newBuilder frontend.NewBuilder, pprofPath string) (*Eth2ScProfile, error) {
+	var p *profile.Profile
+	if pprofPath != "" {
+		p = profile.Start(profile.WithPath(pprofPath))
+	}
+	ccs, err := frontend.Compile(field, newBuilder, NewEth2ScUpdateCircuit(params))
+	if p != nil {
+		p.Stop()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compile Eth2ScUpdateCircuit: %w", err)
+	}
+	steps, err := ProfileEth2ScUpdateSteps(params, field, newBuilder)
+	if err != nil {
+		return nil, err
+	}
+	return &Eth2ScProfile{NbConstraints: ccs.GetNbConstraints(), Steps: steps}, nil
+}
@@ -0,0 +1,24 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileEth2ScUpdateSteps(t *testing.T) {
+	steps, err := ProfileEth2ScUpdateSteps(CircuitParams{}, ecc.BN254.ScalarField(), r1cs.NewBuilder, "signing-root", "merkle-proofs", "period")
+	require.NoError(t, err)
+	require.Len(t, steps, 3)
+	for _, s := range steps {
+		require.Positive(t, s.NbConstraints, s.Step)
+	}
+	// 8 pair hashes for the header and signing roots, 6 + 2*9 for the branches
+	require.Less(t, steps[0].NbConstraints, steps[1].NbConstraints)
+	t.Logf("\n%s", (&Eth2ScProfile{Steps: steps}).String())
+
+	_, err = ProfileEth2ScUpdateSteps(CircuitParams{}, ecc.BN254.ScalarField(), r1cs.NewBuilder, "unknown")
+	require.Error(t, err)
+}
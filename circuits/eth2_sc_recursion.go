@@ -0,0 +1,277 @@
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bw6761"
+	"github.com/consensys/gnark/std/algebra/native/sw_bls12377"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Native recursion path: Eth2ScUpdateCircuit is proven over BLS12-377 (the circuit only uses
+// emulated BLS12-381 arithmetic, so it compiles over any large field), the updates are aggregated
+// over BW6-761 whose scalar field is the BLS12-377 base field, so the inner pairings are native,
+// and the aggregation proof is finally wrapped over BN254 for EVM verification.
+type (
+	bls12377G1El   = sw_bls12377.G1Affine
+	bls12377G2El   = sw_bls12377.G2Affine
+	bls12377GtEl   = sw_bls12377.GT
+	bls12377Field  = sw_bls12377.ScalarField
+	bls12377Proof  = stdgroth16.Proof[bls12377G1El, bls12377G2El]
+	bls12377VK     = stdgroth16.VerifyingKey[bls12377G1El, bls12377G2El, bls12377GtEl]
+	bls12377Public = stdgroth16.Witness[bls12377Field]
+
+	bw6761G1El   = sw_bw6761.G1Affine
+	bw6761G2El   = sw_bw6761.G2Affine
+	bw6761GtEl   = sw_bw6761.GTEl
+	bw6761Field  = sw_bw6761.ScalarField
+	bw6761Proof  = stdgroth16.Proof[bw6761G1El, bw6761G2El]
+	bw6761VK     = stdgroth16.VerifyingKey[bw6761G1El, bw6761G2El, bw6761GtEl]
+	bw6761Public = stdgroth16.Witness[bw6761Field]
+)
+
+// Eth2ScBW6AggregationCircuit is Eth2ScAggregationCircuit over BW6-761, verifying Eth2ScUpdateCircuit
+// proofs made over BLS12-377 with native pairings instead of emulated BN254 ones. It has the same
+// steps and public inputs; its proofs are verified on-chain through Eth2ScBN254WrapCircuit.
+//
+// Inner proofs must be generated with BW6AggregationProverOptions.
+type Eth2ScBW6AggregationCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params  AggregationParams `gnark:"-"`
+	InnerVK bls12377VK        `gnark:"-"`
+
+	// Inner proofs and their public inputs (private inputs)
+	Proofs    []bls12377Proof
+	Witnesses []bls12377Public
+
+	// Committees[i] is the serialized next_sync_committee proved by Proofs[i] (private input)
+	Committees [][SyncCommitteeBytes]uints.U8
+
+	// Public inputs
+	InitialScPubKeysHash [32]uints.U8      `gnark:",public"` // committee trusted by the light client
+	FinalScPubKeysHash   [32]uints.U8      `gnark:",public"` // committee after the last update
+	InitialPeriod        frontend.Variable `gnark:",public"` // period of the first update
+	Domain               [32]uints.U8      `gnark:",public"` // signing domain of all updates
+}
+
+// NewEth2ScBW6AggregationCircuit allocates the aggregation circuit for compilation over BW6-761.
+// innerCcs and innerVK are the constraint system and verifying key of the inner Eth2ScUpdateCircuit
+// compiled over BLS12-377.
+func NewEth2ScBW6AggregationCircuit(params AggregationParams, innerCcs constraint.ConstraintSystem, vk groth16.VerifyingKey) (*Eth2ScBW6AggregationCircuit, error) {
+	if params.NbProofs < 1 {
+		return nil, fmt.Errorf("aggregation needs at least one proof, got %d", params.NbProofs)
+	}
+	if n := params.Inner.SyncCommitteeSize(); n != SyncCommitteeSize {
+		return nil, fmt.Errorf("aggregation chains committees of %d members, the %s preset has %d", SyncCommitteeSize, params.Inner.Preset, n)
+	}
+	if nb := innerCcs.GetNbPublicVariables() - 1; nb != innerNbPublicInputs {
+		return nil, fmt.Errorf("inner circuit has %d public inputs, expected %d", nb, innerNbPublicInputs)
+	}
+	circuitVK, err := stdgroth16.ValueOfVerifyingKeyFixed[bls12377G1El, bls12377G2El, bls12377GtEl](vk)
+	if err != nil {
+		return nil, fmt.Errorf("inner verifying key: %w", err)
+	}
+
+	c := &Eth2ScBW6AggregationCircuit{
+		Params:     params,
+		InnerVK:    circuitVK,
+		Proofs:     make([]bls12377Proof, params.NbProofs),
+		Witnesses:  make([]bls12377Public, params.NbProofs),
+		Committees: make([][SyncCommitteeBytes]uints.U8, params.NbProofs),
+	}
+	for i := 0; i < params.NbProofs; i++ {
+		c.Proofs[i] = stdgroth16.PlaceholderProof[bls12377G1El, bls12377G2El](innerCcs)
+		c.Witnesses[i] = stdgroth16.PlaceholderWitness[bls12377Field](innerCcs)
+	}
+	return c, nil
+}
+
+// NewEth2ScBW6AggregationAssignment builds the witness of the BW6-761 aggregation circuit, see
+// NewEth2ScAggregationAssignment
+func NewEth2ScBW6AggregationAssignment(
+	params AggregationParams,
+	proofs []groth16.Proof,
+	publicWitnesses []witness.Witness,
+	committees []*zrntcommon.SyncCommittee,
+	initialScPubKeysHash [32]byte,
+	finalScPubKeysHash [32]byte,
+	initialPeriod uint64,
+	domain [32]byte,
+) (*Eth2ScBW6AggregationCircuit, error) {
+	c := &Eth2ScBW6AggregationCircuit{
+		Params:               params,
+		InitialScPubKeysHash: [32]uints.U8(uints.NewU8Array(initialScPubKeysHash[:])),
+		FinalScPubKeysHash:   [32]uints.U8(uints.NewU8Array(finalScPubKeysHash[:])),
+		InitialPeriod:        initialPeriod,
+		Domain:               [32]uints.U8(uints.NewU8Array(domain[:])),
+	}
+	var err error
+	c.Proofs, c.Witnesses, c.Committees, err = valueOfInnerProofs[bls12377Field, bls12377G1El, bls12377G2El](params, proofs, publicWitnesses, committees)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// BW6AggregationProverOptions returns the prover options inner Eth2ScUpdateCircuit proofs (over
+// BLS12-377) must be generated with to be verifiable by Eth2ScBW6AggregationCircuit.
+func BW6AggregationProverOptions() backend.ProverOption {
+	return stdgroth16.GetNativeProverOptions(ecc.BW6_761.ScalarField(), ecc.BLS12_377.ScalarField())
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScBW6AggregationCircuit) Define(api frontend.API) error {
+	if len(c.Proofs) != c.Params.NbProofs || len(c.Witnesses) != c.Params.NbProofs || len(c.Committees) != c.Params.NbProofs {
+		return fmt.Errorf("circuit is not allocated for %d proofs", c.Params.NbProofs)
+	}
+
+	verifier, err := stdgroth16.NewVerifier[bls12377Field, bls12377G1El, bls12377G2El, bls12377GtEl](api)
+	if err != nil {
+		return fmt.Errorf("new verifier: %w", err)
+	}
+	fr, err := emulated.NewField[bls12377Field](api)
+	if err != nil {
+		return fmt.Errorf("new emulated field: %w", err)
+	}
+
+	publics := make([][]frontend.Variable, c.Params.NbProofs)
+	for i := 0; i < c.Params.NbProofs; i++ {
+		if err := verifier.AssertProof(c.InnerVK, c.Proofs[i], c.Witnesses[i], stdgroth16.WithCompleteArithmetic()); err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+		publics[i] = innerPublicInputs(api, fr, c.Witnesses[i])
+	}
+	return assertCommitteeChain(api, c.Params.Inner.ScPubKeysHashMode, publics, c.Committees,
+		c.InitialScPubKeysHash, c.FinalScPubKeysHash, c.InitialPeriod, c.Domain)
+}
+
+// Layout of the public inputs of the aggregation circuits, in struct field order:
+// InitialScPubKeysHash, FinalScPubKeysHash, InitialPeriod, Domain.
+const (
+	aggInitialScPubKeysHashOffset = 0
+	aggFinalScPubKeysHashOffset   = 32
+	aggInitialPeriodOffset        = 64
+	aggDomainOffset               = 65
+	aggNbPublicInputs             = 97
+)
+
+// Eth2ScBN254WrapCircuit verifies one Eth2ScBW6AggregationCircuit proof over BN254 and exposes its
+// public inputs unchanged, so that the aggregation is verified by the Solidity verifier of this circuit.
+//
+// The BW6-761 scalar field is larger than the BN254 one, so every public input of the aggregation
+// proof is range checked (bytes, and 64 bits for InitialPeriod) before being used natively.
+//
+// Aggregation proofs must be generated with BN254WrapProverOptions.
+type Eth2ScBN254WrapCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	InnerVK bw6761VK `gnark:"-"`
+
+	// Aggregation proof and its public inputs (private inputs)
+	Proof   bw6761Proof
+	Witness bw6761Public
+
+	// Public inputs, those of the aggregation proof
+	InitialScPubKeysHash [32]uints.U8      `gnark:",public"`
+	FinalScPubKeysHash   [32]uints.U8      `gnark:",public"`
+	InitialPeriod        frontend.Variable `gnark:",public"`
+	Domain               [32]uints.U8      `gnark:",public"`
+}
+
+// NewEth2ScBN254WrapCircuit allocates the wrap circuit for compilation over BN254. aggCcs and vk are
+// the constraint system and verifying key of Eth2ScBW6AggregationCircuit.
+func NewEth2ScBN254WrapCircuit(aggCcs constraint.ConstraintSystem, vk groth16.VerifyingKey) (*Eth2ScBN254WrapCircuit, error) {
+	if nb := aggCcs.GetNbPublicVariables() - 1; nb != aggNbPublicInputs {
+		return nil, fmt.Errorf("aggregation circuit has %d public inputs, expected %d", nb, aggNbPublicInputs)
+	}
+	circuitVK, err := stdgroth16.ValueOfVerifyingKeyFixed[bw6761G1El, bw6761G2El, bw6761GtEl](vk)
+	if err != nil {
+		return nil, fmt.Errorf("aggregation verifying key: %w", err)
+	}
+	return &Eth2ScBN254WrapCircuit{
+		InnerVK: circuitVK,
+		Proof:   stdgroth16.PlaceholderProof[bw6761G1El, bw6761G2El](aggCcs),
+		Witness: stdgroth16.PlaceholderWitness[bw6761Field](aggCcs),
+	}, nil
+}
+
+// NewEth2ScBN254WrapAssignment builds the witness of the wrap circuit from an aggregation proof and
+// its public witness, whose values become the public inputs of the wrap circuit
+func NewEth2ScBN254WrapAssignment(proof groth16.Proof, publicWitness witness.Witness) (*Eth2ScBN254WrapCircuit, error) {
+	public, err := publicWitness.Public()
+	if err != nil {
+		return nil, err
+	}
+	vector, ok := public.Vector().(fr_bw6761.Vector)
+	if !ok || len(vector) != aggNbPublicInputs {
+		return nil, fmt.Errorf("aggregation witness does not hold %d BW6-761 public inputs", aggNbPublicInputs)
+	}
+	values := make([]*big.Int, len(vector))
+	for i := range vector {
+		values[i] = vector[i].BigInt(new(big.Int))
+	}
+
+	c := &Eth2ScBN254WrapCircuit{InitialPeriod: values[aggInitialPeriodOffset]}
+	if c.Proof, err = stdgroth16.ValueOfProof[bw6761G1El, bw6761G2El](proof); err != nil {
+		return nil, fmt.Errorf("aggregation proof: %w", err)
+	}
+	if c.Witness, err = stdgroth16.ValueOfWitness[bw6761Field](publicWitness); err != nil {
+		return nil, fmt.Errorf("aggregation witness: %w", err)
+	}
+	for j := 0; j < 32; j++ {
+		c.InitialScPubKeysHash[j] = uints.NewU8(uint8(values[aggInitialScPubKeysHashOffset+j].Uint64()))
+		c.FinalScPubKeysHash[j] = uints.NewU8(uint8(values[aggFinalScPubKeysHashOffset+j].Uint64()))
+		c.Domain[j] = uints.NewU8(uint8(values[aggDomainOffset+j].Uint64()))
+	}
+	return c, nil
+}
+
+// BN254WrapProverOptions returns the prover options Eth2ScBW6AggregationCircuit proofs must be
+// generated with to be verifiable by Eth2ScBN254WrapCircuit.
+func BN254WrapProverOptions() backend.ProverOption {
+	return stdgroth16.GetNativeProverOptions(ecc.BN254.ScalarField(), ecc.BW6_761.ScalarField())
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScBN254WrapCircuit) Define(api frontend.API) error {
+	verifier, err := stdgroth16.NewVerifier[bw6761Field, bw6761G1El, bw6761G2El, bw6761GtEl](api)
+	if err != nil {
+		return fmt.Errorf("new verifier: %w", err)
+	}
+	fr, err := emulated.NewField[bw6761Field](api)
+	if err != nil {
+		return fmt.Errorf("new emulated field: %w", err)
+	}
+	if len(c.Witness.Public) != aggNbPublicInputs {
+		return fmt.Errorf("aggregation witness has %d public inputs, expected %d", len(c.Witness.Public), aggNbPublicInputs)
+	}
+	if err := verifier.AssertProof(c.InnerVK, c.Proof, c.Witness, stdgroth16.WithCompleteArithmetic()); err != nil {
+		return fmt.Errorf("aggregation proof: %w", err)
+	}
+
+	// narrow converts a public input known to fit in width bits
+	narrow := func(i, width int) frontend.Variable {
+		bits := fr.ToBitsCanonical(&c.Witness.Public[i])
+		for _, bit := range bits[width:] {
+			api.AssertIsEqual(bit, 0)
+		}
+		return api.FromBinary(bits[:width]...)
+	}
+	for j := 0; j < 32; j++ {
+		api.AssertIsEqual(narrow(aggInitialScPubKeysHashOffset+j, 8), c.InitialScPubKeysHash[j].Val)
+		api.AssertIsEqual(narrow(aggFinalScPubKeysHashOffset+j, 8), c.FinalScPubKeysHash[j].Val)
+		api.AssertIsEqual(narrow(aggDomainOffset+j, 8), c.Domain[j].Val)
+	}
+	api.AssertIsEqual(narrow(aggInitialPeriodOffset, 64), c.InitialPeriod)
+	return nil
+}
@@ -0,0 +1,92 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+	gnark_test "github.com/consensys/gnark/test"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+// updateStubCircuit has the public inputs of Eth2ScUpdateCircuit, so that the native recursion is
+// tested with cheap BLS12-377 inner proofs
+type updateStubCircuit struct {
+	Public [innerNbPublicInputs]frontend.Variable `gnark:",public"`
+	Secret frontend.Variable
+}
+
+func (c *updateStubCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.Secret, c.Secret), c.Public[innerPeriodOffset])
+	return nil
+}
+
+// nativeVerifyCircuit is step 1 of Eth2ScBW6AggregationCircuit, the committee chain being tested
+// with the BN254 aggregation
+type nativeVerifyCircuit struct {
+	InnerVK bls12377VK `gnark:"-"`
+
+	Proof   bls12377Proof
+	Witness bls12377Public
+	Period  frontend.Variable `gnark:",public"`
+}
+
+func (c *nativeVerifyCircuit) Define(api frontend.API) error {
+	verifier, err := stdgroth16.NewVerifier[bls12377Field, bls12377G1El, bls12377G2El, bls12377GtEl](api)
+	if err != nil {
+		return err
+	}
+	fr, err := emulated.NewField[bls12377Field](api)
+	if err != nil {
+		return err
+	}
+	if err := verifier.AssertProof(c.InnerVK, c.Proof, c.Witness, stdgroth16.WithCompleteArithmetic()); err != nil {
+		return err
+	}
+	api.AssertIsEqual(innerPublicInputs(api, fr, c.Witness)[innerPeriodOffset], c.Period)
+	return nil
+}
+
+func TestEth2ScBW6AggregationCircuit_NativeVerify(t *testing.T) {
+	const period = 1105 * 1105
+	ccs, err := frontend.Compile(ecc.BLS12_377.ScalarField(), r1cs.NewBuilder, &updateStubCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+	inner := &updateStubCircuit{Secret: 1105}
+	for i := range inner.Public {
+		inner.Public[i] = i
+	}
+	inner.Public[innerPeriodOffset] = period
+	full, err := frontend.NewWitness(inner, ecc.BLS12_377.ScalarField())
+	require.NoError(t, err)
+	proof, err := groth16.Prove(ccs, pk, full, BW6AggregationProverOptions())
+	require.NoError(t, err)
+	public, err := full.Public()
+	require.NoError(t, err)
+
+	// the aggregation circuit allocates and assigns BLS12-377 proofs
+	params := AggregationParams{NbProofs: 1}
+	agg, err := NewEth2ScBW6AggregationCircuit(params, ccs, vk)
+	require.NoError(t, err)
+	circuitVK := agg.InnerVK
+	committee := &zrntcommon.SyncCommittee{Pubkeys: make([]zrntcommon.BLSPubkey, SyncCommitteeSize)}
+	assignment, err := NewEth2ScBW6AggregationAssignment(params, []groth16.Proof{proof}, []witness.Witness{public},
+		[]*zrntcommon.SyncCommittee{committee}, [32]byte{}, [32]byte{}, period, [32]byte{})
+	require.NoError(t, err)
+	_, err = NewEth2ScBW6AggregationCircuit(AggregationParams{NbProofs: 0}, ccs, vk)
+	require.Error(t, err)
+
+	circuit := &nativeVerifyCircuit{InnerVK: circuitVK, Proof: agg.Proofs[0], Witness: agg.Witnesses[0]}
+	verifyAssignment := &nativeVerifyCircuit{Proof: assignment.Proofs[0], Witness: assignment.Witnesses[0], Period: period}
+	require.NoError(t, gnark_test.IsSolved(circuit, verifyAssignment, ecc.BW6_761.ScalarField()))
+
+	verifyAssignment.Period = period + 1
+	require.Error(t, gnark_test.IsSolved(circuit, verifyAssignment, ecc.BW6_761.ScalarField()))
+}
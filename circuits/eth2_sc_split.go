@@ -0,0 +1,227 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// Eth2ScSignatureCircuit is the BLS-verify half of Eth2ScUpdateCircuit: it proves that the sync
+// committee committed to by ScPubKeysHash signed the attested header whose root is HeaderRoot.
+//
+// It is enough to follow the chain (finality, execution block hashes) within a period without
+// rotating the committee; Eth2ScRotationCircuit proves the committee rotation of the same header,
+// the two proofs being linked by their common HeaderRoot public input.
+//
+// This circuit:
+// 1. Verifies the sync committee pubkeys hash and checks the pubkeys are in G1
+// 2. Aggregates the public keys of the participants (ScBits)
+// 3. Computes the header root and requires it to be HeaderRoot
+// 4. Verifies the BLS signature of the aggregated public key over the signing root of HeaderRoot
+// 5. Binds the public AttestedSlot and Period to the header slot
+type Eth2ScSignatureCircuit struct {
+	// Compile-time parameters (not part of the witness), NextScGIndex is not used
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Sync committee data (private inputs)
+	ScPubKeys     []sw_bls12381.G1Affine // Params.SyncCommitteeSize() long
+	ScBits        []frontend.Variable
+	AggregatedSig sw_bls12381.G2Affine
+
+	// Public inputs
+	ScPubKeysHash [32]uints.U8      `gnark:",public"` // SHA2 hash to sync committee pubkeys
+	Period        frontend.Variable `gnark:",public"` // sync committee period of the attested header
+	Domain        [32]uints.U8      `gnark:",public"` // signing domain
+	AttestedSlot  frontend.Variable `gnark:",public"` // slot of the attested header
+	HeaderRoot    [32]uints.U8      `gnark:",public"` // root of the attested header, shared with Eth2ScRotationCircuit
+}
+
+// Eth2ScRotationCircuit is the committee-rotation half of Eth2ScUpdateCircuit: it proves that
+// NextScRoot is the next_sync_committee of the state of the header whose root is HeaderRoot.
+//
+// It says nothing about who signed the header, a consumer must only accept it together with an
+// Eth2ScSignatureCircuit proof of the same HeaderRoot.
+//
+// This circuit:
+// 1. Computes the header root and requires it to be HeaderRoot
+// 2. Verifies next_sync_committee is included in StateRoot via SSZ Merkle proof
+// 3. Binds the public Period to the header slot
+type Eth2ScRotationCircuit struct {
+	// Compile-time parameters (not part of the witness), only NextScGIndex is used
+	Params CircuitParams `gnark:"-"`
+
+	// BeaconBlockHeader fields (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Merkle branch of next_sync_committee in StateRoot, Params.NextSyncCommitteeGIndex().Depth() long
+	NextScBranch [][32]uints.U8
+
+	// Public inputs
+	NextScRoot [32]uints.U8      `gnark:",public"` // SSZ root of next_sync_committee
+	Period     frontend.Variable `gnark:",public"` // sync committee period of the attested header
+	HeaderRoot [32]uints.U8      `gnark:",public"` // root of the attested header, shared with Eth2ScSignatureCircuit
+}
+
+// NewEth2ScSignatureCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScSignatureCircuit(params CircuitParams) *Eth2ScSignatureCircuit {
+	return &Eth2ScSignatureCircuit{
+		Params:    params,
+		ScPubKeys: make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		ScBits:    make([]frontend.Variable, params.SyncCommitteeSize()),
+	}
+}
+
+// NewEth2ScRotationCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScRotationCircuit(params CircuitParams) *Eth2ScRotationCircuit {
+	return &Eth2ScRotationCircuit{
+		Params:       params,
+		NextScBranch: make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+	}
+}
+
+// NewEth2ScRotationAssignment builds the witness proving nextScRoot, with its branch, in the state of header
+func NewEth2ScRotationAssignment(params CircuitParams, header *zrntcommon.BeaconBlockHeader, nextScRoot zrntcommon.Root, branch []zrntcommon.Root) (*Eth2ScRotationCircuit, error) {
+	w := NewEth2ScRotationCircuit(params)
+	if len(branch) != len(w.NextScBranch) {
+		return nil, fmt.Errorf("branch length %d does not match depth %d", len(branch), len(w.NextScBranch))
+	}
+	if !types.VerifySSZBranch(header.StateRoot, nextScRoot, branch, params.NextSyncCommitteeGIndex()) {
+		return nil, fmt.Errorf("next_sync_committee %v is not in state %v", nextScRoot, header.StateRoot)
+	}
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	w.Slot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	for i := range branch {
+		w.NextScBranch[i] = [32]uints.U8(uints.NewU8Array(branch[i][:]))
+	}
+	w.NextScRoot = [32]uints.U8(uints.NewU8Array(nextScRoot[:]))
+	w.Period = params.Preset.Period(uint64(header.Slot))
+	w.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	return w, nil
+}
+
+// SignatureAssignment extracts the Eth2ScSignatureCircuit witness from a full Eth2ScUpdateCircuit
+// witness of the given attested header
+func (c *Eth2ScUpdateCircuit) SignatureAssignment(header *zrntcommon.BeaconBlockHeader) *Eth2ScSignatureCircuit {
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	return &Eth2ScSignatureCircuit{
+		Params:        c.Params,
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+		ScPubKeys:     c.ScPubKeys,
+		ScBits:        c.ScBits,
+		AggregatedSig: c.AggregatedSig,
+		ScPubKeysHash: c.ScPubKeysHash,
+		Period:        c.Period,
+		Domain:        c.Domain,
+		AttestedSlot:  c.AttestedSlot,
+		HeaderRoot:    [32]uints.U8(uints.NewU8Array(headerRoot[:])),
+	}
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScSignatureCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{
+		Params:        c.Params,
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+		ScPubKeys:     c.ScPubKeys,
+		ScBits:        c.ScBits,
+		AggregatedSig: c.AggregatedSig,
+		ScPubKeysHash: c.ScPubKeysHash,
+		Period:        c.Period,
+		Domain:        c.Domain,
+		AttestedSlot:  c.AttestedSlot,
+	}
+
+	// Step 1: the committee
+	if err := sc.verifyScPubKeysHash(api); err != nil {
+		return fmt.Errorf("sync committee pubkeys hash verification failed: %w", err)
+	}
+	infinity, err := sc.scPubKeysInfinity(api)
+	if err != nil {
+		return err
+	}
+	if err := sc.assertScPubKeysOnG1(api, infinity); err != nil {
+		return fmt.Errorf("sync committee pubkeys check failed: %w", err)
+	}
+
+	// Step 2: the participants
+	aggregatedPubKey, err := sc.aggregatePubKeys(api, infinity)
+	if err != nil {
+		return fmt.Errorf("public key aggregation failed: %w", err)
+	}
+
+	// Step 3: the attested header
+	blockRoot := sc.computeBlockRoot(api)
+	gadgets.AssertChunksEqual(api, blockRoot, c.HeaderRoot)
+
+	// Step 4: the signature
+	signingRoot := sc.computeSigningRoot(api, blockRoot)
+	signingRootG2, err := hash2curve.HashToG2(api, signingRoot[:], []byte(hash2curve.EthSignatureDST))
+	if err != nil {
+		return fmt.Errorf("hash-to-curve failed: %w", err)
+	}
+	if err := sc.verifyBLSSignature(api, aggregatedPubKey, signingRootG2); err != nil {
+		return fmt.Errorf("BLS signature verification failed: %w", err)
+	}
+
+	// Step 5: the slot and period
+	sc.verifyPeriod(api)
+	return nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScRotationCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{
+		Params:        c.Params,
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+		NextScBranch:  c.NextScBranch,
+		NextScRoot:    c.NextScRoot,
+		Period:        c.Period,
+		AttestedSlot:  c.Slot,
+	}
+
+	// Step 1: the attested header
+	gadgets.AssertChunksEqual(api, sc.computeBlockRoot(api), c.HeaderRoot)
+
+	// Step 2: next_sync_committee in its state
+	if err := sc.verifyNextSyncCommitteeMerkleProof(api); err != nil {
+		return fmt.Errorf("next_sync_committee Merkle proof verification failed: %w", err)
+	}
+
+	// Step 3: the period
+	sc.verifyPeriod(api)
+	return nil
+}
@@ -0,0 +1,47 @@
+package circuit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ScRotationCircuit(t *testing.T) {
+	rootDir := mustGetRootDir()
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err)
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	params := CircuitParams{}
+	header := &update.Data.AttestedHeader.Beacon
+	nextScRoot := update.Data.NextSyncCommittee.HashTreeRoot(configs.Mainnet, tree.GetHashFn())
+	circuit := NewEth2ScRotationCircuit(params)
+	witness, err := NewEth2ScRotationAssignment(params, header, nextScRoot, update.Data.NextSyncCommitteeBranch)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the signature half of the same update commits to the same header root
+	full := NewEth2ScUpdateCircuit(params)
+	full.Slot = uint64(header.Slot)
+	full.ProposerIndex = uint64(header.ProposerIndex)
+	signature := full.SignatureAssignment(header)
+	require.Equal(t, witness.HeaderRoot, signature.HeaderRoot)
+
+	witness.HeaderRoot = witness.StateRoot
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness.HeaderRoot = signature.HeaderRoot
+	witness.Period = uint64(header.Slot)>>SlotsPerPeriodLog2 + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	_, err = NewEth2ScRotationAssignment(params, header, header.StateRoot, update.Data.NextSyncCommitteeBranch)
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package circuit
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+)
+
+// Eth2ScToyCircuit is a cheap stand-in for Eth2ScUpdateCircuit used by simulations.
+//
+// It only keeps the Slot -> Period binding and carries NextScRoot as a public input, so it
+// compiles, sets up and proves in well under a second and a whole sequence of recorded updates
+// can be pushed through the proving pipeline. It proves nothing about the sync committee;
+// the signature and the Merkle branch are checked natively by the simulation instead.
+type Eth2ScToyCircuit struct {
+	// Preset fixes the slots per period, as in CircuitParams
+	Preset types.Preset `gnark:"-"`
+
+	Slot frontend.Variable
+
+	NextScRoot [32]uints.U8      `gnark:",public"`
+	Period     frontend.Variable `gnark:",public"`
+}
+
+// ToyAssignment extracts the toy circuit witness from a full Eth2ScUpdateCircuit witness
+func (c *Eth2ScUpdateCircuit) ToyAssignment() *Eth2ScToyCircuit {
+	return &Eth2ScToyCircuit{
+		Preset:     c.Params.Preset,
+		Slot:       c.Slot,
+		NextScRoot: c.NextScRoot,
+		Period:     c.Period,
+	}
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScToyCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{Params: CircuitParams{Preset: c.Preset}, Slot: c.Slot, Period: c.Period, AttestedSlot: c.Slot}
+	sc.verifyPeriod(api)
+	return nil
+}
@@ -0,0 +1,106 @@
+package circuit
+
+import (
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// TransitionParams holds the compile-time parameters of Eth2ScTransitionCircuit
+type TransitionParams struct {
+	// From is the mode of the commitment held by the deployed light client
+	From types.ScPubKeysHashMode
+	// To is the mode of the commitment the light client migrates to
+	To types.ScPubKeysHashMode
+}
+
+// Eth2ScTransitionCircuit proves that two ScPubKeysHash commitments of different modes are
+// commitments to the same sync committee, so that a light client can move from the commitment
+// it holds (e.g. truncated) to a stronger one (e.g. full) without a new trusted setup of its chain.
+//
+// This circuit:
+// 1. Recomputes hash_tree_root(SyncCommittee) of Committee and requires it to be ScRoot
+// 2. Hashes the pubkeys of Committee with Params.From and requires it to be OldScPubKeysHash
+// 3. Hashes the pubkeys of Committee with Params.To and requires it to be NewScPubKeysHash
+//
+// ScRoot is the NextScRoot proven by the Eth2ScUpdateCircuit proof of the previous period.
+type Eth2ScTransitionCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params TransitionParams `gnark:"-"`
+
+	// Serialized sync committee: 512 compressed pubkeys || aggregate pubkey (private input)
+	Committee []uints.U8
+
+	// Public inputs
+	ScRoot           [32]uints.U8 `gnark:",public"` // hash_tree_root of the committee
+	OldScPubKeysHash [32]uints.U8 `gnark:",public"` // commitment with Params.From
+	NewScPubKeysHash [32]uints.U8 `gnark:",public"` // commitment with Params.To
+}
+
+// NewEth2ScTransitionCircuit allocates a circuit (or witness) for the given params
+func NewEth2ScTransitionCircuit(params TransitionParams) *Eth2ScTransitionCircuit {
+	return &Eth2ScTransitionCircuit{
+		Params:    params,
+		Committee: make([]uints.U8, SyncCommitteeBytes),
+	}
+}
+
+// NewEth2ScTransitionAssignment assigns the circuit for the given sync committee, of any preset's size
+func NewEth2ScTransitionAssignment(params TransitionParams, sc *zrntcommon.SyncCommittee) (*Eth2ScTransitionCircuit, error) {
+	serialized := make([]byte, 0, (len(sc.Pubkeys)+1)*48)
+	for _, pk := range sc.Pubkeys {
+		serialized = append(serialized, pk[:]...)
+	}
+	serialized = append(serialized, sc.AggregatePubkey[:]...)
+	pubkeys := make([]bls12381.G1Affine, len(sc.Pubkeys))
+	for i := range sc.Pubkeys {
+		if _, err := pubkeys[i].SetBytes(sc.Pubkeys[i][:]); err != nil {
+			return nil, fmt.Errorf("failed to parse pubkey %d: %w", i, err)
+		}
+	}
+	root := types.SyncCommitteeRoot(sc)
+	oldHash := types.ComputeScPubKeysHashWithMode(pubkeys, params.From)
+	newHash := types.ComputeScPubKeysHashWithMode(pubkeys, params.To)
+
+	return &Eth2ScTransitionCircuit{
+		Params:           params,
+		Committee:        uints.NewU8Array(serialized[:]),
+		ScRoot:           [32]uints.U8(uints.NewU8Array(root[:])),
+		OldScPubKeysHash: [32]uints.U8(uints.NewU8Array(oldHash[:])),
+		NewScPubKeysHash: [32]uints.U8(uints.NewU8Array(newHash[:])),
+	}, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ScTransitionCircuit) Define(api frontend.API) error {
+	if c.Params.From == c.Params.To {
+		return fmt.Errorf("transition from and to the same mode %v", c.Params.From)
+	}
+
+	// Step 1: the committee is the one committed to by ScRoot
+	root, err := syncCommitteeRoot(api, c.Committee)
+	if err != nil {
+		return err
+	}
+
+	// Step 2, 3: both commitments are computed from the same pubkeys
+	oldHash, err := committeePubKeysHash(api, c.Params.From, c.Committee)
+	if err != nil {
+		return err
+	}
+	newHash, err := committeePubKeysHash(api, c.Params.To, c.Committee)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(root[i].Val, c.ScRoot[i].Val)
+		api.AssertIsEqual(oldHash[i].Val, c.OldScPubKeysHash[i].Val)
+		api.AssertIsEqual(newHash[i].Val, c.NewScPubKeysHash[i].Val)
+	}
+	return nil
+}
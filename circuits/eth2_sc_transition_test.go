@@ -0,0 +1,73 @@
+package circuit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ScTransitionCircuit(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	// a minimal preset committee (32 members) built from real pubkeys keeps the test engine small
+	spec := configs.Minimal
+	committee := zrntcommon.SyncCommittee{
+		Pubkeys:         update.Data.NextSyncCommittee.Pubkeys[:spec.SYNC_COMMITTEE_SIZE],
+		AggregatePubkey: update.Data.NextSyncCommittee.AggregatePubkey,
+	}
+	var serialized []byte
+	for _, pk := range committee.Pubkeys {
+		serialized = append(serialized, pk[:]...)
+	}
+	serialized = append(serialized, committee.AggregatePubkey[:]...)
+
+	root := committee.HashTreeRoot(spec, tree.GetHashFn())
+	pubkeys := make([]bls12381.G1Affine, len(committee.Pubkeys))
+	for i := range pubkeys {
+		_, err := pubkeys[i].SetBytes(committee.Pubkeys[i][:])
+		require.NoError(t, err)
+	}
+	oldHash := types.ComputeScPubKeysHashWithMode(pubkeys, types.ScPubKeysHashTruncated)
+	newHash := types.ComputeScPubKeysHashWithMode(pubkeys, types.ScPubKeysHashFull)
+
+	params := TransitionParams{From: types.ScPubKeysHashTruncated, To: types.ScPubKeysHashFull}
+	circuit := &Eth2ScTransitionCircuit{Params: params, Committee: make([]uints.U8, len(serialized))}
+	assignment := &Eth2ScTransitionCircuit{
+		Committee:        uints.NewU8Array(serialized),
+		ScRoot:           [32]uints.U8(uints.NewU8Array(root[:])),
+		OldScPubKeysHash: [32]uints.U8(uints.NewU8Array(oldHash[:])),
+		NewScPubKeysHash: [32]uints.U8(uints.NewU8Array(newHash[:])),
+	}
+	require.NoError(t, gnark_test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+
+	// the relayer assigns committees of the minimal preset too
+	assigned, err := NewEth2ScTransitionAssignment(params, &committee)
+	require.NoError(t, err)
+	require.Equal(t, assignment.ScRoot, assigned.ScRoot)
+	require.NoError(t, gnark_test.IsSolved(circuit, assigned, ecc.BN254.ScalarField()))
+
+	// a flipped byte outside of the truncated limbs keeps the old commitment but not the new one
+	serialized[0] ^= 1
+	assignment.Committee = uints.NewU8Array(serialized)
+	require.Error(t, gnark_test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+
+	// the commitments must not be swapped
+	serialized[0] ^= 1
+	assignment.Committee = uints.NewU8Array(serialized)
+	assignment.OldScPubKeysHash, assignment.NewScPubKeysHash = assignment.NewScPubKeysHash, assignment.OldScPubKeysHash
+	require.Error(t, gnark_test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+}
@@ -2,18 +2,21 @@ package circuit
 
 import (
 	"fmt"
-	"math/big"
 
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/std/algebra/emulated/fields_bls12381"
 	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
 	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
 	"github.com/consensys/gnark/std/hash/sha2"
 	"github.com/consensys/gnark/std/math/emulated"
 	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
+	"github.com/kysee/zk-chains/types"
 )
 
-// DOMAIN is the hardcoded domain for Ethereum mainnet Fulu fork
+// DOMAIN is the default sync committee signing domain (Sepolia, Fulu fork), assigned to the public Domain input
+// by the witness builders when CircuitParams.Domain is not set.
 // Domain = 0x07000000f52c15272cff99835cd05aa522af469210b5b2c8807e372b6b9ca539
 // Computed as: domain_type || fork_data_root[:28]
 // where fork_data_root = hash_tree_root(ForkData(fork_version, genesis_validators_root))
@@ -35,15 +38,28 @@ var DOMAIN = [32]uint8{
 // 1. Computes blockRoot from BeaconBlockHeader fields
 // 2. Computes signingRoot = hash(blockRoot, domain)
 // 3. Computes signingRootG2 = hash-to-curve(signingRoot) IN-CIRCUIT
-// 4. Verifies sync committee pubkey hash(sha2)
+// 4. Verifies sync committee pubkey hash(sha2) and checks the pubkeys are in G1
 // 5. Aggregates public keys based on sync committee bits
 // 6. Verifies BLS signature: e(aggregatedPubKey, H(signingRoot)) == e(G1, signature)
 // 7. Verifies next_sync_committee is included in StateRoot via SSZ Merkle proof
 //
+// 8. Exposes the attested header slot and its period as public inputs
+// 9. Verifies the execution block_hash and block_number are included in BodyRoot and exposes them as public inputs
+// 10. With Params.PublicStateRoot, exposes the StateRoot of the attested header as a public input
+//
+// The execution block hash and number let EVM consumers use the verifier as an execution layer block hash oracle.
+// The signing domain is a public input, so the same compiled circuit and proving key
+// serve every network and fork; the verifier contract pins the expected domain.
+//
 // NOTE: For complete verification of next_sync_committee, the following checks must be performed OUTSIDE the circuit:
-// - Slot(Period) validation
+// - Period validation (e.g. the contract requires the public Period to be the expected next period)
+// - Replay protection (the contract requires the public AttestedSlot to increase with every accepted update)
+// - Domain validation (the contract requires the public Domain to be the domain of its network and fork)
 // - Verification that the number of validators who signed the AggregatedSig exceeds 2/3 of the total
 type Eth2ScUpdateCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params CircuitParams `gnark:"-"`
+
 	// BeaconBlockHeader fields (private inputs)
 	Slot          frontend.Variable // uint64
 	ProposerIndex frontend.Variable // uint64
@@ -52,45 +68,98 @@ type Eth2ScUpdateCircuit struct {
 	BodyRoot      [32]uints.U8      // bytes32
 
 	// Sync committee data (private inputs)
-	ScPubKeys     [512]sw_bls12381.G1Affine // 512 sync committee public keys
-	ScBits        [512]frontend.Variable    // Bit array indicating which validators signed (0 or 1)
-	AggregatedSig sw_bls12381.G2Affine      // Aggregated signature
+	ScPubKeys     []sw_bls12381.G1Affine // sync committee public keys, Params.SyncCommitteeSize() long
+	ScBits        []frontend.Variable    // Bit array indicating which validators signed (0 or 1)
+	AggregatedSig sw_bls12381.G2Affine   // Aggregated signature
 
 	// Next sync committee Merkle proof data
-	NextScBranch [6][32]uints.U8 // Merkle branch proving inclusion in StateRoot
+	NextScBranch [][32]uints.U8 // Merkle branch proving inclusion in StateRoot, Params.NextSyncCommitteeGIndex().Depth() long
+
+	// Execution payload header Merkle proof data: the header branch of each field followed by execution_branch
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8 // Merkle branch proving block_hash inclusion in BodyRoot
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8 // Merkle branch proving block_number inclusion in BodyRoot
 
 	// Public inputs - verified by the circuit
-	ScPubKeysHash [32]uints.U8 `gnark:",public"` // SHA2 hash to sync committee pubkeys
-	NextScRoot    [32]uints.U8 `gnark:",public"` // SSZ root of next_sync_committee
+	ScPubKeysHash   [32]uints.U8      `gnark:",public"` // SHA2 hash to sync committee pubkeys
+	NextScRoot      [32]uints.U8      `gnark:",public"` // SSZ root of next_sync_committee
+	Period          frontend.Variable `gnark:",public"` // sync committee period of the attested header (Slot / 8192 on mainnet)
+	Domain          [32]uints.U8      `gnark:",public"` // signing domain: DOMAIN_SYNC_COMMITTEE || fork_data_root[:28]
+	ExecBlockHash   [32]uints.U8      `gnark:",public"` // execution_payload.block_hash of the attested header
+	ExecBlockNumber frontend.Variable `gnark:",public"` // execution_payload.block_number of the attested header
+	AttestedSlot    frontend.Variable `gnark:",public"` // slot of the attested header, equal to Slot
+
+	// StateRoot of the attested header, 32 bytes with Params.PublicStateRoot and empty otherwise, so
+	// the default layout is unchanged
+	AttestedStateRoot []uints.U8 `gnark:",public"`
 }
 
+// NewEth2ScUpdateCircuit allocates a circuit (or witness) whose variable-sized fields
+// match the given compile-time params.
+func NewEth2ScUpdateCircuit(params CircuitParams) *Eth2ScUpdateCircuit {
+	c := &Eth2ScUpdateCircuit{
+		Params:       params,
+		ScPubKeys:    make([]sw_bls12381.G1Affine, params.SyncCommitteeSize()),
+		ScBits:       make([]frontend.Variable, params.SyncCommitteeSize()),
+		NextScBranch: make([][32]uints.U8, params.NextSyncCommitteeGIndex().Depth()),
+	}
+	if params.PublicStateRoot {
+		c.AttestedStateRoot = make([]uints.U8, 32)
+	}
+	return c
+}
+
+// ExecBranchDepth is the depth of the execution payload header fields in the BeaconBlockBody (Deneb ..),
+// see types.ExecutionBlockHashBodyGIndex
+const ExecBranchDepth = 9
+
+// SlotsPerPeriodLog2 is log2(SLOTS_PER_EPOCH * EPOCHS_PER_SYNC_COMMITTEE_PERIOD) = log2(32 * 256) of mainnet,
+// see CircuitParams.SlotsPerPeriodLog2 for the other presets
+const SlotsPerPeriodLog2 = 13
+
 // Define implements the circuit constraints
 func (c *Eth2ScUpdateCircuit) Define(api frontend.API) error {
+	if n := c.Params.SyncCommitteeSize(); len(c.ScPubKeys) != n || len(c.ScBits) != n {
+		return fmt.Errorf("%d pubkeys and %d bits for a sync committee of %d", len(c.ScPubKeys), len(c.ScBits), n)
+	}
+	if n := c.Params.publicStateRootLen(); len(c.AttestedStateRoot) != n {
+		return fmt.Errorf("%d public state root bytes, expected %d", len(c.AttestedStateRoot), n)
+	}
+
 	// Step 1: Verify sync committee pubkeys hash using SHA2
 	err := c.verifyScPubKeysHash(api)
 	if err != nil {
 		return fmt.Errorf("sync committee pubkeys hash verification failed: %w", err)
 	}
 
-	// Step 2: Aggregate public keys based on sync committee bits
-	aggregatedPubKey, err := c.aggregatePubKeys(api)
+	// Step 2: Check the witnessed pubkeys are valid G1 points (or the point at infinity)
+	infinity, err := c.scPubKeysInfinity(api)
+	if err != nil {
+		return err
+	}
+	err = c.assertScPubKeysOnG1(api, infinity)
+	if err != nil {
+		return fmt.Errorf("sync committee pubkeys check failed: %w", err)
+	}
+
+	// Step 3: Aggregate public keys based on sync committee bits
+	aggregatedPubKey, err := c.aggregatePubKeys(api, infinity)
 	if err != nil {
 		return fmt.Errorf("public key aggregation failed: %w", err)
 	}
 
-	// Step 3: Compute blockRoot from BeaconBlockHeader
+	// Step 4: Compute blockRoot from BeaconBlockHeader
 	blockRoot := c.computeBlockRoot(api)
 
-	// Step 4: Compute signingRoot = hash(blockRoot, domain)
+	// Step 5: Compute signingRoot = hash(blockRoot, domain)
 	signingRoot := c.computeSigningRoot(api, blockRoot)
 
-	// Step 5: Compute signingRootG2 = hash-to-curve(signingRoot) IN-CIRCUIT
-	signingRootG2, err := c.hashToG2InCircuit(api, signingRoot)
+	// Step 6: Compute signingRootG2 = hash-to-curve(signingRoot) IN-CIRCUIT
+	signingRootG2, err := hash2curve.HashToG2(api, signingRoot[:], []byte(hash2curve.EthSignatureDST))
 	if err != nil {
 		return fmt.Errorf("hash-to-curve failed: %w", err)
 	}
 
-	// Step 6: Verify BLS signature using the aggregated public key
+	// Step 7: Verify BLS signature using the aggregated public key
 	// If the BeaconBlockHeader fields are incorrect, the blockRoot will be wrong,
 	// leading to wrong signingRoot and signingRootG2, which will fail signature verification
 	err = c.verifyBLSSignature(api, aggregatedPubKey, signingRootG2)
@@ -98,15 +167,47 @@ func (c *Eth2ScUpdateCircuit) Define(api frontend.API) error {
 		return fmt.Errorf("BLS signature verification failed: %w", err)
 	}
 
-	// Step 7: Verify next_sync_committee is included in StateRoot via SSZ Merkle proof
+	// Step 8: Verify next_sync_committee is included in StateRoot via SSZ Merkle proof
 	err = c.verifyNextSyncCommitteeMerkleProof(api)
 	if err != nil {
 		return fmt.Errorf("next_sync_committee Merkle proof verification failed: %w", err)
 	}
 
+	// Step 9: Bind the public AttestedSlot and Period to the attested header slot
+	c.verifyPeriod(api)
+
+	// Step 10: Verify the public ExecBlockHash and ExecBlockNumber are included in BodyRoot via SSZ Merkle proofs
+	err = c.verifyExecBlockHashMerkleProof(api)
+	if err != nil {
+		return fmt.Errorf("execution block hash Merkle proof verification failed: %w", err)
+	}
+	err = c.verifyExecBlockNumberMerkleProof(api)
+	if err != nil {
+		return fmt.Errorf("execution block number Merkle proof verification failed: %w", err)
+	}
+
+	// Step 11: Expose StateRoot, if compiled with PublicStateRoot
+	for i := range c.AttestedStateRoot {
+		api.AssertIsEqual(c.AttestedStateRoot[i].Val, c.StateRoot[i].Val)
+	}
+
 	return nil
 }
 
+// verifyPeriod constrains the public AttestedSlot to be the header Slot and the public Period
+// to be its sync committee period.
+//
+// Slot is range checked to 64 bits by its binary decomposition, and the period is
+// recomposed from the bits above Params.SlotsPerPeriodLog2(), i.e. Period = floor(Slot / 8192) on mainnet.
+// Since the same Slot is hashed into the block root covered by the signature,
+// the verifier contract can rely on AttestedSlot and Period to reject stale or replayed updates.
+func (c *Eth2ScUpdateCircuit) verifyPeriod(api frontend.API) {
+	api.AssertIsEqual(c.AttestedSlot, c.Slot)
+	slotBits := api.ToBinary(c.Slot, 64)
+	period := api.FromBinary(slotBits[c.Params.SlotsPerPeriodLog2():]...)
+	api.AssertIsEqual(period, c.Period)
+}
+
 // computeBlockRoot computes the SSZ hash_tree_root of the beacon block header
 // This reuses the same logic as BlockRootHasher
 func (c *Eth2ScUpdateCircuit) computeBlockRoot(api frontend.API) [32]uints.U8 {
@@ -140,355 +241,192 @@ func (c *Eth2ScUpdateCircuit) computeBlockRoot(api frontend.API) [32]uints.U8 {
 //	object_root: blockRoot (32 bytes)
 //	domain: domain (32 bytes)
 //
-// Note: domain is hardcoded as a constant for Ethereum mainnet Fulu fork
+// Note: domain is the public Domain input, checked against the expected value by the verifier contract
 func (c *Eth2ScUpdateCircuit) computeSigningRoot(api frontend.API, blockRoot [32]uints.U8) [32]uints.U8 {
-	// Convert DOMAIN bytes to []uints.U8
-	domain := uints.NewU8Array(DOMAIN[:])
-
 	// Compute signingRoot = hash(blockRoot || domain)
-	signingRoot := c.hashPair(api, blockRoot, [32]uints.U8(domain))
+	signingRoot := c.hashPair(api, blockRoot, c.Domain)
 	return signingRoot
 }
 
-// hashToG2InCircuit performs RFC 9380 hash_to_G2 for BLS12-381 G2
-// using expand_message_xmd(SHA-256) and ETH2 DST.
-func (c *Eth2ScUpdateCircuit) hashToG2InCircuit(
-	api frontend.API,
-	signingRoot [32]uints.U8,
-) (*sw_bls12381.G2Affine, error) {
-
-	// 1) G2 helper
-	g2, err := sw_bls12381.NewG2(api)
+// verifyScPubKeysHash verifies that ScPubKeysHash is the SHA2 commitment to the sync committee pubkeys,
+// in the mode of Params.ScPubKeysHashMode (see types.ComputeScPubKeysHashWithMode)
+func (c *Eth2ScUpdateCircuit) verifyScPubKeysHash(api frontend.API) error {
+	// Create SHA2 hasher
+	hasher, err := sha2.New(api)
 	if err != nil {
-		return nil, fmt.Errorf("new G2: %w", err)
+		return fmt.Errorf("failed to create SHA2 hasher: %w", err)
 	}
 
-	// 2) hash_to_field(msg, 2) in Fp2
-	u, err := c.hashToFieldBLS12381Fp2(api, signingRoot)
-	if err != nil {
-		return nil, fmt.Errorf("hashToFieldFp2: %w", err)
+	switch c.Params.ScPubKeysHashMode {
+	case types.ScPubKeysHashTruncated:
+		// BLS public key is 48 bytes long, so we hash the last two limbs of x coordinate.
+		// Limbs[0] is the least significant limb of x coordinate.
+		for i := range c.ScPubKeys {
+			xbytes := c.serializeLimbTo8Bytes(api, c.ScPubKeys[i].X.Limbs[1])
+			hasher.Write(xbytes)
+			xbytes = c.serializeLimbTo8Bytes(api, c.ScPubKeys[i].X.Limbs[0])
+			hasher.Write(xbytes)
+		}
+	case types.ScPubKeysHashFull:
+		// Hash the full 48 bytes compressed pubkey, including the flag bits
+		fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+		if err != nil {
+			return fmt.Errorf("new emulated field: %w", err)
+		}
+		for i := range c.ScPubKeys {
+			hasher.Write(c.serializeG1Compressed(api, fp, &c.ScPubKeys[i]))
+		}
+	default:
+		return fmt.Errorf("unsupported sync committee pubkeys hash mode: %v", c.Params.ScPubKeysHashMode)
 	}
 
-	// 3) map_to_curve2 for each u[i]
-	//    주의: MapToG2 인자는 *fields_bls12381.E2 (포인터)
-	Q0, err := g2.MapToG2(&u[0])
-	if err != nil {
-		return nil, fmt.Errorf("MapToG2(u[0]): %w", err)
-	}
-	Q1, err := g2.MapToG2(&u[1])
-	if err != nil {
-		return nil, fmt.Errorf("MapToG2(u[1]): %w", err)
-	}
+	// Compute hash
+	hashResult := hasher.Sum() // Returns []uints.U8 of length 32
 
-	// 4) R = Q0 + Q1   (group law on G2)
-	// G2에는 Add가 없고 AddUnified만 있습니다.
-	R := g2.AddUnified(Q0, Q1)
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(hashResult[i].Val, c.ScPubKeysHash[i].Val)
+	}
 
-	return R, nil
+	return nil
 }
 
-// hashToFieldBLS12381Fp2 implements RFC 9380 hash_to_field for BLS12-381 Fp2.
+// serializeG1Compressed serializes a G1 point into the 48 bytes compressed form used by Ethereum (ZCash format).
 //
-// It takes `msg = signingRoot` (32 bytes) and DST
-// "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
-// and returns two Fp2 elements u[0], u[1] such that:
+//	byte[0] bit 7: compression flag (always 1)
+//	byte[0] bit 6: infinity flag, set for the point at infinity (0, 0), whose other bits are all 0
+//	byte[0] bit 5: sign flag, set if Y is lexicographically largest, i.e. Y > (p-1)/2
+//	remaining 381 bits: X in canonical big-endian form
 //
-// uniform_bytes = expand_message_xmd(msg, DST, 256)
-// tv[i][j] = uniform_bytes[L*(j + i*m) : L*(j + i*m) + L]   (i=0..1, j=0..1)
-// u[i].A0 = OS2IP(tv[i][0]) mod p
-// u[i].A1 = OS2IP(tv[i][1]) mod p
-func (c *Eth2ScUpdateCircuit) hashToFieldBLS12381Fp2(
+// Since p is odd, Y > (p-1)/2 holds iff 2Y >= p, which is exactly when (2Y mod p) is odd,
+// so the sign flag is the least significant bit of the canonical 2Y.
+func (c *Eth2ScUpdateCircuit) serializeG1Compressed(
 	api frontend.API,
-	signingRoot [32]uints.U8,
-) ([2]fields_bls12381.E2, error) {
-
-	const (
-		m     = 2 // extension degree (Fp2)
-		L     = 64
-		count = 2
-	)
-
-	// 1) convert signingRoot -> []uints.U8 (message)
-	msg := signingRoot
-
-	// 2) DST for Ethereum BLS signatures
-	dstBytes := []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
-	dst := make([]uints.U8, len(dstBytes))
-	for i, b := range dstBytes {
-		dst[i] = uints.NewU8(b)
-	}
-
-	// pre-allocate helpers for OS2IP reduction
-	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
-	if err != nil {
-		return [2]fields_bls12381.E2{}, fmt.Errorf("new emulated field: %w", err)
-	}
-	byteAPI, err := uints.NewBytes(api)
-	if err != nil {
-		return [2]fields_bls12381.E2{}, fmt.Errorf("new bytes api: %w", err)
-	}
-
-	// 3) expand_message_xmd(SHA-256)
-	lenInBytes := count * m * L // 256
-	uniform, err := expandMessageXMD_SHA256(api, msg[:], dst, lenInBytes)
-	if err != nil {
-		return [2]fields_bls12381.E2{}, fmt.Errorf("expand_message_xmd: %w", err)
-	}
-	if len(uniform) != lenInBytes {
-		return [2]fields_bls12381.E2{}, fmt.Errorf("uniform_bytes length mismatch")
+	fp *emulated.Field[sw_bls12381.BaseField],
+	p *sw_bls12381.G1Affine,
+) []uints.U8 {
+	xBits := fp.ToBitsCanonical(&p.X)                 // 381 bits, little-endian
+	sign := fp.ToBitsCanonical(fp.Add(&p.Y, &p.Y))[0] // LSB of 2Y mod p
+
+	bits := make([]frontend.Variable, 384)
+	for i := range bits {
+		if i < len(xBits) {
+			bits[i] = xBits[i]
+		} else {
+			bits[i] = 0
+		}
 	}
+	bits[381] = sign                   // 0 at infinity, since Y = 0
+	bits[382] = isInfinity(api, fp, p) // infinity
+	bits[383] = 1                      // compressed
 
-	// 4) slice uniform_bytes into tv blocks and convert to Fp elements
-	var out [2]fields_bls12381.E2
-
-	for i := 0; i < count; i++ {
-		// each u[i] has m (=2) coordinates: tv0 -> A0, tv1 -> A1
-		for j := 0; j < m; j++ {
-			offset := L * (j + i*m)
-			tv := uniform[offset : offset+L] // []uints.U8 length 64
-
-			// OS2IP(tv) mod p using Horner reduction to avoid overflow width issues
-			el, err := c.bytesToBLS12381FpMod(api, fp, byteAPI, tv)
-			if err != nil {
-				return [2]fields_bls12381.E2{}, fmt.Errorf("hashToFieldFp2 os2ip(%d,%d): %w", i, j, err)
-			}
-
-			if j == 0 {
-				out[i].A0 = *el
-			} else {
-				out[i].A1 = *el
-			}
+	out := make([]uints.U8, 48)
+	for byteIdx := 0; byteIdx < 48; byteIdx++ {
+		var byteValue frontend.Variable = 0
+		for bitIdx := 0; bitIdx < 8; bitIdx++ {
+			byteValue = api.Add(byteValue, api.Mul(bits[byteIdx*8+bitIdx], 1<<bitIdx))
 		}
+		// Store in reverse order for big-endian
+		out[47-byteIdx] = uints.U8{Val: byteValue}
 	}
-
-	return out, nil
+	return out
 }
 
-// expandMessageXMD_SHA256 implements expand_message_xmd(msg, DST, len_in_bytes)
-// from RFC 9380, with H = SHA-256 (B = 32, r_in_bytes = 64).
-//
-// All inputs/outputs are uints.U8 in-circuit.
-func expandMessageXMD_SHA256(
-	api frontend.API,
-	msg []uints.U8,
-	dst []uints.U8,
-	lenInBytes int,
-) ([]uints.U8, error) {
-
-	const (
-		B        = 32 // SHA-256 output size in bytes
-		rInBytes = 64
-		maxLen   = 255 * B
-	)
-
-	if lenInBytes <= 0 || lenInBytes > maxLen {
-		return nil, fmt.Errorf("len_in_bytes out of range")
-	}
-
-	ell := (lenInBytes + B - 1) / B
-
-	// DST' = DST || I2OSP(len(DST), 1)
-	dstPrime := make([]uints.U8, 0, len(dst)+1)
-	dstPrime = append(dstPrime, dst...)
-	dstPrime = append(dstPrime, uints.NewU8(uint8(len(dst))))
-
-	// Z_pad = I2OSP(0, r_in_bytes)
-	zPad := make([]uints.U8, rInBytes)
-	for i := 0; i < rInBytes; i++ {
-		zPad[i] = uints.NewU8(0)
-	}
-
-	// l_i_b_str = I2OSP(len_in_bytes, 2) (big-endian)
-	lIB := []uints.U8{
-		uints.NewU8(uint8(lenInBytes >> 8)),
-		uints.NewU8(uint8(lenInBytes & 0xff)),
-	}
-
-	// bytes gadget for XOR and such
-	bapi, err := uints.NewBytes(api)
-	if err != nil {
-		return nil, fmt.Errorf("NewBytes: %w", err)
-	}
-
-	// b0 = H(Z_pad || msg || l_i_b_str || 0x00 || DST')
-	h0, err := sha2.New(api)
+// scPubKeysInfinity returns, for each witnessed pubkey, 1 if it is the point at infinity and 0 otherwise.
+// The point at infinity is witnessed as (0, 0), which is not on the curve.
+func (c *Eth2ScUpdateCircuit) scPubKeysInfinity(api frontend.API) ([]frontend.Variable, error) {
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
 	if err != nil {
-		return nil, fmt.Errorf("sha2.New(b0): %w", err)
+		return nil, fmt.Errorf("new emulated field: %w", err)
 	}
-	h0.Write(zPad)
-	h0.Write(msg)
-	h0.Write(lIB)
-	h0.Write([]uints.U8{uints.NewU8(0x00)})
-	h0.Write(dstPrime)
-	b0 := h0.Sum() // len 32
-
-	// b1 = H(b0 || 0x01 || DST')
-	h1, err := sha2.New(api)
-	if err != nil {
-		return nil, fmt.Errorf("sha2.New(b1): %w", err)
-	}
-	h1.Write(b0)
-	h1.Write([]uints.U8{uints.NewU8(0x01)})
-	h1.Write(dstPrime)
-	b1 := h1.Sum() // len 32
-
-	// uniform_bytes = b1 || b2 || ... || b_ell (truncated)
-	uniform := make([]uints.U8, 0, ell*B)
-	uniform = append(uniform, b1...)
-
-	prev := b1
-	for i := 2; i <= ell; i++ {
-		// t = strxor(b0, prev)
-		if len(b0) != len(prev) {
-			return nil, fmt.Errorf("b0 and prev length mismatch")
-		}
-		t := make([]uints.U8, len(b0))
-		for j := range b0 {
-			t[j] = bapi.Xor(b0[j], prev[j])
-		}
-
-		// b_i = H(t || I2OSP(i,1) || DST')
-		hi, err := sha2.New(api)
-		if err != nil {
-			return nil, fmt.Errorf("sha2.New(b_%d): %w", i, err)
-		}
-		hi.Write(t)
-		hi.Write([]uints.U8{uints.NewU8(uint8(i))})
-		hi.Write(dstPrime)
-		bi := hi.Sum()
-
-		uniform = append(uniform, bi...)
-		prev = bi
+	infinity := make([]frontend.Variable, len(c.ScPubKeys))
+	for i := range c.ScPubKeys {
+		infinity[i] = isInfinity(api, fp, &c.ScPubKeys[i])
 	}
-
-	return uniform[:lenInBytes], nil
+	return infinity, nil
 }
 
-// bytesToBLS12381FpMod reduces a big-endian byte slice to a BLS12-381 Fp element.
-// Implements res = OS2IP(b) mod p via Horner evaluation to stay within limb width constraints.
-func (c *Eth2ScUpdateCircuit) bytesToBLS12381FpMod(
-	api frontend.API,
-	fp *emulated.Field[sw_bls12381.BaseField],
-	byteAPI *uints.Bytes,
-	b []uints.U8,
-) (*emulated.Element[sw_bls12381.BaseField], error) {
-	radix := big.NewInt(256)
-	res := fp.Zero()
-	nbLimbs := len(fp.Modulus().Limbs)
-	limbBuf := make([]frontend.Variable, nbLimbs)
-
-	for _, by := range b {
-		res = fp.MulConst(res, radix) // res *= 256
-		for i := range limbBuf {
-			limbBuf[i] = 0
-		}
-		limbBuf[0] = byteAPI.Value(by)
-		digit := fp.NewElement(limbBuf)
-		res = fp.Add(res, digit) // res += byte
-	}
-
-	// normalize; keeps width bounded even after long Horner accumulation
-	res = fp.Reduce(res)
-	return res, nil
+// isInfinity returns 1 if p is the point at infinity, encoded as (0, 0)
+func isInfinity(api frontend.API, fp *emulated.Field[sw_bls12381.BaseField], p *sw_bls12381.G1Affine) frontend.Variable {
+	return api.And(fp.IsZero(&p.X), fp.IsZero(&p.Y))
 }
 
-//// verifyScPubKeysHash verifies that the commitment to sync committee pubkeys matches
-//// Uses SHA2 hash for compatibility
-//// Only hashes the first two limbs (Limbs[0], Limbs[1]) of each X coordinate for efficiency
-//func (c *Eth2ScUpdateCircuit) verifyScPubKeysHash(api frontend.API) error {
-//	// Create SHA2 hasher
-//	hasher, err := sha2.New(api)
-//	if err != nil {
-//		return fmt.Errorf("failed to create SHA2 hasher: %w", err)
-//	}
-//
-//	// BLS public key is 48 bytes long, so we hash the last two limbs of x coordinate.
-//	// Limbs[0] is the least significant limb of x coordinate.
-//	for i := 0; i < 512; i++ {
-//		xbytes := c.serializeLimbTo8Bytes(api, c.ScPubKeys[i].X.Limbs[1])
-//		hasher.Write(xbytes)
-//		xbytes = c.serializeLimbTo8Bytes(api, c.ScPubKeys[i].X.Limbs[0])
-//		hasher.Write(xbytes)
-//	}
-//
-//	// Compute hash
-//	hashResult := hasher.Sum() // Returns []uints.U8 of length 32
-//
-//	for i := 0; i < 32; i++ {
-//		api.AssertIsEqual(hashResult[i].Val, c.ScPubKeysHash[i].Val)
-//	}
-//
-//	return nil
-//}
+// assertScPubKeysOnG1 validates every witnessed pubkey as selected by Params.ScPubKeysCheck.
+// It runs before aggregatePubKeys, whose incomplete additions are only sound for points of G1.
+func (c *Eth2ScUpdateCircuit) assertScPubKeysOnG1(api frontend.API, infinity []frontend.Variable) error {
+	return assertPubKeysOnG1(api, c.Params.ScPubKeysCheck, c.ScPubKeys, infinity)
+}
 
-func (c *Eth2ScUpdateCircuit) verifyScPubKeysHash(api frontend.API) error {
-	// Create SHA2 hasher
-	hasher, err := sha2.New(api)
-	if err != nil {
-		return fmt.Errorf("failed to create SHA2 hasher: %w", err)
+// assertPubKeysOnG1 asserts that each of pubkeys is either the point at infinity (infinity[i] == 1)
+// or on the BLS12-381 curve and, with PubKeyCheckSubgroup, in the prime order subgroup
+// (P == -[x²]ϕ(P), see sw_bls12381.Pairing.IsOnG1)
+func assertPubKeysOnG1(api frontend.API, check PubKeyCheck, pubkeys []sw_bls12381.G1Affine, infinity []frontend.Variable) error {
+	if check == PubKeyCheckNone {
+		return nil
 	}
-
-	// BLS public key is 48 bytes long, so we hash the last two limbs of x coordinate.
-	// Limbs[0] is the least significant limb of x coordinate.
-	for i := 0; i < 512; i++ {
-		xbytes := c.serializeLimbTo8Bytes(api, c.ScPubKeys[i].X.Limbs[1])
-		hasher.Write(xbytes)
-		xbytes = c.serializeLimbTo8Bytes(api, c.ScPubKeys[i].X.Limbs[0])
-		hasher.Write(xbytes)
+	pairing, err := sw_bls12381.NewPairing(api)
+	if err != nil {
+		return fmt.Errorf("failed to create pairing: %w", err)
 	}
-
-	// Compute hash
-	hashResult := hasher.Sum() // Returns []uints.U8 of length 32
-
-	for i := 0; i < 32; i++ {
-		api.AssertIsEqual(hashResult[i].Val, c.ScPubKeysHash[i].Val)
+	for i := range pubkeys {
+		var valid frontend.Variable
+		switch check {
+		case PubKeyCheckSubgroup:
+			valid = pairing.IsOnG1(&pubkeys[i])
+		case PubKeyCheckOnCurve:
+			valid = pairing.IsOnCurve(&pubkeys[i])
+		default:
+			return fmt.Errorf("unsupported pubkey check: %v", check)
+		}
+		api.AssertIsEqual(api.Or(valid, infinity[i]), 1)
 	}
-
 	return nil
 }
 
 // aggregatePubKeys aggregates public keys based on sync_committee_bits
 // Returns the aggregated public key for validators who participated in signing
-func (c *Eth2ScUpdateCircuit) aggregatePubKeys(api frontend.API) (*sw_bls12381.G1Affine, error) {
+//
+// A pubkey at infinity is the identity of the group, so it is skipped even if its bit is set:
+// the aggregate is the sum of the other selected pubkeys. At least one selected pubkey must not
+// be at infinity, an update signed by no (or only identity) keys is not provable.
+//
+// Every bit is asserted boolean: a bit of 2 would otherwise count a pubkey twice in the participant
+// sum. api.And asserts its inputs boolean too, the explicit assertion keeps the soundness of the
+// aggregation independent of that, for at most one constraint per member.
+func (c *Eth2ScUpdateCircuit) aggregatePubKeys(api frontend.API, infinity []frontend.Variable) (*sw_bls12381.G1Affine, error) {
 	// Create curve for G1 operations
 	curve, err := sw_emulated.New[sw_bls12381.BaseField, sw_bls12381.ScalarField](api, sw_emulated.GetBLS12381Params())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create curve: %w", err)
 	}
 
-	// Find the first validator that participated to initialize the accumulator
-	accumulator := &c.ScPubKeys[0]
-	hasInitialized := c.ScBits[0]
-
-	// Process remaining validators
-	for i := 1; i < 512; i++ {
-		bit := c.ScBits[i]
-
-		// If we haven't initialized yet and this bit is set, use this as initial value
-		isFirstSelected := api.And(api.IsZero(hasInitialized), bit)
-
-		// If hasInitialized is true and bit is set, we should add
-		shouldAdd := api.And(hasInitialized, bit)
-
-		// Compute sum = accumulator + pubkey[i]
-		sum := curve.Add(accumulator, &c.ScPubKeys[i])
-
-		// If shouldAdd, use sum; otherwise keep accumulator
-		tempResult := curve.Select(shouldAdd, sum, accumulator)
-
-		// If this is the first selected key, replace with pubkey[i]; otherwise use tempResult
-		accumulator = curve.Select(isFirstSelected, &c.ScPubKeys[i], tempResult)
-
-		// Update hasInitialized flag
-		hasInitialized = api.Or(hasInitialized, bit)
+	// The accumulator starts at aggregationOffset, so that every step is one incomplete addition and
+	// one selection, without tracking the first selected pubkey: the partial sums are offset by a point
+	// of unknown discrete logarithm and cannot meet the added pubkey.
+	offset := sw_bls12381.NewG1Affine(aggregationOffset)
+	accumulator := &offset
+	participants := make([]frontend.Variable, len(c.ScPubKeys))
+	for i := range c.ScPubKeys {
+		api.AssertIsBoolean(c.ScBits[i])
+		participants[i] = api.And(c.ScBits[i], api.IsZero(infinity[i]))
+		accumulator = curve.Select(participants[i], curve.Add(accumulator, &c.ScPubKeys[i]), accumulator)
 	}
 
-	// Ensure at least one validator participated
-	api.AssertIsEqual(hasInitialized, 1)
+	// Ensure at least one validator participated, the accumulator then differs from the offset
+	api.AssertIsDifferent(api.Add(participants[0], participants[1], participants[2:]...), 0)
 
-	return accumulator, nil
+	return curve.Add(accumulator, curve.Neg(&offset)), nil
 }
 
+// aggregationOffset is the initial accumulator of aggregatePubKeys, hashed to G1 so that its discrete
+// logarithm is unknown
+var aggregationOffset = func() bls12381.G1Affine {
+	p, err := bls12381.HashToG1([]byte("zk-chains sync committee aggregation offset"), []byte("BLS12381G1_XMD:SHA-256_SSWU_RO_"))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}()
+
 // verifyBLSSignature verifies the BLS signature using pairing check
 // Verifies: e(pubkey, H(msg)) == e(G1, signature)
 // Or equivalently: e(pubkey, H(msg)) * e(-G1, signature) == 1
@@ -499,10 +437,12 @@ func (c *Eth2ScUpdateCircuit) verifyBLSSignature(api frontend.API, aggregatedPub
 		return fmt.Errorf("failed to create pairing: %w", err)
 	}
 
-	// Verify inputs are in correct subgroups
+	// Verify inputs are in correct subgroups, the signature as selected by Params.SigCheck
 	pairing.AssertIsOnG1(aggregatedPubKey)
 	pairing.AssertIsOnG2(signingRootG2)
-	pairing.AssertIsOnG2(&c.AggregatedSig)
+	if err := assertSignatureOnG2(api, c.Params.SigCheck, &c.AggregatedSig); err != nil {
+		return err
+	}
 
 	// Create curve for G1 operations
 	curve, err := sw_emulated.New[sw_bls12381.BaseField, sw_bls12381.ScalarField](api, sw_emulated.GetBLS12381Params())
@@ -526,53 +466,68 @@ func (c *Eth2ScUpdateCircuit) verifyBLSSignature(api frontend.API, aggregatedPub
 	return nil
 }
 
+// assertSignatureOnG2 asserts that sig is on the twist of BLS12-381 and, with SigCheckSubgroup, in the
+// prime order subgroup (ψ(Q) == [x₀]Q, see sw_bls12381.Pairing.IsOnG2).
+//
+// gnark accepts (0, 0), the point at infinity, as on the twist and in G2. It is rejected explicitly:
+// an update always has participants, so the identity is never its signature.
+func assertSignatureOnG2(api frontend.API, check SigCheck, sig *sw_bls12381.G2Affine) error {
+	pairing, err := sw_bls12381.NewPairing(api)
+	if err != nil {
+		return fmt.Errorf("failed to create pairing: %w", err)
+	}
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+	if err != nil {
+		return fmt.Errorf("failed to create field: %w", err)
+	}
+	x, y := &sig.P.X, &sig.P.Y
+	infinity := api.And(api.And(fp.IsZero(&x.A0), fp.IsZero(&x.A1)), api.And(fp.IsZero(&y.A0), fp.IsZero(&y.A1)))
+	api.AssertIsEqual(infinity, 0)
+
+	switch check {
+	case SigCheckSubgroup:
+		api.AssertIsEqual(pairing.IsOnG2(sig), 1)
+	case SigCheckOnTwist:
+		pairing.AssertIsOnTwist(sig)
+	default:
+		return fmt.Errorf("unsupported signature check: %v", check)
+	}
+	return nil
+}
+
 // verifyNextSyncCommitteeMerkleProof verifies that next_sync_committee root is included in StateRoot
 // using the SSZ Merkle proof (next_sync_committee_branch).
 //
-// The next_sync_committee field is at generalized index 87 in the BeaconState (Fulu).
-// Position 23 (0-indexed) in the BeaconState structure.
-// Generalized index = 2^depth + position = 64 + 23 = 87
-// Position 23 in binary: 0b10111
+// The generalized index of next_sync_committee depends on the BeaconState layout of the fork
+// and is taken from Params: 55 (depth 5) for Altair .. Deneb, 87 (depth 6) for Electra and Fulu.
+// Generalized index = 2^depth + position, e.g. 64 + 23 = 87
 //
-// For a Merkle branch of length 6, we verify by:
+// For a Merkle branch of length depth, we verify by:
 // 1. Starting with leaf = NextScRoot
 // 2. For each branch node, compute parent = hash(left, right) where left/right depends on the path
 // 3. Final result should equal StateRoot
 func (c *Eth2ScUpdateCircuit) verifyNextSyncCommitteeMerkleProof(api frontend.API) error {
-	// NextSyncCommittee generalized index in Fulu BeaconState
-	// Position 23 (0-indexed) in BeaconState structure
-	// Generalized index = 2^depth + position = 64 + 23 = 87
-	//
-	// Extract the path from position 23 = 0b10111
-	// Path bits (LSB first): [1, 1, 1, 0, 1, 0]
-	// This means at each level: if bit is 1, current node is on the right; if 0, on the left
-	//
-	// The branch contains 6 sibling hashes needed to compute the path to the root
-	path := [6]int{1, 1, 1, 0, 1, 0}
-
-	// Start with the leaf (next_sync_committee root)
-	current := c.NextScRoot
-
-	// Traverse up the tree using the branch
-	for i := 0; i < 6; i++ {
-		sibling := c.NextScBranch[i]
-
-		// Compute parent hash based on path direction
-		if path[i] == 1 {
-			// Current node is on the right, sibling is on the left
-			current = c.hashPair(api, sibling, current)
-		} else {
-			// Current node is on the left, sibling is on the right
-			current = c.hashPair(api, current, sibling)
-		}
-	}
+	return gadgets.VerifySSZBranch(api, c.NextScRoot, c.NextScBranch, c.Params.NextSyncCommitteeGIndex(), c.StateRoot)
+}
 
-	// The final computed root must equal the StateRoot from the BeaconBlockHeader
-	for i := 0; i < 32; i++ {
-		api.AssertIsEqual(current[i].Val, c.StateRoot[i].Val)
-	}
+// verifyExecBlockHashMerkleProof verifies that the public ExecBlockHash is the block_hash of the
+// execution payload header committed to by BodyRoot.
+//
+// The branch goes from block_hash (gindex 44 of the 17 fields ExecutionPayloadHeader, Deneb ..)
+// up to the header root, then through execution_branch (gindex 25 of the BeaconBlockBody),
+// i.e. generalized index 812 of the body.
+func (c *Eth2ScUpdateCircuit) verifyExecBlockHashMerkleProof(api frontend.API) error {
+	return gadgets.VerifySSZBranch(api, c.ExecBlockHash, c.ExecBlockHashBranch[:], types.ExecutionBlockHashBodyGIndex, c.BodyRoot)
+}
 
-	return nil
+// verifyExecBlockNumberMerkleProof verifies that the public ExecBlockNumber is the block_number of the
+// execution payload header committed to by BodyRoot (generalized index 806 of the body).
+//
+// The leaf is the uint64 little-endian chunk of ExecBlockNumber, whose binary decomposition
+// also range checks it to 64 bits.
+func (c *Eth2ScUpdateCircuit) verifyExecBlockNumberMerkleProof(api frontend.API) error {
+	leaf := c.serializeUint64ToChunk(api, c.ExecBlockNumber)
+	return gadgets.VerifySSZBranch(api, leaf, c.ExecBlockNumberBranch[:], types.ExecutionBlockNumberBodyGIndex, c.BodyRoot)
 }
 
 // Helper functions (reused from BlockRootHasher)
@@ -633,18 +588,7 @@ func (c *Eth2ScUpdateCircuit) zeroChunk() [32]uints.U8 {
 }
 
 // hashPair computes the SHA256 hash of two 32-byte arrays (left and right) and returns the resulting 32-byte hash.
+// All the calls of a compilation share one gadgets.PairHasher.
 func (c *Eth2ScUpdateCircuit) hashPair(api frontend.API, left, right [32]uints.U8) [32]uints.U8 {
-	// Create a new SHA256 hasher
-	hasher, err := sha2.New(api)
-	if err != nil {
-		panic(err)
-	}
-
-	// Write 64 bytes total (left || right)
-	hasher.Write(left[:])
-	hasher.Write(right[:])
-
-	// Compute SHA256 hash
-	hashResult := hasher.Sum()
-	return [32]uints.U8(hashResult)
+	return gadgets.HashPair(api, left, right)
 }
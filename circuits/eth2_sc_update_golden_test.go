@@ -0,0 +1,149 @@
+package circuit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden files instead of comparing against them:
+// go test ./circuits -run Golden -update
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or writes it with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, got, 0644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file, run with -update")
+	if !bytes.Equal(want, got) {
+		i := 0
+		for i < len(want) && i < len(got) && want[i] == got[i] {
+			i++
+		}
+		t.Fatalf("%s changed at byte %d (%d -> %d bytes): the public inputs of deployed verifiers would no longer match, "+
+			"run with -update if the change is intended", name, i, len(want), len(got))
+	}
+}
+
+// publicInputs are the public values of Eth2ScUpdateCircuit for sc-update-1105, signed by the committee of sc-update-1104
+type publicInputs struct {
+	scPubKeysHash, nextScRoot, execBlockHash [32]byte
+	period, execBlockNumber                  uint64
+}
+
+// newPublicAssignment assigns the public inputs of Eth2ScUpdateCircuit for sc-update-1105
+func newPublicAssignment(t *testing.T) (*Eth2ScUpdateCircuit, *publicInputs) {
+	var updates [2]types.LightClientUpdate
+	for i, name := range []string{"data/sc-update-1104.json", "data/sc-update-1105.json"} {
+		data, err := os.ReadFile(filepath.Join(rootDir, name))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &updates[i]))
+	}
+	committee, update := &updates[0].Data.NextSyncCommittee, &updates[1]
+
+	pubkeys := make([]bls12381.G1Affine, len(committee.Pubkeys))
+	for i := range committee.Pubkeys {
+		_, err := pubkeys[i].SetBytes(committee.Pubkeys[i][:])
+		require.NoError(t, err)
+	}
+	scPubKeysHash := types.ComputeScPubKeysHash(pubkeys)
+	nextScRoot := update.Data.NextSyncCommittee.HashTreeRoot(configs.Mainnet, tree.GetHashFn())
+
+	public := &publicInputs{
+		scPubKeysHash: scPubKeysHash,
+		nextScRoot:    nextScRoot,
+		period:        uint64(update.Data.AttestedHeader.Beacon.Slot) >> SlotsPerPeriodLog2,
+	}
+	var err error
+	public.execBlockNumber, err = strconv.ParseUint(update.Data.AttestedHeader.Execution.BlockNumber, 10, 64)
+	require.NoError(t, err)
+	require.NoError(t, (*zrntcommon.Root)(&public.execBlockHash).UnmarshalText([]byte(update.Data.AttestedHeader.Execution.BlockHash)))
+
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
+	witness.ScPubKeysHash = [32]uints.U8(uints.NewU8Array(scPubKeysHash[:]))
+	witness.NextScRoot = [32]uints.U8(uints.NewU8Array(nextScRoot[:]))
+	witness.Period = public.period
+	witness.AttestedSlot = uint64(update.Data.AttestedHeader.Beacon.Slot)
+	witness.Domain = [32]uints.U8(uints.NewU8Array(DOMAIN[:]))
+	require.NoError(t, assignExecutionToWitness(update, witness))
+	return witness, public
+}
+
+// TestEth2ScUpdateCircuit_GoldenPublicWitness pins the serialized public witness of a real update.
+// The verifier contract rebuilds the same vector from its own state, so any reordering or
+// re-encoding of the public inputs (which needs a new verifier) shows up here first.
+func TestEth2ScUpdateCircuit_GoldenPublicWitness(t *testing.T) {
+	assignment, public := newPublicAssignment(t)
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	data, err := w.MarshalBinary()
+	require.NoError(t, err)
+	checkGolden(t, "eth2_sc_update_public_witness.golden", []byte(hex.EncodeToString(data)+"\n"))
+
+	// the layout assumed by the aggregation circuit and Eth2LightClient._publicInputs
+	vector, ok := w.Vector().(fr.Vector)
+	require.True(t, ok)
+	require.Len(t, vector, innerNbPublicInputs)
+	at := func(i int) uint64 { return vector[i].Uint64() }
+	for i := 0; i < 32; i++ {
+		require.Equal(t, uint64(public.scPubKeysHash[i]), at(innerScPubKeysHashOffset+i))
+		require.Equal(t, uint64(public.nextScRoot[i]), at(innerNextScRootOffset+i))
+		require.Equal(t, uint64(DOMAIN[i]), at(innerDomainOffset+i))
+		require.Equal(t, uint64(public.execBlockHash[i]), at(innerExecBlockHashOffset+i))
+	}
+	require.Equal(t, public.period, at(innerPeriodOffset))
+	require.Equal(t, public.execBlockNumber, at(innerExecBlockNumberOffset))
+	require.Equal(t, assignment.AttestedSlot, at(innerAttestedSlotOffset))
+}
+
+// TestEth2ScUpdateCircuit_PublicStateRoot checks PublicStateRoot appends the 32 bytes of the state root
+// after the default layout, which stays unchanged (Eth2LightClient._stateRootInputs)
+func TestEth2ScUpdateCircuit_PublicStateRoot(t *testing.T) {
+	assignment, _ := newPublicAssignment(t)
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	base, ok := w.Vector().(fr.Vector)
+	require.True(t, ok)
+
+	stateRoot := [32]byte{0x01, 0x02, 0x03, 31: 0xff}
+	withRoot := NewEth2ScUpdateCircuit(CircuitParams{PublicStateRoot: true})
+	require.Len(t, withRoot.AttestedStateRoot, 32)
+	withRoot.ScPubKeysHash = assignment.ScPubKeysHash
+	withRoot.NextScRoot = assignment.NextScRoot
+	withRoot.Period = assignment.Period
+	withRoot.Domain = assignment.Domain
+	withRoot.ExecBlockHash = assignment.ExecBlockHash
+	withRoot.ExecBlockNumber = assignment.ExecBlockNumber
+	withRoot.AttestedSlot = assignment.AttestedSlot
+	copy(withRoot.AttestedStateRoot, uints.NewU8Array(stateRoot[:]))
+	w, err = frontend.NewWitness(withRoot, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	vector, ok := w.Vector().(fr.Vector)
+	require.True(t, ok)
+	require.Len(t, vector, innerNbPublicInputs+32)
+	require.Equal(t, base, vector[:innerNbPublicInputs])
+	for i := 0; i < 32; i++ {
+		require.Equal(t, uint64(stateRoot[i]), vector[innerNbPublicInputs+i].Uint64())
+	}
+}
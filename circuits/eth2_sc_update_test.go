@@ -2,24 +2,32 @@ package circuit
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	fp_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/constraint/solver"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/emulated"
 	"github.com/consensys/gnark/std/math/uints"
 	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/circuits/hash2curve"
 	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/configs"
 	"github.com/protolambda/ztyp/tree"
 	"github.com/rs/zerolog"
@@ -86,11 +94,14 @@ func TestEth2ScUpdateCircuit_IsSolved(t *testing.T) {
 	}
 
 	// Create witness
-	witness := &Eth2ScUpdateCircuit{}
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
 
 	// Assign BeaconBlockHeader fields
 	witness.Slot = uint64(update.Data.AttestedHeader.Beacon.Slot)
 	witness.ProposerIndex = uint64(update.Data.AttestedHeader.Beacon.ProposerIndex)
+	witness.Period = uint64(update.Data.AttestedHeader.Beacon.Slot) >> SlotsPerPeriodLog2
+	witness.AttestedSlot = uint64(update.Data.AttestedHeader.Beacon.Slot)
+	witness.Domain = [32]uints.U8(uints.NewU8Array(DOMAIN[:]))
 
 	for i := 0; i < 32; i++ {
 		witness.ParentRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.ParentRoot[i])
@@ -124,10 +135,11 @@ func TestEth2ScUpdateCircuit_IsSolved(t *testing.T) {
 
 	// Assign next_sync_committee root and branch to witness
 	assignNextSyncCommitteeToWitness(&update, witness)
+	require.NoError(t, assignExecutionToWitness(&update, witness))
 
 	// Test the circuit using gnark test framework
 	assert := gnark_test.NewAssert(t)
-	err = gnark_test.IsSolved(&Eth2ScUpdateCircuit{}, witness, ecc.BN254.ScalarField())
+	err = gnark_test.IsSolved(NewEth2ScUpdateCircuit(CircuitParams{}), witness, ecc.BN254.ScalarField())
 	assert.NoError(err, "Circuit constraints should be satisfied")
 	t.Logf("✓ Proof solving SUCCEEDED!")
 
@@ -180,11 +192,14 @@ func TestEth2ScUpdateCircuit(t *testing.T) {
 	}
 
 	// Create witness
-	witness := &Eth2ScUpdateCircuit{}
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
 
 	// Assign BeaconBlockHeader fields
 	witness.Slot = uint64(update.Data.AttestedHeader.Beacon.Slot)
 	witness.ProposerIndex = uint64(update.Data.AttestedHeader.Beacon.ProposerIndex)
+	witness.Period = uint64(update.Data.AttestedHeader.Beacon.Slot) >> SlotsPerPeriodLog2
+	witness.AttestedSlot = uint64(update.Data.AttestedHeader.Beacon.Slot)
+	witness.Domain = [32]uints.U8(uints.NewU8Array(DOMAIN[:]))
 	for i := 0; i < 32; i++ {
 		witness.ParentRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.ParentRoot[i])
 		witness.StateRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.StateRoot[i])
@@ -217,6 +232,7 @@ func TestEth2ScUpdateCircuit(t *testing.T) {
 
 	// Assign next_sync_committee root and branch to witness
 	assignNextSyncCommitteeToWitness(&update, witness)
+	require.NoError(t, assignExecutionToWitness(&update, witness))
 
 	// Test proof generation and verification
 	// Create full witness
@@ -301,10 +317,13 @@ func TestEth2ScUpdateCircuitInvalidSignature(t *testing.T) {
 	require.NoError(t, err, "Failed to set random Y")
 
 	// Create witness with invalid signature
-	witness := &Eth2ScUpdateCircuit{}
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
 
 	witness.Slot = uint64(update.Data.AttestedHeader.Beacon.Slot)
 	witness.ProposerIndex = uint64(update.Data.AttestedHeader.Beacon.ProposerIndex)
+	witness.Period = uint64(update.Data.AttestedHeader.Beacon.Slot) >> SlotsPerPeriodLog2
+	witness.AttestedSlot = uint64(update.Data.AttestedHeader.Beacon.Slot)
+	witness.Domain = [32]uints.U8(uints.NewU8Array(DOMAIN[:]))
 	for i := 0; i < 32; i++ {
 		witness.ParentRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.ParentRoot[i])
 		witness.StateRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.StateRoot[i])
@@ -337,6 +356,7 @@ func TestEth2ScUpdateCircuitInvalidSignature(t *testing.T) {
 
 	// Assign next_sync_committee root and branch to witness
 	assignNextSyncCommitteeToWitness(&update, witness)
+	require.NoError(t, assignExecutionToWitness(&update, witness))
 
 	// Create witness
 	fullWitness, err := frontend.NewWitness(witness, ecc.BN254.ScalarField())
@@ -411,10 +431,13 @@ func TestEth2ScUpdateCircuitInvalidBlockRoot(t *testing.T) {
 	}
 
 	// Create witness with invalid block root
-	witness := &Eth2ScUpdateCircuit{}
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
 
 	witness.Slot = uint64(update.Data.AttestedHeader.Beacon.Slot)
 	witness.ProposerIndex = uint64(update.Data.AttestedHeader.Beacon.ProposerIndex)
+	witness.Period = uint64(update.Data.AttestedHeader.Beacon.Slot) >> SlotsPerPeriodLog2
+	witness.AttestedSlot = uint64(update.Data.AttestedHeader.Beacon.Slot)
+	witness.Domain = [32]uints.U8(uints.NewU8Array(DOMAIN[:]))
 	for i := 0; i < 32; i++ {
 		witness.ParentRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.ParentRoot[i])
 		witness.StateRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.StateRoot[i])
@@ -446,6 +469,7 @@ func TestEth2ScUpdateCircuitInvalidBlockRoot(t *testing.T) {
 
 	// Assign next_sync_committee root and branch to witness
 	assignNextSyncCommitteeToWitness(&update, witness)
+	require.NoError(t, assignExecutionToWitness(&update, witness))
 
 	// Create witness
 	fullWitness, err := frontend.NewWitness(witness, ecc.BN254.ScalarField())
@@ -488,9 +512,12 @@ func BenchmarkEth2ScUpdateCircuit(b *testing.B) {
 	var signature bls12381.G2Affine
 	_, _ = signature.SetBytes(sigBytes)
 
-	witness := &Eth2ScUpdateCircuit{}
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
 	witness.Slot = uint64(update.Data.AttestedHeader.Beacon.Slot)
 	witness.ProposerIndex = uint64(update.Data.AttestedHeader.Beacon.ProposerIndex)
+	witness.Period = uint64(update.Data.AttestedHeader.Beacon.Slot) >> SlotsPerPeriodLog2
+	witness.AttestedSlot = uint64(update.Data.AttestedHeader.Beacon.Slot)
+	witness.Domain = [32]uints.U8(uints.NewU8Array(DOMAIN[:]))
 	for i := 0; i < 32; i++ {
 		witness.ParentRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.ParentRoot[i])
 		witness.StateRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.StateRoot[i])
@@ -522,6 +549,7 @@ func BenchmarkEth2ScUpdateCircuit(b *testing.B) {
 
 	// Assign next_sync_committee root and branch to witness
 	assignNextSyncCommitteeToWitness(&update, witness)
+	require.NoError(b, assignExecutionToWitness(&update, witness))
 
 	// Create witness once
 	fullWitness, _ := frontend.NewWitness(witness, ecc.BN254.ScalarField())
@@ -565,6 +593,11 @@ func onceSetupCircuit() {
 	pkPath := filepath.Join(rootDir, ".build/Eth2ScUpdateCircuit.pk")
 	vkPath := filepath.Join(rootDir, ".build/Eth2ScUpdateCircuit.vk")
 
+	// Refuse artifacts of setup_circuit.go which do not match its manifest, e.g. a key of another circuit
+	if err := verifyBuildArtifacts("Eth2ScUpdateCircuit"); err != nil {
+		panic(err)
+	}
+
 	// Step 1: Circuit compile
 	fCcs, err := os.Open(ccsPath)
 	defer fCcs.Close()
@@ -572,7 +605,7 @@ func onceSetupCircuit() {
 	if err != nil {
 		fmt.Println("Compiling Eth2ScUpdateCircuit circuit...")
 		// Compile with BN254 scalar field (for emulated BLS12-381)
-		blsVerifierCCS, err = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &Eth2ScUpdateCircuit{})
+		blsVerifierCCS, err = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, NewEth2ScUpdateCircuit(CircuitParams{}))
 		if err != nil {
 			panic(err)
 		}
@@ -620,6 +653,28 @@ func onceSetupCircuit() {
 	fmt.Println("✓ Setup complete")
 }
 
+// verifyBuildArtifacts checks the artifacts of the named circuit in .build against the checksums of the
+// manifest, if setup_circuit.go wrote one
+func verifyBuildArtifacts(name string) error {
+	dir := filepath.Join(rootDir, ".build")
+	manifest, err := types.LoadArtifactManifest(filepath.Join(dir, types.ManifestFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	artifacts, err := manifest.Circuit(name)
+	if err != nil {
+		return nil
+	}
+	// missing artifacts are compiled and set up again
+	if err := artifacts.VerifyArtifacts(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
 // assignNextSyncCommitteeToWitness computes next_sync_committee root and assigns it along with
 // next_sync_committee_branch to the witness
 func assignNextSyncCommitteeToWitness(
@@ -636,13 +691,35 @@ func assignNextSyncCommitteeToWitness(
 	}
 
 	// Assign next_sync_committee_branch (private input)
-	for i := 0; i < 6; i++ {
+	for i := range witness.NextScBranch {
 		for j := 0; j < 32; j++ {
 			witness.NextScBranch[i][j] = uints.NewU8(update.Data.NextSyncCommitteeBranch[i][j])
 		}
 	}
 }
 
+// assignExecutionToWitness assigns the attested execution block_hash and block_number with their branches to BodyRoot
+func assignExecutionToWitness(update *types.LightClientUpdate, witness *Eth2ScUpdateCircuit) error {
+	proof, err := types.ExecutionFieldProof(update, types.ExecutionBlockHashGIndex)
+	if err != nil {
+		return err
+	}
+	witness.ExecBlockHash = [32]uints.U8(uints.NewU8Array(proof.Leaf[:]))
+	for i := range witness.ExecBlockHashBranch {
+		witness.ExecBlockHashBranch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+
+	proof, err = types.ExecutionFieldProof(update, types.ExecutionBlockNumberGIndex)
+	if err != nil {
+		return err
+	}
+	witness.ExecBlockNumber = binary.LittleEndian.Uint64(proof.Leaf[:8])
+	for i := range witness.ExecBlockNumberBranch {
+		witness.ExecBlockNumberBranch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+	return nil
+}
+
 func mustGetRootDir() string {
 	root, err := projectRoot(".")
 	if err != nil {
@@ -686,3 +763,641 @@ func projectRoot(startPath ...string) (string, error) {
 		fmt.Println("next dir:", currentPath)
 	}
 }
+
+// g1CompressedCircuit checks the in-circuit compressed serialization of G1 points
+type g1CompressedCircuit struct {
+	P        [5]sw_bls12381.G1Affine
+	Expected [5][48]uints.U8
+}
+
+func (c *g1CompressedCircuit) Define(api frontend.API) error {
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+	if err != nil {
+		return err
+	}
+	sc := &Eth2ScUpdateCircuit{}
+	for i := range c.P {
+		out := sc.serializeG1Compressed(api, fp, &c.P[i])
+		for j := 0; j < 48; j++ {
+			api.AssertIsEqual(out[j].Val, c.Expected[i][j].Val)
+		}
+	}
+	return nil
+}
+
+func TestSerializeG1Compressed(t *testing.T) {
+	update1104File, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update1104 types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(update1104File, &update1104))
+
+	witness := &g1CompressedCircuit{}
+	signs := map[bool]bool{}
+	for i := 0; i < 3; i++ {
+		compressed := update1104.Data.NextSyncCommittee.Pubkeys[i]
+		var pk bls12381.G1Affine
+		_, err := pk.SetBytes(compressed[:])
+		require.NoError(t, err)
+		signs[compressed[0]&0x20 != 0] = true
+
+		witness.P[i] = sw_bls12381.NewG1Affine(pk)
+		for j := 0; j < 48; j++ {
+			witness.Expected[i][j] = uints.NewU8(compressed[j])
+		}
+	}
+	// also cover a negated key so both sign flags are exercised
+	var pk, neg bls12381.G1Affine
+	_, err = pk.SetBytes(update1104.Data.NextSyncCommittee.Pubkeys[0][:])
+	require.NoError(t, err)
+	neg.Neg(&pk)
+	negBytes := neg.Bytes()
+	witness.P[3] = sw_bls12381.NewG1Affine(neg)
+	for j := 0; j < 48; j++ {
+		witness.Expected[3][j] = uints.NewU8(negBytes[j])
+	}
+	signs[negBytes[0]&0x20 != 0] = true
+	require.Len(t, signs, 2, "both sign flags should be covered")
+
+	// the point at infinity is 0xc0 followed by zeros
+	var inf bls12381.G1Affine
+	inf.SetInfinity()
+	infBytes := inf.Bytes()
+	require.Equal(t, byte(0xc0), infBytes[0])
+	witness.P[4] = sw_bls12381.NewG1Affine(inf)
+	for j := 0; j < 48; j++ {
+		witness.Expected[4][j] = uints.NewU8(infBytes[j])
+	}
+
+	err = gnark_test.IsSolved(&g1CompressedCircuit{}, witness, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	// a wrong sign flag must not be accepted
+	witness.Expected[3][0] = uints.NewU8(negBytes[0] ^ 0x20)
+	err = gnark_test.IsSolved(&g1CompressedCircuit{}, witness, ecc.BN254.ScalarField())
+	require.Error(t, err)
+}
+
+// periodCircuit checks the Slot -> AttestedSlot and Period binding in isolation
+type periodCircuit struct {
+	Preset types.Preset `gnark:"-"`
+
+	Slot         frontend.Variable
+	Period       frontend.Variable `gnark:",public"`
+	AttestedSlot frontend.Variable `gnark:",public"`
+}
+
+func (c *periodCircuit) Define(api frontend.API) error {
+	sc := &Eth2ScUpdateCircuit{Params: CircuitParams{Preset: c.Preset}, Slot: c.Slot, Period: c.Period, AttestedSlot: c.AttestedSlot}
+	sc.verifyPeriod(api)
+	return nil
+}
+
+func TestEth2ScUpdateCircuit_Period(t *testing.T) {
+	slot := uint64(9052234) // attested slot of sc-update-1105.json
+	err := gnark_test.IsSolved(&periodCircuit{}, &periodCircuit{Slot: slot, Period: slot / 8192, AttestedSlot: slot}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	// period boundaries
+	err = gnark_test.IsSolved(&periodCircuit{}, &periodCircuit{Slot: 1105 * 8192, Period: 1105, AttestedSlot: 1105 * 8192}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	err = gnark_test.IsSolved(&periodCircuit{}, &periodCircuit{Slot: 1105*8192 - 1, Period: 1105, AttestedSlot: 1105*8192 - 1}, ecc.BN254.ScalarField())
+	require.Error(t, err)
+
+	// replaying an old period must not be provable
+	err = gnark_test.IsSolved(&periodCircuit{}, &periodCircuit{Slot: slot, Period: slot/8192 + 1, AttestedSlot: slot}, ecc.BN254.ScalarField())
+	require.Error(t, err)
+
+	// an older slot of the same period must not be provable either
+	err = gnark_test.IsSolved(&periodCircuit{}, &periodCircuit{Slot: slot, Period: slot / 8192, AttestedSlot: slot - 1}, ecc.BN254.ScalarField())
+	require.Error(t, err)
+}
+
+func TestEth2ScUpdateCircuit_MinimalPreset(t *testing.T) {
+	// minimal periods are 64 slots long
+	circuit := &periodCircuit{Preset: types.PresetMinimal}
+	err := gnark_test.IsSolved(circuit, &periodCircuit{Slot: 1105 * 64, Period: 1105, AttestedSlot: 1105 * 64}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	err = gnark_test.IsSolved(circuit, &periodCircuit{Slot: 1105*64 - 1, Period: 1105, AttestedSlot: 1105*64 - 1}, ecc.BN254.ScalarField())
+	require.Error(t, err)
+
+	// the committee is sized by the preset, and Define rejects a committee of another size
+	params := CircuitParams{Preset: types.PresetMinimal}
+	c := NewEth2ScUpdateCircuit(params)
+	require.Len(t, c.ScPubKeys, 32)
+	require.Len(t, c.ScBits, 32)
+	c.ScBits = make([]frontend.Variable, SyncCommitteeSize)
+	_, err = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, c)
+	require.Error(t, err)
+}
+
+func TestDefaultDomain(t *testing.T) {
+	// DOMAIN must be the sync committee domain of the fork parameters used by the test vectors
+	domain, err := types.ComputeDomain(domainType, forkVersion, genesisValidatorsRootBytes)
+	require.NoError(t, err)
+	require.Equal(t, DOMAIN, domain)
+}
+
+func TestNewNetworkCircuitParams(t *testing.T) {
+	params, err := NewNetworkCircuitParams(&types.NetworkSepolia, "fulu")
+	require.NoError(t, err)
+	require.Equal(t, DOMAIN, params.SigningDomain())
+	require.Equal(t, types.NextSyncCommitteeGIndexElectra, params.NextSyncCommitteeGIndex())
+	require.Equal(t, DOMAIN, CircuitParams{}.SigningDomain())
+
+	params, err = NewNetworkCircuitParams(&types.NetworkGnosis, "deneb")
+	require.NoError(t, err)
+	require.Equal(t, types.PresetGnosis, params.Preset)
+	require.Equal(t, types.NextSyncCommitteeGIndexAltair, params.NextSyncCommitteeGIndex())
+	require.NotEqual(t, DOMAIN, params.SigningDomain())
+
+	_, err = NewNetworkCircuitParams(&types.NetworkGnosis, "phase0")
+	require.Error(t, err)
+}
+
+// nextScProofCircuit checks the next_sync_committee Merkle proof in isolation
+type nextScProofCircuit struct {
+	Params       CircuitParams `gnark:"-"`
+	StateRoot    [32]uints.U8
+	NextScRoot   [32]uints.U8
+	NextScBranch [][32]uints.U8
+}
+
+func (c *nextScProofCircuit) Define(api frontend.API) error {
+	sc := NewEth2ScUpdateCircuit(c.Params)
+	sc.StateRoot, sc.NextScRoot, sc.NextScBranch = c.StateRoot, c.NextScRoot, c.NextScBranch
+	return sc.verifyNextSyncCommitteeMerkleProof(api)
+}
+
+func newNextScProofAssignment(params CircuitParams, stateRoot, leaf zrntcommon.Root, branch []zrntcommon.Root) *nextScProofCircuit {
+	c := &nextScProofCircuit{
+		Params:       params,
+		StateRoot:    [32]uints.U8(uints.NewU8Array(stateRoot[:])),
+		NextScRoot:   [32]uints.U8(uints.NewU8Array(leaf[:])),
+		NextScBranch: make([][32]uints.U8, len(branch)),
+	}
+	for i := range branch {
+		c.NextScBranch[i] = [32]uints.U8(uints.NewU8Array(branch[i][:]))
+	}
+	return c
+}
+
+func TestEth2ScUpdateCircuit_NextScGIndex(t *testing.T) {
+	// Electra/Fulu layout (default params) against the real update
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	electra := CircuitParams{}
+	leaf := update.Data.NextSyncCommittee.HashTreeRoot(configs.Mainnet, tree.GetHashFn())
+	stateRoot := update.Data.AttestedHeader.Beacon.StateRoot
+	circuit := &nextScProofCircuit{Params: electra, NextScBranch: make([][32]uints.U8, 6)}
+	err = gnark_test.IsSolved(circuit, newNextScProofAssignment(electra, stateRoot, leaf, update.Data.NextSyncCommitteeBranch), ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	// Altair .. Deneb layout: depth 5 branch at gindex 55
+	altair := CircuitParams{NextScGIndex: types.NextSyncCommitteeGIndexAltair}
+	branch := make([]zrntcommon.Root, 5)
+	for i := range branch {
+		branch[i][0] = byte(i + 1)
+	}
+	altairRoot, err := types.ComputeSSZBranchRoot(leaf, branch, types.NextSyncCommitteeGIndexAltair)
+	require.NoError(t, err)
+	circuit = &nextScProofCircuit{Params: altair, NextScBranch: make([][32]uints.U8, 5)}
+	err = gnark_test.IsSolved(circuit, newNextScProofAssignment(altair, altairRoot, leaf, branch), ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	// the branch must not verify under another gindex of the same depth (54 differs from 55 in the leaf bit)
+	wrong := CircuitParams{NextScGIndex: types.CurrentSyncCommitteeGIndexAltair}
+	circuit = &nextScProofCircuit{Params: wrong, NextScBranch: make([][32]uints.U8, 5)}
+	err = gnark_test.IsSolved(circuit, newNextScProofAssignment(wrong, altairRoot, leaf, branch), ecc.BN254.ScalarField())
+	require.Error(t, err)
+}
+
+// execProofCircuit checks the execution block hash and number Merkle proofs in isolation
+type execProofCircuit struct {
+	BodyRoot              [32]uints.U8
+	ExecBlockHash         [32]uints.U8
+	ExecBlockHashBranch   [ExecBranchDepth][32]uints.U8
+	ExecBlockNumber       frontend.Variable
+	ExecBlockNumberBranch [ExecBranchDepth][32]uints.U8
+}
+
+func (c *execProofCircuit) Define(api frontend.API) error {
+	sc := NewEth2ScUpdateCircuit(CircuitParams{})
+	sc.BodyRoot, sc.ExecBlockHash, sc.ExecBlockHashBranch = c.BodyRoot, c.ExecBlockHash, c.ExecBlockHashBranch
+	sc.ExecBlockNumber, sc.ExecBlockNumberBranch = c.ExecBlockNumber, c.ExecBlockNumberBranch
+	if err := sc.verifyExecBlockHashMerkleProof(api); err != nil {
+		return err
+	}
+	return sc.verifyExecBlockNumberMerkleProof(api)
+}
+
+func TestEth2ScUpdateCircuit_Execution(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	witness := NewEth2ScUpdateCircuit(CircuitParams{})
+	require.NoError(t, assignExecutionToWitness(&update, witness))
+	bodyRoot := update.Data.AttestedHeader.Beacon.BodyRoot
+	require.Equal(t, update.Data.AttestedHeader.Execution.BlockNumber, fmt.Sprint(witness.ExecBlockNumber))
+	assignment := &execProofCircuit{
+		BodyRoot:              [32]uints.U8(uints.NewU8Array(bodyRoot[:])),
+		ExecBlockHash:         witness.ExecBlockHash,
+		ExecBlockHashBranch:   witness.ExecBlockHashBranch,
+		ExecBlockNumber:       witness.ExecBlockNumber,
+		ExecBlockNumberBranch: witness.ExecBlockNumberBranch,
+	}
+	err = gnark_test.IsSolved(&execProofCircuit{}, assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	// another block number must not verify under the same body root
+	wrong := *assignment
+	wrong.ExecBlockNumber = witness.ExecBlockNumber.(uint64) + 1
+	err = gnark_test.IsSolved(&execProofCircuit{}, &wrong, ecc.BN254.ScalarField())
+	require.Error(t, err)
+
+	// another block hash must not verify under the same body root
+	wrong = *assignment
+	wrong.ExecBlockHash = [32]uints.U8(uints.NewU8Array(update.Data.AttestedHeader.Beacon.ParentRoot[:]))
+	err = gnark_test.IsSolved(&execProofCircuit{}, &wrong, ecc.BN254.ScalarField())
+	require.Error(t, err)
+}
+
+// pubKeysCheckCircuit checks the in-circuit validation of witnessed pubkeys in isolation
+type pubKeysCheckCircuit struct {
+	Check   PubKeyCheck `gnark:"-"`
+	PubKeys [2]sw_bls12381.G1Affine
+}
+
+func (c *pubKeysCheckCircuit) Define(api frontend.API) error {
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+	if err != nil {
+		return err
+	}
+	infinity := make([]frontend.Variable, len(c.PubKeys))
+	for i := range c.PubKeys {
+		infinity[i] = isInfinity(api, fp, &c.PubKeys[i])
+	}
+	return assertPubKeysOnG1(api, c.Check, c.PubKeys[:], infinity)
+}
+
+func TestEth2ScUpdateCircuit_ScPubKeysCheck(t *testing.T) {
+	update1104File, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update1104 types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(update1104File, &update1104))
+
+	var pk bls12381.G1Affine
+	_, err = pk.SetBytes(update1104.Data.NextSyncCommittee.Pubkeys[0][:])
+	require.NoError(t, err)
+
+	// a point on the curve but outside the prime order subgroup: y² = x³ + 4 for some x
+	var outside bls12381.G1Affine
+	var four fp_bls12381.Element
+	four.SetUint64(4)
+	for x := uint64(1); ; x++ {
+		var y2 fp_bls12381.Element
+		outside.X.SetUint64(x)
+		y2.Square(&outside.X).Mul(&y2, &outside.X).Add(&y2, &four)
+		if outside.Y.Sqrt(&y2) != nil && !outside.IsInSubGroup() {
+			break
+		}
+	}
+	require.True(t, outside.IsOnCurve())
+
+	offCurve := pk
+	offCurve.Y.Double(&offCurve.Y)
+	require.False(t, offCurve.IsOnCurve())
+
+	assign := func(p bls12381.G1Affine) *pubKeysCheckCircuit {
+		return &pubKeysCheckCircuit{PubKeys: [2]sw_bls12381.G1Affine{sw_bls12381.NewG1Affine(pk), sw_bls12381.NewG1Affine(p)}}
+	}
+
+	// the point at infinity is accepted by every check, it is the identity in the aggregation
+	var inf bls12381.G1Affine
+	inf.SetInfinity()
+
+	for _, tc := range []struct {
+		check             PubKeyCheck
+		outside, offCurve bool // whether the point is accepted
+	}{
+		{PubKeyCheckSubgroup, false, false},
+		{PubKeyCheckOnCurve, true, false},
+		{PubKeyCheckNone, true, true},
+	} {
+		err = gnark_test.IsSolved(&pubKeysCheckCircuit{Check: tc.check}, assign(pk), ecc.BN254.ScalarField())
+		require.NoError(t, err, tc.check.String())
+
+		err = gnark_test.IsSolved(&pubKeysCheckCircuit{Check: tc.check}, assign(inf), ecc.BN254.ScalarField())
+		require.NoError(t, err, tc.check.String())
+
+		err = gnark_test.IsSolved(&pubKeysCheckCircuit{Check: tc.check}, assign(outside), ecc.BN254.ScalarField())
+		require.Equal(t, tc.outside, err == nil, tc.check.String())
+
+		err = gnark_test.IsSolved(&pubKeysCheckCircuit{Check: tc.check}, assign(offCurve), ecc.BN254.ScalarField())
+		require.Equal(t, tc.offCurve, err == nil, tc.check.String())
+	}
+}
+
+// sigCheckCircuit checks the in-circuit validation of the witnessed signature in isolation
+type sigCheckCircuit struct {
+	Check SigCheck `gnark:"-"`
+	Sig   sw_bls12381.G2Affine
+}
+
+func (c *sigCheckCircuit) Define(api frontend.API) error {
+	return assertSignatureOnG2(api, c.Check, &c.Sig)
+}
+
+func TestEth2ScUpdateCircuit_SigCheck(t *testing.T) {
+	update1104File, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update1104 types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(update1104File, &update1104))
+
+	var sig bls12381.G2Affine
+	_, err = sig.SetBytes(update1104.Data.SyncAggregate.SyncCommitteeSignature[:])
+	require.NoError(t, err)
+
+	// a point on the twist but outside the prime order subgroup: y² = x³ + 4(1 + u) for some x
+	var outside bls12381.G2Affine
+	var b bls12381.E2
+	b.A0.SetUint64(4)
+	b.A1.SetUint64(4)
+	for x := uint64(1); ; x++ {
+		var y2 bls12381.E2
+		outside.X.A0.SetUint64(x)
+		y2.Square(&outside.X).Mul(&y2, &outside.X).Add(&y2, &b)
+		if y2.Legendre() == 1 {
+			outside.Y.Sqrt(&y2)
+			break
+		}
+	}
+	require.True(t, outside.IsOnCurve())
+	require.False(t, outside.IsInSubGroup())
+
+	// the signature moved by the non-subgroup point is still on the twist
+	var moved bls12381.G2Affine
+	moved.Add(&sig, &outside)
+	require.True(t, moved.IsOnCurve())
+	require.False(t, moved.IsInSubGroup())
+
+	offTwist := sig
+	offTwist.Y.Double(&offTwist.Y)
+	require.False(t, offTwist.IsOnCurve())
+
+	var inf bls12381.G2Affine
+
+	for _, tc := range []struct {
+		check               SigCheck
+		outside, moved, off bool // whether the point is accepted
+	}{
+		{SigCheckSubgroup, false, false, false},
+		{SigCheckOnTwist, true, true, false},
+	} {
+		circuit := &sigCheckCircuit{Check: tc.check}
+		err = gnark_test.IsSolved(circuit, &sigCheckCircuit{Sig: sw_bls12381.NewG2Affine(sig)}, ecc.BN254.ScalarField())
+		require.NoError(t, err, tc.check.String())
+
+		err = gnark_test.IsSolved(circuit, &sigCheckCircuit{Sig: sw_bls12381.NewG2Affine(outside)}, ecc.BN254.ScalarField())
+		require.Equal(t, tc.outside, err == nil, tc.check.String())
+
+		err = gnark_test.IsSolved(circuit, &sigCheckCircuit{Sig: sw_bls12381.NewG2Affine(moved)}, ecc.BN254.ScalarField())
+		require.Equal(t, tc.moved, err == nil, tc.check.String())
+
+		err = gnark_test.IsSolved(circuit, &sigCheckCircuit{Sig: sw_bls12381.NewG2Affine(offTwist)}, ecc.BN254.ScalarField())
+		require.Equal(t, tc.off, err == nil, tc.check.String())
+
+		// the point at infinity (0, 0) is rejected explicitly
+		err = gnark_test.IsSolved(circuit, &sigCheckCircuit{Sig: sw_bls12381.NewG2Affine(inf)}, ecc.BN254.ScalarField())
+		require.Error(t, err, tc.check.String())
+	}
+
+	for _, c := range []SigCheck{SigCheckSubgroup, SigCheckOnTwist} {
+		parsed, err := ParseSigCheck(c.String())
+		require.NoError(t, err)
+		require.Equal(t, c, parsed)
+	}
+	_, err = ParseSigCheck("none")
+	require.Error(t, err)
+}
+
+// aggregationCircuit checks aggregatePubKeys in isolation, on a minimal committee
+type aggregationCircuit struct {
+	PubKeys   []sw_bls12381.G1Affine
+	Bits      []frontend.Variable
+	Aggregate sw_bls12381.G1Affine `gnark:",public"`
+}
+
+func (c *aggregationCircuit) Define(api frontend.API) error {
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+	if err != nil {
+		return err
+	}
+	sc := &Eth2ScUpdateCircuit{Params: CircuitParams{Preset: types.PresetMinimal}, ScPubKeys: c.PubKeys, ScBits: c.Bits}
+	infinity := make([]frontend.Variable, len(c.PubKeys))
+	for i := range c.PubKeys {
+		infinity[i] = isInfinity(api, fp, &c.PubKeys[i])
+	}
+	aggregate, err := sc.aggregatePubKeys(api, infinity)
+	if err != nil {
+		return err
+	}
+	fp.AssertIsEqual(&aggregate.X, &c.Aggregate.X)
+	fp.AssertIsEqual(&aggregate.Y, &c.Aggregate.Y)
+	return nil
+}
+
+func TestEth2ScUpdateCircuit_AggregatePubKeys(t *testing.T) {
+	update1104File, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update1104 types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(update1104File, &update1104))
+	pubkeys := update1104.Data.NextSyncCommittee.Pubkeys[:32]
+
+	circuit := &aggregationCircuit{PubKeys: make([]sw_bls12381.G1Affine, 32), Bits: make([]frontend.Variable, 32)}
+	assign := func(bits []bool, infinity int) *aggregationCircuit {
+		w := &aggregationCircuit{PubKeys: make([]sw_bls12381.G1Affine, 32), Bits: make([]frontend.Variable, 32)}
+		for i := range pubkeys {
+			var pk bls12381.G1Affine
+			if i != infinity {
+				_, err := pk.SetBytes(pubkeys[i][:])
+				require.NoError(t, err)
+			}
+			w.PubKeys[i] = sw_bls12381.NewG1Affine(pk)
+			w.Bits[i] = 0
+			if bits[i] {
+				w.Bits[i] = 1
+			}
+		}
+		if infinity >= 0 {
+			bits = append([]bool{}, bits...)
+			bits[infinity] = false
+		}
+		// without participants the aggregate is the point at infinity, witnessed as (0, 0)
+		aggregate, _, _ := types.AggregatePublicKeys(pubkeys, bits)
+		w.Aggregate = sw_bls12381.NewG1Affine(aggregate)
+		return w
+	}
+
+	bits := make([]bool, 32)
+	for i := range bits {
+		bits[i] = i%5 != 3
+	}
+	require.NoError(t, gnark_test.IsSolved(circuit, assign(bits, -1), ecc.BN254.ScalarField()))
+
+	// a pubkey at infinity is skipped even if its bit is set
+	require.NoError(t, gnark_test.IsSolved(circuit, assign(bits, 0), ecc.BN254.ScalarField()))
+
+	// a single participant, the last one
+	single := make([]bool, 32)
+	single[31] = true
+	require.NoError(t, gnark_test.IsSolved(circuit, assign(single, -1), ecc.BN254.ScalarField()))
+
+	// the aggregate of other participants
+	wrong := assign(single, -1)
+	wrong.Bits[30] = 1
+	require.Error(t, gnark_test.IsSolved(circuit, wrong, ecc.BN254.ScalarField()))
+
+	// no participant, or only one at infinity
+	require.Error(t, gnark_test.IsSolved(circuit, assign(make([]bool, 32), -1), ecc.BN254.ScalarField()))
+	require.Error(t, gnark_test.IsSolved(circuit, assign(single, 31), ecc.BN254.ScalarField()))
+}
+
+// TestEth2ScUpdateCircuit_ScBitsBoolean checks that non-boolean bits are rejected by the compiled
+// constraint systems, not only by the test engine: a bit of 2 must not count a pubkey twice
+func TestEth2ScUpdateCircuit_ScBitsBoolean(t *testing.T) {
+	update1104File, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update1104 types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(update1104File, &update1104))
+	pubkeys := update1104.Data.NextSyncCommittee.Pubkeys[:32]
+
+	circuit := &aggregationCircuit{PubKeys: make([]sw_bls12381.G1Affine, 32), Bits: make([]frontend.Variable, 32)}
+	r1csCCS, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	require.NoError(t, err)
+	scsCCS, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, circuit)
+	require.NoError(t, err)
+
+	// bits[0] = bit, the other members participating, and the claimed aggregate counting
+	// member 0 weight times
+	assign := func(bit frontend.Variable, weight int64) *aggregationCircuit {
+		w := &aggregationCircuit{PubKeys: make([]sw_bls12381.G1Affine, 32), Bits: make([]frontend.Variable, 32)}
+		var aggregate bls12381.G1Affine
+		for i := range pubkeys {
+			var pk bls12381.G1Affine
+			_, err := pk.SetBytes(pubkeys[i][:])
+			require.NoError(t, err)
+			w.PubKeys[i] = sw_bls12381.NewG1Affine(pk)
+			w.Bits[i] = 1
+			if i == 0 {
+				w.Bits[i] = bit
+				pk.ScalarMultiplication(&pk, big.NewInt(weight))
+			}
+			aggregate.Add(&aggregate, &pk)
+		}
+		w.Aggregate = sw_bls12381.NewG1Affine(aggregate)
+		return w
+	}
+	solved := func(w *aggregationCircuit) []error {
+		full, err := frontend.NewWitness(w, ecc.BN254.ScalarField())
+		require.NoError(t, err)
+		return []error{
+			gnark_test.IsSolved(circuit, w, ecc.BN254.ScalarField()),
+			r1csCCS.IsSolved(full),
+			scsCCS.IsSolved(full),
+		}
+	}
+
+	for _, err := range solved(assign(1, 1)) {
+		require.NoError(t, err)
+	}
+	minusOne := new(big.Int).Sub(ecc.BN254.ScalarField(), big.NewInt(1))
+	for _, tc := range []struct {
+		bit    frontend.Variable
+		weight int64
+	}{
+		{2, 2}, {2, 1}, {2, 0}, {minusOne, 0}, {minusOne, 1},
+	} {
+		for i, err := range solved(assign(tc.bit, tc.weight)) {
+			require.Error(t, err, "bit %v weight %d system %d", tc.bit, tc.weight, i)
+		}
+	}
+}
+
+// TestEth2ScUpdateCircuit_ScBitsTwo checks that the whole circuit, on a minimal committee, rejects a bit
+// of 2 even when the signature counts that member twice
+func TestEth2ScUpdateCircuit_ScBitsTwo(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	params := CircuitParams{Preset: types.PresetMinimal, ScPubKeysCheck: PubKeyCheckNone}
+	n := params.SyncCommitteeSize()
+	_, _, g1, _ := bls12381.Generators()
+	secrets := make([]*big.Int, n)
+	pubkeys := make([]bls12381.G1Affine, n)
+	for i := range secrets {
+		secrets[i] = big.NewInt(int64(1000 + 7*i))
+		pubkeys[i].ScalarMultiplication(&g1, secrets[i])
+	}
+
+	// a state holding a next_sync_committee, and the execution payload of the real update
+	branch := make([]zrntcommon.Root, params.NextSyncCommitteeGIndex().Depth())
+	for i := range branch {
+		branch[i][0] = byte(i + 1)
+	}
+	nextScRoot := zrntcommon.Root{0x05}
+	stateRoot, err := types.ComputeSSZBranchRoot(nextScRoot, branch, params.NextSyncCommitteeGIndex())
+	require.NoError(t, err)
+	header := &zrntcommon.BeaconBlockHeader{
+		Slot:          1105*64 + 3,
+		ProposerIndex: 7,
+		ParentRoot:    zrntcommon.Root{0x01},
+		StateRoot:     stateRoot,
+		BodyRoot:      update.Data.AttestedHeader.Beacon.BodyRoot,
+	}
+	domain := params.SigningDomain()
+	signingRoot := zrntcommon.ComputeSigningRoot(header.HashTreeRoot(tree.GetHashFn()), zrntcommon.BLSDomain(domain))
+	message, err := bls12381.HashToG2(signingRoot[:], []byte(hash2curve.EthSignatureDST))
+	require.NoError(t, err)
+
+	// every member participating, member 0 with the given bit and signing weight times
+	assign := func(bit frontend.Variable, weight int64) *Eth2ScUpdateCircuit {
+		w := NewEth2ScUpdateCircuit(params)
+		w.Slot, w.AttestedSlot, w.Period = uint64(header.Slot), uint64(header.Slot), uint64(1105)
+		w.ProposerIndex = uint64(header.ProposerIndex)
+		w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+		w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+		w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+		w.Domain = [32]uints.U8(uints.NewU8Array(domain[:]))
+		sk := new(big.Int)
+		for i := range pubkeys {
+			w.ScPubKeys[i] = sw_bls12381.NewG1Affine(pubkeys[i])
+			w.ScBits[i] = 1
+			sk.Add(sk, secrets[i])
+		}
+		w.ScBits[0] = bit
+		sk.Add(sk, new(big.Int).Mul(secrets[0], big.NewInt(weight-1)))
+		var signature bls12381.G2Affine
+		signature.ScalarMultiplication(&message, sk)
+		w.AggregatedSig = sw_bls12381.NewG2Affine(signature)
+		hash := types.ComputeScPubKeysHashWithMode(pubkeys, params.ScPubKeysHashMode)
+		w.ScPubKeysHash = [32]uints.U8(uints.NewU8Array(hash[:]))
+		w.NextScRoot = [32]uints.U8(uints.NewU8Array(nextScRoot[:]))
+		for i := range branch {
+			w.NextScBranch[i] = [32]uints.U8(uints.NewU8Array(branch[i][:]))
+		}
+		require.NoError(t, assignExecutionToWitness(&update, w))
+		return w
+	}
+
+	circuit := NewEth2ScUpdateCircuit(params)
+	require.NoError(t, gnark_test.IsSolved(circuit, assign(1, 1), ecc.BN254.ScalarField()))
+	require.Error(t, gnark_test.IsSolved(circuit, assign(2, 2), ecc.BN254.ScalarField()))
+	require.Error(t, gnark_test.IsSolved(circuit, assign(2, 1), ecc.BN254.ScalarField()))
+}
@@ -0,0 +1,96 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Eth2StateFieldCircuit proves an arbitrary 32 bytes node of a BeaconState, e.g. a field root
+// (finalized_checkpoint, justification_bits, ...) or a node inside a field, so that new uses of the
+// state do not need a new circuit.
+//
+// GIndex is expected to be pinned by the consumer for the field it reads: the same compiled circuit and
+// proving key serve every field of depth at most Params.BranchDepth().
+//
+// This circuit verifies Leaf is the node at GIndex in StateRoot via an SSZ Merkle proof whose path
+// follows the bits of GIndex.
+type Eth2StateFieldCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params StateFieldParams `gnark:"-"`
+
+	// Merkle branch of the node (private input), Params.BranchDepth() long: the GIndex.Depth() siblings
+	// of its path, zero padded
+	Branch [][32]uints.U8
+
+	// Public inputs
+	StateRoot [32]uints.U8      `gnark:",public"` // root of the BeaconState
+	GIndex    frontend.Variable `gnark:",public"` // generalized index of the node in the BeaconState
+	Leaf      [32]uints.U8      `gnark:",public"` // proven node
+}
+
+// StateFieldParams holds the compile-time parameters of Eth2StateFieldCircuit
+type StateFieldParams struct {
+	// MaxDepth is the maximum depth of the proven nodes. Zero means 24, enough for the fields of the
+	// BeaconState (depth 6) and the containers and vectors below them, e.g. a block_roots entry (depth 19).
+	MaxDepth int
+}
+
+// BranchDepth returns MaxDepth, defaulting to 24
+func (p StateFieldParams) BranchDepth() int {
+	if p.MaxDepth == 0 {
+		return 24
+	}
+	return p.MaxDepth
+}
+
+// NewEth2StateFieldCircuit allocates a circuit (or witness) for the given params
+func NewEth2StateFieldCircuit(params StateFieldParams) *Eth2StateFieldCircuit {
+	return &Eth2StateFieldCircuit{
+		Params: params,
+		Branch: make([][32]uints.U8, params.BranchDepth()),
+	}
+}
+
+// NewEth2StateFieldAssignment builds the witness proving proof.Leaf at proof.GIndex in the state of stateRoot,
+// proof being generated e.g. by types.BeaconStateProof.
+func NewEth2StateFieldAssignment(params StateFieldParams, stateRoot zrntcommon.Root, proof *types.SSZProof) (*Eth2StateFieldCircuit, error) {
+	if depth := proof.GIndex.Depth(); depth < 1 || depth > params.BranchDepth() {
+		return nil, fmt.Errorf("gindex %v is not 1 to %d levels deep", proof.GIndex, params.BranchDepth())
+	}
+	if !proof.Verify(stateRoot) {
+		return nil, fmt.Errorf("proof of gindex %v does not match state root %v", proof.GIndex, stateRoot)
+	}
+
+	w := NewEth2StateFieldCircuit(params)
+	for i := range w.Branch {
+		var node zrntcommon.Root
+		if i < len(proof.Branch) {
+			node = proof.Branch[i]
+		}
+		w.Branch[i] = [32]uints.U8(uints.NewU8Array(node[:]))
+	}
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(stateRoot[:]))
+	w.GIndex = uint64(proof.GIndex)
+	w.Leaf = [32]uints.U8(uints.NewU8Array(proof.Leaf[:]))
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2StateFieldCircuit) Define(api frontend.API) error {
+	if len(c.Branch) != c.Params.BranchDepth() {
+		return fmt.Errorf("branch length %d does not match depth %d", len(c.Branch), c.Params.BranchDepth())
+	}
+
+	// GIndex has at most BranchDepth()+1 bits and is at least 2, the path follows its bits
+	root, err := gadgets.SSZBranchRootGIndex(api, c.Leaf, c.Branch, c.GIndex)
+	if err != nil {
+		return err
+	}
+	gadgets.AssertChunksEqual(api, root, c.StateRoot)
+	return nil
+}
@@ -0,0 +1,57 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2StateFieldCircuit(t *testing.T) {
+	params := StateFieldParams{MaxDepth: 20}
+	require.Equal(t, 24, StateFieldParams{}.BranchDepth())
+	circuit := NewEth2StateFieldCircuit(params)
+
+	// block_roots[4321] is 13 levels below block_roots
+	blockRootGIndex, err := types.BlockRootsGIndexElectra.Child(blockRootsDepth, 4321)
+	require.NoError(t, err)
+	leaf := zrntcommon.Root{0xaa, 0xbb}
+	for _, gindex := range []types.GIndex{types.FinalizedRootGIndexElectra, types.NextSyncCommitteeGIndexElectra, blockRootGIndex, 2, 3} {
+		branch := make([]zrntcommon.Root, gindex.Depth())
+		for i := range branch {
+			branch[i][31] = byte(i + 1)
+		}
+		stateRoot, err := types.ComputeSSZBranchRoot(leaf, branch, gindex)
+		require.NoError(t, err)
+		proof := &types.SSZProof{GIndex: gindex, Leaf: leaf, Branch: branch}
+
+		witness, err := NewEth2StateFieldAssignment(params, stateRoot, proof)
+		require.NoError(t, err, gindex)
+		require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), gindex)
+
+		// the padding past the depth of the gindex is ignored
+		witness.Branch[len(witness.Branch)-1] = [32]uints.U8(uints.NewU8Array(branch[0][:]))
+		require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), gindex)
+
+		// another node of the same depth
+		witness.GIndex = uint64(gindex ^ 1)
+		require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), gindex)
+		// the same position one level up or down
+		witness.GIndex = uint64(gindex / 2)
+		require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), gindex)
+		witness.GIndex = uint64(gindex * 2)
+		require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), gindex)
+	}
+
+	// a proof of another state, the root itself and nodes deeper than the branch are not provable
+	_, err = NewEth2StateFieldAssignment(params, leaf, &types.SSZProof{GIndex: 2, Leaf: leaf, Branch: []zrntcommon.Root{{}}})
+	require.Error(t, err)
+	_, err = NewEth2StateFieldAssignment(params, leaf, &types.SSZProof{GIndex: 1, Leaf: leaf})
+	require.Error(t, err)
+	_, err = NewEth2StateFieldAssignment(params, leaf, &types.SSZProof{GIndex: 1 << 21, Leaf: leaf, Branch: make([]zrntcommon.Root, 21)})
+	require.Error(t, err)
+}
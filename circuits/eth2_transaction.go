@@ -0,0 +1,337 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/selector"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// DefaultMaxTxBytes is the default bound of the transactions of Eth2TransactionCircuit
+const DefaultMaxTxBytes = 1024
+
+// txHeadBytes is the length of the head of a transaction holding its fields up to the selector: the type,
+// the list prefix (3), chainId, nonce and gas (9 each), two fees (33 each), to (21), value (33), the
+// prefix of data (3) and its first 4 bytes
+const txHeadBytes = 1 + 3 + 3*9 + 2*33 + 21 + 33 + 3 + 4
+
+// Eth2TransactionCircuit proves a call made by a transaction of a beacon block: the to-address, the value
+// and the 4-byte selector of the transaction, read from the execution_payload.transactions of the block,
+// so that a destination chain can react to specific calls (e.g. deposits into a bridge contract) without
+// decoding transactions on-chain.
+//
+// This circuit:
+// 1. Computes the root of the BeaconBlockHeader and requires it to be HeaderRoot
+// 2. Computes hash_tree_root(Transaction) of the zero padded Transaction bytes, a ByteList of
+// MAX_BYTES_PER_TRANSACTION, and verifies it is the element TxIndex of execution_payload.transactions in
+// the BodyRoot of the header via an SSZ Merkle proof
+// 3. Decodes the legacy rlp([nonce, gasPrice, gas, to, value, data, ...]) or typed (EIP-2930, EIP-1559,
+// EIP-4844 and EIP-7702) type || rlp([chainId, nonce, ..., gas, to, value, data, ...]) transaction, and
+// exposes To, Value and Selector
+//
+// Contract creations, which have no to-address, and transactions of more than Params.MaxTxBytes bytes
+// are not provable. Selector is the first 4 bytes of data, zero padded when data is shorter.
+type Eth2TransactionCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params TransactionProofParams `gnark:"-"`
+
+	// BeaconBlockHeader fields of the trusted header (private inputs)
+	Slot          frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Merkle branch of the transaction in the BeaconBlockBody (private input): the
+	// types.TransactionsListDepth levels of the list, its length, then the levels of transactions in the body
+	TransactionBranch [][32]uints.U8
+
+	// The transaction (private inputs), zero padded to Params.TxBytes() bytes
+	Transaction    []uints.U8
+	TransactionLen frontend.Variable
+
+	// Public inputs
+	HeaderRoot [32]uints.U8      `gnark:",public"` // root of the trusted header
+	TxIndex    frontend.Variable `gnark:",public"` // index of the transaction in the block
+	To         [20]uints.U8      `gnark:",public"` // address called by the transaction
+	Value      [32]uints.U8      `gnark:",public"` // wei sent, as a big-endian uint256
+	Selector   [4]uints.U8       `gnark:",public"` // first 4 bytes of the call data
+}
+
+// TransactionProofParams holds the compile-time parameters of Eth2TransactionCircuit
+type TransactionProofParams struct {
+	// TransactionsGIndex is the generalized index of transactions in the ExecutionPayload of the
+	// target fork, see types.ExecutionTransactionsGIndexForFork. Zero means Deneb .. Fulu (45).
+	TransactionsGIndex types.GIndex
+	// MaxTxBytes bounds the length of the transaction, a power of two of at least 256 bytes.
+	// Zero means DefaultMaxTxBytes.
+	MaxTxBytes int
+
+	// Preset fixes the spec the transaction is hashed with, the zero value being mainnet
+	Preset types.Preset
+}
+
+// NewTransactionProofParams returns the params of the given fork
+func NewTransactionProofParams(fork string) (TransactionProofParams, error) {
+	gindex, err := types.ExecutionTransactionsGIndexForFork(fork)
+	if err != nil {
+		return TransactionProofParams{}, err
+	}
+	return TransactionProofParams{TransactionsGIndex: gindex}, nil
+}
+
+// PayloadTransactionsGIndex returns TransactionsGIndex, defaulting to the Deneb .. Fulu layout
+func (p TransactionProofParams) PayloadTransactionsGIndex() types.GIndex {
+	if p.TransactionsGIndex == 0 {
+		return types.ExecutionTransactionsGIndex
+	}
+	return p.TransactionsGIndex
+}
+
+// TxBytes returns MaxTxBytes, defaulting to DefaultMaxTxBytes
+func (p TransactionProofParams) TxBytes() int {
+	if p.MaxTxBytes == 0 {
+		return DefaultMaxTxBytes
+	}
+	return p.MaxTxBytes
+}
+
+// BodyTransactionsGIndex returns the generalized index of execution_payload.transactions in the BeaconBlockBody
+func (p TransactionProofParams) BodyTransactionsGIndex() types.GIndex {
+	return types.GIndex(uint64(types.ExecutionPayloadGIndex)<<uint(p.PayloadTransactionsGIndex().Depth()) |
+		p.PayloadTransactionsGIndex().Index())
+}
+
+// BodyTransactionGIndex returns the generalized index of the root of transaction txIndex in the BeaconBlockBody
+func (p TransactionProofParams) BodyTransactionGIndex(txIndex uint64) (types.GIndex, error) {
+	return types.TransactionBodyGIndex(p.PayloadTransactionsGIndex(), txIndex)
+}
+
+// BranchDepth returns the length of the TransactionBranch
+func (p TransactionProofParams) BranchDepth() int {
+	return types.TransactionsListDepth + 1 + p.BodyTransactionsGIndex().Depth()
+}
+
+func (p TransactionProofParams) validate() error {
+	n := p.TxBytes()
+	if n < 256 || n&(n-1) != 0 || n > 1<<16 {
+		return fmt.Errorf("max transaction bytes %d is not a power of two between 256 and 65536", n)
+	}
+	return nil
+}
+
+// NewEth2TransactionCircuit allocates a circuit (or witness) for the given params
+func NewEth2TransactionCircuit(params TransactionProofParams) *Eth2TransactionCircuit {
+	return &Eth2TransactionCircuit{
+		Params:            params,
+		TransactionBranch: make([][32]uints.U8, params.BranchDepth()),
+		Transaction:       make([]uints.U8, params.TxBytes()),
+	}
+}
+
+// NewEth2TransactionAssignment builds the witness proving transaction txIndex of the block of header, given
+// its bytes (the opaque typed or legacy encoding of the execution payload) and the proof of its root
+// (proof.Leaf) against header.BodyRoot at params.BodyTransactionGIndex(txIndex), e.g. generated by
+// types.BeaconBlockBodyProof.
+func NewEth2TransactionAssignment(params TransactionProofParams, header *zrntcommon.BeaconBlockHeader, proof *types.SSZProof, tx []byte, txIndex int) (*Eth2TransactionCircuit, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	w := NewEth2TransactionCircuit(params)
+	if txIndex < 0 || txIndex >= 1<<types.TransactionsListDepth {
+		return nil, fmt.Errorf("transaction index %d out of range", txIndex)
+	}
+	if len(tx) > len(w.Transaction) {
+		return nil, fmt.Errorf("transaction of %d bytes, the circuit is bounded to %d", len(tx), len(w.Transaction))
+	}
+	if len(proof.Branch) != len(w.TransactionBranch) {
+		return nil, fmt.Errorf("branch length %d does not match depth %d", len(proof.Branch), len(w.TransactionBranch))
+	}
+	gindex, err := params.BodyTransactionGIndex(uint64(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	if root := zrntcommon.Transaction(tx).HashTreeRoot(params.Preset.Spec(), tree.GetHashFn()); root != proof.Leaf {
+		return nil, fmt.Errorf("transaction root is %v, the proof is of %v", root, proof.Leaf)
+	}
+	if !types.VerifySSZBranch(header.BodyRoot, proof.Leaf, proof.Branch, gindex) {
+		return nil, fmt.Errorf("transaction branch does not match body root %v", header.BodyRoot)
+	}
+
+	var decoded gethtypes.Transaction
+	if err := decoded.UnmarshalBinary(tx); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %d: %w", txIndex, err)
+	}
+	if decoded.To() == nil {
+		return nil, fmt.Errorf("transaction %d is a contract creation", txIndex)
+	}
+	if decoded.Value().BitLen() > 256 {
+		return nil, fmt.Errorf("transaction %d value does not fit in 256 bits", txIndex)
+	}
+
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	w.Slot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	for i := range proof.Branch {
+		w.TransactionBranch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+	padded := make([]byte, len(w.Transaction))
+	copy(padded, tx)
+	w.Transaction = uints.NewU8Array(padded)
+	w.TransactionLen = len(tx)
+
+	var value [32]byte
+	decoded.Value().FillBytes(value[:])
+	var selector [4]byte
+	copy(selector[:], decoded.Data())
+	w.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	w.TxIndex = txIndex
+	w.To = [20]uints.U8(uints.NewU8Array(decoded.To().Bytes()))
+	w.Value = [32]uints.U8(uints.NewU8Array(value[:]))
+	w.Selector = [4]uints.U8(uints.NewU8Array(selector[:]))
+	return w, nil
+}
+
+// Define implements the circuit constraints
+func (c *Eth2TransactionCircuit) Define(api frontend.API) error {
+	if err := c.Params.validate(); err != nil {
+		return err
+	}
+	if len(c.TransactionBranch) != c.Params.BranchDepth() {
+		return fmt.Errorf("branch length %d does not match depth %d", len(c.TransactionBranch), c.Params.BranchDepth())
+	}
+	if len(c.Transaction) != c.Params.TxBytes() {
+		return fmt.Errorf("transaction of %d bytes, expected %d", len(c.Transaction), c.Params.TxBytes())
+	}
+
+	// Step 1: the trusted header
+	sc := &Eth2ScUpdateCircuit{
+		Slot:          c.Slot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+	}
+	headerRoot := sc.computeBlockRoot(api)
+	gadgets.AssertChunksEqual(api, headerRoot, c.HeaderRoot)
+
+	// Step 2: hash_tree_root(Transaction), the bytes past TransactionLen being the zero padding of the
+	// chunks, and its branch to the body along TxIndex
+	txBytes := make([]frontend.Variable, len(c.Transaction))
+	var inTx frontend.Variable = 1
+	for i := range c.Transaction {
+		txBytes[i] = c.Transaction[i].Val
+		inTx = api.Sub(inTx, api.IsZero(api.Sub(c.TransactionLen, i)))
+		api.AssertIsEqual(api.Mul(api.Sub(1, inTx), txBytes[i]), 0)
+	}
+	api.AssertIsLessOrEqual(c.TransactionLen, len(c.Transaction))
+	txRoot, err := c.transactionRoot(api, sc)
+	if err != nil {
+		return err
+	}
+
+	path := make([]frontend.Variable, 0, len(c.TransactionBranch))
+	for _, bit := range api.ToBinary(c.TxIndex, types.TransactionsListDepth) {
+		path = append(path, bit)
+	}
+	path = append(path, 0) // the data tree, left of the length of the list
+	for _, bit := range c.Params.BodyTransactionsGIndex().PathBits() {
+		path = append(path, bit)
+	}
+	bodyRoot, err := gadgets.SSZBranchRootAt(api, txRoot, c.TransactionBranch, path)
+	if err != nil {
+		return fmt.Errorf("transaction branch: %w", err)
+	}
+	gadgets.AssertChunksEqual(api, bodyRoot, c.BodyRoot)
+
+	// Step 3: decode the fields up to the selector, all within the head of the transaction
+	head := shiftBytes(api, txBytes, 0, txHeadBytes)
+	byteAt := func(off frontend.Variable) frontend.Variable {
+		return selector.Mux(api, off, head...)
+	}
+	// the type byte of a typed transaction (1 to 4), or the list prefix of a legacy one
+	typeBits := api.ToBinary(head[0], 8)
+	typed := api.Sub(1, typeBits[7])
+	txType := api.Mul(typed, head[0])
+	api.AssertIsEqual(api.Mul(typed, api.IsZero(txType)), 0)
+	api.AssertIsLessOrEqual(txType, 4)
+	list := decodeRLPList(api, byteAt, typed)
+	api.AssertIsEqual(list.valid, 1)
+	api.AssertIsEqual(list.end, c.TransactionLen)
+
+	// skip the 3 (legacy), 4 (EIP-2930) or 5 (EIP-1559 and later) integers before to
+	isAccessList := api.IsZero(api.Sub(txType, 1))
+	skip := []frontend.Variable{1, 1, 1, typed, api.Sub(typed, isAccessList)}
+	off := list.off
+	for _, in := range skip {
+		item := decodeRLPString(api, byteAt, off)
+		api.AssertIsEqual(api.Mul(in, api.Sub(item.valid, 1)), 0)
+		off = api.Select(in, item.end, off)
+	}
+
+	// to is 0x94 followed by the 20 bytes of the address, contract creations (0x80) are rejected
+	to := decodeRLPString(api, byteAt, off)
+	api.AssertIsEqual(to.valid, 1)
+	api.AssertIsEqual(to.len, 20)
+	for i, b := range shiftBytes(api, head, to.off, 20) {
+		api.AssertIsEqual(c.To[i].Val, b)
+	}
+
+	// value is a big endian integer of at most 32 bytes, right aligned by shifting it behind 32 zeros
+	value := decodeRLPString(api, byteAt, to.end)
+	api.AssertIsEqual(value.valid, 1)
+	api.AssertIsLessOrEqual(value.len, 32)
+	window := make([]frontend.Variable, 64)
+	for i := 0; i < 32; i++ {
+		window[i] = 0
+	}
+	copy(window[32:], shiftBytes(api, head, value.off, 32))
+	for i, b := range shiftBytes(api, window, value.len, 32) {
+		api.AssertIsEqual(c.Value[i].Val, b)
+	}
+
+	// the selector is the first 4 bytes of data, zero past its end
+	data := decodeRLPString(api, byteAt, value.end)
+	api.AssertIsEqual(data.valid, 1)
+	var inData frontend.Variable = 1
+	for i, b := range shiftBytes(api, head, data.off, 4) {
+		inData = api.Sub(inData, api.IsZero(api.Sub(data.len, i)))
+		api.AssertIsEqual(c.Selector[i].Val, api.Mul(inData, b))
+	}
+	return nil
+}
+
+// transactionRoot returns hash_tree_root(Transaction): the merkleization of the chunks of the transaction,
+// completed with zero subtrees up to the 2**25 chunks of MAX_BYTES_PER_TRANSACTION and mixed in with its length
+func (c *Eth2TransactionCircuit) transactionRoot(api frontend.API, sc *Eth2ScUpdateCircuit) ([32]uints.U8, error) {
+	chunks := make([][32]uints.U8, len(c.Transaction)/32)
+	for i := range chunks {
+		chunks[i] = [32]uints.U8(c.Transaction[32*i : 32*(i+1)])
+	}
+	root, err := gadgets.Merkleize(api, chunks)
+	if err != nil {
+		return root, err
+	}
+	h, err := gadgets.NewPairHasher(api)
+	if err != nil {
+		return root, err
+	}
+	depth := 0
+	for 1<<depth < len(chunks) {
+		depth++
+	}
+	for ; depth < types.TransactionChunksDepth; depth++ {
+		zero := tree.ZeroHashes[depth]
+		root = h.Hash(root, [32]uints.U8(uints.NewU8Array(zero[:])))
+	}
+	return h.Hash(root, sc.serializeUint64ToChunk(api, c.TransactionLen)), nil
+}
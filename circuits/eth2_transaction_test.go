@@ -0,0 +1,120 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2TransactionCircuit(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := gethtypes.LatestSignerForChainID(big.NewInt(1))
+	bridge := gethcommon.HexToAddress("0x00000000219ab540356cbb839cbe05303d7705fa")
+	deposit := append([]byte{0x22, 0x89, 0x51, 0x18}, make([]byte, 64)...)
+	bigValue := new(big.Int).Lsh(big.NewInt(3), 200)
+
+	var txs [][]byte
+	for _, data := range []gethtypes.TxData{
+		&gethtypes.LegacyTx{Nonce: 7, GasPrice: big.NewInt(30e9), Gas: 21000, To: &bridge, Value: big.NewInt(1e18)},
+		&gethtypes.AccessListTx{ChainID: big.NewInt(1), Nonce: 0, GasPrice: big.NewInt(1), Gas: 50000, To: &bridge, Data: []byte{0xab, 0xcd},
+			AccessList: gethtypes.AccessList{{Address: bridge, StorageKeys: []gethcommon.Hash{{0x01}}}}},
+		&gethtypes.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 300, GasTipCap: big.NewInt(2e9), GasFeeCap: big.NewInt(80e9), Gas: 100000, To: &bridge, Value: bigValue, Data: deposit},
+		&gethtypes.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 301, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 1000000, Data: deposit},
+	} {
+		tx, err := gethtypes.SignNewTx(key, signer, data)
+		require.NoError(t, err)
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		txs = append(txs, raw)
+	}
+
+	spec := configs.Mainnet
+	var block electra.BeaconBlock
+	block.Slot = 12345678
+	block.ProposerIndex = 4321
+	block.ParentRoot = zrntcommon.Root{0x01}
+	block.StateRoot = zrntcommon.Root{0x02}
+	block.Body.SyncAggregate.SyncCommitteeBits = make(altair.SyncCommitteeBits, spec.SYNC_COMMITTEE_SIZE/8)
+	for _, raw := range txs {
+		block.Body.ExecutionPayload.Transactions = append(block.Body.ExecutionPayload.Transactions, raw)
+	}
+	header := block.Header(spec)
+
+	params, err := NewTransactionProofParams("fulu")
+	require.NoError(t, err)
+	params.MaxTxBytes = 256
+	require.Equal(t, types.GIndex(813), params.BodyTransactionsGIndex())
+	require.Equal(t, 30, params.BranchDepth())
+	circuit := NewEth2TransactionCircuit(params)
+
+	proofs := make([]*types.SSZProof, len(txs))
+	for i := range txs {
+		gindex, err := params.BodyTransactionGIndex(uint64(i))
+		require.NoError(t, err)
+		proofs[i], err = types.BeaconBlockBodyProof(spec, &block.Body, gindex)
+		require.NoError(t, err)
+	}
+
+	// a legacy transfer, an EIP-2930 call with 2 bytes of data and an EIP-1559 deposit
+	for i, want := range []struct {
+		value    *big.Int
+		selector [4]byte
+	}{{big.NewInt(1e18), [4]byte{}}, {big.NewInt(0), [4]byte{0xab, 0xcd}}, {bigValue, [4]byte{0x22, 0x89, 0x51, 0x18}}} {
+		witness, err := NewEth2TransactionAssignment(params, header, proofs[i], txs[i], i)
+		require.NoError(t, err)
+		var value [32]byte
+		want.value.FillBytes(value[:])
+		require.Equal(t, uints.NewU8Array(value[:]), witness.Value[:])
+		require.Equal(t, uints.NewU8Array(want.selector[:]), witness.Selector[:])
+		require.Equal(t, uints.NewU8Array(bridge[:]), witness.To[:])
+		require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()), i)
+	}
+
+	// contract creations have no to-address
+	_, err = NewEth2TransactionAssignment(params, header, proofs[3], txs[3], 3)
+	require.Error(t, err)
+	// the proof must be the one of the transaction
+	_, err = NewEth2TransactionAssignment(params, header, proofs[1], txs[2], 2)
+	require.Error(t, err)
+	_, err = NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 1)
+	require.Error(t, err)
+	_, err = NewEth2TransactionAssignment(TransactionProofParams{MaxTxBytes: 128}, header, proofs[2], txs[2], 2)
+	require.Error(t, err)
+
+	witness, err := NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 2)
+	require.NoError(t, err)
+	// another selector, value or address is not the one of the transaction
+	witness.Selector[3] = uints.NewU8(0x19)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness, _ = NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 2)
+	witness.Value[31] = uints.NewU8(1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness, _ = NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 2)
+	witness.To[0] = uints.NewU8(0xff)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	// nor claimed for another transaction
+	witness, _ = NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 2)
+	witness.TxIndex = 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	// the bytes past the transaction are zero
+	witness, _ = NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 2)
+	witness.TransactionLen = len(txs[2]) + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	// another header does not have the trusted root
+	witness, _ = NewEth2TransactionAssignment(params, header, proofs[2], txs[2], 2)
+	witness.Slot = uint64(header.Slot) + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+}
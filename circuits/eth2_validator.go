@@ -0,0 +1,224 @@
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// SlotsPerEpochLog2 is log2(SLOTS_PER_EPOCH) of mainnet, ValidatorParams.Preset sets the one of the circuit
+const SlotsPerEpochLog2 = 5
+
+// Eth2ValidatorCircuit proves the balances and the status of a validator from the validators and
+// balances lists of the BeaconState of a trusted header, e.g. for restaking protocols or oracles.
+//
+// This circuit:
+// 1. Computes the root of the BeaconBlockHeader and requires it to be HeaderRoot
+// 2. Computes hash_tree_root of the Validator and verifies it is validators[ValidatorIndex] in StateRoot
+// 3. Verifies Balance is balances[ValidatorIndex] in StateRoot, 4 balances being packed in each chunk
+// 4. Derives Active (activation_epoch <= epoch < exit_epoch) and Exited (exit_epoch <= epoch) at the epoch of the header
+type Eth2ValidatorCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params ValidatorParams `gnark:"-"`
+
+	// BeaconBlockHeader fields of the trusted header (private inputs)
+	HeaderSlot    frontend.Variable // uint64
+	ProposerIndex frontend.Variable // uint64
+	ParentRoot    [32]uints.U8      // bytes32
+	StateRoot     [32]uints.U8      // bytes32
+	BodyRoot      [32]uints.U8      // bytes32
+
+	// Validator fields which are not public (private inputs)
+	WithdrawalCredentials      [32]uints.U8
+	ActivationEligibilityEpoch frontend.Variable
+	ActivationEpoch            frontend.Variable
+	ExitEpoch                  frontend.Variable
+	WithdrawableEpoch          frontend.Variable
+
+	// Merkle branches in the BeaconState (private inputs): 40 levels in the validators list data (38 in
+	// the balances one), the length mix-in and Params.StateValidatorsGIndex().Depth() state levels
+	ValidatorBranch [][32]uints.U8
+	BalanceChunk    [32]uints.U8 // chunk of the 4 balances holding balances[ValidatorIndex]
+	BalanceBranch   [][32]uints.U8
+
+	// Public inputs
+	HeaderRoot       [32]uints.U8      `gnark:",public"` // root of the trusted header
+	ValidatorIndex   frontend.Variable `gnark:",public"`
+	PubKey           [48]uints.U8      `gnark:",public"` // compressed BLS pubkey of the validator
+	EffectiveBalance frontend.Variable `gnark:",public"` // Gwei
+	Balance          frontend.Variable `gnark:",public"` // Gwei
+	Slashed          frontend.Variable `gnark:",public"` // 0 or 1
+	Active           frontend.Variable `gnark:",public"` // 0 or 1, at the epoch of the header
+	Exited           frontend.Variable `gnark:",public"` // 0 or 1, at the epoch of the header
+}
+
+// ValidatorParams holds the compile-time parameters of Eth2ValidatorCircuit
+type ValidatorParams struct {
+	// ValidatorsGIndex is the generalized index of validators in the BeaconState of the target fork,
+	// balances being the next field. Zero means Electra/Fulu (75).
+	ValidatorsGIndex types.GIndex
+
+	// Preset fixes the slots per epoch, the zero value being mainnet
+	Preset types.Preset
+}
+
+// StateValidatorsGIndex returns ValidatorsGIndex, defaulting to the Electra/Fulu layout
+func (p ValidatorParams) StateValidatorsGIndex() types.GIndex {
+	if p.ValidatorsGIndex == 0 {
+		return types.ValidatorsGIndexElectra
+	}
+	return p.ValidatorsGIndex
+}
+
+// NewEth2ValidatorCircuit allocates a circuit (or witness) for the given params
+func NewEth2ValidatorCircuit(params ValidatorParams) *Eth2ValidatorCircuit {
+	stateDepth := params.StateValidatorsGIndex().Depth()
+	return &Eth2ValidatorCircuit{
+		Params:          params,
+		ValidatorBranch: make([][32]uints.U8, types.ValidatorRegistryDepth+1+stateDepth),
+		BalanceBranch:   make([][32]uints.U8, types.BalancesDepth+1+stateDepth),
+	}
+}
+
+// NewEth2ValidatorAssignment builds the witness of the validator of proof in the state of header,
+// proof being generated by types.BeaconStateValidatorProof.
+func NewEth2ValidatorAssignment(params ValidatorParams, header *zrntcommon.BeaconBlockHeader, proof *types.ValidatorProof) (*Eth2ValidatorCircuit, error) {
+	w := NewEth2ValidatorCircuit(params)
+	if len(proof.ValidatorProof.Branch) != len(w.ValidatorBranch) || len(proof.BalanceProof.Branch) != len(w.BalanceBranch) {
+		return nil, fmt.Errorf("branch lengths %d and %d do not match depths %d and %d",
+			len(proof.ValidatorProof.Branch), len(proof.BalanceProof.Branch), len(w.ValidatorBranch), len(w.BalanceBranch))
+	}
+	if err := proof.Verify(header.StateRoot, params.StateValidatorsGIndex()); err != nil {
+		return nil, err
+	}
+
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	w.HeaderSlot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+
+	v := &proof.Validator
+	w.WithdrawalCredentials = [32]uints.U8(uints.NewU8Array(v.WithdrawalCredentials[:]))
+	w.ActivationEligibilityEpoch = uint64(v.ActivationEligibilityEpoch)
+	w.ActivationEpoch = uint64(v.ActivationEpoch)
+	w.ExitEpoch = uint64(v.ExitEpoch)
+	w.WithdrawableEpoch = uint64(v.WithdrawableEpoch)
+	for i := range w.ValidatorBranch {
+		w.ValidatorBranch[i] = [32]uints.U8(uints.NewU8Array(proof.ValidatorProof.Branch[i][:]))
+	}
+	w.BalanceChunk = [32]uints.U8(uints.NewU8Array(proof.BalanceProof.Leaf[:]))
+	for i := range w.BalanceBranch {
+		w.BalanceBranch[i] = [32]uints.U8(uints.NewU8Array(proof.BalanceProof.Branch[i][:]))
+	}
+
+	epoch := zrntcommon.Epoch(params.Preset.Epoch(uint64(header.Slot)))
+	w.HeaderRoot = [32]uints.U8(uints.NewU8Array(headerRoot[:]))
+	w.ValidatorIndex = proof.Index
+	w.PubKey = [48]uints.U8(uints.NewU8Array(v.Pubkey[:]))
+	w.EffectiveBalance = uint64(v.EffectiveBalance)
+	w.Balance = proof.Balance
+	w.Slashed = boolToVar(v.Slashed)
+	w.Active = boolToVar(v.ActivationEpoch <= epoch && epoch < v.ExitEpoch)
+	w.Exited = boolToVar(v.ExitEpoch <= epoch)
+	return w, nil
+}
+
+func boolToVar(b bool) frontend.Variable {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Define implements the circuit constraints
+func (c *Eth2ValidatorCircuit) Define(api frontend.API) error {
+	// balances is the field right after validators, at the same depth
+	validatorsGIndex := c.Params.StateValidatorsGIndex()
+	stateDepth := validatorsGIndex.Depth()
+	if len(c.ValidatorBranch) != types.ValidatorRegistryDepth+1+stateDepth {
+		return fmt.Errorf("validator branch length %d does not match depth %d", len(c.ValidatorBranch), types.ValidatorRegistryDepth+1+stateDepth)
+	}
+	if len(c.BalanceBranch) != types.BalancesDepth+1+stateDepth {
+		return fmt.Errorf("balance branch length %d does not match depth %d", len(c.BalanceBranch), types.BalancesDepth+1+stateDepth)
+	}
+	bytesAPI, err := uints.NewBytes(api)
+	if err != nil {
+		return fmt.Errorf("new bytes: %w", err)
+	}
+
+	// Step 1: the trusted header
+	sc := &Eth2ScUpdateCircuit{
+		Slot:          c.HeaderSlot,
+		ProposerIndex: c.ProposerIndex,
+		ParentRoot:    c.ParentRoot,
+		StateRoot:     c.StateRoot,
+		BodyRoot:      c.BodyRoot,
+	}
+	headerRoot := sc.computeBlockRoot(api)
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(headerRoot[i].Val, c.HeaderRoot[i].Val)
+	}
+
+	// Step 2: hash_tree_root(Validator), 8 fields, the 48 bytes pubkey taking 2 chunks
+	api.AssertIsBoolean(c.Slashed)
+	var pubKeyHigh [32]uints.U8
+	copy(pubKeyHigh[:16], c.PubKey[32:])
+	for i := 16; i < 32; i++ {
+		pubKeyHigh[i] = uints.NewU8(0)
+	}
+	h01 := sc.hashPair(api, sc.hashPair(api, [32]uints.U8(c.PubKey[:32]), pubKeyHigh), c.WithdrawalCredentials)
+	h23 := sc.hashPair(api, sc.serializeUint64ToChunk(api, c.EffectiveBalance), sc.serializeUint64ToChunk(api, c.Slashed))
+	h45 := sc.hashPair(api, sc.serializeUint64ToChunk(api, c.ActivationEligibilityEpoch), sc.serializeUint64ToChunk(api, c.ActivationEpoch))
+	h67 := sc.hashPair(api, sc.serializeUint64ToChunk(api, c.ExitEpoch), sc.serializeUint64ToChunk(api, c.WithdrawableEpoch))
+	validatorRoot := sc.hashPair(api, sc.hashPair(api, h01, h23), sc.hashPair(api, h45, h67))
+
+	// the validator follows its index in the list data, which is left of the length mix-in
+	indexBits := api.ToBinary(c.ValidatorIndex, types.ValidatorRegistryDepth)
+	listDepth := types.ValidatorRegistryDepth + 1
+	current, err := gadgets.SSZBranchRootAt(api, validatorRoot, c.ValidatorBranch[:listDepth], append(indexBits, 0))
+	if err != nil {
+		return err
+	}
+	if err := gadgets.VerifySSZBranch(api, current, c.ValidatorBranch[listDepth:], validatorsGIndex, c.StateRoot); err != nil {
+		return err
+	}
+
+	// Step 3: balances[ValidatorIndex] is at byte 8 * (ValidatorIndex % 4) of the chunk ValidatorIndex / 4
+	var balance frontend.Variable = 0
+	for k := 7; k >= 0; k-- {
+		lo := bytesAPI.Select(indexBits[0], c.BalanceChunk[8+k], c.BalanceChunk[k])
+		hi := bytesAPI.Select(indexBits[0], c.BalanceChunk[24+k], c.BalanceChunk[16+k])
+		balance = api.Add(api.Mul(balance, 256), bytesAPI.Select(indexBits[1], hi, lo).Val)
+	}
+	api.AssertIsEqual(balance, c.Balance)
+	listDepth = types.BalancesDepth + 1
+	if current, err = gadgets.SSZBranchRootAt(api, c.BalanceChunk, c.BalanceBranch[:listDepth], append(indexBits[2:], 0)); err != nil {
+		return err
+	}
+	if err := gadgets.VerifySSZBranch(api, current, c.BalanceBranch[listDepth:], validatorsGIndex+1, c.StateRoot); err != nil {
+		return err
+	}
+
+	// Step 4: the status at the epoch of the header, the epochs are 64 bits from their chunks
+	headerSlotBits := api.ToBinary(c.HeaderSlot, 64)
+	epoch := api.FromBinary(headerSlotBits[c.Params.Preset.SlotsPerEpochLog2():]...)
+	exited := isLessOrEqual64(api, c.ExitEpoch, epoch)
+	activated := isLessOrEqual64(api, c.ActivationEpoch, epoch)
+	api.AssertIsEqual(c.Exited, exited)
+	api.AssertIsEqual(c.Active, api.Mul(activated, api.Sub(1, exited)))
+	return nil
+}
+
+// isLessOrEqual64 returns 1 if a <= b and 0 otherwise, a and b being less than 2^64
+func isLessOrEqual64(api frontend.API, a, b frontend.Variable) frontend.Variable {
+	bits := api.ToBinary(api.Sub(api.Add(b, new(big.Int).Lsh(big.NewInt(1), 64)), a), 65)
+	return bits[64]
+}
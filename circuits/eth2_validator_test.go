@@ -0,0 +1,85 @@
+package circuit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEth2ValidatorCircuit(t *testing.T) {
+	spec := configs.Mainnet
+	state, err := electra.AsBeaconStateView(electra.BeaconStateType(spec).New(), nil)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		pubkey := zrntcommon.BLSPubkey{0x80, byte(i)}
+		require.NoError(t, state.AddValidator(spec, pubkey, zrntcommon.Root{0x01, byte(i)}, zrntcommon.Gwei(32_000_000_000+i)))
+	}
+	validators, err := state.Validators()
+	require.NoError(t, err)
+	validator, err := validators.Validator(6)
+	require.NoError(t, err)
+	require.NoError(t, validator.SetActivationEpoch(1000))
+	require.NoError(t, validator.SetExitEpoch(2000))
+	var stateSSZ bytes.Buffer
+	require.NoError(t, state.Serialize(codec.NewEncodingWriter(&stateSSZ)))
+
+	proof, err := types.BeaconStateValidatorProof(spec, stateSSZ.Bytes(), 6)
+	require.NoError(t, err)
+	header := &zrntcommon.BeaconBlockHeader{
+		Slot:          1500 * 32,
+		ProposerIndex: 12345,
+		ParentRoot:    zrntcommon.Root{0x01},
+		StateRoot:     state.HashTreeRoot(tree.GetHashFn()),
+		BodyRoot:      zrntcommon.Root{0x02},
+	}
+
+	params := ValidatorParams{}
+	circuit := NewEth2ValidatorCircuit(params)
+	witness, err := NewEth2ValidatorAssignment(params, header, proof)
+	require.NoError(t, err)
+	require.Equal(t, 1, witness.Active)
+	require.Equal(t, 0, witness.Exited)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the balance is the third one of its chunk
+	witness.Balance = uint64(32_000_000_006 + 1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness.Balance = proof.Balance
+	witness.Exited = 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// at the exit epoch the validator is exited
+	header.Slot = 2000 * 32
+	witness, err = NewEth2ValidatorAssignment(params, header, proof)
+	require.NoError(t, err)
+	require.Equal(t, 0, witness.Active)
+	require.Equal(t, 1, witness.Exited)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// with the 16 slots epochs of gnosis, the same slot is past the exit epoch
+	gnosis := ValidatorParams{Preset: types.PresetGnosis}
+	header.Slot = 2000*16 - 1
+	witness, err = NewEth2ValidatorAssignment(gnosis, header, proof)
+	require.NoError(t, err)
+	require.Equal(t, 1, witness.Active)
+	require.NoError(t, gnark_test.IsSolved(NewEth2ValidatorCircuit(gnosis), witness, ecc.BN254.ScalarField()))
+	header.Slot = 2000 * 16
+	witness, err = NewEth2ValidatorAssignment(gnosis, header, proof)
+	require.NoError(t, err)
+	require.Equal(t, 1, witness.Exited)
+	require.NoError(t, gnark_test.IsSolved(NewEth2ValidatorCircuit(gnosis), witness, ecc.BN254.ScalarField()))
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	header.StateRoot = zrntcommon.Root{0x03}
+	_, err = NewEth2ValidatorAssignment(params, header, proof)
+	require.Error(t, err)
+}
@@ -0,0 +1,360 @@
+package circuit
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/selector"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// maxTxIndexBits bounds the transaction index, and so rlp(txIndex) to 3 bytes
+const maxTxIndexBits = 16
+
+// EthEventProofCircuit proves that the receipt of a transaction holds a log emitted by Address with
+// Topic0 as its first topic, i.e. that event Topic0 was emitted by contract Address in that transaction.
+//
+// This circuit:
+// 1. Verifies keccak256(Header) is BlockHash and reads the receiptsRoot of the header
+// 2. Verifies ReceiptProof is the path of rlp(TxIndex) in the receipts trie
+// 3. Walks the logs of the receipt up to the log at LogIndex
+// 4. Requires that log to have Address as its address and Topic0 as its first topic
+// 5. Exposes keccak256 of the log data as DataHash
+type EthEventProofCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params EventProofParams `gnark:"-"`
+
+	// RLP encoded execution block header, zero padded to Params.HeaderBytes() (private inputs)
+	Header    []uints.U8
+	HeaderLen frontend.Variable
+
+	// Proof of the receipt in the receipts trie, and the index of the matching log (private inputs)
+	ReceiptProof MPTProof
+	LogIndex     frontend.Variable
+
+	// Public inputs
+	BlockHash [32]uints.U8      `gnark:",public"` // execution block hash
+	TxIndex   frontend.Variable `gnark:",public"` // index of the transaction in the block
+	Address   [20]uints.U8      `gnark:",public"` // contract that emitted the log
+	Topic0    [32]uints.U8      `gnark:",public"` // event signature
+	DataHash  [32]uints.U8      `gnark:",public"` // keccak256 of the log data
+}
+
+// EventProofParams holds the compile-time parameters of EthEventProofCircuit.
+// Zero values select defaults.
+type EventProofParams struct {
+	// MaxHeaderBytes is the maximum RLP length of the block header (default 1024)
+	MaxHeaderBytes int
+	// Receipt bounds the receipt proof (default 6 nodes, receipts of up to 2048 bytes)
+	Receipt MPTParams
+	// MaxLogs is the number of logs of the receipt that can be walked (default 16)
+	MaxLogs int
+	// MaxLogDataBytes is the maximum length of the data of the matching log (default 256)
+	MaxLogDataBytes int
+}
+
+// HeaderBytes returns MaxHeaderBytes, defaulting to 1024
+func (p EventProofParams) HeaderBytes() int {
+	if p.MaxHeaderBytes == 0 {
+		return 1024
+	}
+	return p.MaxHeaderBytes
+}
+
+// ReceiptParams returns the bounds of the receipt proof. The leaf holds the consensus encoding of
+// the receipt, so the node bound follows the value bound.
+func (p EventProofParams) ReceiptParams() MPTParams {
+	r := p.Receipt
+	if r.MaxDepth == 0 {
+		r.MaxDepth = 6
+	}
+	if r.MaxValueBytes == 0 {
+		r.MaxValueBytes = 2048
+	}
+	if r.MaxNodeBytes == 0 {
+		r.MaxNodeBytes = max(532, r.MaxValueBytes+mptMaxValueOffset)
+	}
+	return r
+}
+
+// LogsBound returns MaxLogs, defaulting to 16
+func (p EventProofParams) LogsBound() int {
+	if p.MaxLogs == 0 {
+		return 16
+	}
+	return p.MaxLogs
+}
+
+// LogDataBytes returns MaxLogDataBytes, defaulting to 256
+func (p EventProofParams) LogDataBytes() int {
+	if p.MaxLogDataBytes == 0 {
+		return 256
+	}
+	return p.MaxLogDataBytes
+}
+
+// NewEthEventProofCircuit allocates a circuit (or witness) for the given params
+func NewEthEventProofCircuit(params EventProofParams) *EthEventProofCircuit {
+	return &EthEventProofCircuit{
+		Params:       params,
+		Header:       make([]uints.U8, params.HeaderBytes()),
+		ReceiptProof: NewMPTProof(params.ReceiptParams()),
+	}
+}
+
+// NewEthEventProofAssignment assigns the circuit for the log logIndex of the receipt of transaction
+// txIndex, given the RLP encoded header and all the receipts of the block.
+func NewEthEventProofAssignment(params EventProofParams, headerRLP []byte, receipts gethtypes.Receipts, txIndex, logIndex int) (*EthEventProofCircuit, error) {
+	if len(headerRLP) > params.HeaderBytes() {
+		return nil, fmt.Errorf("header has %d bytes, at most %d are supported", len(headerRLP), params.HeaderBytes())
+	}
+	if txIndex < 0 || txIndex >= len(receipts) || txIndex >= 1<<maxTxIndexBits {
+		return nil, fmt.Errorf("transaction %d not in block (%d receipts)", txIndex, len(receipts))
+	}
+	logs := receipts[txIndex].Logs
+	if logIndex < 0 || logIndex >= len(logs) || logIndex >= params.LogsBound() {
+		return nil, fmt.Errorf("log %d not provable (%d logs, at most %d are walked)", logIndex, len(logs), params.LogsBound())
+	}
+	log := logs[logIndex]
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("log %d has no topic", logIndex)
+	}
+	if len(log.Data) > params.LogDataBytes() {
+		return nil, fmt.Errorf("log %d has %d bytes of data, at most %d are supported", logIndex, len(log.Data), params.LogDataBytes())
+	}
+
+	nodes, err := ReceiptProof(receipts, txIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewEthEventProofCircuit(params)
+	header := make([]byte, params.HeaderBytes())
+	copy(header, headerRLP)
+	c.Header = uints.NewU8Array(header)
+	c.HeaderLen = len(headerRLP)
+	if c.ReceiptProof, err = AssignMPTProof(params.ReceiptParams(), nodes); err != nil {
+		return nil, fmt.Errorf("receipt proof: %w", err)
+	}
+	c.LogIndex = logIndex
+
+	c.BlockHash = [32]uints.U8(uints.NewU8Array(crypto.Keccak256(headerRLP)))
+	c.TxIndex = txIndex
+	c.Address = [20]uints.U8(uints.NewU8Array(log.Address[:]))
+	c.Topic0 = [32]uints.U8(uints.NewU8Array(log.Topics[0][:]))
+	c.DataHash = [32]uints.U8(uints.NewU8Array(crypto.Keccak256(log.Data)))
+	return c, nil
+}
+
+// mptProofWriter collects the nodes written by trie.Prove, from the root down to the leaf
+type mptProofWriter [][]byte
+
+func (w *mptProofWriter) Put(_ []byte, value []byte) error {
+	*w = append(*w, bytes.Clone(value))
+	return nil
+}
+
+func (w *mptProofWriter) Delete([]byte) error {
+	return nil
+}
+
+// ReceiptProof rebuilds the receipts trie of a block and returns the proof of the receipt of
+// transaction txIndex, as verified by EthEventProofCircuit
+func ReceiptProof(receipts gethtypes.Receipts, txIndex int) ([][]byte, error) {
+	receiptsTrie := trie.NewEmpty(nil)
+	for i, receipt := range receipts {
+		key, err := rlp.EncodeToBytes(uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		value, err := receipt.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode receipt %d: %w", i, err)
+		}
+		if err := receiptsTrie.Update(key, value); err != nil {
+			return nil, err
+		}
+	}
+	key, err := rlp.EncodeToBytes(uint64(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	var nodes mptProofWriter
+	if err := receiptsTrie.Prove(key, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to prove receipt %d: %w", txIndex, err)
+	}
+	return nodes, nil
+}
+
+// Define implements the circuit constraints
+func (c *EthEventProofCircuit) Define(api frontend.API) error {
+	if len(c.Header) != c.Params.HeaderBytes() {
+		return fmt.Errorf("header length %d does not match %d", len(c.Header), c.Params.HeaderBytes())
+	}
+
+	// Step 1: the header hashes to BlockHash and commits to the receipts root
+	receiptsRoot, err := verifyExecutionHeader(api, c.Header, c.HeaderLen, c.BlockHash, headerReceiptsRootOffset)
+	if err != nil {
+		return err
+	}
+
+	// Step 2: the receipt is in the receipts trie, at key rlp(TxIndex)
+	key, keyLen := rlpTxIndex(api, c.TxIndex)
+	receipt, receiptLen, err := verifyMPTProof(api, c.Params.ReceiptParams(), receiptsRoot, key, keyLen, &c.ReceiptProof)
+	if err != nil {
+		return fmt.Errorf("receipt proof: %w", err)
+	}
+	receipt = append(receipt, make([]frontend.Variable, mptNodeSlack)...)
+	for k := len(receipt) - mptNodeSlack; k < len(receipt); k++ {
+		receipt[k] = 0
+	}
+	byteAt := func(off frontend.Variable) frontend.Variable {
+		return selector.Mux(api, off, receipt...)
+	}
+
+	// Step 3: [type ||] rlp([status, cumulativeGasUsed, logsBloom, logs]), the logs being walked
+	// from the start of the list so that the selected offset is the start of a log
+	typed := api.Sub(1, api.ToBinary(receipt[0], 8)[7])
+	list := decodeRLPList(api, byteAt, typed)
+	api.AssertIsEqual(list.valid, 1)
+	api.AssertIsEqual(list.end, receiptLen)
+	off := list.off
+	for i := 0; i < 3; i++ {
+		item := decodeRLPString(api, byteAt, off)
+		api.AssertIsEqual(item.valid, 1)
+		off = item.end
+	}
+	logs := decodeRLPList(api, byteAt, off)
+	api.AssertIsEqual(logs.valid, 1)
+	api.AssertIsEqual(logs.end, list.end)
+
+	// inLogs[i] is 1 if log i exists, the walk must end exactly at the end of the list
+	logOffs := make([]frontend.Variable, c.Params.LogsBound())
+	inLogs := make([]frontend.Variable, c.Params.LogsBound())
+	off = logs.off
+	for i := range logOffs {
+		inLogs[i] = api.Sub(1, api.IsZero(api.Sub(off, logs.end)))
+		if i > 0 {
+			inLogs[i] = api.Mul(inLogs[i], inLogs[i-1])
+		}
+		logOffs[i] = off
+		log := decodeRLPList(api, byteAt, off)
+		api.AssertIsEqual(api.Mul(inLogs[i], api.Sub(1, log.valid)), 0)
+		off = log.end
+	}
+	logOff := selector.Mux(api, c.LogIndex, logOffs...)
+	api.AssertIsEqual(selector.Mux(api, c.LogIndex, inLogs...), 1)
+
+	// Step 4: [address, topics, data] with address == Address and topics[0] == Topic0
+	log := decodeRLPList(api, byteAt, logOff)
+	topics := decodeRLPList(api, byteAt, api.Add(log.off, 21))
+	api.AssertIsEqual(topics.valid, 1)
+	logBytes := shiftBytes(api, receipt, log.off, 1+20+3+33+3)
+	api.AssertIsEqual(logBytes[0], 0x94)
+	for i := 0; i < 20; i++ {
+		api.AssertIsEqual(logBytes[1+i], c.Address[i].Val)
+	}
+	api.AssertIsLessOrEqual(33, topics.len)
+	topic0 := selector.Mux(api, api.Sub(topics.off, log.off), logBytes...)
+	api.AssertIsEqual(topic0, 0xa0)
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(selector.Mux(api, api.Add(api.Sub(topics.off, log.off), 1+i), logBytes...), c.Topic0[i].Val)
+	}
+
+	// Step 5: DataHash is the hash of the data of the log
+	data := decodeRLPString(api, byteAt, topics.end)
+	api.AssertIsEqual(data.valid, 1)
+	api.AssertIsEqual(data.end, log.end)
+	api.AssertIsLessOrEqual(data.len, c.Params.LogDataBytes())
+	dataBytes := shiftBytes(api, receipt, data.off, c.Params.LogDataBytes())
+	dataU8 := make([]uints.U8, len(dataBytes))
+	var inData frontend.Variable = 1
+	for i := range dataBytes {
+		inData = api.Sub(inData, api.IsZero(api.Sub(data.len, i)))
+		dataU8[i] = uints.U8{Val: api.Mul(inData, dataBytes[i])}
+	}
+	dataHash, err := keccak256Sum(api, dataU8, data.len)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(dataHash[i].Val, c.DataHash[i].Val)
+	}
+	return nil
+}
+
+// rlpTxIndex returns rlp(txIndex), the key of a transaction or receipt trie, zero padded to 3 bytes
+// with its length: 0x80 for 0, a single byte below 128, then 0x81 b and 0x82 b b.
+func rlpTxIndex(api frontend.API, txIndex frontend.Variable) ([]frontend.Variable, frontend.Variable) {
+	bits := api.ToBinary(txIndex, maxTxIndexBits)
+	hi := api.FromBinary(bits[8:]...)
+	lo := api.FromBinary(bits[:8]...)
+	isZero := api.IsZero(txIndex)
+	below256 := api.IsZero(hi)
+	below128 := api.Mul(below256, api.Sub(1, bits[7]))
+	oneByte := api.Sub(below256, below128) // 128 <= txIndex < 256
+
+	key := []frontend.Variable{
+		api.Select(isZero, 0x80, api.Select(below128, lo, api.Select(below256, 0x81, 0x82))),
+		api.Select(below128, 0, api.Select(oneByte, lo, hi)),
+		api.Select(below256, 0, lo),
+	}
+	keyLen := api.Select(below128, 1, api.Select(below256, 2, 3))
+	return key, keyLen
+}
+
+// decodeRLPList decodes the prefix of the RLP list at off: 0xc0+len, 0xf8 len or 0xf9 len len.
+// Other items are not valid.
+func decodeRLPList(api frontend.API, byteAt func(frontend.Variable) frontend.Variable, off frontend.Variable) rlpItem {
+	p := byteAt(off)
+	bits := api.ToBinary(p, 8)
+	isF8 := api.IsZero(api.Sub(p, 0xf8))
+	isF9 := api.IsZero(api.Sub(p, 0xf9))
+	isShort := api.Mul(api.And(bits[7], bits[6]), api.Sub(1, api.And(api.And(bits[5], bits[4]), bits[3])))
+	len1 := byteAt(api.Add(off, 1))
+	len2 := byteAt(api.Add(off, 2))
+
+	item := rlpItem{
+		off:   api.Add(off, isShort, api.Mul(isF8, 2), api.Mul(isF9, 3)),
+		len:   api.Add(api.Mul(isShort, api.Sub(p, 0xc0)), api.Mul(isF8, len1), api.Mul(isF9, api.Add(api.Mul(len1, 256), len2))),
+		valid: api.Add(isShort, isF8, isF9),
+	}
+	item.end = api.Add(item.off, item.len)
+	return item
+}
+
+// shiftBytes returns the n bytes of data starting at off (zero past the end of data), with one
+// select per byte and bit of off instead of one multiplexer over data per byte.
+func shiftBytes(api frontend.API, data []frontend.Variable, off frontend.Variable, n int) []frontend.Variable {
+	nbBits := 1
+	for 1<<nbBits < len(data) {
+		nbBits++
+	}
+	bits := api.ToBinary(off, nbBits)
+	shifted := make([]frontend.Variable, len(data)+n)
+	for k := range shifted {
+		if k < len(data) {
+			shifted[k] = data[k]
+		} else {
+			shifted[k] = 0
+		}
+	}
+	for l := 0; l < nbBits; l++ {
+		step := 1 << l
+		next := make([]frontend.Variable, len(shifted))
+		for k := range next {
+			if k+step < len(shifted) {
+				next[k] = api.Select(bits[l], shifted[k+step], shifted[k])
+			} else {
+				next[k] = api.Select(bits[l], 0, shifted[k])
+			}
+		}
+		shifted = next
+	}
+	return shifted[:n]
+}
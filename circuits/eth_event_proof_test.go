@@ -0,0 +1,83 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReceipts returns the receipts of a block of 130 transactions, the one at index 129 (rlp key
+// 0x81 0x81) emitting a Transfer event after an unrelated log, and the header committing to them.
+func newTestReceipts(t *testing.T) ([]byte, gethtypes.Receipts) {
+	token := gethcommon.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	receipts := make(gethtypes.Receipts, 130)
+	for i := range receipts {
+		receipts[i] = &gethtypes.Receipt{
+			Type:              gethtypes.DynamicFeeTxType,
+			Status:            gethtypes.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(21_000 * (i + 1)),
+		}
+	}
+	receipts[0].Type = gethtypes.LegacyTxType
+	receipts[129].Logs = []*gethtypes.Log{
+		{Address: gethcommon.HexToAddress("0x01"), Topics: []gethcommon.Hash{crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))}},
+		{
+			Address: token,
+			Topics:  []gethcommon.Hash{transfer, gethcommon.HexToHash("0x02"), gethcommon.HexToHash("0x03")},
+			Data:    gethcommon.LeftPadBytes(big.NewInt(1_000_000).Bytes(), 32),
+		},
+	}
+	for _, receipt := range receipts {
+		receipt.Bloom = gethtypes.CreateBloom(receipt)
+	}
+
+	header, err := rlp.EncodeToBytes(&gethtypes.Header{
+		ReceiptHash: gethtypes.DeriveSha(receipts, trie.NewStackTrie(nil)),
+		Difficulty:  big.NewInt(0),
+		Number:      big.NewInt(21_000_000),
+		GasLimit:    36_000_000,
+		Time:        1_760_000_000,
+		BaseFee:     big.NewInt(1_000_000_000),
+	})
+	require.NoError(t, err)
+	return header, receipts
+}
+
+func TestEthEventProofCircuit(t *testing.T) {
+	header, receipts := newTestReceipts(t)
+	params := EventProofParams{
+		MaxHeaderBytes:  640,
+		Receipt:         MPTParams{MaxDepth: 5, MaxValueBytes: 512},
+		MaxLogs:         4,
+		MaxLogDataBytes: 64,
+	}
+	circuit := NewEthEventProofCircuit(params)
+
+	witness, err := NewEthEventProofAssignment(params, header, receipts, 129, 1)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the first log has another emitter and topic
+	witness.LogIndex = 0
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// another event of the same contract
+	witness.LogIndex = 1
+	witness.Topic0[0] = uints.NewU8(witness.Topic0[0].Val.(uint8) ^ 1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// no log past the end of the receipt
+	_, err = NewEthEventProofAssignment(params, header, receipts, 129, 2)
+	require.Error(t, err)
+}
@@ -0,0 +1,30 @@
+package circuit
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+)
+
+// Offsets of the roots in the RLP encoding of an execution block header, whose list prefix is always
+// 0xf9 len len: parentHash (0xa0 + 32), ommersHash (0xa0 + 32), coinbase (0x94 + 20), then stateRoot,
+// transactionsRoot and receiptsRoot (0xa0 + 32 each). The offsets are those of the 0xa0 prefixes.
+const (
+	headerStateRootOffset    = 90
+	headerReceiptsRootOffset = 156
+)
+
+// verifyExecutionHeader requires keccak256 of the first headerLen bytes of header to be blockHash,
+// and returns the 32-byte root whose 0xa0 prefix is at rootOffset.
+func verifyExecutionHeader(api frontend.API, header []uints.U8, headerLen frontend.Variable, blockHash [32]uints.U8, rootOffset int) ([32]uints.U8, error) {
+	headerHash, err := keccak256Sum(api, header, headerLen)
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(headerHash[i].Val, blockHash[i].Val)
+	}
+	api.AssertIsEqual(header[0].Val, 0xf9)
+	api.AssertIsEqual(api.Add(api.Mul(header[1].Val, 256), header[2].Val, 3), headerLen)
+	api.AssertIsEqual(header[rootOffset].Val, 0xa0)
+	return [32]uints.U8(header[rootOffset+1 : rootOffset+33]), nil
+}
@@ -0,0 +1,248 @@
+package circuit
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/uints"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// EthReceiptBatchCircuit proves that the receipts of up to Params.BatchSize() transactions are in
+// the receipts trie of an execution block, and commits to them with ReceiptsCommitment, so that a
+// bridge relaying many events of a block verifies one proof and then cheap Merkle branches.
+//
+// The leaf of receipt i is keccak256(uint256(TxIndexes[i]) || keccak256(receipt)), and
+// ReceiptsCommitment is the keccak256 Merkle root of the BatchSize() leaves, the slots past Count
+// holding zero leaves (see ReceiptBatchRoot).
+//
+// This circuit:
+// 1. Verifies keccak256(Header) is BlockHash and reads the receiptsRoot of the header
+// 2. Verifies ReceiptProofs[i] is the path of rlp(TxIndexes[i]) in the receipts trie, for every slot
+// 3. Computes the leaves, zero past Count, and requires their Merkle root to be ReceiptsCommitment
+//
+// The slots past Count still carry a valid proof, the assignment repeats the last proven receipt.
+type EthReceiptBatchCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params ReceiptBatchParams `gnark:"-"`
+
+	// RLP encoded execution block header, zero padded to Params.HeaderBytes() (private inputs)
+	Header    []uints.U8
+	HeaderLen frontend.Variable
+
+	// Proofs of the receipts in the receipts trie with their transaction indexes (private inputs)
+	ReceiptProofs []MPTProof
+	TxIndexes     []frontend.Variable
+
+	// Public inputs
+	BlockHash          [32]uints.U8      `gnark:",public"` // execution block hash
+	Count              frontend.Variable `gnark:",public"` // number of proven receipts, 1 to Params.BatchSize()
+	ReceiptsCommitment [32]uints.U8      `gnark:",public"` // Merkle root of the leaves of the proven receipts
+}
+
+// ReceiptBatchParams holds the compile-time parameters of EthReceiptBatchCircuit.
+// Zero values select defaults.
+type ReceiptBatchParams struct {
+	// MaxHeaderBytes is the maximum RLP length of the block header (default 1024)
+	MaxHeaderBytes int
+	// Receipt bounds each receipt proof (default 6 nodes, receipts of up to 2048 bytes)
+	Receipt MPTParams
+	// MaxReceipts is the number of receipts of a batch, a power of two (default 8)
+	MaxReceipts int
+}
+
+// HeaderBytes returns MaxHeaderBytes, defaulting to 1024
+func (p ReceiptBatchParams) HeaderBytes() int {
+	return EventProofParams{MaxHeaderBytes: p.MaxHeaderBytes}.HeaderBytes()
+}
+
+// ReceiptParams returns the bounds of a receipt proof, with the defaults of EventProofParams
+func (p ReceiptBatchParams) ReceiptParams() MPTParams {
+	return EventProofParams{Receipt: p.Receipt}.ReceiptParams()
+}
+
+// BatchSize returns MaxReceipts, defaulting to 8
+func (p ReceiptBatchParams) BatchSize() int {
+	if p.MaxReceipts == 0 {
+		return 8
+	}
+	return p.MaxReceipts
+}
+
+// NewEthReceiptBatchCircuit allocates a circuit (or witness) for the given params
+func NewEthReceiptBatchCircuit(params ReceiptBatchParams) *EthReceiptBatchCircuit {
+	c := &EthReceiptBatchCircuit{
+		Params:        params,
+		Header:        make([]uints.U8, params.HeaderBytes()),
+		ReceiptProofs: make([]MPTProof, params.BatchSize()),
+		TxIndexes:     make([]frontend.Variable, params.BatchSize()),
+	}
+	for i := range c.ReceiptProofs {
+		c.ReceiptProofs[i] = NewMPTProof(params.ReceiptParams())
+	}
+	return c
+}
+
+// NewEthReceiptBatchAssignment assigns the circuit for the receipts of transactions txIndexes, given
+// the RLP encoded header and all the receipts of the block.
+func NewEthReceiptBatchAssignment(params ReceiptBatchParams, headerRLP []byte, receipts gethtypes.Receipts, txIndexes []int) (*EthReceiptBatchCircuit, error) {
+	if len(headerRLP) > params.HeaderBytes() {
+		return nil, fmt.Errorf("header has %d bytes, at most %d are supported", len(headerRLP), params.HeaderBytes())
+	}
+	if len(txIndexes) == 0 || len(txIndexes) > params.BatchSize() {
+		return nil, fmt.Errorf("batch has %d receipts, expected 1 to %d", len(txIndexes), params.BatchSize())
+	}
+
+	c := NewEthReceiptBatchCircuit(params)
+	header := make([]byte, params.HeaderBytes())
+	copy(header, headerRLP)
+	c.Header = uints.NewU8Array(header)
+	c.HeaderLen = len(headerRLP)
+
+	leaves := make([][32]byte, len(txIndexes))
+	for i := range c.ReceiptProofs {
+		txIndex := txIndexes[min(i, len(txIndexes)-1)]
+		if txIndex < 0 || txIndex >= len(receipts) || txIndex >= 1<<maxTxIndexBits {
+			return nil, fmt.Errorf("transaction %d not in block (%d receipts)", txIndex, len(receipts))
+		}
+		nodes, err := ReceiptProof(receipts, txIndex)
+		if err != nil {
+			return nil, err
+		}
+		if c.ReceiptProofs[i], err = AssignMPTProof(params.ReceiptParams(), nodes); err != nil {
+			return nil, fmt.Errorf("receipt proof %d: %w", i, err)
+		}
+		c.TxIndexes[i] = txIndex
+		if i < len(txIndexes) {
+			receipt, err := receipts[txIndex].MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode receipt %d: %w", txIndex, err)
+			}
+			leaves[i] = ReceiptBatchLeaf(txIndex, receipt)
+		}
+	}
+
+	root, err := ReceiptBatchRoot(params, leaves)
+	if err != nil {
+		return nil, err
+	}
+	c.BlockHash = [32]uints.U8(uints.NewU8Array(crypto.Keccak256(headerRLP)))
+	c.Count = len(txIndexes)
+	c.ReceiptsCommitment = [32]uints.U8(uints.NewU8Array(root[:]))
+	return c, nil
+}
+
+// ReceiptBatchLeaf returns the leaf of a proven receipt, keccak256(uint256(txIndex) || keccak256(receipt)),
+// receipt being its consensus encoding
+func ReceiptBatchLeaf(txIndex int, receipt []byte) [32]byte {
+	var index [32]byte
+	binary.BigEndian.PutUint64(index[24:], uint64(txIndex))
+	return crypto.Keccak256Hash(index[:], crypto.Keccak256(receipt))
+}
+
+// ReceiptBatchRoot returns the keccak256 Merkle root of the leaves, padded with zero leaves to params.BatchSize()
+func ReceiptBatchRoot(params ReceiptBatchParams, leaves [][32]byte) ([32]byte, error) {
+	size := params.BatchSize()
+	if size&(size-1) != 0 {
+		return [32]byte{}, fmt.Errorf("batch size %d is not a power of two", size)
+	}
+	if len(leaves) > size {
+		return [32]byte{}, fmt.Errorf("%d leaves, at most %d are supported", len(leaves), size)
+	}
+	level := make([][32]byte, size)
+	copy(level, leaves)
+	for len(level) > 1 {
+		for i := range level[:len(level)/2] {
+			level[i] = crypto.Keccak256Hash(level[2*i][:], level[2*i+1][:])
+		}
+		level = level[:len(level)/2]
+	}
+	return level[0], nil
+}
+
+// Define implements the circuit constraints
+func (c *EthReceiptBatchCircuit) Define(api frontend.API) error {
+	size := c.Params.BatchSize()
+	if size&(size-1) != 0 {
+		return fmt.Errorf("batch size %d is not a power of two", size)
+	}
+	if len(c.Header) != c.Params.HeaderBytes() {
+		return fmt.Errorf("header length %d does not match %d", len(c.Header), c.Params.HeaderBytes())
+	}
+	if len(c.ReceiptProofs) != size || len(c.TxIndexes) != size {
+		return fmt.Errorf("batch has %d proofs and %d indexes, expected %d", len(c.ReceiptProofs), len(c.TxIndexes), size)
+	}
+
+	// Step 1: the header hashes to BlockHash and commits to the receipts root
+	receiptsRoot, err := verifyExecutionHeader(api, c.Header, c.HeaderLen, c.BlockHash, headerReceiptsRootOffset)
+	if err != nil {
+		return err
+	}
+
+	// 1 <= Count <= size, inBatch is 1 for the slots below Count
+	api.AssertIsEqual(api.IsZero(c.Count), 0)
+	api.AssertIsLessOrEqual(c.Count, size)
+	var inBatch frontend.Variable = 1
+	leaves := make([][32]uints.U8, size)
+	for i := range leaves {
+		inBatch = api.Sub(inBatch, api.IsZero(api.Sub(c.Count, i)))
+
+		// Step 2: the receipt is in the receipts trie, at key rlp(TxIndexes[i])
+		key, keyLen := rlpTxIndex(api, c.TxIndexes[i])
+		receipt, receiptLen, err := verifyMPTProof(api, c.Params.ReceiptParams(), receiptsRoot, key, keyLen, &c.ReceiptProofs[i])
+		if err != nil {
+			return fmt.Errorf("receipt proof %d: %w", i, err)
+		}
+
+		// Step 3: the leaf of the receipt, zero past Count
+		receiptU8 := make([]uints.U8, len(receipt))
+		for k := range receipt {
+			receiptU8[k] = uints.U8{Val: receipt[k]}
+		}
+		receiptHash, err := keccak256Sum(api, receiptU8, receiptLen)
+		if err != nil {
+			return err
+		}
+		bits := api.ToBinary(c.TxIndexes[i], maxTxIndexBits)
+		index := make([]uints.U8, 32)
+		for k := range index[:30] {
+			index[k] = uints.NewU8(0)
+		}
+		index[30] = uints.U8{Val: api.FromBinary(bits[8:]...)}
+		index[31] = uints.U8{Val: api.FromBinary(bits[:8]...)}
+		leaf, err := keccak256Pair(api, [32]uints.U8(index), receiptHash)
+		if err != nil {
+			return err
+		}
+		for k := range leaf {
+			leaves[i][k] = uints.U8{Val: api.Mul(inBatch, leaf[k].Val)}
+		}
+	}
+
+	for len(leaves) > 1 {
+		for i := range leaves[:len(leaves)/2] {
+			if leaves[i], err = keccak256Pair(api, leaves[2*i], leaves[2*i+1]); err != nil {
+				return err
+			}
+		}
+		leaves = leaves[:len(leaves)/2]
+	}
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(leaves[0][i].Val, c.ReceiptsCommitment[i].Val)
+	}
+	return nil
+}
+
+// keccak256Pair returns keccak256(left || right)
+func keccak256Pair(api frontend.API, left, right [32]uints.U8) ([32]uints.U8, error) {
+	hasher, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return [32]uints.U8{}, fmt.Errorf("new keccak256: %w", err)
+	}
+	hasher.Write(left[:])
+	hasher.Write(right[:])
+	return [32]uints.U8(hasher.Sum()), nil
+}
@@ -0,0 +1,39 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthReceiptBatchCircuit(t *testing.T) {
+	header, receipts := newTestReceipts(t)
+	params := ReceiptBatchParams{
+		MaxHeaderBytes: 640,
+		Receipt:        MPTParams{MaxDepth: 5, MaxValueBytes: 512},
+		MaxReceipts:    4,
+	}
+	circuit := NewEthReceiptBatchCircuit(params)
+
+	witness, err := NewEthReceiptBatchAssignment(params, header, receipts, []int{0, 129, 5})
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the padding slot repeats receipt 5, counting it changes the commitment
+	witness.Count = 4
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the proof of receipt 129 is not the path of another index
+	witness.Count = 3
+	witness.TxIndexes[1] = 128
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness.TxIndexes[1] = 129
+	witness.ReceiptsCommitment[0] = uints.NewU8(witness.ReceiptsCommitment[0].Val.(uint8) ^ 1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	_, err = NewEthReceiptBatchAssignment(params, header, receipts, []int{0, 1, 2, 3, 4})
+	require.Error(t, err)
+}
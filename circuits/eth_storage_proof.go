@@ -0,0 +1,244 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/selector"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kysee/zk-chains/types"
+)
+
+// EthStorageProofCircuit proves the value of a storage slot of an account at an execution block,
+// from the accountProof and storageProof returned by eth_getProof (EIP-1186).
+//
+// This circuit:
+// 1. Verifies keccak256(Header) is BlockHash and reads the stateRoot of the header
+// 2. Verifies AccountProof is the path of keccak256(Address) in the state trie and reads the storageRoot of the account
+// 3. Verifies StorageProof is the path of keccak256(Slot) in the storage trie and decodes the slot value
+//
+// Only non-zero slots can be proven (see verifyMPTProof).
+type EthStorageProofCircuit struct {
+	// Compile-time parameters (not part of the witness)
+	Params StorageProofParams `gnark:"-"`
+
+	// RLP encoded execution block header, zero padded to Params.HeaderBytes() (private inputs)
+	Header    []uints.U8
+	HeaderLen frontend.Variable
+
+	// eth_getProof proofs (private inputs)
+	AccountProof MPTProof
+	StorageProof MPTProof
+
+	// Public inputs
+	BlockHash [32]uints.U8 `gnark:",public"` // execution block hash
+	Address   [20]uints.U8 `gnark:",public"` // account address
+	Slot      [32]uints.U8 `gnark:",public"` // storage slot, big-endian
+	Value     [32]uints.U8 `gnark:",public"` // value of the slot, big-endian
+}
+
+// StorageProofParams holds the compile-time parameters of EthStorageProofCircuit.
+// Zero values select defaults sized for mainnet proofs.
+type StorageProofParams struct {
+	// MaxHeaderBytes is the maximum RLP length of the block header (default 1024)
+	MaxHeaderBytes int
+	// Account bounds the accountProof (default 10 nodes)
+	Account MPTParams
+	// Storage bounds the storageProof (default 10 nodes)
+	Storage MPTParams
+}
+
+// HeaderBytes returns MaxHeaderBytes, defaulting to 1024
+func (p StorageProofParams) HeaderBytes() int {
+	if p.MaxHeaderBytes == 0 {
+		return 1024
+	}
+	return p.MaxHeaderBytes
+}
+
+// AccountParams returns the bounds of the accountProof. The leaf value is the RLP encoded
+// account [nonce, balance, storageRoot, codeHash], at most 112 bytes.
+func (p StorageProofParams) AccountParams() MPTParams {
+	return withMPTDefaults(p.Account, 112)
+}
+
+// StorageParams returns the bounds of the storageProof. The leaf value is the RLP encoded
+// slot value, at most 33 bytes.
+func (p StorageProofParams) StorageParams() MPTParams {
+	return withMPTDefaults(p.Storage, 33)
+}
+
+func withMPTDefaults(p MPTParams, maxValueBytes int) MPTParams {
+	if p.MaxDepth == 0 {
+		p.MaxDepth = 10
+	}
+	if p.MaxNodeBytes == 0 {
+		p.MaxNodeBytes = 532
+	}
+	if p.MaxValueBytes == 0 {
+		p.MaxValueBytes = maxValueBytes
+	}
+	return p
+}
+
+// NewEthStorageProofCircuit allocates a circuit (or witness) for the given params
+func NewEthStorageProofCircuit(params StorageProofParams) *EthStorageProofCircuit {
+	return &EthStorageProofCircuit{
+		Params:       params,
+		Header:       make([]uints.U8, params.HeaderBytes()),
+		AccountProof: NewMPTProof(params.AccountParams()),
+		StorageProof: NewMPTProof(params.StorageParams()),
+	}
+}
+
+// NewEthStorageProofAssignment assigns the circuit for the slot storageIndex of an eth_getProof
+// result, against the RLP encoded header of the block the proof was requested at.
+func NewEthStorageProofAssignment(params StorageProofParams, headerRLP []byte, result *types.AccountProofResult, storageIndex int) (*EthStorageProofCircuit, error) {
+	if len(headerRLP) > params.HeaderBytes() {
+		return nil, fmt.Errorf("header has %d bytes, at most %d are supported", len(headerRLP), params.HeaderBytes())
+	}
+	if storageIndex < 0 || storageIndex >= len(result.StorageProof) {
+		return nil, fmt.Errorf("storage proof %d not in result (%d proofs)", storageIndex, len(result.StorageProof))
+	}
+	storage := &result.StorageProof[storageIndex]
+
+	address, err := result.AddressBytes()
+	if err != nil {
+		return nil, err
+	}
+	slot, err := storage.SlotBytes()
+	if err != nil {
+		return nil, fmt.Errorf("storage key: %w", err)
+	}
+	value, err := storage.ValueBytes()
+	if err != nil {
+		return nil, fmt.Errorf("storage value: %w", err)
+	}
+	if value == [32]byte{} {
+		return nil, fmt.Errorf("slot %s is zero, only non-zero slots can be proven", storage.Key)
+	}
+
+	c := NewEthStorageProofCircuit(params)
+	header := make([]byte, params.HeaderBytes())
+	copy(header, headerRLP)
+	c.Header = uints.NewU8Array(header)
+	c.HeaderLen = len(headerRLP)
+	if c.AccountProof, err = AssignMPTProof(params.AccountParams(), toBytesSlice(result.AccountProof)); err != nil {
+		return nil, fmt.Errorf("account proof: %w", err)
+	}
+	if c.StorageProof, err = AssignMPTProof(params.StorageParams(), toBytesSlice(storage.Proof)); err != nil {
+		return nil, fmt.Errorf("storage proof: %w", err)
+	}
+
+	c.BlockHash = [32]uints.U8(uints.NewU8Array(crypto.Keccak256(headerRLP)))
+	c.Address = [20]uints.U8(uints.NewU8Array(address[:]))
+	c.Slot = [32]uints.U8(uints.NewU8Array(slot[:]))
+	c.Value = [32]uints.U8(uints.NewU8Array(value[:]))
+	return c, nil
+}
+
+func toBytesSlice(hbs []types.HexBytes) [][]byte {
+	out := make([][]byte, len(hbs))
+	for i := range hbs {
+		out[i] = hbs[i]
+	}
+	return out
+}
+
+// Define implements the circuit constraints
+func (c *EthStorageProofCircuit) Define(api frontend.API) error {
+	if len(c.Header) != c.Params.HeaderBytes() {
+		return fmt.Errorf("header length %d does not match %d", len(c.Header), c.Params.HeaderBytes())
+	}
+
+	// Step 1: the header hashes to BlockHash and commits to the state root
+	stateRoot, err := verifyExecutionHeader(api, c.Header, c.HeaderLen, c.BlockHash, headerStateRootOffset)
+	if err != nil {
+		return err
+	}
+
+	// Step 2: the account is in the state trie, its storageRoot is the third of its four fields.
+	// storageRoot and codeHash are always 33 bytes (0xa0 + 32), so they end the encoding.
+	accountKey, err := keccak256Sum(api, c.Address[:], len(c.Address))
+	if err != nil {
+		return err
+	}
+	account, accountLen, err := verifyMPTProof(api, c.Params.AccountParams(), stateRoot, u8Vals(accountKey[:]), len(accountKey), &c.AccountProof)
+	if err != nil {
+		return fmt.Errorf("account proof: %w", err)
+	}
+	accountAt := func(off frontend.Variable) frontend.Variable {
+		return selector.Mux(api, off, account...)
+	}
+	api.AssertIsLessOrEqual(66+2, accountLen)
+	api.AssertIsEqual(accountAt(api.Sub(accountLen, 66)), 0xa0)
+	api.AssertIsEqual(accountAt(api.Sub(accountLen, 33)), 0xa0)
+	var storageRoot [32]uints.U8
+	for i := range storageRoot {
+		storageRoot[i] = uints.U8{Val: accountAt(api.Sub(accountLen, 65-i))}
+	}
+
+	// Step 3: the slot is in the storage trie of the account, with the RLP encoded Value
+	storageKey, err := keccak256Sum(api, c.Slot[:], len(c.Slot))
+	if err != nil {
+		return err
+	}
+	storage, storageLen, err := verifyMPTProof(api, c.Params.StorageParams(), storageRoot, u8Vals(storageKey[:]), len(storageKey), &c.StorageProof)
+	if err != nil {
+		return fmt.Errorf("storage proof: %w", err)
+	}
+	c.verifyStorageValue(api, storage, storageLen)
+	return nil
+}
+
+// verifyStorageValue requires the RLP string of the leaf (a single byte below 0x80, or 0x80+n and n
+// big-endian bytes without leading zeros) to decode to Value.
+func (c *EthStorageProofCircuit) verifyStorageValue(api frontend.API, encoded []frontend.Variable, encodedLen frontend.Variable) {
+	prefix := encoded[0]
+	single := api.Sub(1, api.ToBinary(prefix, 8)[7])
+	n := api.Select(single, 1, api.Sub(prefix, 0x80))
+	api.AssertIsLessOrEqual(n, 32)
+	api.AssertIsEqual(encodedLen, api.Select(single, 1, api.Add(n, 1)))
+	api.AssertIsEqual(api.Mul(api.Sub(1, single), api.IsZero(encoded[1])), 0) // canonical, and non-zero
+
+	// payload[k] is the k-th byte of the value, followed by zeros for the reads past its end
+	payload := make([]frontend.Variable, 34)
+	for k := range payload {
+		var next frontend.Variable = 0
+		if k+1 < len(encoded) {
+			next = encoded[k+1]
+		}
+		if k == 0 {
+			payload[k] = api.Select(single, prefix, next)
+		} else {
+			payload[k] = api.Mul(api.Sub(1, single), next)
+		}
+	}
+	var inValue frontend.Variable = 1 // 1 while k < n
+	for k := 0; k < 32; k++ {
+		inValue = api.Sub(inValue, api.IsZero(api.Sub(n, k)))
+		idx := api.Select(inValue, api.Sub(n, 1+k), len(payload)-1)
+		api.AssertIsEqual(c.Value[31-k].Val, selector.Mux(api, idx, payload...))
+	}
+}
+
+// u8Vals returns the variables of the given bytes
+func u8Vals(bytes []uints.U8) []frontend.Variable {
+	vals := make([]frontend.Variable, len(bytes))
+	for i := range bytes {
+		vals[i] = bytes[i].Val
+	}
+	return vals
+}
+
+// keccak256Sum returns keccak256 of the first length bytes of data
+func keccak256Sum(api frontend.API, data []uints.U8, length frontend.Variable) ([32]uints.U8, error) {
+	hasher, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return [32]uints.U8{}, fmt.Errorf("new keccak256: %w", err)
+	}
+	hasher.Write(data)
+	return [32]uints.U8(hasher.FixedLengthSum(length)), nil
+}
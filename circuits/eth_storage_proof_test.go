@@ -0,0 +1,116 @@
+package circuit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAccountProof builds a state trie holding an account with a few storage slots, and returns
+// the eth_getProof result for slot 7 along with the RLP encoded header committing to the state root.
+func newTestAccountProof(t *testing.T) ([]byte, *types.AccountProofResult) {
+	storage := trie.NewEmpty(nil)
+	for i := uint64(1); i <= 64; i++ {
+		slot := gethcommon.BigToHash(new(big.Int).SetUint64(i))
+		value, err := rlp.EncodeToBytes(new(big.Int).SetUint64(i * 1_000_000_007))
+		require.NoError(t, err)
+		storage.MustUpdate(crypto.Keccak256(slot[:]), value)
+	}
+	address := gethcommon.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+
+	state := trie.NewEmpty(nil)
+	for i := uint64(0); i < 64; i++ {
+		var other gethcommon.Address
+		binary.BigEndian.PutUint64(other[12:], i)
+		account := &gethtypes.StateAccount{Nonce: i, Balance: uint256.NewInt(i), Root: gethtypes.EmptyRootHash, CodeHash: gethtypes.EmptyCodeHash[:]}
+		enc, err := rlp.EncodeToBytes(account)
+		require.NoError(t, err)
+		state.MustUpdate(crypto.Keccak256(other[:]), enc)
+	}
+	account := &gethtypes.StateAccount{
+		Nonce:    1,
+		Balance:  uint256.MustFromDecimal("1000000000000000000000"),
+		Root:     storage.Hash(),
+		CodeHash: crypto.Keccak256([]byte("code")),
+	}
+	enc, err := rlp.EncodeToBytes(account)
+	require.NoError(t, err)
+	state.MustUpdate(crypto.Keccak256(address[:]), enc)
+
+	header, err := rlp.EncodeToBytes(&gethtypes.Header{
+		Root:       state.Hash(),
+		Difficulty: big.NewInt(0),
+		Number:     big.NewInt(21_000_000),
+		GasLimit:   36_000_000,
+		Time:       1_760_000_000,
+		Extra:      []byte("zk-chains"),
+		BaseFee:    big.NewInt(1_000_000_000),
+	})
+	require.NoError(t, err)
+
+	var accountProof, storageProof mptProofWriter
+	require.NoError(t, state.Prove(crypto.Keccak256(address[:]), &accountProof))
+	slot := gethcommon.BigToHash(big.NewInt(7))
+	require.NoError(t, storage.Prove(crypto.Keccak256(slot[:]), &storageProof))
+
+	return header, &types.AccountProofResult{
+		Address:      address[:],
+		AccountProof: toHexBytes(accountProof),
+		StorageHash:  account.Root[:],
+		StorageProof: []types.StorageProofResult{{
+			Key:   "0x7",
+			Value: fmt.Sprintf("0x%x", 7*1_000_000_007),
+			Proof: toHexBytes(storageProof),
+		}},
+	}
+}
+
+func toHexBytes(nodes [][]byte) []types.HexBytes {
+	out := make([]types.HexBytes, len(nodes))
+	for i := range nodes {
+		out[i] = nodes[i]
+	}
+	return out
+}
+
+func TestEthStorageProofCircuit(t *testing.T) {
+	header, result := newTestAccountProof(t)
+	params := StorageProofParams{
+		MaxHeaderBytes: 640,
+		Account:        MPTParams{MaxDepth: 4},
+		Storage:        MPTParams{MaxDepth: 4},
+	}
+	circuit := NewEthStorageProofCircuit(params)
+
+	witness, err := NewEthStorageProofAssignment(params, header, result, 0)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// another value for the slot
+	witness.Value[31] = uints.NewU8(witness.Value[31].Val.(uint8) + 1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// another account, with the same proof
+	witness, err = NewEthStorageProofAssignment(params, header, result, 0)
+	require.NoError(t, err)
+	witness.Address[19] = uints.NewU8(witness.Address[19].Val.(uint8) ^ 1)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// zero slots are not provable
+	result.StorageProof[0].Value = "0x0"
+	_, err = NewEthStorageProofAssignment(params, header, result, 0)
+	require.Error(t, err)
+}
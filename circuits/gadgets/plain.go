@@ -0,0 +1,47 @@
+package gadgets
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// HasCommitter reports whether the compiler of api offers the commitment extension, which the
+// lookup based gadgets (uints, emulated arithmetic, range checks) build on
+func HasCommitter(api frontend.API) bool {
+	_, ok := api.Compiler().(frontend.Committer)
+	return ok
+}
+
+// NewPlainBuilder wraps newBuilder so that circuits compile without the commitment extension,
+// for Groth16 verifiers and tooling which only take the 8 words A, B, C of a proof.
+//
+// The gadgets of this package fall back to bit decompositions (see HasCommitter). Gadgets which
+// cannot, such as emulated field arithmetic or gnark's byte lookups, fail the compilation.
+func NewPlainBuilder(newBuilder frontend.NewBuilder) frontend.NewBuilder {
+	return func(field *big.Int, config frontend.CompileConfig) (frontend.Builder[constraint.U64], error) {
+		b, err := newBuilder(field, config)
+		if err != nil {
+			return nil, err
+		}
+		return &plainBuilder{Builder: b}, nil
+	}
+}
+
+// plainBuilder hides the Commit method of the wrapped builder, from the API and from Compiler()
+type plainBuilder struct {
+	frontend.Builder[constraint.U64]
+}
+
+func (b *plainBuilder) Compiler() frontend.Compiler {
+	return b
+}
+
+func (b *plainBuilder) SetKeyValue(key, value any) {
+	b.Builder.(keyValueStore).SetKeyValue(key, value)
+}
+
+func (b *plainBuilder) GetKeyValue(key any) any {
+	return b.Builder.(keyValueStore).GetKeyValue(key)
+}
@@ -0,0 +1,149 @@
+// Package gadgets holds circuit building blocks shared by the circuits of the circuit package.
+package gadgets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/permutation/sha2"
+)
+
+// sha256IV is the initial hash value of SHA-256
+var sha256IV = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+// sha256K are the round constants of SHA-256
+var sha256K = [64]uint32{
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+	0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+	0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+	0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+	0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+	0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+// pairPaddingKW holds K[i] + W[i] of the padding block of a 64 bytes message
+// (0x80, zeros, then the bit length 512), whose message schedule is constant.
+var pairPaddingKW = func() (kw [64]uint32) {
+	var block [64]byte
+	block[0] = 0x80
+	binary.BigEndian.PutUint64(block[56:], 512)
+	var w [64]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(block[4*i:])
+	}
+	for i := 16; i < 64; i++ {
+		s0 := bits.RotateLeft32(w[i-15], -7) ^ bits.RotateLeft32(w[i-15], -18) ^ (w[i-15] >> 3)
+		s1 := bits.RotateLeft32(w[i-2], -17) ^ bits.RotateLeft32(w[i-2], -19) ^ (w[i-2] >> 10)
+		w[i] = w[i-16] + s0 + w[i-7] + s1
+	}
+	for i := range kw {
+		kw[i] = sha256K[i] + w[i]
+	}
+	return kw
+}()
+
+// keyValueStore is implemented by the gnark builders, it lets gadgets be shared by all the
+// calls made during one compilation (or one test engine run).
+type keyValueStore interface {
+	SetKeyValue(key, value any)
+	GetKeyValue(key any) any
+}
+
+type pairHasherKey struct{}
+
+// PairHasher computes SHA-256 over 64 bytes, i.e. the hash of two SSZ chunks.
+//
+// A 64 bytes message takes two compressions: the message block itself and a padding block
+// which is the same for every pair. The message schedule of the padding block is computed
+// natively (see pairPaddingKW), so only its 64 rounds are constrained, instead of sha2.New
+// expanding both schedules in-circuit for every pair.
+//
+// Without the commitment extension (see NewPlainBuilder) the words are handled as bits instead.
+type PairHasher struct {
+	api  frontend.API
+	uapi *uints.BinaryField[uints.U32] // nil without the commitment extension
+}
+
+// NewPairHasher returns the pair hasher of api, created on the first call
+func NewPairHasher(api frontend.API) (*PairHasher, error) {
+	kv, ok := api.(keyValueStore)
+	if ok {
+		if h, ok := kv.GetKeyValue(pairHasherKey{}).(*PairHasher); ok {
+			return h, nil
+		}
+	}
+	h := &PairHasher{api: api}
+	if HasCommitter(api) {
+		uapi, err := uints.New[uints.U32](api)
+		if err != nil {
+			return nil, fmt.Errorf("new uints: %w", err)
+		}
+		h.uapi = uapi
+	}
+	if ok {
+		kv.SetKeyValue(pairHasherKey{}, h)
+	}
+	return h, nil
+}
+
+// Hash returns SHA-256(left || right)
+func (h *PairHasher) Hash(left, right [32]uints.U8) [32]uints.U8 {
+	if h.uapi == nil {
+		return h.hashBits(left, right)
+	}
+	var block [64]uints.U8
+	copy(block[:32], left[:])
+	copy(block[32:], right[:])
+
+	var state [8]uints.U32
+	for i := range state {
+		state[i] = uints.NewU32(sha256IV[i])
+	}
+	state = sha2.Permute(h.uapi, state, block)
+	state = h.compressPadding(state)
+
+	var digest [32]uints.U8
+	for i := range state {
+		copy(digest[4*i:], h.uapi.UnpackMSB(state[i]))
+	}
+	return digest
+}
+
+// compressPadding runs the compression function over the padding block of a 64 bytes message
+func (h *PairHasher) compressPadding(state [8]uints.U32) [8]uints.U32 {
+	u := h.uapi
+	a, b, c, d, e, f, g, hh := state[0], state[1], state[2], state[3], state[4], state[5], state[6], state[7]
+	for i := 0; i < 64; i++ {
+		t1 := u.Add(
+			hh,
+			u.Xor(u.Lrot(e, -6), u.Lrot(e, -11), u.Lrot(e, -25)),
+			u.Xor(u.And(e, f), u.And(u.Not(e), g)),
+			uints.NewU32(pairPaddingKW[i]),
+		)
+		t2 := u.Add(
+			u.Xor(u.Lrot(a, -2), u.Lrot(a, -13), u.Lrot(a, -22)),
+			u.Xor(u.And(a, b), u.And(a, c), u.And(b, c)),
+		)
+		hh, g, f, e, d, c, b, a = g, f, e, u.Add(d, t1), c, b, a, u.Add(t1, t2)
+	}
+	for i, v := range [8]uints.U32{a, b, c, d, e, f, g, hh} {
+		state[i] = u.Add(state[i], v)
+	}
+	return state
+}
+
+// HashPair returns SHA-256(left || right) with the pair hasher of api
+func HashPair(api frontend.API, left, right [32]uints.U8) [32]uints.U8 {
+	h, err := NewPairHasher(api)
+	if err != nil {
+		panic(err)
+	}
+	return h.Hash(left, right)
+}
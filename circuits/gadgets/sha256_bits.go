@@ -0,0 +1,137 @@
+package gadgets
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+)
+
+// bitWord is a 32-bit word as boolean variables, least significant bit first
+type bitWord [32]frontend.Variable
+
+// constWord returns the bits of a constant word
+func constWord(v uint32) bitWord {
+	var w bitWord
+	for i := range w {
+		w[i] = (v >> i) & 1
+	}
+	return w
+}
+
+// hashBits is Hash without the commitment extension: the words are decomposed in bits, XOR, Ch and
+// Maj are computed bit by bit, and additions modulo 2^32 drop the carries of one decomposition.
+func (h *PairHasher) hashBits(left, right [32]uints.U8) [32]uints.U8 {
+	api := h.api
+	var block [64]uints.U8
+	copy(block[:32], left[:])
+	copy(block[32:], right[:])
+
+	var w [64]bitWord
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 4; j++ {
+			// big-endian words, the last byte holds the least significant bits
+			copy(w[i][8*(3-j):], api.ToBinary(block[4*i+j].Val, 8))
+		}
+	}
+	for i := 16; i < 64; i++ {
+		s0 := h.xor3(rotr(w[i-15], 7), rotr(w[i-15], 18), shr(w[i-15], 3))
+		s1 := h.xor3(rotr(w[i-2], 17), rotr(w[i-2], 19), shr(w[i-2], 10))
+		w[i] = h.add(0, w[i-16], s0, w[i-7], s1)
+	}
+
+	var state [8]bitWord
+	for i := range state {
+		state[i] = constWord(sha256IV[i])
+	}
+	state = h.compressBits(state, &w, &sha256K)
+	state = h.compressBits(state, nil, &pairPaddingKW)
+
+	var digest [32]uints.U8
+	for i := range state {
+		for j := 0; j < 4; j++ {
+			digest[4*i+j] = uints.U8{Val: api.FromBinary(state[i][8*(3-j) : 8*(4-j)]...)}
+		}
+	}
+	return digest
+}
+
+// compressBits runs the 64 rounds over the constants k plus the message schedule w, which is nil
+// when k already includes it (see pairPaddingKW)
+func (h *PairHasher) compressBits(state [8]bitWord, w *[64]bitWord, k *[64]uint32) [8]bitWord {
+	api := h.api
+	a, b, c, d, e, f, g, hh := state[0], state[1], state[2], state[3], state[4], state[5], state[6], state[7]
+	for i := 0; i < 64; i++ {
+		var ch, maj bitWord
+		for j := range ch {
+			ch[j] = api.Select(e[j], f[j], g[j])
+			maj[j] = api.Select(api.Xor(b[j], c[j]), a[j], b[j])
+		}
+		s1 := h.xor3(rotr(e, 6), rotr(e, 11), rotr(e, 25))
+		s0 := h.xor3(rotr(a, 2), rotr(a, 13), rotr(a, 22))
+		t1 := h.sum(k[i], hh, s1, ch)
+		if w != nil {
+			t1 = api.Add(t1, api.FromBinary(w[i][:]...))
+		}
+		// t1 < 5 * 2^32, so e < 6 * 2^32 and a < 7 * 2^32
+		hh, g, f = g, f, e
+		e = h.mod32(api.Add(t1, api.FromBinary(d[:]...)), 3)
+		d, c, b = c, b, a
+		a = h.mod32(api.Add(t1, h.sum(0, s0, maj)), 3)
+	}
+	for i, v := range [8]bitWord{a, b, c, d, e, f, g, hh} {
+		state[i] = h.add(0, state[i], v)
+	}
+	return state
+}
+
+// sum returns k + the values of the words, as one variable
+func (h *PairHasher) sum(k uint32, words ...bitWord) frontend.Variable {
+	var s frontend.Variable = k
+	for _, w := range words {
+		s = h.api.Add(s, h.api.FromBinary(w[:]...))
+	}
+	return s
+}
+
+// add returns k + the words modulo 2^32
+func (h *PairHasher) add(k uint32, words ...bitWord) bitWord {
+	carryBits := 1
+	for 1<<carryBits < len(words)+1 {
+		carryBits++
+	}
+	return h.mod32(h.sum(k, words...), carryBits)
+}
+
+// mod32 decomposes s, below 2^(32+carryBits), and returns its lower 32 bits
+func (h *PairHasher) mod32(s frontend.Variable, carryBits int) bitWord {
+	return bitWord(h.api.ToBinary(s, 32+carryBits)[:32])
+}
+
+func (h *PairHasher) xor3(x, y, z bitWord) bitWord {
+	var w bitWord
+	for k := range w {
+		w[k] = h.api.Xor(h.api.Xor(x[k], y[k]), z[k])
+	}
+	return w
+}
+
+// rotr rotates x right by n bits
+func rotr(x bitWord, n int) bitWord {
+	var w bitWord
+	for k := range w {
+		w[k] = x[(k+n)%32]
+	}
+	return w
+}
+
+// shr shifts x right by n bits
+func shr(x bitWord, n int) bitWord {
+	var w bitWord
+	for k := range w {
+		if k+n < 32 {
+			w[k] = x[k+n]
+		} else {
+			w[k] = 0
+		}
+	}
+	return w
+}
@@ -0,0 +1,118 @@
+package gadgets
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+// hashPairsCircuit chains len(Chunks) pair hashes, with the shared pairHasher or with sha2.New
+type hashPairsCircuit struct {
+	UseSha2New bool `gnark:"-"`
+
+	Chunks [][32]uints.U8
+	Root   [32]uints.U8 `gnark:",public"`
+}
+
+func (c *hashPairsCircuit) Define(api frontend.API) error {
+	var current [32]uints.U8
+	for i := range current {
+		current[i] = uints.NewU8(0)
+	}
+	for _, chunk := range c.Chunks {
+		if !c.UseSha2New {
+			current = HashPair(api, current, chunk)
+			continue
+		}
+		h, err := sha2.New(api)
+		if err != nil {
+			return err
+		}
+		h.Write(current[:])
+		h.Write(chunk[:])
+		current = [32]uints.U8(h.Sum())
+	}
+	AssertChunksEqual(api, current, c.Root)
+	return nil
+}
+
+func TestHashPair(t *testing.T) {
+	const n = 8
+	circuit := &hashPairsCircuit{Chunks: make([][32]uints.U8, n)}
+	witness := &hashPairsCircuit{Chunks: make([][32]uints.U8, n)}
+	var current [32]byte
+	for i := 0; i < n; i++ {
+		var chunk [32]byte
+		for j := range chunk {
+			chunk[j] = byte(31*i + 7*j)
+		}
+		witness.Chunks[i] = [32]uints.U8(uints.NewU8Array(chunk[:]))
+		current = sha256.Sum256(append(current[:], chunk[:]...))
+	}
+	witness.Root = [32]uints.U8(uints.NewU8Array(current[:]))
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	witness.Chunks[3][0] = uints.NewU8(0xff)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// report the constraints saved per pair compared to a sha2.New hasher per pair
+	shared, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	require.NoError(t, err)
+	perPair, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &hashPairsCircuit{UseSha2New: true, Chunks: make([][32]uints.U8, n)})
+	require.NoError(t, err)
+	require.Less(t, shared.GetNbConstraints(), perPair.GetNbConstraints())
+	t.Logf("%d pair hashes: %d constraints with the shared hasher, %d with sha2.New per pair (%.1f%% less)",
+		n, shared.GetNbConstraints(), perPair.GetNbConstraints(),
+		100*float64(perPair.GetNbConstraints()-shared.GetNbConstraints())/float64(perPair.GetNbConstraints()))
+}
+
+func TestHashPairPlain(t *testing.T) {
+	const n = 2
+	circuit := &hashPairsCircuit{Chunks: make([][32]uints.U8, n)}
+	witness := &hashPairsCircuit{Chunks: make([][32]uints.U8, n)}
+	var current [32]byte
+	for i := 0; i < n; i++ {
+		var chunk [32]byte
+		for j := range chunk {
+			chunk[j] = byte(31*i + 7*j)
+		}
+		witness.Chunks[i] = [32]uints.U8(uints.NewU8Array(chunk[:]))
+		current = sha256.Sum256(append(current[:], chunk[:]...))
+	}
+	witness.Root = [32]uints.U8(uints.NewU8Array(current[:]))
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), NewPlainBuilder(r1cs.NewBuilder), circuit)
+	require.NoError(t, err)
+	require.Empty(t, ccs.(*cs.R1CS).CommitmentInfo)
+	t.Logf("%d pair hashes without commitments: %d constraints", n, ccs.GetNbConstraints())
+
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+	full, err := frontend.NewWitness(witness, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	proof, err := groth16.Prove(ccs, pk, full)
+	require.NoError(t, err)
+	public, err := full.Public()
+	require.NoError(t, err)
+	require.NoError(t, groth16.Verify(proof, vk, public))
+	require.Len(t, proof.(interface{ MarshalSolidity() []byte }).MarshalSolidity(), 8*32)
+
+	witness.Chunks[1][0] = uints.NewU8(0xff)
+	full, err = frontend.NewWitness(witness, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	_, err = groth16.Prove(ccs, pk, full)
+	require.Error(t, err)
+
+	// emulated arithmetic and gnark's byte lookups need the commitment extension
+	_, err = frontend.Compile(ecc.BN254.ScalarField(), NewPlainBuilder(r1cs.NewBuilder), &hashPairsCircuit{UseSha2New: true, Chunks: make([][32]uints.U8, n)})
+	require.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package gadgets
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+)
+
+// SSZBranchRoot hashes leaf up with the bottom-up branch along the path of gindex, a compile-time
+// constant, and returns the root. The branch must be gindex.Depth() long.
+func SSZBranchRoot(api frontend.API, leaf [32]uints.U8, branch [][32]uints.U8, gindex types.GIndex) ([32]uints.U8, error) {
+	path := gindex.PathBits()
+	if len(path) != len(branch) {
+		return leaf, fmt.Errorf("branch length %d does not match depth %d of gindex %v", len(branch), len(path), gindex)
+	}
+	h, err := NewPairHasher(api)
+	if err != nil {
+		return leaf, err
+	}
+	current := leaf
+	for i, bit := range path {
+		if bit == 1 {
+			// current node is on the right, its sibling on the left
+			current = h.Hash(branch[i], current)
+		} else {
+			current = h.Hash(current, branch[i])
+		}
+	}
+	return current, nil
+}
+
+// VerifySSZBranch asserts that leaf is the node at gindex of the tree with the given root,
+// branch being the bottom-up siblings of its path.
+func VerifySSZBranch(api frontend.API, leaf [32]uints.U8, branch [][32]uints.U8, gindex types.GIndex, root [32]uints.U8) error {
+	current, err := SSZBranchRoot(api, leaf, branch, gindex)
+	if err != nil {
+		return err
+	}
+	AssertChunksEqual(api, current, root)
+	return nil
+}
+
+// SSZBranchRootAt is SSZBranchRoot for a leaf whose position is only known in-circuit: path holds
+// one boolean per level, least significant first, 1 when the node is a right child.
+func SSZBranchRootAt(api frontend.API, leaf [32]uints.U8, branch [][32]uints.U8, path []frontend.Variable) ([32]uints.U8, error) {
+	if len(path) != len(branch) {
+		return leaf, fmt.Errorf("branch length %d does not match path length %d", len(branch), len(path))
+	}
+	bytesAPI, err := uints.NewBytes(api)
+	if err != nil {
+		return leaf, fmt.Errorf("new bytes: %w", err)
+	}
+	h, err := NewPairHasher(api)
+	if err != nil {
+		return leaf, err
+	}
+	current := leaf
+	for i, bit := range path {
+		var left, right [32]uints.U8
+		for j := 0; j < 32; j++ {
+			left[j] = bytesAPI.Select(bit, branch[i][j], current[j])
+			right[j] = bytesAPI.Select(bit, current[j], branch[i][j])
+		}
+		current = h.Hash(left, right)
+	}
+	return current, nil
+}
+
+// SSZBranchRootGIndex is SSZBranchRoot for a gindex only known in-circuit, of depth 1 to len(branch).
+// The path follows the bits of gindex, the levels above its depth are skipped and their branch
+// entries ignored (the witness pads them with zero chunks).
+func SSZBranchRootGIndex(api frontend.API, leaf [32]uints.U8, branch [][32]uints.U8, gindex frontend.Variable) ([32]uints.U8, error) {
+	bytesAPI, err := uints.NewBytes(api)
+	if err != nil {
+		return leaf, fmt.Errorf("new bytes: %w", err)
+	}
+	h, err := NewPairHasher(api)
+	if err != nil {
+		return leaf, err
+	}
+	// gindex has at most len(branch)+1 bits, level i is hashed if any bit above i is set
+	bits := api.ToBinary(gindex, len(branch)+1)
+	inPath := make([]frontend.Variable, len(branch))
+	var above frontend.Variable = bits[len(branch)]
+	for i := len(branch) - 1; i >= 0; i-- {
+		inPath[i] = above
+		above = api.Or(above, bits[i])
+	}
+	api.AssertIsEqual(inPath[0], 1)
+
+	current := leaf
+	for i := range branch {
+		var left, right [32]uints.U8
+		for j := 0; j < 32; j++ {
+			left[j] = bytesAPI.Select(bits[i], branch[i][j], current[j])
+			right[j] = bytesAPI.Select(bits[i], current[j], branch[i][j])
+		}
+		parent := h.Hash(left, right)
+		for j := 0; j < 32; j++ {
+			current[j] = bytesAPI.Select(inPath[i], parent[j], current[j])
+		}
+	}
+	return current, nil
+}
+
+// SSZBranchRootEither is SSZBranchRoot for a leaf at short or at long, selected in-circuit by the boolean
+// isLong. long must be short moved down as the left-most subtree (same Index(), e.g. a BeaconState field
+// once the number of fields crosses a power of two), so both share the first short.Depth() levels.
+// The branch is long.Depth() long, the levels above short.Depth() are ignored when isLong is 0 (the
+// witness pads them with zero chunks).
+func SSZBranchRootEither(api frontend.API, leaf [32]uints.U8, branch [][32]uints.U8, short, long types.GIndex, isLong frontend.Variable) ([32]uints.U8, error) {
+	if short.Index() != long.Index() || short.Depth() > long.Depth() {
+		return leaf, fmt.Errorf("gindex %v is not %v moved down", long, short)
+	}
+	if len(branch) != long.Depth() {
+		return leaf, fmt.Errorf("branch length %d does not match depth %d of gindex %v", len(branch), long.Depth(), long)
+	}
+	bytesAPI, err := uints.NewBytes(api)
+	if err != nil {
+		return leaf, fmt.Errorf("new bytes: %w", err)
+	}
+	shortRoot, err := SSZBranchRoot(api, leaf, branch[:short.Depth()], short)
+	if err != nil {
+		return leaf, err
+	}
+	h, err := NewPairHasher(api)
+	if err != nil {
+		return leaf, err
+	}
+	longRoot := shortRoot
+	for _, sibling := range branch[short.Depth():] {
+		longRoot = h.Hash(longRoot, sibling)
+	}
+
+	api.AssertIsBoolean(isLong)
+	var root [32]uints.U8
+	for j := 0; j < 32; j++ {
+		root[j] = bytesAPI.Select(isLong, longRoot[j], shortRoot[j])
+	}
+	return root, nil
+}
+
+// Merkleize returns the SSZ merkleization of chunks, whose number must be a power of two
+func Merkleize(api frontend.API, chunks [][32]uints.U8) ([32]uints.U8, error) {
+	n := len(chunks)
+	if n == 0 || n&(n-1) != 0 {
+		return [32]uints.U8{}, fmt.Errorf("%d chunks is not a power of two", n)
+	}
+	h, err := NewPairHasher(api)
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+	layer := append([][32]uints.U8{}, chunks...)
+	for len(layer) > 1 {
+		for i := range layer[:len(layer)/2] {
+			layer[i] = h.Hash(layer[2*i], layer[2*i+1])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0], nil
+}
+
+// BLSPubKeyRoot returns hash_tree_root(BLSPubkey): the 48 bytes right padded to two chunks, hashed together
+func BLSPubKeyRoot(api frontend.API, pubkey [48]uints.U8) ([32]uints.U8, error) {
+	h, err := NewPairHasher(api)
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+	var left, right [32]uints.U8
+	copy(left[:], pubkey[:32])
+	copy(right[:], pubkey[32:])
+	for i := 16; i < 32; i++ {
+		right[i] = uints.NewU8(0)
+	}
+	return h.Hash(left, right), nil
+}
+
+// SyncCommitteeRoot returns hash_tree_root(SyncCommittee) of the compressed pubkeys of a committee and
+// its aggregate pubkey, as types.SyncCommitteeRoot does natively:
+//
+//	hash(merkleize(hash_tree_root(pubkeys[i])), hash_tree_root(aggregate_pubkey))
+//
+// The committee size is a power of two (512 on mainnet, 32 on minimal), so the vector has no padding.
+func SyncCommitteeRoot(api frontend.API, pubkeys [][48]uints.U8, aggregatePubKey [48]uints.U8) ([32]uints.U8, error) {
+	leaves := make([][32]uints.U8, len(pubkeys))
+	var err error
+	for i := range pubkeys {
+		if leaves[i], err = BLSPubKeyRoot(api, pubkeys[i]); err != nil {
+			return [32]uints.U8{}, err
+		}
+	}
+	pubkeysRoot, err := Merkleize(api, leaves)
+	if err != nil {
+		return [32]uints.U8{}, fmt.Errorf("pubkeys: %w", err)
+	}
+	aggregateRoot, err := BLSPubKeyRoot(api, aggregatePubKey)
+	if err != nil {
+		return [32]uints.U8{}, err
+	}
+	return HashPair(api, pubkeysRoot, aggregateRoot), nil
+}
+
+// AssertChunksEqual asserts that the two 32 bytes chunks are equal
+func AssertChunksEqual(api frontend.API, a, b [32]uints.U8) {
+	for i := 0; i < 32; i++ {
+		api.AssertIsEqual(a[i].Val, b[i].Val)
+	}
+}
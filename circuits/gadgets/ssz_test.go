@@ -0,0 +1,118 @@
+package gadgets
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+// sszBranchCircuit proves Leaf at GIndex under Root, the lower Depth - UpperGIndex.Depth() levels
+// following the in-circuit Index and the upper ones the constant UpperGIndex
+type sszBranchCircuit struct {
+	UpperGIndex types.GIndex `gnark:"-"`
+
+	Leaf   [32]uints.U8
+	Branch [][32]uints.U8
+	Index  frontend.Variable
+	Root   [32]uints.U8 `gnark:",public"`
+}
+
+func (c *sszBranchCircuit) Define(api frontend.API) error {
+	lower := len(c.Branch) - c.UpperGIndex.Depth()
+	current, err := SSZBranchRootAt(api, c.Leaf, c.Branch[:lower], api.ToBinary(c.Index, lower))
+	if err != nil {
+		return err
+	}
+	return VerifySSZBranch(api, current, c.Branch[lower:], c.UpperGIndex, c.Root)
+}
+
+func toChunk(r zrntcommon.Root) [32]uints.U8 {
+	return [32]uints.U8(uints.NewU8Array(r[:]))
+}
+
+func TestVerifySSZBranch(t *testing.T) {
+	upper := types.GIndex(87)
+	const lower, index = 5, 19
+	leaf := zrntcommon.Root{0xaa, 0xbb}
+	branch := make([]zrntcommon.Root, lower+upper.Depth())
+	for i := range branch {
+		branch[i][31] = byte(i + 1)
+	}
+	gindex, err := types.ConcatGIndices(upper, types.GIndex(1<<lower+index))
+	require.NoError(t, err)
+	root, err := types.ComputeSSZBranchRoot(leaf, branch, gindex)
+	require.NoError(t, err)
+
+	circuit := &sszBranchCircuit{UpperGIndex: upper, Branch: make([][32]uints.U8, len(branch))}
+	witness := &sszBranchCircuit{Leaf: toChunk(leaf), Branch: make([][32]uints.U8, len(branch)), Index: index, Root: toChunk(root)}
+	for i := range branch {
+		witness.Branch[i] = toChunk(branch[i])
+	}
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	witness.Index = index + 1
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness.Index = index
+	witness.Leaf[0] = uints.NewU8(0xab)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the upper branch does not match the depth of its gindex
+	circuit.UpperGIndex = 55
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+}
+
+// syncCommitteeRootCircuit computes the root of a committee of len(PubKeys) members
+type syncCommitteeRootCircuit struct {
+	PubKeys         [][48]uints.U8
+	AggregatePubKey [48]uints.U8
+	Root            [32]uints.U8 `gnark:",public"`
+}
+
+func (c *syncCommitteeRootCircuit) Define(api frontend.API) error {
+	root, err := SyncCommitteeRoot(api, c.PubKeys, c.AggregatePubKey)
+	if err != nil {
+		return err
+	}
+	AssertChunksEqual(api, root, c.Root)
+	return nil
+}
+
+func TestSyncCommitteeRoot(t *testing.T) {
+	committee := &zrntcommon.SyncCommittee{Pubkeys: make([]zrntcommon.BLSPubkey, 32)}
+	for i := range committee.Pubkeys {
+		for j := range committee.Pubkeys[i] {
+			committee.Pubkeys[i][j] = byte(i*48 + j)
+		}
+	}
+	committee.AggregatePubkey[0] = 0xc0
+	root := types.SyncCommitteeRoot(committee)
+
+	circuit := &syncCommitteeRootCircuit{PubKeys: make([][48]uints.U8, len(committee.Pubkeys))}
+	witness := &syncCommitteeRootCircuit{
+		PubKeys:         make([][48]uints.U8, len(committee.Pubkeys)),
+		AggregatePubKey: [48]uints.U8(uints.NewU8Array(committee.AggregatePubkey[:])),
+		Root:            toChunk(root),
+	}
+	for i := range committee.Pubkeys {
+		witness.PubKeys[i] = [48]uints.U8(uints.NewU8Array(committee.Pubkeys[i][:]))
+	}
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// every byte of the pubkeys and of the aggregate pubkey is bound
+	witness.PubKeys[7][47] = uints.NewU8(0)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+	witness.PubKeys[7][47] = uints.NewU8(committee.Pubkeys[7][47])
+	witness.AggregatePubKey[0] = uints.NewU8(0x80)
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	// the vector is not padded
+	circuit.PubKeys = circuit.PubKeys[:24]
+	witness.PubKeys = witness.PubKeys[:24]
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+}
@@ -0,0 +1,119 @@
+package hash2curve
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/uints"
+)
+
+// XMDHash selects the hash function H of expand_message_xmd
+type XMDHash int
+
+const (
+	// SHA256 is the hash of the BLS12381G2_XMD:SHA-256 suites
+	SHA256 XMDHash = iota
+	// Keccak256 is the legacy Keccak-256 used by the EVM chains
+	Keccak256
+)
+
+// New returns a fresh in-circuit hasher
+func (h XMDHash) New(api frontend.API) (hash.BinaryFixedLengthHasher, error) {
+	switch h {
+	case SHA256:
+		return sha2.New(api)
+	case Keccak256:
+		return sha3.NewLegacyKeccak256(api)
+	}
+	return nil, fmt.Errorf("unknown expand_message_xmd hash %d", h)
+}
+
+// BlockSize is r_in_bytes, the input block size of H in bytes (the rate for Keccak)
+func (h XMDHash) BlockSize() int {
+	if h == Keccak256 {
+		return 136
+	}
+	return 64
+}
+
+// ExpandMessageXMD implements expand_message_xmd(msg, DST, len_in_bytes) from RFC 9380 with the hash
+// function H, whose output size is b_in_bytes.
+//
+// All inputs/outputs are uints.U8 in-circuit; DST and len_in_bytes are fixed at compile time.
+func ExpandMessageXMD(api frontend.API, H XMDHash, msg []uints.U8, dst []byte, lenInBytes int) ([]uints.U8, error) {
+	if len(dst) > 255 {
+		return nil, fmt.Errorf("DST of %d bytes is too long", len(dst))
+	}
+
+	// bytes gadget for XOR
+	bapi, err := uints.NewBytes(api)
+	if err != nil {
+		return nil, fmt.Errorf("NewBytes: %w", err)
+	}
+
+	// b0 = H(Z_pad || msg || l_i_b_str || 0x00 || DST')
+	h0, err := H.New(api)
+	if err != nil {
+		return nil, fmt.Errorf("new hash(b0): %w", err)
+	}
+	B := h0.Size()
+	ell := (lenInBytes + B - 1) / B
+	if lenInBytes <= 0 || ell > 255 || lenInBytes > 65535 {
+		return nil, fmt.Errorf("len_in_bytes %d out of range", lenInBytes)
+	}
+
+	// DST' = DST || I2OSP(len(DST), 1)
+	dstPrime := uints.NewU8Array(append(append([]byte{}, dst...), byte(len(dst))))
+
+	// Z_pad = I2OSP(0, r_in_bytes)
+	zPad := uints.NewU8Array(make([]byte, H.BlockSize()))
+
+	// l_i_b_str = I2OSP(len_in_bytes, 2) (big-endian)
+	lIB := uints.NewU8Array([]byte{byte(lenInBytes >> 8), byte(lenInBytes)})
+
+	h0.Write(zPad)
+	h0.Write(msg)
+	h0.Write(lIB)
+	h0.Write([]uints.U8{uints.NewU8(0x00)})
+	h0.Write(dstPrime)
+	b0 := h0.Sum()
+
+	// b1 = H(b0 || 0x01 || DST')
+	h1, err := H.New(api)
+	if err != nil {
+		return nil, fmt.Errorf("new hash(b1): %w", err)
+	}
+	h1.Write(b0)
+	h1.Write([]uints.U8{uints.NewU8(0x01)})
+	h1.Write(dstPrime)
+	b1 := h1.Sum()
+
+	// uniform_bytes = b1 || b2 || ... || b_ell (truncated)
+	uniform := make([]uints.U8, 0, ell*B)
+	uniform = append(uniform, b1...)
+	prev := b1
+	for i := 2; i <= ell; i++ {
+		// t = strxor(b0, prev)
+		t := make([]uints.U8, len(b0))
+		for j := range b0 {
+			t[j] = bapi.Xor(b0[j], prev[j])
+		}
+
+		// b_i = H(t || I2OSP(i,1) || DST')
+		hi, err := H.New(api)
+		if err != nil {
+			return nil, fmt.Errorf("new hash(b_%d): %w", i, err)
+		}
+		hi.Write(t)
+		hi.Write([]uints.U8{uints.NewU8(uint8(i))})
+		hi.Write(dstPrime)
+		bi := hi.Sum()
+
+		uniform = append(uniform, bi...)
+		prev = bi
+	}
+	return uniform[:lenInBytes], nil
+}
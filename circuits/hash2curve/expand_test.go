@@ -0,0 +1,127 @@
+package hash2curve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	stdhash "hash"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/field/hash"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+type expandCircuit struct {
+	H   XMDHash `gnark:"-"`
+	DST []byte  `gnark:"-"`
+
+	Msg      []uints.U8
+	Expected []uints.U8 `gnark:",public"`
+}
+
+func (c *expandCircuit) Define(api frontend.API) error {
+	uniform, err := ExpandMessageXMD(api, c.H, c.Msg, c.DST, len(c.Expected))
+	if err != nil {
+		return err
+	}
+	bapi, err := uints.NewBytes(api)
+	if err != nil {
+		return err
+	}
+	for i := range uniform {
+		bapi.AssertIsEqual(uniform[i], c.Expected[i])
+	}
+	return nil
+}
+
+func isExpanded(H XMDHash, dst, msg, expected []byte) error {
+	circuit := &expandCircuit{H: H, DST: dst, Msg: make([]uints.U8, len(msg)), Expected: make([]uints.U8, len(expected))}
+	witness := &expandCircuit{Msg: uints.NewU8Array(msg), Expected: uints.NewU8Array(expected)}
+	return gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField())
+}
+
+// expandNative is expand_message_xmd over any native hash, with the block size rInBytes
+func expandNative(newHash func() stdhash.Hash, rInBytes int, msg, dst []byte, lenInBytes int) []byte {
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+	h := newHash()
+	h.Write(make([]byte, rInBytes))
+	h.Write(msg)
+	h.Write([]byte{byte(lenInBytes >> 8), byte(lenInBytes), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	var uniform, prev []byte
+	for i := 1; len(uniform) < lenInBytes; i++ {
+		h.Reset()
+		if i == 1 {
+			h.Write(b0)
+		} else {
+			t := make([]byte, len(b0))
+			for j := range t {
+				t[j] = b0[j] ^ prev[j]
+			}
+			h.Write(t)
+		}
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		prev = h.Sum(nil)
+		uniform = append(uniform, prev...)
+	}
+	return uniform[:lenInBytes]
+}
+
+// TestExpandMessageXMD checks the RFC 9380 appendix K.1 vectors (expand_message_xmd with SHA-256)
+func TestExpandMessageXMD(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	vectors := []struct {
+		msg, uniform string
+	}{
+		{"", "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+		{"abc", "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615"},
+		{"abcdef0123456789", "eff31487c770a893cfb36f912fbfcbff40d5661771ca4b2cb4eafe524333f5c1"},
+	}
+	for _, v := range vectors {
+		expected, err := hex.DecodeString(v.uniform)
+		require.NoError(t, err)
+		native, err := hash.ExpandMsgXmd([]byte(v.msg), dst, len(expected))
+		require.NoError(t, err)
+		require.Equal(t, expected, native)
+		require.Equal(t, expected, expandNative(sha256.New, 64, []byte(v.msg), dst, len(expected)))
+		require.NoError(t, isExpanded(SHA256, dst, []byte(v.msg), expected), "msg %q", v.msg)
+	}
+
+	// more than one block, as hash_to_field(msg, 2) in Fp2 needs
+	msg := []byte("a message spanning several blocks")
+	expected, err := hash.ExpandMsgXmd(msg, dst, 256)
+	require.NoError(t, err)
+	require.NoError(t, isExpanded(SHA256, dst, msg, expected))
+
+	// lengths that are not a multiple of the digest size are truncated
+	expected, err = hash.ExpandMsgXmd(msg, dst, 48)
+	require.NoError(t, err)
+	require.NoError(t, isExpanded(SHA256, dst, msg, expected))
+
+	expected[40] ^= 1
+	require.Error(t, isExpanded(SHA256, dst, msg, expected))
+}
+
+func TestExpandMessageXMD_Keccak256(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-expander-KECCAK256")
+	newKeccak := func() stdhash.Hash { return crypto.NewKeccakState() }
+	for _, lenInBytes := range []int{32, 64, 100} {
+		msg := []byte("abc")
+		expected := expandNative(newKeccak, 136, msg, dst, lenInBytes)
+		require.NoError(t, isExpanded(Keccak256, dst, msg, expected), "len_in_bytes %d", lenInBytes)
+
+		expected[lenInBytes-1] ^= 1
+		require.Error(t, isExpanded(Keccak256, dst, msg, expected))
+	}
+
+	// the SHA-256 expander gives other bytes
+	expected := expandNative(newKeccak, 136, nil, dst, 32)
+	require.Error(t, isExpanded(SHA256, dst, nil, expected))
+}
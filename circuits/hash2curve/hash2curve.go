@@ -0,0 +1,251 @@
+// Package hash2curve holds the in-circuit RFC 9380 hash to curve of BLS12-381 G2, as used by the
+// BLS signatures of Ethereum.
+package hash2curve
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/fields_bls12381"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/rangecheck"
+)
+
+// EthSignatureDST is the domain separation tag of the BLS signatures of the beacon chain
+const EthSignatureDST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+
+// HashToG2 performs RFC 9380 hash_to_curve for BLS12-381 G2 (BLS12381G2_XMD:SHA-256_SSWU_RO_)
+// with the given domain separation tag.
+func HashToG2(api frontend.API, msg []uints.U8, dst []byte) (*sw_bls12381.G2Affine, error) {
+	// 1) G2 helper
+	g2, err := sw_bls12381.NewG2(api)
+	if err != nil {
+		return nil, fmt.Errorf("new G2: %w", err)
+	}
+
+	// 2) hash_to_field(msg, 2) in Fp2
+	u, err := HashToFieldFp2(api, msg, dst)
+	if err != nil {
+		return nil, fmt.Errorf("hashToFieldFp2: %w", err)
+	}
+
+	// 3) map_to_curve for each u[i], then clear the cofactor
+	Q0, err := g2.MapToG2(&u[0])
+	if err != nil {
+		return nil, fmt.Errorf("MapToG2(u[0]): %w", err)
+	}
+	Q1, err := g2.MapToG2(&u[1])
+	if err != nil {
+		return nil, fmt.Errorf("MapToG2(u[1]): %w", err)
+	}
+
+	// 4) R = Q0 + Q1 (G2 only has the complete addition AddUnified)
+	return g2.AddUnified(Q0, Q1), nil
+}
+
+// HashToFieldFp2 implements RFC 9380 hash_to_field(msg, 2) for BLS12-381 Fp2:
+//
+// uniform_bytes = expand_message_xmd(msg, DST, 256)
+// tv[i][j] = uniform_bytes[L*(j + i*m) : L*(j + i*m) + L]   (i=0..1, j=0..1)
+// u[i].A0 = OS2IP(tv[i][0]) mod p
+// u[i].A1 = OS2IP(tv[i][1]) mod p
+func HashToFieldFp2(api frontend.API, msg []uints.U8, dst []byte) ([2]fields_bls12381.E2, error) {
+	const (
+		m     = 2 // extension degree (Fp2)
+		L     = 64
+		count = 2
+	)
+
+	// the count*m coordinates are laid out as consecutive base field elements
+	e, err := HashToField[sw_bls12381.BaseField](api, SHA256, msg, dst, count*m, L)
+	if err != nil {
+		return [2]fields_bls12381.E2{}, err
+	}
+	var out [2]fields_bls12381.E2
+	for i := range out {
+		out[i].A0 = *e[i*m]
+		out[i].A1 = *e[i*m+1]
+	}
+	return out, nil
+}
+
+// HashToField implements RFC 9380 hash_to_field(msg, count) for a prime field T (m = 1), with
+// expand_message_xmd over H and L bytes per element, L = ceil((ceil(log2(p)) + k) / 8):
+//
+// uniform_bytes = expand_message_xmd(msg, DST, count * L)
+// u[i] = OS2IP(uniform_bytes[L*i : L*(i+1)]) mod p
+//
+// Extension field elements are the m consecutive elements of their coordinates.
+func HashToField[T emulated.FieldParams](api frontend.API, H XMDHash, msg []uints.U8, dst []byte, count, L int) ([]*emulated.Element[T], error) {
+	// helpers for the OS2IP reduction
+	fp, err := emulated.NewField[T](api)
+	if err != nil {
+		return nil, fmt.Errorf("new emulated field: %w", err)
+	}
+	byteAPI, err := uints.NewBytes(api)
+	if err != nil {
+		return nil, fmt.Errorf("new bytes api: %w", err)
+	}
+
+	uniform, err := ExpandMessageXMD(api, H, msg, dst, count*L)
+	if err != nil {
+		return nil, fmt.Errorf("expand_message_xmd: %w", err)
+	}
+
+	// slice uniform_bytes into tv blocks and convert to field elements
+	out := make([]*emulated.Element[T], count)
+	for i := range out {
+		if out[i], err = bytesToFieldMod(api, fp, byteAPI, uniform[L*i:L*(i+1)]); err != nil {
+			return nil, fmt.Errorf("os2ip: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// bytesToFieldMod reduces a big-endian byte slice to a field element: res = OS2IP(b) mod p.
+//
+// The quotient q and remainder r are hinted (os2ipModHint) and X = OS2IP(b) = q·p + r is checked
+// over the integers on limbs of BitsPerLimb bits, column by column with hinted carries, instead of a
+// Horner evaluation of X in the emulated field. r is range checked as an element, so it is X mod p up
+// to a multiple of p, as any emulated element.
+func bytesToFieldMod[T emulated.FieldParams](api frontend.API, fp *emulated.Field[T], byteAPI *uints.Bytes, b []uints.U8) (*emulated.Element[T], error) {
+	var params T
+	w := int(params.BitsPerLimb())
+	if w%8 != 0 {
+		return nil, fmt.Errorf("limbs of %d bits are not whole bytes", w)
+	}
+	modulus := params.Modulus()
+	nbX := (8*len(b) + w - 1) / w
+	nbP := int(params.NbLimbs())
+	qBits := max(8*len(b)-modulus.BitLen()+1, 1)
+	nbQ := (qBits + w - 1) / w
+	nbCols := max(nbQ+nbP-1, nbX)
+	// a column is at most min(nbQ, nbP) products of two limbs plus a limb and a carry, minus a limb
+	carryBits := w + bits.Len(uint(min(nbQ, nbP))) + 1
+	if api.Compiler().FieldBitLen() <= w+carryBits+1 {
+		return nil, fmt.Errorf("native field too small for limbs of %d bits", w)
+	}
+
+	// X limbs, little endian, from the big-endian bytes
+	xLimbs := make([]frontend.Variable, nbX)
+	for k := range xLimbs {
+		var limb frontend.Variable = 0
+		for j := w/8 - 1; j >= 0; j-- {
+			limb = api.Mul(limb, 256)
+			if i := len(b) - 1 - (k*w/8 + j); i >= 0 {
+				limb = api.Add(limb, byteAPI.Value(b[i]))
+			}
+		}
+		xLimbs[k] = limb
+	}
+	pLimbs := make([]*big.Int, nbP)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(w)), big.NewInt(1))
+	for k := range pLimbs {
+		pLimbs[k] = new(big.Int).And(new(big.Int).Rsh(modulus, uint(k*w)), mask)
+	}
+
+	inputs := []frontend.Variable{w, nbQ, nbP, carryBits, modulus}
+	inputs = append(inputs, xLimbs...)
+	hint, err := api.Compiler().NewHint(os2ipModHint, nbQ+nbP+nbCols-1, inputs...)
+	if err != nil {
+		return nil, fmt.Errorf("os2ip hint: %w", err)
+	}
+	q, rLimbs, carries := hint[:nbQ], hint[nbQ:nbQ+nbP], hint[nbQ+nbP:]
+
+	rc := rangecheck.New(api)
+	for k := range q {
+		rc.Check(q[k], min(w, qBits-k*w))
+	}
+	r := fp.NewElement(rLimbs) // limbs of w bits
+	offset := new(big.Int).Lsh(big.NewInt(1), uint(carryBits))
+	for k := range carries {
+		rc.Check(carries[k], carryBits+1)
+		carries[k] = api.Sub(carries[k], offset)
+	}
+
+	// column k: Σ q[i]·p[j] (i+j = k) + r[k] - X[k] + carry[k-1] = carry[k]·2^w, the last carry being 0
+	base := new(big.Int).Lsh(big.NewInt(1), uint(w))
+	var carry frontend.Variable = 0
+	for k := 0; k < nbCols; k++ {
+		col := carry
+		for i := range q {
+			if j := k - i; j >= 0 && j < nbP {
+				col = api.Add(col, api.Mul(q[i], pLimbs[j]))
+			}
+		}
+		if k < nbP {
+			col = api.Add(col, rLimbs[k])
+		}
+		if k < nbX {
+			col = api.Sub(col, xLimbs[k])
+		}
+		if k == nbCols-1 {
+			api.AssertIsEqual(col, 0)
+		} else {
+			api.AssertIsEqual(col, api.Mul(carries[k], base))
+			carry = carries[k]
+		}
+	}
+	return r, nil
+}
+
+func init() {
+	solver.RegisterHint(os2ipModHint)
+}
+
+// os2ipModHint returns the quotient and remainder limbs of X by p and the carries of the column
+// check of bytesToFieldMod, offset by 2^carryBits.
+// Inputs: limb bits w, nbQ, nbP, carryBits, p, then the limbs of X.
+func os2ipModHint(_ *big.Int, inputs, outputs []*big.Int) error {
+	if len(inputs) < 5 {
+		return fmt.Errorf("expected at least 5 inputs, got %d", len(inputs))
+	}
+	w, nbQ, nbP, carryBits := uint(inputs[0].Uint64()), int(inputs[1].Int64()), int(inputs[2].Int64()), uint(inputs[3].Uint64())
+	p, xLimbs := inputs[4], inputs[5:]
+	nbCols := len(outputs) - nbQ - nbP + 1
+	if nbCols < 1 {
+		return fmt.Errorf("expected more than %d outputs, got %d", nbQ+nbP, len(outputs))
+	}
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), w), big.NewInt(1))
+	limbs := func(v *big.Int, n int) []*big.Int {
+		out := make([]*big.Int, n)
+		for k := range out {
+			out[k] = new(big.Int).And(new(big.Int).Rsh(v, uint(k)*w), mask)
+		}
+		return out
+	}
+
+	x := new(big.Int)
+	for k := len(xLimbs) - 1; k >= 0; k-- {
+		x.Lsh(x, w).Add(x, xLimbs[k])
+	}
+	q, r := new(big.Int).QuoRem(x, p, new(big.Int))
+	qLimbs, pLimbs, rLimbs := limbs(q, nbQ), limbs(p, nbP), limbs(r, nbP)
+	copy(outputs, qLimbs)
+	copy(outputs[nbQ:], rLimbs)
+
+	offset := new(big.Int).Lsh(big.NewInt(1), carryBits)
+	carry := new(big.Int)
+	for k := 0; k < nbCols-1; k++ {
+		col := new(big.Int).Set(carry)
+		for i := range qLimbs {
+			if j := k - i; j >= 0 && j < nbP {
+				col.Add(col, new(big.Int).Mul(qLimbs[i], pLimbs[j]))
+			}
+		}
+		if k < nbP {
+			col.Add(col, rLimbs[k])
+		}
+		if k < len(xLimbs) {
+			col.Sub(col, xLimbs[k])
+		}
+		carry.Rsh(col, w) // exact, col is a multiple of 2^w
+		outputs[nbQ+nbP+k].Add(carry, offset)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package hash2curve
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bn254fp "github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+type hashToG2Circuit struct {
+	Msg      [32]uints.U8
+	Expected sw_bls12381.G2Affine `gnark:",public"`
+}
+
+func (c *hashToG2Circuit) Define(api frontend.API) error {
+	g2, err := sw_bls12381.NewG2(api)
+	if err != nil {
+		return err
+	}
+	h, err := HashToG2(api, c.Msg[:], []byte(EthSignatureDST))
+	if err != nil {
+		return err
+	}
+	g2.AssertIsEqual(h, &c.Expected)
+	return nil
+}
+
+// TestHashToG2 checks the gadget against gnark-crypto's native HashToG2 for a signing root
+func TestHashToG2(t *testing.T) {
+	var msg [32]byte
+	for i := range msg {
+		msg[i] = byte(3*i + 1)
+	}
+	expected, err := bls12381.HashToG2(msg[:], []byte(EthSignatureDST))
+	require.NoError(t, err)
+
+	witness := &hashToG2Circuit{
+		Msg:      [32]uints.U8(uints.NewU8Array(msg[:])),
+		Expected: sw_bls12381.NewG2Affine(expected),
+	}
+	require.NoError(t, gnark_test.IsSolved(&hashToG2Circuit{}, witness, ecc.BN254.ScalarField()))
+
+	witness.Msg[0] = uints.NewU8(msg[0] ^ 1)
+	require.Error(t, gnark_test.IsSolved(&hashToG2Circuit{}, witness, ecc.BN254.ScalarField()))
+}
+
+type hashToFieldCircuit struct {
+	Msg      []uints.U8
+	Expected []emulated.Element[emulated.BN254Fp] `gnark:",public"`
+}
+
+func (c *hashToFieldCircuit) Define(api frontend.API) error {
+	fp, err := emulated.NewField[emulated.BN254Fp](api)
+	if err != nil {
+		return err
+	}
+	// L = ceil((254 + 128) / 8)
+	u, err := HashToField[emulated.BN254Fp](api, SHA256, c.Msg, []byte("QUUX-V01-CS02-with-BN254G1_XMD:SHA-256_SVDW_RO_"), len(c.Expected), 48)
+	if err != nil {
+		return err
+	}
+	for i := range u {
+		fp.AssertIsEqual(u[i], &c.Expected[i])
+	}
+	return nil
+}
+
+// TestHashToField checks hash_to_field over another curve against gnark-crypto's BN254 fp.Hash
+func TestHashToField(t *testing.T) {
+	msg := []byte("abc")
+	expected, err := bn254fp.Hash(msg, []byte("QUUX-V01-CS02-with-BN254G1_XMD:SHA-256_SVDW_RO_"), 2)
+	require.NoError(t, err)
+
+	circuit := &hashToFieldCircuit{Msg: make([]uints.U8, len(msg)), Expected: make([]emulated.Element[emulated.BN254Fp], 2)}
+	witness := &hashToFieldCircuit{Msg: uints.NewU8Array(msg), Expected: make([]emulated.Element[emulated.BN254Fp], 2)}
+	for i := range expected {
+		witness.Expected[i] = emulated.ValueOf[emulated.BN254Fp](expected[i])
+	}
+	require.NoError(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+
+	witness.Expected[0], witness.Expected[1] = witness.Expected[1], witness.Expected[0]
+	require.Error(t, gnark_test.IsSolved(circuit, witness, ecc.BN254.ScalarField()))
+}
+
+type bytesToFieldModCircuit struct {
+	B        [64]uints.U8
+	Expected emulated.Element[sw_bls12381.BaseField] `gnark:",public"`
+}
+
+func (c *bytesToFieldModCircuit) Define(api frontend.API) error {
+	fp, err := emulated.NewField[sw_bls12381.BaseField](api)
+	if err != nil {
+		return err
+	}
+	byteAPI, err := uints.NewBytes(api)
+	if err != nil {
+		return err
+	}
+	res, err := bytesToFieldMod(api, fp, byteAPI, c.B[:])
+	if err != nil {
+		return err
+	}
+	fp.AssertIsEqual(res, &c.Expected)
+	return nil
+}
+
+// TestBytesToFieldMod checks the hinted OS2IP reduction at the edges of the quotient and remainder
+func TestBytesToFieldMod(t *testing.T) {
+	p := sw_bls12381.BaseField{}.Modulus()
+	top := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 512), big.NewInt(1))
+	pattern := new(big.Int)
+	for i := 0; i < 64; i++ {
+		pattern.Lsh(pattern, 8).Or(pattern, big.NewInt(int64(7*i+3)))
+	}
+	for _, x := range []*big.Int{
+		big.NewInt(0),
+		new(big.Int).Sub(p, big.NewInt(1)),
+		p,
+		new(big.Int).Lsh(p, 130),
+		top,
+		pattern,
+	} {
+		var b [64]byte
+		x.FillBytes(b[:])
+		witness := &bytesToFieldModCircuit{
+			B:        [64]uints.U8(uints.NewU8Array(b[:])),
+			Expected: emulated.ValueOf[sw_bls12381.BaseField](new(big.Int).Mod(x, p)),
+		}
+		require.NoError(t, gnark_test.IsSolved(&bytesToFieldModCircuit{}, witness, ecc.BN254.ScalarField()), x.String())
+
+		witness.Expected = emulated.ValueOf[sw_bls12381.BaseField](new(big.Int).Mod(new(big.Int).Add(x, big.NewInt(1)), p))
+		require.Error(t, gnark_test.IsSolved(&bytesToFieldModCircuit{}, witness, ecc.BN254.ScalarField()), x.String())
+	}
+}
@@ -0,0 +1,307 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/selector"
+)
+
+// MPTParams holds the compile-time bounds of a Merkle Patricia Trie proof
+type MPTParams struct {
+	// MaxDepth is the maximum number of nodes of a proof, from the root down to the leaf
+	MaxDepth int
+	// MaxNodeBytes is the maximum RLP length of a node, 532 for a branch node with 16 children
+	MaxNodeBytes int
+	// MaxValueBytes is the maximum length of the value stored in the leaf
+	MaxValueBytes int
+}
+
+// mptBranchItems is the number of items of an RLP encoded branch node (16 children and a value)
+const mptBranchItems = 17
+
+// MPTProof is the witness of a Merkle Patricia Trie inclusion proof, such as the accountProof or
+// a storageProof of eth_getProof, zero padded to the bounds of its MPTParams.
+type MPTProof struct {
+	Nodes    [][]uints.U8        // RLP encoded nodes from the root down to the leaf, MaxDepth x MaxNodeBytes
+	NodeLens []frontend.Variable // length of each node, 0 past the leaf
+	Depth    frontend.Variable   // number of nodes of the proof
+}
+
+// NewMPTProof allocates a proof witness for the given params
+func NewMPTProof(params MPTParams) MPTProof {
+	p := MPTProof{
+		Nodes:    make([][]uints.U8, params.MaxDepth),
+		NodeLens: make([]frontend.Variable, params.MaxDepth),
+	}
+	for i := range p.Nodes {
+		p.Nodes[i] = make([]uints.U8, params.MaxNodeBytes)
+	}
+	return p
+}
+
+// AssignMPTProof assigns the RLP encoded nodes of a proof (e.g. the accountProof of eth_getProof)
+func AssignMPTProof(params MPTParams, nodes [][]byte) (MPTProof, error) {
+	if len(nodes) == 0 || len(nodes) > params.MaxDepth {
+		return MPTProof{}, fmt.Errorf("proof has %d nodes, expected 1 to %d", len(nodes), params.MaxDepth)
+	}
+	p := NewMPTProof(params)
+	p.Depth = len(nodes)
+	for i := range p.Nodes {
+		var node []byte
+		if i < len(nodes) {
+			node = nodes[i]
+		}
+		if len(node) > params.MaxNodeBytes {
+			return MPTProof{}, fmt.Errorf("node %d has %d bytes, at most %d are supported", i, len(node), params.MaxNodeBytes)
+		}
+		padded := make([]byte, params.MaxNodeBytes)
+		copy(padded, node)
+		p.Nodes[i] = uints.NewU8Array(padded)
+		p.NodeLens[i] = len(node)
+	}
+	return p, nil
+}
+
+// rlpItem locates an RLP string inside a node
+type rlpItem struct {
+	off   frontend.Variable // offset of the data
+	len   frontend.Variable // length of the data
+	end   frontend.Variable // offset of the next item
+	valid frontend.Variable // 1 if the item is a string of at most 65535 bytes
+}
+
+// mptMaxValueOffset bounds the offset of the value in a leaf node: list prefix (up to 3 bytes),
+// compact path (up to 34 bytes) and value prefix (up to 3 bytes)
+const mptMaxValueOffset = 3 + 34 + 3
+
+// mptNodeSlack is the number of zero bytes read past the end of a node: the 15 one-byte items
+// walked past a 2-item node, the longest prefix, and a 32-byte reference or path
+const mptNodeSlack = 64
+
+// verifyMPTProof verifies that proof is the path of key (e.g. keccak256(address), or rlp(txIndex) in
+// a receipts trie) in the trie of the given root, and returns the value of the leaf, zero padded to
+// params.MaxValueBytes, with its length. key holds the bytes of the key, of which the first keyLen
+// are used.
+//
+// Every node is hashed with keccak256 and must match the reference held by its parent: the root
+// for the first node, then the child selected by the next key nibble (branch nodes) or the child of
+// an extension node whose shared nibbles match the key. The last node must be the leaf of the key.
+//
+// Only inclusion proofs are supported, so absent keys (e.g. storage slots holding 0) are not provable.
+// Nodes shorter than 32 bytes, which the trie embeds in their parent instead of hashing, are rejected;
+// with hashed keys this only happens in tries far larger than the Ethereum state.
+func verifyMPTProof(api frontend.API, params MPTParams, root [32]uints.U8, key []frontend.Variable, keyLen frontend.Variable, proof *MPTProof) ([]frontend.Variable, frontend.Variable, error) {
+	if len(proof.Nodes) != params.MaxDepth || len(proof.NodeLens) != params.MaxDepth {
+		return nil, nil, fmt.Errorf("proof has %d nodes, expected %d", len(proof.Nodes), params.MaxDepth)
+	}
+
+	// key nibbles (high nibble first), padded so that every key position + path offset is in range
+	maxNibbles := 2 * len(key)
+	keyNibbles := make([]frontend.Variable, 2*maxNibbles)
+	for i := range key {
+		bits := api.ToBinary(key[i], 8)
+		keyNibbles[2*i] = api.FromBinary(bits[4:]...)
+		keyNibbles[2*i+1] = api.FromBinary(bits[:4]...)
+	}
+	for i := maxNibbles; i < len(keyNibbles); i++ {
+		keyNibbles[i] = 0
+	}
+	nbKeyNibbles := api.Mul(keyLen, 2)
+	api.AssertIsLessOrEqual(keyLen, len(key))
+
+	// isLast[i] is 1 for the leaf, exactly one node is the leaf
+	isLast := make([]frontend.Variable, params.MaxDepth)
+	var nbLast frontend.Variable = 0
+	for i := range isLast {
+		isLast[i] = api.IsZero(api.Sub(proof.Depth, i+1))
+		nbLast = api.Add(nbLast, isLast[i])
+	}
+	api.AssertIsEqual(nbLast, 1)
+
+	expected := make([]frontend.Variable, 32)
+	for k := range expected {
+		expected[k] = root[k].Val
+	}
+	var active, keyPos frontend.Variable = 1, 0
+
+	// the bytes of the leaf, with the offset and length of its value
+	leaf := make([]frontend.Variable, params.MaxNodeBytes+mptNodeSlack)
+	for k := range leaf {
+		leaf[k] = 0
+	}
+	var valueOff, valueLen frontend.Variable = 0, 0
+
+	for i := 0; i < params.MaxDepth; i++ {
+		if len(proof.Nodes[i]) != params.MaxNodeBytes {
+			return nil, nil, fmt.Errorf("node %d has %d bytes, expected %d", i, len(proof.Nodes[i]), params.MaxNodeBytes)
+		}
+		nodeLen := proof.NodeLens[i]
+
+		// the node must hash to the reference held by its parent
+		digest, err := keccak256Sum(api, proof.Nodes[i], nodeLen)
+		if err != nil {
+			return nil, nil, err
+		}
+		for k := 0; k < 32; k++ {
+			api.AssertIsEqual(api.Mul(active, api.Sub(digest[k].Val, expected[k])), 0)
+		}
+		api.AssertIsLessOrEqual(api.Select(active, 32, nodeLen), nodeLen)
+
+		// node bytes, with slack for the reads past the end of short nodes
+		b := make([]frontend.Variable, params.MaxNodeBytes+mptNodeSlack)
+		for k := range b {
+			if k < params.MaxNodeBytes {
+				b[k] = proof.Nodes[i][k].Val
+			} else {
+				b[k] = 0
+			}
+		}
+		byteAt := func(off frontend.Variable) frontend.Variable {
+			return selector.Mux(api, off, b...)
+		}
+
+		// list header: 0xc0+len, 0xf8 len or 0xf9 len len
+		b0 := api.ToBinary(b[0], 8)
+		isF8 := api.IsZero(api.Sub(b[0], 0xf8))
+		isF9 := api.IsZero(api.Sub(b[0], 0xf9))
+		isShortList := api.Mul(api.And(b0[7], b0[6]), api.Sub(1, api.And(api.And(b0[5], b0[4]), b0[3])))
+		api.AssertIsEqual(api.Mul(active, api.Sub(1, api.Add(isShortList, isF8, isF9))), 0)
+		payloadOff := api.Add(isShortList, api.Mul(isF8, 2), api.Mul(isF9, 3))
+		payloadLen := api.Add(
+			api.Mul(isShortList, api.Sub(b[0], 0xc0)),
+			api.Mul(isF8, b[1]),
+			api.Mul(isF9, api.Add(api.Mul(b[1], 256), b[2])),
+		)
+		payloadEnd := api.Add(payloadOff, payloadLen)
+		api.AssertIsEqual(api.Mul(active, api.Sub(payloadEnd, nodeLen)), 0)
+
+		// walk the items: a node is either a branch (17 items) or a leaf/extension (2 items)
+		items := make([]rlpItem, mptBranchItems)
+		off := payloadOff
+		for j := range items {
+			items[j] = decodeRLPString(api, byteAt, off)
+			off = items[j].end
+		}
+		isShort := api.IsZero(api.Sub(items[1].end, payloadEnd))
+		isBranch := api.IsZero(api.Sub(items[mptBranchItems-1].end, payloadEnd))
+		api.AssertIsEqual(api.Mul(active, api.Sub(1, api.Add(isShort, isBranch))), 0)
+		for j := range items {
+			inNode := active
+			if j >= 2 {
+				inNode = api.Mul(active, isBranch)
+			}
+			api.AssertIsEqual(api.Mul(inNode, api.Sub(1, items[j].valid)), 0)
+		}
+
+		// leaf or extension: the compact encoded path must match the key from keyPos on
+		pathLen := items[0].len
+		pathBytes := make([][]frontend.Variable, len(key)+1) // nibbles (high, low) of each path byte
+		for k := range pathBytes {
+			bits := api.ToBinary(byteAt(api.Add(items[0].off, k)), 8)
+			pathBytes[k] = []frontend.Variable{api.FromBinary(bits[4:]...), api.FromBinary(bits[:4]...)}
+		}
+		flag := api.ToBinary(pathBytes[0][0], 4)
+		odd, isLeaf := flag[0], api.Mul(isShort, flag[1])
+		isExtension := api.Sub(isShort, isLeaf)
+		api.AssertIsEqual(api.Mul(api.Mul(active, isShort), api.Or(flag[2], flag[3])), 0)
+		nbNibbles := api.Add(api.Mul(api.Sub(pathLen, 1), 2), odd)
+		api.AssertIsLessOrEqual(api.Select(api.Mul(active, isShort), pathLen, 1), len(key)+1)
+
+		var inPath frontend.Variable = 1 // 1 while m < nbNibbles
+		for m := 0; m < maxNibbles; m++ {
+			inPath = api.Sub(inPath, api.IsZero(api.Sub(nbNibbles, m)))
+			var even, oddNibble frontend.Variable
+			even = pathBytes[1+m/2][m%2]
+			if m == 0 {
+				oddNibble = pathBytes[0][1]
+			} else {
+				oddNibble = pathBytes[1+(m-1)/2][(m-1)%2]
+			}
+			pathNibble := api.Select(odd, oddNibble, even)
+			keyNibble := selector.Mux(api, api.Add(keyPos, m), keyNibbles...)
+			api.AssertIsEqual(api.Mul(api.Mul(active, isShort), api.Mul(inPath, api.Sub(pathNibble, keyNibble))), 0)
+		}
+
+		// only the last node is a leaf, and the leaf consumes the whole key
+		api.AssertIsEqual(api.Mul(active, api.Sub(isLast[i], isLeaf)), 0)
+		api.AssertIsEqual(api.Mul(isLast[i], api.Sub(api.Add(keyPos, nbNibbles), nbKeyNibbles)), 0)
+
+		// the leaf and its value
+		for k := range leaf {
+			leaf[k] = api.Add(leaf[k], api.Mul(isLast[i], b[k]))
+		}
+		valueOff = api.Add(valueOff, api.Mul(isLast[i], items[1].off))
+		valueLen = api.Add(valueLen, api.Mul(isLast[i], items[1].len))
+
+		// the reference of the next node: the child at the key nibble, or the child of the extension
+		nibble := selector.Mux(api, keyPos, keyNibbles...)
+		childOffs := make([]frontend.Variable, 16)
+		childLens := make([]frontend.Variable, 16)
+		for j := 0; j < 16; j++ {
+			childOffs[j], childLens[j] = items[j].off, items[j].len
+		}
+		childOff := api.Select(isBranch, selector.Mux(api, nibble, childOffs...), items[1].off)
+		childLen := api.Select(isBranch, selector.Mux(api, nibble, childLens...), items[1].len)
+		next := api.Sub(active, isLast[i])
+		api.AssertIsEqual(api.Mul(next, api.Sub(childLen, 32)), 0)
+		for k := range expected {
+			expected[k] = byteAt(api.Add(childOff, k))
+		}
+
+		// the key may be consumed by a branch, whose child is then a leaf with an empty path
+		keyPos = api.Add(keyPos, isBranch, api.Mul(isExtension, nbNibbles))
+		api.AssertIsLessOrEqual(api.Select(next, keyPos, 0), nbKeyNibbles)
+		active = next
+	}
+
+	// shift the value out of the leaf
+	api.AssertIsLessOrEqual(valueOff, mptMaxValueOffset)
+	api.AssertIsLessOrEqual(valueLen, params.MaxValueBytes)
+	value := make([]frontend.Variable, params.MaxValueBytes)
+	for k := range value {
+		window := make([]frontend.Variable, mptMaxValueOffset+1)
+		for j := range window {
+			if k+j < len(leaf) {
+				window[j] = leaf[k+j]
+			} else {
+				window[j] = 0
+			}
+		}
+		value[k] = selector.Mux(api, valueOff, window...)
+	}
+	// bytes past the end of the value are zero
+	var inValue frontend.Variable = 1
+	for k := range value {
+		inValue = api.Sub(inValue, api.IsZero(api.Sub(valueLen, k)))
+		value[k] = api.Mul(inValue, value[k])
+	}
+
+	return value, valueLen, nil
+}
+
+// decodeRLPString decodes the prefix of the RLP item at off: a single byte (< 0x80), a string of up
+// to 55 bytes (0x80+len) or a string of up to 65535 bytes (0xb8 len, 0xb9 len len). Other items are
+// not valid.
+func decodeRLPString(api frontend.API, byteAt func(frontend.Variable) frontend.Variable, off frontend.Variable) rlpItem {
+	p := byteAt(off)
+	bits := api.ToBinary(p, 8)
+	single := api.Sub(1, bits[7])
+	str := api.Mul(bits[7], api.Sub(1, bits[6]))
+	long := api.Mul(str, api.And(api.And(bits[5], bits[4]), bits[3]))
+	short := api.Sub(str, long)
+	isB8 := api.IsZero(api.Sub(p, 0xb8))
+	isB9 := api.IsZero(api.Sub(p, 0xb9))
+	len1 := byteAt(api.Add(off, 1))
+	len2 := byteAt(api.Add(off, 2))
+
+	shortLen := api.Sub(p, 0x80)
+	item := rlpItem{
+		off:   api.Add(off, short, api.Mul(isB8, 2), api.Mul(isB9, 3)),
+		len:   api.Add(single, api.Mul(short, shortLen), api.Mul(isB8, len1), api.Mul(isB9, api.Add(api.Mul(len1, 256), len2))),
+		valid: api.Add(single, short, isB8, isB9),
+	}
+	item.end = api.Add(item.off, item.len)
+	return item
+}
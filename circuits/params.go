@@ -0,0 +1,165 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/kysee/zk-chains/types"
+)
+
+// CircuitParams holds the compile-time parameters of Eth2ScUpdateCircuit.
+// They change the constraint system, so proving and verifying keys are only valid
+// for the params the circuit was compiled with.
+// The zero value matches the original circuit layout, with every pubkey checked to be in G1.
+type CircuitParams struct {
+	// ScPubKeysHashMode selects the serialization of the pubkeys committed to by ScPubKeysHash
+	ScPubKeysHashMode types.ScPubKeysHashMode
+	// NextScGIndex is the generalized index of next_sync_committee in the BeaconState of the
+	// target fork; it fixes the length and the path of NextScBranch. Zero means Electra/Fulu (87).
+	NextScGIndex types.GIndex
+	// ScPubKeysCheck selects the validation of the witnessed sync committee pubkeys
+	ScPubKeysCheck PubKeyCheck
+	// SigCheck selects the validation of the witnessed AggregatedSig
+	SigCheck SigCheck
+	// Preset fixes the number of sync committee members (the length of ScPubKeys and ScBits)
+	// and the slots per period. Zero means mainnet.
+	Preset types.Preset
+	// Domain is the sync committee signing domain of the target network, assigned to the public Domain
+	// input by the witness builders. It does not change the constraint system, zero means DOMAIN.
+	Domain [32]byte
+	// PublicStateRoot appends the StateRoot of the attested header to the public inputs (AttestedStateRoot),
+	// for consumers verifying their own SSZ proofs against it. It changes the public layout.
+	PublicStateRoot bool
+}
+
+// NewNetworkCircuitParams returns the params of a known network at the given fork, with the default
+// pubkeys hash mode and check
+func NewNetworkCircuitParams(network *types.Network, fork string) (CircuitParams, error) {
+	gindex, err := types.NextSyncCommitteeGIndexForFork(fork)
+	if err != nil {
+		return CircuitParams{}, err
+	}
+	domain, err := network.SyncCommitteeDomain(fork)
+	if err != nil {
+		return CircuitParams{}, err
+	}
+	return CircuitParams{NextScGIndex: gindex, Preset: network.Preset, Domain: domain}, nil
+}
+
+// PubKeyCheck selects how the witnessed sync committee pubkeys are validated in-circuit.
+//
+// ScPubKeys are private inputs only bound by ScPubKeysHash, which (in truncated mode) does not
+// determine the points. Without a check a malicious prover could witness off-curve or
+// small-order points which hash correctly and break the incomplete additions of aggregatePubKeys.
+type PubKeyCheck uint8
+
+const (
+	// PubKeyCheckSubgroup asserts every pubkey is on the curve and in the prime order subgroup.
+	// Like with PubKeyCheckOnCurve, the point at infinity is accepted and never aggregated.
+	PubKeyCheckSubgroup PubKeyCheck = iota
+	// PubKeyCheckOnCurve only asserts every pubkey is on the curve. It is much cheaper, but relies on the
+	// committee being subgroup checked natively by the relayer and the contract deployer.
+	PubKeyCheckOnCurve
+	// PubKeyCheckNone skips the checks, for tests and benchmarks only
+	PubKeyCheckNone
+)
+
+func (c PubKeyCheck) String() string {
+	switch c {
+	case PubKeyCheckSubgroup:
+		return "subgroup"
+	case PubKeyCheckOnCurve:
+		return "curve"
+	case PubKeyCheckNone:
+		return "none"
+	default:
+		return fmt.Sprintf("PubKeyCheck(%d)", uint8(c))
+	}
+}
+
+// ParsePubKeyCheck parses the name returned by PubKeyCheck.String.
+func ParsePubKeyCheck(s string) (PubKeyCheck, error) {
+	switch s {
+	case "", "subgroup":
+		return PubKeyCheckSubgroup, nil
+	case "curve":
+		return PubKeyCheckOnCurve, nil
+	case "none":
+		return PubKeyCheckNone, nil
+	default:
+		return 0, fmt.Errorf("unknown pubkey check: %q", s)
+	}
+}
+
+// SigCheck selects how the witnessed AggregatedSig is validated in-circuit.
+//
+// The pairing check is only specified for points of G2: on points of the twist outside the prime order
+// subgroup, what e(-G1, AggregatedSig) evaluates to depends on the Miller loop of the gnark version, so
+// the signature is explicitly checked rather than relying on PairingCheck to reject it.
+type SigCheck uint8
+
+const (
+	// SigCheckSubgroup asserts the signature is on the twist and in the prime order subgroup:
+	// ψ(Q) == [x₀]Q, x₀ being the seed of the curve (Scott, "A note on group membership tests for G1, G2
+	// and GT on BLS pairing-friendly curves")
+	SigCheckSubgroup SigCheck = iota
+	// SigCheckOnTwist only asserts the signature is on the twist, relying on the relayer checking the
+	// subgroup natively (bls12381.G2Affine.SetBytes does). For benchmarks of the subgroup check only.
+	SigCheckOnTwist
+)
+
+func (c SigCheck) String() string {
+	switch c {
+	case SigCheckSubgroup:
+		return "subgroup"
+	case SigCheckOnTwist:
+		return "twist"
+	default:
+		return fmt.Sprintf("SigCheck(%d)", uint8(c))
+	}
+}
+
+// ParseSigCheck parses the name returned by SigCheck.String.
+func ParseSigCheck(s string) (SigCheck, error) {
+	switch s {
+	case "", "subgroup":
+		return SigCheckSubgroup, nil
+	case "twist":
+		return SigCheckOnTwist, nil
+	default:
+		return 0, fmt.Errorf("unknown signature check: %q", s)
+	}
+}
+
+// NextSyncCommitteeGIndex returns NextScGIndex, defaulting to the Electra/Fulu layout
+func (p CircuitParams) NextSyncCommitteeGIndex() types.GIndex {
+	if p.NextScGIndex == 0 {
+		return types.NextSyncCommitteeGIndexElectra
+	}
+	return p.NextScGIndex
+}
+
+// SigningDomain returns Domain, defaulting to DOMAIN
+func (p CircuitParams) SigningDomain() [32]byte {
+	if p.Domain == ([32]byte{}) {
+		return DOMAIN
+	}
+	return p.Domain
+}
+
+// publicStateRootLen returns the length of the public AttestedStateRoot: 32 with PublicStateRoot, 0 otherwise
+func (p CircuitParams) publicStateRootLen() int {
+	if p.PublicStateRoot {
+		return 32
+	}
+	return 0
+}
+
+// SyncCommitteeSize returns the number of sync committee members of Preset
+func (p CircuitParams) SyncCommitteeSize() int {
+	return p.Preset.SyncCommitteeSize()
+}
+
+// SlotsPerPeriodLog2 returns log2 of the slots per sync committee period of Preset
+func (p CircuitParams) SlotsPerPeriodLog2() int {
+	return p.Preset.SlotsPerPeriodLog2()
+}
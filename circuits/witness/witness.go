@@ -0,0 +1,266 @@
+// Package witness builds the witnesses of the sync committee circuits of the circuit package from the
+// beacon API objects (light client updates and sync committees), so provers do not have to assign the
+// header bytes, pubkeys, bits and branches themselves.
+//
+// The Eth2ScUpdateCircuit witness is the base of its derived circuits: PackedAssignment, HashedAssignment
+// and ToyAssignment convert the one returned by BuildScUpdateWitness.
+package witness
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sync"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/circuits"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// forEach calls fn with each index below n, the indexes split in ranges over GOMAXPROCS goroutines.
+// It returns the error of the lowest index that failed.
+func forEach(n int, fn func(i int) error) error {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := range n {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	size := (n + workers - 1) / workers
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w * size; i < min((w+1)*size, n); i++ {
+				if errs[w] = fn(i); errs[w] != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSyncCommittee decompresses the pubkeys of sc in parallel, rejecting points outside G1. The point
+// at infinity is accepted, the circuits never aggregate it.
+func ParseSyncCommittee(sc *zrntcommon.SyncCommittee) ([]bls12381.G1Affine, error) {
+	pubkeys := make([]bls12381.G1Affine, len(sc.Pubkeys))
+	err := forEach(len(pubkeys), func(i int) error {
+		if _, err := pubkeys[i].SetBytes(sc.Pubkeys[i][:]); err != nil {
+			return fmt.Errorf("pubkey %d: %w", i, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pubkeys, nil
+}
+
+// ParseSyncAggregate returns the participation bits of the sync aggregate of update, the first n of
+// them, and its signature checked to be in G2
+func ParseSyncAggregate(update *types.LightClientUpdate, n int) ([]bool, bls12381.G2Affine, error) {
+	var signature bls12381.G2Affine
+	if _, err := signature.SetBytes(update.Data.SyncAggregate.SyncCommitteeSignature[:]); err != nil {
+		return nil, signature, fmt.Errorf("failed to deserialize signature: %w", err)
+	}
+	bits := types.ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits)
+	if len(bits) < n {
+		return nil, signature, fmt.Errorf("%d sync committee bits for a committee of %d", len(bits), n)
+	}
+	return bits[:n], signature, nil
+}
+
+// BuildScUpdateWitness builds the Eth2ScUpdateCircuit witness of update, signed by committee
+func BuildScUpdateWitness(update *types.LightClientUpdate, committee *zrntcommon.SyncCommittee, params circuit.CircuitParams) (*circuit.Eth2ScUpdateCircuit, error) {
+	pubkeys, err := ParseSyncCommittee(committee)
+	if err != nil {
+		return nil, fmt.Errorf("sync committee: %w", err)
+	}
+	return BuildScUpdateWitnessFromPubKeys(update, pubkeys, params)
+}
+
+// BuildScUpdateWitnessFromPubKeys is BuildScUpdateWitness for a committee already decompressed,
+// e.g. kept across updates by a relayer
+func BuildScUpdateWitnessFromPubKeys(update *types.LightClientUpdate, pubkeys []bls12381.G1Affine, params circuit.CircuitParams) (*circuit.Eth2ScUpdateCircuit, error) {
+	w := circuit.NewEth2ScUpdateCircuit(params)
+	if len(pubkeys) != len(w.ScPubKeys) {
+		return nil, fmt.Errorf("expected %d pubkeys, got %d", len(w.ScPubKeys), len(pubkeys))
+	}
+	bits, signature, err := ParseSyncAggregate(update, len(w.ScBits))
+	if err != nil {
+		return nil, err
+	}
+
+	assignAttestedHeader(update, w)
+
+	// the emulated limbs of the pubkeys are assigned in parallel, next to their hash
+	var scPubKeysHash [32]byte
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scPubKeysHash = types.ComputeScPubKeysHashWithMode(pubkeys, params.ScPubKeysHashMode)
+	}()
+	_ = forEach(len(pubkeys), func(i int) error {
+		w.ScPubKeys[i] = sw_bls12381.NewG1Affine(pubkeys[i])
+		if bits[i] {
+			w.ScBits[i] = 1
+		} else {
+			w.ScBits[i] = 0
+		}
+		return nil
+	})
+	wg.Wait()
+	w.ScPubKeysHash = [32]uints.U8(uints.NewU8Array(scPubKeysHash[:]))
+	w.AggregatedSig = sw_bls12381.NewG2Affine(signature)
+
+	if err := AssignNextSyncCommittee(update, w); err != nil {
+		return nil, err
+	}
+	if err := AssignExecution(update, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// BuildScAggPubKeyWitness builds the Eth2ScUpdateAggPubKeyCircuit witness of update, signed by committee.
+// Unlike BuildScUpdateWitness it only decompresses the pubkeys of the participants, about half the work
+// and memory at the usual participation rates, the others may even be undecodable.
+func BuildScAggPubKeyWitness(update *types.LightClientUpdate, committee *zrntcommon.SyncCommittee, params circuit.CircuitParams) (*circuit.Eth2ScUpdateAggPubKeyCircuit, error) {
+	n := params.SyncCommitteeSize()
+	if len(committee.Pubkeys) != n {
+		return nil, fmt.Errorf("expected %d pubkeys, got %d", n, len(committee.Pubkeys))
+	}
+	bits, signature, err := ParseSyncAggregate(update, n)
+	if err != nil {
+		return nil, err
+	}
+	aggregate, _, err := types.AggregatePublicKeys(committee.Pubkeys, bits)
+	if err != nil {
+		return nil, fmt.Errorf("sync committee: %w", err)
+	}
+
+	// the committee of w is left unassigned, AggPubKeyAssignment drops it
+	w := circuit.NewEth2ScUpdateCircuit(params)
+	assignAttestedHeader(update, w)
+	w.AggregatedSig = sw_bls12381.NewG2Affine(signature)
+	if err := AssignNextSyncCommittee(update, w); err != nil {
+		return nil, err
+	}
+	if err := AssignExecution(update, w); err != nil {
+		return nil, err
+	}
+	return w.AggPubKeyAssignment(aggregate), nil
+}
+
+// assignAttestedHeader assigns the attested header of update, with its slot, period and signing domain, to w
+func assignAttestedHeader(update *types.LightClientUpdate, w *circuit.Eth2ScUpdateCircuit) {
+	header := &update.Data.AttestedHeader.Beacon
+	w.Slot = uint64(header.Slot)
+	w.ProposerIndex = uint64(header.ProposerIndex)
+	w.ParentRoot = [32]uints.U8(uints.NewU8Array(header.ParentRoot[:]))
+	w.StateRoot = [32]uints.U8(uints.NewU8Array(header.StateRoot[:]))
+	w.BodyRoot = [32]uints.U8(uints.NewU8Array(header.BodyRoot[:]))
+	w.Period = w.Params.Preset.Period(uint64(header.Slot))
+	w.AttestedSlot = uint64(header.Slot)
+	domain := w.Params.SigningDomain()
+	w.Domain = [32]uints.U8(uints.NewU8Array(domain[:]))
+	copy(w.AttestedStateRoot, w.StateRoot[:])
+}
+
+// AssignNextSyncCommittee assigns the root of the next_sync_committee of update (public input) and its
+// branch to StateRoot (private input) to w
+func AssignNextSyncCommittee(update *types.LightClientUpdate, w *circuit.Eth2ScUpdateCircuit) error {
+	branch := update.Data.NextSyncCommitteeBranch
+	if len(branch) != len(w.NextScBranch) {
+		return fmt.Errorf("next_sync_committee branch length %d does not match depth %d", len(branch), len(w.NextScBranch))
+	}
+	nextScRoot := types.SyncCommitteeRoot(&update.Data.NextSyncCommittee)
+	w.NextScRoot = [32]uints.U8(uints.NewU8Array(nextScRoot[:]))
+	for i := range branch {
+		w.NextScBranch[i] = [32]uints.U8(uints.NewU8Array(branch[i][:]))
+	}
+	return nil
+}
+
+// AssignExecution assigns the attested execution block_hash and block_number (public inputs) and their
+// branches to BodyRoot (private inputs) to w
+func AssignExecution(update *types.LightClientUpdate, w *circuit.Eth2ScUpdateCircuit) error {
+	proof, err := types.ExecutionFieldProof(update, types.ExecutionBlockHashGIndex)
+	if err != nil {
+		return fmt.Errorf("execution block hash: %w", err)
+	}
+	w.ExecBlockHash = [32]uints.U8(uints.NewU8Array(proof.Leaf[:]))
+	for i := range w.ExecBlockHashBranch {
+		w.ExecBlockHashBranch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+
+	proof, err = types.ExecutionFieldProof(update, types.ExecutionBlockNumberGIndex)
+	if err != nil {
+		return fmt.Errorf("execution block number: %w", err)
+	}
+	w.ExecBlockNumber = binary.LittleEndian.Uint64(proof.Leaf[:8])
+	for i := range w.ExecBlockNumberBranch {
+		w.ExecBlockNumberBranch[i] = [32]uints.U8(uints.NewU8Array(proof.Branch[i][:]))
+	}
+	return nil
+}
+
+// BuildScSignatureWitness builds the Eth2ScSignatureCircuit witness of update, signed by committee
+func BuildScSignatureWitness(update *types.LightClientUpdate, committee *zrntcommon.SyncCommittee, params circuit.CircuitParams) (*circuit.Eth2ScSignatureCircuit, error) {
+	w, err := BuildScUpdateWitness(update, committee, params)
+	if err != nil {
+		return nil, err
+	}
+	return w.SignatureAssignment(&update.Data.AttestedHeader.Beacon), nil
+}
+
+// BuildScRotationWitness builds the Eth2ScRotationCircuit witness of the next_sync_committee of update
+func BuildScRotationWitness(update *types.LightClientUpdate, params circuit.CircuitParams) (*circuit.Eth2ScRotationCircuit, error) {
+	return circuit.NewEth2ScRotationAssignment(params, &update.Data.AttestedHeader.Beacon,
+		types.SyncCommitteeRoot(&update.Data.NextSyncCommittee), update.Data.NextSyncCommitteeBranch)
+}
+
+// BuildScChainedWitness builds the Eth2ScUpdateChainedCircuit witness of update, signed by committee
+func BuildScChainedWitness(update *types.LightClientUpdate, committee *zrntcommon.SyncCommittee, params circuit.CircuitParams) (*circuit.Eth2ScUpdateChainedCircuit, error) {
+	w, err := BuildScUpdateWitness(update, committee, params)
+	if err != nil {
+		return nil, err
+	}
+	return w.ChainedAssignment(&update.Data.NextSyncCommittee)
+}
+
+// BuildScHandoverWitness builds the Eth2ScHandoverCircuit witness of update, signed by committee
+func BuildScHandoverWitness(update *types.LightClientUpdate, committee *zrntcommon.SyncCommittee, params circuit.CircuitParams) (*circuit.Eth2ScHandoverCircuit, error) {
+	pubkeys, err := ParseSyncCommittee(committee)
+	if err != nil {
+		return nil, fmt.Errorf("sync committee: %w", err)
+	}
+	bits, signature, err := ParseSyncAggregate(update, len(pubkeys))
+	if err != nil {
+		return nil, err
+	}
+	return circuit.NewEth2ScHandoverAssignment(params, &update.Data.AttestedHeader.Beacon, pubkeys, bits, signature,
+		&update.Data.NextSyncCommittee, update.Data.NextSyncCommitteeBranch)
+}
+
+// BuildFinalityWitness builds the Eth2FinalityCircuit witness of the finalized header of update
+func BuildFinalityWitness(update *types.LightClientUpdate) (*circuit.Eth2FinalityCircuit, error) {
+	return circuit.NewEth2FinalityAssignment(update.Version, &update.Data.AttestedHeader.Beacon,
+		&update.Data.FinalizedHeader.Beacon, update.Data.FinalityBranch)
+}
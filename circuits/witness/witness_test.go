@@ -0,0 +1,155 @@
+package witness
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	gnark_test "github.com/consensys/gnark/test"
+	"github.com/kysee/zk-chains/circuits"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+// loadUpdates returns sc-update-1104 and sc-update-1105, the latter signed by the next committee of the former
+func loadUpdates(t *testing.T) (*types.LightClientUpdate, *types.LightClientUpdate) {
+	var updates [2]types.LightClientUpdate
+	for i, name := range []string{"sc-update-1104.json", "sc-update-1105.json"} {
+		data, err := os.ReadFile(filepath.Join("..", "..", "data", name))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &updates[i]))
+	}
+	return &updates[0], &updates[1]
+}
+
+func TestBuildScUpdateWitness(t *testing.T) {
+	prev, update := loadUpdates(t)
+	committee := &prev.Data.NextSyncCommittee
+
+	w, err := BuildScUpdateWitness(update, committee, circuit.CircuitParams{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(update.Data.AttestedHeader.Beacon.Slot), w.Slot)
+	require.Empty(t, w.AttestedStateRoot)
+
+	// the same from the decompressed committee
+	pubkeys, err := ParseSyncCommittee(committee)
+	require.NoError(t, err)
+	fromPubKeys, err := BuildScUpdateWitnessFromPubKeys(update, pubkeys, circuit.CircuitParams{})
+	require.NoError(t, err)
+	require.Equal(t, w, fromPubKeys)
+
+	// the public inputs are the ones pinned for the verifier contract
+	public, err := frontend.NewWitness(w, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	data, err := public.MarshalBinary()
+	require.NoError(t, err)
+	golden, err := os.ReadFile(filepath.Join("..", "testdata", "eth2_sc_update_public_witness.golden"))
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimSpace(string(golden)), hex.EncodeToString(data))
+
+	withRoot, err := BuildScUpdateWitness(update, committee, circuit.CircuitParams{PublicStateRoot: true})
+	require.NoError(t, err)
+	require.Equal(t, w.StateRoot[:], withRoot.AttestedStateRoot)
+
+	// a committee of another preset
+	_, err = BuildScUpdateWitness(update, committee, circuit.CircuitParams{Preset: types.PresetMinimal})
+	require.Error(t, err)
+	// a branch of another fork
+	_, err = BuildScUpdateWitness(update, committee, circuit.CircuitParams{NextScGIndex: types.NextSyncCommitteeGIndexAltair})
+	require.Error(t, err)
+	// an undecodable signature
+	bad := *update
+	bad.Data.SyncAggregate.SyncCommitteeSignature[0] ^= 0xff
+	_, err = BuildScUpdateWitness(&bad, committee, circuit.CircuitParams{})
+	require.Error(t, err)
+}
+
+func TestParseSyncCommittee(t *testing.T) {
+	prev, _ := loadUpdates(t)
+	committee := prev.Data.NextSyncCommittee
+
+	// the pubkeys are decompressed in parallel, in order
+	pubkeys, err := ParseSyncCommittee(&committee)
+	require.NoError(t, err)
+	require.Len(t, pubkeys, len(committee.Pubkeys))
+	for _, i := range []int{0, 255, 511} {
+		require.Equal(t, [48]byte(committee.Pubkeys[i]), pubkeys[i].Bytes())
+	}
+
+	// the first invalid pubkey is reported
+	committee.Pubkeys = append([]zrntcommon.BLSPubkey{}, committee.Pubkeys...)
+	committee.Pubkeys[300][5] ^= 0xff
+	committee.Pubkeys[400][5] ^= 0xff
+	_, err = ParseSyncCommittee(&committee)
+	require.ErrorContains(t, err, "pubkey 300")
+}
+
+func TestBuildScAggPubKeyWitness(t *testing.T) {
+	prev, update := loadUpdates(t)
+	committee := prev.Data.NextSyncCommittee
+	params := circuit.CircuitParams{}
+
+	w, err := BuildScAggPubKeyWitness(update, &committee, params)
+	require.NoError(t, err)
+
+	// the public inputs the update witness shares, and the aggregate of the participants
+	full, err := BuildScUpdateWitness(update, &committee, params)
+	require.NoError(t, err)
+	require.Equal(t, full.NextScRoot, w.NextScRoot)
+	require.Equal(t, full.ExecBlockHash, w.ExecBlockHash)
+	require.Equal(t, full.Period, w.Period)
+	bits := types.ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits)
+	aggregate, _, err := types.AggregatePublicKeys(committee.Pubkeys, bits)
+	require.NoError(t, err)
+	require.Equal(t, full.AggPubKeyAssignment(aggregate), w)
+
+	// the pubkeys of the non participants are not decompressed
+	committee.Pubkeys = append([]zrntcommon.BLSPubkey{}, committee.Pubkeys...)
+	absent, present := -1, -1
+	for i, bit := range bits {
+		if !bit {
+			committee.Pubkeys[i][5] ^= 0xff
+			absent = i
+		} else if present < 0 {
+			present = i
+		}
+	}
+	require.GreaterOrEqual(t, absent, 0, "every member participated")
+	withInvalid, err := BuildScAggPubKeyWitness(update, &committee, params)
+	require.NoError(t, err)
+	require.Equal(t, w, withInvalid)
+
+	// those of the participants are
+	committee.Pubkeys[present][5] ^= 0xff
+	_, err = BuildScAggPubKeyWitness(update, &committee, params)
+	require.Error(t, err)
+
+	// a committee of another preset
+	_, err = BuildScAggPubKeyWitness(update, &prev.Data.NextSyncCommittee, circuit.CircuitParams{Preset: types.PresetMinimal})
+	require.Error(t, err)
+}
+
+func TestBuildScRotationWitness(t *testing.T) {
+	_, update := loadUpdates(t)
+	params := circuit.CircuitParams{}
+	w, err := BuildScRotationWitness(update, params)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit.NewEth2ScRotationCircuit(params), w, ecc.BN254.ScalarField()))
+
+	update.Data.NextSyncCommittee.AggregatePubkey[0] ^= 1
+	_, err = BuildScRotationWitness(update, params)
+	require.Error(t, err)
+}
+
+func TestBuildFinalityWitness(t *testing.T) {
+	prev, _ := loadUpdates(t)
+	w, err := BuildFinalityWitness(prev)
+	require.NoError(t, err)
+	require.NoError(t, gnark_test.IsSolved(circuit.NewEth2FinalityCircuit(), w, ecc.BN254.ScalarField()))
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	relayer "github.com/kysee/zk-chains/provers"
+	"github.com/kysee/zk-chains/types"
+)
+
+// maxManifestSize bounds the manifest read from a store
+const maxManifestSize = 16 << 20
+
+// Store is where the artifacts of the setups are published, under one directory per version
+type Store interface {
+	// Put streams size bytes of body, whose hex SHA-256 is checksum, to the file name
+	Put(ctx context.Context, name string, body io.Reader, size int64, checksum string) error
+	// Get opens the file name, the error wraps os.ErrNotExist if there is none
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// NewStore returns the store at location:
+//
+//	dir or file:///dir                           a local or shared directory
+//	s3://bucket/prefix[?endpoint=url&region=r]  S3-compatible object storage, credentials from AWS_ACCESS_KEY_ID,
+//	                                             AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//	http(s)://...                                an HTTP server accepting GET and PUT, with the bearer token of
+//	                                             ARTIFACT_STORE_TOKEN if set
+func NewStore(location string) (Store, error) {
+	switch {
+	case location == "":
+		return nil, fmt.Errorf("no artifact store")
+	case strings.HasPrefix(location, "s3://"):
+		return relayer.NewS3Sink(location)
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		return &HTTPStore{URL: location, Token: os.Getenv("ARTIFACT_STORE_TOKEN"), Client: &http.Client{}}, nil
+	default:
+		return DirStore(strings.TrimPrefix(location, "file://")), nil
+	}
+}
+
+// DirStore keeps the artifacts in a directory
+type DirStore string
+
+func (d DirStore) Put(_ context.Context, name string, body io.Reader, _ int64, checksum string) error {
+	return writeFileAtomic(filepath.Join(string(d), filepath.FromSlash(name)), body, checksum)
+}
+
+func (d DirStore) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// HTTPStore keeps the artifacts on an HTTP server, at URL/name
+type HTTPStore struct {
+	URL string
+	// Token is sent as a bearer token if not empty
+	Token  string
+	Client *http.Client
+}
+
+func (s *HTTPStore) Put(ctx context.Context, name string, body io.Reader, size int64, checksum string) error {
+	req, err := s.request(ctx, http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Checksum-Sha256", checksum)
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Close()
+}
+
+func (s *HTTPStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(req)
+}
+
+func (s *HTTPStore) request(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.URL, "/")+"/"+name, body)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	return req, nil
+}
+
+// do sends req and returns the body of a 2xx response, a 404 is an error wrapping os.ErrNotExist
+func (s *HTTPStore) do(req *http.Request) (io.ReadCloser, error) {
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp.Body, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", req.URL.Redacted(), os.ErrNotExist)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, bytes.TrimSpace(body))
+}
+
+// Upload publishes the artifacts of the manifest of buildDir to store as version, the manifest digest
+// if empty. Circuits without checksums get them recorded in the manifest, the others are checked against
+// theirs. The manifest is published last, so a version is only visible once complete, and a version is
+// never overwritten with another manifest. It returns the version and the hex SHA-256 of the manifest,
+// which the relayer instances pin.
+func Upload(ctx context.Context, store Store, buildDir, version string) (string, string, error) {
+	manifestPath := filepath.Join(buildDir, types.ManifestFileName)
+	manifest, err := types.LoadArtifactManifest(manifestPath)
+	if err != nil {
+		return "", "", err
+	}
+	changed := false
+	for i := range manifest.Circuits {
+		c := &manifest.Circuits[i]
+		if c.Checksums == (types.ArtifactChecksums{}) {
+			if err := c.SetChecksums(buildDir); err != nil {
+				return "", "", err
+			}
+			changed = true
+		} else if err := c.VerifyArtifacts(buildDir); err != nil {
+			return "", "", err
+		}
+	}
+	if changed {
+		if err := manifest.Save(manifestPath); err != nil {
+			return "", "", err
+		}
+	}
+	manifestBlob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	digest := sha256Hex(manifestBlob)
+	if version == "" {
+		version = digest[:16]
+	}
+	if err := checkVersion(version); err != nil {
+		return "", "", err
+	}
+
+	published, err := readManifest(ctx, store, version)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return "", "", err
+	case bytes.Equal(published, manifestBlob):
+		return version, digest, nil
+	default:
+		return "", "", fmt.Errorf("version %s is already published with another manifest (sha256 %s)", version, sha256Hex(published))
+	}
+
+	files, err := artifactFiles(manifest)
+	if err != nil {
+		return "", "", err
+	}
+	for _, file := range files {
+		if err := uploadFile(ctx, store, version, buildDir, file); err != nil {
+			return "", "", err
+		}
+	}
+	if err := store.Put(ctx, path.Join(version, types.ManifestFileName), bytes.NewReader(manifestBlob), int64(len(manifestBlob)), digest); err != nil {
+		return "", "", fmt.Errorf("failed to publish manifest: %w", err)
+	}
+	return version, digest, nil
+}
+
+// uploadFile streams an artifact of buildDir to the version directory of store
+func uploadFile(ctx context.Context, store Store, version, buildDir string, file artifactFile) error {
+	f, err := os.Open(filepath.Join(buildDir, file.path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, path.Join(version, filepath.ToSlash(file.path)), f, info.Size(), file.checksum); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", file.path, err)
+	}
+	return nil
+}
+
+// Download fetches the artifacts of version from store into buildDir. The manifest must have the hex
+// SHA-256 manifestDigest, unless it is empty, and each artifact the checksum the manifest records: a file
+// is only moved into buildDir once it is verified, and the ones already there with the right checksum
+// are kept. The manifest is written last. It returns the number of files downloaded.
+func Download(ctx context.Context, store Store, version, buildDir, manifestDigest string) (int, error) {
+	if err := checkVersion(version); err != nil {
+		return 0, err
+	}
+	manifestBlob, err := readManifest(ctx, store, version)
+	if err != nil {
+		return 0, fmt.Errorf("version %s: %w", version, err)
+	}
+	if digest := sha256Hex(manifestBlob); manifestDigest != "" && !strings.EqualFold(digest, manifestDigest) {
+		return 0, fmt.Errorf("manifest of version %s has sha256 %s, pinned %s", version, digest, manifestDigest)
+	}
+	manifest, err := types.ParseArtifactManifest(manifestBlob)
+	if err != nil {
+		return 0, fmt.Errorf("manifest of version %s: %w", version, err)
+	}
+	files, err := artifactFiles(manifest)
+	if err != nil {
+		return 0, err
+	}
+
+	downloaded := 0
+	for _, file := range files {
+		target := filepath.Join(buildDir, file.path)
+		if checksum, err := types.FileChecksum(target); err == nil && checksum == file.checksum {
+			continue
+		}
+		body, err := store.Get(ctx, path.Join(version, filepath.ToSlash(file.path)))
+		if err != nil {
+			return downloaded, fmt.Errorf("failed to download %s: %w", file.path, err)
+		}
+		err = writeFileAtomic(target, body, file.checksum)
+		_ = body.Close()
+		if err != nil {
+			return downloaded, fmt.Errorf("failed to download %s: %w", file.path, err)
+		}
+		downloaded++
+	}
+	if err := writeFileAtomic(filepath.Join(buildDir, types.ManifestFileName), bytes.NewReader(manifestBlob), ""); err != nil {
+		return downloaded, err
+	}
+	return downloaded, nil
+}
+
+// Verify checks the artifacts of the manifest of buildDir against their recorded checksums, which every
+// circuit must have
+func Verify(buildDir string) (*types.ArtifactManifest, error) {
+	manifest, err := types.LoadArtifactManifest(filepath.Join(buildDir, types.ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := artifactFiles(manifest); err != nil {
+		return nil, err
+	}
+	for i := range manifest.Circuits {
+		if err := manifest.Circuits[i].VerifyArtifacts(buildDir); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// artifactFile is an artifact of a manifest, relative to its directory, and its hex SHA-256
+type artifactFile struct {
+	path     string
+	checksum string
+}
+
+// artifactFiles lists the constraint systems, proving keys and verifying keys of the manifest, each once.
+// Every artifact must have a checksum, and a path within the directory of the manifest.
+func artifactFiles(manifest *types.ArtifactManifest) ([]artifactFile, error) {
+	var files []artifactFile
+	seen := make(map[string]string)
+	for _, c := range manifest.Circuits {
+		for _, file := range []artifactFile{{c.CCS, c.Checksums.CCS}, {c.PK, c.Checksums.PK}, {c.VK, c.Checksums.VK}} {
+			if !filepath.IsLocal(file.path) {
+				return nil, fmt.Errorf("circuit %s: artifact %q is outside of the build directory", c.Name, file.path)
+			}
+			if file.checksum == "" {
+				return nil, fmt.Errorf("circuit %s: artifact %s has no checksum", c.Name, file.path)
+			}
+			if checksum, ok := seen[file.path]; ok {
+				if checksum != file.checksum {
+					return nil, fmt.Errorf("circuit %s: artifact %s has two checksums", c.Name, file.path)
+				}
+				continue
+			}
+			seen[file.path] = file.checksum
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// readManifest reads the manifest of version in store
+func readManifest(ctx context.Context, store Store, version string) ([]byte, error) {
+	body, err := store.Get(ctx, path.Join(version, types.ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(io.LimitReader(body, maxManifestSize))
+}
+
+// checkVersion rejects the versions which are not a single path element
+func checkVersion(version string) error {
+	if version == "" || version == "." || version == ".." || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	return nil
+}
+
+// writeFileAtomic writes body to path through a temporary file of its directory. If checksum is not
+// empty, the file is only moved to path if its hex SHA-256 is checksum.
+func writeFileAtomic(target string, body io.Reader, checksum string) error {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); checksum != "" && got != checksum {
+		return fmt.Errorf("sha256 %s, the manifest records %s", got, checksum)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
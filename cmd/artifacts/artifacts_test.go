@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBuild writes a build directory with the artifacts of one circuit, without checksums
+func writeBuild(t *testing.T, pk string) string {
+	dir := t.TempDir()
+	for file, content := range map[string]string{"Cube.ccs": "ccs", "Cube.pk": pk, "Cube.vk": "vk"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+	}
+	manifest := &types.ArtifactManifest{}
+	manifest.Set(types.CircuitManifest{Name: "Cube", Backend: types.BackendGroth16, Curve: ecc.BN254.String(),
+		Verifier: types.VerifierSolidity, CCS: "Cube.ccs", PK: "Cube.pk", VK: "Cube.vk"})
+	require.NoError(t, manifest.Save(filepath.Join(dir, types.ManifestFileName)))
+	return dir
+}
+
+func TestUploadDownload(t *testing.T) {
+	ctx := context.Background()
+	buildDir := writeBuild(t, "pk")
+	store := DirStore(t.TempDir())
+
+	version, digest, err := Upload(ctx, store, buildDir, "")
+	require.NoError(t, err)
+	require.Equal(t, digest[:16], version)
+	// the checksums were recorded in the local manifest
+	_, err = Verify(buildDir)
+	require.NoError(t, err)
+	// publishing the same build again is a no-op
+	_, again, err := Upload(ctx, store, buildDir, version)
+	require.NoError(t, err)
+	require.Equal(t, digest, again)
+
+	relayerDir := t.TempDir()
+	n, err := Download(ctx, store, version, relayerDir, digest)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	pk, err := os.ReadFile(filepath.Join(relayerDir, "Cube.pk"))
+	require.NoError(t, err)
+	require.Equal(t, "pk", string(pk))
+	_, err = Verify(relayerDir)
+	require.NoError(t, err)
+	// the files already installed are kept
+	n, err = Download(ctx, store, version, relayerDir, digest)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// another manifest than the pinned one
+	_, err = Download(ctx, store, version, t.TempDir(), strings.Repeat("0", 64))
+	require.ErrorContains(t, err, "pinned")
+
+	// another setup cannot be published as the same version
+	_, _, err = Upload(ctx, store, writeBuild(t, "other pk"), version)
+	require.ErrorContains(t, err, "already published")
+
+	// a proving key altered in the store is not installed
+	require.NoError(t, os.WriteFile(filepath.Join(string(store), version, "Cube.pk"), []byte("bad"), 0644))
+	otherDir := t.TempDir()
+	_, err = Download(ctx, store, version, otherDir, digest)
+	require.ErrorContains(t, err, "Cube.pk")
+	_, err = os.Stat(filepath.Join(otherDir, "Cube.pk"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(otherDir, types.ManifestFileName))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// nor is a version that was not published
+	_, err = Download(ctx, store, "v0", otherDir, "")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestHTTPStore(t *testing.T) {
+	var mu sync.Mutex
+	files := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			files[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			blob, ok := files[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(blob)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := &HTTPStore{URL: srv.URL + "/setups", Token: "secret", Client: srv.Client()}
+	version, digest, err := Upload(ctx, store, writeBuild(t, "pk"), "v1")
+	require.NoError(t, err)
+	require.Equal(t, "v1", version)
+	require.Contains(t, files, "/setups/v1/Cube.pk")
+
+	n, err := Download(ctx, store, "v1", t.TempDir(), digest)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	store.Token = ""
+	_, err = Download(ctx, store, "v1", t.TempDir(), digest)
+	require.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,108 @@
+// Command artifacts publishes the build directory of setup_circuit.go (the constraint systems, proving
+// keys and verifying keys of its manifest) to a shared store, and installs a published version on the
+// machines running relayers, so that they all prove with the keys of one setup:
+//
+//	artifacts upload -build .build -to s3://bucket/zk-chains [-version v1]
+//	artifacts download -from s3://bucket/zk-chains -version v1 -sha256 <manifest sha256> -build .build
+//	artifacts verify -build .build
+//
+// Every file is checked against the SHA-256 recorded in the manifest, and a version is never overwritten
+// with another manifest. upload prints the SHA-256 of the published manifest, which download pins.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var err error
+	switch os.Args[1] {
+	case "upload":
+		err = uploadMain(ctx, os.Args[2:])
+	case "download":
+		err = downloadMain(ctx, os.Args[2:])
+	case "verify":
+		err = verifyMain(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		println("error", err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	println("usage: artifacts upload | download | verify [flags]")
+}
+
+func uploadMain(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	buildDir := fs.String("build", ".build", "build directory of setup_circuit.go")
+	to := fs.String("to", "", "store to publish to: a directory, s3://bucket/prefix or an http(s) URL")
+	version := fs.String("version", "", "version to publish as, the prefix of the manifest sha256 by default")
+	_ = fs.Parse(args)
+
+	store, err := NewStore(*to)
+	if err != nil {
+		return err
+	}
+	published, digest, err := Upload(ctx, store, *buildDir, *version)
+	if err != nil {
+		return err
+	}
+	println("✅ Artifacts of", *buildDir, "published to", *to, "as version", published)
+	println("manifest sha256:", digest)
+	return nil
+}
+
+func downloadMain(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	buildDir := fs.String("build", ".build", "build directory to install the artifacts in")
+	from := fs.String("from", "", "store to download from: a directory, s3://bucket/prefix or an http(s) URL")
+	version := fs.String("version", "", "version to download")
+	digest := fs.String("sha256", "", "sha256 of the manifest printed by upload, not checked if empty")
+	_ = fs.Parse(args)
+	if *version == "" {
+		return fmt.Errorf("-version is required")
+	}
+
+	store, err := NewStore(*from)
+	if err != nil {
+		return err
+	}
+	if *digest == "" {
+		println("⚠️  the manifest is not pinned, pass -sha256 to refuse another one")
+	}
+	n, err := Download(ctx, store, *version, *buildDir, *digest)
+	if err != nil {
+		return err
+	}
+	println("✅ Version", *version, "installed in", *buildDir, fmt.Sprintf("(%d files downloaded)", n))
+	return nil
+}
+
+func verifyMain(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	buildDir := fs.String("build", ".build", "build directory of setup_circuit.go")
+	_ = fs.Parse(args)
+
+	manifest, err := Verify(*buildDir)
+	if err != nil {
+		return err
+	}
+	println("✅ Artifacts of", len(manifest.Circuits), "circuits match the manifest of", *buildDir)
+	return nil
+}
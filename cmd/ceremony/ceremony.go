@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/backend/solidity"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"github.com/kysee/zk-chains/types"
+)
+
+// contribution is a Phase1 or Phase2 contribution file
+type contribution interface {
+	io.WriterTo
+	io.ReaderFrom
+	Contribute()
+}
+
+// readFrom reads v from the file at path
+func readFrom(path string, v io.ReaderFrom) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := v.ReadFrom(bufio.NewReader(f)); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeTo writes v to the file at path
+func writeTo(path string, v io.WriterTo) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if _, err := v.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return w.Flush()
+}
+
+// transcriptHash returns the SHA-256 of the serialization of v, which participants publish so the next
+// one (and the verifier) can check they build on the same contribution
+func transcriptHash(v io.WriterTo) ([]byte, error) {
+	h := sha256.New()
+	if _, err := v.WriteTo(h); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// readR1CS reads the constraint system saved by setup_circuit.go, which must be a Groth16 one over BN254,
+// the only setup gnark runs as an MPC
+func readR1CS(path string) (*cs.R1CS, error) {
+	ccs := groth16.NewCS(ecc.BN254)
+	if err := readFrom(path, ccs); err != nil {
+		return nil, err
+	}
+	r1cs, ok := ccs.(*cs.R1CS)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a BN254 R1CS", path)
+	}
+	return r1cs, nil
+}
+
+// DomainSize returns the size of the FFT domain of the circuit, the size of the powers of tau of its phase 1
+func DomainSize(r1cs *cs.R1CS) uint64 {
+	return ecc.NextPowerOfTwo(uint64(r1cs.GetNbConstraints()))
+}
+
+// Phase1Init writes the initial, contribution-less phase 1 of domain size n to out
+func Phase1Init(n uint64, out string) error {
+	if ecc.NextPowerOfTwo(n) != n {
+		return fmt.Errorf("domain size %d is not a power of two", n)
+	}
+	return writeTo(out, mpcsetup.NewPhase1(n))
+}
+
+// Contribute reads the last contribution of the phase from in, adds the randomness of this machine and
+// writes the result to out. It returns the transcript hash of out.
+func Contribute(c contribution, in, out string) ([]byte, error) {
+	if err := readFrom(in, c); err != nil {
+		return nil, err
+	}
+	c.Contribute()
+	if err := writeTo(out, c); err != nil {
+		return nil, err
+	}
+	return transcriptHash(c)
+}
+
+// Phase1Verify verifies the chain of phase 1 contributions of domain size n, in order, seals it with the
+// random beacon and writes the resulting circuit-independent SRS to out
+func Phase1Verify(n uint64, beacon []byte, contributions []string, out string) error {
+	if len(beacon) == 0 {
+		return fmt.Errorf("a random beacon is required to seal the phase")
+	}
+	if len(contributions) == 0 {
+		return fmt.Errorf("no contribution to verify")
+	}
+	phase1 := make([]*mpcsetup.Phase1, len(contributions))
+	for i := range contributions {
+		phase1[i] = new(mpcsetup.Phase1)
+		if err := readFrom(contributions[i], phase1[i]); err != nil {
+			return err
+		}
+	}
+	commons, err := mpcsetup.VerifyPhase1(n, beacon, phase1...)
+	if err != nil {
+		return fmt.Errorf("phase 1: %w", err)
+	}
+	return writeTo(out, &commons)
+}
+
+// Phase2Init writes the initial, contribution-less phase 2 of the circuit at ccsPath, over the SRS
+// sealed by Phase1Verify, to out
+func Phase2Init(ccsPath, commonsPath, out string) error {
+	r1cs, commons, err := readPhase2Inputs(ccsPath, commonsPath)
+	if err != nil {
+		return err
+	}
+	var p mpcsetup.Phase2
+	p.Initialize(r1cs, commons)
+	return writeTo(out, &p)
+}
+
+// Phase2Verify verifies the chain of phase 2 contributions of the circuit at ccsPath, in order, and seals
+// it with the random beacon into its proving and verifying keys
+func Phase2Verify(ccsPath, commonsPath string, beacon []byte, contributions []string) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	if len(beacon) == 0 {
+		return nil, nil, fmt.Errorf("a random beacon is required to seal the phase")
+	}
+	if len(contributions) == 0 {
+		return nil, nil, fmt.Errorf("no contribution to verify")
+	}
+	r1cs, commons, err := readPhase2Inputs(ccsPath, commonsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	phase2 := make([]*mpcsetup.Phase2, len(contributions))
+	for i := range contributions {
+		phase2[i] = new(mpcsetup.Phase2)
+		if err := readFrom(contributions[i], phase2[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	pk, vk, err := mpcsetup.VerifyPhase2(r1cs, commons, beacon, phase2...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("phase 2: %w", err)
+	}
+	return pk, vk, nil
+}
+
+func readPhase2Inputs(ccsPath, commonsPath string) (*cs.R1CS, *mpcsetup.SrsCommons, error) {
+	r1cs, err := readR1CS(ccsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var commons mpcsetup.SrsCommons
+	if err := readFrom(commonsPath, &commons); err != nil {
+		return nil, nil, err
+	}
+	if n := uint64(len(commons.G1.AlphaTau)); n < uint64(r1cs.GetNbConstraints()) {
+		return nil, nil, fmt.Errorf("the SRS of domain size %d is too small for %d constraints", n, r1cs.GetNbConstraints())
+	}
+	return r1cs, &commons, nil
+}
+
+// circuitArtifacts returns the manifest of buildDir and its entry of the named circuit, checking its keys
+// can come from the ceremony
+func circuitArtifacts(buildDir, name string) (*types.ArtifactManifest, *types.CircuitManifest, error) {
+	manifest, err := types.LoadArtifactManifest(filepath.Join(buildDir, types.ManifestFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := manifest.Circuit(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Backend != types.BackendGroth16 {
+		return nil, nil, fmt.Errorf("circuit %s: the ceremony only sets up Groth16, not %s", name, c.Backend)
+	}
+	if curve, err := c.CurveID(); err != nil || curve != ecc.BN254 {
+		return nil, nil, fmt.Errorf("circuit %s: the ceremony only sets up BN254, not %s", name, c.Curve)
+	}
+	return manifest, c, nil
+}
+
+// InstallKeys replaces the single-party keys of the named circuit in buildDir by the keys of the ceremony,
+// updates their checksums in the manifest and regenerates its Solidity verifier if the manifest records one
+func InstallKeys(buildDir, name string, pk groth16.ProvingKey, vk groth16.VerifyingKey) error {
+	manifest, c, err := circuitArtifacts(buildDir, name)
+	if err != nil {
+		return err
+	}
+	if err := writeTo(filepath.Join(buildDir, c.PK), pk); err != nil {
+		return err
+	}
+	if err := writeTo(filepath.Join(buildDir, c.VK), vk); err != nil {
+		return err
+	}
+	if err := c.SetChecksums(buildDir); err != nil {
+		return err
+	}
+	if err := manifest.Save(filepath.Join(buildDir, types.ManifestFileName)); err != nil {
+		return err
+	}
+	if c.Contract == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := vk.ExportSolidity(&buf, solidity.WithHashToFieldFunction(sha256.New())); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Contract, buf.Bytes(), 0644)
+}
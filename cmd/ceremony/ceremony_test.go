@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+type cubeCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubeCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X, c.X), c.Y)
+	return nil
+}
+
+func TestCeremony(t *testing.T) {
+	dir := t.TempDir()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &cubeCircuit{})
+	require.NoError(t, err)
+	require.NoError(t, writeTo(filepath.Join(dir, "Cube.ccs"), ccs))
+	manifest := &types.ArtifactManifest{}
+	manifest.Set(types.CircuitManifest{Name: "Cube", Backend: types.BackendGroth16, Curve: ecc.BN254.String(),
+		Verifier: types.VerifierSolidity, CCS: "Cube.ccs", PK: "Cube.pk", VK: "Cube.vk"})
+	require.NoError(t, manifest.Save(filepath.Join(dir, types.ManifestFileName)))
+
+	_, c, err := circuitArtifacts(dir, "Cube")
+	require.NoError(t, err)
+	r1cs, err := readR1CS(filepath.Join(dir, c.CCS))
+	require.NoError(t, err)
+	n := DomainSize(r1cs)
+
+	// phase 1, two participants
+	path := func(phase, i int) string { return filepath.Join(dir, fmt.Sprintf("phase%d.%d", phase, i)) }
+	require.NoError(t, Phase1Init(n, path(1, 0)))
+	_, err = Contribute(new(mpcsetup.Phase1), path(1, 0), path(1, 1))
+	require.NoError(t, err)
+	_, err = Contribute(new(mpcsetup.Phase1), path(1, 1), path(1, 2))
+	require.NoError(t, err)
+	srs := filepath.Join(dir, "srs.bin")
+	// a contribution missing from the chain
+	require.Error(t, Phase1Verify(n, []byte("beacon"), []string{path(1, 2)}, srs))
+	require.Error(t, Phase1Verify(n, nil, []string{path(1, 1), path(1, 2)}, srs))
+	require.NoError(t, Phase1Verify(n, []byte("beacon"), []string{path(1, 1), path(1, 2)}, srs))
+
+	// phase 2, two participants
+	ccsPath := filepath.Join(dir, c.CCS)
+	require.NoError(t, Phase2Init(ccsPath, srs, path(2, 0)))
+	_, err = Contribute(new(mpcsetup.Phase2), path(2, 0), path(2, 1))
+	require.NoError(t, err)
+	_, err = Contribute(new(mpcsetup.Phase2), path(2, 1), path(2, 2))
+	require.NoError(t, err)
+	_, _, err = Phase2Verify(ccsPath, srs, []byte("beacon"), []string{path(2, 1), path(2, 1)})
+	require.Error(t, err)
+	pk, vk, err := Phase2Verify(ccsPath, srs, []byte("beacon"), []string{path(2, 1), path(2, 2)})
+	require.NoError(t, err)
+	require.NoError(t, InstallKeys(dir, "Cube", pk, vk))
+	// with the checksums of the new keys
+	_, c, err = circuitArtifacts(dir, "Cube")
+	require.NoError(t, err)
+	require.NotEmpty(t, c.Checksums.PK)
+	require.NoError(t, c.VerifyArtifacts(dir))
+
+	// the installed keys prove and verify
+	pk, vk = groth16.NewProvingKey(ecc.BN254), groth16.NewVerifyingKey(ecc.BN254)
+	require.NoError(t, readFrom(filepath.Join(dir, c.PK), pk))
+	require.NoError(t, readFrom(filepath.Join(dir, c.VK), vk))
+	w, err := frontend.NewWitness(&cubeCircuit{X: 3, Y: 27}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	proof, err := groth16.Prove(ccs, pk, w)
+	require.NoError(t, err)
+	public, err := w.Public()
+	require.NoError(t, err)
+	require.NoError(t, groth16.Verify(proof, vk, public))
+
+	// PLONK keys do not come from this ceremony
+	manifest.Set(types.CircuitManifest{Name: "Cube", Backend: types.BackendPlonk, Curve: ecc.BN254.String(), CCS: "Cube.ccs"})
+	require.NoError(t, manifest.Save(filepath.Join(dir, types.ManifestFileName)))
+	_, _, err = circuitArtifacts(dir, "Cube")
+	require.Error(t, err)
+}
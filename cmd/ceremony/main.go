@@ -0,0 +1,195 @@
+// Command ceremony runs the Groth16 setup of the circuits built by setup_circuit.go as a multi-party
+// ceremony, instead of the single-party groth16.Setup whose toxic waste one machine could keep.
+//
+// Phase 1 (powers of tau) is circuit-independent, phase 2 is specific to one circuit of the manifest.
+// Each phase starts from an init file, every participant imports the file of the previous one and exports
+// its own contribution, and the coordinator verifies the whole chain and seals it with a public random
+// beacon drawn after the last contribution:
+//
+//	ceremony phase1-init -circuit Eth2ScUpdateCircuit -out p1.0
+//	ceremony phase1-contribute -in p1.0 -out p1.1   # each participant, on their machine
+//	ceremony phase1-verify -circuit Eth2ScUpdateCircuit -beacon <hex> -out srs.bin p1.1 p1.2 ...
+//	ceremony phase2-init -circuit Eth2ScUpdateCircuit -srs srs.bin -out p2.0
+//	ceremony phase2-contribute -in p2.0 -out p2.1   # each participant, on their machine
+//	ceremony phase2-verify -circuit Eth2ScUpdateCircuit -srs srs.bin -beacon <hex> p2.1 p2.2 ...
+//
+// phase2-verify replaces the proving and verifying keys of the circuit in the build directory and its
+// Solidity verifier. Run it from the repository root, where the manifest paths of the contracts start.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/kysee/zk-chains/types"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "phase1-init":
+		err = phase1InitMain(os.Args[2:])
+	case "phase1-contribute":
+		err = contributeMain(new(mpcsetup.Phase1), os.Args[2:])
+	case "phase1-verify":
+		err = phase1VerifyMain(os.Args[2:])
+	case "phase2-init":
+		err = phase2InitMain(os.Args[2:])
+	case "phase2-contribute":
+		err = contributeMain(new(mpcsetup.Phase2), os.Args[2:])
+	case "phase2-verify":
+		err = phase2VerifyMain(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		println("error", err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	println("usage: ceremony phase1-init | phase1-contribute | phase1-verify | phase2-init | phase2-contribute | phase2-verify [flags]")
+}
+
+// domainFlags are the flags giving the domain size of phase 1, directly or from a circuit of the manifest
+type domainFlags struct {
+	domain   *uint64
+	circuit  *string
+	buildDir *string
+}
+
+func newDomainFlags(fs *flag.FlagSet) domainFlags {
+	return domainFlags{
+		domain:   fs.Uint64("domain", 0, "FFT domain size (power of two), instead of the one of -circuit"),
+		circuit:  fs.String("circuit", "Eth2ScUpdateCircuit", "circuit of the manifest whose domain size is used"),
+		buildDir: fs.String("build", ".build", "build directory of setup_circuit.go"),
+	}
+}
+
+func (f domainFlags) size() (uint64, error) {
+	if *f.domain != 0 {
+		return *f.domain, nil
+	}
+	_, c, err := circuitArtifacts(*f.buildDir, *f.circuit)
+	if err != nil {
+		return 0, err
+	}
+	r1cs, err := readR1CS(filepath.Join(*f.buildDir, c.CCS))
+	if err != nil {
+		return 0, err
+	}
+	return DomainSize(r1cs), nil
+}
+
+func phase1InitMain(args []string) error {
+	fs := flag.NewFlagSet("phase1-init", flag.ExitOnError)
+	domain := newDomainFlags(fs)
+	out := fs.String("out", "phase1.0", "initial phase 1 file")
+	_ = fs.Parse(args)
+
+	n, err := domain.size()
+	if err != nil {
+		return err
+	}
+	if err := Phase1Init(n, *out); err != nil {
+		return err
+	}
+	println("✅ Phase 1 of domain size", n, "initialized to", *out)
+	return nil
+}
+
+func contributeMain(c contribution, args []string) error {
+	fs := flag.NewFlagSet("contribute", flag.ExitOnError)
+	in := fs.String("in", "", "contribution of the previous participant (or the init file)")
+	out := fs.String("out", "", "file of this contribution, to hand to the next participant")
+	_ = fs.Parse(args)
+	if *in == "" || *out == "" {
+		return fmt.Errorf("-in and -out are required")
+	}
+
+	hash, err := Contribute(c, *in, *out)
+	if err != nil {
+		return err
+	}
+	println("✅ Contribution saved to", *out)
+	println("transcript hash:", hex.EncodeToString(hash))
+	return nil
+}
+
+func phase1VerifyMain(args []string) error {
+	fs := flag.NewFlagSet("phase1-verify", flag.ExitOnError)
+	domain := newDomainFlags(fs)
+	beaconHex := fs.String("beacon", "", "hex random beacon, drawn after the last contribution")
+	out := fs.String("out", "srs.bin", "sealed circuit-independent SRS")
+	_ = fs.Parse(args)
+
+	n, err := domain.size()
+	if err != nil {
+		return err
+	}
+	beacon, err := types.HexToBytes(*beaconHex)
+	if err != nil {
+		return fmt.Errorf("beacon: %w", err)
+	}
+	if err := Phase1Verify(n, beacon, fs.Args(), *out); err != nil {
+		return err
+	}
+	println("✅", len(fs.Args()), "phase 1 contributions verified, SRS saved to", *out)
+	return nil
+}
+
+func phase2InitMain(args []string) error {
+	fs := flag.NewFlagSet("phase2-init", flag.ExitOnError)
+	circuit := fs.String("circuit", "Eth2ScUpdateCircuit", "circuit of the manifest to set up")
+	buildDir := fs.String("build", ".build", "build directory of setup_circuit.go")
+	srs := fs.String("srs", "srs.bin", "SRS sealed by phase1-verify")
+	out := fs.String("out", "phase2.0", "initial phase 2 file")
+	_ = fs.Parse(args)
+
+	_, c, err := circuitArtifacts(*buildDir, *circuit)
+	if err != nil {
+		return err
+	}
+	if err := Phase2Init(filepath.Join(*buildDir, c.CCS), *srs, *out); err != nil {
+		return err
+	}
+	println("✅ Phase 2 of", *circuit, "initialized to", *out)
+	return nil
+}
+
+func phase2VerifyMain(args []string) error {
+	fs := flag.NewFlagSet("phase2-verify", flag.ExitOnError)
+	circuit := fs.String("circuit", "Eth2ScUpdateCircuit", "circuit of the manifest to set up")
+	buildDir := fs.String("build", ".build", "build directory of setup_circuit.go")
+	srs := fs.String("srs", "srs.bin", "SRS sealed by phase1-verify")
+	beaconHex := fs.String("beacon", "", "hex random beacon, drawn after the last contribution")
+	_ = fs.Parse(args)
+
+	beacon, err := types.HexToBytes(*beaconHex)
+	if err != nil {
+		return fmt.Errorf("beacon: %w", err)
+	}
+	_, c, err := circuitArtifacts(*buildDir, *circuit)
+	if err != nil {
+		return err
+	}
+	pk, vk, err := Phase2Verify(filepath.Join(*buildDir, c.CCS), *srs, beacon, fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := InstallKeys(*buildDir, *circuit, pk, vk); err != nil {
+		return err
+	}
+	println("✅", len(fs.Args()), "phase 2 contributions verified, keys of", *circuit, "saved to", *buildDir)
+	return nil
+}
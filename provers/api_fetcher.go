@@ -1,39 +1,80 @@
 package relayer
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	types2 "github.com/kysee/zk-chains/provers/types"
 	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
 )
 
+// defaultDecodeRetries is the number of times a truncated response is fetched again
+const defaultDecodeRetries = 2
+
 // APIFetcher implements Fetcher by calling Beacon API REST endpoint
 type APIFetcher struct {
 	BaseURL string
 	Client  *http.Client
+	// DecodeRetries is the number of times a request is retried when its response is truncated.
+	// Other decode errors are not retried, the provider would return the same data.
+	DecodeRetries int
+	// Logger receives the retries of the fetcher, nil drops them
+	Logger types2.Logger
 }
 
 // NewAPIFetcher creates a new APIFetcher with the given base URL
 func NewAPIFetcher(baseURL string) *APIFetcher {
 	return &APIFetcher{
-		BaseURL: baseURL,
-		Client:  &http.Client{},
+		BaseURL:       baseURL,
+		Client:        &http.Client{},
+		DecodeRetries: defaultDecodeRetries,
+	}
+}
+
+// NewAPIFetcherWithLogger creates a new APIFetcher with the given base URL logging to logger
+func NewAPIFetcherWithLogger(baseURL string, logger types2.Logger) *APIFetcher {
+	a := NewAPIFetcher(baseURL)
+	a.Logger = logger
+	return a
+}
+
+// log returns the logger of the fetcher, NopLogger if there is none
+func (a *APIFetcher) log() types2.Logger {
+	if a.Logger == nil {
+		return types2.NopLogger
 	}
+	return a.Logger
 }
 
 // FetchUpdate retrieves the light client update via Beacon API
 // GET /eth/v1/beacon/light_client/updates?start_period=&count=
-func (a *APIFetcher) ScUpdate(period uint64) (*types.LightClientUpdate, error) {
-	return a.FetchUpdateWithParams(period, 1)
+func (a *APIFetcher) ScUpdate(ctx context.Context, period uint64) (*types.LightClientUpdate, error) {
+	return a.FetchUpdateWithParams(ctx, period, 1)
 }
 
 // FetchUpdateWithParams retrieves light client updates with specific parameters
-func (a *APIFetcher) FetchUpdateWithParams(startPeriod uint64, count int) (*types.LightClientUpdate, error) {
+func (a *APIFetcher) FetchUpdateWithParams(ctx context.Context, startPeriod uint64, count int) (*types.LightClientUpdate, error) {
+	updates, err := a.ScUpdates(ctx, startPeriod, count)
+	if err != nil {
+		return nil, err
+	}
+	// Return the first update
+	return updates[0], nil
+}
+
+// ScUpdates retrieves the light client updates of count periods from startPeriod on. The beacon node
+// returns the available ones in order, at least one or an error.
+// GET /eth/v1/beacon/light_client/updates?start_period=&count=
+func (a *APIFetcher) ScUpdates(ctx context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
 	// Build URL with query parameters
 	endpoint, err := url.Parse(a.BaseURL)
 	if err != nil {
@@ -46,41 +87,172 @@ func (a *APIFetcher) FetchUpdateWithParams(startPeriod uint64, count int) (*type
 	query.Set("count", strconv.Itoa(count))
 	endpoint.RawQuery = query.Encode()
 
-	// Send HTTP GET request
-	resp, err := a.Client.Get(endpoint.String())
+	// Send HTTP GET request and parse API response
+	var apiResponse types2.ScUpdateAPIResponse
+	if err := a.getJSON(ctx, endpoint.String(), &apiResponse); err != nil {
+		return nil, err
+	}
+	// Check if we got any updates
+	if len(apiResponse) == 0 {
+		return nil, fmt.Errorf("no light client updates found")
+	}
+
+	updates := make([]*types.LightClientUpdate, len(apiResponse))
+	for i := range apiResponse {
+		updates[i] = &apiResponse[i]
+	}
+	return updates, nil
+}
+
+// HeadSlot retrieves the slot of the head block
+// GET /eth/v1/beacon/headers/head
+func (a *APIFetcher) HeadSlot(ctx context.Context) (uint64, error) {
+	endpoint, err := url.Parse(a.BaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return 0, fmt.Errorf("invalid base URL: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	endpoint.Path = "/eth/v1/beacon/headers/head"
+
+	var head types2.HeadAPIResponse
+	if err := a.getJSON(ctx, endpoint.String(), &head); err != nil {
+		return 0, err
+	}
+	return uint64(head.Data.Header.Message.Slot), nil
+}
+
+// AttestedBlockNumber retrieves the number of the execution block of the latest header attested by the
+// sync committee
+// GET /eth/v1/beacon/light_client/optimistic_update
+func (a *APIFetcher) AttestedBlockNumber(ctx context.Context) (uint64, error) {
+	endpoint, err := url.Parse(a.BaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return 0, fmt.Errorf("invalid base URL: %w", err)
 	}
 
-	// Check HTTP status code
+	endpoint.Path = "/eth/v1/beacon/light_client/optimistic_update"
+
+	var update types2.OptimisticUpdateAPIResponse
+	if err := a.getJSON(ctx, endpoint.String(), &update); err != nil {
+		return 0, err
+	}
+	number, err := strconv.ParseUint(update.Data.AttestedHeader.Execution.BlockNumber, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid execution block number %q: %w", update.Data.AttestedHeader.Execution.BlockNumber, err)
+	}
+	return number, nil
+}
+
+// maxEventSize bounds one line of the event stream, light client updates included
+const maxEventSize = 1 << 20
+
+// Events streams the events of topics to handle until ctx is cancelled or the stream ends, which is
+// reported as an error. Events whose slot cannot be read are skipped.
+// GET /eth/v1/events?topics=
+func (a *APIFetcher) Events(ctx context.Context, topics []string, handle func(types2.BeaconEvent)) error {
+	endpoint, err := url.Parse(a.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+	endpoint.Path = "/eth/v1/events"
+	query := endpoint.Query()
+	query.Set("topics", strings.Join(topics, ","))
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	// Parse API response
-	var apiResponse types2.ScUpdateAPIResponse
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	// an event is an "event:" line and "data:" lines, ended by an empty line
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEventSize)
+	var topic string
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if topic != "" && len(data) != 0 {
+				if slot, err := eventSlot(data); err != nil {
+					a.log().Warnf("skipping %s event: %v\n", topic, err)
+				} else {
+					handle(types2.BeaconEvent{Topic: topic, Slot: slot})
+				}
+			}
+			topic, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			topic = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimSpace(strings.TrimPrefix(line, "data:"))...)
+		}
 	}
-	// Check if we got any updates
-	if len(apiResponse) == 0 {
-		return nil, fmt.Errorf("no light client updates found")
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("event stream failed: %w", err)
 	}
+	return errors.New("event stream closed")
+}
 
-	// Return the first update
-	return &apiResponse[0], nil
+// eventSlot returns the slot of a head event, or the attested slot of a light client update event,
+// with or without its version envelope
+func eventSlot(data []byte) (uint64, error) {
+	type lightClientUpdate struct {
+		AttestedHeader *struct {
+			Beacon struct {
+				Slot common.Slot `json:"slot"`
+			} `json:"beacon"`
+		} `json:"attested_header"`
+	}
+	var event struct {
+		Slot *common.Slot      `json:"slot"`
+		Data lightClientUpdate `json:"data"`
+		lightClientUpdate
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0, err
+	}
+	switch {
+	case event.Slot != nil:
+		return uint64(*event.Slot), nil
+	case event.Data.AttestedHeader != nil:
+		return uint64(event.Data.AttestedHeader.Beacon.Slot), nil
+	case event.AttestedHeader != nil:
+		return uint64(event.AttestedHeader.Beacon.Slot), nil
+	default:
+		return 0, errors.New("no slot")
+	}
+}
+
+// Bootstrap retrieves the light client bootstrap of a block, to be verified against blockRoot
+// GET /eth/v1/beacon/light_client/bootstrap/{block_root}
+func (a *APIFetcher) Bootstrap(ctx context.Context, blockRoot common.Root) (*types.LightClientBootstrap, error) {
+	endpoint, err := url.Parse(a.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	endpoint.Path = fmt.Sprintf("/eth/v1/beacon/light_client/bootstrap/%s", blockRoot)
+
+	var bootstrap types.LightClientBootstrap
+	if err := a.getJSON(ctx, endpoint.String(), &bootstrap); err != nil {
+		return nil, err
+	}
+	return &bootstrap, nil
 }
 
 // FetchBlock retrieves a beacon block by slot
 // GET /eth/v2/beacon/blocks/{slot}
-func (a *APIFetcher) Block(slot uint64) (*types2.BlockAPIResponse, error) {
+func (a *APIFetcher) Block(ctx context.Context, slot uint64) (*types2.BlockAPIResponse, error) {
 	// Build URL with slot parameter
 	endpoint, err := url.Parse(a.BaseURL)
 	if err != nil {
@@ -89,8 +261,50 @@ func (a *APIFetcher) Block(slot uint64) (*types2.BlockAPIResponse, error) {
 
 	endpoint.Path = fmt.Sprintf("/eth/v2/beacon/blocks/%d", slot)
 
+	// Send HTTP GET request and parse API response
+	var blockResponse types2.BlockAPIResponse
+	if err := a.getJSON(ctx, endpoint.String(), &blockResponse); err != nil {
+		return nil, err
+	}
+
+	// Return the full BlockAPIResponse
+	return &blockResponse, nil
+}
+
+// getJSON fetches endpoint and decodes its JSON body into out. Decode failures are counted per
+// endpoint and kind (see recordDecodeError) and returned as a *types.DecodeError; truncated
+// responses are fetched again up to a.DecodeRetries times.
+func (a *APIFetcher) getJSON(ctx context.Context, endpoint string, out any) error {
+	for attempt := 0; ; attempt++ {
+		body, err := a.get(ctx, endpoint)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(body, out)
+		if err == nil {
+			return nil
+		}
+		kind := recordDecodeError(a.BaseURL, err)
+		var decodeErr *types.DecodeError
+		if !errors.As(err, &decodeErr) {
+			decodeErr = &types.DecodeError{Kind: kind, Err: err}
+		}
+		if kind != types.DecodeErrorTruncated || attempt >= a.DecodeRetries {
+			return fmt.Errorf("failed to parse response: %w", decodeErr)
+		}
+		a.log().Warnf("truncated response from %s, retrying (%d/%d)\n", endpoint, attempt+1, a.DecodeRetries)
+	}
+}
+
+// get sends a GET request to endpoint and returns the body of a 200 response
+func (a *APIFetcher) get(ctx context.Context, endpoint string) ([]byte, error) {
 	// Send HTTP GET request
-	resp, err := a.Client.Get(endpoint.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	resp, err := a.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -106,13 +320,5 @@ func (a *APIFetcher) Block(slot uint64) (*types2.BlockAPIResponse, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
-
-	// Parse API response
-	var blockResponse types2.BlockAPIResponse
-	if err := json.Unmarshal(body, &blockResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Return the full BlockAPIResponse
-	return &blockResponse, nil
+	return body, nil
 }
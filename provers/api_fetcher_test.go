@@ -0,0 +1,95 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIFetcherDecodeErrors(t *testing.T) {
+	update, err := os.ReadFile(filepath.Join("..", "data", "sc-update-1105.json"))
+	require.NoError(t, err)
+	full := append(append([]byte("["), update...), ']')
+
+	// responses served in order, the last one is repeated
+	var responses [][]byte
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := min(requests, len(responses)-1)
+		requests++
+		_, _ = w.Write(responses[i])
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	endpoint := u.Host
+
+	fetcher := NewAPIFetcher(server.URL)
+
+	// a truncated response is retried
+	responses = [][]byte{full[:len(full)/2], full}
+	got, err := fetcher.ScUpdate(context.Background(), 1105)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+	require.Equal(t, uint64(9052234), uint64(got.Data.AttestedHeader.Beacon.Slot))
+	require.Equal(t, int64(1), DecodeErrorCounts()[endpoint][types.DecodeErrorTruncated])
+
+	// up to DecodeRetries times
+	requests = 0
+	responses = [][]byte{full[:len(full)/2]}
+	_, err = fetcher.ScUpdate(context.Background(), 1105)
+	var decodeErr *types.DecodeError
+	require.True(t, errors.As(err, &decodeErr))
+	require.Equal(t, types.DecodeErrorTruncated, decodeErr.Kind)
+	require.Equal(t, 1+fetcher.DecodeRetries, requests)
+	require.Equal(t, int64(2+fetcher.DecodeRetries), DecodeErrorCounts()[endpoint][types.DecodeErrorTruncated])
+
+	// other decode errors are not retried
+	requests = 0
+	responses = [][]byte{[]byte(`[{"data": {"next_sync_committee_branch": ["0x0"]}}]`)}
+	_, err = fetcher.ScUpdate(context.Background(), 1105)
+	require.Error(t, err)
+	require.Equal(t, 1, requests)
+	require.Equal(t, int64(1), DecodeErrorCounts()[endpoint][types.DecodeErrorOther])
+}
+
+func TestAPIFetcherBatches(t *testing.T) {
+	var updates [][]byte
+	for _, period := range []string{"1104", "1105"} {
+		update, err := os.ReadFile(filepath.Join("..", "data", "sc-update-"+period+".json"))
+		require.NoError(t, err)
+		updates = append(updates, update)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/eth/v1/beacon/headers/head":
+			_, _ = w.Write([]byte(`{"data": {"header": {"message": {"slot": "9060000"}}}}`))
+		case "/eth/v1/beacon/light_client/updates":
+			require.Equal(t, "1104", r.URL.Query().Get("start_period"))
+			require.Equal(t, "3", r.URL.Query().Get("count"))
+			_, _ = w.Write([]byte("[" + string(updates[0]) + "," + string(updates[1]) + "]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	fetcher := NewAPIFetcher(server.URL)
+
+	slot, err := fetcher.HeadSlot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(9060000), slot)
+
+	// the available updates are returned in order
+	got, err := fetcher.ScUpdates(context.Background(), 1104, 3)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, uint64(9052234), uint64(got[1].Data.AttestedHeader.Beacon.Slot))
+}
@@ -0,0 +1,138 @@
+package relayer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// encryptedMagic prefixes every encrypted artifact so that readers can tell it apart from plaintext
+var encryptedMagic = []byte("ZKCENC1\x00")
+
+// KeySource provides the 32-byte AES-256 key used to encrypt sensitive artifacts.
+// Implement it to fetch keys from a KMS; EnvKeySource reads it from the environment.
+type KeySource interface {
+	Key() ([]byte, error)
+}
+
+// EnvKeySource reads a hex (optionally 0x prefixed) or base64 encoded key from the named environment variable
+type EnvKeySource string
+
+func (e EnvKeySource) Key() ([]byte, error) {
+	val := strings.TrimSpace(os.Getenv(string(e)))
+	if val == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", string(e))
+	}
+	if key, err := hex.DecodeString(strings.TrimPrefix(val, "0x")); err == nil {
+		return key, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s is neither hex nor base64", string(e))
+	}
+	return key, nil
+}
+
+// ArtifactCipher encrypts artifacts that contain private inputs (full witnesses, quarantined updates)
+// with AES-256-GCM. Proofs and public witnesses are never encrypted.
+type ArtifactCipher struct {
+	aead cipher.AEAD
+}
+
+// NewArtifactCipher creates an AES-256-GCM cipher with the key provided by src
+func NewArtifactCipher(src KeySource) (*ArtifactCipher, error) {
+	key, err := src.Key()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load artifact key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("artifact key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &ArtifactCipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, returning magic || nonce || ciphertext
+func (c *ArtifactCipher) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(plaintext)+c.aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, nonce...)
+	// the magic header is authenticated as additional data
+	return c.aead.Seal(out, nonce, plaintext, encryptedMagic), nil
+}
+
+// Open decrypts data produced by Seal
+func (c *ArtifactCipher) Open(data []byte) ([]byte, error) {
+	if !IsEncryptedArtifact(data) {
+		return nil, fmt.Errorf("artifact is not encrypted")
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted artifact is truncated")
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, encryptedMagic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt artifact: %w", err)
+	}
+	return plaintext, nil
+}
+
+// IsEncryptedArtifact reports whether data was produced by ArtifactCipher.Seal
+func IsEncryptedArtifact(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// WriteSensitiveArtifact writes data containing private inputs to path.
+// If c is not nil, the data is encrypted and ".enc" is appended to the file name.
+// It returns the path actually written.
+func WriteSensitiveArtifact(path string, data []byte, c *ArtifactCipher) (string, error) {
+	if c != nil {
+		sealed, err := c.Seal(data)
+		if err != nil {
+			return "", err
+		}
+		data = sealed
+		path += ".enc"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// ReadSensitiveArtifact reads an artifact written by WriteSensitiveArtifact, decrypting it if needed
+func ReadSensitiveArtifact(path string, c *ArtifactCipher) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !IsEncryptedArtifact(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%s is encrypted but no artifact key is configured", path)
+	}
+	return c.Open(data)
+}
@@ -0,0 +1,51 @@
+package relayer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactCipher(t *testing.T) {
+	t.Setenv("TEST_WITNESS_KEY", "0x000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	c, err := NewArtifactCipher(EnvKeySource("TEST_WITNESS_KEY"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	witness := []byte("private witness bytes")
+
+	path, err := WriteSensitiveArtifact(filepath.Join(dir, "witness-period-1.bin"), witness, c)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "witness-period-1.bin.enc"), path)
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, IsEncryptedArtifact(raw))
+	require.NotContains(t, string(raw), string(witness))
+
+	got, err := ReadSensitiveArtifact(path, c)
+	require.NoError(t, err)
+	require.Equal(t, witness, got)
+
+	// encrypted artifacts can't be read without the key
+	_, err = ReadSensitiveArtifact(path, nil)
+	require.Error(t, err)
+
+	// tampering is detected
+	raw[len(raw)-1] ^= 0xff
+	_, err = c.Open(raw)
+	require.Error(t, err)
+
+	// plaintext artifacts are written as is when no key is configured
+	path, err = WriteSensitiveArtifact(filepath.Join(dir, "plain.bin"), witness, nil)
+	require.NoError(t, err)
+	got, err = ReadSensitiveArtifact(path, c)
+	require.NoError(t, err)
+	require.Equal(t, witness, got)
+
+	t.Setenv("TEST_WITNESS_KEY", "00ff")
+	_, err = NewArtifactCipher(EnvKeySource("TEST_WITNESS_KEY"))
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+// memorySampleInterval is the period the memory in use is sampled at while proving
+const memorySampleInterval = 20 * time.Millisecond
+
+// BenchReport is the outcome of proving the same witness Config.BenchRuns times
+type BenchReport struct {
+	Circuit     string `json:"circuit"`
+	Backend     string `json:"backend"`
+	Curve       string `json:"curve"`
+	Constraints int    `json:"constraints"`
+	// Gnark is the version of gnark the relayer was built with
+	Gnark   string `json:"gnark"`
+	Cores   int    `json:"cores"`
+	GPU     bool   `json:"gpu"`
+	Fixture string `json:"fixture"`
+	Period  uint64 `json:"period"`
+	Runs    int    `json:"runs"`
+	// Durations are the proving times of the runs, in order
+	Durations []time.Duration `json:"durations"`
+	Min       time.Duration   `json:"min"`
+	Median    time.Duration   `json:"median"`
+	P95       time.Duration   `json:"p95"`
+	// PeakMemory is the most memory in use (heap and stacks, in bytes) sampled while proving
+	PeakMemory uint64 `json:"peak_memory"`
+	// ProofBytes is the size of the serialized proof, SolidityProofBytes the one submitted on-chain
+	ProofBytes         int `json:"proof_bytes"`
+	SolidityProofBytes int `json:"solidity_proof_bytes"`
+}
+
+// BenchMain proves a recorded update config.BenchRuns times with the circuit of the artifact manifest,
+// and prints (and optionally saves) the JSON report
+func BenchMain(ctx context.Context, config *cfgtypes.Config) {
+	report, err := Bench(ctx, config)
+	if err != nil {
+		fatalf(config.Log(), "Benchmark failed: %v", err)
+	}
+	blob, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fatalf(config.Log(), "Failed to marshal report: %v", err)
+	}
+	fmt.Println(string(blob))
+	if config.ReportPath != "" {
+		if err := os.WriteFile(config.ReportPath, blob, 0644); err != nil {
+			fatalf(config.Log(), "Failed to write report: %v", err)
+		}
+		config.Log().Infof("Report saved to %s\n", config.ReportPath)
+	}
+}
+
+// Bench loads the circuit of the artifact manifest and proves the first two updates of
+// config.FixturesDir (../data by default): the first one hands its committee over to the second, whose
+// witness is proven config.BenchRuns times
+func Bench(ctx context.Context, config *cfgtypes.Config) (*BenchReport, error) {
+	if config.RemoteProver != "" {
+		return nil, errors.New("bench proves locally, without --remote-prover")
+	}
+	if config.FixturesDir == "" {
+		config.FixturesDir = filepath.Join(config.RootDir, "../data")
+	}
+	files, updates, err := loadFixtures(config.FixturesDir, config.Log())
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) < 2 {
+		return nil, fmt.Errorf("need at least 2 recorded updates in %s, found %d", config.FixturesDir, len(updates))
+	}
+
+	r, err := NewRelayer(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	artifacts, dir, err := r.circuitArtifacts()
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := loadCircuit(artifacts, dir, r.proverSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	signers, err := r.parseCommittee(&updates[0].Data.NextSyncCommittee)
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap %s: %w", files[0], err)
+	}
+	fullWitness, _, err := r.assignPeriod(updates[1], signers)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", files[1], err)
+	}
+
+	report, err := benchCircuit(ctx, loaded, fullWitness, config.BenchRuns)
+	if err != nil {
+		return nil, err
+	}
+	report.Fixture = files[1]
+	report.Period = config.Preset.Period(uint64(updates[1].Data.AttestedHeader.Beacon.Slot))
+	report.GPU = config.GPU
+	return report, nil
+}
+
+// benchCircuit proves fullWitness runs times with c and measures each proof. The first proof is
+// verified, so that a benchmark of broken artifacts fails.
+func benchCircuit(ctx context.Context, c *loadedCircuit, fullWitness witness.Witness, runs int) (*BenchReport, error) {
+	if runs <= 0 {
+		return nil, fmt.Errorf("invalid number of runs %d", runs)
+	}
+	report := &BenchReport{
+		Circuit:     c.name,
+		Backend:     string(c.backend),
+		Curve:       c.curve.String(),
+		Constraints: c.ccs.GetNbConstraints(),
+		Gnark:       gnarkVersion(),
+		Cores:       runtime.GOMAXPROCS(0),
+		Runs:        runs,
+	}
+	for run := 0; run < runs; run++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// each run starts from the memory the relayer holds between proofs
+		runtime.GC()
+		stop := sampleMemory(&report.PeakMemory)
+		start := time.Now()
+		proof, err := c.proveLocally(ctx, fullWitness)
+		duration := time.Since(start)
+		stop()
+		if err != nil {
+			return nil, fmt.Errorf("run %d: %w", run+1, err)
+		}
+		report.Durations = append(report.Durations, duration)
+		c.log().Infof("Run %d/%d: proven in %s\n", run+1, runs, duration.Round(time.Millisecond))
+
+		if run == 0 {
+			if err := c.verify(proof, fullWitness); err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			if _, err := proof.(io.WriterTo).WriteTo(&buf); err != nil {
+				return nil, fmt.Errorf("failed to serialize proof: %w", err)
+			}
+			report.ProofBytes = buf.Len()
+			if solidity, ok := proof.(interface{ MarshalSolidity() []byte }); ok {
+				report.SolidityProofBytes = len(solidity.MarshalSolidity())
+			}
+		}
+	}
+
+	sorted := slices.Clone(report.Durations)
+	slices.Sort(sorted)
+	report.Min = sorted[0]
+	report.Median = percentile(sorted, 50)
+	report.P95 = percentile(sorted, 95)
+	return report, nil
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// sampleMemory raises peak to the memory in use, heap and stacks as checkMemoryCeiling counts it, every
+// memorySampleInterval until the returned function is called
+func sampleMemory(peak *uint64) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(memorySampleInterval)
+		defer ticker.Stop()
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			*peak = max(*peak, stats.HeapInuse+stats.StackInuse)
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// gnarkVersion returns the version of the gnark module of the build, "unknown" if it is not recorded
+func gnarkVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/consensys/gnark" {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,63 @@
+package relayer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchCircuit(t *testing.T) {
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled", "--bench-runs", "3")
+	require.Equal(t, 3, config.BenchRuns)
+	r := &Relayer{config: config}
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+	c := &loadedCircuit{name: "square", curve: ecc.BN254, ccs: ccs, pk: pk, vk: vk,
+		backend: types.BackendGroth16, proverSettings: r.proverSettings()}
+
+	// 2 squared 1000 times
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 1000), ecc.BN254.ScalarField())
+	fullWitness, err := frontend.NewWitness(&squareCircuit{X: 2, Y: y}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	report, err := benchCircuit(context.Background(), c, fullWitness, config.BenchRuns)
+	require.NoError(t, err)
+	require.Equal(t, "square", report.Circuit)
+	require.Equal(t, ccs.GetNbConstraints(), report.Constraints)
+	require.Len(t, report.Durations, 3)
+	require.LessOrEqual(t, report.Min, report.Median)
+	require.LessOrEqual(t, report.Median, report.P95)
+	require.NotZero(t, report.PeakMemory)
+	require.NotZero(t, report.ProofBytes)
+	// a, b and c, without commitments
+	require.Equal(t, 256, report.SolidityProofBytes)
+
+	// a witness not satisfying the circuit fails the benchmark
+	fullWitness, err = frontend.NewWitness(&squareCircuit{X: 3, Y: y}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	_, err = benchCircuit(context.Background(), c, fullWitness, 1)
+	require.Error(t, err)
+	_, err = benchCircuit(context.Background(), c, fullWitness, 0)
+	require.Error(t, err)
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 20)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Second
+	}
+	require.Equal(t, 10*time.Second, percentile(sorted, 50))
+	require.Equal(t, 19*time.Second, percentile(sorted, 95))
+	require.Equal(t, time.Second, percentile(sorted[:1], 95))
+}
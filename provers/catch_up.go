@@ -0,0 +1,89 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+)
+
+// maxUpdatesPerRequest is MAX_REQUEST_LIGHT_CLIENT_UPDATES, the most updates a beacon node returns at once
+const maxUpdatesPerRequest = 128
+
+// catchUp fetches the updates of the prepare stage. When the relayer is more than one period behind
+// the beacon head (e.g. after being offline), the updates up to the head are fetched in batches and
+// proven in order, reporting how far behind the relayer still is.
+type catchUp struct {
+	r *Relayer
+	// batches serves the batches and the head, nil if the fetcher only serves single updates
+	batches cfgtypes.BatchFetcher
+	// headPeriod is the period of the beacon head when it was last checked
+	headPeriod uint64
+	// buffered are the updates fetched ahead, of the periods from start on
+	start    uint64
+	buffered []*types.LightClientUpdate
+}
+
+// newCatchUp returns the catch-up of r, which fetches batches if its fetcher can
+func (r *Relayer) newCatchUp() *catchUp {
+	batches, _ := r.fetcher.(cfgtypes.BatchFetcher)
+	return &catchUp{r: r, batches: batches}
+}
+
+// fetch returns the update of period, from the current batch if it holds it. The head is checked once
+// period reaches the last known head period: if period is behind it, the next batch is fetched.
+func (c *catchUp) fetch(ctx context.Context, period uint64) (*types.LightClientUpdate, error) {
+	if c.batches == nil {
+		return c.r.fetcher.ScUpdate(ctx, period)
+	}
+	if update := c.take(period); update != nil {
+		return update, nil
+	}
+
+	if period+1 >= c.headPeriod {
+		slot, err := c.batches.HeadSlot(ctx)
+		if err != nil {
+			c.r.log().Warnf("failed to fetch the beacon head: %v\n", err)
+			return c.r.fetcher.ScUpdate(ctx, period)
+		}
+		c.headPeriod = c.r.config.Preset.Period(slot)
+	}
+	c.r.behind.Store(period+1 < c.headPeriod)
+	if period+1 >= c.headPeriod {
+		return c.r.fetcher.ScUpdate(ctx, period)
+	}
+
+	count := min(c.headPeriod-period, maxUpdatesPerRequest)
+	c.r.log().Infof("Period %d is %d periods behind the head period %d, fetching the next %d updates\n",
+		period, c.headPeriod-period, c.headPeriod, count)
+	updates, err := c.batches.ScUpdates(ctx, period, int(count))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch updates of periods %d to %d: %w", period, period+count-1, err)
+	}
+	c.start, c.buffered = period, updates
+	update := c.take(period)
+	if update == nil {
+		return nil, fmt.Errorf("the updates from period %d do not start with the one of period %d", period, period)
+	}
+	return update, nil
+}
+
+// take returns the buffered update of period, dropping it and the ones before, nil if it is not
+// buffered. The updates of a batch are expected in order, one per period: the buffer is dropped at
+// the first one that is not of its period.
+func (c *catchUp) take(period uint64) *types.LightClientUpdate {
+	if period < c.start || period-c.start >= uint64(len(c.buffered)) {
+		c.buffered = nil
+		return nil
+	}
+	update := c.buffered[period-c.start]
+	c.buffered, c.start = c.buffered[period-c.start+1:], period+1
+	if got := c.r.config.Preset.Period(uint64(update.Data.AttestedHeader.Beacon.Slot)); got != period {
+		c.r.log().Warnf("batched update of period %d is the one of period %d, fetching again\n", period, got)
+		c.buffered = nil
+		return nil
+	}
+	c.r.log().Infof("Catching up: period %d, %d periods behind the head period %d\n", period, c.headPeriod-period, c.headPeriod)
+	return update
+}
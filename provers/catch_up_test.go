@@ -0,0 +1,80 @@
+package relayer
+
+import (
+	"context"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/kysee/zk-chains/types/slots"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/stretchr/testify/require"
+)
+
+// batchFetcher serves the recorded updates in batches, with the head in headPeriod
+type batchFetcher struct {
+	periodFetcher
+	headPeriod uint64
+	// batches are the start periods and counts of the batches requested
+	batches [][2]uint64
+	singles []uint64
+}
+
+func (f *batchFetcher) ScUpdate(ctx context.Context, period uint64) (*types.LightClientUpdate, error) {
+	f.singles = append(f.singles, period)
+	return f.periodFetcher.ScUpdate(ctx, period)
+}
+
+func (f *batchFetcher) ScUpdates(_ context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
+	f.batches = append(f.batches, [2]uint64{startPeriod, uint64(count)})
+	var updates []*types.LightClientUpdate
+	for period := startPeriod; period < startPeriod+uint64(count); period++ {
+		if update, ok := f.updates[period]; ok {
+			updates = append(updates, update)
+		}
+	}
+	return updates, nil
+}
+
+func (f *batchFetcher) HeadSlot(context.Context) (uint64, error) {
+	return slots.PeriodStartSlot(configs.Mainnet, f.headPeriod), nil
+}
+
+func TestCatchUp(t *testing.T) {
+	updates := loadTestUpdates(t)
+	fetcher := &batchFetcher{periodFetcher: periodFetcher{updates: updates}, headPeriod: 1300}
+	r := &Relayer{config: cfgtypes.NewConfig("--log-level", "disabled"), fetcher: fetcher}
+	c := r.newCatchUp()
+
+	// far behind the head, the periods are fetched in batches
+	update, err := c.fetch(context.Background(), 1104)
+	require.NoError(t, err)
+	require.Same(t, updates[1104], update)
+	update, err = c.fetch(context.Background(), 1105)
+	require.NoError(t, err)
+	require.Same(t, updates[1105], update)
+	require.Equal(t, [][2]uint64{{1104, maxUpdatesPerRequest}}, fetcher.batches)
+	require.Empty(t, fetcher.singles)
+
+	// a batch without the requested period fails
+	_, err = c.fetch(context.Background(), 1106)
+	require.Error(t, err)
+
+	// a batch starting with another period is not used
+	_, err = c.fetch(context.Background(), 1103)
+	require.ErrorContains(t, err, "do not start")
+
+	// close to the head, single updates are fetched
+	fetcher.headPeriod = 1106
+	c = r.newCatchUp()
+	update, err = c.fetch(context.Background(), 1105)
+	require.NoError(t, err)
+	require.Same(t, updates[1105], update)
+	require.Equal(t, []uint64{1105}, fetcher.singles)
+
+	// fetchers without batches
+	c = (&Relayer{config: r.config, fetcher: &periodFetcher{updates: updates}}).newCatchUp()
+	update, err = c.fetch(context.Background(), 1104)
+	require.NoError(t, err)
+	require.Same(t, updates[1104], update)
+}
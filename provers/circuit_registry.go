@@ -0,0 +1,191 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark/frontend"
+	circuitwitness "github.com/kysee/zk-chains/circuits/witness"
+	"github.com/kysee/zk-chains/types"
+)
+
+// CircuitKind is a kind of proof the relayer generates, each proven by its own circuit, see Config.Circuits
+type CircuitKind string
+
+const (
+	// CircuitScUpdate proves the sync committee hand-over of each period, the proof submitted to the
+	// destinations. The relayer always generates it.
+	CircuitScUpdate CircuitKind = "sc-update"
+	// CircuitFinality proves the finalized header of the update of each period
+	CircuitFinality CircuitKind = "finality"
+	// CircuitReceipt proves the receipt of a transaction, on demand, see Relayer.ProveReceipt
+	CircuitReceipt CircuitKind = "receipt"
+)
+
+// circuitNames are the names of the circuits of each kind in the artifact manifest
+var circuitNames = map[CircuitKind]string{
+	CircuitScUpdate: "Eth2ScUpdateCircuit",
+	CircuitFinality: "Eth2FinalityCircuit",
+	CircuitReceipt:  "Eth2ReceiptProofCircuit",
+}
+
+// ErrCircuitNotLoaded is returned for a proof of a circuit kind the relayer was not configured with
+var ErrCircuitNotLoaded = errors.New("circuit not loaded")
+
+// ParseCircuitKinds parses the kinds of Config.Circuits, sc-update included whether listed or not
+func ParseCircuitKinds(names []string) ([]CircuitKind, error) {
+	kinds := []CircuitKind{CircuitScUpdate}
+	for _, name := range names {
+		kind := CircuitKind(strings.TrimSpace(name))
+		if _, ok := circuitNames[kind]; !ok {
+			return nil, fmt.Errorf("unknown circuit kind %q", name)
+		}
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+// CircuitRegistry holds the loaded circuit of each kind. It is safe for concurrent use.
+type CircuitRegistry struct {
+	mu       sync.RWMutex
+	circuits map[CircuitKind]*loadedCircuit
+}
+
+// register makes c the circuit of kind
+func (reg *CircuitRegistry) register(kind CircuitKind, c *loadedCircuit) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.circuits == nil {
+		reg.circuits = make(map[CircuitKind]*loadedCircuit)
+	}
+	reg.circuits[kind] = c
+}
+
+// circuit returns the circuit of kind, nil if it is not loaded
+func (reg *CircuitRegistry) circuit(kind CircuitKind) *loadedCircuit {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.circuits[kind]
+}
+
+// Kinds returns the kinds of the loaded circuits, sorted
+func (reg *CircuitRegistry) Kinds() []CircuitKind {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	kinds := make([]CircuitKind, 0, len(reg.circuits))
+	for kind := range reg.circuits {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// byName returns the loaded circuit named name in the artifact manifest, nil if none is
+func (reg *CircuitRegistry) byName(name string) *loadedCircuit {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, c := range reg.circuits {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// setupCircuits registers the loaded Eth2ScUpdateCircuit and loads the circuits of the other kinds of
+// Config.Circuits from the artifact manifest
+func (r *Relayer) setupCircuits() error {
+	kinds, err := ParseCircuitKinds(r.config.Circuits)
+	if err != nil {
+		return err
+	}
+	r.circuits.register(CircuitScUpdate, r.mainCircuit())
+	if len(kinds) == 1 {
+		return nil
+	}
+	manifest, err := types.LoadArtifactManifest(r.config.ManifestPath)
+	if err != nil {
+		return fmt.Errorf("circuits %v need an artifact manifest: %w", kinds[1:], err)
+	}
+	for _, kind := range kinds[1:] {
+		artifacts, err := manifest.Circuit(circuitNames[kind])
+		if err != nil {
+			return fmt.Errorf("%s circuit: %w", kind, err)
+		}
+		loaded, err := loadCircuit(artifacts, filepath.Dir(r.config.ManifestPath), r.proverSettings())
+		if err != nil {
+			return fmt.Errorf("%s circuit: %w", kind, err)
+		}
+		r.circuits.register(kind, loaded)
+	}
+	r.log().Infof("Generating the proofs of circuits %v\n", r.circuits.Kinds())
+	return nil
+}
+
+// proveCircuit proves assignment with the circuit of kind and saves the proof data as name in the
+// kind's subdirectory of the proof directory, and as <kind>-<name> in the sinks. It returns the proof
+// data, a *types.ProofData or *types.PlonkProofData, and the path of the proof file.
+func (r *Relayer) proveCircuit(ctx context.Context, kind CircuitKind, name string, assignment frontend.Circuit) (any, string, error) {
+	loaded := r.circuits.circuit(kind)
+	if loaded == nil {
+		return nil, "", fmt.Errorf("%s: %w", kind, ErrCircuitNotLoaded)
+	}
+	fullWitness, err := frontend.NewWitness(assignment, loaded.curve.ScalarField())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create %s witness: %w", kind, err)
+	}
+	proofSolidity, err := loaded.prove(ctx, fullWitness)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s proof generation failed: %w", kind, err)
+	}
+	proofData, err := types.CreateProofDataFor(loaded.backend, proofSolidity)
+	if err != nil {
+		return nil, "", err
+	}
+	jsonBlob, err := json.MarshalIndent(proofData, "", "  ")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal proof data: %w", err)
+	}
+	outputPath, err := DirSink(filepath.Join(r.config.ProofDir, string(kind))).Store(ctx, name, jsonBlob)
+	if err != nil {
+		return nil, "", err
+	}
+	r.log().Infof("✓ %s proof saved to %s\n", kind, outputPath)
+	r.storeProof(ctx, string(kind)+"-"+name, jsonBlob)
+	return proofData, outputPath, nil
+}
+
+// proveFinality proves the finalized header of update, the update of period, if the finality circuit is loaded
+func (r *Relayer) proveFinality(ctx context.Context, update *types.LightClientUpdate, period uint64) error {
+	if r.circuits.circuit(CircuitFinality) == nil {
+		return nil
+	}
+	assignment, err := circuitwitness.BuildFinalityWitness(update)
+	if err != nil {
+		return fmt.Errorf("failed to build finality witness: %w", err)
+	}
+	_, _, err = r.proveCircuit(ctx, CircuitFinality, proofFileName(period), assignment)
+	return err
+}
+
+// ProveReceipt proves the receipt of transaction txIndex of the block at slot, fetched by listener,
+// with the receipt circuit. It returns the proof data and the path of the proof file.
+func (r *Relayer) ProveReceipt(ctx context.Context, listener *Listener, slot uint64, txIndex int) (any, string, error) {
+	if r.circuits.circuit(CircuitReceipt) == nil {
+		return nil, "", fmt.Errorf("%s: %w", CircuitReceipt, ErrCircuitNotLoaded)
+	}
+	assignment, err := listener.ReceiptWitness(ctx, slot, txIndex)
+	if err != nil {
+		return nil, "", err
+	}
+	return r.proveCircuit(ctx, CircuitReceipt, fmt.Sprintf("receipt-%d-%d.json", slot, txIndex), assignment)
+}
@@ -0,0 +1,73 @@
+package relayer
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitRegistry(t *testing.T) {
+	kinds, err := ParseCircuitKinds([]string{"finality", "sc-update", "finality"})
+	require.NoError(t, err)
+	require.Equal(t, []CircuitKind{CircuitScUpdate, CircuitFinality}, kinds)
+	_, err = ParseCircuitKinds([]string{"headers"})
+	require.Error(t, err)
+
+	// the square circuit stands for the finality circuit in the manifest
+	dir := t.TempDir()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+	for file, v := range map[string]io.WriterTo{"Square.ccs": ccs, "Square.pk": pk, "Square.vk": vk} {
+		f, err := os.Create(filepath.Join(dir, file))
+		require.NoError(t, err)
+		_, err = v.WriteTo(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	artifacts := types.CircuitManifest{Name: "Eth2FinalityCircuit", Backend: types.BackendGroth16, Curve: ecc.BN254.String(),
+		CCS: "Square.ccs", PK: "Square.pk", VK: "Square.vk"}
+	require.NoError(t, artifacts.SetChecksums(dir))
+	manifest := &types.ArtifactManifest{}
+	manifest.Set(artifacts)
+	manifestPath := filepath.Join(dir, "manifest.json")
+	require.NoError(t, manifest.Save(manifestPath))
+
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled", "--proof-dir", t.TempDir(),
+		"--manifest", manifestPath, "--circuits", "finality")
+	r := &Relayer{config: config}
+	require.NoError(t, r.setupCircuits())
+	require.Equal(t, []CircuitKind{CircuitFinality, CircuitScUpdate}, r.circuits.Kinds())
+	require.Same(t, r.circuits.circuit(CircuitScUpdate), r.mainCircuit())
+	require.Same(t, r.circuits.circuit(CircuitFinality), r.circuitByName("Eth2FinalityCircuit"))
+
+	// the proofs are routed to the circuit of their kind, and saved in its subdirectory
+	y := new(big.Int).Exp(big.NewInt(2), new(big.Int).Lsh(big.NewInt(1), 1000), ecc.BN254.ScalarField())
+	proofData, path, err := r.proveCircuit(context.Background(), CircuitFinality, proofFileName(7), &squareCircuit{X: 2, Y: y})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(config.ProofDir, "finality", proofFileName(7)), path)
+	require.FileExists(t, path)
+	require.IsType(t, &types.ProofData{}, proofData)
+
+	_, _, err = r.proveCircuit(context.Background(), CircuitReceipt, "receipt-1-0.json", &squareCircuit{X: 2, Y: y})
+	require.ErrorIs(t, err, ErrCircuitNotLoaded)
+	_, _, err = r.ProveReceipt(context.Background(), nil, 1, 0)
+	require.ErrorIs(t, err, ErrCircuitNotLoaded)
+
+	// the kinds other than sc-update need their artifacts
+	r = &Relayer{config: cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled",
+		"--manifest", manifestPath, "--circuits", "sc-update,receipt")}
+	require.Error(t, r.setupCircuits())
+}
@@ -1,14 +1,74 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/kysee/zk-chains/provers"
+	"github.com/kysee/zk-chains/provers/server"
 	"github.com/kysee/zk-chains/provers/types"
 )
 
 func main() {
-	//relayer.RelayerMain(types.NewConfig(os.Args...))
+	// SIGINT or SIGTERM stops the commands gracefully, a second one kills them
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	relayer.ListenerMain(types.NewConfig(os.Args...))
+	// `status` inspects the state left on disk by a (stopped) relayer and exits
+	if len(os.Args) > 1 && os.Args[1] == "status" {
+		relayer.StatusMain(types.NewConfig(os.Args[2:]...))
+		return
+	}
+	// `simulate --fixtures dir [--prove true] [--report file]` replays recorded updates and exits
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		relayer.SimulateMain(types.NewConfig(os.Args[2:]...))
+		return
+	}
+	// `bench [--fixtures dir] [--bench-runs 5] [--report file]` proves a recorded update repeatedly and
+	// prints the proving times, peak memory and proof sizes as JSON
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		relayer.BenchMain(ctx, types.NewConfig(os.Args[2:]...))
+		return
+	}
+
+	// `tx-status --exec-rpc url --tx hash [--max-gas n]` packages the proof bundle of a successful transaction
+	if len(os.Args) > 1 && os.Args[1] == "tx-status" {
+		relayer.TxStatusMain(ctx, types.NewConfig(os.Args[2:]...))
+		return
+	}
+
+	// `prove-tx --exec-rpc url --tx hash [--max-gas n]` waits for the block of a successful transaction to be
+	// attested and proves its receipt with the receipt circuit of the manifest
+	if len(os.Args) > 1 && os.Args[1] == "prove-tx" {
+		relayer.TxProofMain(ctx, types.NewConfig(os.Args[2:]...))
+		return
+	}
+
+	// `watch --exec-rpc url --watch-contracts 0x… [--watch-topics 0x…]` proves the transactions emitting the watched logs
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		relayer.WatchMain(ctx, types.NewConfig(os.Args[2:]...))
+		return
+	}
+
+	// `run [--once]` runs the relayer as a daemon, proving every period; with --once it proves one period
+	// (or, with --tx, one receipt) and exits 0 once proven, 2 if the update is not available yet, 1 on failure
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		relayer.RelayerMain(ctx, types.NewConfig(os.Args[2:]...))
+		return
+	}
+
+	// `serve [--grpc-addr :9090]` proves sync committee updates and receipts on demand over gRPC
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		server.ServeMain(ctx, types.NewConfig(os.Args[2:]...))
+		return
+	}
+
+	//relayer.RelayerMain(ctx, types.NewConfig(os.Args...))
+
+	relayer.ListenerMain(ctx, types.NewConfig(os.Args...))
 }
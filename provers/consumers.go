@@ -0,0 +1,291 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/kysee/zk-chains/types"
+)
+
+// notifyTimeout bounds the delivery of one proof to one consumer
+const notifyTimeout = 30 * time.Second
+
+// Consumer is a downstream protocol served by the relayer. Consumers committing to the sync committee
+// in the same mode share the proof of each period, the relayer proves once per mode.
+type Consumer struct {
+	Name string `json:"name"`
+	// LightClient is the contract of the consumer the proofs are submitted to
+	LightClient string `json:"lightClient"`
+	// ScHashMode is the commitment to the sync committee stored by LightClient, the relayer's mode if empty
+	ScHashMode string `json:"scHashMode,omitempty"`
+	// Threshold is the minimum number of sync committee participants of the updates delivered, 0 for any
+	Threshold int `json:"threshold,omitempty"`
+	// Notify is where the proofs are delivered: a directory, or an http(s) webhook receiving them as a POST
+	Notify string `json:"notify"`
+
+	mode     types.ScPubKeysHashMode
+	notifier Notifier
+}
+
+// ConsumerNotification is what a consumer receives for each proven period
+type ConsumerNotification struct {
+	Consumer     string         `json:"consumer"`
+	LightClient  string         `json:"lightClient"`
+	Period       uint64         `json:"period"`
+	ScHashMode   string         `json:"scHashMode"`
+	Participants int            `json:"participants"`
+	ProofPath    string         `json:"proofPath"`
+	Calldata     types.HexBytes `json:"calldata"` // submission to LightClient, see EncodeSubmission
+	Proof        any            `json:"proof"`
+}
+
+// Notifier delivers proofs to a consumer
+type Notifier interface {
+	Notify(ctx context.Context, n *ConsumerNotification) error
+}
+
+// DirNotifier writes each notification to <dir>/proof-period-N.json
+type DirNotifier string
+
+func (d DirNotifier) Notify(_ context.Context, n *ConsumerNotification) error {
+	jsonBlob, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(string(d), proofFileName(n.Period)), jsonBlob, 0644)
+}
+
+// WebhookNotifier POSTs each notification as JSON to URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n *ConsumerNotification) error {
+	jsonBlob, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(jsonBlob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s returned %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// NewNotifier returns the notifier of a Consumer.Notify target
+func NewNotifier(target string) (Notifier, error) {
+	switch {
+	case target == "":
+		return nil, fmt.Errorf("no notification target")
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		return &WebhookNotifier{URL: target, Client: &http.Client{}}, nil
+	default:
+		return DirNotifier(strings.TrimPrefix(target, "file://")), nil
+	}
+}
+
+// ConsumerRegistry lists the consumers served by the relayer
+type ConsumerRegistry struct {
+	consumers []*Consumer
+}
+
+// LoadConsumerRegistry reads the JSON array of consumers at path. Consumers without ScHashMode
+// commit in defaultMode, the mode of the relayer's circuit.
+func LoadConsumerRegistry(path string, defaultMode types.ScPubKeysHashMode) (*ConsumerRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var consumers []*Consumer
+	if err := json.Unmarshal(data, &consumers); err != nil {
+		return nil, fmt.Errorf("failed to parse consumers %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for _, c := range consumers {
+		if c.Name == "" || names[c.Name] {
+			return nil, fmt.Errorf("consumer names must be unique and not empty, got %q", c.Name)
+		}
+		names[c.Name] = true
+
+		c.mode = defaultMode
+		if c.ScHashMode != "" {
+			if c.mode, err = types.ParseScPubKeysHashMode(c.ScHashMode); err != nil {
+				return nil, fmt.Errorf("consumer %s: %w", c.Name, err)
+			}
+		}
+		if c.notifier, err = NewNotifier(c.Notify); err != nil {
+			return nil, fmt.Errorf("consumer %s: %w", c.Name, err)
+		}
+	}
+	return &ConsumerRegistry{consumers: consumers}, nil
+}
+
+// Consumers returns the registered consumers
+func (reg *ConsumerRegistry) Consumers() []*Consumer {
+	return reg.consumers
+}
+
+// Modes returns the distinct commitment modes of the consumers, each one needing its own proof
+func (reg *ConsumerRegistry) Modes() []types.ScPubKeysHashMode {
+	var modes []types.ScPubKeysHashMode
+	seen := make(map[types.ScPubKeysHashMode]bool)
+	for _, c := range reg.consumers {
+		if !seen[c.mode] {
+			seen[c.mode] = true
+			modes = append(modes, c.mode)
+		}
+	}
+	return modes
+}
+
+// setupConsumerCircuits loads the Eth2ScUpdateCircuit of every consumer mode other than the relayer's
+func (r *Relayer) setupConsumerCircuits() error {
+	if r.consumers == nil {
+		return nil
+	}
+	r.modeCircuits = make(map[types.ScPubKeysHashMode]*loadedCircuit)
+	var manifest *types.ArtifactManifest
+	for _, mode := range r.consumers.Modes() {
+		if mode == r.config.ScPubKeysHashMode {
+			continue
+		}
+		var err error
+		if manifest == nil {
+			if manifest, err = types.LoadArtifactManifest(r.config.ManifestPath); err != nil {
+				return fmt.Errorf("consumers committing in %v mode need the %s artifacts: %w", mode, types.ModeCircuitName(mode), err)
+			}
+		}
+		artifacts, err := manifest.Circuit(types.ModeCircuitName(mode))
+		if err != nil {
+			return err
+		}
+		if r.modeCircuits[mode], err = loadCircuit(artifacts, filepath.Dir(r.config.ManifestPath), r.proverSettings()); err != nil {
+			return err
+		}
+	}
+	r.log().Infof("Serving %d consumers with %d proofs per period\n", len(r.consumers.Consumers()), len(r.consumers.Modes()))
+	return nil
+}
+
+// modeProof is the proof of one period for the consumers committing in one mode
+type modeProof struct {
+	path string
+	data any
+}
+
+// serveConsumers proves the update in the modes of the consumers that the proof of the relayer's mode
+// (saved at proofPath) does not cover, and notifies every consumer whose threshold the update meets.
+// Delivery failures are only logged, a consumer being down must not stall the others.
+func (r *Relayer) serveConsumers(ctx context.Context, update *types.LightClientUpdate, period uint64, proofData any, proofPath string) error {
+	if r.consumers == nil {
+		return nil
+	}
+	proofs := map[types.ScPubKeysHashMode]*modeProof{
+		r.config.ScPubKeysHashMode: {path: proofPath, data: proofData},
+	}
+	for mode, loaded := range r.modeCircuits {
+		proof, err := r.proveMode(ctx, update, period, mode, loaded)
+		if err != nil {
+			return fmt.Errorf("failed to generate %v mode proof: %w", mode, err)
+		}
+		proofs[mode] = proof
+	}
+
+	participants := 0
+	for _, bit := range types.ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits) {
+		if bit {
+			participants++
+		}
+	}
+	for _, c := range r.consumers.Consumers() {
+		if participants < c.Threshold {
+			r.log().Infof("Consumer %s: period %d has %d participants, below its threshold %d\n", c.Name, period, participants, c.Threshold)
+			continue
+		}
+		proof := proofs[c.mode]
+		calldata, err := EncodeSubmission(proof.data, update, r.config.PublicStateRoot)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err = c.notifier.Notify(ctx, &ConsumerNotification{
+			Consumer:     c.Name,
+			LightClient:  c.LightClient,
+			Period:       period,
+			ScHashMode:   c.mode.String(),
+			Participants: participants,
+			ProofPath:    proof.path,
+			Calldata:     calldata,
+			Proof:        proof.data,
+		})
+		cancel()
+		if err != nil {
+			r.log().Warnf("failed to notify consumer %s of period %d: %v\n", c.Name, period, err)
+			continue
+		}
+		r.log().Infof("✓ Consumer %s notified of period %d\n", c.Name, period)
+	}
+	return nil
+}
+
+// proveMode proves the update with the circuit compiled for mode, and saves the proof in
+// Config.ProofDir/<mode>/proof-period-N.json
+func (r *Relayer) proveMode(ctx context.Context, update *types.LightClientUpdate, period uint64, mode types.ScPubKeysHashMode, loaded *loadedCircuit) (*modeProof, error) {
+	witness, err := r.buildWitness(update, r.currentScPubkeys)
+	if err != nil {
+		return nil, err
+	}
+	witness.Params.ScPubKeysHashMode = mode
+	scPubKeysHash := types.ComputeScPubKeysHashWithMode(r.currentScPubkeys[:], mode)
+	witness.ScPubKeysHash = [32]uints.U8(uints.NewU8Array(scPubKeysHash[:]))
+
+	fullWitness, err := frontend.NewWitness(witness, loaded.curve.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %w", err)
+	}
+	proofSolidity, err := loaded.prove(ctx, fullWitness)
+	if err != nil {
+		return nil, err
+	}
+	proofData, err := types.CreateProofDataFor(loaded.backend, proofSolidity)
+	if err != nil {
+		return nil, err
+	}
+	jsonBlob, err := json.MarshalIndent(proofData, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proof data: %w", err)
+	}
+	dir := filepath.Join(r.config.ProofDir, mode.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create proof directory: %w", err)
+	}
+	outputPath := filepath.Join(dir, proofFileName(period))
+	if err := os.WriteFile(outputPath, jsonBlob, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write proof file: %w", err)
+	}
+	r.log().Infof("✓ %v mode proof saved to %s\n", mode, outputPath)
+	return &modeProof{path: outputPath, data: proofData}, nil
+}
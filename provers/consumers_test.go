@@ -0,0 +1,99 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConsumers(t *testing.T, consumers []map[string]any) string {
+	data, err := json.Marshal(consumers)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "consumers.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestServeConsumers(t *testing.T) {
+	var received []ConsumerNotification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var n ConsumerNotification
+		require.NoError(t, json.Unmarshal(body, &n))
+		received = append(received, n)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := writeConsumers(t, []map[string]any{
+		{"name": "bridge", "lightClient": "0x01", "notify": filepath.Join(dir, "bridge")},
+		{"name": "oracle", "lightClient": "0x02", "threshold": 400, "notify": server.URL},
+		{"name": "strict", "lightClient": "0x03", "threshold": 513, "notify": "file://" + filepath.Join(dir, "strict")},
+	})
+	config := cfgtypes.NewConfig("--consumers", path, "--root", t.TempDir(), "--proof-dir", dir)
+	r, err := NewRelayer(config, nil)
+	require.NoError(t, err)
+	require.Len(t, r.consumers.Consumers(), 3)
+	// every consumer commits in the relayer's mode, a single proof serves them all
+	require.Equal(t, []types.ScPubKeysHashMode{config.ScPubKeysHashMode}, r.consumers.Modes())
+	require.NoError(t, r.setupConsumerCircuits())
+	require.Empty(t, r.modeCircuits)
+
+	update, proofData := loadTestSubmission(t)
+	proofPath := filepath.Join(dir, proofFileName(1105))
+	require.NoError(t, r.serveConsumers(context.Background(), update, 1105, proofData, proofPath))
+
+	data, err := os.ReadFile(filepath.Join(dir, "bridge", proofFileName(1105)))
+	require.NoError(t, err)
+	var n ConsumerNotification
+	require.NoError(t, json.Unmarshal(data, &n))
+	require.Equal(t, "bridge", n.Consumer)
+	require.Equal(t, proofPath, n.ProofPath)
+	calldata, err := EncodeSubmission(proofData, update, false)
+	require.NoError(t, err)
+	require.Equal(t, types.HexBytes(calldata), n.Calldata)
+
+	require.Len(t, received, 1)
+	require.Equal(t, "oracle", received[0].Consumer)
+	require.Equal(t, "0x02", received[0].LightClient)
+	require.GreaterOrEqual(t, received[0].Participants, 400)
+
+	// the update does not meet the threshold of strict
+	_, err = os.Stat(filepath.Join(dir, "strict"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLoadConsumerRegistry(t *testing.T) {
+	path := writeConsumers(t, []map[string]any{
+		{"name": "a", "notify": "out"},
+		{"name": "b", "scHashMode": "full", "notify": "out"},
+		{"name": "c", "scHashMode": "truncated", "notify": "out"},
+	})
+	reg, err := LoadConsumerRegistry(path, types.ScPubKeysHashTruncated)
+	require.NoError(t, err)
+	require.Equal(t, []types.ScPubKeysHashMode{types.ScPubKeysHashTruncated, types.ScPubKeysHashFull}, reg.Modes())
+
+	// the circuit of another mode must be in the manifest
+	config := cfgtypes.NewConfig("--consumers", path, "--root", t.TempDir(), "--manifest", filepath.Join(t.TempDir(), "manifest.json"))
+	r, err := NewRelayer(config, nil)
+	require.NoError(t, err)
+	require.Error(t, r.setupConsumerCircuits())
+
+	for _, consumers := range [][]map[string]any{
+		{{"name": "a", "notify": "out"}, {"name": "a", "notify": "out"}},
+		{{"name": "a"}},
+		{{"name": "a", "scHashMode": "sha3", "notify": "out"}},
+	} {
+		_, err := LoadConsumerRegistry(writeConsumers(t, consumers), types.ScPubKeysHashTruncated)
+		require.Error(t, err)
+	}
+}
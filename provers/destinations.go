@@ -0,0 +1,369 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Destination is a chain the proofs are submitted to, in addition to the one of Config.DestinationRPC
+// and Config.LightClientAddress. Each destination has its own submitter: nonces, fees and gas limits
+// are handled independently, and a destination failing does not hold the others back.
+type Destination struct {
+	Name string `json:"name"`
+	// RPC is the JSON-RPC endpoint of the chain
+	RPC string `json:"rpc"`
+	// ChainID is checked against the chain id of RPC, 0 skips the check
+	ChainID uint64 `json:"chainId,omitempty"`
+	// LightClient is the contract the proofs are submitted to
+	LightClient string `json:"lightClient"`
+	// RemoteSigner, From, SubmitterKeystore and SubmitterKeyEnv set the submitter of the destination as the
+	// Config fields of the same names do, the submitter of the config is used if none is set
+	RemoteSigner      string `json:"remoteSigner,omitempty"`
+	From              string `json:"from,omitempty"`
+	SubmitterKeystore string `json:"submitterKeystore,omitempty"`
+	SubmitterKeyEnv   string `json:"submitterKeyEnv,omitempty"`
+	// GasLimit caps the gas of a submission, Config.GasLimit if 0
+	GasLimit uint64 `json:"gasLimit,omitempty"`
+	// Confirmations is the number of blocks a submission waits for, Config.SubmitConfirmations if 0
+	Confirmations uint64 `json:"confirmations,omitempty"`
+	// MaxFeeGwei and MaxTipGwei cap the fees of a submission, Config.MaxFeeGwei and Config.MaxTipGwei if 0
+	MaxFeeGwei float64 `json:"maxFeeGwei,omitempty"`
+	MaxTipGwei float64 `json:"maxTipGwei,omitempty"`
+}
+
+// LoadDestinations reads the JSON array of destinations at path
+func LoadDestinations(path string) ([]*Destination, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var destinations []*Destination
+	if err := json.Unmarshal(data, &destinations); err != nil {
+		return nil, fmt.Errorf("failed to parse destinations %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for _, d := range destinations {
+		// the name is part of the status files of the destination
+		if d.Name == "" || d.Name == defaultDestination || names[d.Name] || strings.ContainsAny(d.Name, `/\.`) {
+			return nil, fmt.Errorf("destination names must be unique, not empty, not %q and without '/', '\\' or '.', got %q", defaultDestination, d.Name)
+		}
+		names[d.Name] = true
+		if d.RPC == "" {
+			return nil, fmt.Errorf("destination %s has no rpc", d.Name)
+		}
+		if !common.IsHexAddress(d.LightClient) {
+			return nil, fmt.Errorf("destination %s: invalid light client address %q", d.Name, d.LightClient)
+		}
+	}
+	return destinations, nil
+}
+
+// defaultDestination names the destination of Config.DestinationRPC and Config.LightClientAddress
+const defaultDestination = "default"
+
+// destination is a chain the relayer submits to: the one of the config, or a Destination
+type destination struct {
+	name      string
+	submitter *Submitter
+}
+
+func (d *destination) String() string {
+	return fmt.Sprintf("%s (%s)", d.name, d.submitter.to.Hex())
+}
+
+// connect dials the destination and creates its submitter, with the signer, gas limit and confirmations of
+// config where the destination sets none. signer is the submitter of config, nil if none is configured.
+func (d *Destination) connect(ctx context.Context, config *cfgtypes.Config, signer TxSigner) (*destination, error) {
+	client, err := ethclient.Dial(d.RPC)
+	if err != nil {
+		return nil, fmt.Errorf("destination %s: failed to connect to %s: %w", d.Name, d.RPC, err)
+	}
+	if d.RemoteSigner != "" || d.SubmitterKeystore != "" || d.SubmitterKeyEnv != "" {
+		own := *config
+		own.RemoteSigner, own.SubmitterAddress = d.RemoteSigner, d.From
+		own.SubmitterKeystore, own.SubmitterKeyEnv = d.SubmitterKeystore, d.SubmitterKeyEnv
+		if signer, err = LoadSubmitterSigner(ctx, &own); err != nil {
+			return nil, fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("destination %s: no submitter key configured", d.Name)
+	}
+	gasLimit, confirmations := config.GasLimit, config.SubmitConfirmations
+	if d.GasLimit != 0 {
+		gasLimit = d.GasLimit
+	}
+	if d.Confirmations != 0 {
+		confirmations = d.Confirmations
+	}
+	submitter, err := NewSubmitter(ctx, client, signer, common.HexToAddress(d.LightClient), gasLimit, confirmations)
+	if err != nil {
+		return nil, fmt.Errorf("destination %s: %w", d.Name, err)
+	}
+	if d.ChainID != 0 && submitter.chainID.Uint64() != d.ChainID {
+		return nil, fmt.Errorf("destination %s: %s is chain %s, expected %d", d.Name, d.RPC, submitter.chainID, d.ChainID)
+	}
+	feePolicy := NewFeePolicy(config)
+	if d.MaxFeeGwei != 0 {
+		feePolicy.MaxFeePerGas = gweiToWei(d.MaxFeeGwei)
+	}
+	if d.MaxTipGwei != 0 {
+		feePolicy.MaxTipPerGas = gweiToWei(d.MaxTipGwei)
+	}
+	submitter.SetFeePolicy(feePolicy)
+	return &destination{name: d.Name, submitter: submitter}, nil
+}
+
+// connectDestinations connects to the light client of Config.DestinationRPC and Config.LightClientAddress,
+// which simulates the submissions and is read on start, and to the destinations the proofs are submitted
+// to: that light client if a submitter is configured, and the ones of Config.DestinationsPath. It sets
+// Config.SubmitterAddress to the account of the submitter.
+func connectDestinations(config *cfgtypes.Config) (GasEstimator, ethereum.ContractCaller, []*destination, error) {
+	signCtx, cancel := context.WithTimeout(context.Background(), gasEstimateTimeout)
+	signer, err := LoadSubmitterSigner(signCtx, config)
+	cancel()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var gasEstimator GasEstimator
+	var lightClient ethereum.ContractCaller
+	var destinations []*destination
+	if config.DestinationRPC != "" && config.LightClientAddress != "" {
+		client, err := ethclient.Dial(config.DestinationRPC)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to connect to %s: %w", config.DestinationRPC, err)
+		}
+		gasEstimator, lightClient = client, client
+		config.Log().Infof("Submissions to %s are simulated against a %d gas budget\n", config.LightClientAddress, config.GasLimit)
+
+		if signer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), gasEstimateTimeout)
+			submitter, err := NewSubmitter(ctx, client, signer, common.HexToAddress(config.LightClientAddress), config.GasLimit, config.SubmitConfirmations)
+			cancel()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			submitter.SetFeePolicy(NewFeePolicy(config))
+			destinations = append(destinations, &destination{name: defaultDestination, submitter: submitter})
+			// simulations are run from the account that submits
+			config.SubmitterAddress = submitter.From().Hex()
+			config.Log().Infof("Proofs are submitted to %s from %s\n", config.LightClientAddress, config.SubmitterAddress)
+		}
+	}
+
+	if config.DestinationsPath != "" {
+		configured, err := LoadDestinations(config.DestinationsPath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, d := range configured {
+			ctx, cancel := context.WithTimeout(context.Background(), gasEstimateTimeout)
+			dest, err := d.connect(ctx, config, signer)
+			cancel()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			destinations = append(destinations, dest)
+			config.Log().Infof("Proofs are submitted to %s on chain %s from %s\n", dest, dest.submitter.chainID, dest.submitter.From().Hex())
+		}
+	}
+	return gasEstimator, lightClient, destinations, nil
+}
+
+// submittedMarker returns the file marking the proof at proofPath as accepted by the named destination,
+// proof-period-N.json.<name>.submitted. With a single destination the proof file's own marker is used.
+func submittedMarker(proofPath, name string) string {
+	return proofPath + "." + name + submittedSuffix
+}
+
+// submitStage sends the proofs of submissions to every destination. Each destination has its own queue
+// and goroutine, the proofs reach it in order. The proofs go to the destinations of the relayer when they
+// arrive: a destination replaced by Reload gets a new queue, which starts once the previous one is done so
+// that its submitter does not send next to the previous one. It returns once submissions is closed and
+// the queued proofs are submitted.
+func (r *Relayer) submitStage(submissions <-chan submission) {
+	type queue struct {
+		d    *destination
+		ch   chan submission
+		done chan struct{}
+	}
+	start := func(d *destination, previous *queue) *queue {
+		q := &queue{d: d, ch: make(chan submission, pipelineDepth), done: make(chan struct{})}
+		go func() {
+			defer close(q.done)
+			if previous != nil {
+				<-previous.done
+			}
+			for s := range q.ch {
+				r.submitProof(trace.ContextWithSpanContext(context.Background(), s.span), d, s.circuit, s.update, s.proofData, s.proofPath)
+				r.submitting.Add(-1)
+				r.settle()
+			}
+		}()
+		return q
+	}
+
+	queues := make(map[string]*queue)
+	var removed []*queue
+	for s := range submissions {
+		current := make(map[string]bool)
+		for _, d := range r.destinationList() {
+			current[d.name] = true
+			q := queues[d.name]
+			if q == nil || q.d != d {
+				if q != nil {
+					close(q.ch)
+				}
+				q = start(d, q)
+				queues[d.name] = q
+			}
+			r.submitting.Add(1)
+			q.ch <- s
+		}
+		// the proof was counted when it was queued by provePeriod
+		r.submitting.Add(-1)
+		r.settle()
+		for name, q := range queues {
+			if !current[name] {
+				close(q.ch)
+				removed = append(removed, q)
+				delete(queues, name)
+			}
+		}
+	}
+	for _, q := range queues {
+		close(q.ch)
+		<-q.done
+	}
+	for _, q := range removed {
+		<-q.done
+	}
+}
+
+// destinationList returns the destinations of the relayer, as last (re)loaded
+func (r *Relayer) destinationList() []*destination {
+	r.targetsMu.RLock()
+	defer r.targetsMu.RUnlock()
+	return r.destinations
+}
+
+// submitProof submits the proof of the kind circuit saved at proofPath to the light client of d. Proofs set aside by
+// checkSubmissionGas are not submitted, nor are the periods d already accepted, see acceptedBefore: a
+// restarted relayer, or one of several replicas, does not pay for a submission that would revert.
+// Failures before the transaction is sent are retried with the retry policy; a submission that is over
+// budget, reverted or sent but unconfirmed is not, since sending it again would duplicate it. A failed
+// submission is logged and leaves the proof pending for d, the relayer keeps proving the next periods,
+// unless it reverted because the period was accepted meanwhile. An accepted one is recorded in the
+// submission ledger and marked with submittedMarker, and the proof is marked as submitted once every
+// destination accepted it. The submission is traced as a submit span tagged with kind, a child of the
+// span of ctx, whose cancellation it ignores.
+func (r *Relayer) submitProof(ctx context.Context, d *destination, kind CircuitKind, update *types.LightClientUpdate, proofData any, proofPath string) {
+	if _, err := os.Stat(proofPath); err != nil {
+		r.log().Infof("Proof %s is not pending, it is not submitted to %s\n", proofPath, d)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), submitTimeout)
+	defer cancel()
+	period, inputsHash, tracked := r.submissionKey(update, proofPath)
+	if tracked && r.skipAccepted(ctx, d, period, inputsHash, proofPath) {
+		return
+	}
+	ctx, span := tracer().Start(ctx, "submit", trace.WithAttributes(append(updateAttributes(update), attrDestination.String(d.name), attrCircuit.String(string(kind)))...))
+	var receipt *gethtypes.Receipt
+	err := r.retryPolicy().Retry(ctx, func() error {
+		var err error
+		receipt, err = d.submitter.SubmitProof(ctx, proofData, update, r.config.PublicStateRoot)
+		switch {
+		case errors.Is(err, ErrGasOverBudget), errors.Is(err, ErrSubmissionReverted), errors.Is(err, ErrSubmissionUnconfirmed):
+			return Permanent(err)
+		case err != nil:
+			r.log().Warnf("submission of %s to %s failed: %v\n", proofPath, d, err)
+		}
+		return err
+	})
+	endStage(span, err)
+	if err != nil {
+		r.log().Warnf("failed to submit %s to %s: %v\n", proofPath, d, err)
+		// another relayer may have been first
+		if tracked && errors.Is(err, ErrSubmissionReverted) {
+			r.skipAccepted(ctx, d, period, inputsHash, proofPath)
+		}
+		return
+	}
+	r.log().Infof("✓ Proof submitted to %s in %s (block %d, %d gas)\n", d, receipt.TxHash, receipt.BlockNumber, receipt.GasUsed)
+	if tracked {
+		if err := r.updateSubmissionLedger(d, period, &acceptedSubmission{InputsHash: inputsHash, TxHash: receipt.TxHash}); err != nil {
+			r.log().Warnf("failed to record the submission of %s to %s: %v\n", proofPath, d, err)
+		}
+	}
+	r.markSubmitted(d, proofPath, receipt.TxHash)
+}
+
+// submissionKey returns the period of the proof at proofPath and the SubmissionInputsHash of update,
+// false if the submission cannot be tracked in the ledger
+func (r *Relayer) submissionKey(update *types.LightClientUpdate, proofPath string) (uint64, common.Hash, bool) {
+	period, ok := parsePeriod(filepath.Base(proofPath), proofFilePrefix, proofFileSuffix)
+	if !ok || r.config.ProofDir == "" {
+		return 0, common.Hash{}, false
+	}
+	inputsHash, err := SubmissionInputsHash(update)
+	if err != nil {
+		r.log().Warnf("failed to hash the inputs of %s, it is not checked against the submission ledger: %v\n", proofPath, err)
+		return 0, common.Hash{}, false
+	}
+	return period, inputsHash, true
+}
+
+// skipAccepted marks the proof at proofPath as submitted to d if d accepted its period already, and
+// reports whether it did
+func (r *Relayer) skipAccepted(ctx context.Context, d *destination, period uint64, inputsHash common.Hash, proofPath string) bool {
+	accepted, err := r.acceptedBefore(ctx, d, period, inputsHash)
+	if err != nil {
+		r.log().Warnf("failed to check the submissions of period %d to %s: %v\n", period, d, err)
+	}
+	if accepted == nil {
+		return false
+	}
+	if accepted.InputsHash != inputsHash {
+		r.log().Warnf("%s accepted another update of period %d (inputs %s), %s is not submitted\n", d, period, accepted.InputsHash, proofPath)
+	} else {
+		r.log().Infof("%s accepted period %d already, %s is not submitted again\n", d, period, proofPath)
+	}
+	r.markSubmitted(d, proofPath, accepted.TxHash)
+	return true
+}
+
+// markSubmitted marks the proof at proofPath as accepted by d, in txHash: with submittedMarker, and
+// as submitted once every destination accepted it
+func (r *Relayer) markSubmitted(d *destination, proofPath string, txHash common.Hash) {
+	if destinations := r.destinationList(); len(destinations) > 1 {
+		if err := os.WriteFile(submittedMarker(proofPath, d.name), []byte(txHash.Hex()), 0644); err != nil {
+			r.log().Warnf("failed to mark %s as submitted to %s: %v\n", proofPath, d, err)
+			return
+		}
+		for _, other := range destinations {
+			if _, err := os.Stat(submittedMarker(proofPath, other.name)); err != nil {
+				return
+			}
+		}
+	}
+	if err := os.WriteFile(proofPath+submittedSuffix, []byte(txHash.Hex()), 0644); err != nil {
+		r.log().Warnf("failed to mark %s as submitted: %v\n", proofPath, err)
+	}
+}
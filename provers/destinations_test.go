@@ -0,0 +1,101 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDestinations(t *testing.T) {
+	load := func(body string) ([]*Destination, error) {
+		path := filepath.Join(t.TempDir(), "destinations.json")
+		require.NoError(t, os.WriteFile(path, []byte(body), 0644))
+		return LoadDestinations(path)
+	}
+
+	destinations, err := load(`[
+		{"name": "holesky", "rpc": "http://localhost:8545", "chainId": 17000, "lightClient": "0x0000000000000000000000000000000000000001"},
+		{"name": "base", "rpc": "http://localhost:8546", "lightClient": "0x0000000000000000000000000000000000000002", "gasLimit": 3000000}
+	]`)
+	require.NoError(t, err)
+	require.Len(t, destinations, 2)
+	require.Equal(t, uint64(17000), destinations[0].ChainID)
+	require.Equal(t, uint64(3_000_000), destinations[1].GasLimit)
+
+	for _, body := range []string{
+		`[{"name": "a", "rpc": "http://localhost:8545", "lightClient": "0x01"}]`,
+		`[{"name": "a", "lightClient": "0x0000000000000000000000000000000000000001"}]`,
+		`[{"name": "default", "rpc": "http://localhost:8545", "lightClient": "0x0000000000000000000000000000000000000001"}]`,
+		`[{"name": "a.b", "rpc": "http://localhost:8545", "lightClient": "0x0000000000000000000000000000000000000001"}]`,
+		`[{"name": "a", "rpc": "http://localhost:8545", "lightClient": "0x0000000000000000000000000000000000000001"},
+		  {"name": "a", "rpc": "http://localhost:8546", "lightClient": "0x0000000000000000000000000000000000000001"}]`,
+	} {
+		_, err := load(body)
+		require.Error(t, err, body)
+	}
+}
+
+func TestSubmitToDestinations(t *testing.T) {
+	config := &cfgtypes.Config{
+		ProofDir:         t.TempDir(),
+		DestinationsPath: filepath.Join(t.TempDir(), "destinations.json"),
+		RetryMaxAttempts: 1,
+	}
+	require.NoError(t, os.WriteFile(config.DestinationsPath, []byte(`[
+		{"name": "holesky", "rpc": "http://localhost:8545", "lightClient": "0x0000000000000000000000000000000000000001"}
+	]`), 0644))
+
+	r := &Relayer{config: config}
+	var backends []*chainBackend
+	for _, name := range []string{defaultDestination, "holesky"} {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		backend := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100,
+			revertPrefix: 0xff, receipts: map[common.Hash]*gethtypes.Receipt{}}
+		s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), common.HexToAddress("0x01"), 0, 1)
+		require.NoError(t, err)
+		s.pollInterval = time.Millisecond
+		backends = append(backends, backend)
+		r.destinations = append(r.destinations, &destination{name: name, submitter: s})
+	}
+	update, proofData := loadTestSubmission(t)
+	submit := func(periods ...uint64) {
+		submissions := make(chan submission, len(periods))
+		for _, period := range periods {
+			proofPath := filepath.Join(config.ProofDir, proofFileName(period))
+			require.NoError(t, os.WriteFile(proofPath, []byte("{}"), 0644))
+			submissions <- submission{update: update, proofData: proofData, proofPath: proofPath, circuit: CircuitScUpdate}
+		}
+		close(submissions)
+		r.submitStage(submissions)
+	}
+
+	// a destination failing does not hold the other back
+	backends[1].sendErr, backends[1].sendFailures = errors.New("connection refused"), 1
+	submit(1105, 1106)
+	require.Len(t, backends[0].sent, 2)
+	require.Len(t, backends[1].sent, 1)
+	require.Equal(t, uint64(1), backends[0].sent[1].Nonce())
+	require.Equal(t, uint64(0), backends[1].sent[0].Nonce())
+
+	status, err := ReadRelayerStatus(config)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1105}, status.PendingSubmissions)
+	require.Equal(t, map[string][]uint64{defaultDestination: {}, "holesky": {1105}}, status.PendingByDestination)
+
+	// a proof is submitted once every destination accepted it
+	submit(1107)
+	status, err = ReadRelayerStatus(config)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1105}, status.PendingSubmissions)
+	require.Equal(t, []uint64{1105}, status.PendingByDestination["holesky"])
+}
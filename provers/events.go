@@ -0,0 +1,104 @@
+package relayer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+// beaconEventTopics are the events after which a new update may be available
+var beaconEventTopics = []string{"light_client_finality_update", "light_client_optimistic_update", "head"}
+
+// eventWatcher follows the events of the beacon node, so that the prepare stage fetches the update of a
+// period as soon as the node reports a slot of it, instead of polling for it. Polling remains the
+// fallback: every RetryMaxBackoff while the stream is up, with the retry policy while it is down.
+type eventWatcher struct {
+	r *Relayer
+	// connected is set while events are received
+	connected atomic.Bool
+
+	mu sync.Mutex
+	// slot is the latest slot reported, changed is closed when it advances
+	slot    uint64
+	changed chan struct{}
+}
+
+// watchEvents follows the events of the beacon node until ctx is cancelled, it returns nil if
+// Config.BeaconEvents is off or the fetcher cannot stream events
+func (r *Relayer) watchEvents(ctx context.Context) *eventWatcher {
+	fetcher, ok := r.fetcher.(cfgtypes.EventFetcher)
+	if !r.config.BeaconEvents || !ok {
+		return nil
+	}
+	w := &eventWatcher{r: r, changed: make(chan struct{})}
+	go w.run(ctx, fetcher)
+	return w
+}
+
+// run streams the events, reconnecting with the retry policy until ctx is cancelled
+func (w *eventWatcher) run(ctx context.Context, fetcher cfgtypes.EventFetcher) {
+	policy := w.r.retryPolicy()
+	for attempt := 1; ctx.Err() == nil; attempt++ {
+		err := fetcher.Events(ctx, beaconEventTopics, func(event cfgtypes.BeaconEvent) {
+			if !w.connected.Swap(true) {
+				w.r.log().Infof("Following the beacon node events\n")
+			}
+			attempt = 0
+			w.observe(event.Slot)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if w.connected.Swap(false) || attempt == 1 {
+			w.r.log().Warnf("beacon event stream failed, polling until it is back: %v\n", err)
+		}
+		sleep(ctx, policy.Delay(max(attempt, 1)))
+	}
+}
+
+// observe records a slot reported by the node, waking the waits if it is a new one
+func (w *eventWatcher) observe(slot uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if slot <= w.slot {
+		return
+	}
+	w.slot = slot
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// wait returns once the node reports a new slot of period or a later one, or after fallback. While the
+// stream is down it waits for delay instead, as polling does. A nil watcher sleeps for delay.
+func (w *eventWatcher) wait(ctx context.Context, period uint64, delay, fallback time.Duration) {
+	if w == nil {
+		sleep(ctx, delay)
+		return
+	}
+	if w.connected.Load() {
+		delay = max(delay, fallback)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		w.mu.Lock()
+		changed := w.changed
+		w.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-changed:
+			w.mu.Lock()
+			slot := w.slot
+			w.mu.Unlock()
+			if w.r.config.Preset.Period(slot) >= period {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package relayer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types/slots"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIFetcherEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/eth/v1/events", r.URL.Path)
+		require.Equal(t, "head,light_client_finality_update", r.URL.Query().Get("topics"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(": keep-alive\n\n" +
+			"event: head\ndata: {\"slot\":\"9052235\",\"block\":\"0x01\"}\n\n" +
+			"event: light_client_finality_update\ndata: {\"version\":\"deneb\",\"data\":{\"attested_header\":{\"beacon\":{\"slot\":\"9052234\"}}}}\n\n" +
+			"event: light_client_optimistic_update\ndata: {\"attested_header\":{\"beacon\":{\"slot\":\"9052236\"}}}\n\n" +
+			"event: head\ndata: {\"block\":\"0x02\"}\n\n"))
+	}))
+	defer server.Close()
+
+	var events []cfgtypes.BeaconEvent
+	err := NewAPIFetcher(server.URL).Events(context.Background(), []string{"head", "light_client_finality_update"},
+		func(event cfgtypes.BeaconEvent) { events = append(events, event) })
+	require.ErrorContains(t, err, "closed")
+	require.Equal(t, []cfgtypes.BeaconEvent{
+		{Topic: "head", Slot: 9052235},
+		{Topic: "light_client_finality_update", Slot: 9052234},
+		{Topic: "light_client_optimistic_update", Slot: 9052236},
+	}, events)
+}
+
+// eventFetcher streams the slots sent to it
+type eventFetcher struct {
+	cfgtypes.Fetcher
+	slots chan uint64
+}
+
+func (f *eventFetcher) Events(ctx context.Context, _ []string, handle func(cfgtypes.BeaconEvent)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case slot := <-f.slots:
+			handle(cfgtypes.BeaconEvent{Topic: "head", Slot: slot})
+		}
+	}
+}
+
+func TestEventWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fetcher := &eventFetcher{slots: make(chan uint64)}
+	r := &Relayer{config: &cfgtypes.Config{BeaconEvents: true}, fetcher: fetcher}
+	w := r.watchEvents(ctx)
+	require.NotNil(t, w)
+	fetcher.slots <- slots.PeriodStartSlot(configs.Mainnet, 1105)
+	require.Eventually(t, w.connected.Load, time.Second, time.Millisecond)
+
+	// the wait for the update of period 1106 ends with the first slot of the period
+	done := make(chan struct{})
+	go func() {
+		w.wait(ctx, 1106, time.Millisecond, time.Hour)
+		close(done)
+	}()
+	fetcher.slots <- slots.PeriodStartSlot(configs.Mainnet, 1105) + 1
+	select {
+	case <-done:
+		t.Fatal("woken by a slot of period 1105")
+	case <-time.After(50 * time.Millisecond):
+	}
+	fetcher.slots <- slots.PeriodStartSlot(configs.Mainnet, 1106)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("not woken by a slot of period 1106")
+	}
+
+	// without events, waits are the polling delays
+	r.config.BeaconEvents = false
+	require.Nil(t, r.watchEvents(ctx))
+	start := time.Now()
+	(*eventWatcher)(nil).wait(ctx, 1106, 10*time.Millisecond, time.Hour)
+	require.Less(t, time.Since(start), time.Second)
+}
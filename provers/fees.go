@@ -0,0 +1,130 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+const (
+	// baseFeeMultiplier is the number of base fees a submission pays at most on top of its tip, so
+	// that it stays includable while the base fee rises for a few blocks
+	baseFeeMultiplier = 2
+	// minBumpPercent is the fee increase nodes require to replace a pending transaction
+	minBumpPercent = 10
+)
+
+// FeePolicy prices the EIP-1559 submissions: a tip suggested by the node and a fee cap of twice the
+// base fee on top of it, within the caps. A submission not mined after StuckAfter is replaced by the
+// same transaction with fees raised by BumpPercent, until it is mined or the caps are reached.
+type FeePolicy struct {
+	// MaxFeePerGas and MaxTipPerGas cap the fees of a submission, nil means no cap
+	MaxFeePerGas *big.Int
+	MaxTipPerGas *big.Int
+	// StuckAfter is how long a submission waits to be mined before it is replaced, 0 disables replacements
+	StuckAfter time.Duration
+	// BumpPercent is the fee increase of a replacement, at least the 10% nodes require
+	BumpPercent uint64
+}
+
+// NewFeePolicy returns the fee policy of config
+func NewFeePolicy(config *cfgtypes.Config) FeePolicy {
+	return FeePolicy{
+		MaxFeePerGas: gweiToWei(config.MaxFeeGwei),
+		MaxTipPerGas: gweiToWei(config.MaxTipGwei),
+		StuckAfter:   config.StuckAfter,
+		BumpPercent:  config.FeeBumpPercent,
+	}
+}
+
+// gweiToWei converts an amount of gwei, nil for 0
+func gweiToWei(gwei float64) *big.Int {
+	if gwei <= 0 {
+		return nil
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(params.GWei)).Int(nil)
+	return wei
+}
+
+// withDefaults raises BumpPercent to the minimum replacement increase
+func (p FeePolicy) withDefaults() FeePolicy {
+	if p.BumpPercent < minBumpPercent {
+		p.BumpPercent = minBumpPercent
+	}
+	return p
+}
+
+// capped applies the caps of the policy to the fees
+func (p FeePolicy) capped(tip, feeCap *big.Int) (*big.Int, *big.Int) {
+	if p.MaxTipPerGas != nil && tip.Cmp(p.MaxTipPerGas) > 0 {
+		tip = p.MaxTipPerGas
+	}
+	if p.MaxFeePerGas != nil && feeCap.Cmp(p.MaxFeePerGas) > 0 {
+		feeCap = p.MaxFeePerGas
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = feeCap
+	}
+	return tip, feeCap
+}
+
+// fees returns the tip and fee cap of a new submission: the tip suggested by the node and the base fee
+// of the head, within the caps of the policy
+func (s *Submitter) fees(ctx context.Context) (*big.Int, *big.Int, error) {
+	tip, err := s.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch gas tip: %w", err)
+	}
+	head, err := s.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch head: %w", err)
+	}
+	feeCap := new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(baseFeeMultiplier)))
+	tip, feeCap = s.feePolicy.capped(tip, feeCap)
+	return tip, feeCap, nil
+}
+
+// bump returns the replacement of the stuck transaction tx, with its fees raised by the bump percentage
+// or to the current fees if they are higher, nil if the caps leave no room for a replacement
+func (s *Submitter) bump(ctx context.Context, tx *gethtypes.Transaction) (*gethtypes.Transaction, error) {
+	tip, feeCap, err := s.fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy := s.feePolicy.withDefaults()
+	raise := func(fee *big.Int, percent uint64) *big.Int {
+		return new(big.Int).Div(new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent)), big.NewInt(100))
+	}
+	if bumped := raise(tx.GasTipCap(), policy.BumpPercent); bumped.Cmp(tip) > 0 {
+		tip = bumped
+	}
+	if bumped := raise(tx.GasFeeCap(), policy.BumpPercent); bumped.Cmp(feeCap) > 0 {
+		feeCap = bumped
+	}
+	tip, feeCap = policy.capped(tip, feeCap)
+	if tip.Cmp(raise(tx.GasTipCap(), minBumpPercent)) < 0 || feeCap.Cmp(raise(tx.GasFeeCap(), minBumpPercent)) < 0 {
+		return nil, nil
+	}
+
+	replacement, err := s.signer.SignTx(ctx, gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+		ChainID:   s.chainID,
+		Nonce:     tx.Nonce(),
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Data:      tx.Data(),
+	}), s.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign replacement: %w", err)
+	}
+	if err := s.backend.SendTransaction(ctx, replacement); err != nil {
+		return nil, fmt.Errorf("failed to send replacement of %s: %w", tx.Hash(), err)
+	}
+	return replacement, nil
+}
@@ -0,0 +1,78 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeePolicy(t *testing.T) {
+	policy := NewFeePolicy(&cfgtypes.Config{MaxFeeGwei: 15, MaxTipGwei: 0.5, StuckAfter: time.Minute})
+	require.Equal(t, big.NewInt(15*params.GWei), policy.MaxFeePerGas)
+	require.Equal(t, big.NewInt(params.GWei/2), policy.MaxTipPerGas)
+	require.Equal(t, uint64(minBumpPercent), policy.withDefaults().BumpPercent)
+	require.Nil(t, NewFeePolicy(&cfgtypes.Config{}).MaxFeePerGas)
+
+	// the node suggests a 1 gwei tip over a 10 gwei base fee
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	backend := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100, receipts: map[common.Hash]*gethtypes.Receipt{}}
+	s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), common.HexToAddress("0x01"), 0, 1)
+	require.NoError(t, err)
+	tip, feeCap, err := s.fees(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, gweiToWei(1), tip)
+	require.Equal(t, gweiToWei(21), feeCap)
+
+	s.SetFeePolicy(policy)
+	tip, feeCap, err = s.fees(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, gweiToWei(0.5), tip)
+	require.Equal(t, gweiToWei(15), feeCap)
+}
+
+func TestFeeBump(t *testing.T) {
+	submit := func(policy FeePolicy) (*chainBackend, *gethtypes.Receipt, error) {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		// the 21 gwei fee cap of the first submission is under what the chain includes
+		backend := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100,
+			minFeeCap: gweiToWei(25), receipts: map[common.Hash]*gethtypes.Receipt{}}
+		s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), common.HexToAddress("0x01"), 0, 1)
+		require.NoError(t, err)
+		s.pollInterval = time.Millisecond
+		s.SetFeePolicy(policy)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		receipt, err := s.Submit(ctx, []byte{1})
+		return backend, receipt, err
+	}
+
+	// the stuck submission is replaced with 20% higher fees
+	backend, receipt, err := submit(FeePolicy{StuckAfter: 10 * time.Millisecond, BumpPercent: 20})
+	require.NoError(t, err)
+	require.Len(t, backend.sent, 2)
+	require.Equal(t, backend.sent[0].Nonce(), backend.sent[1].Nonce())
+	require.Equal(t, gweiToWei(1.2), backend.sent[1].GasTipCap())
+	require.Equal(t, gweiToWei(25.2), backend.sent[1].GasFeeCap())
+	require.Equal(t, backend.sent[1].Hash(), receipt.TxHash)
+
+	// not beyond the fee cap
+	backend, _, err = submit(FeePolicy{MaxFeePerGas: gweiToWei(22), StuckAfter: 10 * time.Millisecond, BumpPercent: 20})
+	require.True(t, errors.Is(err, ErrSubmissionUnconfirmed), err)
+	require.Len(t, backend.sent, 1)
+
+	// nor without replacements
+	backend, _, err = submit(FeePolicy{})
+	require.True(t, errors.Is(err, ErrSubmissionUnconfirmed), err)
+	require.Len(t, backend.sent, 1)
+}
@@ -1,38 +1,87 @@
 package relayer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
 	"github.com/kysee/zk-chains/types"
 )
 
-// FileFetcher implements Fetcher by reading from a local JSON file
+// FileFetcher implements Fetcher by reading the JSON files of a local directory: the light client
+// update of each period in updates/{period}.json, and the beacon blocks, as served by
+// /eth/v2/beacon/blocks, in blocks/{slot}.json. A missing file fails with an error wrapping
+// os.ErrNotExist, which the relayer retries like an update not published yet.
 type FileFetcher struct {
-	FilePath string
+	Dir string
 }
 
-// NewFileFetcher creates a new FileFetcher with the given file path
-func NewFileFetcher(filePath string) *FileFetcher {
+// NewFileFetcher creates a new FileFetcher reading from dir
+func NewFileFetcher(dir string) *FileFetcher {
 	return &FileFetcher{
-		FilePath: filePath,
+		Dir: dir,
 	}
 }
 
-// FetchUpdate reads and parses the light client update from the file
-func (f *FileFetcher) FetchUpdate(period uint64) (*types.LightClientUpdate, error) {
-	// Read the file
-	data, err := os.ReadFile(f.FilePath)
+// localFetcher returns the fetcher of config.BeaconReplayDir, or else of config.UpdatesDir, and nil if
+// the relayer fetches from the beacon endpoints
+func localFetcher(config *cfgtypes.Config) cfgtypes.Fetcher {
+	switch {
+	case config.BeaconReplayDir != "":
+		return NewReplayFetcher(config.BeaconReplayDir)
+	case config.UpdatesDir != "":
+		return NewFileFetcher(config.UpdatesDir)
+	}
+	return nil
+}
+
+// readJSON reads and parses the file at path into out
+func readJSON(path string, out any) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", f.FilePath, err)
+		return fmt.Errorf("failed to read file %s: %w", path, err)
 	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
 
-	// Parse JSON
+// ScUpdate reads and parses the light client update of period
+func (f *FileFetcher) ScUpdate(_ context.Context, period uint64) (*types.LightClientUpdate, error) {
 	var update types.LightClientUpdate
-	if err := json.Unmarshal(data, &update); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	if err := readJSON(filepath.Join(f.Dir, "updates", strconv.FormatUint(period, 10)+".json"), &update); err != nil {
+		return nil, err
 	}
-
 	return &update, nil
 }
+
+// ScUpdates reads the updates of count periods from startPeriod on, up to the first one missing. Like
+// a beacon node, it fails if there is none.
+func (f *FileFetcher) ScUpdates(ctx context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
+	var updates []*types.LightClientUpdate
+	for period := startPeriod; period < startPeriod+uint64(count); period++ {
+		update, err := f.ScUpdate(ctx, period)
+		if err != nil {
+			if len(updates) == 0 {
+				return nil, err
+			}
+			break
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// Block reads and parses the beacon block of slot
+func (f *FileFetcher) Block(_ context.Context, slot uint64) (*cfgtypes.BlockAPIResponse, error) {
+	var block cfgtypes.BlockAPIResponse
+	if err := readJSON(filepath.Join(f.Dir, "blocks", strconv.FormatUint(slot, 10)+".json"), &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
@@ -0,0 +1,62 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFetcher(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "updates"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "blocks"), 0755))
+	for _, period := range []string{"1104", "1105"} {
+		data, err := os.ReadFile(filepath.Join("..", "data", "sc-update-"+period+".json"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "updates", period+".json"), data, 0644))
+	}
+	block, err := json.Marshal(&cfgtypes.BlockAPIResponse{Version: "electra", Finalized: true})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "blocks", "9052234.json"), block, 0644))
+
+	fetcher, err := NewBeaconFetcher(cfgtypes.NewConfig("--updates-dir", dir, "--log-level", "disabled"))
+	require.NoError(t, err)
+	files := fetcher.(*FileFetcher)
+	ctx := context.Background()
+
+	update, err := files.ScUpdate(ctx, 1105)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9052234), uint64(update.Data.AttestedHeader.Beacon.Slot))
+	updates, err := files.ScUpdates(ctx, 1104, 3)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	got, err := files.Block(ctx, 9052234)
+	require.NoError(t, err)
+	require.Equal(t, "electra", got.Version)
+	require.True(t, got.Finalized)
+
+	// the periods and slots without a file are not available yet
+	_, err = files.ScUpdate(ctx, 1106)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = files.ScUpdates(ctx, 1106, 2)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = files.Block(ctx, 9052235)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// the relayer assigns the updates it reads
+	r := &Relayer{config: cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled"), fetcher: files}
+	signers, err := r.parseCommittee(&updates[0].Data.NextSyncCommittee)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	prepared := make(chan *preparedPeriod, pipelineDepth)
+	go r.prepare(ctx, 1105, signers, prepared)
+	job := <-prepared
+	require.NoError(t, job.err)
+	require.NotNil(t, job.fullWitness)
+}
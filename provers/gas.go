@@ -0,0 +1,196 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/kysee/zk-chains/circuits"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+const (
+	// GasActionAlert logs the over-budget proof and leaves it pending for submission
+	GasActionAlert = "alert"
+	// GasActionSkip sets the over-budget proof aside (proof-period-N.json.over-budget) so it is not submitted
+	GasActionSkip = "skip"
+
+	// overBudgetSuffix marks a proof whose simulated submission exceeded the gas budget
+	overBudgetSuffix = ".over-budget"
+	// gasEstimateTimeout bounds the eth_estimateGas call of one submission
+	gasEstimateTimeout = 30 * time.Second
+)
+
+// ErrGasOverBudget is returned when a simulated submission needs more gas than the configured limit
+var ErrGasOverBudget = errors.New("submission gas over budget")
+
+// ErrPlainGroth16Proof is returned when submitting a Groth16 proof without commitments (a circuit set up
+// with -no-commitments), which Eth2LightClient has no entry point for: such proofs are export only
+var ErrPlainGroth16Proof = errors.New("groth16 proof without commitments cannot be submitted to the light client")
+
+// lightClientABI is the submission interface of verifiers/eth2/contracts/Eth2LightClient.sol
+const lightClientABI = `[
+	{"type":"function","name":"updateSyncCommittee","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"proof","type":"uint256[8]"},
+		{"name":"commitments","type":"uint256[2]"},
+		{"name":"commitmentPok","type":"uint256[2]"},
+		{"name":"slot","type":"uint256"},
+		{"name":"nextSc","type":"bytes"},
+		{"name":"executionBlockHash","type":"bytes32"},
+		{"name":"executionBlockNumber","type":"uint256"}]},
+	{"type":"function","name":"updateSyncCommitteePlonk","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"proof","type":"bytes"},
+		{"name":"slot","type":"uint256"},
+		{"name":"nextSc","type":"bytes"},
+		{"name":"executionBlockHash","type":"bytes32"},
+		{"name":"executionBlockNumber","type":"uint256"}]},
+	{"type":"function","name":"updateSyncCommitteeWithStateRoot","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"proof","type":"uint256[8]"},
+		{"name":"commitments","type":"uint256[2]"},
+		{"name":"commitmentPok","type":"uint256[2]"},
+		{"name":"slot","type":"uint256"},
+		{"name":"nextSc","type":"bytes"},
+		{"name":"executionBlockHash","type":"bytes32"},
+		{"name":"executionBlockNumber","type":"uint256"},
+		{"name":"stateRoot","type":"bytes32"}]},
+	{"type":"function","name":"updateSyncCommitteePlonkWithStateRoot","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"proof","type":"bytes"},
+		{"name":"slot","type":"uint256"},
+		{"name":"nextSc","type":"bytes"},
+		{"name":"executionBlockHash","type":"bytes32"},
+		{"name":"executionBlockNumber","type":"uint256"},
+		{"name":"stateRoot","type":"bytes32"}]}
+]`
+
+var parsedLightClientABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(lightClientABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// GasEstimator simulates a transaction, *ethclient.Client implements it
+type GasEstimator interface {
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+}
+
+// EncodeSubmission encodes the light client call submitting proofData (as returned by
+// types.CreateProofDataFor) for the update's attested slot, next sync committee and execution block hash and number.
+// withStateRoot selects the *WithStateRoot calls, which also take the attested state root, for the proofs
+// of a circuit compiled with -public-state-root (see Config.PublicStateRoot).
+func EncodeSubmission(proofData any, update *types.LightClientUpdate, withStateRoot bool) ([]byte, error) {
+	slot, nextSc, execBlockHash, execBlockNumber, err := submissionInputs(update)
+	if err != nil {
+		return nil, err
+	}
+	stateRoot := [32]byte(update.Data.AttestedHeader.Beacon.StateRoot)
+
+	switch data := proofData.(type) {
+	case *types.ProofData:
+		if len(data.Proof) == 8 && len(data.Commitments) == 0 && len(data.CommitmentPok) == 0 {
+			return nil, ErrPlainGroth16Proof
+		}
+		if len(data.Proof) != 8 || len(data.Commitments) != 2 || len(data.CommitmentPok) != 2 {
+			return nil, fmt.Errorf("malformed groth16 proof data")
+		}
+		var proof [8]*big.Int
+		var commitments, commitmentPok [2]*big.Int
+		for i := range proof {
+			proof[i] = new(big.Int).SetBytes(data.Proof[i])
+		}
+		for i := range commitments {
+			commitments[i] = new(big.Int).SetBytes(data.Commitments[i])
+			commitmentPok[i] = new(big.Int).SetBytes(data.CommitmentPok[i])
+		}
+		if withStateRoot {
+			return parsedLightClientABI.Pack("updateSyncCommitteeWithStateRoot", proof, commitments, commitmentPok, slot, nextSc[:], execBlockHash, execBlockNumber, stateRoot)
+		}
+		return parsedLightClientABI.Pack("updateSyncCommittee", proof, commitments, commitmentPok, slot, nextSc[:], execBlockHash, execBlockNumber)
+	case *types.PlonkProofData:
+		if withStateRoot {
+			return parsedLightClientABI.Pack("updateSyncCommitteePlonkWithStateRoot", []byte(data.Proof), slot, nextSc[:], execBlockHash, execBlockNumber, stateRoot)
+		}
+		return parsedLightClientABI.Pack("updateSyncCommitteePlonk", []byte(data.Proof), slot, nextSc[:], execBlockHash, execBlockNumber)
+	default:
+		return nil, fmt.Errorf("unsupported proof data %T", proofData)
+	}
+}
+
+// submissionInputs returns the inputs of the light client call submitting update, next to its proof
+func submissionInputs(update *types.LightClientUpdate) (slot *big.Int, nextSc []byte, execBlockHash [32]byte, execBlockNumber *big.Int, err error) {
+	serialized, err := circuit.SerializeSyncCommittee(&update.Data.NextSyncCommittee)
+	if err != nil {
+		return nil, nil, execBlockHash, nil, err
+	}
+	slot = new(big.Int).SetUint64(uint64(update.Data.AttestedHeader.Beacon.Slot))
+	if err := (*zrntcommon.Root)(&execBlockHash).UnmarshalText([]byte(update.Data.AttestedHeader.Execution.BlockHash)); err != nil {
+		return nil, nil, execBlockHash, nil, fmt.Errorf("invalid execution block hash: %w", err)
+	}
+	execBlockNumber, ok := new(big.Int).SetString(update.Data.AttestedHeader.Execution.BlockNumber, 10)
+	if !ok {
+		return nil, nil, execBlockHash, nil, fmt.Errorf("invalid execution block number %q", update.Data.AttestedHeader.Execution.BlockNumber)
+	}
+	return slot, serialized[:], execBlockHash, execBlockNumber, nil
+}
+
+// EstimateSubmissionGas simulates the submission of calldata to the light client and checks it
+// against limit (0 means no limit). It returns the estimate, and ErrGasOverBudget if it exceeds the limit.
+func EstimateSubmissionGas(ctx context.Context, estimator GasEstimator, from, lightClient common.Address, calldata []byte, limit uint64) (uint64, error) {
+	gas, err := estimator.EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &lightClient,
+		Data: calldata,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("gas estimation failed: %w", err)
+	}
+	if limit != 0 && gas > limit {
+		return gas, fmt.Errorf("%w: %d > %d", ErrGasOverBudget, gas, limit)
+	}
+	return gas, nil
+}
+
+// checkSubmissionGas simulates the submission of the proof saved at proofPath, if a destination is
+// configured. An over-budget proof is logged and, with GasActionSkip, renamed so it is not submitted.
+// Failed simulations (e.g. the previous period is not on-chain yet) are only logged.
+func (r *Relayer) checkSubmissionGas(update *types.LightClientUpdate, proofData any, proofPath string) error {
+	r.targetsMu.RLock()
+	estimator, from, to := r.gasEstimator, r.config.SubmitterAddress, r.config.LightClientAddress
+	limit, action := r.config.GasLimit, r.config.GasBudgetAction
+	r.targetsMu.RUnlock()
+	if estimator == nil {
+		return nil
+	}
+	calldata, err := EncodeSubmission(proofData, update, r.config.PublicStateRoot)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gasEstimateTimeout)
+	defer cancel()
+	gas, err := EstimateSubmissionGas(ctx, estimator, common.HexToAddress(from), common.HexToAddress(to), calldata, limit)
+	switch {
+	case errors.Is(err, ErrGasOverBudget):
+		r.log().Warnf("ALERT: %s (%d bytes calldata): %v\n", proofPath, len(calldata), err)
+		if action == GasActionSkip {
+			if err := os.Rename(proofPath, proofPath+overBudgetSuffix); err != nil {
+				return fmt.Errorf("failed to set over-budget proof aside: %w", err)
+			}
+			r.log().Infof("Proof moved to %s, it will not be submitted\n", proofPath+overBudgetSuffix)
+		}
+	case err != nil:
+		r.log().Warnf("could not simulate the submission of %s: %v\n", proofPath, err)
+	default:
+		r.log().Infof("✓ Submission simulated: %d gas (%d bytes calldata)\n", gas, len(calldata))
+	}
+	return nil
+}
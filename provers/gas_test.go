@@ -0,0 +1,145 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedGasEstimator returns gas for every call and records the last one
+type fixedGasEstimator struct {
+	gas  uint64
+	last ethereum.CallMsg
+}
+
+func (e *fixedGasEstimator) EstimateGas(_ context.Context, call ethereum.CallMsg) (uint64, error) {
+	e.last = call
+	return e.gas, nil
+}
+
+func loadTestSubmission(t *testing.T) (*types.LightClientUpdate, *types.ProofData) {
+	data, err := os.ReadFile(filepath.Join("..", "data", "sc-update-1105.json"))
+	require.NoError(t, err)
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(data, &update))
+
+	data, err = os.ReadFile(filepath.Join("..", "data", "proof-data.json"))
+	require.NoError(t, err)
+	var proofData types.ProofData
+	require.NoError(t, json.Unmarshal(data, &proofData))
+	return &update, &proofData
+}
+
+func TestEncodeSubmission(t *testing.T) {
+	update, proofData := loadTestSubmission(t)
+
+	calldata, err := EncodeSubmission(proofData, update, false)
+	require.NoError(t, err)
+	method, err := parsedLightClientABI.MethodById(calldata[:4])
+	require.NoError(t, err)
+	require.Equal(t, "updateSyncCommittee", method.Name)
+	args, err := method.Inputs.Unpack(calldata[4:])
+	require.NoError(t, err)
+	require.Len(t, args, 7)
+	require.Len(t, args[4].([]byte), 513*48)
+	execBlockHash := args[5].([32]byte)
+	require.Equal(t, update.Data.AttestedHeader.Execution.BlockHash, hexutil.Encode(execBlockHash[:]))
+	require.Equal(t, update.Data.AttestedHeader.Execution.BlockNumber, args[6].(*big.Int).String())
+
+	calldata, err = EncodeSubmission(&types.PlonkProofData{Backend: types.BackendPlonk, Proof: []byte{1, 2, 3}}, update, false)
+	require.NoError(t, err)
+	method, err = parsedLightClientABI.MethodById(calldata[:4])
+	require.NoError(t, err)
+	require.Equal(t, "updateSyncCommitteePlonk", method.Name)
+
+	_, err = EncodeSubmission(&types.ProofData{}, update, false)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrPlainGroth16Proof)
+
+	// a proof without commitments is export only
+	plain := &types.ProofData{Proof: proofData.Proof, Commitments: []types.HexBytes{}, CommitmentPok: []types.HexBytes{}}
+	_, err = EncodeSubmission(plain, update, false)
+	require.ErrorIs(t, err, ErrPlainGroth16Proof)
+}
+
+func TestEncodeSubmission_WithStateRoot(t *testing.T) {
+	update, proofData := loadTestSubmission(t)
+	stateRoot := [32]byte(update.Data.AttestedHeader.Beacon.StateRoot)
+
+	// the 131 inputs of the update and the 32 bytes of the attested state root: 163 public inputs
+	calldata, err := EncodeSubmission(proofData, update, true)
+	require.NoError(t, err)
+	method, err := parsedLightClientABI.MethodById(calldata[:4])
+	require.NoError(t, err)
+	require.Equal(t, "updateSyncCommitteeWithStateRoot", method.Name)
+	args, err := method.Inputs.Unpack(calldata[4:])
+	require.NoError(t, err)
+	require.Len(t, args, 8)
+	require.Equal(t, update.Data.AttestedHeader.Execution.BlockNumber, args[6].(*big.Int).String())
+	require.Equal(t, stateRoot, args[7].([32]byte))
+
+	calldata, err = EncodeSubmission(&types.PlonkProofData{Backend: types.BackendPlonk, Proof: []byte{1, 2, 3}}, update, true)
+	require.NoError(t, err)
+	method, err = parsedLightClientABI.MethodById(calldata[:4])
+	require.NoError(t, err)
+	require.Equal(t, "updateSyncCommitteePlonkWithStateRoot", method.Name)
+	args, err = method.Inputs.Unpack(calldata[4:])
+	require.NoError(t, err)
+	require.Equal(t, stateRoot, args[5].([32]byte))
+
+	// the gas of a state root proof is simulated with the state root call
+	estimator := &fixedGasEstimator{gas: 900_000}
+	r := &Relayer{
+		config:       &cfgtypes.Config{LightClientAddress: "0x09E38B218b3C2e8F4AAB7c9e9a610BC6972f630D", PublicStateRoot: true},
+		gasEstimator: estimator,
+	}
+	require.NoError(t, r.checkSubmissionGas(update, proofData, filepath.Join(t.TempDir(), proofFileName(1105))))
+	method, err = parsedLightClientABI.MethodById(estimator.last.Data[:4])
+	require.NoError(t, err)
+	require.Equal(t, "updateSyncCommitteeWithStateRoot", method.Name)
+}
+
+func TestCheckSubmissionGas(t *testing.T) {
+	update, proofData := loadTestSubmission(t)
+	proofPath := filepath.Join(t.TempDir(), proofFileName(1105))
+	require.NoError(t, os.WriteFile(proofPath, []byte("{}"), 0644))
+
+	estimator := &fixedGasEstimator{gas: 900_000}
+	r := &Relayer{
+		config: &cfgtypes.Config{
+			LightClientAddress: "0x09E38B218b3C2e8F4AAB7c9e9a610BC6972f630D",
+			GasLimit:           1_000_000,
+			GasBudgetAction:    GasActionSkip,
+		},
+		gasEstimator: estimator,
+	}
+
+	// within budget: the proof stays pending
+	require.NoError(t, r.checkSubmissionGas(update, proofData, proofPath))
+	require.FileExists(t, proofPath)
+	require.Equal(t, "0x09E38B218b3C2e8F4AAB7c9e9a610BC6972f630D", estimator.last.To.Hex())
+
+	// over budget with the alert action: the proof stays pending
+	estimator.gas = 1_000_001
+	r.config.GasBudgetAction = GasActionAlert
+	require.NoError(t, r.checkSubmissionGas(update, proofData, proofPath))
+	require.FileExists(t, proofPath)
+
+	// over budget with the skip action: the proof is set aside
+	r.config.GasBudgetAction = GasActionSkip
+	require.NoError(t, r.checkSubmissionGas(update, proofData, proofPath))
+	require.NoFileExists(t, proofPath)
+	require.FileExists(t, proofPath+overBudgetSuffix)
+
+	_, err := EstimateSubmissionGas(context.Background(), estimator, [20]byte{}, [20]byte{}, nil, 1_000_000)
+	require.ErrorIs(t, err, ErrGasOverBudget)
+}
@@ -0,0 +1,77 @@
+package relayer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// healthCheckTimeout bounds the beacon node lookup of a health check
+const healthCheckTimeout = 5 * time.Second
+
+// HealthReport is the state of a relayer served by /healthz and /readyz
+type HealthReport struct {
+	// Live is false when the relayer is stalled: it has not proven for Config.MaxProofAge
+	Live bool `json:"live"`
+	// Ready is true once the circuits are loaded and while the beacon node is reachable
+	Ready           bool   `json:"ready"`
+	BeaconConnected bool   `json:"beaconConnected"`
+	BeaconError     string `json:"beaconError,omitempty"`
+	ArtifactsLoaded bool   `json:"artifactsLoaded"`
+	// LastProofTime is when the proof of LastProvedPeriod was saved, nil if none was yet
+	LastProofTime    *time.Time `json:"lastProofTime,omitempty"`
+	LastProvedPeriod uint64     `json:"lastProvedPeriod,omitempty"`
+	// SubmissionBacklog is the number of saved proofs not yet submitted, 0 when nothing is submitted
+	SubmissionBacklog int `json:"submissionBacklog"`
+	// Error is why the proof files could not be read
+	Error string `json:"error,omitempty"`
+}
+
+// HealthChecker reports the health of a relayer, *Relayer implements it
+type HealthChecker interface {
+	Health(ctx context.Context) *HealthReport
+}
+
+// Health checks the beacon node and reports the state of the relayer from its proof files
+func (r *Relayer) Health(ctx context.Context) *HealthReport {
+	report := &HealthReport{Live: true, ArtifactsLoaded: r.loaded.Load()}
+
+	// fetchers without a head lookup are not probed
+	report.BeaconConnected = true
+	if head, ok := r.fetcher.(interface {
+		HeadSlot(ctx context.Context) (uint64, error)
+	}); ok {
+		ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+		if _, err := head.HeadSlot(ctx); err != nil {
+			report.BeaconConnected, report.BeaconError = false, err.Error()
+		}
+	}
+	report.Ready = report.BeaconConnected && report.ArtifactsLoaded
+
+	status, err := ReadRelayerStatus(r.config)
+	if err != nil {
+		report.Live, report.Error = false, err.Error()
+		return report
+	}
+	if len(r.destinationList()) != 0 {
+		report.SubmissionBacklog = len(status.PendingSubmissions)
+	}
+	if period, ok := status.LastProvedPeriod(); ok {
+		report.LastProvedPeriod = period
+		if info, err := os.Stat(filepath.Join(r.config.ProofDir, proofFileName(period))); err == nil {
+			modTime := info.ModTime()
+			report.LastProofTime = &modTime
+		}
+	}
+	// a relayer that has not proven anything yet is measured from its start
+	lastProgress := r.started
+	if report.LastProofTime != nil && report.LastProofTime.After(lastProgress) {
+		lastProgress = *report.LastProofTime
+	}
+	if r.config.MaxProofAge > 0 && time.Since(lastProgress) > r.config.MaxProofAge {
+		report.Live = false
+	}
+	return report
+}
@@ -0,0 +1,70 @@
+package relayer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	config := &cfgtypes.Config{ProofDir: t.TempDir(), QuarantineDir: t.TempDir(), MaxProofAge: time.Hour}
+	fetcher := cfgtypes.NewFakeFetcher()
+	fetcher.SetHeadSlot(9_060_000)
+	r := &Relayer{config: config, fetcher: fetcher, started: time.Now()}
+	srv := httptest.NewServer(NewHTTPHandler(config, nil, r))
+	defer srv.Close()
+
+	get := func(path string) (int, *HealthReport) {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var report HealthReport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		return resp.StatusCode, &report
+	}
+
+	// alive but not ready until the circuits are loaded
+	code, report := get("/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, report.BeaconConnected)
+	require.Nil(t, report.LastProofTime)
+	code, _ = get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	r.loaded.Store(true)
+	code, _ = get("/readyz")
+	require.Equal(t, http.StatusOK, code)
+
+	// nor while the beacon node is unreachable
+	fetcher.Fail("HeadSlot", errors.New("connection refused"), 0)
+	code, report = get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "connection refused", report.BeaconError)
+	fetcher.Fail("HeadSlot", nil, 0)
+
+	// the backlog counts the proofs not yet submitted to the destinations
+	r.destinations = []*destination{{name: defaultDestination}}
+	for _, name := range []string{proofFileName(1105), proofFileName(1105) + submittedSuffix, proofFileName(1106)} {
+		require.NoError(t, os.WriteFile(filepath.Join(config.ProofDir, name), []byte("{}"), 0644))
+	}
+	code, report = get("/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, uint64(1106), report.LastProvedPeriod)
+	require.NotNil(t, report.LastProofTime)
+	require.Equal(t, 1, report.SubmissionBacklog)
+
+	// a relayer that has not proven for MaxProofAge is stalled
+	old := time.Now().Add(-2 * time.Hour)
+	r.started = old
+	require.NoError(t, os.Chtimes(filepath.Join(config.ProofDir, proofFileName(1106)), old, old))
+	code, report = get("/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, report.Live)
+}
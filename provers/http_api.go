@@ -0,0 +1,234 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+const (
+	// httpShutdownTimeout bounds the wait for the requests being served when the API stops
+	httpShutdownTimeout = 10 * time.Second
+	// maxProveRequestBytes bounds the body of a POST /prove
+	maxProveRequestBytes = 1 << 16
+)
+
+// ProveRequest is the body of POST /prove and POST /prove/tx
+type ProveRequest struct {
+	TxHash string `json:"txHash"`
+	// MaxGas is the gas budget the transaction is proven to stay within, 0 means no budget
+	MaxGas uint64 `json:"maxGas,omitempty"`
+}
+
+// httpAPI serves the proofs and status of a relayer, see NewHTTPHandler
+type httpAPI struct {
+	config *cfgtypes.Config
+	// listener packages the receipt proofs of POST /prove, nil if no execution RPC is configured
+	listener *Listener
+	// health reports the state of the relayer, nil if the API is served without one
+	health HealthChecker
+}
+
+// NewHTTPHandler returns the HTTP API of the relayer described by config, so that consumers need no
+// access to its files:
+//
+//	GET  /proofs/{period}  the proof file of period, as written to ProofDir
+//	GET  /status           the RelayerStatus, with the StateReport of health if it is a StateReporter
+//	POST /prove            the TxStatusBundle of the ProveRequest's transaction, built with listener (may be nil)
+//	POST /prove/tx         the TxProof of the ProveRequest's transaction, if health is a TxProver, see Relayer.ProveTx
+//	GET  /healthz          the HealthReport of health (may be nil), 503 if the relayer is stalled
+//	GET  /readyz           the HealthReport, 503 until the circuits are loaded or while the beacon node is unreachable
+func NewHTTPHandler(config *cfgtypes.Config, listener *Listener, health HealthChecker) http.Handler {
+	api := &httpAPI{config: config, listener: listener, health: health}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /proofs/{period}", api.proof)
+	mux.HandleFunc("GET /status", api.status)
+	mux.HandleFunc("POST /prove", api.prove)
+	mux.HandleFunc("POST /prove/tx", api.proveTx)
+	mux.HandleFunc("GET /healthz", api.healthz)
+	mux.HandleFunc("GET /readyz", api.readyz)
+	return mux
+}
+
+// ServeHTTPAPI serves NewHTTPHandler on config.HTTPAddr until ctx is cancelled
+func ServeHTTPAPI(ctx context.Context, config *cfgtypes.Config, listener *Listener, health HealthChecker) error {
+	lis, err := net.Listen("tcp", config.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", config.HTTPAddr, err)
+	}
+	srv := &http.Server{Handler: NewHTTPHandler(config, listener, health), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	config.Log().Infof("Serving the HTTP API on %s\n", lis.Addr())
+	if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// proof serves the proof file of a period, or the one set aside over the gas budget
+func (api *httpAPI) proof(w http.ResponseWriter, req *http.Request) {
+	period, err := strconv.ParseUint(req.PathValue("period"), 10, 64)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("invalid period %q", req.PathValue("period")))
+		return
+	}
+	path := filepath.Join(api.config.ProofDir, proofFileName(period))
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		blob, err = os.ReadFile(path + overBudgetSuffix)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		writeHTTPError(w, http.StatusNotFound, fmt.Errorf("no proof of period %d", period))
+		return
+	}
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(blob)
+}
+
+// status serves the status read from the relayer's files, with the state of the relayer when it reports one
+func (api *httpAPI) status(w http.ResponseWriter, _ *http.Request) {
+	status, err := ReadRelayerStatus(api.config)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if reporter, ok := api.health.(StateReporter); ok {
+		status.State = reporter.State()
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// prove serves the status bundle of a transaction
+func (api *httpAPI) prove(w http.ResponseWriter, req *http.Request) {
+	if api.listener == nil {
+		writeHTTPError(w, http.StatusNotImplemented, errors.New("no execution RPC configured"))
+		return
+	}
+	proveReq, txHash, ok := readProveRequest(w, req)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), receiptFetchTimeout)
+	defer cancel()
+	bundle, err := api.listener.TxStatusBundle(ctx, txHash, proveReq.MaxGas)
+	switch {
+	case errors.Is(err, ErrTxFailed), errors.Is(err, ErrTxOverGas):
+		writeHTTPError(w, http.StatusUnprocessableEntity, err)
+	case err != nil:
+		writeHTTPError(w, http.StatusBadGateway, err)
+	default:
+		writeJSON(w, http.StatusOK, bundle)
+	}
+}
+
+// proveTx serves the receipt proof of a transaction, once its block is attested. The request waits for
+// the attestation and the proof, up to txProofTimeout.
+func (api *httpAPI) proveTx(w http.ResponseWriter, req *http.Request) {
+	prover, ok := api.health.(TxProver)
+	if api.listener == nil || !ok {
+		writeHTTPError(w, http.StatusNotImplemented, errors.New("no execution RPC or relayer configured"))
+		return
+	}
+	proveReq, txHash, ok := readProveRequest(w, req)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), txProofTimeout)
+	defer cancel()
+	proof, err := prover.ProveTx(ctx, api.listener, txHash, proveReq.MaxGas)
+	switch {
+	case errors.Is(err, ErrTxFailed), errors.Is(err, ErrTxOverGas):
+		writeHTTPError(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, ErrCircuitNotLoaded):
+		writeHTTPError(w, http.StatusNotImplemented, err)
+	case errors.Is(err, ErrTxNotAttested):
+		writeHTTPError(w, http.StatusGatewayTimeout, err)
+	case err != nil:
+		writeHTTPError(w, http.StatusBadGateway, err)
+	default:
+		writeJSON(w, http.StatusOK, proof)
+	}
+}
+
+// readProveRequest decodes the ProveRequest of req and its transaction hash, writing a 400 response if
+// they are invalid
+func readProveRequest(w http.ResponseWriter, req *http.Request) (*ProveRequest, common.Hash, bool) {
+	var proveReq ProveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxProveRequestBytes)).Decode(&proveReq); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return nil, common.Hash{}, false
+	}
+	txHash, err := parseTxHash(proveReq.TxHash)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return nil, common.Hash{}, false
+	}
+	return &proveReq, txHash, true
+}
+
+// healthz serves the liveness of the relayer
+func (api *httpAPI) healthz(w http.ResponseWriter, req *http.Request) {
+	api.writeHealth(w, req, func(report *HealthReport) bool { return report.Live })
+}
+
+// readyz serves the readiness of the relayer
+func (api *httpAPI) readyz(w http.ResponseWriter, req *http.Request) {
+	api.writeHealth(w, req, func(report *HealthReport) bool { return report.Ready })
+}
+
+// writeHealth writes the health report, with a 503 status if ok does not hold
+func (api *httpAPI) writeHealth(w http.ResponseWriter, req *http.Request, ok func(*HealthReport) bool) {
+	if api.health == nil {
+		writeJSON(w, http.StatusOK, &HealthReport{Live: true, Ready: true})
+		return
+	}
+	report := api.health.Health(req.Context())
+	code := http.StatusOK
+	if !ok(report) {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+// parseTxHash parses a 0x-prefixed transaction hash
+func parseTxHash(s string) (common.Hash, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid transaction hash %q", s)
+	}
+	return common.BytesToHash(b), nil
+}
+
+// writeJSON writes v as the JSON response
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeHTTPError writes err as a JSON {"error": ...} response
+func writeHTTPError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
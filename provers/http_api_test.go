@@ -0,0 +1,81 @@
+package relayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPAPI(t *testing.T) {
+	config := &cfgtypes.Config{ProofDir: t.TempDir(), QuarantineDir: t.TempDir()}
+	proofBlob, err := os.ReadFile(filepath.Join("..", "data", "proof-data.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(config.ProofDir, proofFileName(1105)), proofBlob, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(config.ProofDir, proofFileName(1106)+overBudgetSuffix), proofBlob, 0644))
+	source := newBlockReceiptSource()
+	srv := httptest.NewServer(NewHTTPHandler(config, NewListener(config, nil, source), nil))
+	defer srv.Close()
+
+	get := func(path string) (int, []byte) {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+	prove := func(body string) (int, []byte) {
+		resp, err := http.Post(srv.URL+"/prove", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out json.RawMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+
+	// the proof files are served as written, the ones set aside over the gas budget included
+	code, body := get("/proofs/1105")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, string(proofBlob), string(body))
+	code, _ = get("/proofs/1106")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = get("/proofs/1107")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = get("/proofs/latest")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, body = get("/status")
+	require.Equal(t, http.StatusOK, code)
+	var status RelayerStatus
+	require.NoError(t, json.Unmarshal(body, &status))
+	require.Equal(t, []uint64{1105}, status.ProvedPeriods)
+	require.Equal(t, []uint64{1106}, status.OverBudget)
+
+	// receipt proofs are built on demand
+	code, body = prove(fmt.Sprintf(`{"txHash":"%s","maxGas":50000}`, source.receipts[1].TxHash))
+	require.Equal(t, http.StatusOK, code)
+	var bundle types.TxStatusBundle
+	require.NoError(t, json.Unmarshal(body, &bundle))
+	require.Equal(t, uint64(50_000), bundle.GasUsed)
+	code, _ = prove(fmt.Sprintf(`{"txHash":"%s"}`, source.receipts[2].TxHash))
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	code, _ = prove(`{"txHash":"0x01"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// without an execution RPC
+	noReceipts := httptest.NewServer(NewHTTPHandler(config, nil, nil))
+	defer noReceipts.Close()
+	resp, err := http.Post(noReceipts.URL+"/prove", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
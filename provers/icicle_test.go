@@ -0,0 +1,26 @@
+package relayer
+
+import (
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGPUConfig(t *testing.T) {
+	config := cfgtypes.NewConfig("--gpu", "true", "--root", t.TempDir())
+	require.True(t, config.GPU)
+	_, err := NewRelayer(config, nil)
+	if icicleEnabled {
+		require.NoError(t, err)
+	} else {
+		require.Error(t, err)
+	}
+
+	// only Groth16 on BN254 runs on the GPU
+	_, err = loadCircuit(&types.CircuitManifest{Name: "Eth2ScUpdateCircuit", Backend: types.BackendPlonk, Curve: "bn254"}, t.TempDir(), proverSettings{gpu: true})
+	require.ErrorContains(t, err, "GPU")
+	_, err = loadCircuit(&types.CircuitManifest{Name: "Eth2ScUpdateCircuit", Backend: types.BackendGroth16, Curve: "bls12_377"}, t.TempDir(), proverSettings{gpu: true})
+	require.ErrorContains(t, err, "GPU")
+}
@@ -0,0 +1,107 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// lightClientStateABI is the state of verifiers/eth2/contracts/Eth2LightClient.sol the relayer resumes from
+const lightClientStateABI = `[
+	{"type":"function","name":"lastPeriod","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"scPubkeysHashes","stateMutability":"view","inputs":[{"name":"","type":"uint256"}],"outputs":[{"name":"","type":"bytes32"}]}
+]`
+
+var parsedLightClientStateABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(lightClientStateABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// LightClientState is the state of a deployed light client: the period it accepts the update of next,
+// and the commitment to the sync committee signing that update
+type LightClientState struct {
+	Period        uint64
+	ScPubKeysHash [32]byte
+}
+
+// ReadLightClientState reads the state of the light client at address, *ethclient.Client is a caller
+func ReadLightClientState(ctx context.Context, caller ethereum.ContractCaller, address common.Address) (*LightClientState, error) {
+	var period *big.Int
+	if err := callLightClient(ctx, caller, address, &period, "lastPeriod"); err != nil {
+		return nil, err
+	}
+	if !period.IsUint64() {
+		return nil, fmt.Errorf("invalid last period %s", period)
+	}
+	state := &LightClientState{Period: period.Uint64()}
+	if err := callLightClient(ctx, caller, address, &state.ScPubKeysHash, "scPubkeysHashes", period); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// callLightClient calls the view method of the light client at address and unpacks its output into out
+func callLightClient(ctx context.Context, caller ethereum.ContractCaller, address common.Address, out any, method string, args ...any) error {
+	data, err := parsedLightClientStateABI.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call %s of %s: %w", method, address, err)
+	}
+	if err := parsedLightClientStateABI.UnpackIntoInterface(out, method, result); err != nil {
+		return fmt.Errorf("failed to decode %s of %s: %w", method, address, err)
+	}
+	return nil
+}
+
+// resumeFromChain reads the state of the light client of the config: the relayer resumes from the
+// period it accepts next, whatever InitPeriod and the local proofs say. It returns nil if no light
+// client is configured or Config.ResumeFromChain is off.
+func (r *Relayer) resumeFromChain(ctx context.Context) (*LightClientState, error) {
+	r.targetsMu.RLock()
+	lightClient, address := r.lightClient, common.HexToAddress(r.config.LightClientAddress)
+	r.targetsMu.RUnlock()
+	if lightClient == nil || !r.config.ResumeFromChain {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, gasEstimateTimeout)
+	defer cancel()
+	state, err := ReadLightClientState(ctx, lightClient, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state of the light client: %w", err)
+	}
+	if state.Period == 0 {
+		return nil, Permanent(fmt.Errorf("the light client %s accepts the update of period 0, which has no previous one", address))
+	}
+	r.log().Infof("Light client %s accepts the update of period %d next, with scPubKeysHash 0x%x\n", address, state.Period, state.ScPubKeysHash)
+
+	if state.Period-1 != r.config.InitPeriod {
+		r.log().Infof("Resuming from period %d of the light client instead of --init-period %d\n", state.Period-1, r.config.InitPeriod)
+	}
+	if status, err := ReadRelayerStatus(r.config); err == nil {
+		if last, ok := status.LastProvedPeriod(); ok && last+1 != state.Period {
+			r.log().Warnf("the local proofs end with period %d, the light client is at period %d: resuming from the light client\n", last, state.Period)
+		}
+	}
+	return state, nil
+}
+
+// checkChainCommittee checks that the committee the relayer starts with is the one the light client
+// expects, as another hash mode or network would not
+func (r *Relayer) checkChainCommittee(state *LightClientState) error {
+	if state == nil || bytes.Equal(r.scPubKeysHash, state.ScPubKeysHash[:]) {
+		return nil
+	}
+	return fmt.Errorf("the committee of period %d hashes to 0x%x, the light client expects 0x%x", state.Period, r.scPubKeysHash, state.ScPubKeysHash)
+}
@@ -0,0 +1,74 @@
+package relayer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+// lightClientCaller answers the view calls of a light client at period, with scPubKeysHash
+type lightClientCaller struct {
+	period        uint64
+	scPubKeysHash [32]byte
+}
+
+func (c *lightClientCaller) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	method, err := parsedLightClientStateABI.MethodById(call.Data)
+	if err != nil {
+		return nil, err
+	}
+	if method.Name == "lastPeriod" {
+		return method.Outputs.Pack(new(big.Int).SetUint64(c.period))
+	}
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	if args[0].(*big.Int).Uint64() != c.period {
+		return method.Outputs.Pack([32]byte{})
+	}
+	return method.Outputs.Pack(c.scPubKeysHash)
+}
+
+func TestResumeFromChain(t *testing.T) {
+	update, _ := loadTestSubmission(t)
+	address := common.HexToAddress("0x0000000000000000000000000000000000000042")
+
+	// the committee handed over by the update of period 1104
+	r := &Relayer{config: &cfgtypes.Config{}}
+	require.NoError(t, r.setCurrentCommittee(&update.Data.NextSyncCommittee))
+	caller := &lightClientCaller{period: 1105, scPubKeysHash: [32]byte(r.scPubKeysHash)}
+
+	state, err := ReadLightClientState(context.Background(), caller, address)
+	require.NoError(t, err)
+	require.Equal(t, &LightClientState{Period: 1105, ScPubKeysHash: caller.scPubKeysHash}, state)
+
+	// the relayer starts from the light client, not from InitPeriod
+	ctx, cancel := context.WithCancel(context.Background())
+	fetcher := &cancellingFetcher{update: update, cancel: cancel}
+	config := &cfgtypes.Config{InitPeriod: 1000, ResumeFromChain: true, LightClientAddress: address.Hex(), ProofDir: t.TempDir()}
+	r = &Relayer{config: config, fetcher: fetcher, lightClient: caller}
+	require.NoError(t, r.Run(ctx))
+	require.Equal(t, []uint64{1104, 1105}, fetcher.periods)
+
+	// a light client expecting another committee is not resumed
+	caller.scPubKeysHash[0] ^= 1
+	fetcher.periods = nil
+	require.ErrorContains(t, r.Run(context.Background()), "the light client expects")
+
+	// nor is one at period 0
+	caller.period = 0
+	_, err = r.resumeFromChain(context.Background())
+	require.ErrorContains(t, err, "period 0")
+
+	// unless resuming from the chain is off
+	config.ResumeFromChain = false
+	state, err = r.resumeFromChain(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, state)
+}
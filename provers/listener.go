@@ -2,23 +2,27 @@ package relayer
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
-	"log"
 
 	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
-	"github.com/protolambda/zrnt/eth2/configs"
 	"github.com/protolambda/ztyp/tree"
 )
 
-func ListenerMain(config *cfgtypes.Config) {
+func ListenerMain(ctx context.Context, config *cfgtypes.Config) {
 	// Create and run relayer
-	relayer := NewListener(config, NewAPIFetcher(config.RPCEndpoint))
+	fetcher, err := NewBeaconFetcher(config)
+	if err != nil {
+		fatalf(config.Log(), "failed to create beacon fetcher: %v", err)
+	}
+	relayer := NewListener(config, fetcher, nil)
 
-	_, err := relayer.GetTransaction(config.Slot, 0)
+	_, err = relayer.GetTransaction(ctx, config.Slot, 0)
 	if err != nil {
-		log.Fatalf("failed to get transaction: %w", err)
+		fatalf(config.Log(), "failed to get transaction: %v", err)
 	}
 
 }
@@ -26,20 +30,29 @@ func ListenerMain(config *cfgtypes.Config) {
 type Listener struct {
 	config  *cfgtypes.Config
 	fetcher cfgtypes.Fetcher
+	// receipts serves the execution blocks and receipts, nil if no execution RPC is configured
+	receipts ReceiptSource
 }
 
-// NewListener creates a new Listener with the given APIFetcher
-func NewListener(config *cfgtypes.Config, fetcher cfgtypes.Fetcher) *Listener {
+// NewListener creates a new Listener with the given APIFetcher and, for the receipt pipeline,
+// the given ReceiptSource (may be nil)
+func NewListener(config *cfgtypes.Config, fetcher cfgtypes.Fetcher, receipts ReceiptSource) *Listener {
 	return &Listener{
-		config:  config,
-		fetcher: fetcher,
+		config:   config,
+		fetcher:  fetcher,
+		receipts: receipts,
 	}
 }
 
+// log returns the logger of the listener's config
+func (listener *Listener) log() cfgtypes.Logger {
+	return listener.config.Log()
+}
+
 // GetTransaction retrieves a block by slot and prints the transaction at the given index
-func (listener *Listener) GetTransaction(slot uint64, txIdx int) ([]byte, error) {
+func (listener *Listener) GetTransaction(ctx context.Context, slot uint64, txIdx int) ([]byte, error) {
 	// Fetch block by slot
-	blockResponse, err := listener.fetcher.Block(slot)
+	blockResponse, err := listener.fetcher.Block(ctx, slot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch block at slot %d: %w", slot, err)
 	}
@@ -55,18 +68,18 @@ func (listener *Listener) GetTransaction(slot uint64, txIdx int) ([]byte, error)
 		return nil, fmt.Errorf("transaction index %d out of range (block has %d transactions)", txIdx, len(transactions))
 	}
 
-	spec := configs.Mainnet
+	spec := listener.config.Preset.Spec()
 	hFn := tree.GetHashFn()
 
 	// Get the tx and leaf at the specified index
 	tx := transactions[txIdx]
 	txLeaf := tx.HashTreeRoot(spec, hFn)
-	log.Printf("Transaction[%d] Leaf: %v", txIdx, txLeaf)
+	listener.log().Infof("Transaction[%d] Leaf: %v", txIdx, txLeaf)
 
 	// Get ExecutionPayloadHeader from the block
 	executionPayloadHeader := block.Body.ExecutionPayload.Header(spec)
 	executionPayloadHeaderRoot := executionPayloadHeader.HashTreeRoot(hFn)
-	log.Printf("ExecutionPayloadHeaderRoot: %v", executionPayloadHeaderRoot)
+	listener.log().Infof("ExecutionPayloadHeaderRoot: %v", executionPayloadHeaderRoot)
 
 	// Generate merkle proof (branch) for the transaction
 	branch, err := generateTransactionMerkleProof(transactions, txIdx, spec, hFn)
@@ -74,20 +87,20 @@ func (listener *Listener) GetTransaction(slot uint64, txIdx int) ([]byte, error)
 		return nil, fmt.Errorf("failed to generate merkle proof: %w", err)
 	}
 
-	log.Printf("Merkle proof (branch) for transaction[%d]:", txIdx)
+	listener.log().Infof("Merkle proof (branch) for transaction[%d]:", txIdx)
 	for i, sibling := range branch {
-		log.Printf("  Branch[%d]: %v", i, sibling)
+		listener.log().Debugf("  Branch[%d]: %v", i, sibling)
 	}
 
 	// Verify the proof using our implementation
 	// Note: SSZ List uses Mixin, so TransactionsRoot = hash(Merkleize(leaves), length)
-	verified := verifyTransactionMerkleProof(txLeaf, branch, txIdx, uint64(len(transactions)), executionPayloadHeader.TransactionsRoot, hFn)
-	log.Printf("Custom merkle proof verification: %v", verified)
+	verified := listener.verifyTransactionMerkleProof(txLeaf, branch, txIdx, uint64(len(transactions)), executionPayloadHeader.TransactionsRoot)
+	listener.log().Infof("Custom merkle proof verification: %v", verified)
 
 	// Double-check using zrnt's HashTreeRoot (the authoritative implementation)
 	calculatedTxRoot := transactions.HashTreeRoot(spec, hFn)
 	zrntVerified := bytes.Equal(calculatedTxRoot[:], executionPayloadHeader.TransactionsRoot[:])
-	log.Printf("zrnt HashTreeRoot verification: %v (calculated: %v, expected: %v)",
+	listener.log().Infof("zrnt HashTreeRoot verification: %v (calculated: %v, expected: %v)",
 		zrntVerified, calculatedTxRoot, executionPayloadHeader.TransactionsRoot)
 
 	if !zrntVerified {
@@ -175,32 +188,21 @@ func generateTransactionMerkleProof(transactions common.PayloadTransactions, txI
 
 // verifyTransactionMerkleProof verifies a merkle proof for SSZ List
 // SSZ Lists use: root = hash(Merkleize(leaves), length)
-func verifyTransactionMerkleProof(leaf common.Root, branch []common.Root, index int, length uint64, expectedRoot common.Root, hFn tree.HashFn) bool {
-	// Step 1: Compute the merkleized root from leaf and branch
-	merkleizedRoot := leaf
-	idx := uint64(index)
-
-	for _, sibling := range branch {
-		if idx%2 == 0 {
-			// Current node is left child
-			merkleizedRoot = hFn(merkleizedRoot, sibling)
-		} else {
-			// Current node is right child
-			merkleizedRoot = hFn(sibling, merkleizedRoot)
-		}
-		idx = idx / 2
-	}
-
-	log.Printf("Computed MerkleizedRoot: %v", merkleizedRoot)
-
-	// Step 2: Apply Mixin with length (SSZ List specific)
-	// Mixin(root, length) = hash(root, length_as_32bytes)
+func (listener *Listener) verifyTransactionMerkleProof(leaf common.Root, branch []common.Root, index int, length uint64, expectedRoot common.Root) bool {
+	// Mixin(root, length) = hash(root, length_as_32bytes), so the length chunk is
+	// the last sibling and the data tree hangs off gindex 2 of the list root.
 	var lengthRoot common.Root
 	binary.LittleEndian.PutUint64(lengthRoot[:], length)
 
-	finalRoot := hFn(merkleizedRoot, lengthRoot)
-	log.Printf("After Mixin with length %d: %v", length, finalRoot)
-	log.Printf("Expected TransactionsRoot: %v", expectedRoot)
+	fullBranch := append(append([]common.Root{}, branch...), lengthRoot)
+	gindex, err := types.ConcatGIndices(2, types.GIndex(uint64(1)<<uint(len(branch))|uint64(index)))
+	if err != nil {
+		listener.log().Warnf("invalid transaction index %d: %v", index, err)
+		return false
+	}
+
+	verified := types.VerifySSZBranch(expectedRoot, leaf, fullBranch, gindex)
+	listener.log().Infof("Transaction gindex %d (list length %d), expected TransactionsRoot: %v", gindex, length, expectedRoot)
 
-	return bytes.Equal(finalRoot[:], expectedRoot[:])
+	return verified
 }
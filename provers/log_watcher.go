@@ -0,0 +1,284 @@
+package relayer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+// LogSource serves the logs of the execution chain, *ethclient.Client implements it. Over HTTP, it
+// cannot subscribe and the watcher polls instead.
+type LogSource interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]gethtypes.Log, error)
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- gethtypes.Log) (ethereum.Subscription, error)
+}
+
+// ReceiptJob is the proof of a transaction that emitted a watched log, waiting for its block to be attested
+type ReceiptJob struct {
+	TxHash      common.Hash
+	BlockHash   common.Hash
+	BlockNumber uint64
+}
+
+// WatchMain proves the transactions emitting the logs of config.WatchContracts and config.WatchTopics
+// until ctx is cancelled
+func WatchMain(ctx context.Context, config *cfgtypes.Config) {
+	if config.ExecutionRPC == "" {
+		fatalf(config.Log(), "watch needs --exec-rpc")
+	}
+	client, err := ethclient.DialContext(ctx, config.ExecutionRPC)
+	if err != nil {
+		fatalf(config.Log(), "failed to connect to %s: %v", config.ExecutionRPC, err)
+	}
+	fetcher, err := NewBeaconFetcher(config)
+	if err != nil {
+		fatalf(config.Log(), "failed to create beacon fetcher: %v", err)
+	}
+	watcher, err := NewLogWatcher(config, NewListener(config, fetcher, client), client)
+	if err != nil {
+		fatalf(config.Log(), "failed to create log watcher: %v", err)
+	}
+	if err := watcher.Run(ctx); err != nil {
+		fatalf(config.Log(), "log watcher failed: %v", err)
+	}
+}
+
+// LogWatcher follows the logs of the configured contracts and topics on the execution chain, and writes
+// the status bundle (see Listener.TxStatusBundle) of each transaction emitting one once its block is
+// attested by the sync committee, so that the message it carries can be bridged.
+type LogWatcher struct {
+	config   *cfgtypes.Config
+	listener *Listener
+	logs     LogSource
+	attested cfgtypes.AttestationFetcher
+	query    ethereum.FilterQuery
+
+	// next is the first block whose logs were not read yet
+	next uint64
+	// jobs are the transactions waiting for their block to be attested, in block order
+	jobs []ReceiptJob
+	// seen are the transactions already queued, by the block they were seen in
+	seen map[common.Hash]uint64
+}
+
+// NewLogWatcher returns the watcher of the logs of config, read from logs and proven with listener,
+// whose fetcher must report the attested headers
+func NewLogWatcher(config *cfgtypes.Config, listener *Listener, logs LogSource) (*LogWatcher, error) {
+	attested, ok := listener.fetcher.(cfgtypes.AttestationFetcher)
+	if !ok {
+		return nil, errors.New("the beacon fetcher cannot report the attested headers")
+	}
+	var query ethereum.FilterQuery
+	for _, contract := range config.WatchContracts {
+		if !common.IsHexAddress(contract) {
+			return nil, fmt.Errorf("invalid contract address %q", contract)
+		}
+		query.Addresses = append(query.Addresses, common.HexToAddress(contract))
+	}
+	var topics []common.Hash
+	for _, topic := range config.WatchTopics {
+		hash, err := parseHash(topic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid topic %q: %w", topic, err)
+		}
+		topics = append(topics, hash)
+	}
+	if config.WatchInterval <= 0 {
+		return nil, fmt.Errorf("invalid watch interval %s", config.WatchInterval)
+	}
+	if len(query.Addresses) == 0 && len(topics) == 0 {
+		return nil, errors.New("no contract or topic to watch")
+	}
+	if len(topics) > 0 {
+		query.Topics = [][]common.Hash{topics}
+	}
+	return &LogWatcher{
+		config:   config,
+		listener: listener,
+		logs:     logs,
+		attested: attested,
+		query:    query,
+		next:     config.WatchFromBlock,
+		seen:     make(map[common.Hash]uint64),
+	}, nil
+}
+
+// parseHash parses a 0x-prefixed 32-byte hex string
+func parseHash(s string) (common.Hash, error) {
+	var hash common.Hash
+	if err := hash.UnmarshalText([]byte(s)); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+// Run watches the logs until ctx is cancelled. It reads the logs from Config.WatchFromBlock, or the
+// head, then follows them with a subscription, polling every Config.WatchInterval when the execution
+// RPC cannot stream them or the stream fails.
+func (w *LogWatcher) Run(ctx context.Context) error {
+	if w.next == 0 {
+		head, err := w.logs.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the head block: %w", err)
+		}
+		w.next = head
+	}
+	w.listener.log().Infof("Watching the logs from block %d\n", w.next)
+
+	ticker := time.NewTicker(w.config.WatchInterval)
+	defer ticker.Stop()
+	logs := make(chan gethtypes.Log, 256)
+	var sub ethereum.Subscription
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	for {
+		if sub == nil {
+			var err error
+			if sub, err = w.logs.SubscribeFilterLogs(ctx, w.query, logs); err != nil {
+				sub = nil
+			}
+			// the logs emitted before the subscription, or while there was none, are polled
+			if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+				w.listener.log().Warnf("failed to read the logs from block %d: %v\n", w.next, err)
+			}
+		}
+		w.dispatch(ctx)
+
+		var subErr <-chan error
+		if sub != nil {
+			subErr = sub.Err()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case log := <-logs:
+			// the logs of the blocks polled were queued already
+			if log.BlockNumber >= w.next || log.Removed {
+				w.enqueue(log)
+				w.next = max(w.next, log.BlockNumber)
+			}
+		case err := <-subErr:
+			w.listener.log().Warnf("log subscription failed, polling until it is back: %v\n", err)
+			sub.Unsubscribe()
+			sub = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the logs of the blocks from w.next to the head
+func (w *LogWatcher) poll(ctx context.Context) error {
+	head, err := w.logs.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the head block: %w", err)
+	}
+	if head < w.next {
+		return nil
+	}
+	query := w.query
+	query.FromBlock, query.ToBlock = new(big.Int).SetUint64(w.next), new(big.Int).SetUint64(head)
+	logs, err := w.logs.FilterLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+	for _, log := range logs {
+		w.enqueue(log)
+	}
+	w.next = head + 1
+	return nil
+}
+
+// enqueue queues the proof of the transaction of log, or drops it if log was removed by a reorg
+func (w *LogWatcher) enqueue(log gethtypes.Log) {
+	if log.Removed {
+		w.jobs = slices.DeleteFunc(w.jobs, func(job ReceiptJob) bool {
+			return job.TxHash == log.TxHash && job.BlockHash == log.BlockHash
+		})
+		delete(w.seen, log.TxHash)
+		return
+	}
+	if _, ok := w.seen[log.TxHash]; ok {
+		return
+	}
+	w.seen[log.TxHash] = log.BlockNumber
+	job := ReceiptJob{TxHash: log.TxHash, BlockHash: log.BlockHash, BlockNumber: log.BlockNumber}
+	i, _ := slices.BinarySearchFunc(w.jobs, job.BlockNumber+1, func(job ReceiptJob, number uint64) int {
+		return cmp.Compare(job.BlockNumber, number)
+	})
+	w.jobs = slices.Insert(w.jobs, i, job)
+	w.listener.log().Infof("Transaction %s of block %d queued\n", log.TxHash, log.BlockNumber)
+}
+
+// dispatch writes the bundles of the queued transactions whose block is attested. A transaction that
+// failed, or went over Config.MaxGas, is dropped; one whose bundle could not be built stays queued.
+func (w *LogWatcher) dispatch(ctx context.Context) {
+	if len(w.jobs) == 0 {
+		return
+	}
+	attested, err := w.attested.AttestedBlockNumber(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.listener.log().Warnf("failed to fetch the attested header: %v\n", err)
+		}
+		return
+	}
+	var pending []ReceiptJob
+	for _, job := range w.jobs {
+		if job.BlockNumber > attested || ctx.Err() != nil {
+			pending = append(pending, job)
+			continue
+		}
+		if err := w.prove(ctx, job); err != nil {
+			if errors.Is(err, ErrTxFailed) || errors.Is(err, ErrTxOverGas) || errors.Is(err, errReorged) {
+				w.listener.log().Warnf("transaction %s dropped: %v\n", job.TxHash, err)
+				continue
+			}
+			w.listener.log().Warnf("failed to prove transaction %s, retrying: %v\n", job.TxHash, err)
+			pending = append(pending, job)
+		}
+	}
+	w.jobs = pending
+
+	// the transactions of the blocks that will not be read again are forgotten
+	for txHash, number := range w.seen {
+		if number < w.next && !slices.ContainsFunc(w.jobs, func(job ReceiptJob) bool { return job.TxHash == txHash }) {
+			delete(w.seen, txHash)
+		}
+	}
+}
+
+// errReorged is returned for a job whose transaction is no longer in the block it was seen in
+var errReorged = errors.New("block reorganized")
+
+// prove writes the status bundle of the transaction of job to Config.ProofDir
+func (w *LogWatcher) prove(ctx context.Context, job ReceiptJob) error {
+	ctx, cancel := context.WithTimeout(ctx, receiptFetchTimeout)
+	defer cancel()
+	bundle, err := w.listener.TxStatusBundle(ctx, job.TxHash, w.config.MaxGas)
+	if err != nil {
+		return err
+	}
+	if common.BytesToHash(bundle.BlockHash) != job.BlockHash {
+		return fmt.Errorf("%w: %s is in block %x, not %s", errReorged, job.TxHash, bundle.BlockHash, job.BlockHash)
+	}
+	path, err := writeTxStatusBundle(w.config.ProofDir, bundle)
+	if err != nil {
+		return err
+	}
+	w.listener.log().Infof("✓ Transaction %s of block %d proven, bundle saved to %s\n", job.TxHash, job.BlockNumber, path)
+	return nil
+}
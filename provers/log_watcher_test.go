@@ -0,0 +1,93 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+// attestingFetcher reports block as the latest attested execution block
+type attestingFetcher struct {
+	cfgtypes.Fetcher
+	block uint64
+}
+
+func (f *attestingFetcher) AttestedBlockNumber(context.Context) (uint64, error) {
+	return f.block, nil
+}
+
+// polledLogSource serves logs over HTTP: it cannot subscribe
+type polledLogSource struct {
+	head uint64
+	logs []gethtypes.Log
+}
+
+func (s *polledLogSource) BlockNumber(context.Context) (uint64, error) {
+	return s.head, nil
+}
+
+func (s *polledLogSource) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]gethtypes.Log, error) {
+	var logs []gethtypes.Log
+	for _, log := range s.logs {
+		if log.BlockNumber >= q.FromBlock.Uint64() && log.BlockNumber <= q.ToBlock.Uint64() {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (s *polledLogSource) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- gethtypes.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("notifications not supported")
+}
+
+func TestLogWatcher(t *testing.T) {
+	receipts := newBlockReceiptSource()
+	number := receipts.header.Number.Uint64()
+	config := &cfgtypes.Config{ProofDir: t.TempDir(), WatchContracts: []string{"0x0000000000000000000000000000000000000001"}, WatchInterval: time.Second}
+	fetcher := &attestingFetcher{block: number - 1}
+	logSource := &polledLogSource{head: number}
+	for _, receipt := range receipts.receipts {
+		// two logs of the second transaction, one of the reverted third
+		logSource.logs = append(logSource.logs, gethtypes.Log{TxHash: receipt.TxHash, BlockHash: receipt.BlockHash, BlockNumber: number})
+	}
+	logSource.logs = append(logSource.logs[1:], logSource.logs[1])
+
+	w, err := NewLogWatcher(config, NewListener(config, fetcher, receipts), logSource)
+	require.NoError(t, err)
+	w.next = number - 10
+	ctx := context.Background()
+	require.NoError(t, w.poll(ctx))
+	require.Equal(t, number+1, w.next)
+	require.Len(t, w.jobs, 2)
+
+	// nothing is proven before the block is attested
+	w.dispatch(ctx)
+	require.Len(t, w.jobs, 2)
+
+	fetcher.block = number
+	w.dispatch(ctx)
+	require.Empty(t, w.jobs)
+	bundlePath := filepath.Join(config.ProofDir, "tx-status-"+common.Bytes2Hex(receipts.receipts[1].TxHash[:])+".json")
+	require.FileExists(t, bundlePath)
+	// the reverted transaction was dropped
+	entries, err := os.ReadDir(config.ProofDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// a log removed by a reorg drops its job
+	w.enqueue(gethtypes.Log{TxHash: common.Hash{1}, BlockHash: common.Hash{2}, BlockNumber: number + 1})
+	w.enqueue(gethtypes.Log{TxHash: common.Hash{1}, BlockHash: common.Hash{2}, BlockNumber: number + 1, Removed: true})
+	require.Empty(t, w.jobs)
+
+	_, err = NewLogWatcher(&cfgtypes.Config{WatchInterval: time.Second}, NewListener(config, fetcher, receipts), logSource)
+	require.ErrorContains(t, err, "no contract")
+}
@@ -0,0 +1,53 @@
+package relayer
+
+import (
+	"expvar"
+	"net/url"
+	"sync"
+
+	"github.com/kysee/zk-chains/types"
+)
+
+// decodeErrors counts the responses that could not be decoded, per endpoint (host of the data
+// provider) and per types.DecodeErrorKind. It is published as "fetch_decode_errors" on /debug/vars.
+var (
+	decodeErrors   = expvar.NewMap("fetch_decode_errors")
+	decodeErrorsMu sync.Mutex
+)
+
+// recordDecodeError counts err against the endpoint serving baseURL and returns its kind
+func recordDecodeError(baseURL string, err error) types.DecodeErrorKind {
+	kind := types.ClassifyDecodeError(err)
+	endpoint := baseURL
+	if u, perr := url.Parse(baseURL); perr == nil && u.Host != "" {
+		endpoint = u.Host
+	}
+
+	decodeErrorsMu.Lock()
+	counters, ok := decodeErrors.Get(endpoint).(*expvar.Map)
+	if !ok {
+		counters = new(expvar.Map)
+		decodeErrors.Set(endpoint, counters)
+	}
+	decodeErrorsMu.Unlock()
+	counters.Add(string(kind), 1)
+	return kind
+}
+
+// DecodeErrorCounts returns a snapshot of the decode error counters: endpoint -> kind -> count
+func DecodeErrorCounts() map[string]map[types.DecodeErrorKind]int64 {
+	out := make(map[string]map[types.DecodeErrorKind]int64)
+	decodeErrors.Do(func(kv expvar.KeyValue) {
+		counters, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
+		out[kv.Key] = make(map[types.DecodeErrorKind]int64)
+		counters.Do(func(c expvar.KeyValue) {
+			if v, ok := c.Value.(*expvar.Int); ok {
+				out[kv.Key][types.DecodeErrorKind(c.Key)] = v.Value()
+			}
+		})
+	})
+	return out
+}
@@ -0,0 +1,254 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// ErrBeaconQuorum is returned when the beacon endpoints of a quorum serve different updates
+var ErrBeaconQuorum = errors.New("beacon endpoints disagree")
+
+// NewBeaconFetcher returns the fetcher of the beacon endpoints of config: an APIFetcher for a single
+// endpoint, a MultiFetcher otherwise, recording to config.BeaconRecordDir if set. With
+// config.BeaconReplayDir or config.UpdatesDir, it reads local files instead, see localFetcher.
+func NewBeaconFetcher(config *cfgtypes.Config) (cfgtypes.Fetcher, error) {
+	if fetcher := localFetcher(config); fetcher != nil {
+		return fetcher, nil
+	}
+	endpoints := config.BeaconEndpoints()
+	if len(endpoints) == 1 && config.BeaconQuorum <= 1 {
+		return recordTo(config, NewAPIFetcherWithLogger(endpoints[0], config.Log()))
+	}
+	fetcher, err := NewMultiFetcher(endpoints, config.BeaconQuorum, config.Log())
+	if err != nil {
+		return nil, err
+	}
+	return recordTo(config, fetcher)
+}
+
+// MultiFetcher fetches from several beacon endpoints. A request failing on one endpoint is sent to the
+// next ones, the first endpoint answering serves the following requests. With a quorum above 1, the
+// updates are fetched from that many endpoints, which must agree on their attested header and next
+// sync committee: a single broken or malicious endpoint cannot have the relayer prove its updates.
+// The endpoints can be replaced while the fetcher is used, see SetEndpoints.
+type MultiFetcher struct {
+	endpoints atomic.Pointer[beaconEndpoints]
+	logger    cfgtypes.Logger
+}
+
+// beaconEndpoints are the endpoints of a MultiFetcher and their quorum
+type beaconEndpoints struct {
+	fetchers []*APIFetcher
+	quorum   int
+	// current is the index of the endpoint tried first
+	current atomic.Int32
+}
+
+// NewMultiFetcher returns the fetcher of endpoints, fetching the updates from quorum of them
+func NewMultiFetcher(endpoints []string, quorum int, logger cfgtypes.Logger) (*MultiFetcher, error) {
+	m := &MultiFetcher{logger: logger}
+	if err := m.SetEndpoints(endpoints, quorum); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetEndpoints replaces the endpoints of the fetcher and their quorum, the requests in flight finish
+// with the previous ones
+func (m *MultiFetcher) SetEndpoints(endpoints []string, quorum int) error {
+	e, err := newBeaconEndpoints(endpoints, quorum, m.logger)
+	if err != nil {
+		return err
+	}
+	m.endpoints.Store(e)
+	return nil
+}
+
+// newBeaconEndpoints returns the fetchers of endpoints, fetching the updates from quorum of them
+func newBeaconEndpoints(endpoints []string, quorum int, logger cfgtypes.Logger) (*beaconEndpoints, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no beacon endpoint")
+	}
+	quorum = max(quorum, 1)
+	if quorum > len(endpoints) {
+		return nil, fmt.Errorf("a quorum of %d beacon endpoints out of %d", quorum, len(endpoints))
+	}
+	e := &beaconEndpoints{quorum: quorum}
+	for _, endpoint := range endpoints {
+		e.fetchers = append(e.fetchers, NewAPIFetcherWithLogger(endpoint, logger))
+	}
+	return e, nil
+}
+
+// log returns the logger of the fetcher, NopLogger if there is none
+func (m *MultiFetcher) log() cfgtypes.Logger {
+	if m.logger == nil {
+		return cfgtypes.NopLogger
+	}
+	return m.logger
+}
+
+// try calls fetch with each endpoint in turn, from the current one, until one succeeds. The endpoint
+// which succeeded becomes the current one.
+func (m *MultiFetcher) try(ctx context.Context, fetch func(*APIFetcher) error) error {
+	e := m.endpoints.Load()
+	start := int(e.current.Load())
+	var errs []error
+	for i := range e.fetchers {
+		index := (start + i) % len(e.fetchers)
+		err := fetch(e.fetchers[index])
+		if err == nil {
+			if index != start {
+				e.current.Store(int32(index))
+				m.log().Warnf("switched to beacon endpoint %s\n", e.fetchers[index].BaseURL)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", e.fetchers[index].BaseURL, err))
+	}
+	return errors.Join(errs...)
+}
+
+// agreed fetches updates from a quorum of endpoints, from the current one, and returns the ones they
+// agree on: the updates up to the shortest answer, which must have the same roots in every answer
+func (m *MultiFetcher) agreed(ctx context.Context, fetch func(*APIFetcher) ([]*types.LightClientUpdate, error)) ([]*types.LightClientUpdate, error) {
+	e := m.endpoints.Load()
+	if e.quorum == 1 {
+		var updates []*types.LightClientUpdate
+		err := m.try(ctx, func(f *APIFetcher) error {
+			var err error
+			updates, err = fetch(f)
+			return err
+		})
+		return updates, err
+	}
+
+	start := int(e.current.Load())
+	var answers [][]*types.LightClientUpdate
+	var sources []string
+	var errs []error
+	for i := 0; i < len(e.fetchers) && len(answers) < e.quorum; i++ {
+		f := e.fetchers[(start+i)%len(e.fetchers)]
+		updates, err := fetch(f)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", f.BaseURL, err))
+			continue
+		}
+		answers, sources = append(answers, updates), append(sources, f.BaseURL)
+	}
+	if len(answers) < e.quorum {
+		return nil, fmt.Errorf("%d of the %d beacon endpoints of the quorum answered: %w", len(answers), e.quorum, errors.Join(errs...))
+	}
+
+	agreed := answers[0]
+	for _, answer := range answers[1:] {
+		agreed = agreed[:min(len(agreed), len(answer))]
+	}
+	for i, update := range agreed {
+		want := updateRoots(update)
+		for j, answer := range answers[1:] {
+			if got := updateRoots(answer[i]); got != want {
+				return nil, fmt.Errorf("%w: update of slot %d from %s has roots %x, the one from %s %x", ErrBeaconQuorum,
+					update.Data.AttestedHeader.Beacon.Slot, sources[0], want, sources[j+1], got)
+			}
+		}
+	}
+	return agreed, nil
+}
+
+// updateRoots returns the roots the endpoints of a quorum must agree on: the ones of the attested header
+// and of the next sync committee of update
+func updateRoots(update *types.LightClientUpdate) [2]zrntcommon.Root {
+	hFn := tree.GetHashFn()
+	return [2]zrntcommon.Root{
+		update.Data.AttestedHeader.Beacon.HashTreeRoot(hFn),
+		types.SyncCommitteeRoot(&update.Data.NextSyncCommittee),
+	}
+}
+
+func (m *MultiFetcher) ScUpdate(ctx context.Context, period uint64) (*types.LightClientUpdate, error) {
+	updates, err := m.agreed(ctx, func(f *APIFetcher) ([]*types.LightClientUpdate, error) {
+		update, err := f.ScUpdate(ctx, period)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.LightClientUpdate{update}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updates[0], nil
+}
+
+func (m *MultiFetcher) ScUpdates(ctx context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
+	return m.agreed(ctx, func(f *APIFetcher) ([]*types.LightClientUpdate, error) {
+		return f.ScUpdates(ctx, startPeriod, count)
+	})
+}
+
+func (m *MultiFetcher) HeadSlot(ctx context.Context) (uint64, error) {
+	var slot uint64
+	err := m.try(ctx, func(f *APIFetcher) error {
+		var err error
+		slot, err = f.HeadSlot(ctx)
+		return err
+	})
+	return slot, err
+}
+
+func (m *MultiFetcher) AttestedBlockNumber(ctx context.Context) (uint64, error) {
+	var number uint64
+	err := m.try(ctx, func(f *APIFetcher) error {
+		var err error
+		number, err = f.AttestedBlockNumber(ctx)
+		return err
+	})
+	return number, err
+}
+
+// Bootstrap is served by the first endpoint answering, the relayer verifies it against the trusted root
+func (m *MultiFetcher) Bootstrap(ctx context.Context, blockRoot zrntcommon.Root) (*types.LightClientBootstrap, error) {
+	var bootstrap *types.LightClientBootstrap
+	err := m.try(ctx, func(f *APIFetcher) error {
+		var err error
+		bootstrap, err = f.Bootstrap(ctx, blockRoot)
+		return err
+	})
+	return bootstrap, err
+}
+
+func (m *MultiFetcher) Block(ctx context.Context, slot uint64) (*cfgtypes.BlockAPIResponse, error) {
+	var block *cfgtypes.BlockAPIResponse
+	err := m.try(ctx, func(f *APIFetcher) error {
+		var err error
+		block, err = f.Block(ctx, slot)
+		return err
+	})
+	return block, err
+}
+
+// Events streams the events of the current endpoint. When the stream fails, the next endpoint becomes
+// the current one, for the next stream.
+func (m *MultiFetcher) Events(ctx context.Context, topics []string, handle func(cfgtypes.BeaconEvent)) error {
+	e := m.endpoints.Load()
+	current := int(e.current.Load())
+	f := e.fetchers[current]
+	err := f.Events(ctx, topics, handle)
+	if ctx.Err() == nil && len(e.fetchers) > 1 {
+		e.current.CompareAndSwap(int32(current), int32((current+1)%len(e.fetchers)))
+	}
+	return fmt.Errorf("%s: %w", f.BaseURL, err)
+}
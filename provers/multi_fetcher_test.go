@@ -0,0 +1,93 @@
+package relayer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// beaconServer serves update as the update of every period, it fails if down is set
+func beaconServer(t *testing.T, update string, down *atomic.Bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, "/eth/v1/beacon/light_client/updates", r.URL.Path)
+		_, _ = w.Write([]byte("[" + update + "]"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMultiFetcher(t *testing.T) {
+	blob, err := os.ReadFile(filepath.Join("..", "data", "sc-update-1105.json"))
+	require.NoError(t, err)
+	update := string(blob)
+	forged := strings.Replace(update, "0xe68a843807b4b3772f8966cca41212e0f21cd476e8480f6157cab7f506837099",
+		"0x0000000000000000000000000000000000000000000000000000000000000000", 1)
+	require.NotEqual(t, update, forged)
+
+	var down [3]atomic.Bool
+	first, second, third := beaconServer(t, update, &down[0]), beaconServer(t, update, &down[1]), beaconServer(t, forged, &down[2])
+	ctx := context.Background()
+
+	// a failing endpoint is replaced by the next one
+	fetcher, err := NewMultiFetcher([]string{first.URL, second.URL, third.URL}, 1, nil)
+	require.NoError(t, err)
+	down[0].Store(true)
+	got, err := fetcher.ScUpdate(ctx, 1105)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9052234), uint64(got.Data.AttestedHeader.Beacon.Slot))
+	require.Equal(t, int32(1), fetcher.endpoints.Load().current.Load())
+	down[0].Store(false)
+	down[1].Store(true)
+	_, err = fetcher.ScUpdate(ctx, 1105)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fetcher.endpoints.Load().current.Load())
+	down[0].Store(true)
+	down[2].Store(true)
+	_, err = fetcher.ScUpdate(ctx, 1105)
+	require.ErrorContains(t, err, first.URL)
+	require.ErrorContains(t, err, second.URL)
+	for i := range down {
+		down[i].Store(false)
+	}
+
+	// a quorum of endpoints serving the same update
+	fetcher, err = NewMultiFetcher([]string{first.URL, second.URL, third.URL}, 2, nil)
+	require.NoError(t, err)
+	_, err = fetcher.ScUpdate(ctx, 1105)
+	require.NoError(t, err)
+
+	// an endpoint of the quorum serving another one
+	down[1].Store(true)
+	_, err = fetcher.ScUpdate(ctx, 1105)
+	require.ErrorIs(t, err, ErrBeaconQuorum)
+	require.ErrorContains(t, err, third.URL)
+
+	// not enough endpoints for the quorum
+	down[2].Store(true)
+	_, err = fetcher.ScUpdate(ctx, 1105)
+	require.ErrorContains(t, err, "1 of the 2")
+
+	_, err = NewMultiFetcher([]string{first.URL}, 2, nil)
+	require.Error(t, err)
+}
+
+func TestUpdateRootsMinimalPreset(t *testing.T) {
+	// the endpoints of a minimal preset chain are compared on their 32-member committees
+	update := minimalUpdate(t, loadTestUpdates(t), 1105)
+	roots := updateRoots(update)
+	update.Data.NextSyncCommittee.Pubkeys[0][1] ^= 1
+	forged := updateRoots(update)
+	require.Equal(t, roots[0], forged[0])
+	require.NotEqual(t, roots[1], forged[1])
+}
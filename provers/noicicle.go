@@ -0,0 +1,7 @@
+//go:build !icicle
+
+package relayer
+
+// icicleEnabled reports whether the relayer was built with gnark's ICICLE backend (-tags icicle),
+// which runs the MSMs and FFTs of Groth16 BN254 proving on a CUDA GPU when Config.GPU is set
+const icicleEnabled = false
@@ -0,0 +1,98 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kysee/zk-chains/types"
+)
+
+// ProofStage is a step of an on-demand proof, reported to the progress callback of ProveUpdate
+type ProofStage string
+
+const (
+	// StageFetching fetches the update and the one of the previous period, whose committee signs it
+	StageFetching ProofStage = "fetching"
+	// StageAssigning validates the update and assigns its witness
+	StageAssigning ProofStage = "assigning"
+	// StageQueued waits for the proof requested before to be generated
+	StageQueued ProofStage = "queued"
+	// StageProving generates the proof, the long part
+	StageProving ProofStage = "proving"
+)
+
+// UpdateProof is the proof of a sync committee update generated on demand
+type UpdateProof struct {
+	Period uint64
+	// ScPubKeysHash commits to the committee signing the update, NextScPubKeysHash to the one it hands over to
+	ScPubKeysHash     []byte
+	NextScPubKeysHash []byte
+	Backend           types.ProofBackend
+	// Proof is the proof serialized with MarshalSolidity
+	Proof []byte
+	// ProofData is Proof split as in the proof files, a *types.ProofData or *types.PlonkProofData
+	ProofData any
+}
+
+// ProveUpdate proves the update of period, signed by the committee of the previous period, without
+// saving, submitting or serving it: it answers the requests of other services while Run proves the
+// periods in order. Proofs are generated one at a time, later requests wait in StageQueued.
+// progress, if not nil, is called as each stage starts.
+func (r *Relayer) ProveUpdate(ctx context.Context, period uint64, progress func(ProofStage)) (*UpdateProof, error) {
+	if period == 0 {
+		return nil, fmt.Errorf("period 0 has no previous committee to be proven against")
+	}
+	if progress == nil {
+		progress = func(ProofStage) {}
+	}
+
+	progress(StageFetching)
+	previous, err := r.fetcher.ScUpdate(ctx, period-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch update of period %d: %w", period-1, err)
+	}
+	update, err := r.fetcher.ScUpdate(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch update of period %d: %w", period, err)
+	}
+
+	progress(StageAssigning)
+	signers, err := r.parseCommittee(&previous.Data.NextSyncCommittee)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.validateUpdate(update, signers.sc); err != nil {
+		return nil, fmt.Errorf("invalid update for period %d: %w", period, err)
+	}
+	fullWitness, next, err := r.assignPeriod(update, signers)
+	if err != nil {
+		return nil, err
+	}
+
+	progress(StageQueued)
+	select {
+	case r.proverSlot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.proverSlot }()
+
+	progress(StageProving)
+	r.log().Infof("Generating on-demand proof of period %d\n", period)
+	proofSolidity, err := r.proveWithRetry(ctx, period, update, fullWitness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof: %w", err)
+	}
+	proofData, err := types.CreateProofDataFor(r.proofBackend(), proofSolidity)
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateProof{
+		Period:            period,
+		ScPubKeysHash:     signers.hash,
+		NextScPubKeysHash: next.hash,
+		Backend:           r.proofBackend(),
+		Proof:             proofSolidity,
+		ProofData:         proofData,
+	}, nil
+}
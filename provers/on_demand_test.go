@@ -0,0 +1,37 @@
+package relayer
+
+import (
+	"context"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProveUpdateStages(t *testing.T) {
+	r := &Relayer{
+		config:     cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled"),
+		fetcher:    &periodFetcher{updates: loadTestUpdates(t)},
+		proverSlot: make(chan struct{}, 1),
+	}
+	var stages []ProofStage
+	progress := func(stage ProofStage) { stages = append(stages, stage) }
+
+	// the committee of the previous period is needed
+	_, err := r.ProveUpdate(context.Background(), 1104, progress)
+	require.ErrorContains(t, err, "period 1103")
+	require.Equal(t, []ProofStage{StageFetching}, stages)
+
+	// the update is assigned, then waits for the proof being generated
+	stages = nil
+	r.proverSlot <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = r.ProveUpdate(ctx, 1105, func(stage ProofStage) {
+		progress(stage)
+		if stage == StageQueued {
+			cancel()
+		}
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []ProofStage{StageFetching, StageAssigning, StageQueued}, stages)
+}
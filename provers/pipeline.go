@@ -0,0 +1,310 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/kysee/zk-chains/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrUpdateUnavailable is returned by a Config.Once run when the update of its period cannot be fetched
+// yet, a run to try again later
+var ErrUpdateUnavailable = errors.New("update not available")
+
+const (
+	// pipelineDepth is the number of periods prepared ahead of the one being proven, and of proofs
+	// queued for submission while the next one is proven
+	pipelineDepth = 1
+)
+
+// preparedPeriod is an update fetched, validated and assigned by the prepare stage, ready to be proven
+type preparedPeriod struct {
+	period uint64
+	update *types.LightClientUpdate
+	// signers is the committee signing update, next the one it hands over to
+	signers     *committee
+	next        *committee
+	fullWitness witness.Witness
+	// err stops the pipeline, the update could not be assigned
+	err error
+	// span is the span of the period, ended once it is proven
+	span trace.Span
+}
+
+// submission is a saved proof waiting for the submit stage
+type submission struct {
+	update    *types.LightClientUpdate
+	proofData any
+	proofPath string
+	// circuit is the kind of the proof, tagging its submit spans
+	circuit CircuitKind
+	// span is the span of the period of the proof, the parent of its submit spans
+	span trace.SpanContext
+}
+
+// pipelineError is the failure of the pipeline at period, signed by signers, where it restarts from
+type pipelineError struct {
+	period  uint64
+	signers *committee
+	err     error
+}
+
+func (e *pipelineError) Error() string { return e.err.Error() }
+func (e *pipelineError) Unwrap() error { return e.err }
+
+// runPipeline proves the periods from period on, signed by signers first, in three stages: the prepare
+// stage fetches, validates and assigns the next period while the current one is proven, and the submit
+// stage sends the saved proofs to each destination in order while the next ones are proven. Proofs,
+// committee hand-overs, transition proofs and consumers stay on the calling goroutine, in period order,
+// from the transition proof of period. It fails with the pipelineError of the period to restart from.
+func (r *Relayer) runPipeline(ctx context.Context, period uint64, signers *committee) error {
+	r.useCommittee(signers)
+	if err := r.emitTransitionProof(ctx, period); err != nil {
+		return &pipelineError{period: period, signers: signers, err: err}
+	}
+
+	prepareCtx, cancel := context.WithCancel(ctx)
+	prepared := make(chan *preparedPeriod, pipelineDepth)
+	submissions := make(chan submission, pipelineDepth)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		r.prepare(prepareCtx, period, signers, prepared)
+	}()
+	go func() {
+		defer wg.Done()
+		r.submitStage(submissions)
+	}()
+	// on the way out the prepared periods are dropped, the queued proofs are still submitted
+	defer func() {
+		cancel()
+		close(submissions)
+		wg.Wait()
+	}()
+
+	for {
+		if ctx.Err() != nil {
+			return r.stopped(period)
+		}
+		r.setState(r.idleState(), period, nil)
+		var job *preparedPeriod
+		select {
+		case <-ctx.Done():
+			return r.stopped(period)
+		case job = <-prepared:
+		}
+		if job == nil {
+			return r.stopped(period)
+		}
+		if job.err != nil {
+			endStage(job.span, job.err)
+			return &pipelineError{period: job.period, signers: job.signers, err: fmt.Errorf("failed to generate proof: %w", Permanent(job.err))}
+		}
+		r.setState(StateProving, job.period, nil)
+		err := r.provePeriod(trace.ContextWithSpan(ctx, job.span), job, submissions)
+		endStage(job.span, err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return r.stopped(period)
+			}
+			return &pipelineError{period: job.period, signers: job.signers, err: err}
+		}
+
+		// Update pubkeys and scPubKeysHash for next iteration
+		r.useCommittee(job.next)
+		r.log().Infof("Updated scPubKeysHash: 0x%x\n", r.scPubKeysHash)
+		period = job.period + 1
+		if err := r.emitTransitionProof(ctx, period); err != nil {
+			return &pipelineError{period: period, signers: job.next, err: err}
+		}
+		if r.config.Once {
+			return nil
+		}
+	}
+}
+
+// prepare fetches the updates from period on, in batches while catching up with the head (see catchUp),
+// validates them against the committee signing them and assigns their witnesses, handing the committee
+// over after each one. A missing or invalid update is fetched again, without limit since the period
+// may not have ended yet, once the beacon node reports a new slot of the period (see eventWatcher) or
+// after waiting as the retry policy does. The first fetch of a period waits until its update is
+// expected (see waitForPeriod). Each period is traced as a period span, from its first fetch until it
+// is proven, with the fetch, pre-validate and witness spans of its attempts. It closes out when ctx is
+// cancelled or after a period that could not be assigned. With Config.Once, it prepares a single period, without waiting or fetching
+// again: a missing or invalid update is handed over as the error of the period.
+func (r *Relayer) prepare(ctx context.Context, period uint64, signers *committee, out chan<- *preparedPeriod) {
+	defer close(out)
+	policy := r.retryPolicy()
+	updates := r.newCatchUp()
+	var events *eventWatcher
+	if !r.config.Once {
+		events = r.watchEvents(ctx)
+	}
+	// span is the span of the period being prepared, handed over with it
+	var span trace.Span
+	var periodCtx context.Context
+	defer func() {
+		if span != nil {
+			endStage(span, ctx.Err())
+		}
+	}()
+	for attempt := 1; ctx.Err() == nil; attempt++ {
+		if attempt == 1 && !r.config.Once {
+			r.waitForPeriod(ctx, period)
+		}
+		if span == nil {
+			periodCtx, span = startStage(ctx, "period", period)
+		}
+		r.log().Infof("\n### Fetching update for period %d ###\n", period)
+		fetchCtx, fetchSpan := startStage(periodCtx, "fetch", period)
+		update, err := updates.fetch(fetchCtx, period)
+		endStage(fetchSpan, err)
+		if err != nil {
+			if r.config.Once && ctx.Err() == nil {
+				r.prepareFailed(ctx, out, period, signers, span, fmt.Errorf("%w: %w", ErrUpdateUnavailable, err))
+				span = nil
+				return
+			}
+			if ctx.Err() == nil {
+				r.log().Errorf("%v\n", err)
+			}
+			events.wait(ctx, period, policy.Delay(attempt), policy.MaxBackoff)
+			continue
+		}
+
+		// Pre-validate update before spending minutes on proving
+		span.SetAttributes(updateAttributes(update)...)
+		_, validateSpan := startStage(periodCtx, "pre-validate", period, updateAttributes(update)...)
+		err = r.validateUpdate(update, signers.sc)
+		endStage(validateSpan, err)
+		if err != nil {
+			if r.config.Once {
+				r.prepareFailed(ctx, out, period, signers, span, fmt.Errorf("invalid update for period %d: %w", period, err))
+				span = nil
+				return
+			}
+			r.log().Warnf("invalid update for period %d: %v\n", period, err)
+			events.wait(ctx, period, policy.Delay(attempt), policy.MaxBackoff)
+			continue
+		}
+
+		job := &preparedPeriod{period: period, update: update, signers: signers, span: span}
+		_, witnessSpan := startStage(periodCtx, "witness", period, append(updateAttributes(update), r.mainCircuit().attributes()...)...)
+		job.fullWitness, job.next, job.err = r.assignPeriod(update, signers)
+		endStage(witnessSpan, job.err)
+		select {
+		case out <- job:
+			span = nil
+		case <-ctx.Done():
+			return
+		}
+		if job.err != nil || r.config.Once {
+			return
+		}
+		r.log().Infof("✓ Witness of period %d assigned\n", period)
+		period++
+		signers = job.next
+		attempt = 0
+	}
+}
+
+// prepareFailed hands over the error of a period that could not be prepared, and its span
+func (r *Relayer) prepareFailed(ctx context.Context, out chan<- *preparedPeriod, period uint64, signers *committee, span trace.Span, err error) {
+	select {
+	case out <- &preparedPeriod{period: period, signers: signers, err: err, span: span}:
+	case <-ctx.Done():
+		endStage(span, ctx.Err())
+	}
+}
+
+// assignPeriod creates the full witness of update signed by signers, and parses the committee it hands over to
+func (r *Relayer) assignPeriod(update *types.LightClientUpdate, signers *committee) (witness.Witness, *committee, error) {
+	assignment, err := r.buildWitness(update, signers.pubkeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullWitness, err := frontend.NewWitness(assignment, r.mainCircuit().curve.ScalarField())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create witness: %w", err)
+	}
+	next, err := r.parseCommittee(&update.Data.NextSyncCommittee)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fullWitness, next, nil
+}
+
+// provePeriod proves a prepared period, saves the proof, queues it for submission, proves its finality
+// if the finality circuit is loaded and serves the consumers
+func (r *Relayer) provePeriod(ctx context.Context, job *preparedPeriod, submissions chan<- submission) error {
+	r.useCommittee(job.signers)
+	r.log().Infof("\n=== Generating proof of period %d ===\n", job.period)
+	r.log().Infof("Current scPubKeysHash: 0x%x\n", r.scPubKeysHash)
+
+	proofSolidity, err := r.proveWithRetry(ctx, job.period, job.update, job.fullWitness)
+	if err != nil {
+		return fmt.Errorf("failed to generate proof: %w", err)
+	}
+
+	// Save proof to file, and copy it to the configured sinks
+	proofData, err := types.CreateProofDataFor(r.proofBackend(), proofSolidity)
+	if err != nil {
+		return err
+	}
+	jsonBlob, err := json.MarshalIndent(proofData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal proof data: %w", err)
+	}
+	outputPath, err := DirSink(r.config.ProofDir).Store(ctx, proofFileName(job.period), jsonBlob)
+	if err != nil {
+		return err
+	}
+	r.log().Infof("✓ Proof saved to %s\n", outputPath)
+	r.storeProof(ctx, proofFileName(job.period), jsonBlob)
+
+	// Check the proof can be submitted within the gas budget
+	if err := r.checkSubmissionGas(job.update, proofData, outputPath); err != nil {
+		return err
+	}
+
+	// Send the proof to the destinations, unless it was set aside, while the next period is proven
+	if len(r.destinationList()) != 0 {
+		r.submitting.Add(1)
+		submissions <- submission{update: job.update, proofData: proofData, proofPath: outputPath,
+			circuit: CircuitScUpdate, span: trace.SpanContextFromContext(ctx)}
+	}
+
+	// Prove the finalized header of the period, when the finality circuit is loaded
+	if err := r.proveFinality(ctx, job.update, job.period); err != nil {
+		return err
+	}
+
+	// Deliver the proof, and the ones of the other commitment modes, to the registered consumers
+	return r.serveConsumers(ctx, job.update, job.period, proofData, outputPath)
+}
+
+// proveWithRetry proves the full witness of update, the update of period. Proving is retried with the
+// retry policy when the memory ceiling was hit or the remote prover was unavailable, other failures
+// would repeat: the update is quarantined and the error returned.
+func (r *Relayer) proveWithRetry(ctx context.Context, period uint64, update *types.LightClientUpdate, fullWitness witness.Witness) ([]byte, error) {
+	var proofSolidity []byte
+	err := r.retryPolicy().Retry(ctx, func() error {
+		var err error
+		proofSolidity, err = r.generateProof(ctx, update, fullWitness)
+		if err != nil && !errors.Is(err, ErrMemoryCeiling) && !errors.Is(err, ErrRemoteProverUnavailable) {
+			return Permanent(err)
+		}
+		if err != nil {
+			r.log().Warnf("proof of period %d failed: %v\n", period, err)
+		}
+		return err
+	})
+	return proofSolidity, err
+}
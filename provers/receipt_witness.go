@@ -0,0 +1,57 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/kysee/zk-chains/circuits"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+)
+
+// ReceiptWitness fetches the block at slot and the receipts of its execution payload, and builds the
+// Eth2ReceiptProofCircuit witness proving the status and cumulative gas of transaction txIndex
+// against the root of the block header
+func (listener *Listener) ReceiptWitness(ctx context.Context, slot uint64, txIndex int) (*circuit.Eth2ReceiptProofCircuit, error) {
+	if listener.receipts == nil {
+		return nil, fmt.Errorf("no execution RPC to fetch receipts from")
+	}
+	blockResponse, err := listener.fetcher.Block(ctx, slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block at slot %d: %w", slot, err)
+	}
+	block := &blockResponse.Data.Message
+	blockHash := common.Hash(block.Body.ExecutionPayload.BlockHash)
+	receipts, err := listener.receipts.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(blockHash, true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipts of block %s: %w", blockHash, err)
+	}
+	return buildReceiptWitness(blockResponse.Version, listener.config.Preset.Spec(), block, receipts, txIndex)
+}
+
+// buildReceiptWitness extracts the receipts_root branch of the body of block, a block of the given fork
+// (the "version" of the block response) hashed with spec, and the trie proof of receipt txIndex. Blocks
+// are decoded with the Electra layout, which Fulu kept, so earlier forks are rejected rather than proven
+// against a wrong body root.
+func buildReceiptWitness(fork string, spec *zrntcommon.Spec, block *electra.BeaconBlock, receipts gethtypes.Receipts, txIndex int) (*circuit.Eth2ReceiptProofCircuit, error) {
+	switch strings.ToLower(fork) {
+	case "electra", "fulu":
+	default:
+		return nil, fmt.Errorf("block of fork %q is not supported", fork)
+	}
+	params, err := circuit.NewReceiptProofParams(fork)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := types.BeaconBlockBodyProof(spec, &block.Body, params.BodyReceiptsRootGIndex())
+	if err != nil {
+		return nil, fmt.Errorf("receipts root proof: %w", err)
+	}
+	return circuit.NewEth2ReceiptProofAssignment(params, block.Header(spec), proof, receipts, txIndex)
+}
@@ -0,0 +1,66 @@
+package relayer
+
+import (
+	"context"
+	"testing"
+
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// blockFetcher serves a single block
+type blockFetcher struct {
+	block *cfgtypes.BlockAPIResponse
+}
+
+func (f *blockFetcher) ScUpdate(context.Context, uint64) (*types.LightClientUpdate, error) {
+	return nil, nil
+}
+
+func (f *blockFetcher) Block(context.Context, uint64) (*cfgtypes.BlockAPIResponse, error) {
+	return f.block, nil
+}
+
+func TestReceiptWitness(t *testing.T) {
+	source := newBlockReceiptSource()
+	spec := configs.Mainnet
+	resp := &cfgtypes.BlockAPIResponse{Version: "fulu"}
+	block := &resp.Data.Message
+	block.Slot = 12345678
+	block.Body.SyncAggregate.SyncCommitteeBits = make(altair.SyncCommitteeBits, spec.SYNC_COMMITTEE_SIZE/8)
+	block.Body.ExecutionPayload.BlockHash = zrntcommon.Hash32(source.header.Hash())
+	block.Body.ExecutionPayload.ReceiptsRoot = zrntcommon.Root(source.header.ReceiptHash)
+	headerRoot := block.Header(spec).HashTreeRoot(tree.GetHashFn())
+
+	listener := NewListener(&cfgtypes.Config{}, &blockFetcher{block: resp}, source)
+	witness, err := listener.ReceiptWitness(context.Background(), uint64(block.Slot), 2)
+	require.NoError(t, err)
+	require.Len(t, witness.ReceiptsRootBranch, 9)
+	for i := 0; i < 32; i++ {
+		require.Equal(t, block.Body.ExecutionPayload.ReceiptsRoot[i], witness.ReceiptsRoot[i].Val)
+		require.Equal(t, headerRoot[i], witness.HeaderRoot[i].Val)
+	}
+	require.Equal(t, gethtypes.ReceiptStatusFailed, witness.Status)
+	require.Equal(t, uint64(101_000), witness.CumulativeGasUsed)
+
+	// receipts of another block
+	block.Body.ExecutionPayload.ReceiptsRoot[0] ^= 1
+	_, err = listener.ReceiptWitness(context.Background(), uint64(block.Slot), 2)
+	require.Error(t, err)
+	block.Body.ExecutionPayload.ReceiptsRoot[0] ^= 1
+
+	// Deneb bodies don't decode with the Electra layout
+	resp.Version = "deneb"
+	_, err = listener.ReceiptWitness(context.Background(), uint64(block.Slot), 2)
+	require.Error(t, err)
+
+	// no execution RPC
+	_, err = NewListener(&cfgtypes.Config{}, &blockFetcher{block: resp}, nil).ReceiptWitness(context.Background(), uint64(block.Slot), 2)
+	require.Error(t, err)
+}
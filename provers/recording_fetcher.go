@@ -0,0 +1,227 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// beaconFetcher is implemented by the fetchers of beacon endpoints, APIFetcher and MultiFetcher
+type beaconFetcher interface {
+	cfgtypes.Fetcher
+	cfgtypes.BatchFetcher
+	cfgtypes.BootstrapFetcher
+	cfgtypes.EventFetcher
+	cfgtypes.AttestationFetcher
+}
+
+// the files of a recording: the updates by period, the blocks by slot, the bootstraps by block root,
+// and the last head slot and attested block number. The updates are named like the fixtures of data/,
+// so that a recording can be replayed by the simulate command too.
+const (
+	recordedHead     = "head.json"
+	recordedAttested = "attested.json"
+)
+
+func recordedUpdate(period uint64) string { return fmt.Sprintf("sc-update-%d.json", period) }
+
+func recordedBlock(slot uint64) string { return fmt.Sprintf("block-%d.json", slot) }
+
+func recordedBootstrap(root zrntcommon.Root) string { return fmt.Sprintf("bootstrap-%s.json", root) }
+
+// RecordingFetcher saves every response of a beacon fetcher to Dir, for a ReplayFetcher to serve them
+// back: an integration test or a bug report is then reproduced without a live beacon node. A response
+// failing to be saved is logged, the fetch itself succeeds. Events are streamed, not recorded.
+type RecordingFetcher struct {
+	Dir     string
+	fetcher beaconFetcher
+	logger  cfgtypes.Logger
+}
+
+// NewRecordingFetcher returns the fetcher recording the responses of fetcher to dir, which it creates
+func NewRecordingFetcher(fetcher beaconFetcher, dir string, logger cfgtypes.Logger) (*RecordingFetcher, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the recording directory: %w", err)
+	}
+	return &RecordingFetcher{Dir: dir, fetcher: fetcher, logger: logger}, nil
+}
+
+// recordTo wraps fetcher in a RecordingFetcher when config.BeaconRecordDir is set
+func recordTo(config *cfgtypes.Config, fetcher beaconFetcher) (cfgtypes.Fetcher, error) {
+	if config.BeaconRecordDir == "" {
+		return fetcher, nil
+	}
+	config.Log().Infof("Recording the beacon responses to %s\n", config.BeaconRecordDir)
+	return NewRecordingFetcher(fetcher, config.BeaconRecordDir, config.Log())
+}
+
+// record saves v as name, through a temporary file so that concurrent fetches of the same response
+// leave a complete one
+func (f *RecordingFetcher) record(name string, v any) {
+	err := func() error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(f.Dir, name+".*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(data); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), filepath.Join(f.Dir, name))
+	}()
+	if err != nil && f.logger != nil {
+		f.logger.Warnf("failed to record %s: %v\n", name, err)
+	}
+}
+
+// ScUpdate fetches and records the update of period
+func (f *RecordingFetcher) ScUpdate(ctx context.Context, period uint64) (*types.LightClientUpdate, error) {
+	update, err := f.fetcher.ScUpdate(ctx, period)
+	if err == nil {
+		f.record(recordedUpdate(period), update)
+	}
+	return update, err
+}
+
+// ScUpdates fetches the updates of count periods from startPeriod on, and records each under its period
+func (f *RecordingFetcher) ScUpdates(ctx context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
+	updates, err := f.fetcher.ScUpdates(ctx, startPeriod, count)
+	for i, update := range updates {
+		f.record(recordedUpdate(startPeriod+uint64(i)), update)
+	}
+	return updates, err
+}
+
+// HeadSlot fetches and records the slot of the beacon head
+func (f *RecordingFetcher) HeadSlot(ctx context.Context) (uint64, error) {
+	slot, err := f.fetcher.HeadSlot(ctx)
+	if err == nil {
+		f.record(recordedHead, slot)
+	}
+	return slot, err
+}
+
+// AttestedBlockNumber fetches and records the execution block number of the latest attested header
+func (f *RecordingFetcher) AttestedBlockNumber(ctx context.Context) (uint64, error) {
+	number, err := f.fetcher.AttestedBlockNumber(ctx)
+	if err == nil {
+		f.record(recordedAttested, number)
+	}
+	return number, err
+}
+
+// Bootstrap fetches and records the light client bootstrap of blockRoot
+func (f *RecordingFetcher) Bootstrap(ctx context.Context, blockRoot zrntcommon.Root) (*types.LightClientBootstrap, error) {
+	bootstrap, err := f.fetcher.Bootstrap(ctx, blockRoot)
+	if err == nil {
+		f.record(recordedBootstrap(blockRoot), bootstrap)
+	}
+	return bootstrap, err
+}
+
+// Block fetches and records the beacon block of slot
+func (f *RecordingFetcher) Block(ctx context.Context, slot uint64) (*cfgtypes.BlockAPIResponse, error) {
+	block, err := f.fetcher.Block(ctx, slot)
+	if err == nil {
+		f.record(recordedBlock(slot), block)
+	}
+	return block, err
+}
+
+// Events streams the events of the recorded fetcher
+func (f *RecordingFetcher) Events(ctx context.Context, topics []string, handle func(cfgtypes.BeaconEvent)) error {
+	return f.fetcher.Events(ctx, topics, handle)
+}
+
+// ReplayFetcher serves the responses a RecordingFetcher saved to Dir. A response that was not recorded
+// fails with an error wrapping os.ErrNotExist. It streams no events, the relayer polls it instead.
+type ReplayFetcher struct {
+	Dir string
+}
+
+// NewReplayFetcher returns the fetcher of the responses recorded in dir
+func NewReplayFetcher(dir string) *ReplayFetcher {
+	return &ReplayFetcher{Dir: dir}
+}
+
+// replay decodes the recorded response name into out
+func (f *ReplayFetcher) replay(name string, out any) error {
+	data, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if err != nil {
+		return fmt.Errorf("no recorded %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse the recorded %s: %w", name, err)
+	}
+	return nil
+}
+
+// ScUpdate serves the recorded update of period
+func (f *ReplayFetcher) ScUpdate(_ context.Context, period uint64) (*types.LightClientUpdate, error) {
+	var update types.LightClientUpdate
+	if err := f.replay(recordedUpdate(period), &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// ScUpdates serves the recorded updates of count periods from startPeriod on, up to the first one
+// missing. Like a beacon node, it fails if there is none.
+func (f *ReplayFetcher) ScUpdates(ctx context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
+	var updates []*types.LightClientUpdate
+	for period := startPeriod; period < startPeriod+uint64(count); period++ {
+		update, err := f.ScUpdate(ctx, period)
+		if len(updates) == 0 && err != nil {
+			return nil, err
+		}
+		if err != nil {
+			break
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// HeadSlot serves the last recorded head slot
+func (f *ReplayFetcher) HeadSlot(context.Context) (uint64, error) {
+	var slot uint64
+	return slot, f.replay(recordedHead, &slot)
+}
+
+// AttestedBlockNumber serves the last recorded execution block number of the attested header
+func (f *ReplayFetcher) AttestedBlockNumber(context.Context) (uint64, error) {
+	var number uint64
+	return number, f.replay(recordedAttested, &number)
+}
+
+// Bootstrap serves the recorded light client bootstrap of blockRoot
+func (f *ReplayFetcher) Bootstrap(_ context.Context, blockRoot zrntcommon.Root) (*types.LightClientBootstrap, error) {
+	var bootstrap types.LightClientBootstrap
+	if err := f.replay(recordedBootstrap(blockRoot), &bootstrap); err != nil {
+		return nil, err
+	}
+	return &bootstrap, nil
+}
+
+// Block serves the recorded beacon block of slot
+func (f *ReplayFetcher) Block(_ context.Context, slot uint64) (*cfgtypes.BlockAPIResponse, error) {
+	var block cfgtypes.BlockAPIResponse
+	if err := f.replay(recordedBlock(slot), &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
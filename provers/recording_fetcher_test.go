@@ -0,0 +1,78 @@
+package relayer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var updates [][]byte
+	for _, period := range []string{"1104", "1105"} {
+		update, err := os.ReadFile(filepath.Join("..", "data", "sc-update-"+period+".json"))
+		require.NoError(t, err)
+		updates = append(updates, update)
+	}
+	bootstrap, err := os.ReadFile(filepath.Join("..", "data", "bootstrap-1105.json"))
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/eth/v1/beacon/headers/head":
+			_, _ = w.Write([]byte(`{"data": {"header": {"message": {"slot": "9060000"}}}}`))
+		case r.URL.Path == "/eth/v1/beacon/light_client/updates":
+			_, _ = w.Write([]byte("[" + string(updates[0]) + "," + string(updates[1]) + "]"))
+		case filepath.Dir(r.URL.Path) == "/eth/v1/beacon/light_client/bootstrap":
+			_, _ = w.Write(bootstrap)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// the responses of the beacon node are recorded
+	config := cfgtypes.NewConfig("--rpc", server.URL, "--record-dir", dir, "--log-level", "disabled")
+	fetcher, err := NewBeaconFetcher(config)
+	require.NoError(t, err)
+	recording := fetcher.(*RecordingFetcher)
+	live, err := recording.ScUpdates(ctx, 1104, 3)
+	require.NoError(t, err)
+	require.Len(t, live, 2)
+	_, err = recording.HeadSlot(ctx)
+	require.NoError(t, err)
+	root := live[1].Data.AttestedHeader.Beacon.HashTreeRoot(tree.GetHashFn())
+	_, err = recording.Bootstrap(ctx, root)
+	require.NoError(t, err)
+	server.Close()
+
+	// and served back without it
+	config = cfgtypes.NewConfig("--replay-dir", dir, "--log-level", "disabled")
+	fetcher, err = NewBeaconFetcher(config)
+	require.NoError(t, err)
+	replay := fetcher.(*ReplayFetcher)
+	got, err := replay.ScUpdates(ctx, 1104, 3)
+	require.NoError(t, err)
+	require.Equal(t, live, got)
+	slot, err := replay.HeadSlot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9060000), slot)
+	_, err = replay.ScUpdate(ctx, 1106)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = replay.AttestedBlockNumber(ctx)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// the replayed responses bootstrap and validate like the live ones
+	config.TrustedBlockRoot = root
+	r := &Relayer{config: config, fetcher: replay}
+	signers, period, err := r.bootstrap(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1105), period)
+	require.NoError(t, r.validateUpdate(got[1], signers.sc))
+}
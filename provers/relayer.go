@@ -1,300 +1,917 @@
 package relayer
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/solidity"
+	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/constraint"
-	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/std/algebra/emulated/sw_bls12381"
-	"github.com/consensys/gnark/std/math/uints"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/kysee/zk-chains/circuits"
+	circuitwitness "github.com/kysee/zk-chains/circuits/witness"
 	cfgtypes "github.com/kysee/zk-chains/provers/types"
 	"github.com/kysee/zk-chains/types"
-	"github.com/protolambda/zrnt/eth2/configs"
-	"github.com/protolambda/ztyp/tree"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Main entry point for the relayer
-func RelayerMain(config *cfgtypes.Config) {
-	// Create and run relayer
-	relayer, err := NewRelayer(config, NewAPIFetcher(config.RPCEndpoint))
+// Exit codes of a Config.Once run, for the schedulers driving it
+const (
+	// ExitProved is returned once the period is proven, and submitted if a destination is configured
+	ExitProved = 0
+	// ExitFailed is returned when the run failed or was interrupted
+	ExitFailed = 1
+	// ExitNotReady is returned when the update of the period is not available yet, see ErrUpdateUnavailable
+	ExitNotReady = 2
+)
+
+// Main entry point for the relayer. It runs as a daemon, or with Config.Once proves a single period (or
+// the receipt of Config.TxHash) and exits with one of the Exit codes.
+func RelayerMain(ctx context.Context, config *cfgtypes.Config) {
+	if config.Once && config.TxHash != "" {
+		TxStatusMain(ctx, config)
+		return
+	}
+
+	// Export the spans of the proving stages, flushed before exiting
+	shutdownTracing, err := SetupTracing(ctx, config)
+	if err != nil {
+		fatalf(config.Log(), "Failed to set up tracing: %v", err)
+	}
+
+	// Create and run relayer, with a MultiFetcher even for a single endpoint so that SIGHUP can replace them
+	fetcher := localFetcher(config)
+	if fetcher == nil {
+		beacon, err := NewMultiFetcher(config.BeaconEndpoints(), config.BeaconQuorum, config.Log())
+		if err != nil {
+			fatalf(config.Log(), "Failed to create beacon fetcher: %v", err)
+		}
+		if fetcher, err = recordTo(config, beacon); err != nil {
+			fatalf(config.Log(), "Failed to create beacon fetcher: %v", err)
+		}
+	}
+	relayer, err := NewRelayer(config, fetcher)
 	if err != nil {
-		log.Fatalf("Failed to create relayer: %v", err)
+		fatalf(config.Log(), "Failed to create relayer: %v", err)
+	}
+	if !config.Once {
+		go relayer.reloadOnSignal(ctx)
+	}
+
+	var listener *Listener
+	var client *ethclient.Client
+	if config.ExecutionRPC != "" {
+		if client, err = ethclient.Dial(config.ExecutionRPC); err != nil {
+			fatalf(config.Log(), "failed to connect to %s: %v", config.ExecutionRPC, err)
+		}
+		listener = NewListener(config, fetcher, client)
+	}
+
+	// Prove the transactions emitting the watched logs while running
+	if !config.Once && (len(config.WatchContracts) > 0 || len(config.WatchTopics) > 0) {
+		if listener == nil {
+			fatalf(config.Log(), "watching logs needs --exec-rpc")
+		}
+		watcher, err := NewLogWatcher(config, listener, client)
+		if err != nil {
+			fatalf(config.Log(), "failed to create log watcher: %v", err)
+		}
+		go func() {
+			if err := watcher.Run(ctx); err != nil {
+				fatalf(config.Log(), "log watcher failed: %v", err)
+			}
+		}()
+	}
+
+	// Serve the proofs and status over HTTP while running
+	if !config.Once && config.HTTPAddr != "" {
+		go func() {
+			if err := ServeHTTPAPI(ctx, config, listener, relayer); err != nil {
+				fatalf(config.Log(), "failed to serve the HTTP API: %v", err)
+			}
+		}()
 	}
 
 	// Setup circuit first
-	if err := relayer.setupCircuit(); err != nil {
-		log.Fatalf("failed to setup circuit: %w", err)
+	if err := relayer.SetupCircuit(); err != nil {
+		fatalf(config.Log(), "failed to setup circuit: %v", err)
 	}
 
-	if err := relayer.Run(); err != nil {
-		log.Fatalf("Failed to run relayer: %v", err)
+	err = relayer.Run(ctx)
+	if err != nil {
+		config.Log().Errorf("Failed to run relayer: %v", err)
+	}
+	flushTracing(config, shutdownTracing)
+	if config.Once {
+		os.Exit(onceExitCode(ctx, err))
+	}
+	if err != nil {
+		os.Exit(ExitFailed)
 	}
 }
 
+// onceExitCode returns the exit code of a Config.Once run that returned err
+func onceExitCode(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, ErrUpdateUnavailable):
+		return ExitNotReady
+	case err != nil || ctx.Err() != nil:
+		return ExitFailed
+	}
+	return ExitProved
+}
+
+// fatalf logs an error of a command and exits
+func fatalf(logger cfgtypes.Logger, format string, args ...any) {
+	logger.Errorf(format, args...)
+	os.Exit(1)
+}
+
+// ErrProofVerification is returned for a generated proof that the verifying key rejects
+var ErrProofVerification = errors.New("proof verification failed")
+
 // Relayer is the main relayer struct
 type Relayer struct {
-	config           *cfgtypes.Config
-	fetcher          cfgtypes.Fetcher
-	ccs              constraint.ConstraintSystem
-	pk               groth16.ProvingKey
+	config  *cfgtypes.Config
+	fetcher cfgtypes.Fetcher
+	ccs     constraint.ConstraintSystem
+	pk      groth16.ProvingKey
+	plonkPk plonk.ProvingKey
+	// vk and plonkVk verify the proofs before they are saved, see loadedCircuit.verify
+	vk      groth16.VerifyingKey
+	plonkVk plonk.VerifyingKey
+	// transition proves the dual commitments of each committee during a migration window, nil outside of one
+	transition *loadedCircuit
+	// consumers are the downstream protocols served with each proof, nil if no registry is configured
+	consumers *ConsumerRegistry
+	// circuits are the circuits of the kinds of Config.Circuits, see SetupCircuit
+	circuits CircuitRegistry
+	// modeCircuits prove for the consumers committing in a mode other than ScPubKeysHashMode
+	modeCircuits map[types.ScPubKeysHashMode]*loadedCircuit
+	// artifacts describes the loaded circuit (backend, curve, verifier), see SetupCircuit
+	artifacts        *types.CircuitManifest
 	scPubKeysHash    []byte
-	currentScPubkeys [512]bls12381.G1Affine
+	currentScPubkeys []bls12381.G1Affine
+	currentSc        *zrntcommon.SyncCommittee
+	// targetsMu guards gasEstimator, lightClient, destinations and the gas settings of config, which
+	// Reload replaces
+	targetsMu sync.RWMutex
+	// gasEstimator simulates submissions on the destination chain, nil if none is configured
+	gasEstimator GasEstimator
+	// lightClient reads the state of the light client of Config.LightClientAddress, nil if none is configured
+	lightClient ethereum.ContractCaller
+	// destinations are the light clients the proofs are submitted to: the one of the config, if a submitter
+	// key is configured, and the ones of Config.DestinationsPath
+	destinations []*destination
+	// artifactCipher encrypts witness and quarantine files, nil if no key is configured
+	artifactCipher *ArtifactCipher
+	// proverSlot is held by the on-demand proof being generated, see ProveUpdate
+	proverSlot chan struct{}
+	// proofGuard bounds the proofs generated at once by all the circuits, nil for no limit
+	proofGuard *proofGuard
+	// sinks receive a copy of each proof file, see Config.ProofSinks
+	sinks []ProofSink
+	// remoteProver proves the witnesses of the relayer, nil if they are proven locally
+	remoteProver *RemoteProver
+	// loaded is set once SetupCircuit loaded the circuits, started is when the relayer was created
+	loaded  atomic.Bool
+	started time.Time
+	// state is what the relayer is doing, see Run. submitting counts the proofs queued for a destination
+	// and behind is set while the beacon head is more than one period ahead of the period prepared.
+	state      stateMachine
+	submitting atomic.Int32
+	behind     atomic.Bool
+	// ledgerMu guards the submission ledger of the proof directory, see submissionLedger
+	ledgerMu sync.Mutex
 }
 
 // NewRelayer creates a new Relayer with the given configuration
 func NewRelayer(config *cfgtypes.Config, fetcher cfgtypes.Fetcher) (*Relayer, error) {
+	if config.GPU && !icicleEnabled {
+		return nil, errors.New("GPU proving needs a relayer built with -tags icicle")
+	}
+	applyRuntimeLimits(config)
 	_ = os.MkdirAll(config.RootDir, 0755)
 
-	return &Relayer{
-		fetcher: fetcher,
-		config:  config,
-	}, nil
-}
-
-// Run executes the relayer to fetch and display attested header information
-func (r *Relayer) Run() error {
-	period := r.config.InitPeriod
-	log.Printf("Starting from period %d\n", period)
+	var artifactCipher *ArtifactCipher
+	if config.ArtifactKeyEnv != "" && os.Getenv(config.ArtifactKeyEnv) != "" {
+		var err error
+		artifactCipher, err = NewArtifactCipher(EnvKeySource(config.ArtifactKeyEnv))
+		if err != nil {
+			return nil, err
+		}
+		config.Log().Infof("Witness and quarantine files are encrypted with the key from %s\n", config.ArtifactKeyEnv)
+	}
 
-	// Fetch first update to initialize currentScPubkeys
-	log.Printf("\n### Fetching initial update for period %d ###\n", period)
-	var err error
-	initialUpdate, err := r.fetcher.ScUpdate(period)
+	gasEstimator, lightClient, destinations, err := connectDestinations(config)
 	if err != nil {
-		return fmt.Errorf("failed to fetch initial update: %w", err)
+		return nil, err
 	}
 
-	// Parse and store current sync committee pubkeys
-	for i := 0; i < 512; i++ {
-		pubkeyBytes := initialUpdate.Data.NextSyncCommittee.Pubkeys[i][:]
-		_, err = r.currentScPubkeys[i].SetBytes(pubkeyBytes)
+	var consumers *ConsumerRegistry
+	if config.ConsumersPath != "" {
+		var err error
+		consumers, err = LoadConsumerRegistry(config.ConsumersPath, config.ScPubKeysHashMode)
 		if err != nil {
-			return fmt.Errorf("failed to parse pubkey %d: %w", i, err)
+			return nil, err
 		}
 	}
 
-	// Compute and store scPubKeysHash
-	hashArray := types.ComputeScPubKeysHash(r.currentScPubkeys[:])
-	r.scPubKeysHash = hashArray[:]
-	log.Printf("Initial scPubKeysHash: 0x%x\n", r.scPubKeysHash)
-
-	period++
-
-	// Main loop
-	for {
-		// Fetch update
-		log.Printf("\n### Fetching update for period %d ###\n", period)
-		update, err := r.fetcher.ScUpdate(period)
+	sinks := make([]ProofSink, 0, len(config.ProofSinks))
+	for _, target := range config.ProofSinks {
+		sink, err := NewProofSink(target)
 		if err != nil {
-			log.Println("error", err)
-			time.Sleep(1000 * time.Millisecond)
-			continue //return fmt.Errorf("failed to fetch update for period %d: %w", period, err)
+			return nil, fmt.Errorf("invalid proof sink: %w", err)
 		}
+		sinks = append(sinks, sink)
+	}
 
-		//// Display attested header information
-		//attestedHeader := update.Data.AttestedHeader
-		//log.Printf("=== Attested Header ===\n")
-		//log.Printf("Beacon Block Header:\n")
-		//log.Printf("  Slot: %s\n", attestedHeader.Beacon.Slot)
-		//log.Printf("  Proposer Index: %s\n", attestedHeader.Beacon.ProposerIndex)
-		//log.Printf("Execution Payload Header:\n")
-		//log.Printf("  Block Number: %s\n", attestedHeader.Execution.BlockNumber)
-		//log.Printf("  Block Hash: %s\n", attestedHeader.Execution.BlockHash)
-		//log.Printf("  Timestamp: %s\n", attestedHeader.Execution.Timestamp)
-
-		// Generate proof
-		log.Printf("\n=== Generating proof ===\n")
-		log.Printf("Current scPubKeysHash: 0x%x\n", r.scPubKeysHash)
-
-		proofSolidity, err := r.generateProof(update)
-		if err != nil {
-			return fmt.Errorf("failed to generate proof: %w", err)
+	var remoteProver *RemoteProver
+	if config.RemoteProver != "" {
+		if remoteProver, err = DialRemoteProver(config.RemoteProver); err != nil {
+			return nil, err
 		}
+		config.Log().Infof("Witnesses are proven by %s\n", config.RemoteProver)
+	}
 
-		// Save proof to file
-		outputPath := fmt.Sprintf("output/proof-period-%d.json", period)
-		proofData := types.CreateProofData(proofSolidity)
-		jsonBlob, err := json.MarshalIndent(proofData, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal proof data: %w", err)
+	return &Relayer{
+		fetcher:        fetcher,
+		config:         config,
+		gasEstimator:   gasEstimator,
+		lightClient:    lightClient,
+		destinations:   destinations,
+		artifactCipher: artifactCipher,
+		consumers:      consumers,
+		proverSlot:     make(chan struct{}, 1),
+		proofGuard:     newProofGuard(config),
+		sinks:          sinks,
+		remoteProver:   remoteProver,
+		started:        time.Now(),
+	}, nil
+}
+
+// log returns the logger of the relayer's config
+func (r *Relayer) log() cfgtypes.Logger {
+	return r.config.Log()
+}
+
+// Run executes the relayer: it proves every period from InitPeriod+1 on, from the period the light
+// client accepts next (see resumeFromChain), or from the period of the trusted block when
+// TrustedBlockRoot is set, see runPipeline. It returns nil once ctx is cancelled:
+// a period being proven is finished (saved, submitted and served) first, the fetches and waits between
+// periods are interrupted.
+//
+// The relayer goes through the RelayerStates, and recovers from each failure the same way: the start
+// is retried with the retry policy; a failed period, a proof that could not be saved or served, or a
+// transition proof that failed, restarts the pipeline at that period after waiting as the retry policy
+// does, up to MaxAttempts times in a row. A permanent failure (see Permanent) faults the relayer and is
+// returned: an invalid bootstrap, a committee the light client does not expect, a witness that cannot
+// be assigned or a proof that fails for another reason than resources. A missing or invalid update is
+// fetched again without limit, see prepare, and a failed submission leaves the proof pending, see
+// submitProof. With Config.Once nothing is retried, its scheduler runs it again.
+func (r *Relayer) Run(ctx context.Context) error {
+	var period uint64
+	var signers *committee
+	err := r.recoverStart(ctx, func() error {
+		var err error
+		period, signers, err = r.start(ctx)
+		return err
+	})
+	if ctx.Err() != nil {
+		if r.config.TrustedBlockRoot != ([32]byte{}) || period == 0 {
+			r.log().Infof("Relayer stopped while bootstrapping\n")
+			return nil
+		}
+		return r.stopped(period)
+	}
+	if err != nil {
+		r.setState(StateFaulted, period, err)
+		return err
+	}
+
+	policy := r.retryPolicy()
+	err = r.runPipeline(ctx, period, signers)
+	for restarts := 1; err != nil && !r.config.Once; restarts++ {
+		var failed *pipelineError
+		if !errors.As(err, &failed) || !IsRetryable(failed.err) {
+			break
 		}
-		err = os.WriteFile(outputPath, jsonBlob, 0644)
+		// the restarts are counted at the same period, a pipeline failing further on starts over
+		if failed.period != period {
+			period, restarts = failed.period, 1
+		}
+		if restarts >= policy.MaxAttempts {
+			break
+		}
+		delay := policy.Delay(restarts)
+		r.setState(StateFaulted, period, fmt.Errorf("restarting at period %d in %s: %w", period, delay.Round(time.Millisecond), failed.err))
+		sleep(ctx, delay)
+		if ctx.Err() != nil {
+			return r.stopped(period)
+		}
+		err = r.runPipeline(ctx, period, failed.signers)
+	}
+	if err != nil {
+		r.setState(StateFaulted, r.State().Period, err)
+	}
+	return err
+}
+
+// start determines the first period to prove and the committee signing it, by bootstrapping from the
+// trusted block root or from the update of the period before it. It returns the period, once known,
+// with its error.
+func (r *Relayer) start(ctx context.Context) (uint64, *committee, error) {
+	if r.config.TrustedBlockRoot != ([32]byte{}) {
+		r.setState(StateBootstrapping, 0, nil)
+		signers, period, err := r.bootstrap(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to write proof file: %w", err)
+			return 0, nil, err
 		}
-		log.Printf("✓ Proof saved to %s\n", outputPath)
-
-		// Update pubkeys and scPubKeysHash for next iteration
-		for i := 0; i < 512; i++ {
-			pubkeyBytes := update.Data.NextSyncCommittee.Pubkeys[i][:]
-			_, err = r.currentScPubkeys[i].SetBytes(pubkeyBytes)
-			if err != nil {
-				return fmt.Errorf("failed to parse pubkey %d: %w", i, err)
-			}
+		return period, signers, nil
+	}
+
+	period := r.config.InitPeriod
+	r.setState(StateBootstrapping, period+1, nil)
+	chainState, err := r.resumeFromChain(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if chainState != nil {
+		period = chainState.Period - 1
+	}
+	r.log().Infof("Starting from period %d\n", period)
+	r.setState(StateBootstrapping, period+1, nil)
+
+	// Fetch first update to initialize currentScPubkeys
+	r.log().Infof("\n### Fetching initial update for period %d ###\n", period)
+	initialUpdate, err := r.fetcher.ScUpdate(ctx, period)
+	if err != nil {
+		return period + 1, nil, fmt.Errorf("failed to fetch initial update: %w", err)
+	}
+
+	// Parse and store current sync committee pubkeys, and compute scPubKeysHash
+	if err := r.setCurrentCommittee(&initialUpdate.Data.NextSyncCommittee); err != nil {
+		return period + 1, nil, Permanent(err)
+	}
+	r.log().Infof("Initial scPubKeysHash: 0x%x\n", r.scPubKeysHash)
+	if err := r.checkChainCommittee(chainState); err != nil {
+		return period + 1, nil, Permanent(err)
+	}
+	return period + 1, &committee{sc: r.currentSc, pubkeys: r.currentScPubkeys, hash: r.scPubKeysHash}, nil
+}
+
+// recoverStart runs start until it succeeds with the retry policy, logging the failures it retries. A
+// Config.Once run starts once.
+func (r *Relayer) recoverStart(ctx context.Context, start func() error) error {
+	if r.config.Once {
+		return start()
+	}
+	return r.retryPolicy().Retry(ctx, func() error {
+		err := start()
+		if IsRetryable(err) && ctx.Err() == nil {
+			r.setState(StateFaulted, r.State().Period, fmt.Errorf("retrying the start: %w", err))
 		}
-		hashArray := types.ComputeScPubKeysHash(r.currentScPubkeys[:])
-		r.scPubKeysHash = hashArray[:]
-		log.Printf("Updated scPubKeysHash: 0x%x\n", r.scPubKeysHash)
+		return err
+	})
+}
 
-		// Move to next period
-		period++
+// bootstrap fetches the bootstrap of the trusted block and verifies it natively. It returns the current
+// sync committee of the block's state and its period, the first one to prove: the committee signs it.
+func (r *Relayer) bootstrap(ctx context.Context) (*committee, uint64, error) {
+	fetcher, ok := r.fetcher.(cfgtypes.BootstrapFetcher)
+	if !ok {
+		return nil, 0, Permanent(fmt.Errorf("the fetcher cannot bootstrap from a trusted block root"))
+	}
+	trustedRoot := zrntcommon.Root(r.config.TrustedBlockRoot)
+	r.log().Infof("\n### Bootstrapping from block %v ###\n", trustedRoot)
+	bootstrap, err := fetcher.Bootstrap(ctx, trustedRoot)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch bootstrap: %w", err)
+	}
+	if err := bootstrap.Verify(trustedRoot, r.config.Fork); err != nil {
+		return nil, 0, Permanent(fmt.Errorf("invalid bootstrap: %w", err))
+	}
+	signers, err := r.parseCommittee(&bootstrap.Data.CurrentSyncCommittee)
+	if err != nil {
+		return nil, 0, Permanent(err)
+	}
+	slot := uint64(bootstrap.Data.Header.Beacon.Slot)
+	period := r.config.Preset.Period(slot)
+	r.log().Infof("✓ Bootstrapped at slot %d, scPubKeysHash of period %d: 0x%x\n", slot, period, signers.hash)
+	return signers, period, nil
+}
 
-		time.Sleep(1000 * time.Millisecond)
+// retryPolicy returns the retry policy of the config
+func (r *Relayer) retryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: r.config.RetryMaxAttempts,
+		Backoff:     r.config.RetryBackoff,
+		MaxBackoff:  r.config.RetryMaxBackoff,
+	}.withDefaults()
+}
+
+// stopped logs where a cancelled Run stopped: every period before period is proven, so a restart
+// from the previous one (whose committee signs period) resumes the chain of proofs
+func (r *Relayer) stopped(period uint64) error {
+	r.log().Infof("Relayer stopped before period %d, resume with --init-period %d\n", period, period-1)
+	return nil
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
 	}
 }
 
-// setupCircuit loads the compiled circuit and proving key from output directory
-func (r *Relayer) setupCircuit() error {
-	if r.ccs != nil {
-		log.Println("Circuit already loaded")
+// SetupCircuit loads the compiled circuits and proving keys described by the artifact manifest, it is
+// called once before Run or ProveUpdate. With a remote prover only their verifying keys are loaded.
+func (r *Relayer) SetupCircuit() error {
+	if r.loaded.Load() {
+		r.log().Infof("Circuit already loaded")
 		return nil
 	}
 
-	ccsPath := filepath.Join(r.config.RootDir, "../.build/Eth2ScUpdateCircuit.ccs")
-	pkPath := filepath.Join(r.config.RootDir, "../.build/Eth2ScUpdateCircuit.pk")
+	artifacts, dir, err := r.circuitArtifacts()
+	if err != nil {
+		return err
+	}
+	loaded, err := loadCircuit(artifacts, dir, r.proverSettings())
+	if err != nil {
+		return err
+	}
+	r.ccs, r.pk, r.plonkPk = loaded.ccs, loaded.pk, loaded.plonkPk
+	r.vk, r.plonkVk = loaded.vk, loaded.plonkVk
+	r.artifacts = artifacts
+	if err := r.setupCircuits(); err != nil {
+		return err
+	}
+
+	if r.config.TransitionUntilPeriod > r.config.InitPeriod {
+		if err := r.setupTransitionCircuit(); err != nil {
+			return err
+		}
+	}
+	if err := r.setupConsumerCircuits(); err != nil {
+		return err
+	}
+	r.loaded.Store(true)
+	return nil
+}
+
+// loadedCircuit is a compiled circuit with the proving key of its backend
+type loadedCircuit struct {
+	// name and ccsChecksum identify the circuit to a remote prover, see RemoteProver
+	name        string
+	ccsChecksum string
+	curve       ecc.ID
+	// ccs and the proving keys are nil when the circuit is proven remotely
+	ccs     constraint.ConstraintSystem
+	pk      groth16.ProvingKey
+	plonkPk plonk.ProvingKey
+	// vk and plonkVk check the proofs before they leave the relayer, nil if the manifest has no verifying key
+	vk      groth16.VerifyingKey
+	plonkVk plonk.VerifyingKey
+	backend types.ProofBackend
+	proverSettings
+}
+
+// loadCircuit reads the constraint system and proving key of a manifest entry, relative to dir, to be
+// proven with the given settings. With a remote prover it only reads the verifying key.
+func loadCircuit(artifacts *types.CircuitManifest, dir string, settings proverSettings) (*loadedCircuit, error) {
+	curve, err := artifacts.CurveID()
+	if err != nil {
+		return nil, err
+	}
+	if settings.remote != nil {
+		return loadRemoteCircuit(artifacts, dir, curve, settings)
+	}
+	if settings.gpu && (artifacts.Backend == types.BackendPlonk || curve != ecc.BN254) {
+		return nil, fmt.Errorf("%s: GPU proving is only supported for Groth16 on BN254, not %s on %s", artifacts.Name, artifacts.Backend, artifacts.Curve)
+	}
+	if err := artifacts.VerifyArtifacts(dir); err != nil {
+		return nil, err
+	}
+	ccsPath := filepath.Join(dir, artifacts.CCS)
+	pkPath := filepath.Join(dir, artifacts.PK)
 
 	// Load compiled circuit
-	log.Println("Loading Eth2ScUpdateCircuit...")
+	settings.log().Infof("Loading %s (%s, %s)...\n", artifacts.Name, artifacts.Backend, artifacts.Curve)
 	fCcs, err := os.Open(ccsPath)
 	if err != nil {
-		return fmt.Errorf("failed to open CCS file: %w", err)
+		return nil, fmt.Errorf("failed to open CCS file: %w", err)
 	}
 
-	r.ccs = groth16.NewCS(ecc.BN254)
-	_, err = r.ccs.ReadFrom(fCcs)
+	loaded := &loadedCircuit{name: artifacts.Name, ccsChecksum: artifacts.Checksums.CCS, curve: curve,
+		backend: artifacts.Backend, proverSettings: settings}
+	var pk io.ReaderFrom
+	switch artifacts.Backend {
+	case types.BackendPlonk:
+		loaded.ccs = plonk.NewCS(curve)
+		loaded.plonkPk = plonk.NewProvingKey(curve)
+		pk = loaded.plonkPk
+	default:
+		loaded.ccs = groth16.NewCS(curve)
+		loaded.pk = groth16.NewProvingKey(curve)
+		pk = loaded.pk
+	}
+	_, err = loaded.ccs.ReadFrom(fCcs)
 	_ = fCcs.Close()
 	if err != nil {
-		return fmt.Errorf("failed to read CCS: %w", err)
+		return nil, fmt.Errorf("failed to read CCS: %w", err)
 	}
 
-	log.Printf("✓ Circuit loaded: %d constraints\n", r.ccs.GetNbConstraints())
+	if artifacts.Constraints != 0 && artifacts.Constraints != loaded.ccs.GetNbConstraints() {
+		return nil, fmt.Errorf("%s: %d constraints, the manifest records %d", artifacts.Name, loaded.ccs.GetNbConstraints(), artifacts.Constraints)
+	}
+	settings.log().Infof("✓ Circuit loaded: %d constraints\n", loaded.ccs.GetNbConstraints())
 
 	// Load proving key
-	log.Println("Loading proving key...")
+	settings.log().Infof("Loading proving key...")
 	fpk, err := os.Open(pkPath)
 	if err != nil {
-		return fmt.Errorf("failed to open PK file: %w", err)
+		return nil, fmt.Errorf("failed to open PK file: %w", err)
 	}
 
-	r.pk = groth16.NewProvingKey(ecc.BN254)
-	_, err = r.pk.ReadFrom(fpk)
+	_, err = pk.ReadFrom(fpk)
 	_ = fpk.Close()
 	if err != nil {
-		return fmt.Errorf("failed to read PK: %w", err)
+		return nil, fmt.Errorf("failed to read PK: %w", err)
 	}
 
-	log.Println("✓ Proving key loaded")
+	settings.log().Infof("✓ Proving key loaded")
+
+	if err := loaded.loadVerifyingKey(artifacts, dir, curve); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// loadVerifyingKey reads the verifying key of a manifest entry, relative to dir, which checks each
+// proof before it is saved or submitted. Without one the proofs are not checked.
+func (c *loadedCircuit) loadVerifyingKey(artifacts *types.CircuitManifest, dir string, curve ecc.ID) error {
+	if artifacts.VK == "" {
+		c.log().Warnf("%s has no verifying key, its proofs are not verified locally\n", artifacts.Name)
+		return nil
+	}
+	fvk, err := os.Open(filepath.Join(dir, artifacts.VK))
+	if errors.Is(err, os.ErrNotExist) {
+		c.log().Warnf("%s: verifying key %s not found, its proofs are not verified locally\n", artifacts.Name, artifacts.VK)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open VK file: %w", err)
+	}
+	defer fvk.Close()
+
+	var vk io.ReaderFrom
+	switch c.backend {
+	case types.BackendPlonk:
+		c.plonkVk = plonk.NewVerifyingKey(curve)
+		vk = c.plonkVk
+	default:
+		c.vk = groth16.NewVerifyingKey(curve)
+		vk = c.vk
+	}
+	if _, err := vk.ReadFrom(fvk); err != nil {
+		return fmt.Errorf("failed to read VK: %w", err)
+	}
+	c.log().Infof("✓ Verifying key loaded")
 	return nil
 }
 
-// generateProof generates a ZK proof for the given light client update
-// update contains the update to prove
-// Uses r.currentScPubkeys and r.scPubKeysHash
-func (r *Relayer) generateProof(update *types.LightClientUpdate) ([]byte, error) {
-	// Parse sync committee bits from update
-	bits := types.ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits)
+// prove generates a proof of fullWitness in the Solidity format of the circuit's backend, locally or
+// with the remote prover. Proving and verifying are traced as the prove and verify spans, children of
+// the span of ctx.
+func (c *loadedCircuit) prove(ctx context.Context, fullWitness witness.Witness) ([]byte, error) {
+	_, span := tracer().Start(ctx, "prove", trace.WithAttributes(c.attributes()...))
+	var proof any
+	var err error
+	if c.remote != nil {
+		proof, err = c.remote.prove(c, fullWitness)
+	} else {
+		proof, err = c.proveLocally(ctx, fullWitness)
+	}
+	if err != nil {
+		endStage(span, err)
+		return nil, err
+	}
+
+	// Convert to Solidity format
+	_proof, ok := proof.(interface{ MarshalSolidity() []byte })
+	if !ok {
+		err := fmt.Errorf("proof does not implement MarshalSolidity()")
+		endStage(span, err)
+		return nil, err
+	}
+	proofSolidity := _proof.MarshalSolidity()
+	span.SetAttributes(attrProofBytes.Int(len(proofSolidity)))
+	endStage(span, nil)
 
-	// Parse signature (G2 point)
-	sigBytes := update.Data.SyncAggregate.SyncCommitteeSignature[:]
-	var signature bls12381.G2Affine
-	_, err := signature.SetBytes(sigBytes)
+	_, span = tracer().Start(ctx, "verify", trace.WithAttributes(c.attributes()...))
+	err = c.verify(proof, fullWitness)
+	endStage(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to deserialize signature: %w", err)
+		return nil, err
 	}
+	return proofSolidity, nil
+}
 
-	// Create witness
-	witness := &circuit.Eth2ScUpdateCircuit{}
+// attributes are the attributes of the spans of c: its name, backend and constraint count
+func (c *loadedCircuit) attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attrCircuit.String(c.name), attrBackend.String(string(c.backend))}
+	if c.ccs != nil {
+		attrs = append(attrs, attrConstraints.Int(c.ccs.GetNbConstraints()))
+	}
+	return attrs
+}
 
-	// Assign BeaconBlockHeader fields
-	witness.Slot = uint64(update.Data.AttestedHeader.Beacon.Slot)
-	witness.ProposerIndex = uint64(update.Data.AttestedHeader.Beacon.ProposerIndex)
-	for i := 0; i < 32; i++ {
-		witness.ParentRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.ParentRoot[i])
-		witness.StateRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.StateRoot[i])
-		witness.BodyRoot[i] = uints.NewU8(update.Data.AttestedHeader.Beacon.BodyRoot[i])
+// proveLocally generates a proof of fullWitness with the proving key of the circuit, once the proofs
+// running elsewhere leave room for it (see proofGuard)
+func (c *loadedCircuit) proveLocally(ctx context.Context, fullWitness witness.Witness) (any, error) {
+	release, err := c.guard.acquire(ctx, proveMemoryEstimate(c.ccs, c.backend), c.log())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := checkMemoryCeiling(c.ccs, c.backend, c.memoryLimit); err != nil {
+		return nil, err
 	}
+	c.log().Infof("Generating %s proof...\n", c.backend)
+	switch c.backend {
+	case types.BackendPlonk:
+		return plonk.Prove(c.ccs, c.plonkPk, fullWitness,
+			append(c.proverOptions(), solidity.WithProverTargetSolidityVerifier(backend.PLONK))...)
+	default:
+		return groth16.Prove(c.ccs, c.pk, fullWitness,
+			append(c.proverOptions(), backend.WithProverHashToFieldFunction(sha256.New()))...)
+	}
+}
 
-	// Assign sync committee public keys (PRIVATE INPUT)
-	for i := 0; i < 512; i++ {
-		witness.ScPubKeys[i] = sw_bls12381.NewG1Affine(r.currentScPubkeys[i])
+// verify checks proof against the public part of fullWitness with the verifying key, so that corrupted
+// artifacts or a witness the circuit does not constrain as expected fail here rather than on-chain
+func (c *loadedCircuit) verify(proof any, fullWitness witness.Witness) error {
+	if c.vk == nil && c.plonkVk == nil {
+		return nil
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return fmt.Errorf("failed to extract public witness: %w", err)
+	}
+	switch c.backend {
+	case types.BackendPlonk:
+		err = plonk.Verify(proof.(plonk.Proof), c.plonkVk, publicWitness,
+			solidity.WithVerifierTargetSolidityVerifier(backend.PLONK))
+	default:
+		err = groth16.Verify(proof.(groth16.Proof), c.vk, publicWitness,
+			backend.WithVerifierHashToFieldFunction(sha256.New()))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProofVerification, err)
+	}
+	c.log().Infof("✓ Proof verified locally\n")
+	return nil
+}
+
+// circuitArtifacts returns the manifest entry of Eth2ScUpdateCircuit and the directory its paths are
+// relative to. Builds without a manifest are Groth16 on BN254 in ../.build.
+func (r *Relayer) circuitArtifacts() (*types.CircuitManifest, string, error) {
+	manifest, err := types.LoadArtifactManifest(r.config.ManifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &types.CircuitManifest{
+			Name:     "Eth2ScUpdateCircuit",
+			Backend:  types.BackendGroth16,
+			Curve:    ecc.BN254.String(),
+			Verifier: types.VerifierSolidity,
+			CCS:      "Eth2ScUpdateCircuit.ccs",
+			PK:       "Eth2ScUpdateCircuit.pk",
+			VK:       "Eth2ScUpdateCircuit.vk",
+		}, filepath.Join(r.config.RootDir, "../.build"), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := manifest.CheckTarget(r.config.Network, r.config.Preset, r.config.Fork); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", r.config.ManifestPath, err)
+	}
+	artifacts, err := manifest.Circuit("Eth2ScUpdateCircuit")
+	if err != nil {
+		return nil, "", err
 	}
+	if artifacts.Verifier != types.VerifierSolidity {
+		return nil, "", fmt.Errorf("unsupported verifier type %q", artifacts.Verifier)
+	}
+	return artifacts, filepath.Dir(r.config.ManifestPath), nil
+}
 
-	// Use r.scPubKeysHash directly (PUBLIC INPUT)
-	for i := 0; i < 32; i++ {
-		witness.ScPubKeysHash[i] = uints.NewU8(r.scPubKeysHash[i])
+// proofBackend returns the backend of the loaded circuit
+func (r *Relayer) proofBackend() types.ProofBackend {
+	if r.artifacts == nil {
+		return types.BackendGroth16
 	}
+	return r.artifacts.Backend
+}
 
-	// Assign sync committee bits (PUBLIC INPUT)
-	for i := 0; i < 512; i++ {
-		if bits[i] {
-			witness.ScBits[i] = 1
-		} else {
-			witness.ScBits[i] = 0
+// mainCircuit returns the loaded Eth2ScUpdateCircuit, the sc-update circuit of the registry once
+// SetupCircuit registered it
+func (r *Relayer) mainCircuit() *loadedCircuit {
+	if loaded := r.circuits.circuit(CircuitScUpdate); loaded != nil {
+		return loaded
+	}
+	loaded := &loadedCircuit{ccs: r.ccs, pk: r.pk, plonkPk: r.plonkPk, vk: r.vk, plonkVk: r.plonkVk,
+		curve: ecc.BN254, backend: r.proofBackend(), proverSettings: r.proverSettings()}
+	if r.artifacts != nil {
+		loaded.name, loaded.ccsChecksum = r.artifacts.Name, r.artifacts.Checksums.CCS
+		if curve, err := r.artifacts.CurveID(); err == nil {
+			loaded.curve = curve
 		}
 	}
+	return loaded
+}
 
-	// Assign BLS signature
-	witness.AggregatedSig = sw_bls12381.NewG2Affine(signature)
+// generateProof generates a ZK proof of fullWitness, the assignment of the given light client update.
+// The update and witness are quarantined if proving, or the local verification of the proof, fails.
+func (r *Relayer) generateProof(ctx context.Context, update *types.LightClientUpdate, fullWitness witness.Witness) ([]byte, error) {
+	proofSolidity, err := r.mainCircuit().prove(ctx, fullWitness)
+	if errors.Is(err, ErrMemoryCeiling) || errors.Is(err, ErrRemoteProverUnavailable) {
+		// the witness is fine, the update is proven again with more memory, or the prover, available
+		return nil, err
+	}
+	if err != nil {
+		r.quarantine(update, fullWitness)
+		return nil, fmt.Errorf("proof generation failed: %w", err)
+	}
+	r.log().Infof("✓ Proof generated successfully (%d bytes)\n", len(proofSolidity))
 
-	// Assign next_sync_committee root and branch to witness
-	assignNextSyncCommitteeToWitness(update, witness)
+	return proofSolidity, nil
+}
 
-	// Create full witness
-	fullWitness, err := frontend.NewWitness(witness, ecc.BN254.ScalarField())
+// buildWitness assigns the Eth2ScUpdateCircuit witness for the given update, signed by pubkeys
+func (r *Relayer) buildWitness(update *types.LightClientUpdate, pubkeys []bls12381.G1Affine) (*circuit.Eth2ScUpdateCircuit, error) {
+	params, err := r.circuitParams()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create witness: %w", err)
+		return nil, err
 	}
+	return circuitwitness.BuildScUpdateWitnessFromPubKeys(update, pubkeys, params)
+}
+
+// committee is a parsed sync committee and its scPubKeysHash in ScPubKeysHashMode
+type committee struct {
+	sc      *zrntcommon.SyncCommittee
+	pubkeys []bls12381.G1Affine
+	hash    []byte
+}
 
-	// Generate proof
-	log.Println("Generating proof...")
-	proof, err := groth16.Prove(r.ccs, r.pk, fullWitness,
-		backend.WithProverHashToFieldFunction(sha256.New()))
+// parseCommittee parses the pubkeys of sc and computes its scPubKeysHash
+func (r *Relayer) parseCommittee(sc *zrntcommon.SyncCommittee) (*committee, error) {
+	if n := r.config.Preset.SyncCommitteeSize(); len(sc.Pubkeys) != n {
+		return nil, fmt.Errorf("expected %d pubkeys, got %d", n, len(sc.Pubkeys))
+	}
+	pubkeys, err := circuitwitness.ParseSyncCommittee(sc)
 	if err != nil {
-		return nil, fmt.Errorf("proof generation failed: %w", err)
+		return nil, fmt.Errorf("failed to parse %w", err)
 	}
+	for i := range pubkeys {
+		if pubkeys[i].IsInfinity() {
+			r.log().Warnf("sync committee pubkey %d is the point at infinity, it never counts as a participant\n", i)
+		}
+	}
+	hashArray := types.ComputeScPubKeysHashWithMode(pubkeys, r.config.ScPubKeysHashMode)
+	return &committee{sc: sc, pubkeys: pubkeys, hash: hashArray[:]}, nil
+}
 
-	// Convert to Solidity format
-	_proof, ok := proof.(interface{ MarshalSolidity() []byte })
-	if !ok {
-		return nil, fmt.Errorf("proof does not implement MarshalSolidity()")
+// setCurrentCommittee hands the sync committee over: it parses the pubkeys of sc into
+// r.currentScPubkeys and recomputes r.scPubKeysHash
+func (r *Relayer) setCurrentCommittee(sc *zrntcommon.SyncCommittee) error {
+	c, err := r.parseCommittee(sc)
+	if err != nil {
+		return err
 	}
+	r.useCommittee(c)
+	return nil
+}
 
-	proofSolidity := _proof.MarshalSolidity()
-	log.Printf("✓ Proof generated successfully (%d bytes)\n", len(proofSolidity))
+// useCommittee makes c the current committee of the relayer, the one the proofs and transition
+// proofs are made for
+func (r *Relayer) useCommittee(c *committee) {
+	r.currentScPubkeys = c.pubkeys
+	r.scPubKeysHash = c.hash
+	r.currentSc = c.sc
+}
 
-	return proofSolidity, nil
+// quarantine stores the update and the full witness (which holds the private inputs) of a failed proof
+// so it can be investigated later. Files are encrypted when an artifact key is configured.
+func (r *Relayer) quarantine(update *types.LightClientUpdate, fullWitness witness.Witness) {
+	if r.config.QuarantineDir == "" {
+		return
+	}
+	period := r.config.Preset.Period(uint64(update.Data.AttestedHeader.Beacon.Slot))
+	base := filepath.Join(r.config.QuarantineDir, fmt.Sprintf("period-%d", period))
+
+	if updateBlob, err := json.Marshal(update); err != nil {
+		r.log().Errorf("failed to marshal quarantined update: %v\n", err)
+	} else if path, err := WriteSensitiveArtifact(base+"-update.json", updateBlob, r.artifactCipher); err != nil {
+		r.log().Errorf("failed to quarantine update: %v\n", err)
+	} else {
+		r.log().Infof("Update quarantined to %s\n", path)
+	}
+
+	if witnessBlob, err := fullWitness.MarshalBinary(); err != nil {
+		r.log().Errorf("failed to marshal quarantined witness: %v\n", err)
+	} else if path, err := WriteSensitiveArtifact(base+"-witness.bin", witnessBlob, r.artifactCipher); err != nil {
+		r.log().Errorf("failed to quarantine witness: %v\n", err)
+	} else {
+		r.log().Infof("Witness quarantined to %s\n", path)
+	}
 }
 
-// assignNextSyncCommitteeToWitness computes next_sync_committee root and assigns it along with
-// next_sync_committee_branch to the witness
-func assignNextSyncCommitteeToWitness(
-	update *types.LightClientUpdate,
-	witness *circuit.Eth2ScUpdateCircuit,
-) {
-	// Compute next_sync_committee root
-	nextSCRoot := update.Data.NextSyncCommittee.HashTreeRoot(configs.Mainnet, tree.GetHashFn())
-	//log.Printf("next_sync_committee root: %v\n", nextSCRoot.String())
+// circuitParams returns the compile-time params of the loaded circuit, as described by the config:
+// those of Network at Fork if set, with the configured Domain overriding the network's one
+func (r *Relayer) circuitParams() (circuit.CircuitParams, error) {
+	var params circuit.CircuitParams
+	if r.config.Network != "" {
+		network, err := r.config.BeaconNetwork()
+		if err != nil {
+			return params, err
+		}
+		if params, err = circuit.NewNetworkCircuitParams(network, r.config.Fork); err != nil {
+			return params, err
+		}
+	} else {
+		gindex, err := types.NextSyncCommitteeGIndexForFork(r.config.Fork)
+		if err != nil {
+			return params, err
+		}
+		params.NextScGIndex = gindex
+		params.Preset = r.config.Preset
+	}
+	params.ScPubKeysHashMode = r.config.ScPubKeysHashMode
+	params.PublicStateRoot = r.config.PublicStateRoot
+	if r.config.Domain != ([32]byte{}) {
+		params.Domain = r.config.Domain
+	}
+	return params, nil
+}
 
-	// Assign next_sync_committee root (public input)
-	for i := 0; i < 32; i++ {
-		witness.NextScRoot[i] = uints.NewU8(nextSCRoot[i])
+// validateUpdate natively checks the parts of the update that the circuit would reject,
+// so a broken update from the beacon node fails fast instead of failing at proving time.
+// sc is the committee signing the update, nil to skip the participation check.
+func (r *Relayer) validateUpdate(update *types.LightClientUpdate, sc *zrntcommon.SyncCommittee) error {
+	params, err := r.circuitParams()
+	if err != nil {
+		return err
+	}
+	gindex := params.NextSyncCommitteeGIndex()
+	if update.Version != "" {
+		updateGIndex, err := types.NextSyncCommitteeGIndexForFork(update.Version)
+		if err != nil {
+			return err
+		}
+		if updateGIndex != gindex {
+			return fmt.Errorf("update is for fork %s, the circuit was compiled for fork %s", update.Version, r.config.Fork)
+		}
 	}
 
-	// Assign next_sync_committee_branch (private input)
-	for i := 0; i < 6; i++ {
-		for j := 0; j < 32; j++ {
-			witness.NextScBranch[i][j] = uints.NewU8(update.Data.NextSyncCommitteeBranch[i][j])
+	// The circuit requires at least one participant whose pubkey is not the point at infinity
+	if sc != nil {
+		bits := types.ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits)
+		if _, _, err := types.AggregatePublicKeys(sc.Pubkeys, bits); err != nil {
+			return fmt.Errorf("sync aggregate is not provable: %w", err)
 		}
 	}
+
+	nextSCRoot := types.SyncCommitteeRoot(&update.Data.NextSyncCommittee)
+	if !types.VerifySSZBranch(
+		update.Data.AttestedHeader.Beacon.StateRoot,
+		nextSCRoot,
+		update.Data.NextSyncCommitteeBranch,
+		gindex,
+	) {
+		return fmt.Errorf("next_sync_committee branch does not match state root %v", update.Data.AttestedHeader.Beacon.StateRoot)
+	}
+
+	if _, err := types.ExecutionFieldProof(update, types.ExecutionBlockHashGIndex); err != nil {
+		return fmt.Errorf("execution block hash is not provable: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,407 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCircuitChecksums(t *testing.T) {
+	dir := t.TempDir()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	writeSetup := func(name string) {
+		pk, vk, err := groth16.Setup(ccs)
+		require.NoError(t, err)
+		for file, v := range map[string]interface {
+			WriteTo(w io.Writer) (int64, error)
+		}{name + ".ccs": ccs, name + ".pk": pk, name + ".vk": vk} {
+			f, err := os.Create(filepath.Join(dir, file))
+			require.NoError(t, err)
+			_, err = v.WriteTo(f)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+		}
+	}
+	writeSetup("Square")
+	artifacts := &types.CircuitManifest{Name: "Square", Backend: types.BackendGroth16, Curve: ecc.BN254.String(),
+		CCS: "Square.ccs", PK: "Square.pk", VK: "Square.vk", Constraints: ccs.GetNbConstraints()}
+	require.NoError(t, artifacts.SetChecksums(dir))
+	_, err = loadCircuit(artifacts, dir, proverSettings{})
+	require.NoError(t, err)
+
+	// the keys of another setup of the same circuit
+	writeSetup("Other")
+	require.NoError(t, os.Rename(filepath.Join(dir, "Other.pk"), filepath.Join(dir, "Square.pk")))
+	_, err = loadCircuit(artifacts, dir, proverSettings{})
+	require.Error(t, err)
+
+	// a constraint system which is not the one of the manifest
+	require.NoError(t, artifacts.SetChecksums(dir))
+	artifacts.Constraints++
+	_, err = loadCircuit(artifacts, dir, proverSettings{})
+	require.Error(t, err)
+}
+
+func TestNetworkProfile(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "provers")
+	build := filepath.Join(root, "../.build")
+
+	// without a network, the relayer follows Sepolia with the artifacts of .build
+	config := cfgtypes.NewConfig("--root", root, "--log-level", "disabled")
+	require.Equal(t, types.NetworkSepolia.BeaconAPI, config.RPCEndpoint)
+	require.Equal(t, filepath.Join(build, types.ManifestFileName), config.ManifestPath)
+
+	// a network selects its preset, slot duration, domain, beacon API and artifacts
+	manifest := &types.ArtifactManifest{Network: "holesky", Preset: "mainnet", Fork: "fulu"}
+	require.NoError(t, manifest.Save(filepath.Join(build, "holesky", types.ManifestFileName)))
+	config = cfgtypes.NewConfig("--root", root, "--log-level", "disabled", "--network", "holesky")
+	require.Equal(t, types.NetworkHolesky.BeaconAPI, config.RPCEndpoint)
+	require.Equal(t, filepath.Join(build, "holesky", types.ManifestFileName), config.ManifestPath)
+	require.Equal(t, 12*time.Second, config.SlotDuration)
+	domain, err := types.NetworkHolesky.SyncCommitteeDomain("fulu")
+	require.NoError(t, err)
+	require.Equal(t, domain, config.Domain)
+
+	// but not artifacts built for another fork
+	config = cfgtypes.NewConfig("--root", root, "--log-level", "disabled", "--network", "holesky", "--fork", "electra")
+	_, _, err = (&Relayer{config: config}).circuitArtifacts()
+	require.ErrorContains(t, err, "built for fulu")
+
+	// a devnet needs its genesis
+	require.Panics(t, func() { cfgtypes.NewConfig("--network", "devnet") })
+	config = cfgtypes.NewConfig("--root", root, "--log-level", "disabled", "--network", "devnet", "--rpc", "http://localhost:5052",
+		"--genesis-validators-root", "0x0100000000000000000000000000000000000000000000000000000000000000", "--fork-version", "0x60000038")
+	require.Equal(t, types.PresetMinimal, config.Preset)
+	require.Equal(t, 6*time.Second, config.SlotDuration)
+	require.Equal(t, "http://localhost:5052", config.RPCEndpoint)
+	params, err := (&Relayer{config: config}).circuitParams()
+	require.NoError(t, err)
+	require.NotZero(t, params.Domain)
+	require.Equal(t, config.Domain, params.Domain)
+}
+
+func TestInvalidEnvConfig(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("SC_HASH_MODE", "full")
+	config := cfgtypes.NewConfig("--root", root, "--log-level", "disabled")
+	require.Equal(t, types.ScPubKeysHashFull, config.ScPubKeysHashMode)
+
+	// a mistyped mode is not replaced by the truncated one, it fails like the flag
+	t.Setenv("SC_HASH_MODE", "ful")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	_, err := config.Reload()
+	require.ErrorContains(t, err, "SC_HASH_MODE")
+	t.Setenv("SC_HASH_MODE", "")
+	t.Setenv("TRANSITION_SC_HASH_MODE", "ful")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("TRANSITION_SC_HASH_MODE", "")
+
+	t.Setenv("PRESET", "minimal")
+	require.Equal(t, types.PresetMinimal, cfgtypes.NewConfig("--root", root, "--log-level", "disabled").Preset)
+	t.Setenv("PRESET", "minimall")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("PRESET", "")
+
+	// a mistyped trusted root does not fall back to trusting the InitPeriod update
+	t.Setenv("TRUSTED_BLOCK_ROOT", "0x01")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("TRUSTED_BLOCK_ROOT", "")
+
+	t.Setenv("DOMAIN", "0x07000000")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("DOMAIN", "")
+
+	// a mistyped genesis, fork or fork version does not select the domain of another network
+	t.Setenv("GENESIS_VALIDATORS_ROOT", "0xzz")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("GENESIS_VALIDATORS_ROOT", "")
+	t.Setenv("FORK_VERSION", "0x0600")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("FORK_VERSION", "")
+	t.Setenv("FORK", "fuluu")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("FORK", "deneb")
+	require.Equal(t, "deneb", cfgtypes.NewConfig("--root", root, "--log-level", "disabled").Fork)
+
+	// a mistyped fee cap does not leave the fees uncapped, nor a mistyped delay replace on every poll
+	t.Setenv("MAX_FEE_GWEI", "50")
+	require.Equal(t, 50.0, cfgtypes.NewConfig("--root", root, "--log-level", "disabled").MaxFeeGwei)
+	t.Setenv("MAX_FEE_GWEI", "50gwei")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("MAX_FEE_GWEI", "-1")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("MAX_FEE_GWEI", "")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled", "--max-fee-gwei", "50gwei") })
+	t.Setenv("MAX_TIP_GWEI", "two")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("MAX_TIP_GWEI", "")
+	t.Setenv("STUCK_AFTER", "3")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("STUCK_AFTER", "")
+	t.Setenv("FEE_BUMP_PERCENT", "20%")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("FEE_BUMP_PERCENT", "")
+
+	// a mistyped action does not fall back to alerting
+	t.Setenv("GAS_BUDGET_ACTION", "skip")
+	require.Equal(t, GasActionSkip, cfgtypes.NewConfig("--root", root, "--log-level", "disabled").GasBudgetAction)
+	t.Setenv("GAS_BUDGET_ACTION", "skipp")
+	require.Panics(t, func() { cfgtypes.NewConfig("--root", root, "--log-level", "disabled") })
+	t.Setenv("GAS_BUDGET_ACTION", "")
+}
+
+func TestLocalProofVerification(t *testing.T) {
+	dir := t.TempDir()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+	for file, v := range map[string]io.WriterTo{"Square.ccs": ccs, "Square.pk": pk, "Square.vk": vk} {
+		f, err := os.Create(filepath.Join(dir, file))
+		require.NoError(t, err)
+		_, err = v.WriteTo(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	artifacts := &types.CircuitManifest{Name: "Square", Backend: types.BackendGroth16, Curve: ecc.BN254.String(),
+		CCS: "Square.ccs", PK: "Square.pk", VK: "Square.vk"}
+	loaded, err := loadCircuit(artifacts, dir, proverSettings{})
+	require.NoError(t, err)
+	require.NotNil(t, loaded.vk)
+	fullWitness, err := frontend.NewWitness(&squareCircuit{X: 1, Y: 1}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	_, err = loaded.prove(context.Background(), fullWitness)
+	require.NoError(t, err)
+
+	// the proofs of a proving key the verifying key is not the one of are rejected
+	_, loaded.vk, err = groth16.Setup(ccs)
+	require.NoError(t, err)
+	_, err = loaded.prove(context.Background(), fullWitness)
+	require.ErrorIs(t, err, ErrProofVerification)
+
+	// without a verifying key the proofs are not checked
+	require.NoError(t, os.Remove(filepath.Join(dir, "Square.vk")))
+	loaded, err = loadCircuit(artifacts, dir, proverSettings{})
+	require.NoError(t, err)
+	require.Nil(t, loaded.vk)
+	_, err = loaded.prove(context.Background(), fullWitness)
+	require.NoError(t, err)
+}
+
+// cancellingFetcher serves update for the initial period and cancels the run on the next fetch
+type cancellingFetcher struct {
+	update  *types.LightClientUpdate
+	cancel  context.CancelFunc
+	periods []uint64
+}
+
+func (f *cancellingFetcher) ScUpdate(ctx context.Context, period uint64) (*types.LightClientUpdate, error) {
+	f.periods = append(f.periods, period)
+	if len(f.periods) == 1 {
+		return f.update, nil
+	}
+	f.cancel()
+	return nil, ctx.Err()
+}
+
+func (f *cancellingFetcher) Block(context.Context, uint64) (*cfgtypes.BlockAPIResponse, error) {
+	return nil, errors.New("no blocks")
+}
+
+func TestRunCancel(t *testing.T) {
+	update, _ := loadTestSubmission(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	fetcher := &cancellingFetcher{update: update, cancel: cancel}
+	r := &Relayer{config: &cfgtypes.Config{InitPeriod: 1104}, fetcher: fetcher}
+
+	// the wait after the failed fetch is interrupted and Run returns cleanly
+	start := time.Now()
+	require.NoError(t, r.Run(ctx))
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, []uint64{1104, 1105}, fetcher.periods)
+
+	// a run cancelled before it starts proves nothing
+	fetcher.periods = nil
+	require.NoError(t, r.Run(ctx))
+	require.Len(t, fetcher.periods, 1)
+}
+
+// periodFetcher serves the recorded updates of data/ and fails for the other periods
+type periodFetcher struct {
+	updates map[uint64]*types.LightClientUpdate
+}
+
+func (f *periodFetcher) ScUpdate(_ context.Context, period uint64) (*types.LightClientUpdate, error) {
+	if update, ok := f.updates[period]; ok {
+		return update, nil
+	}
+	return nil, fmt.Errorf("no update for period %d", period)
+}
+
+func (f *periodFetcher) Block(context.Context, uint64) (*cfgtypes.BlockAPIResponse, error) {
+	return nil, errors.New("no blocks")
+}
+
+// loadTestUpdates reads the recorded updates of periods 1104 and 1105 from data/
+func loadTestUpdates(t *testing.T) map[uint64]*types.LightClientUpdate {
+	updates := map[uint64]*types.LightClientUpdate{}
+	for _, period := range []uint64{1104, 1105} {
+		data, err := os.ReadFile(filepath.Join("..", "data", fmt.Sprintf("sc-update-%d.json", period)))
+		require.NoError(t, err)
+		var update types.LightClientUpdate
+		require.NoError(t, json.Unmarshal(data, &update))
+		updates[period] = &update
+	}
+	return updates
+}
+
+func TestPrepareStage(t *testing.T) {
+	updates := loadTestUpdates(t)
+	r := &Relayer{config: cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled"), fetcher: &periodFetcher{updates: updates}}
+	signers, err := r.parseCommittee(&updates[1104].Data.NextSyncCommittee)
+	require.NoError(t, err)
+
+	// period 1105 is assigned ahead of its proof, signed by the committee of 1104
+	ctx, cancel := context.WithCancel(context.Background())
+	prepared := make(chan *preparedPeriod, pipelineDepth)
+	go r.prepare(ctx, 1105, signers, prepared)
+	job := <-prepared
+	require.NoError(t, job.err)
+	require.Equal(t, uint64(1105), job.period)
+	require.Same(t, signers, job.signers)
+	require.NotNil(t, job.fullWitness)
+	require.Equal(t, &updates[1105].Data.NextSyncCommittee, job.next.sc)
+
+	// period 1106 is not available yet, the stage retries until it is stopped
+	cancel()
+	_, ok := <-prepared
+	require.False(t, ok)
+
+	// an update that cannot be assigned for the preset stops the stage
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	prepared = make(chan *preparedPeriod, pipelineDepth)
+	r.config.Preset = types.PresetMinimal
+	go r.prepare(ctx, 1105, signers, prepared)
+	job = <-prepared
+	require.Error(t, job.err)
+	_, ok = <-prepared
+	require.False(t, ok)
+}
+
+func TestPrepareOnce(t *testing.T) {
+	updates := loadTestUpdates(t)
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled", "--once")
+	require.True(t, config.Once)
+	r := &Relayer{config: config, fetcher: &periodFetcher{updates: updates}}
+	signers, err := r.parseCommittee(&updates[1104].Data.NextSyncCommittee)
+	require.NoError(t, err)
+
+	// a single period is prepared
+	prepared := make(chan *preparedPeriod, pipelineDepth)
+	go r.prepare(context.Background(), 1105, signers, prepared)
+	job := <-prepared
+	require.NoError(t, job.err)
+	require.Equal(t, uint64(1105), job.period)
+	_, ok := <-prepared
+	require.False(t, ok)
+
+	// a period without an update is not waited for
+	prepared = make(chan *preparedPeriod, pipelineDepth)
+	go r.prepare(context.Background(), 1106, signers, prepared)
+	job = <-prepared
+	require.ErrorIs(t, job.err, ErrUpdateUnavailable)
+	_, ok = <-prepared
+	require.False(t, ok)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, ExitProved, onceExitCode(context.Background(), nil))
+	require.Equal(t, ExitNotReady, onceExitCode(context.Background(), fmt.Errorf("failed to generate proof: %w", job.err)))
+	require.Equal(t, ExitFailed, onceExitCode(context.Background(), errors.New("invalid update")))
+	require.Equal(t, ExitFailed, onceExitCode(cancelled, nil))
+}
+
+// bootstrapFetcher serves the recorded bootstrap of data/ for any block root
+type bootstrapFetcher struct {
+	periodFetcher
+}
+
+func (f *bootstrapFetcher) Bootstrap(context.Context, zrntcommon.Root) (*types.LightClientBootstrap, error) {
+	data, err := os.ReadFile(filepath.Join("..", "data", "bootstrap-1105.json"))
+	if err != nil {
+		return nil, err
+	}
+	var bootstrap types.LightClientBootstrap
+	return &bootstrap, json.Unmarshal(data, &bootstrap)
+}
+
+func TestBootstrap(t *testing.T) {
+	updates := loadTestUpdates(t)
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled")
+	config.TrustedBlockRoot = updates[1105].Data.AttestedHeader.Beacon.HashTreeRoot(tree.GetHashFn())
+	r := &Relayer{config: config, fetcher: &bootstrapFetcher{periodFetcher{updates: updates}}}
+
+	// the committee of the trusted block's period, the one signing its update
+	signers, period, err := r.bootstrap(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1105), period)
+	require.Equal(t, &updates[1104].Data.NextSyncCommittee, signers.sc)
+	require.NoError(t, r.validateUpdate(updates[1105], signers.sc))
+
+	// the bootstrap of another block is rejected
+	r.config.TrustedBlockRoot = updates[1104].Data.AttestedHeader.Beacon.HashTreeRoot(tree.GetHashFn())
+	_, _, err = r.bootstrap(context.Background())
+	require.ErrorContains(t, err, "invalid bootstrap")
+
+	r.fetcher = &periodFetcher{updates: updates}
+	_, _, err = r.bootstrap(context.Background())
+	require.Error(t, err)
+}
+
+// minimalUpdate turns the recorded update of period into one of a minimal preset chain: its committees keep
+// their first 32 members, and the attested state root is the one their next_sync_committee branch leads to
+func minimalUpdate(t *testing.T, updates map[uint64]*types.LightClientUpdate, period uint64) *types.LightClientUpdate {
+	size := int(types.PresetMinimal.Spec().SYNC_COMMITTEE_SIZE)
+	update := updates[period]
+	update.Data.NextSyncCommittee.Pubkeys = update.Data.NextSyncCommittee.Pubkeys[:size]
+	update.Data.SyncAggregate.SyncCommitteeBits = update.Data.SyncAggregate.SyncCommitteeBits[:2+size/4]
+	gindex, err := types.NextSyncCommitteeGIndexForFork(update.Version)
+	require.NoError(t, err)
+	update.Data.AttestedHeader.Beacon.StateRoot, err = types.ComputeSSZBranchRoot(
+		types.SyncCommitteeRoot(&update.Data.NextSyncCommittee), update.Data.NextSyncCommitteeBranch, gindex)
+	require.NoError(t, err)
+	return update
+}
+
+func TestValidateUpdateMinimalPreset(t *testing.T) {
+	updates := loadTestUpdates(t)
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled")
+	config.Preset = types.PresetMinimal
+	r := &Relayer{config: config}
+	signers := minimalUpdate(t, updates, 1104).Data.NextSyncCommittee
+	update := minimalUpdate(t, updates, 1105)
+
+	// the 32-member committees are hashed as such
+	require.NoError(t, r.validateUpdate(update, &signers))
+	update.Data.NextSyncCommittee.Pubkeys[0][1] ^= 1
+	require.ErrorContains(t, r.validateUpdate(update, &signers), "branch")
+}
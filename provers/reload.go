@@ -0,0 +1,77 @@
+package relayer
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+// Reload applies the beacon endpoints, log level, gas settings and destinations of next to the running
+// relayer, without loading its circuits again: a new log level and beacon endpoints take effect on the
+// next message and request, new destinations and gas settings on the next proof. The other settings
+// need a restart. Nothing is applied if a beacon endpoint or destination of next is invalid.
+func (r *Relayer) Reload(next *cfgtypes.Config) error {
+	next.Logger = r.config.Logger
+	fetcher := r.fetcher
+	if recording, ok := fetcher.(*RecordingFetcher); ok {
+		fetcher = recording.fetcher
+	}
+	beacon, _ := fetcher.(*MultiFetcher)
+	endpoints := next.BeaconEndpoints()
+	var set *beaconEndpoints
+	if beacon != nil {
+		var err error
+		if set, err = newBeaconEndpoints(endpoints, next.BeaconQuorum, beacon.logger); err != nil {
+			return err
+		}
+	} else if !slices.Equal(endpoints, r.config.BeaconEndpoints()) || next.BeaconQuorum != r.config.BeaconQuorum {
+		r.log().Warnf("the beacon endpoints of this fetcher cannot be reloaded, restart the relayer to change them\n")
+	}
+	gasEstimator, lightClient, destinations, err := connectDestinations(next)
+	if err != nil {
+		return err
+	}
+
+	if setter, ok := r.log().(cfgtypes.LevelSetter); ok {
+		if err := setter.SetLevel(next.LogLevel); err != nil {
+			r.log().Warnf("log level not reloaded: %v\n", err)
+		}
+	}
+	if set != nil {
+		beacon.endpoints.Store(set)
+	}
+	r.targetsMu.Lock()
+	r.gasEstimator, r.lightClient, r.destinations = gasEstimator, lightClient, destinations
+	r.config.DestinationRPC, r.config.LightClientAddress = next.DestinationRPC, next.LightClientAddress
+	r.config.SubmitterAddress, r.config.GasLimit, r.config.GasBudgetAction = next.SubmitterAddress, next.GasLimit, next.GasBudgetAction
+	r.targetsMu.Unlock()
+	r.log().Infof("Configuration reloaded: %d beacon endpoints, %d destinations, log level %s\n", len(endpoints), len(destinations), next.LogLevel)
+	return nil
+}
+
+// reloadOnSignal reloads the configuration of the relayer (see Config.Reload and Reload) on each SIGHUP,
+// until ctx is cancelled. An invalid configuration is logged and the current one kept.
+func (r *Relayer) reloadOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		r.log().Infof("SIGHUP received, reloading the configuration\n")
+		next, err := r.config.Reload()
+		if err == nil {
+			err = r.Reload(next)
+		}
+		if err != nil {
+			r.log().Errorf("failed to reload the configuration, keeping the current one: %v\n", err)
+		}
+	}
+}
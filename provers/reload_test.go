@@ -0,0 +1,111 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := cfgtypes.NewLogger(&logs, "info")
+	require.NoError(t, err)
+	envFile := filepath.Join(t.TempDir(), "relayer.env")
+	require.NoError(t, os.WriteFile(envFile, []byte("RPC_ENDPOINT=http://a:5052\n"), 0644))
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--env-file", envFile)
+	require.Equal(t, "http://a:5052", config.RPCEndpoint)
+	config.Logger = logger
+	fetcher, err := NewMultiFetcher(config.BeaconEndpoints(), config.BeaconQuorum, logger)
+	require.NoError(t, err)
+	r := &Relayer{config: config, fetcher: fetcher, destinations: []*destination{{name: defaultDestination}}}
+
+	// the env file is read again
+	require.NoError(t, os.WriteFile(envFile, []byte(`# rotated
+RPC_ENDPOINT=http://b:5052
+export RPC_ENDPOINTS="http://c:5052"
+BEACON_QUORUM=2
+LOG_LEVEL='debug'
+GAS_LIMIT=5000000
+`), 0644))
+	next, err := config.Reload()
+	require.NoError(t, err)
+	require.NoError(t, r.Reload(next))
+
+	endpoints := fetcher.endpoints.Load()
+	require.Equal(t, 2, endpoints.quorum)
+	require.Equal(t, "http://b:5052", endpoints.fetchers[0].BaseURL)
+	require.Equal(t, "http://c:5052", endpoints.fetchers[1].BaseURL)
+	require.Empty(t, r.destinationList())
+	require.Equal(t, uint64(5_000_000), r.config.GasLimit)
+	logger.Debugf("debug message")
+	require.Contains(t, logs.String(), "debug message")
+
+	// an invalid configuration is not applied
+	next, err = cfgtypes.NewConfig("--rpc", "http://d:5052", "--beacon-quorum", "2").Reload()
+	require.NoError(t, err)
+	require.Error(t, r.Reload(next))
+	require.Same(t, endpoints, fetcher.endpoints.Load())
+
+	_, err = (&cfgtypes.Config{Args: []string{"--log-level", "verbose"}}).Reload()
+	require.Error(t, err)
+	require.NoError(t, os.WriteFile(envFile, []byte("RPC_ENDPOINT\n"), 0644))
+	_, err = config.Reload()
+	require.Error(t, err)
+}
+
+func TestSubmitToReloadedDestination(t *testing.T) {
+	config := &cfgtypes.Config{ProofDir: t.TempDir(), RetryMaxAttempts: 1}
+	r := &Relayer{config: config}
+	var backends []*chainBackend
+	var destinations []*destination
+	for range 2 {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		backend := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100,
+			revertPrefix: 0xff, receipts: map[common.Hash]*gethtypes.Receipt{}}
+		s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), common.HexToAddress("0x01"), 0, 1)
+		require.NoError(t, err)
+		s.pollInterval = time.Millisecond
+		backends = append(backends, backend)
+		destinations = append(destinations, &destination{name: defaultDestination, submitter: s})
+	}
+	r.destinations = destinations[:1]
+
+	update, proofData := loadTestSubmission(t)
+	submissions := make(chan submission)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.submitStage(submissions)
+	}()
+	submit := func(period uint64) string {
+		proofPath := filepath.Join(config.ProofDir, proofFileName(period))
+		require.NoError(t, os.WriteFile(proofPath, []byte("{}"), 0644))
+		submissions <- submission{update: update, proofData: proofData, proofPath: proofPath, circuit: CircuitScUpdate}
+		return proofPath
+	}
+
+	// the proofs following a reload go to the destination replacing the previous one
+	proofPath := submit(1105)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(proofPath + submittedSuffix)
+		return err == nil
+	}, 5*time.Second, time.Millisecond)
+	r.targetsMu.Lock()
+	r.destinations = destinations[1:]
+	r.targetsMu.Unlock()
+	submit(1106)
+	close(submissions)
+	<-done
+	require.Len(t, backends[0].sent, 1)
+	require.Len(t, backends[1].sent, 1)
+}
@@ -0,0 +1,192 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/kysee/zk-chains/provers/server/proverpb"
+	"github.com/kysee/zk-chains/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// remoteProofTimeout bounds one proof of the remote prover, queueing included
+const remoteProofTimeout = time.Hour
+
+var (
+	// ErrRemoteProverUnavailable is returned when the remote prover could not prove a witness for now:
+	// it is unreachable, busy, or the proof was corrupted on the way. The proof is retried.
+	ErrRemoteProverUnavailable = errors.New("remote prover unavailable")
+	// ErrWitnessRejected is returned by ProveWitness for a witness it cannot prove: malformed, or of a
+	// circuit the prover does not hold
+	ErrWitnessRejected = errors.New("witness rejected")
+)
+
+// RemoteProver proves the witnesses of a relayer with the proving keys of a serve command, so that the
+// relayer, which follows the beacon chain and assigns the witnesses, runs on a small instance. The
+// witness holds the private inputs of the proof: the connection is not encrypted, the prover must be
+// reached over a private network.
+type RemoteProver struct {
+	addr   string
+	conn   *grpc.ClientConn
+	client proverpb.ProverClient
+}
+
+// DialRemoteProver returns the RemoteProver served at addr, the connection is made on the first proof
+func DialRemoteProver(addr string) (*RemoteProver, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote prover %s: %w", addr, err)
+	}
+	return &RemoteProver{addr: addr, conn: conn, client: proverpb.NewProverClient(conn)}, nil
+}
+
+// Close closes the connection to the prover
+func (p *RemoteProver) Close() error {
+	if p.conn == nil {
+		return nil
+	}
+	return p.conn.Close()
+}
+
+// prove ships fullWitness, a witness of c, to the prover and returns the proof it generated. Both ways
+// are checked against their SHA-256, the proof is verified by the caller with the verifying key of c.
+func (p *RemoteProver) prove(c *loadedCircuit, fullWitness witness.Witness) (any, error) {
+	witnessBlob, err := fullWitness.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal witness: %w", err)
+	}
+	witnessDigest := sha256.Sum256(witnessBlob)
+	c.log().Infof("Sending the %s witness (%d bytes) to %s...\n", c.name, len(witnessBlob), p.addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteProofTimeout)
+	defer cancel()
+	resp, err := p.client.ProveWitness(ctx, &proverpb.ProveWitnessRequest{
+		Circuit:       c.name,
+		CcsChecksum:   c.ccsChecksum,
+		Witness:       witnessBlob,
+		WitnessSha256: witnessDigest[:],
+	})
+	if err != nil {
+		return nil, p.error(err)
+	}
+	if backend := types.ProofBackend(resp.Backend); backend != c.backend {
+		return nil, fmt.Errorf("remote prover %s: %s proof of a %s circuit", p.addr, backend, c.backend)
+	}
+	if proofDigest := sha256.Sum256(resp.Proof); !bytes.Equal(proofDigest[:], resp.ProofSha256) {
+		return nil, fmt.Errorf("%w: proof of %s does not match its sha256", ErrRemoteProverUnavailable, p.addr)
+	}
+
+	var proof io.ReaderFrom
+	switch c.backend {
+	case types.BackendPlonk:
+		proof = plonk.NewProof(c.curve)
+	default:
+		proof = groth16.NewProof(c.curve)
+	}
+	if _, err := proof.ReadFrom(bytes.NewReader(resp.Proof)); err != nil {
+		return nil, fmt.Errorf("failed to read proof of %s: %w", p.addr, err)
+	}
+	c.log().Infof("✓ Proof received from %s\n", p.addr)
+	return proof, nil
+}
+
+// error wraps the error of a call to the prover, with ErrRemoteProverUnavailable if it is worth retrying
+func (p *RemoteProver) error(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		return fmt.Errorf("%w: %s: %w", ErrRemoteProverUnavailable, p.addr, err)
+	default:
+		return fmt.Errorf("remote prover %s: %w", p.addr, err)
+	}
+}
+
+// loadRemoteCircuit reads the verifying key of a manifest entry, relative to dir, to check the proofs of
+// the remote prover of settings. The verifying key is required: the proofs are not trusted otherwise.
+func loadRemoteCircuit(artifacts *types.CircuitManifest, dir string, curve ecc.ID, settings proverSettings) (*loadedCircuit, error) {
+	if err := artifacts.VerifyVerifyingKey(dir); err != nil {
+		return nil, err
+	}
+	settings.log().Infof("%s (%s, %s) is proven by %s\n", artifacts.Name, artifacts.Backend, artifacts.Curve, settings.remote.addr)
+	loaded := &loadedCircuit{name: artifacts.Name, ccsChecksum: artifacts.Checksums.CCS, curve: curve,
+		backend: artifacts.Backend, proverSettings: settings}
+	if err := loaded.loadVerifyingKey(artifacts, dir, curve); err != nil {
+		return nil, err
+	}
+	if loaded.vk == nil && loaded.plonkVk == nil {
+		return nil, fmt.Errorf("%s: proving remotely needs the verifying key to check the proofs", artifacts.Name)
+	}
+	return loaded, nil
+}
+
+// ProveWitness proves witnessBlob, a full witness of the named circuit serialized with MarshalBinary, for
+// a relayer proving remotely. ccsChecksum is the checksum of the constraint system the relayer assigned
+// the witness for, if it has one. It returns the backend of the circuit and the proof serialized with
+// its WriteTo. Proofs are generated one at a time, along with the on-demand ones.
+func (r *Relayer) ProveWitness(ctx context.Context, circuitName, ccsChecksum string, witnessBlob []byte) (types.ProofBackend, []byte, error) {
+	c := r.circuitByName(circuitName)
+	if c == nil || c.ccs == nil {
+		return "", nil, fmt.Errorf("%w: circuit %s is not loaded with its proving key", ErrWitnessRejected, circuitName)
+	}
+	if ccsChecksum != "" && c.ccsChecksum != "" && ccsChecksum != c.ccsChecksum {
+		return "", nil, fmt.Errorf("%w: constraint system %s of %s, the prover has %s", ErrWitnessRejected, ccsChecksum, circuitName, c.ccsChecksum)
+	}
+	fullWitness, err := witness.New(c.ccs.Field())
+	if err != nil {
+		return "", nil, err
+	}
+	if err := fullWitness.UnmarshalBinary(witnessBlob); err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrWitnessRejected, err)
+	}
+
+	select {
+	case r.proverSlot <- struct{}{}:
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	}
+	defer func() { <-r.proverSlot }()
+
+	r.log().Infof("Generating remote proof of %s\n", circuitName)
+	proof, err := c.proveLocally(ctx, fullWitness)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := c.verify(proof, fullWitness); err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := proof.(io.WriterTo).WriteTo(&buf); err != nil {
+		return "", nil, fmt.Errorf("failed to serialize proof: %w", err)
+	}
+	return c.backend, buf.Bytes(), nil
+}
+
+// circuitByName returns the loaded circuit named name in the artifact manifest, nil if none is
+func (r *Relayer) circuitByName(name string) *loadedCircuit {
+	if main := r.mainCircuit(); main.name == name {
+		return main
+	}
+	if c := r.circuits.byName(name); c != nil {
+		return c
+	}
+	if r.transition != nil && r.transition.name == name {
+		return r.transition
+	}
+	for _, c := range r.modeCircuits {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package relayer
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/kysee/zk-chains/provers/server/proverpb"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// localProverClient serves ProveWitness calls with the circuits of a relayer, flipping a bit of the
+// proofs if corrupt is set
+type localProverClient struct {
+	proverpb.ProverClient
+	r       *Relayer
+	corrupt bool
+}
+
+func (c *localProverClient) ProveWitness(ctx context.Context, req *proverpb.ProveWitnessRequest, _ ...grpc.CallOption) (*proverpb.ProveWitnessResponse, error) {
+	backend, proof, err := c.r.ProveWitness(ctx, req.Circuit, req.CcsChecksum, req.Witness)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(proof)
+	if c.corrupt {
+		proof[len(proof)-1] ^= 1
+	}
+	return &proverpb.ProveWitnessResponse{Backend: string(backend), Proof: proof, ProofSha256: digest[:]}, nil
+}
+
+func TestRemoteProver(t *testing.T) {
+	dir := t.TempDir()
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+	for file, v := range map[string]io.WriterTo{"Square.ccs": ccs, "Square.pk": pk, "Square.vk": vk} {
+		f, err := os.Create(filepath.Join(dir, file))
+		require.NoError(t, err)
+		_, err = v.WriteTo(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	artifacts := &types.CircuitManifest{Name: "Square", Backend: types.BackendGroth16, Curve: ecc.BN254.String(),
+		CCS: "Square.ccs", PK: "Square.pk", VK: "Square.vk"}
+	require.NoError(t, artifacts.SetChecksums(dir))
+
+	// the proving server holds the constraint system and the proving key
+	loaded, err := loadCircuit(artifacts, dir, proverSettings{})
+	require.NoError(t, err)
+	server := &Relayer{config: &cfgtypes.Config{}, artifacts: artifacts, proverSlot: make(chan struct{}, 1),
+		ccs: loaded.ccs, pk: loaded.pk, vk: loaded.vk}
+
+	// the relayer holds only the verifying key
+	relayerDir := t.TempDir()
+	vkBlob, err := os.ReadFile(filepath.Join(dir, "Square.vk"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(relayerDir, "Square.vk"), vkBlob, 0644))
+	client := &localProverClient{r: server}
+	remote, err := loadCircuit(artifacts, relayerDir, proverSettings{remote: &RemoteProver{addr: "prover", client: client}})
+	require.NoError(t, err)
+	require.Nil(t, remote.ccs)
+
+	fullWitness, err := frontend.NewWitness(&squareCircuit{X: 1, Y: 1}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	proof, err := remote.prove(context.Background(), fullWitness)
+	require.NoError(t, err)
+	require.NotEmpty(t, proof)
+
+	// a proof corrupted on the way is retried
+	client.corrupt = true
+	_, err = remote.prove(context.Background(), fullWitness)
+	require.ErrorIs(t, err, ErrRemoteProverUnavailable)
+	client.corrupt = false
+
+	// a witness assigned for another constraint system is rejected
+	remote.ccsChecksum = "00"
+	_, err = remote.prove(context.Background(), fullWitness)
+	require.ErrorIs(t, err, ErrWitnessRejected)
+
+	// and a relayer without the verifying key cannot check the proofs
+	require.NoError(t, os.Remove(filepath.Join(relayerDir, "Square.vk")))
+	_, err = loadCircuit(artifacts, relayerDir, proverSettings{remote: &RemoteProver{addr: "prover", client: client}})
+	require.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/constraint/solver"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+)
+
+// ErrMemoryCeiling is returned, instead of proving, when a proof is not expected to fit under
+// Config.MemoryLimitMB
+var ErrMemoryCeiling = errors.New("memory ceiling reached")
+
+// proverSettings are the resources a loadedCircuit proves with, see Config.GPU, Config.ProverCores,
+// Config.MemoryLimitMB and Config.RemoteProver
+type proverSettings struct {
+	gpu         bool
+	nbTasks     int    // solver workers, 0 for one per CPU
+	memoryLimit uint64 // bytes, 0 for none
+	logger      cfgtypes.Logger
+	remote      *RemoteProver // nil to prove locally
+	guard       *proofGuard   // nil for no limit
+}
+
+// proverSettings returns the prover resources of the config
+func (r *Relayer) proverSettings() proverSettings {
+	return proverSettings{
+		gpu:         r.config.GPU,
+		nbTasks:     r.config.ProverCores,
+		memoryLimit: r.config.MemoryLimitMB << 20,
+		logger:      r.config.Log(),
+		remote:      r.remoteProver,
+		guard:       r.proofGuard,
+	}
+}
+
+// log returns the logger of the settings, NopLogger if there is none
+func (s proverSettings) log() cfgtypes.Logger {
+	if s.logger == nil {
+		return cfgtypes.NopLogger
+	}
+	return s.logger
+}
+
+// applyRuntimeLimits caps the Go runtime to the cores and memory of the config: GOMAXPROCS bounds the
+// goroutines of the solver and of the MSMs and FFTs of the prover running at once, and the memory
+// limit makes the garbage collector work harder instead of growing the heap past it
+func applyRuntimeLimits(config *cfgtypes.Config) {
+	if config.ProverCores > 0 {
+		runtime.GOMAXPROCS(config.ProverCores)
+		config.Log().Infof("Proving on %d cores\n", config.ProverCores)
+	}
+	if config.MemoryLimitMB > 0 {
+		debug.SetMemoryLimit(int64(config.MemoryLimitMB << 20))
+		config.Log().Infof("Memory limited to %d MiB\n", config.MemoryLimitMB)
+	}
+}
+
+// proverOptions returns the gnark prover options of s
+func (s proverSettings) proverOptions() []backend.ProverOption {
+	var opts []backend.ProverOption
+	if s.nbTasks > 0 {
+		opts = append(opts, backend.WithSolverOptions(solver.WithNbTasks(s.nbTasks)))
+	}
+	if s.gpu {
+		opts = append(opts, backend.WithIcicleAcceleration())
+	}
+	return opts
+}
+
+// proveMemoryEstimate is a coarse estimate of the memory allocated by a proof of ccs on top of its proving
+// key: the solution, one fr.Element per wire, and the polynomials over the evaluation domain, about 8 of
+// them for Groth16 (a, b, c and their coset evaluations) and 40 for PLONK (the selectors, wires and
+// quotient evaluated on the 4 times larger coset).
+func proveMemoryEstimate(ccs constraint.ConstraintSystem, proofBackend types.ProofBackend) uint64 {
+	const frBytes = 32
+	nbWires := uint64(ccs.GetNbInternalVariables() + ccs.GetNbSecretVariables() + ccs.GetNbPublicVariables())
+	domain := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints() + ccs.GetNbPublicVariables()))
+	polys := uint64(8)
+	if proofBackend == types.BackendPlonk {
+		polys = 40
+	}
+	return frBytes * (nbWires + polys*domain)
+}
+
+// checkMemoryCeiling returns ErrMemoryCeiling if proving ccs is expected to take the memory in use past
+// limit bytes, so the relayer reports and skips the proof instead of being OOM-killed
+func checkMemoryCeiling(ccs constraint.ConstraintSystem, proofBackend types.ProofBackend, limit uint64) error {
+	if limit == 0 {
+		return nil
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	inUse := stats.HeapInuse + stats.StackInuse
+	need := proveMemoryEstimate(ccs, proofBackend)
+	if inUse+need > limit {
+		return fmt.Errorf("%w: proving %d constraints needs about %d MiB on top of the %d MiB in use, over the %d MiB limit",
+			ErrMemoryCeiling, ccs.GetNbConstraints(), need>>20, inUse>>20, limit>>20)
+	}
+	return nil
+}
+
+// proofGuard bounds the proofs generated at once by the circuits of a relayer, whichever the caller:
+// at most max of them (0 for no limit), whose estimated memory (see proveMemoryEstimate) adds up to at
+// most limit bytes (0 for no limit). A proof over either waits for a running one to end, so that the
+// proofs requested while catching up queue instead of running the host out of memory. A proof that
+// runs alone is always let through, checkMemoryCeiling decides whether it fits.
+type proofGuard struct {
+	max   int
+	limit uint64
+
+	mu       sync.Mutex
+	running  int
+	reserved uint64
+	// released is closed, and replaced, whenever a proof ends
+	released chan struct{}
+}
+
+// newProofGuard returns the guard of the proofs of config, nil if it limits neither their number nor
+// their memory
+func newProofGuard(config *cfgtypes.Config) *proofGuard {
+	if config.MaxConcurrentProofs <= 0 && config.MemoryLimitMB == 0 {
+		return nil
+	}
+	return &proofGuard{
+		max:      max(config.MaxConcurrentProofs, 0),
+		limit:    config.MemoryLimitMB << 20,
+		released: make(chan struct{}),
+	}
+}
+
+// fits reports whether a proof needing need bytes can start next to the running ones
+func (g *proofGuard) fits(need uint64) bool {
+	if g.running == 0 {
+		return true
+	}
+	return (g.max == 0 || g.running < g.max) && (g.limit == 0 || g.reserved+need <= g.limit)
+}
+
+// acquire waits until a proof needing need bytes fits, and reserves it. The returned function ends the
+// proof. It fails only when ctx is cancelled while waiting. A nil guard lets every proof through.
+func (g *proofGuard) acquire(ctx context.Context, need uint64, logger cfgtypes.Logger) (func(), error) {
+	if g == nil {
+		return func() {}, nil
+	}
+	g.mu.Lock()
+	if !g.fits(need) {
+		logger.Infof("Proof queued: %d proofs running, %d MiB reserved\n", g.running, g.reserved>>20)
+	}
+	for !g.fits(need) {
+		released := g.released
+		g.mu.Unlock()
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		g.mu.Lock()
+	}
+	g.running++
+	g.reserved += need
+	g.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.running--
+			g.reserved -= need
+			close(g.released)
+			g.released = make(chan struct{})
+		})
+	}, nil
+}
@@ -0,0 +1,99 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+type squareCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *squareCircuit) Define(api frontend.API) error {
+	x := c.X
+	for i := 0; i < 1000; i++ {
+		x = api.Mul(x, x)
+	}
+	api.AssertIsEqual(x, c.Y)
+	return nil
+}
+
+func TestProverResources(t *testing.T) {
+	config := cfgtypes.NewConfig("--prover-cores", "2", "--memory-limit-mb", "512", "--root", t.TempDir())
+	require.Equal(t, 2, config.ProverCores)
+	require.Equal(t, uint64(512), config.MemoryLimitMB)
+	r := &Relayer{config: config}
+	settings := r.proverSettings()
+	require.Equal(t, uint64(512<<20), settings.memoryLimit)
+	require.Len(t, settings.proverOptions(), 1)
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	// 1001 constraints on a domain of 1024
+	groth16Need := proveMemoryEstimate(ccs, types.BackendGroth16)
+	require.Equal(t, uint64(32*(ccs.GetNbInternalVariables()+3+8*1024)), groth16Need)
+	require.Greater(t, proveMemoryEstimate(ccs, types.BackendPlonk), groth16Need)
+
+	require.NoError(t, checkMemoryCeiling(ccs, types.BackendGroth16, 0))
+	require.NoError(t, checkMemoryCeiling(ccs, types.BackendGroth16, 1<<40))
+	err = checkMemoryCeiling(ccs, types.BackendGroth16, 1<<10)
+	require.True(t, errors.Is(err, ErrMemoryCeiling), err)
+}
+
+func TestProofGuard(t *testing.T) {
+	require.Nil(t, newProofGuard(cfgtypes.NewConfig("--max-concurrent-proofs", "0")))
+	config := cfgtypes.NewConfig("--max-concurrent-proofs", "2", "--memory-limit-mb", "10")
+	require.Equal(t, 2, config.MaxConcurrentProofs)
+	guard := newProofGuard(config)
+	ctx := context.Background()
+
+	// a proof running alone is let through whatever its memory
+	first, err := guard.acquire(ctx, 20<<20, cfgtypes.NopLogger)
+	require.NoError(t, err)
+	acquired := make(chan func())
+	go func() {
+		release, err := guard.acquire(ctx, 4<<20, cfgtypes.NopLogger)
+		if err == nil {
+			acquired <- release
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("a proof over the memory limit was not queued")
+	case <-time.After(50 * time.Millisecond):
+	}
+	first()
+	first()
+	second := <-acquired
+
+	// two proofs fit under the limit, the third waits
+	third, err := guard.acquire(ctx, 4<<20, cfgtypes.NopLogger)
+	require.NoError(t, err)
+	cancelled, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = guard.acquire(cancelled, 1, cfgtypes.NopLogger)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	second()
+	fourth, err := guard.acquire(ctx, 1, cfgtypes.NopLogger)
+	require.NoError(t, err)
+	third()
+	fourth()
+	require.Zero(t, guard.running)
+	require.Zero(t, guard.reserved)
+
+	var none *proofGuard
+	release, err := none.acquire(ctx, 1<<40, cfgtypes.NopLogger)
+	require.NoError(t, err)
+	release()
+}
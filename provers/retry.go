@@ -0,0 +1,96 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultRetryPolicy is used for the fields of the config's retry policy that are not set
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+// RetryPolicy retries failed operations with an exponential backoff: the wait after attempt n is
+// Backoff * 2^(n-1), capped at MaxBackoff, of which the upper half is random so that relayers
+// restarted together do not hit their endpoints in step.
+type RetryPolicy struct {
+	// MaxAttempts bounds the attempts of an operation, the first one included. 1 disables retries.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// permanentError marks an error that retrying would only repeat
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as fatal: Retry returns it without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsRetryable reports whether err may go away when the operation is retried. Permanent errors and
+// the cancellation of the operation's context are not retryable.
+func IsRetryable(err error) bool {
+	var permanent *permanentError
+	return err != nil && !errors.As(err, &permanent) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// withDefaults fills the unset fields of p with the ones of DefaultRetryPolicy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultRetryPolicy.Backoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	return p
+}
+
+// Delay returns the wait after the given failed attempt (1 for the first one)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d-d/2)
+}
+
+// Retry calls op until it succeeds, returns an error that is not retryable or fails MaxAttempts times,
+// waiting Delay between the attempts. It returns the last error of op, or the error of ctx if it is
+// cancelled while waiting.
+func (p RetryPolicy) Retry(ctx context.Context, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !IsRetryable(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+		timer := time.NewTimer(p.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
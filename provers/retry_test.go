@@ -0,0 +1,67 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	// doubled per attempt, capped, with the upper half random
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 60: time.Second} {
+		for i := 0; i < 20; i++ {
+			d := policy.Delay(attempt)
+			require.GreaterOrEqual(t, d, want/2, attempt)
+			require.LessOrEqual(t, d, want, attempt)
+		}
+	}
+	require.Equal(t, DefaultRetryPolicy, RetryPolicy{}.withDefaults())
+
+	// retryable errors are retried up to MaxAttempts
+	policy = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	transient := errors.New("transient")
+	calls := 0
+	err := policy.Retry(context.Background(), func() error {
+		calls++
+		return transient
+	})
+	require.ErrorIs(t, err, transient)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	require.NoError(t, policy.Retry(context.Background(), func() error {
+		if calls++; calls < 2 {
+			return transient
+		}
+		return nil
+	}))
+	require.Equal(t, 2, calls)
+
+	// permanent errors are not
+	calls = 0
+	err = policy.Retry(context.Background(), func() error {
+		calls++
+		return Permanent(transient)
+	})
+	require.ErrorIs(t, err, transient)
+	require.False(t, IsRetryable(err))
+	require.Equal(t, 1, calls)
+	require.False(t, IsRetryable(context.Canceled))
+	require.Nil(t, Permanent(nil))
+
+	// a cancelled context stops the waits
+	policy = RetryPolicy{MaxAttempts: 10, Backoff: time.Hour, MaxBackoff: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = policy.Retry(ctx, func() error {
+		calls++
+		cancel()
+		return transient
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, calls)
+}
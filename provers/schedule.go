@@ -0,0 +1,44 @@
+package relayer
+
+import (
+	"context"
+	"time"
+
+	"github.com/kysee/zk-chains/types/slots"
+)
+
+// periodDelay returns how long after the head slot head the update of period is expected: once the
+// first epoch of period is finalized, plus Config.PeriodMargin. It is 0 if that slot was reached.
+// Slots last Config.SlotDuration, or SECONDS_PER_SLOT of the preset.
+func (r *Relayer) periodDelay(head, period uint64) time.Duration {
+	delay := slots.FinalizationDelay(r.config.Preset.Spec(), head, period, r.config.SlotDuration)
+	if delay == 0 {
+		return 0
+	}
+	return delay + r.config.PeriodMargin
+}
+
+// waitForPeriod sleeps until the update of period is expected (see periodDelay), rather than polling
+// for it through the ~27 hours of a mainnet period. It returns at once if the fetcher cannot report
+// the head, or fails to.
+func (r *Relayer) waitForPeriod(ctx context.Context, period uint64) {
+	head, ok := r.fetcher.(interface {
+		HeadSlot(ctx context.Context) (uint64, error)
+	})
+	if !ok {
+		return
+	}
+	slot, err := head.HeadSlot(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log().Warnf("failed to fetch the beacon head, polling for the update of period %d: %v\n", period, err)
+		}
+		return
+	}
+	delay := r.periodDelay(slot, period)
+	if delay == 0 {
+		return
+	}
+	r.log().Infof("Head at slot %d, the update of period %d is expected in %s\n", slot, period, delay.Round(time.Second))
+	sleep(ctx, delay)
+}
@@ -0,0 +1,41 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodDelay(t *testing.T) {
+	r := &Relayer{config: &cfgtypes.Config{PeriodMargin: time.Minute}}
+	// the update of period 1106 is expected 2 epochs after its first slot, 9060352
+	require.Equal(t, 416*12*time.Second+time.Minute, r.periodDelay(9_060_000, 1106))
+	require.Equal(t, 12*time.Second+time.Minute, r.periodDelay(9_060_415, 1106))
+	require.Zero(t, r.periodDelay(9_060_416, 1106))
+	require.Zero(t, r.periodDelay(9_060_000, 1105))
+
+	r.config.SlotDuration = 5 * time.Second
+	require.Equal(t, 416*5*time.Second+time.Minute, r.periodDelay(9_060_000, 1106))
+
+	// with the head in period 1105, the relayer sleeps until period 1106 only
+	fetcher := cfgtypes.NewFakeFetcher()
+	fetcher.SetHeadSlot(9_060_000)
+	r.fetcher = fetcher
+	start := time.Now()
+	r.waitForPeriod(context.Background(), 1105)
+	require.Less(t, time.Since(start), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r.waitForPeriod(ctx, 1106)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// without a head, it does not wait
+	fetcher.Fail("HeadSlot", errors.New("unreachable"), 0)
+	start = time.Now()
+	r.waitForPeriod(context.Background(), 1106)
+	require.Less(t, time.Since(start), time.Second)
+}
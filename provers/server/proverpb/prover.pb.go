@@ -0,0 +1,1017 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: proverpb/prover.proto
+
+package proverpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Stage int32
+
+const (
+	Stage_STAGE_UNSPECIFIED Stage = 0
+	Stage_STAGE_FETCHING    Stage = 1
+	Stage_STAGE_ASSIGNING   Stage = 2
+	Stage_STAGE_QUEUED      Stage = 3
+	Stage_STAGE_PROVING     Stage = 4
+)
+
+// Enum value maps for Stage.
+var (
+	Stage_name = map[int32]string{
+		0: "STAGE_UNSPECIFIED",
+		1: "STAGE_FETCHING",
+		2: "STAGE_ASSIGNING",
+		3: "STAGE_QUEUED",
+		4: "STAGE_PROVING",
+	}
+	Stage_value = map[string]int32{
+		"STAGE_UNSPECIFIED": 0,
+		"STAGE_FETCHING":    1,
+		"STAGE_ASSIGNING":   2,
+		"STAGE_QUEUED":      3,
+		"STAGE_PROVING":     4,
+	}
+)
+
+func (x Stage) Enum() *Stage {
+	p := new(Stage)
+	*p = x
+	return p
+}
+
+func (x Stage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Stage) Descriptor() protoreflect.EnumDescriptor {
+	return file_proverpb_prover_proto_enumTypes[0].Descriptor()
+}
+
+func (Stage) Type() protoreflect.EnumType {
+	return &file_proverpb_prover_proto_enumTypes[0]
+}
+
+func (x Stage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Stage.Descriptor instead.
+func (Stage) EnumDescriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{0}
+}
+
+type ProveScUpdateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// period is proven against the committee of period - 1, it must not be 0
+	Period        uint64 `protobuf:"varint,1,opt,name=period,proto3" json:"period,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProveScUpdateRequest) Reset() {
+	*x = ProveScUpdateRequest{}
+	mi := &file_proverpb_prover_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveScUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveScUpdateRequest) ProtoMessage() {}
+
+func (x *ProveScUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveScUpdateRequest.ProtoReflect.Descriptor instead.
+func (*ProveScUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ProveScUpdateRequest) GetPeriod() uint64 {
+	if x != nil {
+		return x.Period
+	}
+	return 0
+}
+
+type Progress struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Stage Stage                  `protobuf:"varint,1,opt,name=stage,proto3,enum=zkchains.prover.v1.Stage" json:"stage,omitempty"`
+	// elapsed_ms is the time since the request was received
+	ElapsedMs     uint64 `protobuf:"varint,2,opt,name=elapsed_ms,json=elapsedMs,proto3" json:"elapsed_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Progress) Reset() {
+	*x = Progress{}
+	mi := &file_proverpb_prover_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Progress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Progress) ProtoMessage() {}
+
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Progress) GetStage() Stage {
+	if x != nil {
+		return x.Stage
+	}
+	return Stage_STAGE_UNSPECIFIED
+}
+
+func (x *Progress) GetElapsedMs() uint64 {
+	if x != nil {
+		return x.ElapsedMs
+	}
+	return 0
+}
+
+type ScUpdateProof struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Period uint64                 `protobuf:"varint,1,opt,name=period,proto3" json:"period,omitempty"`
+	// sc_pub_keys_hash commits to the committee signing the update, next_sc_pub_keys_hash to the one it hands over to
+	ScPubKeysHash     []byte `protobuf:"bytes,2,opt,name=sc_pub_keys_hash,json=scPubKeysHash,proto3" json:"sc_pub_keys_hash,omitempty"`
+	NextScPubKeysHash []byte `protobuf:"bytes,3,opt,name=next_sc_pub_keys_hash,json=nextScPubKeysHash,proto3" json:"next_sc_pub_keys_hash,omitempty"`
+	// backend is "groth16" or "plonk"
+	Backend string `protobuf:"bytes,4,opt,name=backend,proto3" json:"backend,omitempty"`
+	// proof is serialized with MarshalSolidity
+	Proof []byte `protobuf:"bytes,5,opt,name=proof,proto3" json:"proof,omitempty"`
+	// proof_data_json is the proof as written to the proof files
+	ProofDataJson []byte `protobuf:"bytes,6,opt,name=proof_data_json,json=proofDataJson,proto3" json:"proof_data_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScUpdateProof) Reset() {
+	*x = ScUpdateProof{}
+	mi := &file_proverpb_prover_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScUpdateProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScUpdateProof) ProtoMessage() {}
+
+func (x *ScUpdateProof) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScUpdateProof.ProtoReflect.Descriptor instead.
+func (*ScUpdateProof) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ScUpdateProof) GetPeriod() uint64 {
+	if x != nil {
+		return x.Period
+	}
+	return 0
+}
+
+func (x *ScUpdateProof) GetScPubKeysHash() []byte {
+	if x != nil {
+		return x.ScPubKeysHash
+	}
+	return nil
+}
+
+func (x *ScUpdateProof) GetNextScPubKeysHash() []byte {
+	if x != nil {
+		return x.NextScPubKeysHash
+	}
+	return nil
+}
+
+func (x *ScUpdateProof) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *ScUpdateProof) GetProof() []byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *ScUpdateProof) GetProofDataJson() []byte {
+	if x != nil {
+		return x.ProofDataJson
+	}
+	return nil
+}
+
+type ProveScUpdateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ProveScUpdateResponse_Progress
+	//	*ProveScUpdateResponse_Proof
+	Event         isProveScUpdateResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProveScUpdateResponse) Reset() {
+	*x = ProveScUpdateResponse{}
+	mi := &file_proverpb_prover_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveScUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveScUpdateResponse) ProtoMessage() {}
+
+func (x *ProveScUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveScUpdateResponse.ProtoReflect.Descriptor instead.
+func (*ProveScUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ProveScUpdateResponse) GetEvent() isProveScUpdateResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ProveScUpdateResponse) GetProgress() *Progress {
+	if x != nil {
+		if x, ok := x.Event.(*ProveScUpdateResponse_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+func (x *ProveScUpdateResponse) GetProof() *ScUpdateProof {
+	if x != nil {
+		if x, ok := x.Event.(*ProveScUpdateResponse_Proof); ok {
+			return x.Proof
+		}
+	}
+	return nil
+}
+
+type isProveScUpdateResponse_Event interface {
+	isProveScUpdateResponse_Event()
+}
+
+type ProveScUpdateResponse_Progress struct {
+	Progress *Progress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"`
+}
+
+type ProveScUpdateResponse_Proof struct {
+	Proof *ScUpdateProof `protobuf:"bytes,2,opt,name=proof,proto3,oneof"`
+}
+
+func (*ProveScUpdateResponse_Progress) isProveScUpdateResponse_Event() {}
+
+func (*ProveScUpdateResponse_Proof) isProveScUpdateResponse_Event() {}
+
+type ProveReceiptRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	TxHash []byte                 `protobuf:"bytes,1,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	// max_gas is the gas budget the transaction is proven to stay within, 0 means no budget
+	MaxGas        uint64 `protobuf:"varint,2,opt,name=max_gas,json=maxGas,proto3" json:"max_gas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProveReceiptRequest) Reset() {
+	*x = ProveReceiptRequest{}
+	mi := &file_proverpb_prover_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveReceiptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveReceiptRequest) ProtoMessage() {}
+
+func (x *ProveReceiptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveReceiptRequest.ProtoReflect.Descriptor instead.
+func (*ProveReceiptRequest) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ProveReceiptRequest) GetTxHash() []byte {
+	if x != nil {
+		return x.TxHash
+	}
+	return nil
+}
+
+func (x *ProveReceiptRequest) GetMaxGas() uint64 {
+	if x != nil {
+		return x.MaxGas
+	}
+	return 0
+}
+
+type ProveReceiptResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	BlockHash   []byte                 `protobuf:"bytes,1,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockNumber uint64                 `protobuf:"varint,2,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	// header is the RLP encoded header, keccak256(header) == block_hash
+	Header       []byte `protobuf:"bytes,3,opt,name=header,proto3" json:"header,omitempty"`
+	ReceiptsRoot []byte `protobuf:"bytes,4,opt,name=receipts_root,json=receiptsRoot,proto3" json:"receipts_root,omitempty"`
+	TxHash       []byte `protobuf:"bytes,5,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	TxIndex      uint64 `protobuf:"varint,6,opt,name=tx_index,json=txIndex,proto3" json:"tx_index,omitempty"`
+	// receipt is the consensus encoding of the receipt, receipt_proof the trie nodes from the receipts root to it
+	Receipt      []byte   `protobuf:"bytes,7,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	ReceiptProof [][]byte `protobuf:"bytes,8,rep,name=receipt_proof,json=receiptProof,proto3" json:"receipt_proof,omitempty"`
+	// prev_receipt and prev_receipt_proof are the ones of transaction tx_index - 1, empty for the first transaction
+	PrevReceipt           []byte   `protobuf:"bytes,9,opt,name=prev_receipt,json=prevReceipt,proto3" json:"prev_receipt,omitempty"`
+	PrevReceiptProof      [][]byte `protobuf:"bytes,10,rep,name=prev_receipt_proof,json=prevReceiptProof,proto3" json:"prev_receipt_proof,omitempty"`
+	Status                uint64   `protobuf:"varint,11,opt,name=status,proto3" json:"status,omitempty"`
+	CumulativeGasUsed     uint64   `protobuf:"varint,12,opt,name=cumulative_gas_used,json=cumulativeGasUsed,proto3" json:"cumulative_gas_used,omitempty"`
+	PrevCumulativeGasUsed uint64   `protobuf:"varint,13,opt,name=prev_cumulative_gas_used,json=prevCumulativeGasUsed,proto3" json:"prev_cumulative_gas_used,omitempty"`
+	GasUsed               uint64   `protobuf:"varint,14,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	MaxGas                uint64   `protobuf:"varint,15,opt,name=max_gas,json=maxGas,proto3" json:"max_gas,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *ProveReceiptResponse) Reset() {
+	*x = ProveReceiptResponse{}
+	mi := &file_proverpb_prover_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveReceiptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveReceiptResponse) ProtoMessage() {}
+
+func (x *ProveReceiptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveReceiptResponse.ProtoReflect.Descriptor instead.
+func (*ProveReceiptResponse) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ProveReceiptResponse) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetBlockNumber() uint64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *ProveReceiptResponse) GetHeader() []byte {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetReceiptsRoot() []byte {
+	if x != nil {
+		return x.ReceiptsRoot
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetTxHash() []byte {
+	if x != nil {
+		return x.TxHash
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetTxIndex() uint64 {
+	if x != nil {
+		return x.TxIndex
+	}
+	return 0
+}
+
+func (x *ProveReceiptResponse) GetReceipt() []byte {
+	if x != nil {
+		return x.Receipt
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetReceiptProof() [][]byte {
+	if x != nil {
+		return x.ReceiptProof
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetPrevReceipt() []byte {
+	if x != nil {
+		return x.PrevReceipt
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetPrevReceiptProof() [][]byte {
+	if x != nil {
+		return x.PrevReceiptProof
+	}
+	return nil
+}
+
+func (x *ProveReceiptResponse) GetStatus() uint64 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *ProveReceiptResponse) GetCumulativeGasUsed() uint64 {
+	if x != nil {
+		return x.CumulativeGasUsed
+	}
+	return 0
+}
+
+func (x *ProveReceiptResponse) GetPrevCumulativeGasUsed() uint64 {
+	if x != nil {
+		return x.PrevCumulativeGasUsed
+	}
+	return 0
+}
+
+func (x *ProveReceiptResponse) GetGasUsed() uint64 {
+	if x != nil {
+		return x.GasUsed
+	}
+	return 0
+}
+
+func (x *ProveReceiptResponse) GetMaxGas() uint64 {
+	if x != nil {
+		return x.MaxGas
+	}
+	return 0
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_proverpb_prover_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{6}
+}
+
+type QuarantinedPeriod struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Period        uint64                 `protobuf:"varint,1,opt,name=period,proto3" json:"period,omitempty"`
+	Files         []string               `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	ModTimeUnix   int64                  `protobuf:"varint,3,opt,name=mod_time_unix,json=modTimeUnix,proto3" json:"mod_time_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuarantinedPeriod) Reset() {
+	*x = QuarantinedPeriod{}
+	mi := &file_proverpb_prover_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuarantinedPeriod) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuarantinedPeriod) ProtoMessage() {}
+
+func (x *QuarantinedPeriod) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuarantinedPeriod.ProtoReflect.Descriptor instead.
+func (*QuarantinedPeriod) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *QuarantinedPeriod) GetPeriod() uint64 {
+	if x != nil {
+		return x.Period
+	}
+	return 0
+}
+
+func (x *QuarantinedPeriod) GetFiles() []string {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *QuarantinedPeriod) GetModTimeUnix() int64 {
+	if x != nil {
+		return x.ModTimeUnix
+	}
+	return 0
+}
+
+type StatusResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	ProvedPeriods      []uint64               `protobuf:"varint,1,rep,packed,name=proved_periods,json=provedPeriods,proto3" json:"proved_periods,omitempty"`
+	PendingSubmissions []uint64               `protobuf:"varint,2,rep,packed,name=pending_submissions,json=pendingSubmissions,proto3" json:"pending_submissions,omitempty"`
+	OverBudget         []uint64               `protobuf:"varint,3,rep,packed,name=over_budget,json=overBudget,proto3" json:"over_budget,omitempty"`
+	// recent_failures are the most recently quarantined periods, newest first
+	RecentFailures []*QuarantinedPeriod `protobuf:"bytes,4,rep,name=recent_failures,json=recentFailures,proto3" json:"recent_failures,omitempty"`
+	// proofs_in_flight counts the ProveScUpdate and ProveWitness calls being served
+	ProofsInFlight uint32 `protobuf:"varint,5,opt,name=proofs_in_flight,json=proofsInFlight,proto3" json:"proofs_in_flight,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_proverpb_prover_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StatusResponse) GetProvedPeriods() []uint64 {
+	if x != nil {
+		return x.ProvedPeriods
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetPendingSubmissions() []uint64 {
+	if x != nil {
+		return x.PendingSubmissions
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetOverBudget() []uint64 {
+	if x != nil {
+		return x.OverBudget
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetRecentFailures() []*QuarantinedPeriod {
+	if x != nil {
+		return x.RecentFailures
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetProofsInFlight() uint32 {
+	if x != nil {
+		return x.ProofsInFlight
+	}
+	return 0
+}
+
+type ProveWitnessRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// circuit is the name of the circuit in the artifact manifest, ccs_checksum the SHA-256 of its
+	// constraint system, empty if the manifest of the relayer has none
+	Circuit     string `protobuf:"bytes,1,opt,name=circuit,proto3" json:"circuit,omitempty"`
+	CcsChecksum string `protobuf:"bytes,2,opt,name=ccs_checksum,json=ccsChecksum,proto3" json:"ccs_checksum,omitempty"`
+	// witness is the full witness serialized with MarshalBinary, witness_sha256 its SHA-256
+	Witness       []byte `protobuf:"bytes,3,opt,name=witness,proto3" json:"witness,omitempty"`
+	WitnessSha256 []byte `protobuf:"bytes,4,opt,name=witness_sha256,json=witnessSha256,proto3" json:"witness_sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProveWitnessRequest) Reset() {
+	*x = ProveWitnessRequest{}
+	mi := &file_proverpb_prover_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveWitnessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveWitnessRequest) ProtoMessage() {}
+
+func (x *ProveWitnessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveWitnessRequest.ProtoReflect.Descriptor instead.
+func (*ProveWitnessRequest) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ProveWitnessRequest) GetCircuit() string {
+	if x != nil {
+		return x.Circuit
+	}
+	return ""
+}
+
+func (x *ProveWitnessRequest) GetCcsChecksum() string {
+	if x != nil {
+		return x.CcsChecksum
+	}
+	return ""
+}
+
+func (x *ProveWitnessRequest) GetWitness() []byte {
+	if x != nil {
+		return x.Witness
+	}
+	return nil
+}
+
+func (x *ProveWitnessRequest) GetWitnessSha256() []byte {
+	if x != nil {
+		return x.WitnessSha256
+	}
+	return nil
+}
+
+type ProveWitnessResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// backend is "groth16" or "plonk"
+	Backend string `protobuf:"bytes,1,opt,name=backend,proto3" json:"backend,omitempty"`
+	// proof is the gnark binary serialization of the proof (WriteTo), proof_sha256 its SHA-256
+	Proof         []byte `protobuf:"bytes,2,opt,name=proof,proto3" json:"proof,omitempty"`
+	ProofSha256   []byte `protobuf:"bytes,3,opt,name=proof_sha256,json=proofSha256,proto3" json:"proof_sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProveWitnessResponse) Reset() {
+	*x = ProveWitnessResponse{}
+	mi := &file_proverpb_prover_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProveWitnessResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProveWitnessResponse) ProtoMessage() {}
+
+func (x *ProveWitnessResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proverpb_prover_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProveWitnessResponse.ProtoReflect.Descriptor instead.
+func (*ProveWitnessResponse) Descriptor() ([]byte, []int) {
+	return file_proverpb_prover_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ProveWitnessResponse) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *ProveWitnessResponse) GetProof() []byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *ProveWitnessResponse) GetProofSha256() []byte {
+	if x != nil {
+		return x.ProofSha256
+	}
+	return nil
+}
+
+var File_proverpb_prover_proto protoreflect.FileDescriptor
+
+const file_proverpb_prover_proto_rawDesc = "" +
+	"\n" +
+	"\x15proverpb/prover.proto\x12\x12zkchains.prover.v1\".\n" +
+	"\x14ProveScUpdateRequest\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\x04R\x06period\"Z\n" +
+	"\bProgress\x12/\n" +
+	"\x05stage\x18\x01 \x01(\x0e2\x19.zkchains.prover.v1.StageR\x05stage\x12\x1d\n" +
+	"\n" +
+	"elapsed_ms\x18\x02 \x01(\x04R\telapsedMs\"\xda\x01\n" +
+	"\rScUpdateProof\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\x04R\x06period\x12'\n" +
+	"\x10sc_pub_keys_hash\x18\x02 \x01(\fR\rscPubKeysHash\x120\n" +
+	"\x15next_sc_pub_keys_hash\x18\x03 \x01(\fR\x11nextScPubKeysHash\x12\x18\n" +
+	"\abackend\x18\x04 \x01(\tR\abackend\x12\x14\n" +
+	"\x05proof\x18\x05 \x01(\fR\x05proof\x12&\n" +
+	"\x0fproof_data_json\x18\x06 \x01(\fR\rproofDataJson\"\x97\x01\n" +
+	"\x15ProveScUpdateResponse\x12:\n" +
+	"\bprogress\x18\x01 \x01(\v2\x1c.zkchains.prover.v1.ProgressH\x00R\bprogress\x129\n" +
+	"\x05proof\x18\x02 \x01(\v2!.zkchains.prover.v1.ScUpdateProofH\x00R\x05proofB\a\n" +
+	"\x05event\"G\n" +
+	"\x13ProveReceiptRequest\x12\x17\n" +
+	"\atx_hash\x18\x01 \x01(\fR\x06txHash\x12\x17\n" +
+	"\amax_gas\x18\x02 \x01(\x04R\x06maxGas\"\x8e\x04\n" +
+	"\x14ProveReceiptResponse\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\x01 \x01(\fR\tblockHash\x12!\n" +
+	"\fblock_number\x18\x02 \x01(\x04R\vblockNumber\x12\x16\n" +
+	"\x06header\x18\x03 \x01(\fR\x06header\x12#\n" +
+	"\rreceipts_root\x18\x04 \x01(\fR\freceiptsRoot\x12\x17\n" +
+	"\atx_hash\x18\x05 \x01(\fR\x06txHash\x12\x19\n" +
+	"\btx_index\x18\x06 \x01(\x04R\atxIndex\x12\x18\n" +
+	"\areceipt\x18\a \x01(\fR\areceipt\x12#\n" +
+	"\rreceipt_proof\x18\b \x03(\fR\freceiptProof\x12!\n" +
+	"\fprev_receipt\x18\t \x01(\fR\vprevReceipt\x12,\n" +
+	"\x12prev_receipt_proof\x18\n" +
+	" \x03(\fR\x10prevReceiptProof\x12\x16\n" +
+	"\x06status\x18\v \x01(\x04R\x06status\x12.\n" +
+	"\x13cumulative_gas_used\x18\f \x01(\x04R\x11cumulativeGasUsed\x127\n" +
+	"\x18prev_cumulative_gas_used\x18\r \x01(\x04R\x15prevCumulativeGasUsed\x12\x19\n" +
+	"\bgas_used\x18\x0e \x01(\x04R\agasUsed\x12\x17\n" +
+	"\amax_gas\x18\x0f \x01(\x04R\x06maxGas\"\x0f\n" +
+	"\rStatusRequest\"e\n" +
+	"\x11QuarantinedPeriod\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\x04R\x06period\x12\x14\n" +
+	"\x05files\x18\x02 \x03(\tR\x05files\x12\"\n" +
+	"\rmod_time_unix\x18\x03 \x01(\x03R\vmodTimeUnix\"\x83\x02\n" +
+	"\x0eStatusResponse\x12%\n" +
+	"\x0eproved_periods\x18\x01 \x03(\x04R\rprovedPeriods\x12/\n" +
+	"\x13pending_submissions\x18\x02 \x03(\x04R\x12pendingSubmissions\x12\x1f\n" +
+	"\vover_budget\x18\x03 \x03(\x04R\n" +
+	"overBudget\x12N\n" +
+	"\x0frecent_failures\x18\x04 \x03(\v2%.zkchains.prover.v1.QuarantinedPeriodR\x0erecentFailures\x12(\n" +
+	"\x10proofs_in_flight\x18\x05 \x01(\rR\x0eproofsInFlight\"\x93\x01\n" +
+	"\x13ProveWitnessRequest\x12\x18\n" +
+	"\acircuit\x18\x01 \x01(\tR\acircuit\x12!\n" +
+	"\fccs_checksum\x18\x02 \x01(\tR\vccsChecksum\x12\x18\n" +
+	"\awitness\x18\x03 \x01(\fR\awitness\x12%\n" +
+	"\x0ewitness_sha256\x18\x04 \x01(\fR\rwitnessSha256\"i\n" +
+	"\x14ProveWitnessResponse\x12\x18\n" +
+	"\abackend\x18\x01 \x01(\tR\abackend\x12\x14\n" +
+	"\x05proof\x18\x02 \x01(\fR\x05proof\x12!\n" +
+	"\fproof_sha256\x18\x03 \x01(\fR\vproofSha256*l\n" +
+	"\x05Stage\x12\x15\n" +
+	"\x11STAGE_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0eSTAGE_FETCHING\x10\x01\x12\x13\n" +
+	"\x0fSTAGE_ASSIGNING\x10\x02\x12\x10\n" +
+	"\fSTAGE_QUEUED\x10\x03\x12\x11\n" +
+	"\rSTAGE_PROVING\x10\x042\x87\x03\n" +
+	"\x06Prover\x12f\n" +
+	"\rProveScUpdate\x12(.zkchains.prover.v1.ProveScUpdateRequest\x1a).zkchains.prover.v1.ProveScUpdateResponse0\x01\x12a\n" +
+	"\fProveReceipt\x12'.zkchains.prover.v1.ProveReceiptRequest\x1a(.zkchains.prover.v1.ProveReceiptResponse\x12O\n" +
+	"\x06Status\x12!.zkchains.prover.v1.StatusRequest\x1a\".zkchains.prover.v1.StatusResponse\x12a\n" +
+	"\fProveWitness\x12'.zkchains.prover.v1.ProveWitnessRequest\x1a(.zkchains.prover.v1.ProveWitnessResponseB4Z2github.com/kysee/zk-chains/provers/server/proverpbb\x06proto3"
+
+var (
+	file_proverpb_prover_proto_rawDescOnce sync.Once
+	file_proverpb_prover_proto_rawDescData []byte
+)
+
+func file_proverpb_prover_proto_rawDescGZIP() []byte {
+	file_proverpb_prover_proto_rawDescOnce.Do(func() {
+		file_proverpb_prover_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proverpb_prover_proto_rawDesc), len(file_proverpb_prover_proto_rawDesc)))
+	})
+	return file_proverpb_prover_proto_rawDescData
+}
+
+var file_proverpb_prover_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proverpb_prover_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proverpb_prover_proto_goTypes = []any{
+	(Stage)(0),                    // 0: zkchains.prover.v1.Stage
+	(*ProveScUpdateRequest)(nil),  // 1: zkchains.prover.v1.ProveScUpdateRequest
+	(*Progress)(nil),              // 2: zkchains.prover.v1.Progress
+	(*ScUpdateProof)(nil),         // 3: zkchains.prover.v1.ScUpdateProof
+	(*ProveScUpdateResponse)(nil), // 4: zkchains.prover.v1.ProveScUpdateResponse
+	(*ProveReceiptRequest)(nil),   // 5: zkchains.prover.v1.ProveReceiptRequest
+	(*ProveReceiptResponse)(nil),  // 6: zkchains.prover.v1.ProveReceiptResponse
+	(*StatusRequest)(nil),         // 7: zkchains.prover.v1.StatusRequest
+	(*QuarantinedPeriod)(nil),     // 8: zkchains.prover.v1.QuarantinedPeriod
+	(*StatusResponse)(nil),        // 9: zkchains.prover.v1.StatusResponse
+	(*ProveWitnessRequest)(nil),   // 10: zkchains.prover.v1.ProveWitnessRequest
+	(*ProveWitnessResponse)(nil),  // 11: zkchains.prover.v1.ProveWitnessResponse
+}
+var file_proverpb_prover_proto_depIdxs = []int32{
+	0,  // 0: zkchains.prover.v1.Progress.stage:type_name -> zkchains.prover.v1.Stage
+	2,  // 1: zkchains.prover.v1.ProveScUpdateResponse.progress:type_name -> zkchains.prover.v1.Progress
+	3,  // 2: zkchains.prover.v1.ProveScUpdateResponse.proof:type_name -> zkchains.prover.v1.ScUpdateProof
+	8,  // 3: zkchains.prover.v1.StatusResponse.recent_failures:type_name -> zkchains.prover.v1.QuarantinedPeriod
+	1,  // 4: zkchains.prover.v1.Prover.ProveScUpdate:input_type -> zkchains.prover.v1.ProveScUpdateRequest
+	5,  // 5: zkchains.prover.v1.Prover.ProveReceipt:input_type -> zkchains.prover.v1.ProveReceiptRequest
+	7,  // 6: zkchains.prover.v1.Prover.Status:input_type -> zkchains.prover.v1.StatusRequest
+	10, // 7: zkchains.prover.v1.Prover.ProveWitness:input_type -> zkchains.prover.v1.ProveWitnessRequest
+	4,  // 8: zkchains.prover.v1.Prover.ProveScUpdate:output_type -> zkchains.prover.v1.ProveScUpdateResponse
+	6,  // 9: zkchains.prover.v1.Prover.ProveReceipt:output_type -> zkchains.prover.v1.ProveReceiptResponse
+	9,  // 10: zkchains.prover.v1.Prover.Status:output_type -> zkchains.prover.v1.StatusResponse
+	11, // 11: zkchains.prover.v1.Prover.ProveWitness:output_type -> zkchains.prover.v1.ProveWitnessResponse
+	8,  // [8:12] is the sub-list for method output_type
+	4,  // [4:8] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_proverpb_prover_proto_init() }
+func file_proverpb_prover_proto_init() {
+	if File_proverpb_prover_proto != nil {
+		return
+	}
+	file_proverpb_prover_proto_msgTypes[3].OneofWrappers = []any{
+		(*ProveScUpdateResponse_Progress)(nil),
+		(*ProveScUpdateResponse_Proof)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proverpb_prover_proto_rawDesc), len(file_proverpb_prover_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proverpb_prover_proto_goTypes,
+		DependencyIndexes: file_proverpb_prover_proto_depIdxs,
+		EnumInfos:         file_proverpb_prover_proto_enumTypes,
+		MessageInfos:      file_proverpb_prover_proto_msgTypes,
+	}.Build()
+	File_proverpb_prover_proto = out.File
+	file_proverpb_prover_proto_goTypes = nil
+	file_proverpb_prover_proto_depIdxs = nil
+}
@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proverpb/prover.proto
+
+package proverpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Prover_ProveScUpdate_FullMethodName = "/zkchains.prover.v1.Prover/ProveScUpdate"
+	Prover_ProveReceipt_FullMethodName  = "/zkchains.prover.v1.Prover/ProveReceipt"
+	Prover_Status_FullMethodName        = "/zkchains.prover.v1.Prover/Status"
+	Prover_ProveWitness_FullMethodName  = "/zkchains.prover.v1.Prover/ProveWitness"
+)
+
+// ProverClient is the client API for Prover service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Prover generates proofs on demand, for the services that need them outside of the relayer's
+// periodic proofs (indexers, bridges)
+type ProverClient interface {
+	// ProveScUpdate proves the sync committee update of a period. The stream reports the progress of
+	// the proof, repeated while it takes, and ends with the proof.
+	ProveScUpdate(ctx context.Context, in *ProveScUpdateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProveScUpdateResponse], error)
+	// ProveReceipt packages the proof bundle of a successful transaction (see the tx-status command)
+	ProveReceipt(ctx context.Context, in *ProveReceiptRequest, opts ...grpc.CallOption) (*ProveReceiptResponse, error)
+	// Status reports the proofs left on disk by the relayer and the on-demand proofs in flight
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// ProveWitness proves a full witness assigned by a relayer that does not hold the proving key (see
+	// its --remote-prover flag), with the circuits loaded by the server
+	ProveWitness(ctx context.Context, in *ProveWitnessRequest, opts ...grpc.CallOption) (*ProveWitnessResponse, error)
+}
+
+type proverClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProverClient(cc grpc.ClientConnInterface) ProverClient {
+	return &proverClient{cc}
+}
+
+func (c *proverClient) ProveScUpdate(ctx context.Context, in *ProveScUpdateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProveScUpdateResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Prover_ServiceDesc.Streams[0], Prover_ProveScUpdate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProveScUpdateRequest, ProveScUpdateResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Prover_ProveScUpdateClient = grpc.ServerStreamingClient[ProveScUpdateResponse]
+
+func (c *proverClient) ProveReceipt(ctx context.Context, in *ProveReceiptRequest, opts ...grpc.CallOption) (*ProveReceiptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProveReceiptResponse)
+	err := c.cc.Invoke(ctx, Prover_ProveReceipt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proverClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, Prover_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proverClient) ProveWitness(ctx context.Context, in *ProveWitnessRequest, opts ...grpc.CallOption) (*ProveWitnessResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProveWitnessResponse)
+	err := c.cc.Invoke(ctx, Prover_ProveWitness_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProverServer is the server API for Prover service.
+// All implementations must embed UnimplementedProverServer
+// for forward compatibility.
+//
+// Prover generates proofs on demand, for the services that need them outside of the relayer's
+// periodic proofs (indexers, bridges)
+type ProverServer interface {
+	// ProveScUpdate proves the sync committee update of a period. The stream reports the progress of
+	// the proof, repeated while it takes, and ends with the proof.
+	ProveScUpdate(*ProveScUpdateRequest, grpc.ServerStreamingServer[ProveScUpdateResponse]) error
+	// ProveReceipt packages the proof bundle of a successful transaction (see the tx-status command)
+	ProveReceipt(context.Context, *ProveReceiptRequest) (*ProveReceiptResponse, error)
+	// Status reports the proofs left on disk by the relayer and the on-demand proofs in flight
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	// ProveWitness proves a full witness assigned by a relayer that does not hold the proving key (see
+	// its --remote-prover flag), with the circuits loaded by the server
+	ProveWitness(context.Context, *ProveWitnessRequest) (*ProveWitnessResponse, error)
+	mustEmbedUnimplementedProverServer()
+}
+
+// UnimplementedProverServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProverServer struct{}
+
+func (UnimplementedProverServer) ProveScUpdate(*ProveScUpdateRequest, grpc.ServerStreamingServer[ProveScUpdateResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ProveScUpdate not implemented")
+}
+func (UnimplementedProverServer) ProveReceipt(context.Context, *ProveReceiptRequest) (*ProveReceiptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProveReceipt not implemented")
+}
+func (UnimplementedProverServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedProverServer) ProveWitness(context.Context, *ProveWitnessRequest) (*ProveWitnessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProveWitness not implemented")
+}
+func (UnimplementedProverServer) mustEmbedUnimplementedProverServer() {}
+func (UnimplementedProverServer) testEmbeddedByValue()                {}
+
+// UnsafeProverServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProverServer will
+// result in compilation errors.
+type UnsafeProverServer interface {
+	mustEmbedUnimplementedProverServer()
+}
+
+func RegisterProverServer(s grpc.ServiceRegistrar, srv ProverServer) {
+	// If the following call pancis, it indicates UnimplementedProverServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Prover_ServiceDesc, srv)
+}
+
+func _Prover_ProveScUpdate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProveScUpdateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProverServer).ProveScUpdate(m, &grpc.GenericServerStream[ProveScUpdateRequest, ProveScUpdateResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Prover_ProveScUpdateServer = grpc.ServerStreamingServer[ProveScUpdateResponse]
+
+func _Prover_ProveReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProveReceiptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProverServer).ProveReceipt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Prover_ProveReceipt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProverServer).ProveReceipt(ctx, req.(*ProveReceiptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Prover_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProverServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Prover_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProverServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Prover_ProveWitness_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProveWitnessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProverServer).ProveWitness(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Prover_ProveWitness_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProverServer).ProveWitness(ctx, req.(*ProveWitnessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Prover_ServiceDesc is the grpc.ServiceDesc for Prover service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Prover_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "zkchains.prover.v1.Prover",
+	HandlerType: (*ProverServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProveReceipt",
+			Handler:    _Prover_ProveReceipt_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Prover_Status_Handler,
+		},
+		{
+			MethodName: "ProveWitness",
+			Handler:    _Prover_ProveWitness_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProveScUpdate",
+			Handler:       _Prover_ProveScUpdate_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proverpb/prover.proto",
+}
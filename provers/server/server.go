@@ -0,0 +1,341 @@
+// Package server exposes the provers over gRPC, so that other services (indexers, bridges) can request
+// proofs on demand instead of running the polling relayer
+package server
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proverpb/prover.proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	relayer "github.com/kysee/zk-chains/provers"
+	"github.com/kysee/zk-chains/provers/server/proverpb"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// progressInterval is the period of the progress messages repeated while a proof takes
+	progressInterval = 10 * time.Second
+	// receiptTimeout bounds the execution RPC calls of one ProveReceipt
+	receiptTimeout = 60 * time.Second
+	// maxWitnessSize is the largest ProveWitness request, above the 4 MiB gRPC default
+	maxWitnessSize = 256 << 20
+)
+
+// UpdateProver proves sync committee updates, *relayer.Relayer implements it
+type UpdateProver interface {
+	ProveUpdate(ctx context.Context, period uint64, progress func(relayer.ProofStage)) (*relayer.UpdateProof, error)
+}
+
+// ReceiptProver packages the proof bundles of transactions, *relayer.Listener implements it
+type ReceiptProver interface {
+	TxStatusBundle(ctx context.Context, txHash common.Hash, maxGas uint64) (*types.TxStatusBundle, error)
+}
+
+// WitnessProver proves the witnesses of relayers proving remotely, *relayer.Relayer implements it
+type WitnessProver interface {
+	ProveWitness(ctx context.Context, circuitName, ccsChecksum string, witnessBlob []byte) (types.ProofBackend, []byte, error)
+}
+
+// Server implements proverpb.ProverServer
+type Server struct {
+	proverpb.UnimplementedProverServer
+	config  *cfgtypes.Config
+	updates UpdateProver
+	// receipts is nil if no execution RPC is configured, ProveReceipt is then unavailable
+	receipts ReceiptProver
+	// witnesses is nil if updates cannot prove witnesses, ProveWitness is then unavailable
+	witnesses WitnessProver
+	inFlight  atomic.Int32
+	// progressInterval is the period of the repeated progress messages
+	progressInterval time.Duration
+}
+
+// New creates a Server proving with updates and receipts (may be nil), reporting the status of the
+// relayer whose files are described by config. The witnesses of remote relayers are proven by updates
+// if it is a WitnessProver.
+func New(config *cfgtypes.Config, updates UpdateProver, receipts ReceiptProver) *Server {
+	witnesses, _ := updates.(WitnessProver)
+	return &Server{
+		config:           config,
+		updates:          updates,
+		receipts:         receipts,
+		witnesses:        witnesses,
+		progressInterval: progressInterval,
+	}
+}
+
+// ServeMain loads the circuits and serves the Prover service on config.GRPCAddr until ctx is cancelled
+func ServeMain(ctx context.Context, config *cfgtypes.Config) {
+	fetcher, err := relayer.NewBeaconFetcher(config)
+	if err != nil {
+		fatalf(config, "Failed to create beacon fetcher: %v", err)
+	}
+	r, err := relayer.NewRelayer(config, fetcher)
+	if err != nil {
+		fatalf(config, "Failed to create relayer: %v", err)
+	}
+	if err := r.SetupCircuit(); err != nil {
+		fatalf(config, "failed to setup circuit: %v", err)
+	}
+
+	var receipts ReceiptProver
+	if config.ExecutionRPC != "" {
+		client, err := ethclient.Dial(config.ExecutionRPC)
+		if err != nil {
+			fatalf(config, "failed to connect to %s: %v", config.ExecutionRPC, err)
+		}
+		receipts = relayer.NewListener(config, fetcher, client)
+	}
+
+	lis, err := net.Listen("tcp", config.GRPCAddr)
+	if err != nil {
+		fatalf(config, "failed to listen on %s: %v", config.GRPCAddr, err)
+	}
+	config.Log().Infof("Serving proofs on %s\n", lis.Addr())
+	if err := Serve(ctx, lis, New(config, r, receipts)); err != nil {
+		fatalf(config, "failed to serve: %v", err)
+	}
+}
+
+// fatalf logs an error of the serve command and exits
+func fatalf(config *cfgtypes.Config, format string, args ...any) {
+	config.Log().Errorf(format, args...)
+	os.Exit(1)
+}
+
+// Serve serves s on lis until ctx is cancelled, then stops accepting calls and waits for the ones
+// being served to finish. The standard gRPC health service reports the Prover service as serving
+// until then, for load balancers and Kubernetes probes.
+func Serve(ctx context.Context, lis net.Listener, s *Server) error {
+	g := grpc.NewServer(grpc.MaxRecvMsgSize(maxWitnessSize))
+	proverpb.RegisterProverServer(g, s)
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus(proverpb.Prover_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(g, healthSrv)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		healthSrv.Shutdown()
+		g.GracefulStop()
+	}()
+	err := g.Serve(lis)
+	if ctx.Err() != nil {
+		<-stopped
+		return nil
+	}
+	return err
+}
+
+// ProveScUpdate streams a progress message as each stage of the proof starts and every
+// progressInterval while it takes, then the proof. A proof already started when the call is
+// cancelled is finished, the next requests wait for it.
+func (s *Server) ProveScUpdate(req *proverpb.ProveScUpdateRequest, stream grpc.ServerStreamingServer[proverpb.ProveScUpdateResponse]) error {
+	if req.Period == 0 {
+		return status.Error(codes.InvalidArgument, "period 0 cannot be proven")
+	}
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	ctx := stream.Context()
+	start := time.Now()
+	// the stream is only written from this goroutine, the stages are handed over from the prover's
+	stages := make(chan relayer.ProofStage)
+	type result struct {
+		proof *relayer.UpdateProof
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		proof, err := s.updates.ProveUpdate(ctx, req.Period, func(stage relayer.ProofStage) {
+			select {
+			case stages <- stage:
+			case <-ctx.Done():
+			}
+		})
+		done <- result{proof, err}
+	}()
+
+	ticker := time.NewTicker(s.progressInterval)
+	defer ticker.Stop()
+	stage := proverpb.Stage_STAGE_UNSPECIFIED
+	sendProgress := func() error {
+		return stream.Send(&proverpb.ProveScUpdateResponse{Event: &proverpb.ProveScUpdateResponse_Progress{
+			Progress: &proverpb.Progress{Stage: stage, ElapsedMs: uint64(time.Since(start).Milliseconds())},
+		}})
+	}
+	for {
+		select {
+		case st := <-stages:
+			stage = stageOf(st)
+			if err := sendProgress(); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if stage == proverpb.Stage_STAGE_UNSPECIFIED {
+				continue
+			}
+			if err := sendProgress(); err != nil {
+				return err
+			}
+		case res := <-done:
+			if res.err != nil {
+				return statusError(res.err)
+			}
+			proof, err := scUpdateProof(res.proof)
+			if err != nil {
+				return statusError(err)
+			}
+			return stream.Send(&proverpb.ProveScUpdateResponse{Event: &proverpb.ProveScUpdateResponse_Proof{Proof: proof}})
+		}
+	}
+}
+
+// stageOf returns the protobuf stage of st
+func stageOf(st relayer.ProofStage) proverpb.Stage {
+	switch st {
+	case relayer.StageFetching:
+		return proverpb.Stage_STAGE_FETCHING
+	case relayer.StageAssigning:
+		return proverpb.Stage_STAGE_ASSIGNING
+	case relayer.StageQueued:
+		return proverpb.Stage_STAGE_QUEUED
+	case relayer.StageProving:
+		return proverpb.Stage_STAGE_PROVING
+	default:
+		return proverpb.Stage_STAGE_UNSPECIFIED
+	}
+}
+
+// scUpdateProof converts an update proof to its protobuf message
+func scUpdateProof(p *relayer.UpdateProof) (*proverpb.ScUpdateProof, error) {
+	proofDataJSON, err := json.Marshal(p.ProofData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proof data: %w", err)
+	}
+	return &proverpb.ScUpdateProof{
+		Period:            p.Period,
+		ScPubKeysHash:     p.ScPubKeysHash,
+		NextScPubKeysHash: p.NextScPubKeysHash,
+		Backend:           string(p.Backend),
+		Proof:             p.Proof,
+		ProofDataJson:     proofDataJSON,
+	}, nil
+}
+
+// ProveReceipt returns the proof bundle of a successful transaction
+func (s *Server) ProveReceipt(ctx context.Context, req *proverpb.ProveReceiptRequest) (*proverpb.ProveReceiptResponse, error) {
+	if s.receipts == nil {
+		return nil, status.Error(codes.Unimplemented, "no execution RPC configured")
+	}
+	if len(req.TxHash) != common.HashLength {
+		return nil, status.Errorf(codes.InvalidArgument, "transaction hash of %d bytes", len(req.TxHash))
+	}
+	ctx, cancel := context.WithTimeout(ctx, receiptTimeout)
+	defer cancel()
+	bundle, err := s.receipts.TxStatusBundle(ctx, common.BytesToHash(req.TxHash), req.MaxGas)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &proverpb.ProveReceiptResponse{
+		BlockHash:             bundle.BlockHash,
+		BlockNumber:           bundle.BlockNumber,
+		Header:                bundle.Header,
+		ReceiptsRoot:          bundle.ReceiptsRoot,
+		TxHash:                bundle.TxHash,
+		TxIndex:               bundle.TxIndex,
+		Receipt:               bundle.Receipt,
+		ReceiptProof:          hexBytesList(bundle.ReceiptProof),
+		PrevReceipt:           bundle.PrevReceipt,
+		PrevReceiptProof:      hexBytesList(bundle.PrevReceiptProof),
+		Status:                bundle.Status,
+		CumulativeGasUsed:     bundle.CumulativeGasUsed,
+		PrevCumulativeGasUsed: bundle.PrevCumulativeGasUsed,
+		GasUsed:               bundle.GasUsed,
+		MaxGas:                bundle.MaxGas,
+	}, nil
+}
+
+// hexBytesList converts a list of HexBytes to a protobuf bytes list
+func hexBytesList(list []types.HexBytes) [][]byte {
+	out := make([][]byte, len(list))
+	for i, b := range list {
+		out[i] = b
+	}
+	return out
+}
+
+// ProveWitness proves the full witness of a relayer proving remotely, after checking it against its
+// SHA-256, and returns the proof with its own
+func (s *Server) ProveWitness(ctx context.Context, req *proverpb.ProveWitnessRequest) (*proverpb.ProveWitnessResponse, error) {
+	if s.witnesses == nil {
+		return nil, status.Error(codes.Unimplemented, "witnesses are not proven by this server")
+	}
+	if digest := sha256.Sum256(req.Witness); !bytes.Equal(digest[:], req.WitnessSha256) {
+		return nil, status.Error(codes.DataLoss, "witness does not match its sha256")
+	}
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	backend, proof, err := s.witnesses.ProveWitness(ctx, req.Circuit, req.CcsChecksum, req.Witness)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	digest := sha256.Sum256(proof)
+	return &proverpb.ProveWitnessResponse{Backend: string(backend), Proof: proof, ProofSha256: digest[:]}, nil
+}
+
+// Status reports the relayer status read from its files and the number of proofs in flight
+func (s *Server) Status(context.Context, *proverpb.StatusRequest) (*proverpb.StatusResponse, error) {
+	relayerStatus, err := relayer.ReadRelayerStatus(s.config)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &proverpb.StatusResponse{
+		ProvedPeriods:      relayerStatus.ProvedPeriods,
+		PendingSubmissions: relayerStatus.PendingSubmissions,
+		OverBudget:         relayerStatus.OverBudget,
+		ProofsInFlight:     uint32(s.inFlight.Load()),
+	}
+	for _, q := range relayerStatus.RecentFailures {
+		resp.RecentFailures = append(resp.RecentFailures, &proverpb.QuarantinedPeriod{
+			Period:      q.Period,
+			Files:       q.Files,
+			ModTimeUnix: q.ModTime.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// statusError maps err to the gRPC status returned to the client
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, relayer.ErrTxFailed), errors.Is(err, relayer.ErrTxOverGas):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, relayer.ErrWitnessRejected):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, relayer.ErrMemoryCeiling):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	relayer "github.com/kysee/zk-chains/provers"
+	"github.com/kysee/zk-chains/provers/server/proverpb"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// stagedProver goes through the stages of a proof, proving for provingTime
+type stagedProver struct {
+	provingTime time.Duration
+}
+
+func (p *stagedProver) ProveUpdate(ctx context.Context, period uint64, progress func(relayer.ProofStage)) (*relayer.UpdateProof, error) {
+	progress(relayer.StageFetching)
+	if period == 1 {
+		return nil, errors.New("no update for period 0")
+	}
+	progress(relayer.StageAssigning)
+	progress(relayer.StageProving)
+	time.Sleep(p.provingTime)
+	return &relayer.UpdateProof{
+		Period:    period,
+		Backend:   types.BackendPlonk,
+		Proof:     []byte{1, 2, 3},
+		ProofData: &types.PlonkProofData{Backend: types.BackendPlonk, Proof: []byte{1, 2, 3}},
+	}, nil
+}
+
+// bundleProver returns a bundle for the transactions within maxGas
+type bundleProver struct{}
+
+func (bundleProver) TxStatusBundle(_ context.Context, txHash common.Hash, maxGas uint64) (*types.TxStatusBundle, error) {
+	if maxGas != 0 && maxGas < 21000 {
+		return nil, relayer.ErrTxOverGas
+	}
+	return &types.TxStatusBundle{TxHash: txHash[:], ReceiptProof: []types.HexBytes{{1}, {2}}, GasUsed: 21000}, nil
+}
+
+// witnessProver proves the witnesses of the Square circuit, reversing them
+type witnessProver struct {
+	stagedProver
+}
+
+func (witnessProver) ProveWitness(_ context.Context, circuitName, _ string, witnessBlob []byte) (types.ProofBackend, []byte, error) {
+	if circuitName != "Square" {
+		return "", nil, relayer.ErrWitnessRejected
+	}
+	proof := make([]byte, len(witnessBlob))
+	for i, b := range witnessBlob {
+		proof[len(proof)-1-i] = b
+	}
+	return types.BackendGroth16, proof, nil
+}
+
+// dial serves s over an in-memory connection and returns a client of it
+func dial(t *testing.T, s *Server) proverpb.ProverClient {
+	return proverpb.NewProverClient(dialConn(t, s))
+}
+
+// dialConn serves s over an in-memory connection and returns the connection to it
+func dialConn(t *testing.T, s *Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Serve(ctx, lis, s) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestProveScUpdate(t *testing.T) {
+	s := New(&cfgtypes.Config{}, &stagedProver{provingTime: 50 * time.Millisecond}, nil)
+	s.progressInterval = 20 * time.Millisecond
+	client := dial(t, s)
+
+	stream, err := client.ProveScUpdate(context.Background(), &proverpb.ProveScUpdateRequest{Period: 1105})
+	require.NoError(t, err)
+	var stages []proverpb.Stage
+	var proof *proverpb.ScUpdateProof
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+		if progress := resp.GetProgress(); progress != nil {
+			stages = append(stages, progress.Stage)
+			continue
+		}
+		proof = resp.GetProof()
+	}
+
+	// each stage is reported, proving repeatedly while it takes
+	require.Equal(t, []proverpb.Stage{proverpb.Stage_STAGE_FETCHING, proverpb.Stage_STAGE_ASSIGNING, proverpb.Stage_STAGE_PROVING}, stages[:3])
+	require.Greater(t, len(stages), 3)
+	for _, stage := range stages[3:] {
+		require.Equal(t, proverpb.Stage_STAGE_PROVING, stage)
+	}
+	require.Equal(t, uint64(1105), proof.Period)
+	require.Equal(t, "plonk", proof.Backend)
+	require.JSONEq(t, `{"backend":"plonk","proof":"0x010203"}`, string(proof.ProofDataJson))
+
+	// failures end the stream with an error
+	stream, err = client.ProveScUpdate(context.Background(), &proverpb.ProveScUpdateRequest{Period: 1})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	stream, err = client.ProveScUpdate(context.Background(), &proverpb.ProveScUpdateRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestProveReceipt(t *testing.T) {
+	client := dial(t, New(&cfgtypes.Config{}, &stagedProver{}, bundleProver{}))
+	txHash := common.HexToHash("0x01")
+
+	resp, err := client.ProveReceipt(context.Background(), &proverpb.ProveReceiptRequest{TxHash: txHash[:]})
+	require.NoError(t, err)
+	require.Equal(t, txHash[:], resp.TxHash)
+	require.Equal(t, [][]byte{{1}, {2}}, resp.ReceiptProof)
+	require.Equal(t, uint64(21000), resp.GasUsed)
+
+	_, err = client.ProveReceipt(context.Background(), &proverpb.ProveReceiptRequest{TxHash: txHash[:], MaxGas: 20000})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.ProveReceipt(context.Background(), &proverpb.ProveReceiptRequest{TxHash: []byte{1}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// without an execution RPC
+	client = dial(t, New(&cfgtypes.Config{}, &stagedProver{}, nil))
+	_, err = client.ProveReceipt(context.Background(), &proverpb.ProveReceiptRequest{TxHash: txHash[:]})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestStatus(t *testing.T) {
+	proofDir := t.TempDir()
+	for _, name := range []string{"proof-period-1104.json", "proof-period-1104.json.submitted", "proof-period-1105.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(proofDir, name), []byte("{}"), 0644))
+	}
+	client := dial(t, New(&cfgtypes.Config{ProofDir: proofDir}, &stagedProver{}, nil))
+
+	resp, err := client.Status(context.Background(), &proverpb.StatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1104, 1105}, resp.ProvedPeriods)
+	require.Equal(t, []uint64{1105}, resp.PendingSubmissions)
+	require.Zero(t, resp.ProofsInFlight)
+}
+
+func TestProveWitness(t *testing.T) {
+	client := dial(t, New(&cfgtypes.Config{}, &witnessProver{}, nil))
+	witness := []byte{1, 2, 3}
+	digest := sha256.Sum256(witness)
+	resp, err := client.ProveWitness(context.Background(), &proverpb.ProveWitnessRequest{Circuit: "Square", Witness: witness, WitnessSha256: digest[:]})
+	require.NoError(t, err)
+	require.Equal(t, "groth16", resp.Backend)
+	require.Equal(t, []byte{3, 2, 1}, resp.Proof)
+	proofDigest := sha256.Sum256(resp.Proof)
+	require.Equal(t, proofDigest[:], resp.ProofSha256)
+
+	// witnesses altered on the way are not proven
+	_, err = client.ProveWitness(context.Background(), &proverpb.ProveWitnessRequest{Circuit: "Square", Witness: []byte{1, 2, 4}, WitnessSha256: digest[:]})
+	require.Equal(t, codes.DataLoss, status.Code(err))
+
+	// nor the ones of other circuits
+	_, err = client.ProveWitness(context.Background(), &proverpb.ProveWitnessRequest{Circuit: "Cube", Witness: witness, WitnessSha256: digest[:]})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// by a server that does not prove witnesses
+	_, err = dial(t, New(&cfgtypes.Config{}, &stagedProver{}, nil)).ProveWitness(context.Background(),
+		&proverpb.ProveWitnessRequest{Circuit: "Square", Witness: witness, WitnessSha256: digest[:]})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestHealth(t *testing.T) {
+	client := healthpb.NewHealthClient(dialConn(t, New(&cfgtypes.Config{}, &stagedProver{}, nil)))
+	for _, service := range []string{"", proverpb.Prover_ServiceDesc.ServiceName} {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	}
+}
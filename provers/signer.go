@@ -0,0 +1,165 @@
+package relayer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+// TxSigner signs the submissions of one account
+type TxSigner interface {
+	// Address returns the account the transactions are signed for
+	Address() common.Address
+	// SignTx returns tx signed for chainID
+	SignTx(ctx context.Context, tx *gethtypes.Transaction, chainID *big.Int) (*gethtypes.Transaction, error)
+}
+
+// KeySigner signs with a private key held in memory
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewKeySigner returns the signer of key
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *KeySigner) SignTx(_ context.Context, tx *gethtypes.Transaction, chainID *big.Int) (*gethtypes.Transaction, error) {
+	return gethtypes.SignTx(tx, gethtypes.LatestSignerForChainID(chainID), s.key)
+}
+
+// EnvSubmitterKey reads the hex (optionally 0x prefixed) secp256k1 private key of the submitter from the
+// named environment variable. A plaintext key is discouraged, see LoadKeystoreSigner and RemoteSigner.
+func EnvSubmitterKey(name string) (*ecdsa.PrivateKey, error) {
+	val := strings.TrimSpace(os.Getenv(name))
+	if val == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(val, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s is not a private key: %w", name, err)
+	}
+	return key, nil
+}
+
+// LoadKeystoreSigner decrypts the go-ethereum keystore file at path (as written by geth account new or
+// clef) with the password held by the named environment variable
+func LoadKeystoreSigner(path, passwordEnv string) (*KeySigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+	password, ok := os.LookupEnv(passwordEnv)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s holding the password of %s is not set", passwordEnv, path)
+	}
+	key, err := keystore.DecryptKey(keyJSON, strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", path, err)
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+// RemoteSigner signs with the eth_signTransaction method of a remote signer (web3signer in eth1 mode,
+// clef's ext API, ...) holding the key of the account, so that the relayer never sees it
+type RemoteSigner struct {
+	client  *rpc.Client
+	account common.Address
+}
+
+// DialRemoteSigner connects to the signer at url, which signs for account
+func DialRemoteSigner(ctx context.Context, url string, account common.Address) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer %s: %w", url, err)
+	}
+	return &RemoteSigner{client: client, account: account}, nil
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.account
+}
+
+// remoteTx is the transaction object of eth_signTransaction
+type remoteTx struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// SignTx has the signer sign tx and checks that the signed transaction is tx, from the account
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *gethtypes.Transaction, chainID *big.Int) (*gethtypes.Transaction, error) {
+	var raw hexutil.Bytes
+	err := s.client.CallContext(ctx, &raw, "eth_signTransaction", &remoteTx{
+		From:                 s.account,
+		To:                   tx.To(),
+		Gas:                  hexutil.Uint64(tx.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+		Value:                (*hexutil.Big)(tx.Value()),
+		Nonce:                hexutil.Uint64(tx.Nonce()),
+		Data:                 tx.Data(),
+		ChainID:              (*hexutil.Big)(chainID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+	signed := new(gethtypes.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %w", err)
+	}
+	from, err := gethtypes.Sender(gethtypes.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid signature: %w", err)
+	}
+	if from != s.account || signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() ||
+		*signed.To() != *tx.To() || signed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 || string(signed.Data()) != string(tx.Data()) {
+		return nil, fmt.Errorf("remote signer returned a transaction other than the one requested")
+	}
+	return signed, nil
+}
+
+// LoadSubmitterSigner returns the signer of the submitter configured by config, nil if none is: the
+// remote signer of Config.RemoteSigner for Config.SubmitterAddress, the keystore file of
+// Config.SubmitterKeystore, or the plaintext key of the Config.SubmitterKeyEnv variable.
+func LoadSubmitterSigner(ctx context.Context, config *cfgtypes.Config) (TxSigner, error) {
+	switch {
+	case config.RemoteSigner != "":
+		if !common.IsHexAddress(config.SubmitterAddress) {
+			return nil, fmt.Errorf("a remote signer needs the submitter address (--from)")
+		}
+		return DialRemoteSigner(ctx, config.RemoteSigner, common.HexToAddress(config.SubmitterAddress))
+	case config.SubmitterKeystore != "":
+		return LoadKeystoreSigner(config.SubmitterKeystore, config.SubmitterPasswordEnv)
+	case config.SubmitterKeyEnv != "" && os.Getenv(config.SubmitterKeyEnv) != "":
+		key, err := EnvSubmitterKey(config.SubmitterKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		config.Log().Warnf("the submitter key is read in plaintext from %s, prefer --submitter-keystore or --remote-signer\n", config.SubmitterKeyEnv)
+		return NewKeySigner(key), nil
+	default:
+		return nil, nil
+	}
+}
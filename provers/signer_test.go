@@ -0,0 +1,120 @@
+package relayer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+// signerService is the eth namespace of a remote signer holding key, shifting the nonces it signs by nonceShift
+type signerService struct {
+	key        *ecdsa.PrivateKey
+	nonceShift uint64
+}
+
+func (s *signerService) SignTransaction(args remoteTx) (hexutil.Bytes, error) {
+	tx, err := gethtypes.SignNewTx(s.key, gethtypes.LatestSignerForChainID(args.ChainID.ToInt()), &gethtypes.DynamicFeeTx{
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     uint64(args.Nonce) + s.nonceShift,
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	service := &signerService{key: key}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", service))
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	config := &cfgtypes.Config{RemoteSigner: srv.URL, SubmitterAddress: account.Hex()}
+	signer, err := LoadSubmitterSigner(context.Background(), config)
+	require.NoError(t, err)
+	require.Equal(t, account, signer.Address())
+
+	to := common.HexToAddress("0x01")
+	chainID := big.NewInt(11155111)
+	tx := gethtypes.NewTx(&gethtypes.DynamicFeeTx{ChainID: chainID, Nonce: 7, GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(21e9), Gas: 500_000, To: &to, Data: []byte{1, 2, 3}})
+	signed, err := signer.SignTx(context.Background(), tx, chainID)
+	require.NoError(t, err)
+	from, err := gethtypes.Sender(gethtypes.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, account, from)
+	require.Equal(t, uint64(7), signed.Nonce())
+
+	// a transaction other than the requested one is rejected
+	service.nonceShift = 1
+	_, err = signer.SignTx(context.Background(), tx, chainID)
+	require.ErrorContains(t, err, "other than the one requested")
+
+	// the signer needs the account it signs for
+	config.SubmitterAddress = ""
+	_, err = LoadSubmitterSigner(context.Background(), config)
+	require.Error(t, err)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "submitter.json")
+	require.NoError(t, os.WriteFile(path, keyJSON, 0600))
+
+	config := &cfgtypes.Config{SubmitterKeystore: path, SubmitterPasswordEnv: "TEST_SUBMITTER_PASSWORD", SubmitterKeyEnv: "TEST_SUBMITTER_KEY"}
+	t.Setenv("TEST_SUBMITTER_KEY", "0x"+common.Bytes2Hex(crypto.FromECDSA(key)))
+	_, err = LoadSubmitterSigner(context.Background(), config)
+	require.ErrorContains(t, err, "TEST_SUBMITTER_PASSWORD")
+
+	// the keystore takes precedence over the plaintext key
+	t.Setenv("TEST_SUBMITTER_PASSWORD", "correct horse\n")
+	signer, err := LoadSubmitterSigner(context.Background(), config)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	t.Setenv("TEST_SUBMITTER_PASSWORD", "wrong")
+	_, err = LoadSubmitterSigner(context.Background(), config)
+	require.Error(t, err)
+
+	config.SubmitterKeystore = ""
+	signer, err = LoadSubmitterSigner(context.Background(), config)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	// no submitter configured
+	config.SubmitterKeyEnv = ""
+	signer, err = LoadSubmitterSigner(context.Background(), config)
+	require.NoError(t, err)
+	require.Nil(t, signer)
+}
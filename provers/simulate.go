@@ -0,0 +1,239 @@
+package relayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/kysee/zk-chains/circuits"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+)
+
+// SimulationStep is the outcome of replaying one recorded update
+type SimulationStep struct {
+	File         string        `json:"file"`
+	Period       uint64        `json:"period"`
+	Slot         uint64        `json:"slot"`
+	Participants int           `json:"participants"`
+	Validated    bool          `json:"validated"`
+	Witness      bool          `json:"witness"`
+	Proved       bool          `json:"proved"`
+	Duration     time.Duration `json:"duration"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// SimulationReport summarizes a replay of recorded updates
+type SimulationReport struct {
+	FixturesDir string           `json:"fixtures_dir"`
+	Prove       bool             `json:"prove"`
+	Bootstrap   string           `json:"bootstrap"`
+	Steps       []SimulationStep `json:"steps"`
+	Passed      int              `json:"passed"`
+	Failed      int              `json:"failed"`
+	Duration    time.Duration    `json:"duration"`
+}
+
+// SimulateMain replays config.FixturesDir and prints (and optionally saves) the report
+func SimulateMain(config *cfgtypes.Config) {
+	report, err := Simulate(config)
+	if err != nil {
+		fatalf(config.Log(), "Simulation failed: %v", err)
+	}
+
+	for _, step := range report.Steps {
+		status := "ok"
+		if step.Error != "" {
+			status = "FAIL: " + step.Error
+		}
+		fmt.Printf("period %d (slot %d, %d participants, %s): %s\n",
+			step.Period, step.Slot, step.Participants, step.Duration.Round(time.Millisecond), status)
+	}
+	fmt.Printf("%d passed, %d failed in %s\n", report.Passed, report.Failed, report.Duration.Round(time.Millisecond))
+
+	if config.ReportPath != "" {
+		blob, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fatalf(config.Log(), "Failed to marshal report: %v", err)
+		}
+		if err := os.WriteFile(config.ReportPath, blob, 0644); err != nil {
+			fatalf(config.Log(), "Failed to write report: %v", err)
+		}
+		fmt.Printf("Report saved to %s\n", config.ReportPath)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// Simulate runs the relayer pipeline over the updates recorded in config.FixturesDir:
+// native validation (Merkle branch, sync aggregate signature and participation), witness assignment,
+// optional proving with the toy circuit (config.SimulateProve) and the committee hand-over.
+//
+// The first update (by attested slot) only bootstraps the current sync committee, as in Run.
+// The following updates must cover consecutive periods. A failing update is reported and
+// the simulation continues with the committee it would have handed over.
+func Simulate(config *cfgtypes.Config) (*SimulationReport, error) {
+	start := time.Now()
+	files, updates, err := loadFixtures(config.FixturesDir, config.Log())
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) < 2 {
+		return nil, fmt.Errorf("need at least 2 recorded updates in %s, found %d", config.FixturesDir, len(updates))
+	}
+
+	r, err := NewRelayer(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	var toyCcs constraint.ConstraintSystem
+	var toyPk groth16.ProvingKey
+	var toyVk groth16.VerifyingKey
+	if config.SimulateProve {
+		toyCcs, err = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit.Eth2ScToyCircuit{Preset: config.Preset})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile toy circuit: %w", err)
+		}
+		// NB! UNSAFE setup, the toy circuit only exercises the pipeline
+		toyPk, toyVk, err = groth16.Setup(toyCcs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup toy circuit: %w", err)
+		}
+	}
+
+	report := &SimulationReport{
+		FixturesDir: config.FixturesDir,
+		Prove:       config.SimulateProve,
+		Bootstrap:   files[0],
+	}
+	if err := r.setCurrentCommittee(&updates[0].Data.NextSyncCommittee); err != nil {
+		return nil, fmt.Errorf("bootstrap %s: %w", files[0], err)
+	}
+	expectedPeriod := config.Preset.Period(uint64(updates[0].Data.AttestedHeader.Beacon.Slot)) + 1
+
+	for i := 1; i < len(updates); i++ {
+		update := updates[i]
+		stepStart := time.Now()
+		step := SimulationStep{
+			File:   files[i],
+			Slot:   uint64(update.Data.AttestedHeader.Beacon.Slot),
+			Period: config.Preset.Period(uint64(update.Data.AttestedHeader.Beacon.Slot)),
+		}
+
+		err := func() error {
+			if step.Period != expectedPeriod {
+				return fmt.Errorf("expected period %d", expectedPeriod)
+			}
+			if err := r.validateUpdate(update, r.currentSc); err != nil {
+				return err
+			}
+			params, err := r.circuitParams()
+			if err != nil {
+				return err
+			}
+			participants, err := types.VerifySyncAggregate(r.currentSc, update, params.SigningDomain())
+			step.Participants = participants
+			if err != nil {
+				return err
+			}
+			if participants*3 < len(r.currentScPubkeys)*2 {
+				return fmt.Errorf("insufficient participation: %d/%d", participants, len(r.currentScPubkeys))
+			}
+			step.Validated = true
+
+			witness, err := r.buildWitness(update, r.currentScPubkeys)
+			if err != nil {
+				return err
+			}
+			if _, err := frontend.NewWitness(witness, ecc.BN254.ScalarField()); err != nil {
+				return fmt.Errorf("failed to create witness: %w", err)
+			}
+			step.Witness = true
+
+			if config.SimulateProve {
+				toyWitness, err := frontend.NewWitness(witness.ToyAssignment(), ecc.BN254.ScalarField())
+				if err != nil {
+					return fmt.Errorf("failed to create toy witness: %w", err)
+				}
+				proof, err := groth16.Prove(toyCcs, toyPk, toyWitness)
+				if err != nil {
+					return fmt.Errorf("toy proof generation failed: %w", err)
+				}
+				publicWitness, err := toyWitness.Public()
+				if err != nil {
+					return err
+				}
+				if err := groth16.Verify(proof, toyVk, publicWitness); err != nil {
+					return fmt.Errorf("toy proof verification failed: %w", err)
+				}
+				step.Proved = true
+			}
+			return nil
+		}()
+		if err != nil {
+			step.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Passed++
+		}
+
+		// Hand the committee over even on failure, so one bad fixture doesn't fail the rest
+		if err := r.setCurrentCommittee(&update.Data.NextSyncCommittee); err != nil {
+			return nil, fmt.Errorf("%s: %w", files[i], err)
+		}
+		expectedPeriod = step.Period + 1
+		step.Duration = time.Since(stepStart)
+		report.Steps = append(report.Steps, step)
+	}
+
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// loadFixtures reads every light client update (*.json) of dir, ordered by attested slot.
+// Files that are not light client updates are skipped.
+func loadFixtures(dir string, logger cfgtypes.Logger) ([]string, []*types.LightClientUpdate, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	var updates []*types.LightClientUpdate
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var update types.LightClientUpdate
+		if err := json.Unmarshal(data, &update); err != nil || len(update.Data.NextSyncCommittee.Pubkeys) == 0 {
+			logger.Infof("skipping %s: not a light client update\n", path)
+			continue
+		}
+		files = append(files, filepath.Base(path))
+		updates = append(updates, &update)
+	}
+
+	idx := make([]int, len(updates))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return updates[idx[a]].Data.AttestedHeader.Beacon.Slot < updates[idx[b]].Data.AttestedHeader.Beacon.Slot
+	})
+	sortedFiles := make([]string, len(idx))
+	sortedUpdates := make([]*types.LightClientUpdate, len(idx))
+	for i, j := range idx {
+		sortedFiles[i], sortedUpdates[i] = files[j], updates[j]
+	}
+	return sortedFiles, sortedUpdates, nil
+}
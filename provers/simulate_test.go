@@ -0,0 +1,59 @@
+package relayer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/kysee/zk-chains/types/slots"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulate(t *testing.T) {
+	fixtures := t.TempDir()
+	for _, name := range []string{"sc-update-1104.json", "sc-update-1105.json"} {
+		data, err := os.ReadFile(filepath.Join("..", "data", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(fixtures, name), data, 0644))
+	}
+	// non-update files are skipped
+	require.NoError(t, os.WriteFile(filepath.Join(fixtures, "proof-data.json"), []byte(`{"proof":[]}`), 0644))
+
+	config := cfgtypes.NewConfig("--fixtures", fixtures, "--prove", "true", "--root", t.TempDir())
+	report, err := Simulate(config)
+	require.NoError(t, err)
+	require.Equal(t, "sc-update-1104.json", report.Bootstrap)
+	require.Len(t, report.Steps, 1)
+	require.Equal(t, 1, report.Passed, report.Steps[0].Error)
+
+	step := report.Steps[0]
+	require.Equal(t, uint64(1105), step.Period)
+	require.True(t, step.Validated)
+	require.True(t, step.Witness)
+	require.True(t, step.Proved)
+	require.Greater(t, step.Participants, 512*2/3)
+
+	// a gap in the recorded periods is reported
+	require.NoError(t, os.Rename(filepath.Join(fixtures, "sc-update-1104.json"), filepath.Join(fixtures, "sc-update-1103.json")))
+	data, err := os.ReadFile(filepath.Join("..", "data", "sc-update-1105.json"))
+	require.NoError(t, err)
+	var update types.LightClientUpdate
+	require.NoError(t, json.Unmarshal(data, &update))
+	update.Data.AttestedHeader.Beacon.Slot += zrntcommon.Slot(2 * slots.SlotsPerPeriod(configs.Mainnet))
+	data, err = json.Marshal(update)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(fixtures, "sc-update-1107.json"), data, 0644))
+
+	config.SimulateProve = false
+	report, err = Simulate(config)
+	require.NoError(t, err)
+	require.Len(t, report.Steps, 2)
+	require.Equal(t, 1, report.Passed)
+	require.Equal(t, 1, report.Failed)
+	require.Contains(t, report.Steps[1].Error, "expected period 1106")
+}
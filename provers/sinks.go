@@ -0,0 +1,331 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sinkTimeout bounds the storage of one proof file in one sink
+const sinkTimeout = 60 * time.Second
+
+// ProofSink stores the proof files of the relayer. The files are always written to Config.ProofDir,
+// which the relayer keeps its state in, and copied to the sinks of Config.ProofSinks.
+type ProofSink interface {
+	// Store saves blob as the file name and returns where it was stored
+	Store(ctx context.Context, name string, blob []byte) (string, error)
+}
+
+// NewProofSink returns the sink of a Config.ProofSinks target:
+//
+//	dir or file:///dir                           a local directory
+//	s3://bucket/prefix[?endpoint=url&region=r]  S3-compatible object storage, credentials from AWS_ACCESS_KEY_ID,
+//	                                             AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//	ipfs+http(s)://host:port                     the HTTP API of an IPFS node, which pins the files
+//	http(s)://...                                a webhook receiving each file as a POST
+func NewProofSink(target string) (ProofSink, error) {
+	switch {
+	case target == "":
+		return nil, fmt.Errorf("no proof sink target")
+	case strings.HasPrefix(target, "s3://"):
+		return NewS3Sink(target)
+	case strings.HasPrefix(target, "ipfs+http://"), strings.HasPrefix(target, "ipfs+https://"):
+		return &IPFSSink{API: strings.TrimPrefix(target, "ipfs+"), Client: &http.Client{}}, nil
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		return &WebhookSink{URL: target, Client: &http.Client{}}, nil
+	default:
+		return DirSink(strings.TrimPrefix(target, "file://")), nil
+	}
+}
+
+// DirSink writes the files to a local directory, created if needed
+type DirSink string
+
+func (d DirSink) Store(_ context.Context, name string, blob []byte) (string, error) {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return "", fmt.Errorf("failed to create proof directory: %w", err)
+	}
+	p := filepath.Join(string(d), name)
+	if err := os.WriteFile(p, blob, 0644); err != nil {
+		return "", fmt.Errorf("failed to write proof file: %w", err)
+	}
+	return p, nil
+}
+
+// WebhookSink POSTs each file as JSON to URL, named by the X-Proof-Name header
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WebhookSink) Store(ctx context.Context, name string, blob []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(blob))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Proof-Name", name)
+	if _, err := doRequest(w.Client, req); err != nil {
+		return "", fmt.Errorf("webhook %s: %w", w.URL, err)
+	}
+	return w.URL, nil
+}
+
+// IPFSSink adds and pins the files with the HTTP API of an IPFS node (Kubo's /api/v0/add)
+type IPFSSink struct {
+	API    string
+	Client *http.Client
+}
+
+func (s *IPFSSink) Store(ctx context.Context, name string, blob []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(blob); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.API, "/")+"/api/v0/add?pin=true", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	respBody, err := doRequest(s.Client, req)
+	if err != nil {
+		return "", fmt.Errorf("ipfs %s: %w", s.API, err)
+	}
+	var added struct {
+		Hash string `json:"Hash"`
+	}
+	if err := json.Unmarshal(respBody, &added); err != nil || added.Hash == "" {
+		return "", fmt.Errorf("ipfs %s: unexpected response %q", s.API, respBody)
+	}
+	return "ipfs://" + added.Hash, nil
+}
+
+// S3Sink puts the files in a bucket of an S3-compatible object storage, signing the requests with
+// AWS Signature Version 4. Objects are addressed path-style, which S3 and its compatibles (MinIO,
+// R2, ...) all accept.
+type S3Sink struct {
+	Endpoint string
+	Region   string
+	Bucket   string
+	Prefix   string
+	// AccessKey, SecretKey and SessionToken (may be empty) are the credentials of the requests
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Client       *http.Client
+}
+
+// NewS3Sink returns the sink of an s3://bucket/prefix[?endpoint=url&region=r] target, with the
+// credentials of the AWS_* environment variables. The endpoint defaults to the one of AWS in region,
+// us-east-1 by default.
+func NewS3Sink(target string) (*S3Sink, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 target %q", target)
+	}
+	s := &S3Sink{
+		Region:       u.Query().Get("region"),
+		Endpoint:     u.Query().Get("endpoint"),
+		Bucket:       u.Host,
+		Prefix:       strings.Trim(u.Path, "/"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		Client:       &http.Client{},
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.Region)
+	}
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return nil, fmt.Errorf("S3 target %q needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", target)
+	}
+	return s, nil
+}
+
+func (s *S3Sink) Store(ctx context.Context, name string, blob []byte) (string, error) {
+	req, err := s.request(ctx, http.MethodPut, name, bytes.NewReader(blob))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, sha256Hex(blob), time.Now().UTC())
+	if _, err := doRequest(s.Client, req); err != nil {
+		return "", fmt.Errorf("s3 %s: %w", s.Endpoint, err)
+	}
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, path.Join(s.Prefix, name)), nil
+}
+
+// Put streams size bytes of body, whose hex SHA-256 is payloadHash, to the object name
+func (s *S3Sink) Put(ctx context.Context, name string, body io.Reader, size int64, payloadHash string) error {
+	req, err := s.request(ctx, http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	s.sign(req, payloadHash, time.Now().UTC())
+	if _, err := doRequest(s.Client, req); err != nil {
+		return fmt.Errorf("s3 %s: %w", s.Endpoint, err)
+	}
+	return nil
+}
+
+// Get opens the object name, it returns an error wrapping os.ErrNotExist if there is none
+func (s *S3Sink) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, sha256Hex(nil), time.Now().UTC())
+	return openResponse(s.Client, req)
+}
+
+// request returns a request of the object name, addressed path-style
+func (s *S3Sink) request(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", s.Endpoint, err)
+	}
+	endpoint.Path += "/" + s.Bucket + "/" + path.Join(s.Prefix, name)
+	return http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+}
+
+// sign adds the AWS Signature Version 4 headers of req, whose body has the hex SHA-256 payloadHash, at
+// time now
+func (s *S3Sink) sign(req *http.Request, payloadHash string, now time.Time) {
+	const service = "s3"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	// the canonical request signs the host, the content type and the x-amz-* headers
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.SecretKey, date, s.Region, service), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the Signature Version 4 key of secret for a date, region and service
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// doRequest sends req and returns the body of a 2xx response
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// openResponse sends req and returns the body of a 2xx response, to be closed by the caller. A 404 is
+// an error wrapping os.ErrNotExist.
+func openResponse(client *http.Client, req *http.Request) (io.ReadCloser, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp.Body, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", req.URL.Redacted(), os.ErrNotExist)
+	}
+	return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// storeProof copies a proof file to the configured sinks. A sink failing after the retries of the
+// retry policy is logged and skipped: the proof is in Config.ProofDir.
+func (r *Relayer) storeProof(ctx context.Context, name string, blob []byte) {
+	for _, sink := range r.sinks {
+		err := r.retryPolicy().Retry(ctx, func() error {
+			ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+			defer cancel()
+			location, err := sink.Store(ctx, name, blob)
+			if err == nil {
+				r.log().Infof("✓ Proof stored to %s\n", location)
+			}
+			return err
+		})
+		if err != nil {
+			r.log().Errorf("failed to store %s: %v\n", name, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package relayer
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProofSink(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	sink, err := NewProofSink("file:///tmp/proofs")
+	require.NoError(t, err)
+	require.Equal(t, DirSink("/tmp/proofs"), sink)
+	sink, err = NewProofSink("https://example.com/hook")
+	require.NoError(t, err)
+	require.IsType(t, &WebhookSink{}, sink)
+	sink, err = NewProofSink("ipfs+http://localhost:5001")
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:5001", sink.(*IPFSSink).API)
+	sink, err = NewProofSink("s3://proofs/sepolia/?region=eu-west-1")
+	require.NoError(t, err)
+	s3 := sink.(*S3Sink)
+	require.Equal(t, "proofs", s3.Bucket)
+	require.Equal(t, "sepolia", s3.Prefix)
+	require.Equal(t, "https://s3.eu-west-1.amazonaws.com", s3.Endpoint)
+
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err = NewProofSink("s3://proofs")
+	require.Error(t, err)
+	_, err = NewProofSink("")
+	require.Error(t, err)
+}
+
+func TestProofSinks(t *testing.T) {
+	ctx := context.Background()
+	blob := []byte(`{"proof":[]}`)
+	var got []*http.Request
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		got, bodies = append(got, req), append(bodies, string(body))
+		if req.URL.Path == "/api/v0/add" {
+			_, _ = io.WriteString(w, `{"Name":"proof-period-1105.json","Hash":"QmTest","Size":"12"}`)
+		}
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "copies")
+	location, err := DirSink(dir).Store(ctx, "proof-period-1105.json", blob)
+	require.NoError(t, err)
+	stored, err := os.ReadFile(location)
+	require.NoError(t, err)
+	require.Equal(t, blob, stored)
+
+	location, err = (&WebhookSink{URL: srv.URL + "/hook", Client: srv.Client()}).Store(ctx, "proof-period-1105.json", blob)
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/hook", location)
+	require.Equal(t, "proof-period-1105.json", got[0].Header.Get("X-Proof-Name"))
+	require.Equal(t, string(blob), bodies[0])
+
+	location, err = (&IPFSSink{API: srv.URL, Client: srv.Client()}).Store(ctx, "proof-period-1105.json", blob)
+	require.NoError(t, err)
+	require.Equal(t, "ipfs://QmTest", location)
+	require.Equal(t, "true", got[1].URL.Query().Get("pin"))
+	require.Contains(t, bodies[1], string(blob))
+
+	s3 := &S3Sink{Endpoint: srv.URL, Region: "us-east-1", Bucket: "proofs", Prefix: "sepolia", AccessKey: "AKIDEXAMPLE", SecretKey: "secret", Client: srv.Client()}
+	location, err = s3.Store(ctx, "proof-period-1105.json", blob)
+	require.NoError(t, err)
+	require.Equal(t, "s3://proofs/sepolia/proof-period-1105.json", location)
+	require.Equal(t, http.MethodPut, got[2].Method)
+	require.Equal(t, "/proofs/sepolia/proof-period-1105.json", got[2].URL.Path)
+	require.Equal(t, sha256Hex(blob), got[2].Header.Get("X-Amz-Content-Sha256"))
+	auth := got[2].Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), auth)
+	require.Contains(t, auth, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date,")
+
+	// a rejected upload is an error
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	_, err = (&WebhookSink{URL: failing.URL, Client: failing.Client()}).Store(ctx, "proof-period-1105.json", blob)
+	require.ErrorContains(t, err, "403")
+}
+
+func TestSigningKey(t *testing.T) {
+	// the example of the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	require.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
@@ -0,0 +1,179 @@
+package relayer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// RelayerState is what a running relayer is doing, served with the status by GET /status
+type RelayerState int
+
+const (
+	// StateBootstrapping is the start of a run: the committee of the first period is bootstrapped from
+	// the trusted block root, or read from the update of the period before it
+	StateBootstrapping RelayerState = iota
+	// StateCatchingUp waits for the next period to be prepared while it is behind the beacon head
+	StateCatchingUp
+	// StateSynced waits for the update of the next period to be published
+	StateSynced
+	// StateProving generates the proof of a period
+	StateProving
+	// StateSubmitting waits for the proofs queued for submission, with nothing to prove
+	StateSubmitting
+	// StateFaulted is a run that failed: it restarts at the failed period, see Relayer.Run, or stops
+	StateFaulted
+)
+
+// maxStateTransitions is the number of transitions kept for the status
+const maxStateTransitions = 16
+
+var stateNames = [...]string{"bootstrapping", "catching-up", "synced", "proving", "submitting", "faulted"}
+
+func (s RelayerState) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return "unknown"
+	}
+	return stateNames[s]
+}
+
+// MarshalText encodes the state as its name
+func (s RelayerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a state
+func (s *RelayerState) UnmarshalText(text []byte) error {
+	for i, name := range stateNames {
+		if name == string(text) {
+			*s = RelayerState(i)
+			return nil
+		}
+	}
+	return errors.New("unknown relayer state " + string(text))
+}
+
+// StateTransition is a change of the state of a relayer
+type StateTransition struct {
+	From   RelayerState `json:"from"`
+	To     RelayerState `json:"to"`
+	Period uint64       `json:"period"`
+	// Reason is the error that faulted the relayer, or how it recovers from it
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// StateReport is the state of a running relayer
+type StateReport struct {
+	State RelayerState `json:"state"`
+	// Period is the period the state is about: the one bootstrapped, proven or waited for
+	Period uint64    `json:"period"`
+	Since  time.Time `json:"since"`
+	// LastError is the last failure of the run, kept once the relayer recovered from it
+	LastError string `json:"lastError,omitempty"`
+	// Transitions are the latest transitions, oldest first
+	Transitions []StateTransition `json:"transitions"`
+}
+
+// StateReporter reports the state of a running relayer, *Relayer implements it
+type StateReporter interface {
+	State() *StateReport
+}
+
+// stateMachine holds the state of a relayer. Its zero value is bootstrapping.
+type stateMachine struct {
+	mu          sync.Mutex
+	state       RelayerState
+	period      uint64
+	since       time.Time
+	lastError   string
+	transitions []StateTransition
+}
+
+// transition moves to state at period (nil keeps the current one) if from accepts the current state
+// (nil accepts any), recording err as the last error. It returns the transition, false if the state
+// was not left or changed.
+func (m *stateMachine) transition(from func(RelayerState) bool, state RelayerState, at *uint64, err error) (StateTransition, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if from != nil && !from(m.state) {
+		return StateTransition{}, false
+	}
+	period := m.period
+	if at != nil {
+		period = *at
+	}
+	if err != nil {
+		m.lastError = err.Error()
+	}
+	if state == m.state && err == nil {
+		m.period = period
+		return StateTransition{}, false
+	}
+	t := StateTransition{From: m.state, To: state, Period: period, Time: time.Now()}
+	if err != nil {
+		t.Reason = err.Error()
+	}
+	m.state, m.period, m.since = state, period, t.Time
+	if len(m.transitions) == maxStateTransitions {
+		m.transitions = m.transitions[1:]
+	}
+	m.transitions = append(m.transitions, t)
+	return t, true
+}
+
+// report returns a copy of the state
+func (m *stateMachine) report() *StateReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &StateReport{
+		State:       m.state,
+		Period:      m.period,
+		Since:       m.since,
+		LastError:   m.lastError,
+		Transitions: append([]StateTransition{}, m.transitions...),
+	}
+}
+
+// State returns the state of the relayer
+func (r *Relayer) State() *StateReport {
+	return r.state.report()
+}
+
+// setState moves the relayer to state at period, logging the transition. err is why, for StateFaulted.
+func (r *Relayer) setState(state RelayerState, period uint64, err error) {
+	r.logTransition(r.state.transition(nil, state, &period, err))
+}
+
+// idleState is the state of a relayer waiting for the next period to be prepared: submitting while
+// proofs are queued for submission, then catching up while the beacon head is more than one period
+// ahead, else synced
+func (r *Relayer) idleState() RelayerState {
+	switch {
+	case r.submitting.Load() > 0:
+		return StateSubmitting
+	case r.behind.Load():
+		return StateCatchingUp
+	}
+	return StateSynced
+}
+
+// settle moves a waiting relayer to its idleState once a submission is done
+func (r *Relayer) settle() {
+	waiting := func(s RelayerState) bool {
+		return s == StateSynced || s == StateCatchingUp || s == StateSubmitting
+	}
+	r.logTransition(r.state.transition(waiting, r.idleState(), nil, nil))
+}
+
+// logTransition logs t if ok
+func (r *Relayer) logTransition(t StateTransition, ok bool) {
+	if !ok {
+		return
+	}
+	if t.Reason != "" {
+		r.log().Infof("Relayer %s -> %s at period %d: %s\n", t.From, t.To, t.Period, t.Reason)
+		return
+	}
+	r.log().Infof("Relayer %s -> %s at period %d\n", t.From, t.To, t.Period)
+}
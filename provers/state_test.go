@@ -0,0 +1,103 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayerState(t *testing.T) {
+	config := &cfgtypes.Config{ProofDir: t.TempDir(), QuarantineDir: t.TempDir()}
+	r := &Relayer{config: config}
+	require.Equal(t, StateBootstrapping, r.State().State)
+
+	// waiting for a period, the relayer is submitting while proofs are queued
+	r.setState(StateProving, 1105, nil)
+	r.submitting.Add(1)
+	r.setState(r.idleState(), 1106, nil)
+	require.Equal(t, StateSubmitting, r.State().State)
+	r.submitting.Add(-1)
+	r.settle()
+	report := r.State()
+	require.Equal(t, StateSynced, report.State)
+	require.Equal(t, uint64(1106), report.Period)
+
+	// but not once it proves again
+	r.setState(StateProving, 1106, nil)
+	r.settle()
+	require.Equal(t, StateProving, r.State().State)
+
+	// a failure is kept once recovered from, and the latest transitions are
+	r.setState(StateFaulted, 1106, errors.New("out of memory"))
+	for i := range maxStateTransitions - 1 {
+		r.setState(StateProving+RelayerState(i%2), 1107, nil)
+	}
+	report = r.State()
+	require.Equal(t, "out of memory", report.LastError)
+	require.Len(t, report.Transitions, maxStateTransitions)
+	require.Equal(t, StateFaulted, report.Transitions[0].To)
+
+	// the state is served with the status
+	srv := httptest.NewServer(NewHTTPHandler(config, nil, r))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var status struct {
+		State struct {
+			State  string `json:"state"`
+			Period uint64 `json:"period"`
+		} `json:"state"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Equal(t, "proving", status.State.State)
+	require.Equal(t, uint64(1107), status.State.Period)
+
+	var state RelayerState
+	require.NoError(t, state.UnmarshalText([]byte("catching-up")))
+	require.Equal(t, StateCatchingUp, state)
+	require.Error(t, state.UnmarshalText([]byte("idle")))
+}
+
+func TestRunRecovery(t *testing.T) {
+	update, _ := loadTestSubmission(t)
+	fetcher := cfgtypes.NewFakeFetcher()
+	fetcher.SetUpdate(1104, update)
+	fetcher.Fail("ScUpdate", errors.New("connection refused"), 1)
+	config := &cfgtypes.Config{InitPeriod: 1104, RetryBackoff: time.Millisecond, RetryMaxBackoff: time.Millisecond}
+	r := &Relayer{config: config, fetcher: fetcher}
+
+	// the failed start is retried, then the relayer waits for the update of period 1105
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	require.Eventually(t, func() bool { return r.State().State == StateSynced }, 5*time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	report := r.State()
+	require.Equal(t, uint64(1105), report.Period)
+	require.Contains(t, report.LastError, "connection refused")
+	var states []RelayerState
+	for _, transition := range report.Transitions {
+		states = append(states, transition.To)
+	}
+	require.Equal(t, []RelayerState{StateFaulted, StateBootstrapping, StateSynced}, states)
+
+	// a committee that cannot be parsed faults the relayer without retrying
+	broken := *update
+	broken.Data.NextSyncCommittee.Pubkeys = broken.Data.NextSyncCommittee.Pubkeys[:1]
+	fetcher.SetUpdate(1104, &broken)
+	calls := fetcher.Calls("ScUpdate")
+	r = &Relayer{config: config, fetcher: fetcher}
+	require.ErrorContains(t, r.Run(context.Background()), "expected 512 pubkeys")
+	require.Equal(t, calls+1, fetcher.Calls("ScUpdate"))
+	require.Equal(t, StateFaulted, r.State().State)
+}
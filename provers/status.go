@@ -0,0 +1,229 @@
+package relayer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+)
+
+const (
+	proofFilePrefix = "proof-period-"
+	proofFileSuffix = ".json"
+	// submittedSuffix marks a proof that has been accepted on-chain: proof-period-N.json.submitted
+	submittedSuffix = ".submitted"
+	// maxRecentFailures is the number of quarantined periods reported by the status command
+	maxRecentFailures = 10
+)
+
+// proofFileName returns the name of the proof file of the given period inside Config.ProofDir
+func proofFileName(period uint64) string {
+	return fmt.Sprintf("%s%d%s", proofFilePrefix, period, proofFileSuffix)
+}
+
+// RelayerStatus is the relayer state reconstructed from the files it leaves on disk
+type RelayerStatus struct {
+	// ProvedPeriods are the periods with a proof file, in ascending order
+	ProvedPeriods []uint64 `json:"provedPeriods"`
+	// PendingSubmissions are the proved periods that are not marked as submitted, in ascending order
+	PendingSubmissions []uint64 `json:"pendingSubmissions"`
+	// PendingByDestination are the pending submissions of each named destination, when the proofs are
+	// submitted to several, see Config.DestinationsPath
+	PendingByDestination map[string][]uint64 `json:"pendingByDestination,omitempty"`
+	// OverBudget are the proved periods set aside because their submission exceeded the gas budget
+	OverBudget []uint64 `json:"overBudget"`
+	// RecentFailures are the most recently quarantined periods, newest first
+	RecentFailures []QuarantinedPeriod `json:"recentFailures"`
+	// State is the state of the running relayer serving the status, nil when read from its files only
+	State *StateReport `json:"state,omitempty"`
+}
+
+// QuarantinedPeriod describes the quarantine files left by a failed proof generation
+type QuarantinedPeriod struct {
+	Period  uint64    `json:"period"`
+	Files   []string  `json:"files"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// LastProvedPeriod returns the highest proved period, false if no proof was generated yet
+func (s *RelayerStatus) LastProvedPeriod() (uint64, bool) {
+	if len(s.ProvedPeriods) == 0 {
+		return 0, false
+	}
+	return s.ProvedPeriods[len(s.ProvedPeriods)-1], true
+}
+
+// ReadRelayerStatus inspects the proof and quarantine directories without modifying them,
+// so it can be used while the relayer is stopped. Missing directories are treated as empty.
+func ReadRelayerStatus(config *cfgtypes.Config) (*RelayerStatus, error) {
+	status := &RelayerStatus{}
+
+	entries, err := readDirIfExists(config.ProofDir)
+	if err != nil {
+		return nil, err
+	}
+	submitted := make(map[uint64]bool)
+	// submittedTo are the periods accepted by each named destination
+	submittedTo := make(map[string]map[uint64]bool)
+	if config.DestinationsPath != "" {
+		destinations, err := LoadDestinations(config.DestinationsPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range destinations {
+			submittedTo[d.Name] = make(map[uint64]bool)
+		}
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, submittedSuffix) {
+			name = strings.TrimSuffix(name, submittedSuffix)
+			if period, ok := parsePeriod(name, proofFilePrefix, proofFileSuffix); ok {
+				submitted[period] = true
+			} else if i := strings.LastIndex(name, "."); i >= 0 {
+				// proof-period-N.json.<destination>.submitted, see submittedMarker
+				if period, ok := parsePeriod(name[:i], proofFilePrefix, proofFileSuffix); ok {
+					if submittedTo[name[i+1:]] == nil {
+						submittedTo[name[i+1:]] = make(map[uint64]bool)
+					}
+					submittedTo[name[i+1:]][period] = true
+				}
+			}
+			continue
+		}
+		if strings.HasSuffix(name, overBudgetSuffix) {
+			if period, ok := parsePeriod(strings.TrimSuffix(name, overBudgetSuffix), proofFilePrefix, proofFileSuffix); ok {
+				status.OverBudget = append(status.OverBudget, period)
+			}
+			continue
+		}
+		if period, ok := parsePeriod(name, proofFilePrefix, proofFileSuffix); ok {
+			status.ProvedPeriods = append(status.ProvedPeriods, period)
+		}
+	}
+	sort.Slice(status.ProvedPeriods, func(i, j int) bool { return status.ProvedPeriods[i] < status.ProvedPeriods[j] })
+	sort.Slice(status.OverBudget, func(i, j int) bool { return status.OverBudget[i] < status.OverBudget[j] })
+	for _, period := range status.ProvedPeriods {
+		if !submitted[period] {
+			status.PendingSubmissions = append(status.PendingSubmissions, period)
+		}
+	}
+	for name, periods := range submittedTo {
+		if status.PendingByDestination == nil {
+			status.PendingByDestination = make(map[string][]uint64)
+		}
+		pending := []uint64{}
+		for _, period := range status.PendingSubmissions {
+			if !periods[period] {
+				pending = append(pending, period)
+			}
+		}
+		status.PendingByDestination[name] = pending
+	}
+
+	entries, err = readDirIfExists(config.QuarantineDir)
+	if err != nil {
+		return nil, err
+	}
+	failures := make(map[uint64]*QuarantinedPeriod)
+	for _, entry := range entries {
+		// quarantine files are named period-N-update.json[.enc] and period-N-witness.bin[.enc]
+		name := entry.Name()
+		rest, ok := strings.CutPrefix(name, "period-")
+		if !ok {
+			continue
+		}
+		num, _, ok := strings.Cut(rest, "-")
+		if !ok {
+			continue
+		}
+		period, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := failures[period]
+		if !ok {
+			f = &QuarantinedPeriod{Period: period}
+			failures[period] = f
+		}
+		f.Files = append(f.Files, name)
+		if info.ModTime().After(f.ModTime) {
+			f.ModTime = info.ModTime()
+		}
+	}
+	for _, f := range failures {
+		status.RecentFailures = append(status.RecentFailures, *f)
+	}
+	sort.Slice(status.RecentFailures, func(i, j int) bool {
+		return status.RecentFailures[i].ModTime.After(status.RecentFailures[j].ModTime)
+	})
+	if len(status.RecentFailures) > maxRecentFailures {
+		status.RecentFailures = status.RecentFailures[:maxRecentFailures]
+	}
+
+	return status, nil
+}
+
+// StatusMain prints the state of a (possibly stopped) relayer
+func StatusMain(config *cfgtypes.Config) {
+	status, err := ReadRelayerStatus(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read relayer status: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Proof directory:      %s\n", config.ProofDir)
+	fmt.Printf("Quarantine directory: %s\n", config.QuarantineDir)
+	if last, ok := status.LastProvedPeriod(); ok {
+		fmt.Printf("Last proved period:   %d (%d proofs)\n", last, len(status.ProvedPeriods))
+	} else {
+		fmt.Println("Last proved period:   none")
+	}
+	fmt.Printf("Pending submissions:  %d %v\n", len(status.PendingSubmissions), status.PendingSubmissions)
+	names := make([]string, 0, len(status.PendingByDestination))
+	for name := range status.PendingByDestination {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  to %s: %d %v\n", name, len(status.PendingByDestination[name]), status.PendingByDestination[name])
+	}
+	if len(status.OverBudget) > 0 {
+		fmt.Printf("Over gas budget:      %d %v\n", len(status.OverBudget), status.OverBudget)
+	}
+	fmt.Printf("Recent failures:      %d\n", len(status.RecentFailures))
+	for _, f := range status.RecentFailures {
+		fmt.Printf("  period %d at %s: %s\n", f.Period, f.ModTime.Format(time.RFC3339), strings.Join(f.Files, ", "))
+	}
+}
+
+func readDirIfExists(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Clean(dir), err)
+	}
+	return entries, nil
+}
+
+func parsePeriod(name, prefix, suffix string) (uint64, bool) {
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	period, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return period, true
+}
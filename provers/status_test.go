@@ -0,0 +1,57 @@
+package relayer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRelayerStatus(t *testing.T) {
+	config := &cfgtypes.Config{
+		ProofDir:      filepath.Join(t.TempDir(), "output"),
+		QuarantineDir: filepath.Join(t.TempDir(), "quarantine"),
+	}
+
+	// nothing on disk yet
+	status, err := ReadRelayerStatus(config)
+	require.NoError(t, err)
+	_, ok := status.LastProvedPeriod()
+	require.False(t, ok)
+
+	require.NoError(t, os.MkdirAll(config.ProofDir, 0755))
+	for _, name := range []string{
+		proofFileName(1105), proofFileName(1106), proofFileName(1107),
+		proofFileName(1105) + submittedSuffix,
+		proofFileName(1104) + overBudgetSuffix,
+		"unrelated.txt",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(config.ProofDir, name), nil, 0644))
+	}
+
+	_, err = WriteSensitiveArtifact(filepath.Join(config.QuarantineDir, "period-1100-update.json"), []byte("{}"), nil)
+	require.NoError(t, err)
+	_, err = WriteSensitiveArtifact(filepath.Join(config.QuarantineDir, "period-1100-witness.bin"), []byte{1}, nil)
+	require.NoError(t, err)
+	_, err = WriteSensitiveArtifact(filepath.Join(config.QuarantineDir, "period-1108-update.json"), []byte("{}"), nil)
+	require.NoError(t, err)
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(config.QuarantineDir, "period-1100-update.json"), old, old))
+	require.NoError(t, os.Chtimes(filepath.Join(config.QuarantineDir, "period-1100-witness.bin"), old, old))
+
+	status, err = ReadRelayerStatus(config)
+	require.NoError(t, err)
+	last, ok := status.LastProvedPeriod()
+	require.True(t, ok)
+	require.Equal(t, uint64(1107), last)
+	require.Equal(t, []uint64{1105, 1106, 1107}, status.ProvedPeriods)
+	require.Equal(t, []uint64{1106, 1107}, status.PendingSubmissions)
+	require.Equal(t, []uint64{1104}, status.OverBudget)
+	require.Len(t, status.RecentFailures, 2)
+	require.Equal(t, uint64(1108), status.RecentFailures[0].Period)
+	require.Equal(t, uint64(1100), status.RecentFailures[1].Period)
+	require.Len(t, status.RecentFailures[1].Files, 2)
+}
@@ -0,0 +1,132 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kysee/zk-chains/types"
+)
+
+// submissionLedgerFile is the file of Config.ProofDir recording the submissions accepted by each
+// destination, see submissionLedger
+const submissionLedgerFile = "submissions.json"
+
+// SubmissionInputsHash is the keccak256 of the public inputs a light client is submitted for update:
+// its attested slot, next sync committee and execution block hash and number. The proofs of the same
+// update share it, whichever backend or run proved them.
+func SubmissionInputsHash(update *types.LightClientUpdate) (common.Hash, error) {
+	slot, nextSc, execBlockHash, execBlockNumber, err := submissionInputs(update)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(common.BigToHash(slot).Bytes(), nextSc, execBlockHash[:], common.BigToHash(execBlockNumber).Bytes()), nil
+}
+
+// acceptedSubmission is a submission of a period accepted by a destination
+type acceptedSubmission struct {
+	InputsHash common.Hash `json:"inputsHash"`
+	// TxHash is the submission transaction, zero when another relayer submitted the period
+	TxHash common.Hash `json:"txHash"`
+}
+
+// submissionLedger is the content of submissionLedgerFile: the accepted submissions by destination
+// name and period. A light client accepts a single update per period, so the relayer does not submit a
+// period again once it is in the ledger, after a restart or when its proof is generated again.
+type submissionLedger map[string]map[uint64]acceptedSubmission
+
+// readSubmissionLedger reads the ledger of the proof directory, empty if there is none yet.
+// r.ledgerMu must be held.
+func (r *Relayer) readSubmissionLedger() (submissionLedger, error) {
+	ledger := submissionLedger{}
+	data, err := os.ReadFile(filepath.Join(r.config.ProofDir, submissionLedgerFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", submissionLedgerFile, err)
+	}
+	return ledger, nil
+}
+
+// updateSubmissionLedger records accepted as the submission of period accepted by d, or forgets it if
+// nil, and saves the ledger through a temporary file
+func (r *Relayer) updateSubmissionLedger(d *destination, period uint64, accepted *acceptedSubmission) error {
+	r.ledgerMu.Lock()
+	defer r.ledgerMu.Unlock()
+	ledger, err := r.readSubmissionLedger()
+	if err != nil {
+		return err
+	}
+	if accepted == nil {
+		delete(ledger[d.name], period)
+	} else {
+		if ledger[d.name] == nil {
+			ledger[d.name] = make(map[uint64]acceptedSubmission)
+		}
+		ledger[d.name][period] = *accepted
+	}
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.config.ProofDir, submissionLedgerFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// acceptedBefore returns the submission of period d accepted, nil if it still expects one. The light
+// client of d is asked when its backend can be called: it accepted the period once it expects a later
+// one, maybe from another relayer, which is then recorded. A ledger entry of a period the light client
+// still expects (e.g. it was redeployed) is stale: it is forgotten, and the period submitted again.
+// Without the light client, the ledger is trusted.
+func (r *Relayer) acceptedBefore(ctx context.Context, d *destination, period uint64, inputsHash common.Hash) (*acceptedSubmission, error) {
+	r.ledgerMu.Lock()
+	ledger, err := r.readSubmissionLedger()
+	r.ledgerMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	recorded, ok := ledger[d.name][period]
+
+	caller, isCaller := d.submitter.backend.(ethereum.ContractCaller)
+	if !isCaller {
+		if !ok {
+			return nil, nil
+		}
+		return &recorded, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, gasEstimateTimeout)
+	defer cancel()
+	state, err := ReadLightClientState(ctx, caller, d.submitter.to)
+	if err != nil {
+		// the submission itself fails if the light client cannot be reached
+		r.log().Warnf("failed to read the light client of %s, relying on the submission ledger: %v\n", d, err)
+		if !ok {
+			return nil, nil
+		}
+		return &recorded, nil
+	}
+	switch {
+	case state.Period > period && ok:
+		return &recorded, nil
+	case state.Period > period:
+		accepted := &acceptedSubmission{InputsHash: inputsHash}
+		return accepted, r.updateSubmissionLedger(d, period, accepted)
+	case ok:
+		r.log().Warnf("%s expects period %d, the submission of period %d recorded in %s is replaced\n", d, state.Period, period, submissionLedgerFile)
+		return nil, r.updateSubmissionLedger(d, period, nil)
+	}
+	return nil, nil
+}
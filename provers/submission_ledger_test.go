@@ -0,0 +1,87 @@
+package relayer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+)
+
+// callingBackend is a chain whose light client reports its state
+type callingBackend struct {
+	*chainBackend
+	*lightClientCaller
+}
+
+func TestSubmissionLedger(t *testing.T) {
+	update, proofData := loadTestSubmission(t)
+	inputsHash, err := SubmissionInputsHash(update)
+	require.NoError(t, err)
+	other := *update
+	other.Data.AttestedHeader.Beacon.Slot++
+	otherHash, err := SubmissionInputsHash(&other)
+	require.NoError(t, err)
+	require.NotEqual(t, inputsHash, otherHash)
+
+	config := &cfgtypes.Config{ProofDir: t.TempDir(), RetryMaxAttempts: 1}
+	r := &Relayer{config: config}
+	newDestination := func(caller *lightClientCaller) *chainBackend {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		chain := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100,
+			revertPrefix: 0xff, receipts: map[common.Hash]*gethtypes.Receipt{}}
+		var backend SubmitterBackend = chain
+		if caller != nil {
+			backend = &callingBackend{chain, caller}
+		}
+		s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), common.HexToAddress("0x01"), 0, 1)
+		require.NoError(t, err)
+		s.pollInterval = time.Millisecond
+		r.destinations = []*destination{{name: defaultDestination, submitter: s}}
+		return chain
+	}
+	submit := func(period uint64) string {
+		proofPath := filepath.Join(config.ProofDir, proofFileName(period))
+		require.NoError(t, os.WriteFile(proofPath, []byte("{}"), 0644))
+		r.submitProof(context.Background(), r.destinations[0], CircuitScUpdate, update, proofData, proofPath)
+		return proofPath
+	}
+
+	// a period accepted is recorded, and not submitted again after a restart
+	chain := newDestination(nil)
+	proofPath := submit(1105)
+	require.Len(t, chain.sent, 1)
+	require.NoError(t, os.Remove(proofPath+submittedSuffix))
+	r = &Relayer{config: config, destinations: r.destinations}
+	submit(1105)
+	require.Len(t, chain.sent, 1)
+	require.FileExists(t, proofPath+submittedSuffix)
+	ledger, err := r.readSubmissionLedger()
+	require.NoError(t, err)
+	require.Equal(t, inputsHash, ledger[defaultDestination][1105].InputsHash)
+	require.Equal(t, chain.sent[0].Hash(), ledger[defaultDestination][1105].TxHash)
+
+	// nor is a period another relayer submitted
+	caller := &lightClientCaller{period: 1107}
+	chain = newDestination(caller)
+	submit(1106)
+	require.Empty(t, chain.sent)
+	ledger, err = r.readSubmissionLedger()
+	require.NoError(t, err)
+	require.Equal(t, acceptedSubmission{InputsHash: inputsHash}, ledger[defaultDestination][1106])
+
+	// a light client expecting a recorded period again, e.g. redeployed, is submitted to
+	caller.period = 1105
+	submit(1106)
+	require.Len(t, chain.sent, 1)
+	ledger, err = r.readSubmissionLedger()
+	require.NoError(t, err)
+	require.Equal(t, chain.sent[0].Hash(), ledger[defaultDestination][1106].TxHash)
+}
@@ -0,0 +1,286 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kysee/zk-chains/types"
+)
+
+const (
+	// submitTimeout bounds the sending and confirmation of one submission
+	submitTimeout = 10 * time.Minute
+	// receiptPollInterval is the delay between two receipt lookups of a sent submission
+	receiptPollInterval = 2 * time.Second
+	// gasMarginPercent is added to the estimate of a submission to set its gas limit
+	gasMarginPercent = 20
+)
+
+var (
+	// ErrSubmissionReverted is returned when a submission is mined with a failed status
+	ErrSubmissionReverted = errors.New("submission reverted")
+	// ErrSubmissionUnconfirmed is returned when a submission was sent but its receipt could not be
+	// confirmed, it may still be mined
+	ErrSubmissionUnconfirmed = errors.New("submission unconfirmed")
+)
+
+// verifierPlonkABI is the PLONK verifier generated by gnark, the Groth16 one is encoded by EncodeVerifierCall
+// since its input array has the size of the circuit's public witness
+const verifierPlonkABI = `[
+	{"type":"function","name":"Verify","stateMutability":"view","outputs":[{"name":"success","type":"bool"}],"inputs":[
+		{"name":"proof","type":"bytes"},
+		{"name":"public_inputs","type":"uint256[]"}]}
+]`
+
+var parsedVerifierPlonkABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(verifierPlonkABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// SubmitterBackend sends transactions to the destination chain, *ethclient.Client implements it
+type SubmitterBackend interface {
+	GasEstimator
+	ChainID(ctx context.Context) (*big.Int, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*gethtypes.Header, error)
+	SendTransaction(ctx context.Context, tx *gethtypes.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*gethtypes.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Submitter signs and sends proof submissions from one account and waits for their receipts.
+// It tracks the nonce of the account itself so that consecutive submissions do not wait for each
+// other to be mined, and refetches it from the node after a failed send.
+type Submitter struct {
+	backend SubmitterBackend
+	signer  TxSigner
+	from    common.Address
+	to      common.Address
+	chainID *big.Int
+	// gasLimit caps the gas of a submission, 0 means no cap
+	gasLimit uint64
+	// confirmations is the number of blocks, the inclusion one included, a receipt waits for
+	confirmations uint64
+	// pollInterval is the delay between two receipt lookups
+	pollInterval time.Duration
+	// feePolicy prices the submissions and replaces the stuck ones
+	feePolicy FeePolicy
+
+	mu       sync.Mutex
+	nonce    uint64
+	hasNonce bool
+}
+
+// NewSubmitter creates a Submitter sending from the account of signer to the contract at to
+func NewSubmitter(ctx context.Context, backend SubmitterBackend, signer TxSigner, to common.Address, gasLimit, confirmations uint64) (*Submitter, error) {
+	chainID, err := backend.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chain id: %w", err)
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	return &Submitter{
+		backend:       backend,
+		signer:        signer,
+		from:          signer.Address(),
+		to:            to,
+		chainID:       chainID,
+		gasLimit:      gasLimit,
+		confirmations: confirmations,
+		pollInterval:  receiptPollInterval,
+	}, nil
+}
+
+// SetFeePolicy sets how the submissions are priced and replaced when stuck
+func (s *Submitter) SetFeePolicy(policy FeePolicy) {
+	s.feePolicy = policy
+}
+
+// From returns the account the submissions are sent from
+func (s *Submitter) From() common.Address {
+	return s.from
+}
+
+// SubmitProof submits proofData (as returned by types.CreateProofDataFor) for update to the light client,
+// with the attested state root if withStateRoot (see EncodeSubmission)
+func (s *Submitter) SubmitProof(ctx context.Context, proofData any, update *types.LightClientUpdate, withStateRoot bool) (*gethtypes.Receipt, error) {
+	calldata, err := EncodeSubmission(proofData, update, withStateRoot)
+	if err != nil {
+		return nil, err
+	}
+	return s.Submit(ctx, calldata)
+}
+
+// Submit signs a dynamic fee transaction calling the contract with calldata, sends it and waits for
+// its receipt to have the configured confirmations, replacing it with higher fees while it is stuck.
+// A mined but failed transaction returns its receipt with ErrSubmissionReverted.
+func (s *Submitter) Submit(ctx context.Context, calldata []byte) (*gethtypes.Receipt, error) {
+	tx, err := s.send(ctx, calldata)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := s.waitReceipt(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSubmissionUnconfirmed, err)
+	}
+	if receipt.Status != gethtypes.ReceiptStatusSuccessful {
+		return receipt, fmt.Errorf("%w: %s in block %d", ErrSubmissionReverted, receipt.TxHash, receipt.BlockNumber)
+	}
+	return receipt, nil
+}
+
+// send signs and sends the transaction with the next nonce of the account
+func (s *Submitter) send(ctx context.Context, calldata []byte) (*gethtypes.Transaction, error) {
+	gas, err := EstimateSubmissionGas(ctx, s.backend, s.from, s.to, calldata, s.gasLimit)
+	if err != nil {
+		return nil, err
+	}
+	gas += gas * gasMarginPercent / 100
+	if s.gasLimit != 0 && gas > s.gasLimit {
+		gas = s.gasLimit
+	}
+	tip, feeCap, err := s.fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hasNonce {
+		if s.nonce, err = s.backend.PendingNonceAt(ctx, s.from); err != nil {
+			return nil, fmt.Errorf("failed to fetch nonce of %s: %w", s.from, err)
+		}
+		s.hasNonce = true
+	}
+	tx, err := s.signer.SignTx(ctx, gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+		ChainID:   s.chainID,
+		Nonce:     s.nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &s.to,
+		Data:      calldata,
+	}), s.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign submission: %w", err)
+	}
+	if err := s.backend.SendTransaction(ctx, tx); err != nil {
+		// the node may know a nonce this submitter does not (e.g. another sender of the account)
+		s.hasNonce = false
+		return nil, fmt.Errorf("failed to send submission: %w", err)
+	}
+	s.nonce++
+	return tx, nil
+}
+
+// waitReceipt polls the receipt of tx until it is mined and confirmed. While tx is not mined it is
+// replaced every FeePolicy.StuckAfter with higher fees, the receipt is the one of whichever of tx and
+// its replacements is mined.
+func (s *Submitter) waitReceipt(ctx context.Context, tx *gethtypes.Transaction) (*gethtypes.Receipt, error) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	sent := []*gethtypes.Transaction{tx}
+	lastSent := time.Now()
+	for {
+		receipt, err := s.findReceipt(ctx, sent)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			if s.feePolicy.StuckAfter > 0 && time.Since(lastSent) >= s.feePolicy.StuckAfter {
+				// a failed replacement (e.g. the stuck transaction was just mined) leaves the sent ones pending
+				if replacement, err := s.bump(ctx, sent[len(sent)-1]); err == nil && replacement != nil {
+					sent = append(sent, replacement)
+				}
+				lastSent = time.Now()
+			}
+		case err != nil:
+			return nil, err
+		default:
+			head, err := s.backend.BlockNumber(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch block number: %w", err)
+			}
+			if head+1 >= receipt.BlockNumber.Uint64()+s.confirmations {
+				return receipt, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			// a transaction that is still pending keeps its nonce, the node tells which one is next
+			s.hasNonce = false
+			s.mu.Unlock()
+			return nil, fmt.Errorf("submission %s not confirmed: %w", sent[len(sent)-1].Hash(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// findReceipt returns the receipt of the mined one of the transactions sent with the same nonce,
+// ethereum.NotFound if none is mined
+func (s *Submitter) findReceipt(ctx context.Context, sent []*gethtypes.Transaction) (*gethtypes.Receipt, error) {
+	for i := len(sent) - 1; i >= 0; i-- {
+		receipt, err := s.backend.TransactionReceipt(ctx, sent[i].Hash())
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+		case err != nil:
+			return nil, fmt.Errorf("failed to fetch receipt of %s: %w", sent[i].Hash(), err)
+		default:
+			return receipt, nil
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+// EncodeVerifierCall encodes a direct call of the verifier generated for the circuit with proofData
+// (as returned by types.CreateProofDataFor) and the public inputs of the proof, in the order of the
+// public witness. The Groth16 verifier reverts on an invalid proof, the PLONK one returns false.
+func EncodeVerifierCall(proofData any, inputs []*big.Int) ([]byte, error) {
+	switch data := proofData.(type) {
+	case *types.ProofData:
+		if len(data.Proof) != 8 {
+			return nil, fmt.Errorf("malformed groth16 proof data")
+		}
+		words := append([]types.HexBytes{}, data.Proof...)
+		signature := "verifyProof(uint256[8],"
+		if len(data.Commitments) != 0 {
+			if len(data.Commitments) != 2 || len(data.CommitmentPok) != 2 {
+				return nil, fmt.Errorf("malformed groth16 proof data")
+			}
+			words = append(append(words, data.Commitments...), data.CommitmentPok...)
+			signature += "uint256[2],uint256[2],"
+		}
+		signature += fmt.Sprintf("uint256[%d])", len(inputs))
+
+		// every argument is a static array, so the call is the selector followed by their words
+		calldata := crypto.Keccak256([]byte(signature))[:4]
+		for _, word := range words {
+			calldata = append(calldata, common.LeftPadBytes(word, 32)...)
+		}
+		for _, input := range inputs {
+			if input.Sign() < 0 || input.BitLen() > 256 {
+				return nil, fmt.Errorf("public input %s is not a uint256", input)
+			}
+			calldata = append(calldata, common.LeftPadBytes(input.Bytes(), 32)...)
+		}
+		return calldata, nil
+	case *types.PlonkProofData:
+		return parsedVerifierPlonkABI.Pack("Verify", []byte(data.Proof), inputs)
+	default:
+		return nil, fmt.Errorf("unsupported proof data %T", proofData)
+	}
+}
@@ -0,0 +1,196 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+// chainBackend mines every sent transaction in its own block, reverting the calls whose data starts
+// with revertPrefix and leaving the ones under minFeeCap pending, and advances its head by one block
+// per block number lookup
+type chainBackend struct {
+	fixedGasEstimator
+	head         uint64
+	pendingNonce uint64
+	sendErr      error
+	// sendFailures is the number of sends failing with sendErr
+	sendFailures int
+	revertPrefix byte
+	minFeeCap    *big.Int
+	sent         []*gethtypes.Transaction
+	receipts     map[common.Hash]*gethtypes.Receipt
+}
+
+func (b *chainBackend) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(11155111), nil
+}
+
+func (b *chainBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return b.pendingNonce, nil
+}
+
+func (b *chainBackend) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (b *chainBackend) HeaderByNumber(context.Context, *big.Int) (*gethtypes.Header, error) {
+	return &gethtypes.Header{Number: new(big.Int).SetUint64(b.head), BaseFee: big.NewInt(10e9)}, nil
+}
+
+func (b *chainBackend) SendTransaction(_ context.Context, tx *gethtypes.Transaction) error {
+	if b.sendFailures > 0 {
+		b.sendFailures--
+		return b.sendErr
+	}
+	b.sent = append(b.sent, tx)
+	if b.minFeeCap != nil && tx.GasFeeCap().Cmp(b.minFeeCap) < 0 {
+		return nil
+	}
+	b.pendingNonce = tx.Nonce() + 1
+	status := gethtypes.ReceiptStatusSuccessful
+	if len(tx.Data()) > 0 && tx.Data()[0] == b.revertPrefix {
+		status = gethtypes.ReceiptStatusFailed
+	}
+	b.receipts[tx.Hash()] = &gethtypes.Receipt{TxHash: tx.Hash(), Status: status, BlockNumber: new(big.Int).SetUint64(b.head + 1), GasUsed: b.gas}
+	return nil
+}
+
+func (b *chainBackend) TransactionReceipt(_ context.Context, txHash common.Hash) (*gethtypes.Receipt, error) {
+	receipt, ok := b.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (b *chainBackend) BlockNumber(context.Context) (uint64, error) {
+	b.head++
+	return b.head, nil
+}
+
+func TestSubmitter(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	lightClient := common.HexToAddress("0x09E38B218b3C2e8F4AAB7c9e9a610BC6972f630D")
+	backend := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100, pendingNonce: 7,
+		revertPrefix: 0xff, receipts: map[common.Hash]*gethtypes.Receipt{}}
+	s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), lightClient, 1_000_000, 3)
+	require.NoError(t, err)
+	s.pollInterval = time.Millisecond
+
+	// signed by the key, to the light client, with the pending nonce of the account and a gas margin
+	receipt, err := s.Submit(context.Background(), []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.Len(t, backend.sent, 1)
+	tx := backend.sent[0]
+	sender, err := gethtypes.Sender(gethtypes.LatestSignerForChainID(big.NewInt(11155111)), tx)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), sender)
+	require.Equal(t, lightClient, *tx.To())
+	require.Equal(t, uint64(7), tx.Nonce())
+	require.Equal(t, uint64(600_000), tx.Gas())
+	require.Equal(t, big.NewInt(21e9), tx.GasFeeCap())
+	// with 3 confirmations
+	require.GreaterOrEqual(t, backend.head, receipt.BlockNumber.Uint64()+2)
+
+	// the next submission takes the next nonce, a reverted one returns its receipt
+	receipt, err = s.Submit(context.Background(), []byte{0xff})
+	require.ErrorIs(t, err, ErrSubmissionReverted)
+	require.Equal(t, gethtypes.ReceiptStatusFailed, receipt.Status)
+	require.Equal(t, uint64(8), backend.sent[1].Nonce())
+
+	// after a failed send the nonce is refetched from the node
+	backend.sendErr, backend.sendFailures = errors.New("connection refused"), 1
+	_, err = s.Submit(context.Background(), []byte{1})
+	require.Error(t, err)
+	backend.pendingNonce = 20
+	_, err = s.Submit(context.Background(), []byte{1})
+	require.NoError(t, err)
+	require.Equal(t, uint64(20), backend.sent[2].Nonce())
+
+	// over the gas budget nothing is sent
+	backend.gas = 1_000_001
+	_, err = s.Submit(context.Background(), []byte{1})
+	require.ErrorIs(t, err, ErrGasOverBudget)
+	require.Len(t, backend.sent, 3)
+
+	// a proof and its update are encoded for the light client
+	backend.gas = 500_000
+	update, proofData := loadTestSubmission(t)
+	_, err = s.SubmitProof(context.Background(), proofData, update, false)
+	require.NoError(t, err)
+	method, err := parsedLightClientABI.MethodById(backend.sent[3].Data()[:4])
+	require.NoError(t, err)
+	require.Equal(t, "updateSyncCommittee", method.Name)
+}
+
+func TestEncodeVerifierCall(t *testing.T) {
+	_, proofData := loadTestSubmission(t)
+	inputs := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+
+	calldata, err := EncodeVerifierCall(proofData, inputs)
+	require.NoError(t, err)
+	require.Equal(t, crypto.Keccak256([]byte("verifyProof(uint256[8],uint256[2],uint256[2],uint256[3])"))[:4], calldata[:4])
+	require.Len(t, calldata, 4+(8+2+2+3)*32)
+	require.Equal(t, common.LeftPadBytes(proofData.CommitmentPok[1], 32), calldata[4+11*32:4+12*32])
+	require.Equal(t, common.LeftPadBytes([]byte{3}, 32), calldata[len(calldata)-32:])
+
+	// without commitments
+	calldata, err = EncodeVerifierCall(&types.ProofData{Proof: proofData.Proof}, inputs)
+	require.NoError(t, err)
+	require.Equal(t, crypto.Keccak256([]byte("verifyProof(uint256[8],uint256[3])"))[:4], calldata[:4])
+	require.Len(t, calldata, 4+(8+3)*32)
+
+	calldata, err = EncodeVerifierCall(&types.PlonkProofData{Backend: types.BackendPlonk, Proof: []byte{1, 2, 3}}, inputs)
+	require.NoError(t, err)
+	args, err := parsedVerifierPlonkABI.Methods["Verify"].Inputs.Unpack(calldata[4:])
+	require.NoError(t, err)
+	require.Equal(t, inputs, args[1])
+
+	_, err = EncodeVerifierCall(proofData, []*big.Int{big.NewInt(-1)})
+	require.Error(t, err)
+}
+
+func TestSubmitProofRetries(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	backend := &chainBackend{fixedGasEstimator: fixedGasEstimator{gas: 500_000}, head: 100,
+		revertPrefix: 0xff, receipts: map[common.Hash]*gethtypes.Receipt{}}
+	s, err := NewSubmitter(context.Background(), backend, NewKeySigner(key), common.HexToAddress("0x01"), 0, 1)
+	require.NoError(t, err)
+	s.pollInterval = time.Millisecond
+	r := &Relayer{config: &cfgtypes.Config{RetryMaxAttempts: 3, RetryBackoff: time.Millisecond},
+		destinations: []*destination{{name: defaultDestination, submitter: s}}}
+	update, proofData := loadTestSubmission(t)
+	proofPath := filepath.Join(t.TempDir(), proofFileName(1105))
+	require.NoError(t, os.WriteFile(proofPath, []byte("{}"), 0644))
+
+	// failed sends are retried
+	backend.sendErr, backend.sendFailures = errors.New("connection refused"), 2
+	r.submitProof(context.Background(), r.destinations[0], CircuitScUpdate, update, proofData, proofPath)
+	require.Len(t, backend.sent, 1)
+
+	// up to the policy's attempts
+	backend.sendFailures = 3
+	r.submitProof(context.Background(), r.destinations[0], CircuitScUpdate, update, proofData, proofPath)
+	require.Len(t, backend.sent, 1)
+	require.Zero(t, backend.sendFailures)
+
+	// a proof set aside is not submitted
+	require.NoError(t, os.Rename(proofPath, proofPath+overBudgetSuffix))
+	r.submitProof(context.Background(), r.destinations[0], CircuitScUpdate, update, proofData, proofPath)
+	require.Len(t, backend.sent, 1)
+}
@@ -0,0 +1,98 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultServiceName is the service.name of the spans, unless OTEL_SERVICE_NAME is set
+const defaultServiceName = "zk-chains-relayer"
+
+// tracingFlushTimeout bounds the export of the last spans on exit
+const tracingFlushTimeout = 5 * time.Second
+
+// tracer creates the spans of the proving stages: the period being proven, and its fetch,
+// pre-validate, witness, prove, verify and submit stages. Until SetupTracing installs an exporter,
+// the global provider drops them.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/kysee/zk-chains/provers")
+}
+
+// the attributes of the stage spans
+var (
+	attrPeriod      = attribute.Key("period")
+	attrSlot        = attribute.Key("slot")
+	attrConstraints = attribute.Key("constraints")
+	attrProofBytes  = attribute.Key("proof_bytes")
+	attrCircuit     = attribute.Key("circuit")
+	attrBackend     = attribute.Key("backend")
+	attrDestination = attribute.Key("destination")
+)
+
+// SetupTracing exports the spans to the OTLP collector of config.OTLPEndpoint over gRPC, and returns
+// the function flushing them on shutdown. Without an endpoint, spans are dropped.
+func SetupTracing(ctx context.Context, config *cfgtypes.Config) (func(context.Context) error, error) {
+	if config.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(config.OTLPEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP exporter of %s: %w", config.OTLPEndpoint, err)
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the service name, host.name tells the
+	// relayers of a fleet apart
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the relayer: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	config.Log().Infof("Spans are exported to %s\n", config.OTLPEndpoint)
+	return provider.Shutdown, nil
+}
+
+// flushTracing exports the spans still buffered by SetupTracing, waiting at most tracingFlushTimeout
+func flushTracing(config *cfgtypes.Config, shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		config.Log().Warnf("failed to export the last spans: %v\n", err)
+	}
+}
+
+// startStage starts the span of a stage of the proof of period, a child of the span of ctx
+func startStage(ctx context.Context, name string, period uint64, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(append(attrs, attrPeriod.Int64(int64(period)))...))
+}
+
+// endStage ends span, marking it failed with err if not nil
+func endStage(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// updateAttributes are the attributes of the stages of update: its attested slot
+func updateAttributes(update *types.LightClientUpdate) []attribute.KeyValue {
+	if update == nil {
+		return nil
+	}
+	return []attribute.KeyValue{attrSlot.Int64(int64(update.Data.AttestedHeader.Beacon.Slot))}
+}
@@ -0,0 +1,87 @@
+package relayer
+
+import (
+	"context"
+	"testing"
+
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans installs a tracer provider recording the ended spans for the duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return recorder
+}
+
+// spanAttribute returns the value of the attribute key of span
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestPrepareSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	updates := loadTestUpdates(t)
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled", "--once")
+	r := &Relayer{config: config, fetcher: &periodFetcher{updates: updates}}
+	signers, err := r.parseCommittee(&updates[1104].Data.NextSyncCommittee)
+	require.NoError(t, err)
+
+	prepared := make(chan *preparedPeriod, pipelineDepth)
+	go r.prepare(context.Background(), 1105, signers, prepared)
+	job := <-prepared
+	require.NoError(t, job.err)
+	endStage(job.span, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	period := spans[3]
+	require.Equal(t, "period", period.Name())
+	for i, name := range []string{"fetch", "pre-validate", "witness"} {
+		require.Equal(t, name, spans[i].Name())
+		require.Equal(t, period.SpanContext().SpanID(), spans[i].Parent().SpanID())
+		value, ok := spanAttribute(spans[i], attrPeriod)
+		require.True(t, ok)
+		require.Equal(t, int64(1105), value.AsInt64())
+	}
+	slot, ok := spanAttribute(period, attrSlot)
+	require.True(t, ok)
+	require.Equal(t, int64(updates[1105].Data.AttestedHeader.Beacon.Slot), slot.AsInt64())
+
+	// a period without an update ends with failed fetch and period spans
+	recorder.Reset()
+	prepared = make(chan *preparedPeriod, pipelineDepth)
+	go r.prepare(context.Background(), 1106, signers, prepared)
+	job = <-prepared
+	require.ErrorIs(t, job.err, ErrUpdateUnavailable)
+	endStage(job.span, job.err)
+	spans = recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "fetch", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestSetupTracingDisabled(t *testing.T) {
+	config := cfgtypes.NewConfig("--root", t.TempDir(), "--log-level", "disabled")
+	require.Empty(t, config.OTLPEndpoint)
+	shutdown, err := SetupTracing(context.Background(), config)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	config = cfgtypes.NewConfig("--otlp-endpoint", "http://localhost:4317")
+	require.Equal(t, "http://localhost:4317", config.OTLPEndpoint)
+}
@@ -0,0 +1,118 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/kysee/zk-chains/circuits"
+	"github.com/kysee/zk-chains/types"
+)
+
+// transitionCircuitName is the manifest entry of Eth2ScTransitionCircuit
+const transitionCircuitName = "Eth2ScTransitionCircuit"
+
+// transitionFileName returns the name of the transition proof file of the given period inside Config.ProofDir
+func transitionFileName(period uint64) string {
+	return fmt.Sprintf("transition-period-%d.json", period)
+}
+
+// TransitionProof is the content of a transition proof file: the two commitments to the sync
+// committee of Period and the proof that they commit to the same committee.
+type TransitionProof struct {
+	Period           uint64         `json:"period"`
+	FromMode         string         `json:"fromMode"`
+	ToMode           string         `json:"toMode"`
+	ScRoot           types.HexBytes `json:"scRoot"`
+	OldScPubKeysHash types.HexBytes `json:"oldScPubKeysHash"`
+	NewScPubKeysHash types.HexBytes `json:"newScPubKeysHash"`
+	Proof            any            `json:"proof"`
+}
+
+// transitionParams returns the params of the transition circuit of the configured migration
+func (r *Relayer) transitionParams() circuit.TransitionParams {
+	return circuit.TransitionParams{
+		From: r.config.ScPubKeysHashMode,
+		To:   r.config.TransitionScPubKeysHashMode,
+	}
+}
+
+// inTransitionWindow reports whether the committee of period must be proven in both modes
+func (r *Relayer) inTransitionWindow(period uint64) bool {
+	return period <= r.config.TransitionUntilPeriod
+}
+
+// setupTransitionCircuit loads Eth2ScTransitionCircuit, which must be listed in the artifact manifest
+func (r *Relayer) setupTransitionCircuit() error {
+	params := r.transitionParams()
+	if params.From == params.To {
+		return fmt.Errorf("migration window to %v, which is already the sync committee pubkeys hash mode", params.To)
+	}
+	manifest, err := types.LoadArtifactManifest(r.config.ManifestPath)
+	if err != nil {
+		return fmt.Errorf("a migration window needs the %s artifacts: %w", transitionCircuitName, err)
+	}
+	artifacts, err := manifest.Circuit(transitionCircuitName)
+	if err != nil {
+		return err
+	}
+	r.transition, err = loadCircuit(artifacts, filepath.Dir(r.config.ManifestPath), r.proverSettings())
+	if err != nil {
+		return err
+	}
+	r.log().Infof("Committees up to period %d are also committed to in %v mode\n", r.config.TransitionUntilPeriod, params.To)
+	return nil
+}
+
+// emitTransitionProof proves that the current committee, which signs the updates of period, has
+// both the old and the new commitment, and saves it next to the update proofs. It does nothing
+// outside of the migration window.
+func (r *Relayer) emitTransitionProof(ctx context.Context, period uint64) error {
+	if r.transition == nil || !r.inTransitionWindow(period) {
+		return nil
+	}
+	params := r.transitionParams()
+	assignment, err := circuit.NewEth2ScTransitionAssignment(params, r.currentSc)
+	if err != nil {
+		return fmt.Errorf("failed to assign transition witness: %w", err)
+	}
+	fullWitness, err := frontend.NewWitness(assignment, r.transition.curve.ScalarField())
+	if err != nil {
+		return fmt.Errorf("failed to create transition witness: %w", err)
+	}
+	proofSolidity, err := r.transition.prove(ctx, fullWitness)
+	if err != nil {
+		return fmt.Errorf("transition proof generation failed: %w", err)
+	}
+	proofData, err := types.CreateProofDataFor(r.transition.backend, proofSolidity)
+	if err != nil {
+		return err
+	}
+
+	newHash := types.ComputeScPubKeysHashWithMode(r.currentScPubkeys[:], params.To)
+	scRoot := types.SyncCommitteeRoot(r.currentSc)
+	jsonBlob, err := json.MarshalIndent(&TransitionProof{
+		Period:           period,
+		FromMode:         params.From.String(),
+		ToMode:           params.To.String(),
+		ScRoot:           scRoot[:],
+		OldScPubKeysHash: r.scPubKeysHash,
+		NewScPubKeysHash: newHash[:],
+		Proof:            proofData,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transition proof: %w", err)
+	}
+	if err := os.MkdirAll(r.config.ProofDir, 0755); err != nil {
+		return fmt.Errorf("failed to create proof directory: %w", err)
+	}
+	outputPath := filepath.Join(r.config.ProofDir, transitionFileName(period))
+	if err := os.WriteFile(outputPath, jsonBlob, 0644); err != nil {
+		return fmt.Errorf("failed to write transition proof file: %w", err)
+	}
+	r.log().Infof("✓ Transition proof (%v -> %v) saved to %s\n", params.From, params.To, outputPath)
+	return nil
+}
@@ -0,0 +1,201 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+	"github.com/kysee/zk-chains/types/slots"
+)
+
+// txProofTimeout bounds an on-demand transaction proof: locating the transaction, waiting for its block
+// to be attested by the sync committee, and proving its receipt
+const txProofTimeout = 15 * time.Minute
+
+// ErrTxNotAttested is returned for a transaction whose block the sync committee did not attest in time
+var ErrTxNotAttested = errors.New("block not attested")
+
+// TxLocation is where a transaction is in the execution and beacon chains
+type TxLocation struct {
+	TxHash      types.HexBytes `json:"txHash"`
+	BlockHash   types.HexBytes `json:"blockHash"`
+	BlockNumber uint64         `json:"blockNumber"`
+	TxIndex     uint64         `json:"txIndex"`
+	// Slot is the slot of the beacon block whose execution payload is the block of the transaction
+	Slot uint64 `json:"slot"`
+}
+
+// TxProof is the proof of the receipt of a transaction generated on demand, see Relayer.ProveTx
+type TxProof struct {
+	TxLocation
+	Backend types.ProofBackend `json:"backend"`
+	// ProofData is the proof of the receipt circuit, a *types.ProofData or *types.PlonkProofData
+	ProofData any `json:"proofData"`
+}
+
+// TxProver proves transactions on demand, *Relayer implements it
+type TxProver interface {
+	ProveTx(ctx context.Context, listener *Listener, txHash common.Hash, maxGas uint64) (*TxProof, error)
+}
+
+// TxProofMain proves the receipt of config.TxHash with the receipt circuit of the manifest, and writes
+// the proof to the receipt directory of config.ProofDir
+func TxProofMain(ctx context.Context, config *cfgtypes.Config) {
+	if config.ExecutionRPC == "" || config.TxHash == "" {
+		fatalf(config.Log(), "prove-tx needs --exec-rpc and --tx")
+	}
+	txHash, err := parseTxHash(config.TxHash)
+	if err != nil {
+		fatalf(config.Log(), "%v", err)
+	}
+	client, err := ethclient.Dial(config.ExecutionRPC)
+	if err != nil {
+		fatalf(config.Log(), "failed to connect to %s: %v", config.ExecutionRPC, err)
+	}
+	fetcher, err := NewBeaconFetcher(config)
+	if err != nil {
+		fatalf(config.Log(), "failed to create beacon fetcher: %v", err)
+	}
+	relayer, err := NewRelayer(config, fetcher)
+	if err != nil {
+		fatalf(config.Log(), "failed to create relayer: %v", err)
+	}
+	// only the receipt circuit is loaded, Eth2ScUpdateCircuit is not needed
+	config.Circuits = append(config.Circuits, string(CircuitReceipt))
+	if err := relayer.setupCircuits(); err != nil {
+		fatalf(config.Log(), "failed to setup circuit: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, txProofTimeout)
+	defer cancel()
+	proof, err := relayer.ProveTx(ctx, NewListener(config, fetcher, client), txHash, config.MaxGas)
+	if err != nil {
+		fatalf(config.Log(), "failed to prove transaction %s: %v", txHash, err)
+	}
+	config.Log().Infof("✓ Transaction %s of block %d (slot %d) proven\n", txHash, proof.BlockNumber, proof.Slot)
+}
+
+// ProveTx proves the receipt of txHash, which must have succeeded within maxGas gas (0 means no budget):
+// it locates the beacon block of the transaction, waits for the sync committee to attest it, and proves
+// the receipt with the receipt circuit. Like ProveUpdate, proofs are generated one at a time.
+func (r *Relayer) ProveTx(ctx context.Context, listener *Listener, txHash common.Hash, maxGas uint64) (*TxProof, error) {
+	if r.circuits.circuit(CircuitReceipt) == nil {
+		return nil, fmt.Errorf("%s: %w", CircuitReceipt, ErrCircuitNotLoaded)
+	}
+	location, err := listener.LocateTx(ctx, txHash, maxGas)
+	if err != nil {
+		return nil, err
+	}
+	if err := listener.WaitAttested(ctx, location.BlockNumber); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.proverSlot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.proverSlot }()
+
+	proofData, _, err := r.ProveReceipt(ctx, listener, location.Slot, int(location.TxIndex))
+	if err != nil {
+		return nil, err
+	}
+	return &TxProof{TxLocation: *location, Backend: r.circuits.circuit(CircuitReceipt).backend, ProofData: proofData}, nil
+}
+
+// LocateTx finds the block of txHash and the slot of its beacon block. Payload timestamps advance by a
+// slot duration per slot, missed slots included, so the slot is the one of the beacon head moved back by
+// the time between the payloads. The transaction must have succeeded within maxGas gas (0 means no budget).
+func (listener *Listener) LocateTx(ctx context.Context, txHash common.Hash, maxGas uint64) (*TxLocation, error) {
+	if listener.receipts == nil {
+		return nil, fmt.Errorf("no execution RPC configured")
+	}
+	heads, ok := listener.fetcher.(cfgtypes.BatchFetcher)
+	if !ok {
+		return nil, errors.New("the beacon fetcher cannot report the head slot")
+	}
+	receipt, err := listener.receipts.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt of %s: %w", txHash, err)
+	}
+	if receipt.Status != gethtypes.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("%w: %s has status %d", ErrTxFailed, txHash, receipt.Status)
+	}
+	if maxGas != 0 && receipt.GasUsed > maxGas {
+		return nil, fmt.Errorf("%w: %s used %d gas, budget is %d", ErrTxOverGas, txHash, receipt.GasUsed, maxGas)
+	}
+	header, err := listener.receipts.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch header %s: %w", receipt.BlockHash, err)
+	}
+
+	headSlot, err := heads.HeadSlot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch head slot: %w", err)
+	}
+	head, err := listener.fetcher.Block(ctx, headSlot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block at slot %d: %w", headSlot, err)
+	}
+	headTime := uint64(head.Data.Message.Body.ExecutionPayload.Timestamp)
+	slotSeconds := uint64(listener.slotDuration() / time.Second)
+	if header.Time > headTime || (headTime-header.Time)/slotSeconds > headSlot {
+		return nil, fmt.Errorf("block %s at time %d is not before the beacon head at slot %d", receipt.BlockHash, header.Time, headSlot)
+	}
+	slot := headSlot - (headTime-header.Time)/slotSeconds
+
+	block, err := listener.fetcher.Block(ctx, slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block at slot %d: %w", slot, err)
+	}
+	if common.Hash(block.Data.Message.Body.ExecutionPayload.BlockHash) != receipt.BlockHash {
+		return nil, fmt.Errorf("the payload of slot %d is block %s, not %s", slot, common.Hash(block.Data.Message.Body.ExecutionPayload.BlockHash), receipt.BlockHash)
+	}
+	return &TxLocation{
+		TxHash:      txHash[:],
+		BlockHash:   receipt.BlockHash[:],
+		BlockNumber: header.Number.Uint64(),
+		TxIndex:     uint64(receipt.TransactionIndex),
+		Slot:        slot,
+	}, nil
+}
+
+// WaitAttested waits for the sync committee to attest a header whose execution block is blockNumber or
+// a later one, polling the fetcher every slot. It fails with ErrTxNotAttested once ctx is done.
+func (listener *Listener) WaitAttested(ctx context.Context, blockNumber uint64) error {
+	attested, ok := listener.fetcher.(cfgtypes.AttestationFetcher)
+	if !ok {
+		return errors.New("the beacon fetcher cannot report the attested headers")
+	}
+	for {
+		number, err := attested.AttestedBlockNumber(ctx)
+		if err == nil && number >= blockNumber {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			listener.log().Warnf("failed to fetch the attested header: %v\n", err)
+		}
+		if err == nil {
+			listener.log().Infof("Waiting for block %d to be attested, the attested header is at block %d\n", blockNumber, number)
+		}
+		sleep(ctx, listener.slotDuration())
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: block %d: %w", ErrTxNotAttested, blockNumber, ctx.Err())
+		}
+	}
+}
+
+// slotDuration is Config.SlotDuration, or SECONDS_PER_SLOT of the preset
+func (listener *Listener) slotDuration() time.Duration {
+	if listener.config.SlotDuration > 0 {
+		return listener.config.SlotDuration
+	}
+	return slots.SlotDuration(listener.config.Preset.Spec())
+}
@@ -0,0 +1,122 @@
+package relayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+// txProverStub proves every transaction of its location, it is not attested if its block is not
+type txProverStub struct {
+	location *TxLocation
+	attested bool
+}
+
+func (s *txProverStub) Health(context.Context) *HealthReport {
+	return &HealthReport{Live: true, Ready: true}
+}
+
+func (s *txProverStub) ProveTx(_ context.Context, _ *Listener, txHash common.Hash, _ uint64) (*TxProof, error) {
+	if !s.attested {
+		return nil, fmt.Errorf("%w: block %d", ErrTxNotAttested, s.location.BlockNumber)
+	}
+	location := *s.location
+	location.TxHash = txHash[:]
+	return &TxProof{TxLocation: location, Backend: "groth16"}, nil
+}
+
+func TestProveTx(t *testing.T) {
+	source := newBlockReceiptSource()
+	source.header.Time = 1_700_000_120
+	for _, receipt := range source.receipts {
+		receipt.BlockHash = source.header.Hash()
+	}
+	config := &cfgtypes.Config{SlotDuration: 12 * time.Second}
+	fetcher := cfgtypes.NewFakeFetcher()
+	// the block of the transactions is at slot 1000, the head 4 slots later after a missed slot
+	block := &cfgtypes.BlockAPIResponse{Version: "fulu"}
+	block.Data.Message.Slot = 1000
+	block.Data.Message.Body.ExecutionPayload.BlockHash = zrntcommon.Hash32(source.header.Hash())
+	block.Data.Message.Body.ExecutionPayload.Timestamp = zrntcommon.Timestamp(source.header.Time)
+	fetcher.SetBlock(1000, block)
+	head := &cfgtypes.BlockAPIResponse{Version: "fulu"}
+	head.Data.Message.Slot = 1004
+	head.Data.Message.Body.ExecutionPayload.Timestamp = zrntcommon.Timestamp(source.header.Time + 4*12)
+	fetcher.SetBlock(1004, head)
+	fetcher.SetHeadSlot(1004)
+	listener := NewListener(config, fetcher, source)
+	ctx := context.Background()
+
+	location, err := listener.LocateTx(ctx, source.receipts[1].TxHash, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), location.Slot)
+	require.Equal(t, uint64(1), location.TxIndex)
+	require.Equal(t, source.header.Number.Uint64(), location.BlockNumber)
+	_, err = listener.LocateTx(ctx, source.receipts[2].TxHash, 0)
+	require.ErrorIs(t, err, ErrTxFailed)
+	_, err = listener.LocateTx(ctx, source.receipts[1].TxHash, 40_000)
+	require.ErrorIs(t, err, ErrTxOverGas)
+
+	// a payload that is not the block of the transaction is rejected
+	block.Data.Message.Body.ExecutionPayload.BlockHash[0] ^= 1
+	_, err = listener.LocateTx(ctx, source.receipts[1].TxHash, 0)
+	require.Error(t, err)
+	block.Data.Message.Body.ExecutionPayload.BlockHash[0] ^= 1
+
+	// the proof waits for the block to be attested
+	config.SlotDuration = 10 * time.Millisecond
+	fetcher.SetAttestedBlockNumber(location.BlockNumber - 1)
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, listener.WaitAttested(waitCtx, location.BlockNumber), ErrTxNotAttested)
+	fetcher.SetAttestedBlockNumber(location.BlockNumber)
+	require.NoError(t, listener.WaitAttested(ctx, location.BlockNumber))
+
+	// a relayer without the receipt circuit cannot prove it
+	r := &Relayer{config: config, proverSlot: make(chan struct{}, 1)}
+	_, err = r.ProveTx(ctx, listener, source.receipts[1].TxHash, 0)
+	require.ErrorIs(t, err, ErrCircuitNotLoaded)
+
+	// POST /prove/tx serves the proofs of the relayer
+	prover := &txProverStub{location: location}
+	srv := httptest.NewServer(NewHTTPHandler(config, listener, prover))
+	defer srv.Close()
+	proveTx := func(body string) (int, []byte) {
+		resp, err := http.Post(srv.URL+"/prove/tx", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out json.RawMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+	request := fmt.Sprintf(`{"txHash":"%s"}`, source.receipts[1].TxHash)
+	code, _ := proveTx(request)
+	require.Equal(t, http.StatusGatewayTimeout, code)
+	prover.attested = true
+	code, body := proveTx(request)
+	require.Equal(t, http.StatusOK, code)
+	var proof TxProof
+	require.NoError(t, json.Unmarshal(body, &proof))
+	require.Equal(t, uint64(1000), proof.Slot)
+	require.Equal(t, source.receipts[1].TxHash[:], []byte(proof.TxHash))
+	code, _ = proveTx(`{"txHash":"0x01"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// without a relayer to prove them
+	noProver := httptest.NewServer(NewHTTPHandler(config, listener, nil))
+	defer noProver.Close()
+	resp, err := http.Post(noProver.URL+"/prove/tx", "application/json", strings.NewReader(request))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
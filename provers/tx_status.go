@@ -0,0 +1,186 @@
+package relayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/kysee/zk-chains/circuits"
+	cfgtypes "github.com/kysee/zk-chains/provers/types"
+	"github.com/kysee/zk-chains/types"
+)
+
+// receiptFetchTimeout bounds the execution RPC calls of one transaction status bundle
+const receiptFetchTimeout = 60 * time.Second
+
+var (
+	// ErrTxFailed is returned for a transaction whose receipt status is not 1
+	ErrTxFailed = errors.New("transaction failed")
+	// ErrTxOverGas is returned for a transaction that used more gas than the requested budget
+	ErrTxOverGas = errors.New("transaction gas used over budget")
+)
+
+// ReceiptSource fetches execution headers and receipts, *ethclient.Client implements it
+type ReceiptSource interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*gethtypes.Receipt, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*gethtypes.Header, error)
+	BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*gethtypes.Receipt, error)
+}
+
+// TxStatusMain writes the status bundle of config.TxHash to config.ProofDir
+func TxStatusMain(ctx context.Context, config *cfgtypes.Config) {
+	if config.ExecutionRPC == "" || config.TxHash == "" {
+		fatalf(config.Log(), "tx-status needs --exec-rpc and --tx")
+	}
+	client, err := ethclient.Dial(config.ExecutionRPC)
+	if err != nil {
+		fatalf(config.Log(), "failed to connect to %s: %v", config.ExecutionRPC, err)
+	}
+	fetcher, err := NewBeaconFetcher(config)
+	if err != nil {
+		fatalf(config.Log(), "failed to create beacon fetcher: %v", err)
+	}
+	listener := NewListener(config, fetcher, client)
+
+	ctx, cancel := context.WithTimeout(ctx, receiptFetchTimeout)
+	defer cancel()
+	bundle, err := listener.TxStatusBundle(ctx, common.HexToHash(config.TxHash), config.MaxGas)
+	if err != nil {
+		fatalf(config.Log(), "failed to build transaction status bundle: %v", err)
+	}
+
+	outputPath, err := writeTxStatusBundle(config.ProofDir, bundle)
+	if err != nil {
+		fatalf(config.Log(), "%v", err)
+	}
+	config.Log().Infof("✓ Transaction 0x%s succeeded with %d gas (budget %d), bundle saved to %s\n",
+		bundle.TxHash, bundle.GasUsed, bundle.MaxGas, outputPath)
+}
+
+// writeTxStatusBundle writes bundle to dir as tx-status-<hash>.json and returns its path
+func writeTxStatusBundle(dir string, bundle *types.TxStatusBundle) (string, error) {
+	jsonBlob, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal transaction status bundle: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create proof directory: %w", err)
+	}
+	outputPath := filepath.Join(dir, fmt.Sprintf("tx-status-%x.json", []byte(bundle.TxHash)))
+	if err := os.WriteFile(outputPath, jsonBlob, 0644); err != nil {
+		return "", fmt.Errorf("failed to write transaction status bundle: %w", err)
+	}
+	return outputPath, nil
+}
+
+// TxStatusBundle packages the proof material that txHash succeeded (status 1) and used at most
+// maxGas gas, 0 meaning no budget. The receipts of the block are checked against the receipts root
+// of its header, so a bundle that is returned is provable.
+func (listener *Listener) TxStatusBundle(ctx context.Context, txHash common.Hash, maxGas uint64) (*types.TxStatusBundle, error) {
+	if listener.receipts == nil {
+		return nil, fmt.Errorf("no execution RPC configured")
+	}
+	receipt, err := listener.receipts.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt of %s: %w", txHash, err)
+	}
+	if receipt.Status != gethtypes.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("%w: %s has status %d", ErrTxFailed, txHash, receipt.Status)
+	}
+	if maxGas != 0 && receipt.GasUsed > maxGas {
+		return nil, fmt.Errorf("%w: %s used %d gas, budget is %d", ErrTxOverGas, txHash, receipt.GasUsed, maxGas)
+	}
+
+	header, err := listener.receipts.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch header %s: %w", receipt.BlockHash, err)
+	}
+	headerRLP, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode header: %w", err)
+	}
+	if header.Hash() != receipt.BlockHash {
+		return nil, fmt.Errorf("header of %s hashes to %s", receipt.BlockHash, header.Hash())
+	}
+
+	receipts, err := listener.receipts.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(receipt.BlockHash, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipts of %s: %w", receipt.BlockHash, err)
+	}
+	if root := gethtypes.DeriveSha(gethtypes.Receipts(receipts), trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		return nil, fmt.Errorf("receipts of %s derive root %s, header has %s", receipt.BlockHash, root, header.ReceiptHash)
+	}
+	txIndex := int(receipt.TransactionIndex)
+	if txIndex >= len(receipts) || receipts[txIndex].TxHash != txHash {
+		return nil, fmt.Errorf("receipt %d of %s is not the one of %s", txIndex, receipt.BlockHash, txHash)
+	}
+
+	bundle := &types.TxStatusBundle{
+		BlockHash:         receipt.BlockHash[:],
+		BlockNumber:       header.Number.Uint64(),
+		Header:            headerRLP,
+		ReceiptsRoot:      header.ReceiptHash[:],
+		TxHash:            txHash[:],
+		TxIndex:           uint64(txIndex),
+		Status:            receipts[txIndex].Status,
+		CumulativeGasUsed: receipts[txIndex].CumulativeGasUsed,
+		MaxGas:            maxGas,
+	}
+	if bundle.Receipt, bundle.ReceiptProof, err = receiptWithProof(receipts, txIndex); err != nil {
+		return nil, err
+	}
+	if txIndex > 0 {
+		if bundle.PrevReceipt, bundle.PrevReceiptProof, err = receiptWithProof(receipts, txIndex-1); err != nil {
+			return nil, err
+		}
+		bundle.PrevCumulativeGasUsed = receipts[txIndex-1].CumulativeGasUsed
+	}
+	bundle.GasUsed = bundle.CumulativeGasUsed - bundle.PrevCumulativeGasUsed
+	if bundle.GasUsed != receipt.GasUsed {
+		return nil, fmt.Errorf("cumulative gas of the block gives %d gas used, the receipt has %d", bundle.GasUsed, receipt.GasUsed)
+	}
+	return bundle, nil
+}
+
+// receiptWithProof returns the consensus encoding of receipt txIndex and its receipts trie proof
+func receiptWithProof(receipts gethtypes.Receipts, txIndex int) (types.HexBytes, []types.HexBytes, error) {
+	encoded, err := receipts[txIndex].MarshalBinary()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode receipt %d: %w", txIndex, err)
+	}
+	nodes, err := circuit.ReceiptProof(receipts, txIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := make([]types.HexBytes, len(nodes))
+	for i := range nodes {
+		proof[i] = nodes[i]
+	}
+	if !bytes.Equal(lastNodeValue(nodes), encoded) {
+		return nil, nil, fmt.Errorf("proof of receipt %d does not end with its encoding", txIndex)
+	}
+	return encoded, proof, nil
+}
+
+// lastNodeValue returns the value of the leaf ending a proof, nil if it is not a leaf
+func lastNodeValue(nodes [][]byte) []byte {
+	if len(nodes) == 0 {
+		return nil
+	}
+	var leaf [][]byte
+	if err := rlp.DecodeBytes(nodes[len(nodes)-1], &leaf); err != nil || len(leaf) != 2 {
+		return nil
+	}
+	return leaf[1]
+}
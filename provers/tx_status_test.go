@@ -0,0 +1,131 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+// blockReceiptSource serves the header and receipts of a single block
+type blockReceiptSource struct {
+	header   *gethtypes.Header
+	receipts []*gethtypes.Receipt
+}
+
+func (s *blockReceiptSource) TransactionReceipt(_ context.Context, txHash common.Hash) (*gethtypes.Receipt, error) {
+	for _, receipt := range s.receipts {
+		if receipt.TxHash == txHash {
+			return receipt, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown transaction %s", txHash)
+}
+
+func (s *blockReceiptSource) HeaderByHash(_ context.Context, hash common.Hash) (*gethtypes.Header, error) {
+	if hash != s.header.Hash() {
+		return nil, fmt.Errorf("unknown block %s", hash)
+	}
+	return s.header, nil
+}
+
+func (s *blockReceiptSource) BlockReceipts(_ context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*gethtypes.Receipt, error) {
+	if hash, ok := blockNrOrHash.Hash(); !ok || hash != s.header.Hash() {
+		return nil, fmt.Errorf("unknown block %s", blockNrOrHash.String())
+	}
+	return s.receipts, nil
+}
+
+// newBlockReceiptSource returns a block of 3 transactions using 21000, 50000 and 30000 gas, the last one reverted
+func newBlockReceiptSource() *blockReceiptSource {
+	gasUsed := []uint64{21_000, 50_000, 30_000}
+	receipts := make([]*gethtypes.Receipt, len(gasUsed))
+	var cumulative uint64
+	for i := range receipts {
+		cumulative += gasUsed[i]
+		receipts[i] = &gethtypes.Receipt{
+			Type:              gethtypes.DynamicFeeTxType,
+			Status:            gethtypes.ReceiptStatusSuccessful,
+			CumulativeGasUsed: cumulative,
+			TxHash:            crypto.Keccak256Hash([]byte{byte(i)}),
+			GasUsed:           gasUsed[i],
+			TransactionIndex:  uint(i),
+		}
+	}
+	receipts[1].Logs = []*gethtypes.Log{{Address: common.HexToAddress("0x01"), Topics: []common.Hash{{1}}, Data: []byte{1, 2, 3}}}
+	receipts[2].Status = gethtypes.ReceiptStatusFailed
+	for _, receipt := range receipts {
+		receipt.Bloom = gethtypes.CreateBloom(receipt)
+	}
+
+	header := &gethtypes.Header{
+		ReceiptHash: gethtypes.DeriveSha(gethtypes.Receipts(receipts), trie.NewStackTrie(nil)),
+		Difficulty:  big.NewInt(0),
+		Number:      big.NewInt(21_000_000),
+		GasLimit:    36_000_000,
+		GasUsed:     cumulative,
+		BaseFee:     big.NewInt(1_000_000_000),
+	}
+	for _, receipt := range receipts {
+		receipt.BlockHash = header.Hash()
+		receipt.BlockNumber = header.Number
+	}
+	return &blockReceiptSource{header: header, receipts: receipts}
+}
+
+func TestTxStatusBundle(t *testing.T) {
+	source := newBlockReceiptSource()
+	listener := NewListener(nil, nil, source)
+	ctx := context.Background()
+
+	bundle, err := listener.TxStatusBundle(ctx, source.receipts[1].TxHash, 50_000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), bundle.TxIndex)
+	require.Equal(t, uint64(1), bundle.Status)
+	require.Equal(t, uint64(71_000), bundle.CumulativeGasUsed)
+	require.Equal(t, uint64(21_000), bundle.PrevCumulativeGasUsed)
+	require.Equal(t, uint64(50_000), bundle.GasUsed)
+	require.Equal(t, source.header.Hash().Bytes(), []byte(crypto.Keccak256(bundle.Header)))
+
+	// both proofs verify against the receipts root of the header
+	for i, c := range []struct {
+		proof []types.HexBytes
+		value types.HexBytes
+	}{{bundle.PrevReceiptProof, bundle.PrevReceipt}, {bundle.ReceiptProof, bundle.Receipt}} {
+		db := memorydb.New()
+		for _, node := range c.proof {
+			require.NoError(t, db.Put(crypto.Keccak256(node), node))
+		}
+		key, err := rlp.EncodeToBytes(uint64(i))
+		require.NoError(t, err)
+		value, err := trie.VerifyProof(source.header.ReceiptHash, key, db)
+		require.NoError(t, err)
+		require.Equal(t, []byte(c.value), value)
+	}
+
+	// the first transaction has no previous receipt
+	bundle, err = listener.TxStatusBundle(ctx, source.receipts[0].TxHash, 0)
+	require.NoError(t, err)
+	require.Empty(t, bundle.PrevReceiptProof)
+	require.Equal(t, uint64(21_000), bundle.GasUsed)
+
+	_, err = listener.TxStatusBundle(ctx, source.receipts[1].TxHash, 49_999)
+	require.ErrorIs(t, err, ErrTxOverGas)
+	_, err = listener.TxStatusBundle(ctx, source.receipts[2].TxHash, 0)
+	require.ErrorIs(t, err, ErrTxFailed)
+
+	// receipts that do not match the header are rejected
+	source.receipts[0].CumulativeGasUsed++
+	_, err = listener.TxStatusBundle(ctx, source.receipts[1].TxHash, 0)
+	require.Error(t, err)
+}
@@ -2,33 +2,351 @@ package types
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kysee/zk-chains/types"
 )
 
 // Config holds the relayer configuration
 type Config struct {
 	RootDir string
 
-	// RPCEndpoint is used when DataSource is "rpc"
+	// RPCEndpoint is the beacon endpoint fetched first, the beacon API of Network by default, or of
+	// Sepolia without a network
 	RPCEndpoint string
+	// RPCEndpoints are more beacon endpoints, which the relayer fails over to when RPCEndpoint fails,
+	// see BeaconEndpoints
+	RPCEndpoints []string
+	// BeaconQuorum is the number of beacon endpoints each update is fetched from, which must agree on
+	// it before it is proven. 1 trusts the first endpoint answering.
+	BeaconQuorum int
+	// BeaconRecordDir, if set, is where the responses of the beacon endpoints are saved, to be served
+	// back by BeaconReplayDir
+	BeaconRecordDir string
+	// BeaconReplayDir serves the responses recorded in it instead of fetching from the beacon endpoints
+	BeaconReplayDir string
+	// UpdatesDir, if set, holds the updates (updates/{period}.json) and blocks (blocks/{slot}.json) relayed
+	// offline instead of fetching from the beacon endpoints
+	UpdatesDir string
 	// InitPeriod is the period to start fetching updates from
 	InitPeriod uint64
+	// Once proves a single period, or the receipt of TxHash, and exits instead of running as a daemon
+	Once bool
+	// ResumeFromChain starts from the period the light client of LightClientAddress accepts next,
+	// instead of InitPeriod, when a destination is configured
+	ResumeFromChain bool
+	// TrustedBlockRoot is the root of a block trusted by the operator (e.g. a finalized checkpoint). When
+	// set, the relayer starts from the verified bootstrap of that block instead of InitPeriod.
+	TrustedBlockRoot [32]byte
 
 	Slot uint64
+
+	// ScPubKeysHashMode must match the mode the circuit was compiled with
+	ScPubKeysHashMode types.ScPubKeysHashMode
+
+	// TransitionScPubKeysHashMode is the mode a deployment migrates to. Until TransitionUntilPeriod
+	// (inclusive), every committee handed over also gets an Eth2ScTransitionCircuit proof binding its
+	// ScPubKeysHashMode and TransitionScPubKeysHashMode commitments. 0 disables the migration window.
+	TransitionScPubKeysHashMode types.ScPubKeysHashMode
+	TransitionUntilPeriod       uint64
+
+	// Network is the beacon chain followed (see types.NetworkByName), empty for a custom one. It sets
+	// Preset and, unless configured, Domain for Fork, SlotDuration, RPCEndpoint and the manifest of
+	// its artifacts, see BeaconNetwork.
+	Network string
+	// GenesisValidatorsRoot and ForkVersion, the version of Fork, override those of Network. A devnet
+	// network needs both to compute its domain.
+	GenesisValidatorsRoot [32]byte
+	ForkVersion           [4]byte
+
+	// Preset is the consensus preset of the network the circuit was compiled for, it fixes the size
+	// of the sync committee and the slots per period
+	Preset types.Preset
+
+	// Fork selects the BeaconState layout the circuit was compiled for (e.g. "deneb", "fulu"),
+	// which determines the generalized index of next_sync_committee
+	Fork string
+
+	// Domain is the sync committee signing domain of the target network and fork.
+	// The zero value means the circuit's default domain.
+	Domain [32]byte
+
+	// PublicStateRoot must be set when the circuit was compiled with -public-state-root, the proofs
+	// then also expose the state root of the attested header and are submitted with the *WithStateRoot
+	// calls of the light client
+	PublicStateRoot bool
+
+	// GPU proves the Groth16 BN254 circuits on a CUDA GPU with gnark's ICICLE backend. The relayer must
+	// be built with -tags icicle and the ICICLE libraries installed; the proving keys are unchanged.
+	GPU bool
+	// ProverCores caps the cores used by the solver and the prover (GOMAXPROCS), 0 uses them all
+	ProverCores int
+	// MemoryLimitMB is the memory ceiling of the relayer in MiB, 0 for none. It is the soft limit of the
+	// garbage collector, and a proof not expected to fit under it fails with relayer.ErrMemoryCeiling instead of
+	// running the process out of memory.
+	MemoryLimitMB uint64
+	// MaxConcurrentProofs is the number of proofs generated at once, by the relayer and the on-demand
+	// and remote requests it serves, 0 for no limit. The proofs over it, or whose estimated memory does
+	// not fit under MemoryLimitMB next to the ones running, wait for a running proof to end.
+	MaxConcurrentProofs int
+	// RemoteProver is the gRPC address of a serve command holding the proving keys. The relayer then
+	// only assigns the witnesses, which it ships to it, and verifies the returned proofs with the
+	// verifying keys: it needs neither the constraint systems nor the proving keys.
+	RemoteProver string
+
+	// ManifestPath is the artifact manifest written by setup_circuit, which records the backend,
+	// curve and artifacts of each circuit. Without a manifest the relayer assumes Groth16 on BN254.
+	ManifestPath string
+	// Circuits are the kinds of proofs the relayer generates, see relayer.CircuitKind. The kinds other
+	// than sc-update, always generated, need the artifacts of their circuit in the manifest.
+	Circuits []string
+
+	// ProofDir receives the generated proofs (proof-period-N.json)
+	ProofDir string
+	// ProofSinks receive a copy of each proof file: directories, s3://, ipfs+http:// or webhook URLs,
+	// see relayer.NewProofSink
+	ProofSinks []string
+	// FixturesDir holds the recorded updates replayed by the simulate command
+	FixturesDir string
+	// SimulateProve enables proving with the toy circuit during simulations
+	SimulateProve bool
+	// ReportPath is where the simulate and bench commands write their JSON report, if set
+	ReportPath string
+	// BenchRuns is the number of proofs of the same update the bench command measures
+	BenchRuns int
+
+	// DestinationRPC is the JSON-RPC endpoint of the chain hosting the light client. When set,
+	// every proof's submission is simulated (eth_estimateGas) against GasLimit.
+	DestinationRPC string
+	// LightClientAddress is the Eth2LightClient contract the proofs are submitted to
+	LightClientAddress string
+	// SubmitterAddress is the sender used to simulate submissions, and the account of RemoteSigner
+	SubmitterAddress string
+	// SubmitterKeyEnv names the environment variable holding the hex private key the proofs are
+	// submitted with. Submission is disabled, proofs are only saved, when neither the variable,
+	// SubmitterKeystore nor RemoteSigner is set. A plaintext key is discouraged.
+	SubmitterKeyEnv string
+	// SubmitterKeystore is an encrypted go-ethereum keystore file holding the submitter key, decrypted
+	// with the password of the SubmitterPasswordEnv environment variable
+	SubmitterKeystore    string
+	SubmitterPasswordEnv string
+	// RemoteSigner is the URL of a signer (web3signer, clef, ...) signing the submissions of
+	// SubmitterAddress with eth_signTransaction, so that the relayer holds no key
+	RemoteSigner string
+	// SubmitConfirmations is the number of blocks a submission waits for, the inclusion one included
+	SubmitConfirmations uint64
+	// GasLimit is the gas budget of one submission, 0 disables the check
+	GasLimit uint64
+	// MaxFeeGwei and MaxTipGwei cap the maxFeePerGas and maxPriorityFeePerGas of a submission, 0 for no cap
+	MaxFeeGwei float64
+	MaxTipGwei float64
+	// StuckAfter is how long a submission waits to be mined before it is replaced with higher fees,
+	// 0 disables replacements
+	StuckAfter time.Duration
+	// FeeBumpPercent is the fee increase of a replacement, at least 10
+	FeeBumpPercent uint64
+	// GasBudgetAction is what happens to an over-budget proof: "alert" (log only) or "skip" (set aside)
+	GasBudgetAction string
+
+	// ConsumersPath is a JSON array of the consumers served by the relayer (see relayer.Consumer),
+	// each with its own contract, commitment mode, threshold and notification target
+	ConsumersPath string
+	// DestinationsPath is a JSON array of further chains the proofs are submitted to (see
+	// relayer.Destination), each with its own RPC, chain id, light client and submitter key
+	DestinationsPath string
+
+	// ExecutionRPC is the JSON-RPC endpoint of the execution chain the receipts are fetched from
+	ExecutionRPC string
+	// TxHash is the transaction whose success the tx-status command packages a proof bundle for
+	TxHash string
+	// MaxGas is the gas budget the transaction is proven to stay within, 0 means no budget
+	MaxGas uint64
+	// WatchContracts and WatchTopics select the logs of the execution chain whose transactions the
+	// watcher proves: logs of any of the contracts, with any of the topics as first topic. An empty list
+	// matches every contract, or every topic.
+	WatchContracts []string
+	WatchTopics    []string
+	// WatchFromBlock is the first block the watcher reads the logs of, 0 for the head of the chain
+	WatchFromBlock uint64
+	// WatchInterval is how often the watcher polls for new logs, when the execution RPC cannot stream
+	// them, and for the latest attested header
+	WatchInterval time.Duration
+
+	// QuarantineDir receives the witness and update of a period whose proof generation failed
+	QuarantineDir string
+	// ArtifactKeyEnv names the environment variable holding the AES-256 key used to encrypt
+	// witness and quarantine files. Encryption is disabled when the variable is empty.
+	ArtifactKeyEnv string
+
+	// RetryMaxAttempts bounds the attempts of a proof or a submission, and of the start and restarts
+	// of the relayer at a period, 1 disables retries. Fetches are retried until the update is
+	// available, waiting up to RetryMaxBackoff.
+	RetryMaxAttempts int
+	// RetryBackoff is the wait after the first failed attempt, doubled after each following one
+	RetryBackoff time.Duration
+	// RetryMaxBackoff caps the wait between two attempts
+	RetryMaxBackoff time.Duration
+	// BeaconEvents follows the events of the beacon node (/eth/v1/events) to fetch an update as soon as
+	// it is available, polling only every RetryMaxBackoff while the stream is up
+	BeaconEvents bool
+	// SlotDuration is the length of a slot of the beacon chain, set by Network unless configured, 12
+	// seconds if neither is
+	SlotDuration time.Duration
+	// PeriodMargin is added to the time the update of a period is expected at: the first slot of the
+	// period and the epochs finalizing it. The relayer sleeps until then instead of polling for it.
+	PeriodMargin time.Duration
+
+	// GRPCAddr is the address the serve command listens on for proof requests
+	GRPCAddr string
+	// HTTPAddr is the address the relayer serves its proofs and status on, empty to disable the HTTP API
+	HTTPAddr string
+	// MaxProofAge is how long the relayer may go without a new proof before /healthz reports it as
+	// stalled, 0 disables the check
+	MaxProofAge time.Duration
+	// OTLPEndpoint is the URL of the OpenTelemetry collector the spans of the proving stages are exported
+	// to over gRPC (http:// without TLS), empty to drop them
+	OTLPEndpoint string
+
+	// LogLevel is the lowest level of the messages NewConfig's Logger writes to stderr
+	LogLevel string
+	// Logger receives the messages of the relayer, set it to embed the package in another service.
+	// A nil Logger drops them.
+	Logger Logger
+	// EnvFile holds KEY=VALUE lines, with the names of the environment variables, that override them.
+	// Unlike the environment, it is read again by Reload.
+	EnvFile string
+	// Args are the arguments NewConfig parsed, see Reload
+	Args []string
 }
 
 func NewConfig(args ...string) *Config {
-	// Parse configuration from environment variables or command line args
+	// Parse configuration from environment variables, the env file, or command line args
+	env := loadEnvironment(args)
 	config := Config{
-		RootDir:     getEnv("ROOT", "."),
-		RPCEndpoint: getEnv("RPC_ENDPOINT", "https://lodestar-sepolia.chainsafe.io/"),
+		RootDir:     env.get("ROOT", "."),
+		RPCEndpoint: env.get("RPC_ENDPOINT", ""),
 		InitPeriod:  0,
 		Slot:        0,
 	}
+	config.Fork = env.get("FORK", "fulu")
+	config.ProofDir = env.get("PROOF_DIR", "output")
+	config.ProofSinks = parseList(env.get("PROOF_SINKS", ""))
+	config.ManifestPath = env.get("MANIFEST", "")
+	config.Circuits = parseList(env.get("CIRCUITS", "sc-update"))
+	config.DestinationRPC = env.get("DESTINATION_RPC", "")
+	config.LightClientAddress = env.get("LIGHT_CLIENT_ADDRESS", "")
+	config.SubmitterAddress = env.get("SUBMITTER_ADDRESS", "")
+	config.SubmitterKeyEnv = env.get("SUBMITTER_KEY_ENV", "SUBMITTER_KEY")
+	config.SubmitterKeystore = env.get("SUBMITTER_KEYSTORE", "")
+	config.SubmitterPasswordEnv = env.get("SUBMITTER_PASSWORD_ENV", "SUBMITTER_PASSWORD")
+	config.RemoteSigner = env.get("REMOTE_SIGNER", "")
+	config.SubmitConfirmations, _ = strconv.ParseUint(env.get("SUBMIT_CONFIRMATIONS", "1"), 10, 64)
+	config.GasLimit, _ = strconv.ParseUint(env.get("GAS_LIMIT", "10000000"), 10, 64)
+	config.ConsumersPath = env.get("CONSUMERS", "")
+	config.DestinationsPath = env.get("DESTINATIONS", "")
+	config.ExecutionRPC = env.get("EXECUTION_RPC", "")
+	config.WatchContracts = parseList(env.get("WATCH_CONTRACTS", ""))
+	config.WatchTopics = parseList(env.get("WATCH_TOPICS", ""))
+	config.WatchFromBlock, _ = strconv.ParseUint(env.get("WATCH_FROM_BLOCK", "0"), 10, 64)
+	config.WatchInterval, _ = time.ParseDuration(env.get("WATCH_INTERVAL", "12s"))
+	config.QuarantineDir = env.get("QUARANTINE_DIR", filepath.Join(config.RootDir, "quarantine"))
+	config.ArtifactKeyEnv = env.get("ARTIFACT_KEY_ENV", "ARTIFACT_KEY")
+	config.GRPCAddr = env.get("GRPC_ADDR", ":9090")
+	config.HTTPAddr = env.get("HTTP_ADDR", "")
+	config.MaxProofAge, _ = time.ParseDuration(env.get("MAX_PROOF_AGE", "0"))
+	config.OTLPEndpoint = env.get("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	config.LogLevel = env.get("LOG_LEVEL", "info")
+	config.RetryMaxAttempts, _ = strconv.Atoi(env.get("RETRY_MAX_ATTEMPTS", "3"))
+	config.RetryBackoff, _ = time.ParseDuration(env.get("RETRY_BACKOFF", "1s"))
+	config.RetryMaxBackoff, _ = time.ParseDuration(env.get("RETRY_MAX_BACKOFF", "1m"))
+	config.RPCEndpoints = parseList(env.get("RPC_ENDPOINTS", ""))
+	config.BeaconQuorum, _ = strconv.Atoi(env.get("BEACON_QUORUM", "1"))
+	config.BeaconRecordDir = env.get("BEACON_RECORD_DIR", "")
+	config.BeaconReplayDir = env.get("BEACON_REPLAY_DIR", "")
+	config.UpdatesDir = env.get("UPDATES_DIR", "")
+	config.BeaconEvents, _ = strconv.ParseBool(env.get("BEACON_EVENTS", "true"))
+	config.ResumeFromChain, _ = strconv.ParseBool(env.get("RESUME_FROM_CHAIN", "true"))
+	config.Once, _ = strconv.ParseBool(env.get("ONCE", "false"))
+	config.SlotDuration, _ = time.ParseDuration(env.get("SLOT_DURATION", "0s"))
+	config.PeriodMargin, _ = time.ParseDuration(env.get("PERIOD_MARGIN", "1m"))
+
+	// a mistyped domain would prove against the default one the contract rejects, it fails like --domain
+	domain, err := parseDomain(env.get("DOMAIN", ""))
+	if err != nil {
+		panic(fmt.Errorf("DOMAIN: %w", err))
+	}
+	config.Domain = domain
+	// a mistyped root would trust the committee of the InitPeriod update, it fails like --trusted-block-root
+	trustedRoot, err := parseRoot(env.get("TRUSTED_BLOCK_ROOT", ""))
+	if err != nil {
+		panic(fmt.Errorf("TRUSTED_BLOCK_ROOT: %w", err))
+	}
+	config.TrustedBlockRoot = trustedRoot
+	// a mistyped genesis, fork or fork version would sign with the domain of another network, they fail
+	// like their flags
+	if config.GenesisValidatorsRoot, err = parseRoot(env.get("GENESIS_VALIDATORS_ROOT", "")); err != nil {
+		panic(fmt.Errorf("GENESIS_VALIDATORS_ROOT: %w", err))
+	}
+	if config.ForkVersion, err = parseForkVersion(env.get("FORK_VERSION", "")); err != nil {
+		panic(fmt.Errorf("FORK_VERSION: %w", err))
+	}
+	if _, err := types.NextSyncCommitteeGIndexForFork(config.Fork); err != nil {
+		panic(fmt.Errorf("FORK: %w", err))
+	}
+
+	// a mistyped preset would size the committees for mainnet, it fails like --preset
+	preset, err := types.ParsePreset(env.get("PRESET", ""))
+	if err != nil {
+		panic(fmt.Errorf("PRESET: %w", err))
+	}
+	config.Preset = preset
+
+	// a mistyped cap would leave the fees uncapped and a mistyped delay would replace the submissions on
+	// every poll, they fail like their flags
+	if config.MaxFeeGwei, err = parseGwei(env.get("MAX_FEE_GWEI", "0")); err != nil {
+		panic(fmt.Errorf("MAX_FEE_GWEI: %w", err))
+	}
+	if config.MaxTipGwei, err = parseGwei(env.get("MAX_TIP_GWEI", "0")); err != nil {
+		panic(fmt.Errorf("MAX_TIP_GWEI: %w", err))
+	}
+	if config.StuckAfter, err = time.ParseDuration(env.get("STUCK_AFTER", "3m")); err != nil {
+		panic(fmt.Errorf("STUCK_AFTER: %w", err))
+	}
+	if config.FeeBumpPercent, err = strconv.ParseUint(env.get("FEE_BUMP_PERCENT", "20"), 10, 64); err != nil {
+		panic(fmt.Errorf("FEE_BUMP_PERCENT: %w", err))
+	}
+	// a mistyped action would alert instead of skipping the over-budget proofs
+	if config.GasBudgetAction, err = parseGasBudgetAction(env.get("GAS_BUDGET_ACTION", "alert")); err != nil {
+		panic(fmt.Errorf("GAS_BUDGET_ACTION: %w", err))
+	}
+
+	config.Network = env.get("NETWORK", "")
+	config.PublicStateRoot, _ = strconv.ParseBool(env.get("PUBLIC_STATE_ROOT", "false"))
+	config.GPU, _ = strconv.ParseBool(env.get("GPU", "false"))
+	config.ProverCores, _ = strconv.Atoi(env.get("PROVER_CORES", "0"))
+	config.MemoryLimitMB, _ = strconv.ParseUint(env.get("MEMORY_LIMIT_MB", "0"), 10, 64)
+	config.MaxConcurrentProofs, _ = strconv.Atoi(env.get("MAX_CONCURRENT_PROOFS", "1"))
+	config.BenchRuns, _ = strconv.Atoi(env.get("BENCH_RUNS", "5"))
+	config.RemoteProver = env.get("REMOTE_PROVER", "")
+
+	// an invalid mode would commit to committees differently from the circuit, it fails like the flags
+	mode, err := types.ParseScPubKeysHashMode(env.get("SC_HASH_MODE", ""))
+	if err != nil {
+		panic(fmt.Errorf("SC_HASH_MODE: %w", err))
+	}
+	config.ScPubKeysHashMode = mode
+	if config.TransitionScPubKeysHashMode, err = types.ParseScPubKeysHashMode(env.get("TRANSITION_SC_HASH_MODE", "full")); err != nil {
+		panic(fmt.Errorf("TRANSITION_SC_HASH_MODE: %w", err))
+	}
+	config.TransitionUntilPeriod, _ = strconv.ParseUint(env.get("TRANSITION_UNTIL_PERIOD", "0"), 10, 64)
 
 	for i := 0; i < len(args); i++ {
-		if len(args) <= i+1 {
+		if len(args) <= i+1 && args[i] != "--once" {
 			panic(fmt.Errorf("missing argument for %s", args[i-1]))
 		}
 
@@ -42,15 +360,463 @@ func NewConfig(args ...string) *Config {
 		case "--init-period":
 			config.InitPeriod, _ = strconv.ParseUint(args[i+1], 10, 64)
 			i++
+		case "--once":
+			// a bare --once, unlike the other switches, needs no value
+			config.Once = true
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				config.Once, _ = strconv.ParseBool(args[i+1])
+				i++
+			}
+		case "--resume-from-chain":
+			config.ResumeFromChain, _ = strconv.ParseBool(args[i+1])
+			i++
+		case "--trusted-block-root":
+			root, err := parseRoot(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.TrustedBlockRoot = root
+			i++
 		case "--rpc":
 			config.RPCEndpoint = args[i+1]
 			i++
+		case "--rpc-endpoints":
+			config.RPCEndpoints = parseList(args[i+1])
+			i++
+		case "--beacon-quorum":
+			config.BeaconQuorum, _ = strconv.Atoi(args[i+1])
+			i++
+		case "--record-dir":
+			config.BeaconRecordDir = args[i+1]
+			i++
+		case "--replay-dir":
+			config.BeaconReplayDir = args[i+1]
+			i++
+		case "--updates-dir":
+			config.UpdatesDir = args[i+1]
+			i++
+		case "--manifest":
+			config.ManifestPath = args[i+1]
+			i++
+		case "--proof-dir":
+			config.ProofDir = args[i+1]
+			i++
+		case "--proof-sinks":
+			config.ProofSinks = parseList(args[i+1])
+			i++
+		case "--circuits":
+			config.Circuits = parseList(args[i+1])
+			i++
+		case "--fixtures":
+			config.FixturesDir = args[i+1]
+			i++
+		case "--prove":
+			config.SimulateProve, _ = strconv.ParseBool(args[i+1])
+			i++
+		case "--report":
+			config.ReportPath = args[i+1]
+			i++
+		case "--bench-runs":
+			config.BenchRuns, _ = strconv.Atoi(args[i+1])
+			i++
+		case "--dest-rpc":
+			config.DestinationRPC = args[i+1]
+			i++
+		case "--light-client":
+			config.LightClientAddress = args[i+1]
+			i++
+		case "--from":
+			config.SubmitterAddress = args[i+1]
+			i++
+		case "--submitter-key-env":
+			config.SubmitterKeyEnv = args[i+1]
+			i++
+		case "--submitter-keystore":
+			config.SubmitterKeystore = args[i+1]
+			i++
+		case "--submitter-password-env":
+			config.SubmitterPasswordEnv = args[i+1]
+			i++
+		case "--remote-signer":
+			config.RemoteSigner = args[i+1]
+			i++
+		case "--confirmations":
+			config.SubmitConfirmations, _ = strconv.ParseUint(args[i+1], 10, 64)
+			i++
+		case "--gas-limit":
+			config.GasLimit, _ = strconv.ParseUint(args[i+1], 10, 64)
+			i++
+		case "--max-fee-gwei":
+			gwei, err := parseGwei(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.MaxFeeGwei = gwei
+			i++
+		case "--max-tip-gwei":
+			gwei, err := parseGwei(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.MaxTipGwei = gwei
+			i++
+		case "--stuck-after":
+			stuckAfter, err := time.ParseDuration(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.StuckAfter = stuckAfter
+			i++
+		case "--fee-bump-percent":
+			percent, err := strconv.ParseUint(args[i+1], 10, 64)
+			if err != nil {
+				panic(err)
+			}
+			config.FeeBumpPercent = percent
+			i++
+		case "--gas-budget-action":
+			action, err := parseGasBudgetAction(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.GasBudgetAction = action
+			i++
+		case "--consumers":
+			config.ConsumersPath = args[i+1]
+			i++
+		case "--destinations":
+			config.DestinationsPath = args[i+1]
+			i++
+		case "--exec-rpc":
+			config.ExecutionRPC = args[i+1]
+			i++
+		case "--tx":
+			config.TxHash = args[i+1]
+			i++
+		case "--max-gas":
+			config.MaxGas, _ = strconv.ParseUint(args[i+1], 10, 64)
+			i++
+		case "--watch-contracts":
+			config.WatchContracts = parseList(args[i+1])
+			i++
+		case "--watch-topics":
+			config.WatchTopics = parseList(args[i+1])
+			i++
+		case "--watch-from-block":
+			config.WatchFromBlock, _ = strconv.ParseUint(args[i+1], 10, 64)
+			i++
+		case "--watch-interval":
+			config.WatchInterval, _ = time.ParseDuration(args[i+1])
+			i++
+		case "--quarantine-dir":
+			config.QuarantineDir = args[i+1]
+			i++
+		case "--artifact-key-env":
+			config.ArtifactKeyEnv = args[i+1]
+			i++
+		case "--fork":
+			if _, err := types.NextSyncCommitteeGIndexForFork(args[i+1]); err != nil {
+				panic(err)
+			}
+			config.Fork = args[i+1]
+			i++
+		case "--domain":
+			domain, err := parseDomain(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.Domain = domain
+			i++
+		case "--genesis-validators-root":
+			root, err := parseRoot(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.GenesisValidatorsRoot = root
+			i++
+		case "--fork-version":
+			version, err := parseForkVersion(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.ForkVersion = version
+			i++
+		case "--public-state-root":
+			config.PublicStateRoot, _ = strconv.ParseBool(args[i+1])
+			i++
+		case "--gpu":
+			config.GPU, _ = strconv.ParseBool(args[i+1])
+			i++
+		case "--prover-cores":
+			config.ProverCores, _ = strconv.Atoi(args[i+1])
+			i++
+		case "--memory-limit-mb":
+			config.MemoryLimitMB, _ = strconv.ParseUint(args[i+1], 10, 64)
+			i++
+		case "--max-concurrent-proofs":
+			config.MaxConcurrentProofs, _ = strconv.Atoi(args[i+1])
+			i++
+		case "--remote-prover":
+			config.RemoteProver = args[i+1]
+			i++
+		case "--network":
+			if _, err := types.NetworkByName(args[i+1]); err != nil {
+				panic(err)
+			}
+			config.Network = args[i+1]
+			i++
+		case "--preset":
+			preset, err := types.ParsePreset(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.Preset = preset
+			i++
+		case "--sc-hash-mode":
+			mode, err := types.ParseScPubKeysHashMode(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.ScPubKeysHashMode = mode
+			i++
+		case "--transition-sc-hash-mode":
+			mode, err := types.ParseScPubKeysHashMode(args[i+1])
+			if err != nil {
+				panic(err)
+			}
+			config.TransitionScPubKeysHashMode = mode
+			i++
+		case "--transition-until-period":
+			config.TransitionUntilPeriod, _ = strconv.ParseUint(args[i+1], 10, 64)
+			i++
+		case "--retry-max-attempts":
+			config.RetryMaxAttempts, _ = strconv.Atoi(args[i+1])
+			i++
+		case "--retry-backoff":
+			config.RetryBackoff, _ = time.ParseDuration(args[i+1])
+			i++
+		case "--retry-max-backoff":
+			config.RetryMaxBackoff, _ = time.ParseDuration(args[i+1])
+			i++
+		case "--beacon-events":
+			config.BeaconEvents, _ = strconv.ParseBool(args[i+1])
+			i++
+		case "--slot-duration":
+			config.SlotDuration, _ = time.ParseDuration(args[i+1])
+			i++
+		case "--period-margin":
+			config.PeriodMargin, _ = time.ParseDuration(args[i+1])
+			i++
+		case "--grpc-addr":
+			config.GRPCAddr = args[i+1]
+			i++
+		case "--http-addr":
+			config.HTTPAddr = args[i+1]
+			i++
+		case "--max-proof-age":
+			config.MaxProofAge, _ = time.ParseDuration(args[i+1])
+			i++
+		case "--otlp-endpoint":
+			config.OTLPEndpoint = args[i+1]
+			i++
+		case "--log-level":
+			config.LogLevel = args[i+1]
+			i++
+		case "--env-file":
+			// read by loadEnvironment
+			i++
 		}
 	}
 
+	logger, err := NewLogger(os.Stderr, config.LogLevel)
+	if err != nil {
+		panic(err)
+	}
+	config.Logger = logger
+	config.Args = args
+	config.EnvFile = env.path
+
+	if config.Network != "" {
+		if err := config.applyNetwork(); err != nil {
+			panic(err)
+		}
+	}
+	if config.RPCEndpoint == "" && config.Network == "" {
+		config.RPCEndpoint = types.NetworkSepolia.BeaconAPI
+	}
+	if config.ManifestPath == "" {
+		config.ManifestPath = config.defaultManifestPath()
+	}
 	return &config
 }
 
+// defaultManifestPath returns the manifest of the artifacts of Network in ../.build/<network> if there is
+// one, or else the one of ../.build
+func (c *Config) defaultManifestPath() string {
+	build := filepath.Join(c.RootDir, "../.build")
+	if c.Network != "" {
+		path := filepath.Join(build, strings.ToLower(c.Network), types.ManifestFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(build, types.ManifestFileName)
+}
+
+// Reload parses the environment and the arguments of c again, as NewConfig did, and returns the
+// config they set now. An invalid value is returned as an error rather than a panic.
+func (c *Config) Reload() (next *Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			next, err = nil, fmt.Errorf("invalid configuration: %v", r)
+		}
+	}()
+	return NewConfig(c.Args...), nil
+}
+
+// Log returns the Logger of the config, NopLogger if there is none
+func (c *Config) Log() Logger {
+	if c == nil || c.Logger == nil {
+		return NopLogger
+	}
+	return c.Logger
+}
+
+// BeaconEndpoints returns the beacon endpoints in the order they are tried: RPCEndpoint, then
+// RPCEndpoints, each once
+func (c *Config) BeaconEndpoints() []string {
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, endpoint := range append([]string{c.RPCEndpoint}, c.RPCEndpoints...) {
+		if endpoint != "" && !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// BeaconNetwork returns the parameters of Network, with the configured GenesisValidatorsRoot and
+// ForkVersion of Fork
+func (c *Config) BeaconNetwork() (*types.Network, error) {
+	network, err := types.NetworkByName(c.Network)
+	if err != nil {
+		return nil, err
+	}
+	return network.WithGenesis(c.GenesisValidatorsRoot, c.Fork, c.ForkVersion), nil
+}
+
+// applyNetwork sets the preset of Network, and its slot duration, beacon endpoint and sync committee
+// domain at Fork if they are not configured
+func (c *Config) applyNetwork() error {
+	network, err := c.BeaconNetwork()
+	if err != nil {
+		return err
+	}
+	c.Preset = network.Preset
+	if c.SlotDuration == 0 {
+		c.SlotDuration = network.SlotDuration
+	}
+	if c.RPCEndpoint == "" {
+		c.RPCEndpoint = network.BeaconAPI
+	}
+	if c.Domain == ([32]byte{}) {
+		if c.Domain, err = network.SyncCommitteeDomain(c.Fork); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseGasBudgetAction parses the action taken on an over-budget proof, alert or skip
+func parseGasBudgetAction(s string) (string, error) {
+	if s != "alert" && s != "skip" {
+		return "", fmt.Errorf("unknown gas budget action %q", s)
+	}
+	return s, nil
+}
+
+// parseGwei parses a fee cap in gwei, 0 meaning no cap
+func parseGwei(s string) (float64, error) {
+	gwei, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gwei amount %q: %w", s, err)
+	}
+	if gwei < 0 || math.IsNaN(gwei) || math.IsInf(gwei, 0) {
+		return 0, fmt.Errorf("invalid gwei amount %q", s)
+	}
+	return gwei, nil
+}
+
+// parseDomain parses a 32-byte hex encoded signing domain, an empty string yields the zero domain
+func parseDomain(s string) ([32]byte, error) {
+	var domain [32]byte
+	if s == "" {
+		return domain, nil
+	}
+	b, err := types.HexToBytes(s)
+	if err != nil {
+		return domain, fmt.Errorf("invalid domain %q: %w", s, err)
+	}
+	if len(b) != 32 {
+		return domain, fmt.Errorf("domain must be 32 bytes, got %d", len(b))
+	}
+	copy(domain[:], b)
+	return domain, nil
+}
+
+// environment are the settings of the env file of ENV_FILE or --env-file, if any, which override the
+// environment variables
+type environment struct {
+	path   string
+	values map[string]string
+}
+
+// loadEnvironment reads the env file named by ENV_FILE, or by --env-file in args. Blank lines and lines
+// starting with # are skipped, values may be quoted.
+func loadEnvironment(args []string) environment {
+	env := environment{path: getEnv("ENV_FILE", "")}
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--env-file" {
+			env.path = args[i+1]
+		}
+	}
+	if env.path == "" {
+		return env
+	}
+	data, err := os.ReadFile(env.path)
+	if err != nil {
+		panic(fmt.Errorf("failed to read env file: %w", err))
+	}
+	env.values = make(map[string]string)
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			panic(fmt.Errorf("%s:%d: expected KEY=VALUE", env.path, n+1))
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		env.values[strings.TrimSpace(key)] = value
+	}
+	return env
+}
+
+// get returns the value of key in the env file, else in the environment, else defaultValue
+func (e environment) get(key, defaultValue string) string {
+	if value := e.values[key]; value != "" {
+		return value
+	}
+	return getEnv(key, defaultValue)
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -58,3 +824,48 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// parseList parses a comma-separated list, dropping the empty items
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRoot parses a 32-byte hex encoded block root, an empty string yields the zero root
+func parseRoot(s string) ([32]byte, error) {
+	var root [32]byte
+	if s == "" {
+		return root, nil
+	}
+	b, err := types.HexToBytes(s)
+	if err != nil {
+		return root, fmt.Errorf("invalid block root %q: %w", s, err)
+	}
+	if len(b) != 32 {
+		return root, fmt.Errorf("block root must be 32 bytes, got %d", len(b))
+	}
+	copy(root[:], b)
+	return root, nil
+}
+
+// parseForkVersion parses a 4-byte hex encoded fork version, an empty string yields the zero version
+func parseForkVersion(s string) ([4]byte, error) {
+	var version [4]byte
+	if s == "" {
+		return version, nil
+	}
+	b, err := types.HexToBytes(s)
+	if err != nil {
+		return version, fmt.Errorf("invalid fork version %q: %w", s, err)
+	}
+	if len(b) != 4 {
+		return version, fmt.Errorf("fork version must be 4 bytes, got %d", len(b))
+	}
+	copy(version[:], b)
+	return version, nil
+}
@@ -0,0 +1,203 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// FakeFetcher is an in-memory Fetcher, BatchFetcher, BootstrapFetcher and AttestationFetcher serving
+// programmed responses, to exercise the relayer and the listener without a beacon node or fixture
+// files. A response that was not programmed fails with an error wrapping os.ErrNotExist, as a file
+// or update not available yet does. Errors are injected per method with Fail, and the calls of each
+// method counted by Calls. It streams no events. It is safe for concurrent use.
+type FakeFetcher struct {
+	mu            sync.Mutex
+	updates       map[uint64]*types.LightClientUpdate
+	blocks        map[uint64]*BlockAPIResponse
+	bootstraps    map[common.Root]*types.LightClientBootstrap
+	headSlot      *uint64
+	attestedBlock *uint64
+	failures      map[string]*fakeFailure
+	calls         map[string]int
+}
+
+// fakeFailure is an error injected in a method of a FakeFetcher, for times calls or, if 0, all of them
+type fakeFailure struct {
+	err   error
+	times int
+}
+
+// NewFakeFetcher returns a FakeFetcher without any response
+func NewFakeFetcher() *FakeFetcher {
+	return &FakeFetcher{
+		updates:    map[uint64]*types.LightClientUpdate{},
+		blocks:     map[uint64]*BlockAPIResponse{},
+		bootstraps: map[common.Root]*types.LightClientBootstrap{},
+		failures:   map[string]*fakeFailure{},
+		calls:      map[string]int{},
+	}
+}
+
+// SetUpdate serves update as the light client update of period
+func (f *FakeFetcher) SetUpdate(period uint64, update *types.LightClientUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates[period] = update
+}
+
+// SetBlock serves block as the beacon block of slot
+func (f *FakeFetcher) SetBlock(slot uint64, block *BlockAPIResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocks[slot] = block
+}
+
+// SetBootstrap serves bootstrap as the light client bootstrap of blockRoot
+func (f *FakeFetcher) SetBootstrap(blockRoot common.Root, bootstrap *types.LightClientBootstrap) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bootstraps[blockRoot] = bootstrap
+}
+
+// SetHeadSlot serves slot as the slot of the beacon head
+func (f *FakeFetcher) SetHeadSlot(slot uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.headSlot = &slot
+}
+
+// SetAttestedBlockNumber serves number as the execution block number of the latest attested header
+func (f *FakeFetcher) SetAttestedBlockNumber(number uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attestedBlock = &number
+}
+
+// Fail makes the next times calls of method, the name of a fetcher method such as "ScUpdate", fail
+// with err. With times 0 every call fails, until Fail(method, nil, 0) clears the error.
+func (f *FakeFetcher) Fail(method string, err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.failures, method)
+		return
+	}
+	f.failures[method] = &fakeFailure{err: err, times: times}
+}
+
+// Calls returns the number of calls of method, failed ones included
+func (f *FakeFetcher) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// call counts a call of method and returns the error injected in it, if any. f.mu must be held.
+func (f *FakeFetcher) call(method string) error {
+	f.calls[method]++
+	failure, ok := f.failures[method]
+	if !ok {
+		return nil
+	}
+	if failure.times > 0 {
+		if failure.times--; failure.times == 0 {
+			delete(f.failures, method)
+		}
+	}
+	return failure.err
+}
+
+// ScUpdate serves the update of period
+func (f *FakeFetcher) ScUpdate(_ context.Context, period uint64) (*types.LightClientUpdate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ScUpdate"); err != nil {
+		return nil, err
+	}
+	update, ok := f.updates[period]
+	if !ok {
+		return nil, fmt.Errorf("no update for period %d: %w", period, os.ErrNotExist)
+	}
+	return update, nil
+}
+
+// ScUpdates serves the updates of count periods from startPeriod on, up to the first one missing. Like
+// a beacon node, it fails if there is none.
+func (f *FakeFetcher) ScUpdates(_ context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ScUpdates"); err != nil {
+		return nil, err
+	}
+	var updates []*types.LightClientUpdate
+	for period := startPeriod; period < startPeriod+uint64(count); period++ {
+		update, ok := f.updates[period]
+		if !ok {
+			break
+		}
+		updates = append(updates, update)
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("no update from period %d: %w", startPeriod, os.ErrNotExist)
+	}
+	return updates, nil
+}
+
+// Block serves the beacon block of slot
+func (f *FakeFetcher) Block(_ context.Context, slot uint64) (*BlockAPIResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Block"); err != nil {
+		return nil, err
+	}
+	block, ok := f.blocks[slot]
+	if !ok {
+		return nil, fmt.Errorf("no block at slot %d: %w", slot, os.ErrNotExist)
+	}
+	return block, nil
+}
+
+// Bootstrap serves the light client bootstrap of blockRoot
+func (f *FakeFetcher) Bootstrap(_ context.Context, blockRoot common.Root) (*types.LightClientBootstrap, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Bootstrap"); err != nil {
+		return nil, err
+	}
+	bootstrap, ok := f.bootstraps[blockRoot]
+	if !ok {
+		return nil, fmt.Errorf("no bootstrap for block %s: %w", blockRoot, os.ErrNotExist)
+	}
+	return bootstrap, nil
+}
+
+// HeadSlot serves the slot of the beacon head
+func (f *FakeFetcher) HeadSlot(context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("HeadSlot"); err != nil {
+		return 0, err
+	}
+	if f.headSlot == nil {
+		return 0, fmt.Errorf("no head slot: %w", os.ErrNotExist)
+	}
+	return *f.headSlot, nil
+}
+
+// AttestedBlockNumber serves the execution block number of the latest attested header
+func (f *FakeFetcher) AttestedBlockNumber(context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("AttestedBlockNumber"); err != nil {
+		return 0, err
+	}
+	if f.attestedBlock == nil {
+		return 0, fmt.Errorf("no attested block number: %w", os.ErrNotExist)
+	}
+	return *f.attestedBlock, nil
+}
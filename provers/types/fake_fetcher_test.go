@@ -0,0 +1,69 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/kysee/zk-chains/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeFetcher(t *testing.T) {
+	var fetcher Fetcher = NewFakeFetcher()
+	_, ok := fetcher.(BatchFetcher)
+	require.True(t, ok)
+	_, ok = fetcher.(EventFetcher)
+	require.False(t, ok)
+
+	f := fetcher.(*FakeFetcher)
+	ctx := context.Background()
+	update := &types.LightClientUpdate{}
+	f.SetUpdate(1104, update)
+	f.SetUpdate(1105, update)
+	f.SetHeadSlot(9_060_000)
+
+	got, err := f.ScUpdate(ctx, 1104)
+	require.NoError(t, err)
+	require.Same(t, update, got)
+	updates, err := f.ScUpdates(ctx, 1104, 3)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	slot, err := f.HeadSlot(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9_060_000), slot)
+
+	// the responses that were not programmed are not available
+	_, err = f.ScUpdate(ctx, 1106)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = f.ScUpdates(ctx, 1106, 2)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = f.Block(ctx, 9_060_000)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = f.AttestedBlockNumber(ctx)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// an error injected for 2 calls, then for every call until cleared
+	unreachable := errors.New("unreachable")
+	f.Fail("ScUpdate", unreachable, 2)
+	for range 2 {
+		_, err = f.ScUpdate(ctx, 1104)
+		require.ErrorIs(t, err, unreachable)
+	}
+	_, err = f.ScUpdate(ctx, 1104)
+	require.NoError(t, err)
+	f.Fail("HeadSlot", unreachable, 0)
+	for range 3 {
+		_, err = f.HeadSlot(ctx)
+		require.ErrorIs(t, err, unreachable)
+	}
+	f.Fail("HeadSlot", nil, 0)
+	_, err = f.HeadSlot(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, 5, f.Calls("ScUpdate"))
+	require.Equal(t, 5, f.Calls("HeadSlot"))
+	require.Equal(t, 2, f.Calls("ScUpdates"))
+	require.Zero(t, f.Calls("Bootstrap"))
+}
@@ -1,7 +1,10 @@
 package types
 
 import (
+	"context"
+
 	"github.com/kysee/zk-chains/types"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/electra"
 )
 
@@ -19,6 +22,61 @@ type BlockAPIResponse struct {
 // Fetcher defines the interface for fetching light client update data
 type Fetcher interface {
 	// FetchUpdate retrieves a light client update
-	ScUpdate(period uint64) (*types.LightClientUpdate, error)
-	Block(slot uint64) (*BlockAPIResponse, error)
+	ScUpdate(ctx context.Context, period uint64) (*types.LightClientUpdate, error)
+	Block(ctx context.Context, slot uint64) (*BlockAPIResponse, error)
+}
+
+// HeadAPIResponse represents the Beacon API response for the head block header
+type HeadAPIResponse struct {
+	Data struct {
+		Header struct {
+			Message common.BeaconBlockHeader `json:"message"`
+		} `json:"header"`
+	} `json:"data"`
+}
+
+// BatchFetcher is implemented by the fetchers able to serve several periods at once, which the relayer
+// uses to catch up when it is several periods behind the beacon head
+type BatchFetcher interface {
+	// ScUpdates retrieves the updates of count periods from startPeriod on, the available ones in order
+	ScUpdates(ctx context.Context, startPeriod uint64, count int) ([]*types.LightClientUpdate, error)
+	// HeadSlot retrieves the slot of the beacon head
+	HeadSlot(ctx context.Context) (uint64, error)
+}
+
+// BootstrapFetcher is implemented by the fetchers able to bootstrap the relayer from a trusted block
+type BootstrapFetcher interface {
+	// Bootstrap retrieves the light client bootstrap of the block with the given root
+	Bootstrap(ctx context.Context, blockRoot common.Root) (*types.LightClientBootstrap, error)
+}
+
+// BeaconEvent is an event of the beacon node: the head, or a light client update, reached Slot
+type BeaconEvent struct {
+	Topic string
+	// Slot is the slot of the head, or the attested slot of the light client update
+	Slot uint64
+}
+
+// EventFetcher is implemented by the fetchers able to stream the events of the beacon node, which
+// the relayer follows to fetch an update as soon as it is available instead of polling for it
+type EventFetcher interface {
+	// Events streams the events of topics to handle until ctx is cancelled or the stream fails
+	Events(ctx context.Context, topics []string, handle func(BeaconEvent)) error
+}
+
+// OptimisticUpdateAPIResponse represents the Beacon API response for the latest light client optimistic update
+type OptimisticUpdateAPIResponse struct {
+	Data struct {
+		AttestedHeader struct {
+			Beacon    common.BeaconBlockHeader     `json:"beacon"`
+			Execution types.ExecutionPayloadHeader `json:"execution"`
+		} `json:"attested_header"`
+	} `json:"data"`
+}
+
+// AttestationFetcher is implemented by the fetchers able to report the latest header attested by the sync
+// committee, which the log watcher waits for before proving the transactions of a block
+type AttestationFetcher interface {
+	// AttestedBlockNumber retrieves the number of the execution block of the latest attested header
+	AttestedBlockNumber(ctx context.Context) (uint64, error)
 }
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// Logger receives the progress messages of the relayer, listener and fetchers. Implement it to route
+// them to the logging of an embedding service; NewLogger writes them to a writer, NopLogger drops them.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// NopLogger drops every message
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...any) {}
+func (nopLogger) Infof(string, ...any)  {}
+func (nopLogger) Warnf(string, ...any)  {}
+func (nopLogger) Errorf(string, ...any) {}
+
+// LevelSetter is a Logger whose level can be changed while it is used, as the ones of NewLogger
+type LevelSetter interface {
+	SetLevel(level string) error
+}
+
+// NewLogger returns a Logger writing human-readable lines to w, dropping the messages below level
+// ("debug", "info", "warn", "error" or "disabled"). It is a LevelSetter.
+func NewLogger(w io.Writer, level string) (Logger, error) {
+	z := zerologLogger{l: zerolog.New(zerolog.ConsoleWriter{Out: w, NoColor: true}).With().Timestamp().Logger(), level: new(atomic.Int32)}
+	if err := z.SetLevel(level); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// NewZerologLogger returns a Logger writing to l, whose level filters the messages
+func NewZerologLogger(l zerolog.Logger) Logger {
+	return zerologLogger{l: l}
+}
+
+type zerologLogger struct {
+	l zerolog.Logger
+	// level filters the messages on top of the level of l, nil if only l's does
+	level *atomic.Int32
+}
+
+// SetLevel drops the messages below level from now on, it fails for a logger of NewZerologLogger
+func (z zerologLogger) SetLevel(level string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil || lvl == zerolog.NoLevel {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	if z.level == nil {
+		return errors.New("the level of a zerolog.Logger is set by its owner")
+	}
+	z.level.Store(int32(lvl))
+	return nil
+}
+
+// event returns the event of a message at lvl, nil if it is dropped
+func (z zerologLogger) event(lvl zerolog.Level) *zerolog.Event {
+	if z.level != nil && lvl < zerolog.Level(z.level.Load()) {
+		return nil
+	}
+	return z.l.WithLevel(lvl)
+}
+
+// msgf formats a message, the line breaks around it are the writer's business
+func (z zerologLogger) msgf(e *zerolog.Event, format string, args []any) {
+	if e == nil {
+		return
+	}
+	e.Msg(strings.Trim(fmt.Sprintf(format, args...), "\n"))
+}
+
+func (z zerologLogger) Debugf(format string, args ...any) {
+	z.msgf(z.event(zerolog.DebugLevel), format, args)
+}
+func (z zerologLogger) Infof(format string, args ...any) {
+	z.msgf(z.event(zerolog.InfoLevel), format, args)
+}
+func (z zerologLogger) Warnf(format string, args ...any) {
+	z.msgf(z.event(zerolog.WarnLevel), format, args)
+}
+func (z zerologLogger) Errorf(format string, args ...any) {
+	z.msgf(z.event(zerolog.ErrorLevel), format, args)
+}
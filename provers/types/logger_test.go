@@ -0,0 +1,45 @@
+package types
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "warn")
+	require.NoError(t, err)
+
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %d\n", 2)
+	logger.Warnf("\n### warn %d ###\n", 3)
+	logger.Errorf("error %d", 4)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], "WRN ### warn 3 ###")
+	require.Contains(t, lines[1], "ERR error 4")
+
+	_, err = NewLogger(&buf, "verbose")
+	require.Error(t, err)
+
+	// the level changes while the logger is used
+	buf.Reset()
+	setter, ok := logger.(LevelSetter)
+	require.True(t, ok)
+	require.NoError(t, setter.SetLevel("debug"))
+	logger.Debugf("debug %d", 5)
+	require.Error(t, setter.SetLevel("verbose"))
+	require.NoError(t, setter.SetLevel("disabled"))
+	logger.Errorf("error %d", 6)
+	require.Contains(t, buf.String(), "DBG debug 5")
+	require.NotContains(t, buf.String(), "error 6")
+
+	// configs without a logger drop the messages
+	var config *Config
+	require.Equal(t, NopLogger, config.Log())
+	require.Equal(t, NopLogger, (&Config{}).Log())
+	require.Equal(t, logger, (&Config{Logger: logger}).Log())
+}
@@ -3,23 +3,129 @@ package main
 import (
 	"bytes"
 	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
 	"github.com/consensys/gnark/backend/solidity"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/consensys/gnark/logger"
+	"github.com/consensys/gnark/test/unsafekzg"
 	"github.com/kysee/zk-chains/circuits"
+	"github.com/kysee/zk-chains/circuits/gadgets"
+	"github.com/kysee/zk-chains/types"
 )
 
 const rootDir = "."
 
+// buildTarget is the network, preset and fork the manifest records the circuits were built for
+var buildTarget types.ArtifactManifest
+
+// buildDir is where the artifacts and their manifest are saved, .build/<network> when built for a network
+var buildDir = filepath.Join(rootDir, ".build")
+
 func main() {
-	_, _, vk, err := SetupCircuit()
+	scHashMode := flag.String("sc-hash-mode", "truncated", "sync committee pubkeys hash mode: truncated | full")
+	backendName := flag.String("backend", "groth16", "proof system: groth16 | plonk")
+	pubKeyCheck := flag.String("pubkey-check", "subgroup", "in-circuit validation of the sync committee pubkeys: subgroup | curve | none")
+	sigCheck := flag.String("sig-check", "subgroup", "in-circuit validation of the aggregated signature: subgroup | twist (subgroup checked natively only)")
+	fork := flag.String("fork", "fulu", "BeaconState layout of the next_sync_committee branch: altair | bellatrix | capella | deneb | electra | fulu")
+	alsoScHashMode := flag.String("also-sc-hash-mode", "", "also build Eth2ScUpdateCircuit-<mode> in this mode (e.g. full), for relayer consumers committing in it")
+	transitionTo := flag.String("transition-to", "", "also build Eth2ScTransitionCircuit, from sc-hash-mode to this mode (e.g. full), for a migration window")
+	split := flag.Bool("split", false, "also build Eth2ScSignatureCircuit and Eth2ScRotationCircuit, the two halves of Eth2ScUpdateCircuit")
+	nativeRecursion := flag.Int("native-recursion", 0, "also build the native recursion path for this many updates: Eth2ScUpdateCircuit over BLS12-377, Eth2ScBW6AggregationCircuit over BW6-761 and Eth2ScBN254WrapCircuit")
+	noCommitments := flag.Bool("no-commitments", false, "with -split and groth16, compile Eth2ScRotationCircuit without commitments, for verifiers taking plain 8-word proofs (export only: Eth2LightClient and the relayer submissions take proofs with commitments)")
+	packed := flag.Bool("packed", false, "also build Eth2ScUpdatePackedCircuit, with the public inputs packed into 128 bits words")
+	calldata := flag.Bool("calldata", false, "also build Eth2ScUpdateCalldataCircuit, with the public inputs packed into 128 bits words in the calldata order of the verifier contract")
+	hashed := flag.Bool("hashed", false, "also build Eth2ScUpdateHashedCircuit, whose only public input is the SHA-256 of the public values")
+	chained := flag.Bool("chained", false, "also build Eth2ScUpdateChainedCircuit, which also outputs the sync committee pubkeys hash of the next committee")
+	network := flag.String("network", "", "network whose preset, next_sync_committee gindex (at -fork) and domain the circuits are built for: mainnet | sepolia | holesky | gnosis, overriding -preset")
+	preset := flag.String("preset", "mainnet", "consensus preset, fixing the sync committee size and the slots per period: mainnet | minimal (32 members, for devnets) | gnosis")
+	publicStateRoot := flag.Bool("public-state-root", false, "also expose the state root of the attested header as a public input of Eth2ScUpdateCircuit, for consumers verifying SSZ proofs against it")
+	profilePath := flag.String("profile", "", "only report the constraints of Eth2ScUpdateCircuit per step, and write gnark's pprof profile of the circuit to this file")
+	flag.Parse()
+
+	mode, err := types.ParseScPubKeysHashMode(*scHashMode)
+	if err != nil {
+		println("error", err.Error())
+		return
+	}
+	nextScGIndex, err := types.NextSyncCommitteeGIndexForFork(*fork)
+	if err != nil {
+		println("error", err.Error())
+		return
+	}
+
+	consensusPreset, err := types.ParsePreset(*preset)
+	if err != nil {
+		println("error", err.Error())
+		return
+	}
+
+	scPubKeysCheck, err := circuit.ParsePubKeyCheck(*pubKeyCheck)
+	if err != nil {
+		println("error", err.Error())
+		return
+	}
+	aggregatedSigCheck, err := circuit.ParseSigCheck(*sigCheck)
+	if err != nil {
+		println("error", err.Error())
+		return
+	}
+	proofBackend, err := types.ParseProofBackend(*backendName)
+	if err != nil {
+		println("error", err.Error())
+		return
+	}
+
+	params := circuit.CircuitParams{NextScGIndex: nextScGIndex, Preset: consensusPreset}
+	if *network != "" {
+		n, err := types.NetworkByName(*network)
+		if err != nil {
+			println("error", err.Error())
+			return
+		}
+		if params, err = circuit.NewNetworkCircuitParams(n, *fork); err != nil {
+			println("error", err.Error())
+			return
+		}
+		buildTarget.Network = n.Name
+		// the relayer of the network loads .build/<network>/manifest.json, and another network's build
+		// keeps its own manifest
+		buildDir = filepath.Join(rootDir, ".build", strings.ToLower(n.Name))
+		if err := os.MkdirAll(buildDir, 0o755); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+	buildTarget.Preset, buildTarget.Fork = params.Preset.String(), *fork
+	params.ScPubKeysHashMode = mode
+	params.ScPubKeysCheck = scPubKeysCheck
+	params.SigCheck = aggregatedSigCheck
+	params.PublicStateRoot = *publicStateRoot
+
+	if *profilePath != "" {
+		report, err := circuit.ProfileEth2ScUpdateCircuit(params, ecc.BN254.ScalarField(), newBuilder(proofBackend), *profilePath)
+		if err != nil {
+			println("error", err.Error())
+			return
+		}
+		fmt.Print(report)
+		return
+	}
+
+	ccs, _, vk, err := SetupCircuit(params, proofBackend)
 	if err != nil {
 		println("error", err)
 		return
@@ -27,21 +133,283 @@ func main() {
 
 	if err := CreateSolidity(vk); err != nil {
 		println("error", err)
+		return
+	}
+
+	if err := WriteManifest(ccs, proofBackend); err != nil {
+		println("error", err)
+		return
+	}
+
+	if *alsoScHashMode != "" {
+		also, err := types.ParseScPubKeysHashMode(*alsoScHashMode)
+		if err != nil {
+			println("error", err.Error())
+			return
+		}
+		alsoParams := params
+		alsoParams.ScPubKeysHashMode = also
+		if err := SetupModeCircuit(alsoParams, proofBackend); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *transitionTo != "" {
+		to, err := types.ParseScPubKeysHashMode(*transitionTo)
+		if err != nil {
+			println("error", err.Error())
+			return
+		}
+		if err := SetupTransitionCircuit(circuit.TransitionParams{From: mode, To: to}, proofBackend); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *nativeRecursion > 0 {
+		if err := SetupNativeRecursionCircuits(circuit.AggregationParams{NbProofs: *nativeRecursion, Inner: params}); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *packed {
+		if err := SetupPackedCircuit(params, proofBackend); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *calldata {
+		if err := SetupCalldataCircuit(params, proofBackend); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *hashed {
+		if err := SetupHashedCircuit(params, proofBackend); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *chained {
+		if err := SetupChainedCircuit(params, proofBackend); err != nil {
+			println("error", err.Error())
+			return
+		}
+	}
+
+	if *split {
+		if err := SetupSplitCircuits(params, proofBackend, *noCommitments); err != nil {
+			println("error", err.Error())
+		}
 	}
 }
 
-func SetupCircuit() (constraint.ConstraintSystem, groth16.ProvingKey, groth16.VerifyingKey, error) {
+// SetupCircuit compiles the circuit and generates its keys for the given backend.
+// The keys are groth16.ProvingKey/VerifyingKey or plonk.ProvingKey/VerifyingKey accordingly.
+func SetupCircuit(params circuit.CircuitParams, proofBackend types.ProofBackend) (constraint.ConstraintSystem, io.WriterTo, VerifyingKey, error) {
+	println("🕧 Compile Eth2ScUpdateCircuit circuit... (backend:", string(proofBackend)+", sc-hash-mode:", params.ScPubKeysHashMode.String()+", next_sync_committee gindex:", params.NextSyncCommitteeGIndex().String()+", pubkey-check:", params.ScPubKeysCheck.String()+", sig-check:", params.SigCheck.String()+", preset:", params.Preset.String()+", public-state-root:", strconv.FormatBool(params.PublicStateRoot)+")")
+	return setupNamedCircuit("Eth2ScUpdateCircuit", circuit.NewEth2ScUpdateCircuit(params), proofBackend)
+}
+
+// SetupModeCircuit builds Eth2ScUpdateCircuit for another sync committee pubkeys hash mode with its
+// Solidity verifier, and records it in the manifest as types.ModeCircuitName(mode)
+func SetupModeCircuit(params circuit.CircuitParams, proofBackend types.ProofBackend) error {
+	name := types.ModeCircuitName(params.ScPubKeysHashMode)
+	println("🕧 Compile", name, "circuit... (backend:", string(proofBackend)+", sc-hash-mode:", params.ScPubKeysHashMode.String()+")")
+	ccs, _, vk, err := setupNamedCircuit(name, circuit.NewEth2ScUpdateCircuit(params), proofBackend)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScUpdateVerifier-" + params.ScPubKeysHashMode.String() + ".sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(name, contract, ccs, proofBackend)
+}
+
+// SetupTransitionCircuit builds Eth2ScTransitionCircuit with its Solidity verifier and records it in
+// the manifest, for the relayer to emit both commitments during a migration window
+func SetupTransitionCircuit(params circuit.TransitionParams, proofBackend types.ProofBackend) error {
+	const name = "Eth2ScTransitionCircuit"
+	println("🕧 Compile", name, "circuit... (backend:", string(proofBackend)+", from:", params.From.String()+", to:", params.To.String()+")")
+	ccs, _, vk, err := setupNamedCircuit(name, circuit.NewEth2ScTransitionCircuit(params), proofBackend)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScTransitionVerifier.sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(name, contract, ccs, proofBackend)
+}
+
+// SetupPackedCircuit builds Eth2ScUpdatePackedCircuit with its Solidity verifier and records it in the
+// manifest, for verifiers taking the types.PackedPublicInputs layout
+func SetupPackedCircuit(params circuit.CircuitParams, proofBackend types.ProofBackend) error {
+	const name = "Eth2ScUpdatePackedCircuit"
+	println("🕧 Compile", name, "circuit... (backend:", string(proofBackend)+")")
+	ccs, _, vk, err := setupNamedCircuit(name, circuit.NewEth2ScUpdatePackedCircuit(params), proofBackend)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScUpdatePackedVerifier.sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(name, contract, ccs, proofBackend)
+}
+
+// SetupCalldataCircuit builds Eth2ScUpdateCalldataCircuit with its Solidity verifier and records it in the
+// manifest, for verifiers taking the types.CalldataPublicInputs layout
+func SetupCalldataCircuit(params circuit.CircuitParams, proofBackend types.ProofBackend) error {
+	const name = "Eth2ScUpdateCalldataCircuit"
+	println("🕧 Compile", name, "circuit... (backend:", string(proofBackend)+")")
+	ccs, _, vk, err := setupNamedCircuit(name, circuit.NewEth2ScUpdateCalldataCircuit(params), proofBackend)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScUpdateCalldataVerifier.sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(name, contract, ccs, proofBackend)
+}
+
+// SetupHashedCircuit builds Eth2ScUpdateHashedCircuit with its Solidity verifier and records it in the
+// manifest, for verifiers hashing the public values themselves (types.PackedPublicInputs.InputsHash)
+func SetupHashedCircuit(params circuit.CircuitParams, proofBackend types.ProofBackend) error {
+	const name = "Eth2ScUpdateHashedCircuit"
+	println("🕧 Compile", name, "circuit... (backend:", string(proofBackend)+")")
+	ccs, _, vk, err := setupNamedCircuit(name, circuit.NewEth2ScUpdateHashedCircuit(params), proofBackend)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScUpdateHashedVerifier.sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(name, contract, ccs, proofBackend)
+}
+
+// SetupChainedCircuit builds Eth2ScUpdateChainedCircuit with its Solidity verifier and records it in the manifest
+func SetupChainedCircuit(params circuit.CircuitParams, proofBackend types.ProofBackend) error {
+	const name = "Eth2ScUpdateChainedCircuit"
+	println("🕧 Compile", name, "circuit... (backend:", string(proofBackend)+", sc-hash-mode:", params.ScPubKeysHashMode.String()+")")
+	ccs, _, vk, err := setupNamedCircuit(name, circuit.NewEth2ScUpdateChainedCircuit(params), proofBackend)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScUpdateChainedVerifier.sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(name, contract, ccs, proofBackend)
+}
+
+// SetupSplitCircuits builds Eth2ScSignatureCircuit and Eth2ScRotationCircuit with their Solidity
+// verifiers and records them in the manifest, for the relayer to prove finality without rotating.
+// With plainRotation, Eth2ScRotationCircuit is compiled without Groth16 commitments; the emulated
+// BLS12-381 arithmetic of Eth2ScSignatureCircuit cannot be.
+func SetupSplitCircuits(params circuit.CircuitParams, proofBackend types.ProofBackend, plainRotation bool) error {
+	circuits := []struct {
+		name  string
+		c     frontend.Circuit
+		plain bool
+	}{
+		{"Eth2ScSignatureCircuit", circuit.NewEth2ScSignatureCircuit(params), false},
+		{"Eth2ScRotationCircuit", circuit.NewEth2ScRotationCircuit(params), plainRotation},
+	}
+	for _, sc := range circuits {
+		println("🕧 Compile", sc.name, "circuit... (backend:", string(proofBackend)+")")
+		builder := newBuilder(proofBackend)
+		if sc.plain {
+			if proofBackend != types.BackendGroth16 {
+				return fmt.Errorf("%s: compiling without commitments is only supported with groth16", sc.name)
+			}
+			builder = gadgets.NewPlainBuilder(builder)
+		}
+		ccs, _, vk, err := setupNamedCircuitWith(sc.name, sc.c, ecc.BN254.ScalarField(), builder, proofBackend)
+		if err != nil {
+			return err
+		}
+		contract := "verifiers/eth2/contracts/" + strings.TrimSuffix(sc.name, "Circuit") + "Verifier.sol"
+		if err := createSolidityAt(vk, contract); err != nil {
+			return err
+		}
+		if err := writeManifestEntry(sc.name, contract, ccs, proofBackend); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetupNativeRecursionCircuits builds the native recursion path of params.NbProofs updates with
+// Groth16: Eth2ScUpdateCircuit over BLS12-377, Eth2ScBW6AggregationCircuit over BW6-761, and
+// Eth2ScBN254WrapCircuit over BN254 with its Solidity verifier, the only one recorded in the manifest.
+func SetupNativeRecursionCircuits(params circuit.AggregationParams) error {
+	const innerName, aggName, wrapName = "Eth2ScUpdateCircuit-bls12377", "Eth2ScBW6AggregationCircuit", "Eth2ScBN254WrapCircuit"
+	println("🕧 Compile", innerName, "circuit... (curve:", ecc.BLS12_377.String()+")")
+	innerCcs, _, innerVK, err := setupNamedCircuitWith(innerName, circuit.NewEth2ScUpdateCircuit(params.Inner), ecc.BLS12_377.ScalarField(), r1cs.NewBuilder, types.BackendGroth16)
+	if err != nil {
+		return err
+	}
+
+	println("🕧 Compile", aggName, "circuit... (curve:", ecc.BW6_761.String()+", updates:", params.NbProofs, ")")
+	agg, err := circuit.NewEth2ScBW6AggregationCircuit(params, innerCcs, innerVK.(groth16.VerifyingKey))
+	if err != nil {
+		return err
+	}
+	aggCcs, _, aggVK, err := setupNamedCircuitWith(aggName, agg, ecc.BW6_761.ScalarField(), r1cs.NewBuilder, types.BackendGroth16)
+	if err != nil {
+		return err
+	}
+
+	println("🕧 Compile", wrapName, "circuit... (curve:", ecc.BN254.String()+")")
+	wrap, err := circuit.NewEth2ScBN254WrapCircuit(aggCcs, aggVK.(groth16.VerifyingKey))
+	if err != nil {
+		return err
+	}
+	ccs, _, vk, err := setupNamedCircuitWith(wrapName, wrap, ecc.BN254.ScalarField(), r1cs.NewBuilder, types.BackendGroth16)
+	if err != nil {
+		return err
+	}
+	contract := "verifiers/eth2/contracts/Eth2ScBN254WrapVerifier.sol"
+	if err := createSolidityAt(vk, contract); err != nil {
+		return err
+	}
+	return writeManifestEntry(wrapName, contract, ccs, types.BackendGroth16)
+}
+
+// setupNamedCircuit compiles c, generates its keys for the given backend and saves them as <buildDir>/<name>.*
+func setupNamedCircuit(name string, c frontend.Circuit, proofBackend types.ProofBackend) (constraint.ConstraintSystem, io.WriterTo, VerifyingKey, error) {
+	return setupNamedCircuitWith(name, c, ecc.BN254.ScalarField(), newBuilder(proofBackend), proofBackend)
+}
+
+// newBuilder returns the constraint system builder of the backend
+func newBuilder(proofBackend types.ProofBackend) frontend.NewBuilder {
+	if proofBackend == types.BackendPlonk {
+		return scs.NewBuilder
+	}
+	return r1cs.NewBuilder
+}
+
+// setupNamedCircuitWith is setupNamedCircuit compiling over the given scalar field with the given builder
+func setupNamedCircuitWith(name string, c frontend.Circuit, field *big.Int, builder frontend.NewBuilder, proofBackend types.ProofBackend) (constraint.ConstraintSystem, io.WriterTo, VerifyingKey, error) {
 	logger.Disable()
 
-	ccsPath := filepath.Join(rootDir, ".build/Eth2ScUpdateCircuit.ccs")
-	pkPath := filepath.Join(rootDir, ".build/Eth2ScUpdateCircuit.pk")
-	vkPath := filepath.Join(rootDir, ".build/Eth2ScUpdateCircuit.vk")
+	ccsPath := filepath.Join(buildDir, name+".ccs")
+	pkPath := filepath.Join(buildDir, name+".pk")
+	vkPath := filepath.Join(buildDir, name+".vk")
 
 	//
 	// Step 1: Compile circuit and save to file
-	println("🕧 Compile Eth2ScUpdateCircuit circuit...")
-	// Compile with BN254 scalar field (for emulated BLS12-381)
-	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit.Eth2ScUpdateCircuit{})
+	// Compile with BN254 scalar field (for emulated BLS12-381) unless a recursion curve is asked for
+	ccs, err := frontend.Compile(field, builder, c)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -59,7 +427,7 @@ func SetupCircuit() (constraint.ConstraintSystem, groth16.ProvingKey, groth16.Ve
 	//
 	// Step 2: Setup (generate proving and verifying keys)
 	println("🕧 Generating proving and verifying keys...")
-	pk, vk, err := groth16.Setup(ccs)
+	pk, vk, err := setupKeys(ccs, proofBackend)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -84,9 +452,85 @@ func SetupCircuit() (constraint.ConstraintSystem, groth16.ProvingKey, groth16.Ve
 	return ccs, pk, vk, nil
 }
 
-func CreateSolidity(vk groth16.VerifyingKey) error {
-	path := "verifiers/eth2/contracts/Eth2ScUpdateVerifier.sol"
+// VerifyingKey is a verifying key of either backend, which can be saved and exported to Solidity
+type VerifyingKey interface {
+	io.WriterTo
+	solidity.VerifyingKey
+}
+
+func setupKeys(ccs constraint.ConstraintSystem, proofBackend types.ProofBackend) (io.WriterTo, VerifyingKey, error) {
+	if proofBackend == types.BackendPlonk {
+		// NB! UNSAFE SRS, a production deployment must use the output of a ceremony
+		srs, srsLagrange, err := unsafekzg.NewSRS(ccs)
+		if err != nil {
+			return nil, nil, err
+		}
+		return plonk.Setup(ccs, srs, srsLagrange)
+	}
+	// NB! single-party setup, a production deployment replaces these keys by the ones of cmd/ceremony
+	return groth16.Setup(ccs)
+}
+
+// WriteManifest records the artifacts of Eth2ScUpdateCircuit in <buildDir>/manifest.json, so the relayer
+// loads and proves with the matching backend
+func WriteManifest(ccs constraint.ConstraintSystem, proofBackend types.ProofBackend) error {
+	return writeManifestEntry("Eth2ScUpdateCircuit", "verifiers/eth2/contracts/Eth2ScUpdateVerifier.sol", ccs, proofBackend)
+}
+
+// writeManifestEntry records the artifacts of the named circuit, as saved by setupNamedCircuit
+func writeManifestEntry(name, contract string, ccs constraint.ConstraintSystem, proofBackend types.ProofBackend) error {
+	path := filepath.Join(buildDir, types.ManifestFileName)
+	// only a missing manifest starts empty, an unreadable one would lose the entries of the other circuits
+	manifest, err := types.LoadArtifactManifest(path)
+	if os.IsNotExist(err) {
+		manifest = &types.ArtifactManifest{}
+	} else if err != nil {
+		return err
+	}
+	curve, err := curveOf(ccs)
+	if err != nil {
+		return err
+	}
+	entry := types.CircuitManifest{
+		Name:         name,
+		Backend:      proofBackend,
+		Curve:        curve.String(),
+		Verifier:     types.VerifierSolidity,
+		CCS:          name + ".ccs",
+		PK:           name + ".pk",
+		VK:           name + ".vk",
+		Contract:     contract,
+		Constraints:  ccs.GetNbConstraints(),
+		PublicInputs: ccs.GetNbPublicVariables() - 1,
+	}
+	// pin the keys to the constraint system, the relayer refuses to prove with another pair
+	if err := entry.SetChecksums(filepath.Dir(path)); err != nil {
+		return err
+	}
+	manifest.Network, manifest.Preset, manifest.Fork = buildTarget.Network, buildTarget.Preset, buildTarget.Fork
+	manifest.Set(entry)
+	if err := manifest.Save(path); err != nil {
+		return err
+	}
+	println("✅ Manifest saved to", path)
+	return nil
+}
+
+// curveOf returns the pairing curve whose scalar field ccs was compiled over
+func curveOf(ccs constraint.ConstraintSystem) (ecc.ID, error) {
+	for _, id := range []ecc.ID{ecc.BN254, ecc.BLS12_377, ecc.BLS12_381, ecc.BLS24_315, ecc.BLS24_317, ecc.BW6_761, ecc.BW6_633} {
+		if id.ScalarField().Cmp(ccs.Field()) == 0 {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("no curve has the scalar field %s", ccs.Field())
+}
+
+func CreateSolidity(vk VerifyingKey) error {
+	return createSolidityAt(vk, "verifiers/eth2/contracts/Eth2ScUpdateVerifier.sol")
+}
 
+func createSolidityAt(vk VerifyingKey, path string) error {
 	// Solidity verifier 생성
 	var buf bytes.Buffer
 	err := vk.ExportSolidity(&buf, solidity.WithHashToFieldFunction(sha256.New()))
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// AccountProofResult is the result of eth_getProof (EIP-1186)
+type AccountProofResult struct {
+	Address      HexBytes             `json:"address"`
+	AccountProof []HexBytes           `json:"accountProof"`
+	Balance      string               `json:"balance"`
+	CodeHash     HexBytes             `json:"codeHash"`
+	Nonce        string               `json:"nonce"`
+	StorageHash  HexBytes             `json:"storageHash"`
+	StorageProof []StorageProofResult `json:"storageProof"`
+}
+
+// StorageProofResult is the proof of a storage slot in the result of eth_getProof.
+// Key and Value are quantities, i.e. hex strings without leading zeros.
+type StorageProofResult struct {
+	Key   string     `json:"key"`
+	Value string     `json:"value"`
+	Proof []HexBytes `json:"proof"`
+}
+
+// AddressBytes returns the 20-byte address of the account
+func (r *AccountProofResult) AddressBytes() ([20]byte, error) {
+	var addr [20]byte
+	if len(r.Address) != len(addr) {
+		return addr, fmt.Errorf("invalid address length %d", len(r.Address))
+	}
+	copy(addr[:], r.Address)
+	return addr, nil
+}
+
+// SlotBytes returns the storage slot as a 32-byte big-endian word
+func (p *StorageProofResult) SlotBytes() ([32]byte, error) {
+	return quantityToWord(p.Key)
+}
+
+// ValueBytes returns the value of the storage slot as a 32-byte big-endian word
+func (p *StorageProofResult) ValueBytes() ([32]byte, error) {
+	return quantityToWord(p.Value)
+}
+
+func quantityToWord(quantity string) ([32]byte, error) {
+	var word [32]byte
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(quantity, "0x"), 16)
+	if !ok || v.Sign() < 0 || v.BitLen() > 256 {
+		return word, fmt.Errorf("invalid 256-bit quantity %q", quantity)
+	}
+	v.FillBytes(word[:])
+	return word, nil
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"fmt"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// SlotsPerHistoricalRoot is the length of the BeaconState block_roots ring buffer (SLOTS_PER_HISTORICAL_ROOT)
+const SlotsPerHistoricalRoot = 8192
+
+// BeaconState.block_roots field (field 5)
+const (
+	BlockRootsGIndexAltair  GIndex = 32 + 5 // Altair .. Deneb, 32 fields or less
+	BlockRootsGIndexElectra GIndex = 64 + 5 // Electra, Fulu, more than 32 fields
+)
+
+// BlockRootsGIndexForFork returns the generalized index of block_roots in the BeaconState of the given fork
+func BlockRootsGIndexForFork(fork string) (GIndex, error) {
+	gindex, err := NextSyncCommitteeGIndexForFork(fork)
+	if err != nil {
+		return 0, err
+	}
+	if gindex == NextSyncCommitteeGIndexAltair {
+		return BlockRootsGIndexAltair, nil
+	}
+	return BlockRootsGIndexElectra, nil
+}
+
+// BlockRootGIndex returns the generalized index, in the BeaconState, of the block_roots entry holding
+// the root of the block at slot: block_roots[slot % SLOTS_PER_HISTORICAL_ROOT].
+func BlockRootGIndex(blockRootsGIndex GIndex, slot uint64) (GIndex, error) {
+	return blockRootsGIndex.Child(13, slot%SlotsPerHistoricalRoot)
+}
+
+// CheckBlockRootsWindow checks that the root of the block at slot is still held by the block_roots
+// ring buffer of a state at stateSlot, i.e. stateSlot - SLOTS_PER_HISTORICAL_ROOT <= slot < stateSlot.
+func CheckBlockRootsWindow(stateSlot, slot uint64) error {
+	if slot >= stateSlot {
+		return fmt.Errorf("slot %d is not before state slot %d", slot, stateSlot)
+	}
+	if stateSlot-slot > SlotsPerHistoricalRoot {
+		return fmt.Errorf("slot %d is more than %d slots before state slot %d", slot, SlotsPerHistoricalRoot, stateSlot)
+	}
+	return nil
+}
+
+// BlockRootProof generates the proof of block_roots[slot % SLOTS_PER_HISTORICAL_ROOT] in an
+// SSZ-encoded (Electra) BeaconState at stateSlot.
+func BlockRootProof(spec *zrntcommon.Spec, stateSSZ []byte, stateSlot, slot uint64) (*SSZProof, error) {
+	if err := CheckBlockRootsWindow(stateSlot, slot); err != nil {
+		return nil, err
+	}
+	gindex, err := BlockRootGIndex(BlockRootsGIndexElectra, slot)
+	if err != nil {
+		return nil, err
+	}
+	return BeaconStateProof(spec, stateSSZ, gindex)
+}
+
+// VerifyBlockRoot verifies that blockRoot is the root of the block at slot, as recorded in the
+// block_roots of the state with the given root at stateSlot.
+func VerifyBlockRoot(stateRoot, blockRoot zrntcommon.Root, branch []zrntcommon.Root, blockRootsGIndex GIndex, stateSlot, slot uint64) error {
+	if err := CheckBlockRootsWindow(stateSlot, slot); err != nil {
+		return err
+	}
+	gindex, err := BlockRootGIndex(blockRootsGIndex, slot)
+	if err != nil {
+		return err
+	}
+	if !VerifySSZBranch(stateRoot, blockRoot, branch, gindex) {
+		return fmt.Errorf("block root of slot %d is not in block_roots of state %v", slot, stateRoot)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"testing"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockRootProof(t *testing.T) {
+	spec := configs.Mainnet
+	stateSlot := uint64(9052234)
+	slot := stateSlot - 100
+	blockRoot := zrntcommon.Root{0xaa, 0xbb}
+
+	state, err := electra.AsBeaconStateView(electra.BeaconStateType(spec).New(), nil)
+	require.NoError(t, err)
+	blockRoots, err := state.BlockRoots()
+	require.NoError(t, err)
+	require.NoError(t, blockRoots.(*phase0.BatchRootsView).SetRoot(zrntcommon.Slot(slot), blockRoot))
+	stateRoot := state.HashTreeRoot(tree.GetHashFn())
+
+	gindex, err := BlockRootGIndex(BlockRootsGIndexElectra, slot)
+	require.NoError(t, err)
+	require.Equal(t, 6+13, gindex.Depth())
+	proof, err := GenerateSSZProofFromView(state, gindex)
+	require.NoError(t, err)
+	require.Equal(t, blockRoot, proof.Leaf)
+
+	require.NoError(t, VerifyBlockRoot(stateRoot, blockRoot, proof.Branch, BlockRootsGIndexElectra, stateSlot, slot))
+	// the same ring buffer entry one period later has been overwritten
+	require.Error(t, VerifyBlockRoot(stateRoot, blockRoot, proof.Branch, BlockRootsGIndexElectra, stateSlot, slot-SlotsPerHistoricalRoot))
+	// wrong layout or wrong root
+	require.Error(t, VerifyBlockRoot(stateRoot, blockRoot, proof.Branch, BlockRootsGIndexAltair, stateSlot, slot))
+	require.Error(t, VerifyBlockRoot(stateRoot, zrntcommon.Root{}, proof.Branch, BlockRootsGIndexElectra, stateSlot, slot))
+
+	require.NoError(t, CheckBlockRootsWindow(stateSlot, stateSlot-SlotsPerHistoricalRoot))
+	require.Error(t, CheckBlockRootsWindow(stateSlot, stateSlot))
+	require.Error(t, CheckBlockRootsWindow(stateSlot, stateSlot-SlotsPerHistoricalRoot-1))
+
+	g, err := BlockRootsGIndexForFork("deneb")
+	require.NoError(t, err)
+	require.Equal(t, BlockRootsGIndexAltair, g)
+}
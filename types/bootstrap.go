@@ -0,0 +1,44 @@
+package types
+
+import (
+	"fmt"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// LightClientBootstrap is the response of /eth/v1/beacon/light_client/bootstrap/{block_root}: the
+// current sync committee of the state of a trusted block, with its branch to the state root
+type LightClientBootstrap struct {
+	Data struct {
+		Header struct {
+			Beacon zrntcommon.BeaconBlockHeader `json:"beacon"`
+		} `json:"header"`
+		CurrentSyncCommittee       zrntcommon.SyncCommittee `json:"current_sync_committee"`
+		CurrentSyncCommitteeBranch []zrntcommon.Root        `json:"current_sync_committee_branch"` // 5 roots before Electra, 6 since
+	} `json:"data"`
+	Version string `json:"version"`
+}
+
+// Verify checks the bootstrap against trustedRoot, the root of a block the caller trusts (e.g. a
+// finalized checkpoint): the header must hash to trustedRoot and current_sync_committee must be in
+// the header's state. fork is used when the bootstrap has no version.
+func (b *LightClientBootstrap) Verify(trustedRoot zrntcommon.Root, fork string) error {
+	hFn := tree.GetHashFn()
+	header := &b.Data.Header.Beacon
+	if root := header.HashTreeRoot(hFn); root != trustedRoot {
+		return fmt.Errorf("bootstrap header hashes to %v, expected %v", root, trustedRoot)
+	}
+	if b.Version != "" {
+		fork = b.Version
+	}
+	gindex, err := CurrentSyncCommitteeGIndexForFork(fork)
+	if err != nil {
+		return err
+	}
+	scRoot := SyncCommitteeRoot(&b.Data.CurrentSyncCommittee)
+	if !VerifySSZBranch(header.StateRoot, scRoot, b.Data.CurrentSyncCommitteeBranch, gindex) {
+		return fmt.Errorf("current_sync_committee branch does not match state root %v", header.StateRoot)
+	}
+	return nil
+}
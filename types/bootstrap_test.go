@@ -0,0 +1,54 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLightClientBootstrapVerify(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join(rootDir, "data/bootstrap-1105.json"))
+	require.NoError(t, err)
+	var bootstrap LightClientBootstrap
+	require.NoError(t, json.Unmarshal(data, &bootstrap))
+	trustedRoot := bootstrap.Data.Header.Beacon.HashTreeRoot(tree.GetHashFn())
+	require.NoError(t, bootstrap.Verify(trustedRoot, ""))
+
+	// the header of another block
+	require.ErrorContains(t, bootstrap.Verify(bootstrap.Data.Header.Beacon.ParentRoot, ""), "hashes to")
+
+	// the branch is checked at the gindex of the bootstrap's fork
+	bootstrap.Version = "deneb"
+	require.ErrorContains(t, bootstrap.Verify(trustedRoot, ""), "branch")
+	bootstrap.Version = ""
+	require.ErrorContains(t, bootstrap.Verify(trustedRoot, ""), "unknown fork")
+	require.NoError(t, bootstrap.Verify(trustedRoot, "fulu"))
+
+	// a committee that is not the one of the state
+	bootstrap.Data.CurrentSyncCommittee.Pubkeys[0][1] ^= 1
+	require.ErrorContains(t, bootstrap.Verify(trustedRoot, "fulu"), "branch")
+}
+
+func TestLightClientBootstrapVerifyMinimalPreset(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join(rootDir, "data/bootstrap-1105.json"))
+	require.NoError(t, err)
+	var bootstrap LightClientBootstrap
+	require.NoError(t, json.Unmarshal(data, &bootstrap))
+
+	// a minimal preset committee of 32 members, in a state whose root its branch leads to
+	sc := &bootstrap.Data.CurrentSyncCommittee
+	sc.Pubkeys = sc.Pubkeys[:PresetMinimal.Spec().SYNC_COMMITTEE_SIZE]
+	gindex, err := CurrentSyncCommitteeGIndexForFork("fulu")
+	require.NoError(t, err)
+	bootstrap.Data.Header.Beacon.StateRoot, err = ComputeSSZBranchRoot(SyncCommitteeRoot(sc), bootstrap.Data.CurrentSyncCommitteeBranch, gindex)
+	require.NoError(t, err)
+	trustedRoot := bootstrap.Data.Header.Beacon.HashTreeRoot(tree.GetHashFn())
+	require.NoError(t, bootstrap.Verify(trustedRoot, "fulu"))
+
+	sc.Pubkeys[0][1] ^= 1
+	require.ErrorContains(t, bootstrap.Verify(trustedRoot, "fulu"), "branch")
+}
@@ -0,0 +1,56 @@
+package types
+
+import "math/big"
+
+// CalldataNbPublicInputs is the number of public inputs of Eth2ScUpdateCalldataCircuit
+const CalldataNbPublicInputs = 11
+
+// CalldataPublicInputs are the public values of Eth2ScUpdateCalldataCircuit, laid out for the cost of
+// building the input array in the verifier contract rather than for the circuit.
+//
+// The values the contract holds in storage come first, so their slots are copied in one run: the
+// ScPubKeysHash of the current committee, the Domain and the Period (lastPeriod). They are followed by the
+// values read from the calldata of the update, in the order of its arguments. Every input is one half of
+// one calldata word: a 32 bytes value is split in two big-endian 128 bits words (hi, lo), i.e.
+// calldataload(p) >> 128 and uint128(calldataload(p)), and a uint64 is a whole word.
+type CalldataPublicInputs struct {
+	// stored by the contract
+	ScPubKeysHash [32]byte
+	Domain        [32]byte
+	Period        uint64
+	// read from the calldata
+	AttestedSlot    uint64
+	NextScRoot      [32]byte
+	ExecBlockHash   [32]byte
+	ExecBlockNumber uint64
+}
+
+// NewCalldataPublicInputs takes the public values of p, whose sync committee bits are not public in this layout
+func NewCalldataPublicInputs(p *PackedPublicInputs) *CalldataPublicInputs {
+	return &CalldataPublicInputs{
+		ScPubKeysHash:   p.ScPubKeysHash,
+		Domain:          p.Domain,
+		Period:          p.Period,
+		AttestedSlot:    p.AttestedSlot,
+		NextScRoot:      p.NextScRoot,
+		ExecBlockHash:   p.ExecBlockHash,
+		ExecBlockNumber: p.ExecBlockNumber,
+	}
+}
+
+// Encode returns the CalldataNbPublicInputs public inputs of Eth2ScUpdateCalldataCircuit, the uint256[]
+// array its Solidity verifier takes
+func (p *CalldataPublicInputs) Encode() []*big.Int {
+	out := make([]*big.Int, 0, CalldataNbPublicInputs)
+	pack := func(b []byte) {
+		words, _ := PackWords(b) // whole words by construction
+		out = append(out, words...)
+	}
+	pack(p.ScPubKeysHash[:])
+	pack(p.Domain[:])
+	out = append(out, new(big.Int).SetUint64(p.Period), new(big.Int).SetUint64(p.AttestedSlot))
+	pack(p.NextScRoot[:])
+	pack(p.ExecBlockHash[:])
+	out = append(out, new(big.Int).SetUint64(p.ExecBlockNumber))
+	return out
+}
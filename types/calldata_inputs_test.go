@@ -0,0 +1,43 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalldataPublicInputs(t *testing.T) {
+	packed := &PackedPublicInputs{Period: 1105, ExecBlockNumber: 22_000_000, AttestedSlot: 1105*8192 + 7}
+	for i := 0; i < 32; i++ {
+		packed.ScPubKeysHash[i] = byte(i)
+		packed.NextScRoot[i] = byte(0x80 + i)
+		packed.Domain[i] = 0xff
+		packed.ExecBlockHash[i] = byte(255 - i)
+	}
+	p := NewCalldataPublicInputs(packed)
+
+	inputs := p.Encode()
+	require.Len(t, inputs, CalldataNbPublicInputs)
+	hex := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(s, 16)
+		require.True(t, ok)
+		return v
+	}
+	// stored values
+	require.Equal(t, hex("000102030405060708090a0b0c0d0e0f"), inputs[0])
+	require.Equal(t, hex("101112131415161718191a1b1c1d1e1f"), inputs[1])
+	require.Equal(t, hex("ffffffffffffffffffffffffffffffff"), inputs[2])
+	require.Equal(t, hex("ffffffffffffffffffffffffffffffff"), inputs[3])
+	require.Equal(t, big.NewInt(1105), inputs[4])
+	// calldata values
+	require.Equal(t, big.NewInt(1105*8192+7), inputs[5])
+	require.Equal(t, hex("808182838485868788898a8b8c8d8e8f"), inputs[6])
+	require.Equal(t, hex("909192939495969798999a9b9c9d9e9f"), inputs[7])
+	require.Equal(t, hex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0"), inputs[8])
+	require.Equal(t, hex("efeeedecebeae9e8e7e6e5e4e3e2e1e0"), inputs[9])
+	require.Equal(t, big.NewInt(22_000_000), inputs[10])
+	for _, v := range inputs {
+		require.LessOrEqual(t, v.BitLen(), 128)
+	}
+}
@@ -0,0 +1,173 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// Generalized indices of ExecutionPayloadHeader fields (Deneb ..: 17 fields, depth 5),
+// rooted at the header, and of the header rooted at the BeaconBlockBody.
+const (
+	ExecutionBlockNumberGIndex GIndex = 38 // 32 + 6
+	ExecutionBlockHashGIndex   GIndex = 44 // 32 + 12
+
+	// ExecutionBranchDepth is the length of the execution_branch of a light client header
+	ExecutionBranchDepth = 4
+)
+
+// Generalized indices of execution_payload.block_number and execution_payload.block_hash in the
+// BeaconBlockBody (Deneb ..), i.e. ExecutionPayloadGIndex concatenated with the header gindex.
+var (
+	ExecutionBlockNumberBodyGIndex = mustConcatGIndices(ExecutionPayloadGIndex, ExecutionBlockNumberGIndex)
+	ExecutionBlockHashBodyGIndex   = mustConcatGIndices(ExecutionPayloadGIndex, ExecutionBlockHashGIndex)
+)
+
+// Generalized indices of execution_payload.receipts_root (field 3), rooted at the ExecutionPayload.
+// Bellatrix and Capella payloads have 14 and 15 fields (depth 4), Deneb .. 17 fields (depth 5).
+const (
+	ExecutionReceiptsRootGIndexBellatrix GIndex = 19 // 16 + 3
+	ExecutionReceiptsRootGIndex          GIndex = 35 // 32 + 3
+)
+
+// ExecutionReceiptsRootGIndexForFork returns the generalized index of receipts_root in the
+// ExecutionPayload of the given fork. Its depth is the length of the payload part of a receipts_root branch.
+func ExecutionReceiptsRootGIndexForFork(fork string) (GIndex, error) {
+	switch strings.ToLower(fork) {
+	case "bellatrix", "capella":
+		return ExecutionReceiptsRootGIndexBellatrix, nil
+	case "deneb", "electra", "fulu":
+		return ExecutionReceiptsRootGIndex, nil
+	default:
+		return 0, fmt.Errorf("fork %q has no execution payload receipts root", fork)
+	}
+}
+
+// ReceiptsRootBodyGIndexForFork returns the generalized index of execution_payload.receipts_root in the
+// BeaconBlockBody of the given fork (403 up to Capella, 803 from Deneb)
+func ReceiptsRootBodyGIndexForFork(fork string) (GIndex, error) {
+	gindex, err := ExecutionReceiptsRootGIndexForFork(fork)
+	if err != nil {
+		return 0, err
+	}
+	return ConcatGIndices(ExecutionPayloadGIndex, gindex)
+}
+
+// Generalized indices of execution_payload.transactions (field 13), rooted at the ExecutionPayload,
+// with the same depths as ExecutionReceiptsRootGIndexBellatrix and ExecutionReceiptsRootGIndex.
+const (
+	ExecutionTransactionsGIndexBellatrix GIndex = 29 // 16 + 13
+	ExecutionTransactionsGIndex          GIndex = 45 // 32 + 13
+)
+
+// SSZ limits of the transactions of an ExecutionPayload, as depths of their merkle trees:
+// MAX_TRANSACTIONS_PER_PAYLOAD = 2**20 transactions of MAX_BYTES_PER_TRANSACTION = 2**30 bytes (2**25 chunks)
+const (
+	TransactionsListDepth  = 20
+	TransactionChunksDepth = 25
+)
+
+// ExecutionTransactionsGIndexForFork returns the generalized index of transactions in the
+// ExecutionPayload of the given fork
+func ExecutionTransactionsGIndexForFork(fork string) (GIndex, error) {
+	switch strings.ToLower(fork) {
+	case "bellatrix", "capella":
+		return ExecutionTransactionsGIndexBellatrix, nil
+	case "deneb", "electra", "fulu":
+		return ExecutionTransactionsGIndex, nil
+	default:
+		return 0, fmt.Errorf("fork %q has no execution payload transactions", fork)
+	}
+}
+
+// TransactionBodyGIndex returns the generalized index of the hash_tree_root of transaction txIndex in the
+// BeaconBlockBody, given the gindex of transactions in the ExecutionPayload: the payload, the field, the
+// data tree of the list (left of its length) and the leaf of the transaction
+func TransactionBodyGIndex(transactionsGIndex GIndex, txIndex uint64) (GIndex, error) {
+	leaf, err := NewGIndex(TransactionsListDepth, txIndex)
+	if err != nil {
+		return 0, err
+	}
+	return ConcatGIndices(ExecutionPayloadGIndex, transactionsGIndex, 2, leaf)
+}
+
+func mustConcatGIndices(gindices ...GIndex) GIndex {
+	g, err := ConcatGIndices(gindices...)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// CheckExecutionFork returns an error if the execution header of the given fork does not have the
+// Deneb layout assumed by ExecutionBlockHashGIndex (Capella headers lack the blob gas fields).
+func CheckExecutionFork(fork string) error {
+	switch strings.ToLower(fork) {
+	case "deneb", "electra", "fulu":
+		return nil
+	default:
+		return fmt.Errorf("execution header of fork %q is not supported", fork)
+	}
+}
+
+// ToSpec converts the JSON header into the SSZ ExecutionPayloadHeader of Deneb ..
+func (h *ExecutionPayloadHeader) ToSpec() (*deneb.ExecutionPayloadHeader, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	var header deneb.ExecutionPayloadHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("invalid execution payload header: %w", err)
+	}
+	return &header, nil
+}
+
+// ExecutionFieldProof proves the field of the attested execution header at gindex (rooted at the
+// header, e.g. ExecutionBlockHashGIndex) against the BodyRoot of the attested beacon header.
+// The returned branch is the header branch of the field followed by the update's execution_branch.
+func ExecutionFieldProof(update *LightClientUpdate, gindex GIndex) (*SSZProof, error) {
+	if update.Version != "" {
+		if err := CheckExecutionFork(update.Version); err != nil {
+			return nil, err
+		}
+	}
+	attested := &update.Data.AttestedHeader
+	if len(attested.ExecutionBranch) != ExecutionBranchDepth {
+		return nil, fmt.Errorf("execution_branch has %d roots, expected %d", len(attested.ExecutionBranch), ExecutionBranchDepth)
+	}
+
+	header, err := attested.Execution.ToSpec()
+	if err != nil {
+		return nil, err
+	}
+	fieldProof, err := GenerateSSZProofFromView(header.View(), gindex)
+	if err != nil {
+		return nil, err
+	}
+
+	executionBranch := make([]zrntcommon.Root, ExecutionBranchDepth)
+	for i, s := range attested.ExecutionBranch {
+		if err := executionBranch[i].UnmarshalText([]byte(s)); err != nil {
+			return nil, fmt.Errorf("execution_branch[%d]: %w", i, err)
+		}
+	}
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+	if !VerifySSZBranch(attested.Beacon.BodyRoot, headerRoot, executionBranch, ExecutionPayloadGIndex) {
+		return nil, fmt.Errorf("execution_branch does not match body root %v", attested.Beacon.BodyRoot)
+	}
+
+	bodyGIndex, err := ConcatGIndices(ExecutionPayloadGIndex, gindex)
+	if err != nil {
+		return nil, err
+	}
+	return &SSZProof{
+		GIndex: bodyGIndex,
+		Leaf:   fieldProof.Leaf,
+		Branch: append(fieldProof.Branch, executionBranch...),
+	}, nil
+}
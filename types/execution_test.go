@@ -0,0 +1,147 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionFieldProof(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+	bodyRoot := update.Data.AttestedHeader.Beacon.BodyRoot
+
+	require.Equal(t, GIndex(812), ExecutionBlockHashBodyGIndex)
+	require.Equal(t, GIndex(806), ExecutionBlockNumberBodyGIndex)
+	proof, err := ExecutionFieldProof(&update, ExecutionBlockHashGIndex)
+	require.NoError(t, err)
+	require.Equal(t, ExecutionBlockHashBodyGIndex, proof.GIndex)
+	require.Len(t, proof.Branch, 9)
+	var blockHash zrntcommon.Root
+	require.NoError(t, blockHash.UnmarshalText([]byte(update.Data.AttestedHeader.Execution.BlockHash)))
+	require.Equal(t, blockHash, proof.Leaf)
+	require.True(t, proof.Verify(bodyRoot))
+
+	// block_number is a little-endian uint64 leaf
+	proof, err = ExecutionFieldProof(&update, ExecutionBlockNumberGIndex)
+	require.NoError(t, err)
+	require.Equal(t, ExecutionBlockNumberBodyGIndex, proof.GIndex)
+	require.True(t, proof.Verify(bodyRoot))
+	header, err := update.Data.AttestedHeader.Execution.ToSpec()
+	require.NoError(t, err)
+	require.Equal(t, uint64(header.BlockNumber), uint64(proof.Leaf[0])|uint64(proof.Leaf[1])<<8|uint64(proof.Leaf[2])<<16|uint64(proof.Leaf[3])<<24)
+
+	// a tampered header no longer matches the execution_branch
+	tampered := update
+	tampered.Data.AttestedHeader.Execution.GasUsed = "1"
+	_, err = ExecutionFieldProof(&tampered, ExecutionBlockHashGIndex)
+	require.Error(t, err)
+
+	tampered = update
+	tampered.Version = "capella"
+	_, err = ExecutionFieldProof(&tampered, ExecutionBlockHashGIndex)
+	require.Error(t, err)
+}
+
+func TestReceiptsRootGIndexForFork(t *testing.T) {
+	spec := configs.Mainnet
+	var receiptsRoot zrntcommon.Root
+	receiptsRoot[0] = 0x01
+
+	// the payload gindex must resolve to receipts_root in the payload tree of each fork
+	var bellatrixPayload bellatrix.ExecutionPayload
+	bellatrixPayload.ReceiptsRoot = receiptsRoot
+	var capellaPayload capella.ExecutionPayload
+	capellaPayload.ReceiptsRoot = receiptsRoot
+	var denebPayload deneb.ExecutionPayload
+	denebPayload.ReceiptsRoot = receiptsRoot
+	for _, tc := range []struct {
+		fork    string
+		typ     view.TypeDef
+		payload zrntcommon.SpecObj
+		body    GIndex
+	}{
+		{"bellatrix", bellatrix.ExecutionPayloadType(spec), &bellatrixPayload, 403},
+		{"capella", capella.ExecutionPayloadType(spec), &capellaPayload, 403},
+		{"deneb", deneb.ExecutionPayloadType(spec), &denebPayload, 803},
+		{"electra", deneb.ExecutionPayloadType(spec), &denebPayload, 803},
+		{"Fulu", deneb.ExecutionPayloadType(spec), &denebPayload, 803},
+	} {
+		gindex, err := ExecutionReceiptsRootGIndexForFork(tc.fork)
+		require.NoError(t, err, tc.fork)
+		v, err := ViewFromSpecObj(spec, tc.typ, tc.payload)
+		require.NoError(t, err, tc.fork)
+		proof, err := GenerateSSZProofFromView(v, gindex)
+		require.NoError(t, err, tc.fork)
+		require.Equal(t, receiptsRoot, proof.Leaf, tc.fork)
+
+		bodyGIndex, err := ReceiptsRootBodyGIndexForFork(tc.fork)
+		require.NoError(t, err, tc.fork)
+		require.Equal(t, tc.body, bodyGIndex, tc.fork)
+		require.Equal(t, ExecutionPayloadGIndex.Depth()+gindex.Depth(), bodyGIndex.Depth(), tc.fork)
+	}
+
+	for _, fork := range []string{"phase0", "altair", "gloas"} {
+		_, err := ExecutionReceiptsRootGIndexForFork(fork)
+		require.Error(t, err, fork)
+		_, err = ReceiptsRootBodyGIndexForFork(fork)
+		require.Error(t, err, fork)
+	}
+}
+
+func TestTransactionsGIndexForFork(t *testing.T) {
+	spec := configs.Mainnet
+	txs := zrntcommon.PayloadTransactions{{0x02, 0xc0}, {0xc1, 0x80}, {0x01}}
+
+	// the payload gindex of transactions, then the leaf of each transaction under the list
+	var bellatrixPayload bellatrix.ExecutionPayload
+	bellatrixPayload.Transactions = txs
+	var capellaPayload capella.ExecutionPayload
+	capellaPayload.Transactions = txs
+	var denebPayload deneb.ExecutionPayload
+	denebPayload.Transactions = txs
+	for _, tc := range []struct {
+		fork    string
+		typ     view.TypeDef
+		payload zrntcommon.SpecObj
+	}{
+		{"bellatrix", bellatrix.ExecutionPayloadType(spec), &bellatrixPayload},
+		{"capella", capella.ExecutionPayloadType(spec), &capellaPayload},
+		{"deneb", deneb.ExecutionPayloadType(spec), &denebPayload},
+		{"Fulu", deneb.ExecutionPayloadType(spec), &denebPayload},
+	} {
+		gindex, err := ExecutionTransactionsGIndexForFork(tc.fork)
+		require.NoError(t, err, tc.fork)
+		v, err := ViewFromSpecObj(spec, tc.typ, tc.payload)
+		require.NoError(t, err, tc.fork)
+		proof, err := GenerateSSZProofFromView(v, gindex)
+		require.NoError(t, err, tc.fork)
+		require.Equal(t, txs.HashTreeRoot(spec, tree.GetHashFn()), proof.Leaf, tc.fork)
+
+		bodyGIndex, err := TransactionBodyGIndex(gindex, 2)
+		require.NoError(t, err, tc.fork)
+		require.Equal(t, ExecutionPayloadGIndex.Depth()+gindex.Depth()+1+TransactionsListDepth, bodyGIndex.Depth(), tc.fork)
+		leaf, err := ConcatGIndices(gindex, 2, GIndex(1<<TransactionsListDepth|2))
+		require.NoError(t, err)
+		proof, err = GenerateSSZProofFromView(v, leaf)
+		require.NoError(t, err, tc.fork)
+		require.Equal(t, txs[2].HashTreeRoot(spec, tree.GetHashFn()), proof.Leaf, tc.fork)
+	}
+
+	_, err := ExecutionTransactionsGIndexForFork("altair")
+	require.Error(t, err)
+	_, err = TransactionBodyGIndex(ExecutionTransactionsGIndex, 1<<TransactionsListDepth)
+	require.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package types
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// GIndex is an SSZ generalized index: the root is 1, and the children of node n are 2n and 2n+1.
+type GIndex uint64
+
+// Generalized indices used by the light client protocol (consensus-specs, altair/electra light-client).
+const (
+	// BeaconState fields (Altair .. Deneb)
+	FinalizedRootGIndexAltair        GIndex = 105
+	CurrentSyncCommitteeGIndexAltair GIndex = 54
+	NextSyncCommitteeGIndexAltair    GIndex = 55
+
+	// BeaconState fields (Electra, Fulu)
+	FinalizedRootGIndexElectra        GIndex = 169
+	CurrentSyncCommitteeGIndexElectra GIndex = 86
+	NextSyncCommitteeGIndexElectra    GIndex = 87
+
+	// BeaconBlockBody.execution_payload (Bellatrix ..)
+	ExecutionPayloadGIndex GIndex = 25
+)
+
+// FinalizedRootGIndexForFork returns the generalized index of finalized_checkpoint.root in the
+// BeaconState of the given fork, as NextSyncCommitteeGIndexForFork.
+func FinalizedRootGIndexForFork(fork string) (GIndex, error) {
+	switch strings.ToLower(fork) {
+	case "altair", "bellatrix", "capella", "deneb":
+		return FinalizedRootGIndexAltair, nil
+	case "electra", "fulu":
+		return FinalizedRootGIndexElectra, nil
+	default:
+		return 0, fmt.Errorf("unknown fork %q", fork)
+	}
+}
+
+// NextSyncCommitteeGIndexForFork returns the generalized index of next_sync_committee in the
+// BeaconState of the given fork (the "version" field of a light client update, e.g. "deneb" or "fulu").
+func NextSyncCommitteeGIndexForFork(fork string) (GIndex, error) {
+	switch strings.ToLower(fork) {
+	case "altair", "bellatrix", "capella", "deneb":
+		return NextSyncCommitteeGIndexAltair, nil
+	case "electra", "fulu":
+		return NextSyncCommitteeGIndexElectra, nil
+	default:
+		return 0, fmt.Errorf("unknown fork %q", fork)
+	}
+}
+
+// CurrentSyncCommitteeGIndexForFork returns the generalized index of current_sync_committee in the
+// BeaconState of the given fork, as NextSyncCommitteeGIndexForFork.
+func CurrentSyncCommitteeGIndexForFork(fork string) (GIndex, error) {
+	switch strings.ToLower(fork) {
+	case "altair", "bellatrix", "capella", "deneb":
+		return CurrentSyncCommitteeGIndexAltair, nil
+	case "electra", "fulu":
+		return CurrentSyncCommitteeGIndexElectra, nil
+	default:
+		return 0, fmt.Errorf("unknown fork %q", fork)
+	}
+}
+
+// NewGIndex returns the generalized index of the node at position index of a tree level at the given depth.
+func NewGIndex(depth int, index uint64) (GIndex, error) {
+	if depth < 0 || depth > 63 {
+		return 0, fmt.Errorf("depth %d out of range", depth)
+	}
+	if index >= uint64(1)<<uint(depth) {
+		return 0, fmt.Errorf("index %d out of range for depth %d", index, depth)
+	}
+	return GIndex(uint64(1)<<uint(depth) | index), nil
+}
+
+// IsValid reports whether g is a valid generalized index (>= 1).
+func (g GIndex) IsValid() bool {
+	return g >= 1
+}
+
+// Depth returns the depth of the node, i.e. the length of its Merkle branch.
+func (g GIndex) Depth() int {
+	if g == 0 {
+		return 0
+	}
+	return bits.Len64(uint64(g)) - 1
+}
+
+// Index returns the position of the node within its tree level.
+func (g GIndex) Index() uint64 {
+	return uint64(g) &^ (uint64(1) << uint(g.Depth()))
+}
+
+// PathBits returns the path from the node up to the root, least significant bit first:
+// bit i is 1 when the node at height i (0 = the node itself) is a right child,
+// i.e. its sibling branch[i] must be hashed on the left.
+//
+// For example, GIndex 87 (next_sync_committee in Electra) gives [1, 1, 1, 0, 1, 0].
+func (g GIndex) PathBits() []int {
+	depth := g.Depth()
+	path := make([]int, depth)
+	for i := 0; i < depth; i++ {
+		path[i] = int(uint64(g)>>uint(i)) & 1
+	}
+	return path
+}
+
+// IsLeft reports whether the node is the left child of its parent.
+func (g GIndex) IsLeft() bool {
+	return g&1 == 0
+}
+
+// Left returns the left child.
+func (g GIndex) Left() GIndex {
+	return g * 2
+}
+
+// Right returns the right child.
+func (g GIndex) Right() GIndex {
+	return g*2 + 1
+}
+
+// Parent returns the parent node.
+func (g GIndex) Parent() GIndex {
+	return g / 2
+}
+
+// Sibling returns the other child of the parent node.
+func (g GIndex) Sibling() GIndex {
+	return g ^ 1
+}
+
+// Child returns the node at position index of the subtree of the given depth rooted at g.
+func (g GIndex) Child(depth int, index uint64) (GIndex, error) {
+	sub, err := NewGIndex(depth, index)
+	if err != nil {
+		return 0, err
+	}
+	return ConcatGIndices(g, sub)
+}
+
+// ConcatGIndices concatenates nested generalized indices, so that the result points
+// from the outermost root to the innermost node (concat_generalized_indices of the spec).
+func ConcatGIndices(gindices ...GIndex) (GIndex, error) {
+	o := GIndex(1)
+	for _, g := range gindices {
+		if !g.IsValid() {
+			return 0, fmt.Errorf("invalid generalized index: %d", g)
+		}
+		depth := g.Depth()
+		if o.Depth()+depth > 63 {
+			return 0, fmt.Errorf("concatenated generalized index does not fit in 64 bits")
+		}
+		o = o<<uint(depth) | GIndex(g.Index())
+	}
+	return o, nil
+}
+
+func (g GIndex) String() string {
+	return fmt.Sprintf("%d", uint64(g))
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGIndex(t *testing.T) {
+	g := NextSyncCommitteeGIndexElectra
+	require.Equal(t, 6, g.Depth())
+	require.Equal(t, uint64(23), g.Index())
+	require.Equal(t, []int{1, 1, 1, 0, 1, 0}, g.PathBits())
+	require.False(t, g.IsLeft())
+	require.Equal(t, GIndex(86), g.Sibling())
+	require.Equal(t, CurrentSyncCommitteeGIndexElectra, g.Sibling())
+	require.Equal(t, GIndex(43), g.Parent())
+	require.Equal(t, GIndex(174), g.Left())
+	require.Equal(t, GIndex(175), g.Right())
+
+	require.Equal(t, 5, NextSyncCommitteeGIndexAltair.Depth())
+	require.Equal(t, []int{1, 1, 1, 0, 1}, NextSyncCommitteeGIndexAltair.PathBits())
+
+	n, err := NewGIndex(6, 23)
+	require.NoError(t, err)
+	require.Equal(t, g, n)
+	_, err = NewGIndex(6, 64)
+	require.Error(t, err)
+
+	root := GIndex(1)
+	require.Equal(t, 0, root.Depth())
+	require.Empty(t, root.PathBits())
+}
+
+func TestConcatGIndices(t *testing.T) {
+	// BeaconBlockBody.execution_payload (25) -> ExecutionPayload.receipts_root (32 + 3)
+	g, err := ConcatGIndices(ExecutionPayloadGIndex, 35)
+	require.NoError(t, err)
+	require.Equal(t, GIndex(25*32+3), g)
+	require.Equal(t, 9, g.Depth())
+
+	// concatenating with the root is the identity
+	g, err = ConcatGIndices(1, NextSyncCommitteeGIndexElectra, 1)
+	require.NoError(t, err)
+	require.Equal(t, NextSyncCommitteeGIndexElectra, g)
+
+	g, err = ExecutionPayloadGIndex.Child(5, 3)
+	require.NoError(t, err)
+	require.Equal(t, GIndex(25*32+3), g)
+
+	_, err = ConcatGIndices(0)
+	require.Error(t, err)
+}
+
+func TestNextSyncCommitteeGIndexForFork(t *testing.T) {
+	for fork, want := range map[string]GIndex{
+		"altair": NextSyncCommitteeGIndexAltair,
+		"deneb":  NextSyncCommitteeGIndexAltair,
+		"Fulu":   NextSyncCommitteeGIndexElectra,
+	} {
+		g, err := NextSyncCommitteeGIndexForFork(fork)
+		require.NoError(t, err)
+		require.Equal(t, want, g, fork)
+	}
+	_, err := NextSyncCommitteeGIndexForFork("phase0")
+	require.Error(t, err)
+}
+
+func TestCurrentSyncCommitteeGIndexForFork(t *testing.T) {
+	for fork, want := range map[string]GIndex{
+		"capella": CurrentSyncCommitteeGIndexAltair,
+		"electra": CurrentSyncCommitteeGIndexElectra,
+	} {
+		g, err := CurrentSyncCommitteeGIndexForFork(fork)
+		require.NoError(t, err)
+		require.Equal(t, want, g, fork)
+		// the sibling of next_sync_committee
+		next, err := NextSyncCommitteeGIndexForFork(fork)
+		require.NoError(t, err)
+		require.Equal(t, next.Sibling(), g)
+	}
+	_, err := CurrentSyncCommitteeGIndexForFork("phase0")
+	require.Error(t, err)
+}
+
+func TestFinalizedRootGIndexForFork(t *testing.T) {
+	for fork, want := range map[string]GIndex{
+		"capella": FinalizedRootGIndexAltair,
+		"deneb":   FinalizedRootGIndexAltair,
+		"electra": FinalizedRootGIndexElectra,
+	} {
+		g, err := FinalizedRootGIndexForFork(fork)
+		require.NoError(t, err)
+		require.Equal(t, want, g, fork)
+	}
+	// the Electra state tree has the pre-Electra one as its left half
+	require.Equal(t, FinalizedRootGIndexAltair.Index(), FinalizedRootGIndexElectra.Index())
+	_, err := FinalizedRootGIndexForFork("phase0")
+	require.Error(t, err)
+}
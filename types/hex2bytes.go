@@ -3,10 +3,63 @@ package types
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 )
 
+// DecodeErrorKind classifies why a value from a data provider could not be decoded
+type DecodeErrorKind string
+
+const (
+	// DecodeErrorHex is a malformed hex string (bad digit or odd length)
+	DecodeErrorHex DecodeErrorKind = "hex"
+	// DecodeErrorBase64 is a malformed base64 string
+	DecodeErrorBase64 DecodeErrorKind = "base64"
+	// DecodeErrorTruncated is a document or value cut short, typically a truncated response body
+	DecodeErrorTruncated DecodeErrorKind = "truncated"
+	// DecodeErrorOther is any other decoding failure (wrong JSON type, unexpected layout, ...)
+	DecodeErrorOther DecodeErrorKind = "other"
+)
+
+// DecodeError is returned by HexBytes.UnmarshalJSON and wraps the underlying decoder error
+type DecodeError struct {
+	Kind DecodeErrorKind
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s decode error: %v", e.Kind, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyDecodeError returns the kind of a decoding failure, including the errors of the hex and
+// base64 decoders used by other types (e.g. zrnt roots) and of the JSON decoder itself.
+func ClassifyDecodeError(err error) DecodeErrorKind {
+	var decodeErr *DecodeError
+	var syntaxErr *json.SyntaxError
+	var invalidByteErr hex.InvalidByteError
+	var corruptErr base64.CorruptInputError
+	switch {
+	case errors.As(err, &decodeErr):
+		return decodeErr.Kind
+	case errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &syntaxErr) && syntaxErr.Error() == "unexpected end of JSON input":
+		return DecodeErrorTruncated
+	case errors.Is(err, hex.ErrLength), errors.As(err, &invalidByteErr):
+		return DecodeErrorHex
+	case errors.As(err, &corruptErr):
+		return DecodeErrorBase64
+	default:
+		return DecodeErrorOther
+	}
+}
+
 func HexToBytes(hexStr string) ([]byte, error) {
 	if strings.HasPrefix(hexStr, "0x") {
 		hexStr = hexStr[2:]
@@ -32,7 +85,12 @@ func (hb HexBytes) MarshalJSON() ([]byte, error) {
 // This is the point of Bytes.
 func (hb *HexBytes) UnmarshalJSON(data []byte) error {
 	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
-		return fmt.Errorf("invalid hex string: %s", data)
+		kind := DecodeErrorOther
+		if len(data) > 0 && data[0] == '"' {
+			// the closing quote is missing
+			kind = DecodeErrorTruncated
+		}
+		return &DecodeError{Kind: kind, Err: fmt.Errorf("invalid hex string: %s", data)}
 	}
 
 	// escape double quote
@@ -42,14 +100,19 @@ func (hb *HexBytes) UnmarshalJSON(data []byte) error {
 		str := strings.TrimPrefix(string(val), "0x")
 		bz, err := hex.DecodeString(str)
 		if err != nil {
-			return err
+			return &DecodeError{Kind: DecodeErrorHex, Err: err}
 		}
 		*hb = bz
 	} else {
 		// base64
 		bz, err := base64.StdEncoding.DecodeString(string(val))
 		if err != nil {
-			return err
+			kind := DecodeErrorBase64
+			if strings.HasPrefix(string(val), "0x") {
+				// most likely a hex string with an odd length or a bad digit
+				kind = DecodeErrorHex
+			}
+			return &DecodeError{Kind: kind, Err: err}
 		}
 		*hb = bz
 	}
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHexBytesDecodeErrors(t *testing.T) {
+	var hb HexBytes
+	require.NoError(t, json.Unmarshal([]byte(`"0x0102"`), &hb))
+	require.Equal(t, HexBytes{1, 2}, hb)
+	require.NoError(t, json.Unmarshal([]byte(`"AQI="`), &hb))
+	require.Equal(t, HexBytes{1, 2}, hb)
+
+	for input, kind := range map[string]DecodeErrorKind{
+		`"0x010"`:  DecodeErrorHex,
+		`"0x01zz"`: DecodeErrorHex,
+		`"A*I="`:   DecodeErrorBase64,
+		`"0x0102`:  DecodeErrorTruncated,
+		`12`:       DecodeErrorOther,
+	} {
+		err := hb.UnmarshalJSON([]byte(input))
+		var decodeErr *DecodeError
+		require.True(t, errors.As(err, &decodeErr), input)
+		require.Equal(t, kind, decodeErr.Kind, input)
+		require.Equal(t, kind, ClassifyDecodeError(err), input)
+	}
+
+	// errors of other decoders
+	var update LightClientUpdate
+	require.Equal(t, DecodeErrorTruncated, ClassifyDecodeError(json.Unmarshal([]byte(`{"data": {`), &update)))
+	require.Equal(t, DecodeErrorHex, ClassifyDecodeError(json.Unmarshal([]byte(`{"data": {"next_sync_committee_branch": ["0x`+strings.Repeat("zz", 32)+`"]}}`), &update)))
+	require.Equal(t, DecodeErrorOther, ClassifyDecodeError(json.Unmarshal([]byte(`{"data": 1}`), &update)))
+}
@@ -0,0 +1,143 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// HistoricalSummariesDepth is log2(HISTORICAL_ROOTS_LIMIT), the depth of the historical_summaries list data
+const HistoricalSummariesDepth = 24
+
+// BeaconState.historical_summaries field (field 27, Capella ..)
+const (
+	HistoricalSummariesGIndexCapella GIndex = 32 + 27 // Capella, Deneb, 32 fields or less
+	HistoricalSummariesGIndexElectra GIndex = 64 + 27 // Electra, Fulu, more than 32 fields
+)
+
+// HistoricalSummariesGIndexForFork returns the generalized index of historical_summaries in the BeaconState
+// of the given fork. The list was introduced in Capella.
+func HistoricalSummariesGIndexForFork(fork string) (GIndex, error) {
+	gindex, err := BlockRootsGIndexForFork(fork)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(fork) {
+	case "altair", "bellatrix":
+		return 0, fmt.Errorf("fork %q has no historical_summaries", fork)
+	}
+	if gindex == BlockRootsGIndexAltair {
+		return HistoricalSummariesGIndexCapella, nil
+	}
+	return HistoricalSummariesGIndexElectra, nil
+}
+
+// HistoricalSummariesStartPeriod returns the period (slot / SLOTS_PER_HISTORICAL_ROOT) summarized by
+// historical_summaries[0], i.e. the one holding the first Capella slot (758 on mainnet).
+func HistoricalSummariesStartPeriod(spec *zrntcommon.Spec) uint64 {
+	return uint64(spec.CAPELLA_FORK_EPOCH) * uint64(spec.SLOTS_PER_EPOCH) / SlotsPerHistoricalRoot
+}
+
+// HistoricalBlockRootGIndex returns the generalized index, in the BeaconState, of the root of the block
+// at slot inside the block_summary_root of historical_summaries[summaryIndex]: the list data (left of
+// the length mix-in), the summary, its block_summary_root (field 0) and the block_roots entry.
+func HistoricalBlockRootGIndex(historicalSummariesGIndex GIndex, summaryIndex, slot uint64) (GIndex, error) {
+	summary, err := NewGIndex(HistoricalSummariesDepth, summaryIndex)
+	if err != nil {
+		return 0, err
+	}
+	entry, err := NewGIndex(13, slot%SlotsPerHistoricalRoot)
+	if err != nil {
+		return 0, err
+	}
+	return ConcatGIndices(historicalSummariesGIndex, 2, summary, 2, entry)
+}
+
+// CheckHistoricalSummariesWindow checks that the period of slot has been summarized in the
+// historical_summaries of a state at stateSlot, and returns the index of its summary. A summary is
+// appended once the state reaches the next period, so slot / 8192 < stateSlot / 8192.
+func CheckHistoricalSummariesWindow(startPeriod, stateSlot, slot uint64) (uint64, error) {
+	period := slot / SlotsPerHistoricalRoot
+	if period < startPeriod {
+		return 0, fmt.Errorf("slot %d is before the first summarized period %d", slot, startPeriod)
+	}
+	if period >= stateSlot/SlotsPerHistoricalRoot {
+		return 0, fmt.Errorf("period of slot %d is not summarized yet at state slot %d", slot, stateSlot)
+	}
+	return period - startPeriod, nil
+}
+
+// HistoricalBlockRootProof generates the proof of the root of the block at slot in an SSZ-encoded
+// (Electra) BeaconState at stateSlot, through historical_summaries. blockRoots is the block_roots
+// vector that was summarized for the period of slot, e.g. taken from the state at its last slot.
+func HistoricalBlockRootProof(spec *zrntcommon.Spec, stateSSZ []byte, blockRoots []zrntcommon.Root, stateSlot, slot uint64) (*SSZProof, error) {
+	summaryIndex, err := CheckHistoricalSummariesWindow(HistoricalSummariesStartPeriod(spec), stateSlot, slot)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockRoots) != SlotsPerHistoricalRoot {
+		return nil, fmt.Errorf("block_roots has %d roots, expected %d", len(blockRoots), SlotsPerHistoricalRoot)
+	}
+
+	// lower levels: the entry inside the summarized block_roots vector
+	roots := phase0.HistoricalBatchRoots(blockRoots)
+	vector, err := ViewFromSpecObj(spec, phase0.BatchRootsType(spec), &roots)
+	if err != nil {
+		return nil, fmt.Errorf("block roots: %w", err)
+	}
+	entryGIndex, err := NewGIndex(13, slot%SlotsPerHistoricalRoot)
+	if err != nil {
+		return nil, err
+	}
+	lower, err := GenerateSSZProofFromView(vector, entryGIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	// upper levels: block_summary_root of the summary in the state
+	summary, err := NewGIndex(HistoricalSummariesDepth, summaryIndex)
+	if err != nil {
+		return nil, err
+	}
+	summaryRootGIndex, err := ConcatGIndices(HistoricalSummariesGIndexElectra, 2, summary, 2)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := BeaconStateProof(spec, stateSSZ, summaryRootGIndex)
+	if err != nil {
+		return nil, err
+	}
+	if root := vector.HashTreeRoot(tree.GetHashFn()); upper.Leaf != root {
+		return nil, fmt.Errorf("block_roots root %v is not block_summary_root %v of summary %d", root, upper.Leaf, summaryIndex)
+	}
+
+	gindex, err := HistoricalBlockRootGIndex(HistoricalSummariesGIndexElectra, summaryIndex, slot)
+	if err != nil {
+		return nil, err
+	}
+	return &SSZProof{
+		GIndex: gindex,
+		Leaf:   lower.Leaf,
+		Branch: append(lower.Branch, upper.Branch...),
+	}, nil
+}
+
+// VerifyHistoricalBlockRoot verifies that blockRoot is the root of the block at slot, as recorded in
+// the historical_summaries of the state with the given root at stateSlot.
+func VerifyHistoricalBlockRoot(stateRoot, blockRoot zrntcommon.Root, branch []zrntcommon.Root, historicalSummariesGIndex GIndex, startPeriod, stateSlot, slot uint64) error {
+	summaryIndex, err := CheckHistoricalSummariesWindow(startPeriod, stateSlot, slot)
+	if err != nil {
+		return err
+	}
+	gindex, err := HistoricalBlockRootGIndex(historicalSummariesGIndex, summaryIndex, slot)
+	if err != nil {
+		return err
+	}
+	if !VerifySSZBranch(stateRoot, blockRoot, branch, gindex) {
+		return fmt.Errorf("block root of slot %d is not in historical_summaries of state %v", slot, stateRoot)
+	}
+	return nil
+}
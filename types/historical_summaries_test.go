@@ -0,0 +1,79 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoricalBlockRootProof(t *testing.T) {
+	spec := configs.Mainnet
+	startPeriod := HistoricalSummariesStartPeriod(spec)
+	require.Equal(t, uint64(758), startPeriod)
+
+	// the third summarized period holds the block
+	slot := (startPeriod+2)*SlotsPerHistoricalRoot + 1234
+	stateSlot := (startPeriod + 3) * SlotsPerHistoricalRoot
+	blockRoots := make([]zrntcommon.Root, SlotsPerHistoricalRoot)
+	for i := range blockRoots {
+		blockRoots[i] = zrntcommon.Root{byte(i), byte(i >> 8), 0xbb}
+	}
+	blockRoot := blockRoots[slot%SlotsPerHistoricalRoot]
+	batchRoots := phase0.HistoricalBatchRoots(blockRoots)
+
+	state, err := electra.AsBeaconStateView(electra.BeaconStateType(spec).New(), nil)
+	require.NoError(t, err)
+	require.NoError(t, state.SetSlot(zrntcommon.Slot(stateSlot)))
+	summaries, err := state.HistoricalSummaries()
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		summary := capella.HistoricalSummary{StateSummaryRoot: zrntcommon.Root{byte(i)}}
+		if i == 2 {
+			summary.BlockSummaryRoot = batchRoots.HashTreeRoot(spec, tree.GetHashFn())
+		}
+		require.NoError(t, summaries.Append(summary))
+	}
+	stateRoot := state.HashTreeRoot(tree.GetHashFn())
+	var stateSSZ bytes.Buffer
+	require.NoError(t, state.Serialize(codec.NewEncodingWriter(&stateSSZ)))
+
+	proof, err := HistoricalBlockRootProof(spec, stateSSZ.Bytes(), blockRoots, stateSlot, slot)
+	require.NoError(t, err)
+	require.Equal(t, blockRoot, proof.Leaf)
+	require.Equal(t, 13+1+HistoricalSummariesDepth+1+6, proof.GIndex.Depth())
+	require.True(t, proof.Verify(stateRoot))
+
+	require.NoError(t, VerifyHistoricalBlockRoot(stateRoot, blockRoot, proof.Branch, HistoricalSummariesGIndexElectra, startPeriod, stateSlot, slot))
+	// another period maps to another summary
+	require.Error(t, VerifyHistoricalBlockRoot(stateRoot, blockRoot, proof.Branch, HistoricalSummariesGIndexElectra, startPeriod, stateSlot, slot-SlotsPerHistoricalRoot))
+	// wrong layout or wrong root
+	require.Error(t, VerifyHistoricalBlockRoot(stateRoot, blockRoot, proof.Branch, HistoricalSummariesGIndexCapella, startPeriod, stateSlot, slot))
+	require.Error(t, VerifyHistoricalBlockRoot(stateRoot, zrntcommon.Root{}, proof.Branch, HistoricalSummariesGIndexElectra, startPeriod, stateSlot, slot))
+
+	// the summary of the block_roots of another period does not match
+	blockRoots[0][31] ^= 1
+	_, err = HistoricalBlockRootProof(spec, stateSSZ.Bytes(), blockRoots, stateSlot, slot)
+	require.Error(t, err)
+
+	// the period of the state itself is not summarized yet
+	_, err = CheckHistoricalSummariesWindow(startPeriod, stateSlot, stateSlot-1)
+	require.NoError(t, err)
+	_, err = CheckHistoricalSummariesWindow(startPeriod, stateSlot, stateSlot)
+	require.Error(t, err)
+	_, err = CheckHistoricalSummariesWindow(startPeriod, stateSlot, startPeriod*SlotsPerHistoricalRoot-1)
+	require.Error(t, err)
+
+	g, err := HistoricalSummariesGIndexForFork("deneb")
+	require.NoError(t, err)
+	require.Equal(t, HistoricalSummariesGIndexCapella, g)
+	_, err = HistoricalSummariesGIndexForFork("bellatrix")
+	require.Error(t, err)
+}
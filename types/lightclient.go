@@ -8,6 +8,7 @@ import (
 	bn254_fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	zrntaltair "github.com/protolambda/zrnt/eth2/beacon/altair"
 	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
 )
 
 type SyncCommittee struct {
@@ -28,9 +29,13 @@ type LightClientUpdate struct {
 			ExecutionBranch []string                     `json:"execution_branch"`
 		} `json:"attested_header"`
 		NextSyncCommittee       zrntcommon.SyncCommittee `json:"next_sync_committee"`
-		NextSyncCommitteeBranch [6]zrntcommon.Root       `json:"next_sync_committee_branch"`
+		NextSyncCommitteeBranch []zrntcommon.Root        `json:"next_sync_committee_branch"` // 5 roots before Electra, 6 since
 		SyncAggregate           zrntaltair.SyncAggregate `json:"sync_aggregate"`
 		SignatureSlot           string                   `json:"signature_slot"`
+		FinalizedHeader         struct {
+			Beacon zrntcommon.BeaconBlockHeader `json:"beacon"`
+		} `json:"finalized_header"`
+		FinalityBranch []zrntcommon.Root `json:"finality_branch"` // 6 roots before Electra, 7 since
 	} `json:"data"`
 	Version string `json:"version"`
 }
@@ -68,6 +73,8 @@ func ParseSyncCommitteeBits(bitsBytes []byte) []bool {
 }
 
 // Aggregate public keys using gnark-crypto (native BLS12-381)
+// Pubkeys at infinity are the identity and are skipped, like in the circuit: they are not counted as
+// participants. It fails if no participant has a pubkey other than infinity.
 func AggregatePublicKeys(pubkeys []zrntcommon.BLSPubkey, bits []bool) (bls12381.G1Affine, int, error) {
 	var aggPubkey bls12381.G1Affine
 	aggPubkey.SetInfinity() // Start with identity element
@@ -82,6 +89,9 @@ func AggregatePublicKeys(pubkeys []zrntcommon.BLSPubkey, bits []bool) (bls12381.
 		if err != nil {
 			return aggPubkey, 0, fmt.Errorf("failed to deserialize pubkey %d: %v", i, err)
 		}
+		if pubkey.IsInfinity() {
+			continue
+		}
 
 		// Add to aggregate
 		aggPubkey.Add(&aggPubkey, &pubkey)
@@ -95,6 +105,44 @@ func AggregatePublicKeys(pubkeys []zrntcommon.BLSPubkey, bits []bool) (bls12381.
 	return aggPubkey, count, nil
 }
 
+// VerifySyncAggregate natively verifies the sync aggregate signature of the update against the given
+// committee and signing domain, and returns the number of participating members.
+func VerifySyncAggregate(committee *zrntcommon.SyncCommittee, update *LightClientUpdate, domain [32]byte) (int, error) {
+	bits := ParseSyncCommitteeBits(update.Data.SyncAggregate.SyncCommitteeBits)
+	aggPubkey, count, err := AggregatePublicKeys(committee.Pubkeys, bits)
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate public keys: %w", err)
+	}
+
+	var signature bls12381.G2Affine
+	if _, err := signature.SetBytes(update.Data.SyncAggregate.SyncCommitteeSignature[:]); err != nil {
+		return count, fmt.Errorf("failed to deserialize signature: %w", err)
+	}
+
+	blockRoot := update.Data.AttestedHeader.Beacon.HashTreeRoot(tree.GetHashFn())
+	signingRoot := zrntcommon.ComputeSigningRoot(blockRoot, zrntcommon.BLSDomain(domain))
+	messageHash, err := bls12381.HashToG2(signingRoot[:], []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"))
+	if err != nil {
+		return count, fmt.Errorf("failed to hash to G2: %w", err)
+	}
+
+	// e(pubkey, H(msg)) * e(-G1, signature) == 1
+	_, _, g1Gen, _ := bls12381.Generators()
+	var negG1 bls12381.G1Affine
+	negG1.Neg(&g1Gen)
+	valid, err := bls12381.PairingCheck(
+		[]bls12381.G1Affine{aggPubkey, negG1},
+		[]bls12381.G2Affine{messageHash, signature},
+	)
+	if err != nil {
+		return count, fmt.Errorf("pairing check error: %w", err)
+	}
+	if !valid {
+		return count, fmt.Errorf("signature verification failed")
+	}
+	return count, nil
+}
+
 // ComputeScPubKeysHash computes a SHA256 commitment to the sync committee public keys
 // This matches the commitment computation in the circuit
 //
@@ -114,18 +162,81 @@ func AggregatePublicKeys(pubkeys []zrntcommon.BLSPubkey, bits []bool) (bls12381.
 //		return commitment
 //	}
 func ComputeScPubKeysHash(pubkeys []bls12381.G1Affine) [32]byte {
-	hasher := sha256.New()
+	return ComputeScPubKeysHashWithMode(pubkeys, ScPubKeysHashTruncated)
+}
+
+// ScPubKeysHashMode selects how each sync committee pubkey is serialized into the ScPubKeysHash commitment.
+// The circuit, the relayer and the on-chain light client of one deployment must all use the same mode.
+type ScPubKeysHashMode uint8
+
+const (
+	// ScPubKeysHashTruncated hashes only the 16 least significant bytes of each X coordinate
+	// (X.Limbs[1] || X.Limbs[0] in the circuit). It is cheap but binds the committee weakly.
+	ScPubKeysHashTruncated ScPubKeysHashMode = iota
+	// ScPubKeysHashFull hashes the full 48-byte compressed pubkey, including the flag bits.
+	ScPubKeysHashFull
+)
+
+func (m ScPubKeysHashMode) String() string {
+	switch m {
+	case ScPubKeysHashTruncated:
+		return "truncated"
+	case ScPubKeysHashFull:
+		return "full"
+	default:
+		return fmt.Sprintf("ScPubKeysHashMode(%d)", uint8(m))
+	}
+}
+
+// ParseScPubKeysHashMode parses the name returned by ScPubKeysHashMode.String.
+func ParseScPubKeysHashMode(s string) (ScPubKeysHashMode, error) {
+	switch s {
+	case "", "truncated":
+		return ScPubKeysHashTruncated, nil
+	case "full":
+		return ScPubKeysHashFull, nil
+	default:
+		return 0, fmt.Errorf("unknown sync committee pubkeys hash mode: %q", s)
+	}
+}
+
+// ComputeScPubKeysHashWithMode computes the SHA256 commitment to the sync committee public keys
+// using the serialization selected by mode. The serialized pubkeys are hashed at once, from a buffer
+// allocated up front.
+func ComputeScPubKeysHashWithMode(pubkeys []bls12381.G1Affine, mode ScPubKeysHashMode) [32]byte {
+	if mode == ScPubKeysHashFull {
+		// Hash the 48 bytes compressed form: flags(3 bits) || X (big-endian)
+		// This matches the circuit which serializes X and the sign of Y in-circuit
+		buf := make([]byte, 0, len(pubkeys)*bls12381.SizeOfG1AffineCompressed)
+		for i := range pubkeys {
+			compressed := pubkeys[i].Bytes()
+			buf = append(buf, compressed[:]...)
+		}
+		return sha256.Sum256(buf)
+	}
 
 	// Hash only the first two limbs (Limbs[0], Limbs[1]) of each X coordinate for efficiency
 	// This matches the circuit which hashes Limbs[0] and Limbs[1] in big-endian format
-	for i := 0; i < len(pubkeys); i++ {
+	buf := make([]byte, 0, len(pubkeys)*16)
+	for i := range pubkeys {
 		// Get the X coordinate as bytes (big-endian, 48 bytes = 384 bits)
 		xBytes := pubkeys[i].X.Bytes()
-		bytesToHash := xBytes[32:] // [32..48] = 128bits. it's for X.Limbs[1] || X.Limbs[0] in the circuit
-		hasher.Write(bytesToHash)
-		//if i < 10 {
-		//	fmt.Printf("pubkey[%d] to hash: 0x%x\n", i, bytesToHash)
-		//}
+		buf = append(buf, xBytes[32:]...) // [32..48] = 128bits. it's for X.Limbs[1] || X.Limbs[0] in the circuit
+	}
+	return sha256.Sum256(buf)
+}
+
+// ComputeSyncCommitteePubKeysHash computes the commitment of ComputeScPubKeysHashWithMode from the
+// compressed pubkeys of a SyncCommittee, as the circuit does for the next sync committee: the 16 least
+// significant bytes of X are the last 16 bytes of the compressed form, and the flag bits are in its first byte.
+func ComputeSyncCommitteePubKeysHash(pubkeys []zrntcommon.BLSPubkey, mode ScPubKeysHashMode) [32]byte {
+	hasher := sha256.New()
+	for i := range pubkeys {
+		if mode == ScPubKeysHashFull {
+			hasher.Write(pubkeys[i][:])
+		} else {
+			hasher.Write(pubkeys[i][32:])
+		}
 	}
 
 	var commitment [32]byte
@@ -133,6 +244,15 @@ func ComputeScPubKeysHash(pubkeys []bls12381.G1Affine) [32]byte {
 	return commitment
 }
 
+// SyncCommitteeRoot returns hash_tree_root(sc) for a committee of len(sc.Pubkeys) members, whatever the preset
+func SyncCommitteeRoot(sc *zrntcommon.SyncCommittee) zrntcommon.Root {
+	hFn := tree.GetHashFn()
+	pubkeysRoot := hFn.ComplexVectorHTR(func(i uint64) tree.HTR {
+		return &sc.Pubkeys[i]
+	}, uint64(len(sc.Pubkeys)))
+	return hFn(pubkeysRoot, sc.AggregatePubkey.HashTreeRoot(hFn))
+}
+
 // ComputeDomain computes the BLS domain for sync committee signatures
 // domain = domain_type || fork_data_root[:28]
 // where fork_data_root = hash_tree_root(ForkData(fork_version, genesis_validators_root))
@@ -169,18 +289,28 @@ func ComputeDomain(domainType []byte, forkVersion []byte, genesisValidatorsRoot
 	return domain, nil
 }
 
+// ProofData is the calldata of Eth2LightClient.updateSyncCommittee for a Groth16 proof. Backend is
+// left empty, a proof data without backend being a Groth16 one.
 type ProofData struct {
-	Proof         []HexBytes `json:"proof"`
-	Commitments   []HexBytes `json:"commitments"`
-	CommitmentPok []HexBytes `json:"commitmentPok"`
+	Backend       ProofBackend `json:"backend,omitempty"`
+	Proof         []HexBytes   `json:"proof"`
+	Commitments   []HexBytes   `json:"commitments"`
+	CommitmentPok []HexBytes   `json:"commitmentPok"`
 }
 
+// CreateProofData splits a Groth16 proof serialized with MarshalSolidity. A proof of a circuit
+// compiled without commitments is the plain 8 words A, B, C, its Commitments and CommitmentPok
+// are then empty: such a proof is export only, for verifiers taking plain proofs, Eth2LightClient
+// taking proofs with commitments.
 func CreateProofData(proofSolidity []byte) *ProofData {
 	// A, B, C
 	proof := make([]HexBytes, 8)
 	for i := 0; i < len(proof); i++ {
 		proof[i] = proofSolidity[i*bn254_fr.Bytes : (i+1)*bn254_fr.Bytes]
 	}
+	if len(proofSolidity) == 8*bn254_fr.Bytes {
+		return &ProofData{Proof: proof, Commitments: []HexBytes{}, CommitmentPok: []HexBytes{}}
+	}
 
 	startIdx0 := 8*bn254_fr.Bytes + 4
 	commitments := make([]HexBytes, 4)
@@ -195,3 +325,26 @@ func CreateProofData(proofSolidity []byte) *ProofData {
 		CommitmentPok: commitments[2:4],
 	}
 }
+
+// PlonkProofData is the calldata of Eth2LightClient.updateSyncCommitteePlonk: the PLONK verifier
+// takes the proof as opaque bytes
+type PlonkProofData struct {
+	Backend ProofBackend `json:"backend"`
+	Proof   HexBytes     `json:"proof"`
+}
+
+// CreateProofDataFor splits a proof serialized with MarshalSolidity into the calldata expected
+// by the light client for the given backend (*ProofData or *PlonkProofData)
+func CreateProofDataFor(b ProofBackend, proofSolidity []byte) (any, error) {
+	switch b {
+	case BackendGroth16:
+		if len(proofSolidity) != 8*bn254_fr.Bytes && len(proofSolidity) < 8*bn254_fr.Bytes+4+4*bn254_fr.Bytes {
+			return nil, fmt.Errorf("groth16 proof too short: %d bytes", len(proofSolidity))
+		}
+		return CreateProofData(proofSolidity), nil
+	case BackendPlonk:
+		return &PlonkProofData{Backend: BackendPlonk, Proof: proofSolidity}, nil
+	default:
+		return nil, fmt.Errorf("unknown proof backend %q", b)
+	}
+}
@@ -0,0 +1,123 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden files instead of comparing against them:
+// go test ./types -run Golden -update
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestComputeScPubKeysHashWithMode(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	committee := update.Data.NextSyncCommittee
+	pubkeys := make([]bls12381.G1Affine, len(committee.Pubkeys))
+	full := sha256.New()
+	truncated := sha256.New()
+	for i := range committee.Pubkeys {
+		_, err := pubkeys[i].SetBytes(committee.Pubkeys[i][:])
+		require.NoError(t, err)
+		full.Write(committee.Pubkeys[i][:])
+		truncated.Write(committee.Pubkeys[i][32:])
+	}
+
+	// full mode is the plain hash of the serialized committee pubkeys
+	require.Equal(t, full.Sum(nil), sliceOf(ComputeScPubKeysHashWithMode(pubkeys, ScPubKeysHashFull)))
+
+	// truncated mode only hashes the 16 least significant bytes of each X
+	require.Equal(t, truncated.Sum(nil), sliceOf(ComputeScPubKeysHashWithMode(pubkeys, ScPubKeysHashTruncated)))
+	require.Equal(t, ComputeScPubKeysHash(pubkeys), ComputeScPubKeysHashWithMode(pubkeys, ScPubKeysHashTruncated))
+
+	mode, err := ParseScPubKeysHashMode(ScPubKeysHashFull.String())
+	require.NoError(t, err)
+	require.Equal(t, ScPubKeysHashFull, mode)
+	_, err = ParseScPubKeysHashMode("poseidon")
+	require.Error(t, err)
+}
+
+func TestAggregatePublicKeys_Infinity(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1104.json"))
+	require.NoError(t, err, "Failed to read file")
+	var update LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	var inf bls12381.G1Affine
+	inf.SetInfinity()
+	pubkeys := []zrntcommon.BLSPubkey{update.Data.NextSyncCommittee.Pubkeys[0], inf.Bytes(), update.Data.NextSyncCommittee.Pubkeys[1]}
+
+	// infinity is skipped and not counted
+	aggPubkey, count, err := AggregatePublicKeys(pubkeys, []bool{true, true, true})
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	expected, _, err := AggregatePublicKeys(pubkeys, []bool{true, false, true})
+	require.NoError(t, err)
+	require.True(t, expected.Equal(&aggPubkey))
+
+	// only the point at infinity participates
+	_, _, err = AggregatePublicKeys(pubkeys, []bool{false, true, false})
+	require.Error(t, err)
+	// nobody participates
+	_, _, err = AggregatePublicKeys(pubkeys, []bool{false, false, false})
+	require.Error(t, err)
+
+	// both hash modes serialize infinity as the circuit does: zero X, 0xc0 flags
+	infHash := ComputeScPubKeysHashWithMode([]bls12381.G1Affine{inf}, ScPubKeysHashFull)
+	require.Equal(t, sha256.Sum256(append([]byte{0xc0}, make([]byte, 47)...)), infHash)
+	infHash = ComputeScPubKeysHashWithMode([]bls12381.G1Affine{inf}, ScPubKeysHashTruncated)
+	require.Equal(t, sha256.Sum256(make([]byte, 16)), infHash)
+}
+
+func sliceOf(b [32]byte) []byte {
+	return b[:]
+}
+
+// TestProofDataGolden pins the JSON structure of the proof data consumed by the verifier scripts
+// (verifiers/eth2/test/utils.ts) and the relayer submissions, for a proof with deterministic bytes.
+func TestProofDataGolden(t *testing.T) {
+	proofSolidity := make([]byte, 8*32+4+4*32)
+	for i := range proofSolidity {
+		proofSolidity[i] = byte(i)
+	}
+
+	for _, b := range []ProofBackend{BackendGroth16, BackendPlonk} {
+		data, err := CreateProofDataFor(b, proofSolidity)
+		require.NoError(t, err)
+		got, err := json.MarshalIndent(data, "", "  ")
+		require.NoError(t, err)
+		got = append(got, '\n')
+
+		path := filepath.Join("testdata", "proof-data-"+string(b)+".golden.json")
+		if *updateGolden {
+			require.NoError(t, os.WriteFile(path, got, 0644))
+			continue
+		}
+		want, err := os.ReadFile(path)
+		require.NoError(t, err, "missing golden file, run with -update")
+		require.Equal(t, string(want), string(got), "%s changed", path)
+	}
+
+	// the checked-in proof data must still decode without unknown fields
+	f, err := os.Open(filepath.Join(rootDir, "data/proof-data.json"))
+	require.NoError(t, err)
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var proofData ProofData
+	require.NoError(t, dec.Decode(&proofData))
+	require.Len(t, proofData.Proof, 8)
+	require.Len(t, proofData.Commitments, 2)
+	require.Len(t, proofData.CommitmentPok, 2)
+}
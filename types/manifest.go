@@ -0,0 +1,282 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+)
+
+// ManifestFileName is the name of the artifact manifest written next to the compiled circuits
+const ManifestFileName = "manifest.json"
+
+// ModeCircuitName returns the manifest entry of an Eth2ScUpdateCircuit compiled for the given
+// ScPubKeysHashMode, next to the one of the relayer's mode, for consumers committing in that mode
+func ModeCircuitName(mode ScPubKeysHashMode) string {
+	return "Eth2ScUpdateCircuit-" + mode.String()
+}
+
+// ProofBackend is the gnark proof system a circuit is compiled and proved with
+type ProofBackend string
+
+const (
+	BackendGroth16 ProofBackend = "groth16"
+	BackendPlonk   ProofBackend = "plonk"
+)
+
+// ParseProofBackend parses a backend name, the empty string means Groth16.
+func ParseProofBackend(s string) (ProofBackend, error) {
+	switch ProofBackend(strings.ToLower(s)) {
+	case "", BackendGroth16:
+		return BackendGroth16, nil
+	case BackendPlonk:
+		return BackendPlonk, nil
+	default:
+		return "", fmt.Errorf("unknown proof backend %q", s)
+	}
+}
+
+// ID returns the gnark backend identifier
+func (b ProofBackend) ID() backend.ID {
+	switch b {
+	case BackendPlonk:
+		return backend.PLONK
+	default:
+		return backend.GROTH16
+	}
+}
+
+// VerifierType is the kind of verifier the circuit's verifying key is exported to
+type VerifierType string
+
+const (
+	// VerifierSolidity is a gnark generated Solidity verifier contract
+	VerifierSolidity VerifierType = "solidity"
+)
+
+// CircuitManifest describes the build artifacts of one compiled circuit.
+// Artifact paths are relative to the directory of the manifest.
+type CircuitManifest struct {
+	Name         string       `json:"name"`
+	Backend      ProofBackend `json:"backend"`
+	Curve        string       `json:"curve"`
+	Verifier     VerifierType `json:"verifier"`
+	CCS          string       `json:"ccs"`
+	PK           string       `json:"pk"`
+	VK           string       `json:"vk"`
+	Contract     string       `json:"contract,omitempty"`
+	Constraints  int          `json:"constraints"`
+	PublicInputs int          `json:"public_inputs"`
+	// GnarkVersion is the gnark module version which serialized the artifacts
+	GnarkVersion string            `json:"gnark_version,omitempty"`
+	Checksums    ArtifactChecksums `json:"checksums,omitempty"`
+}
+
+// ArtifactChecksums are the hex SHA-256 of the artifact files of a circuit, pinning its proving and
+// verifying keys to its constraint system
+type ArtifactChecksums struct {
+	CCS string `json:"ccs,omitempty"`
+	PK  string `json:"pk,omitempty"`
+	VK  string `json:"vk,omitempty"`
+}
+
+// GnarkVersion returns the version of the gnark module of the running binary, empty if unknown
+func GnarkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/consensys/gnark" {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// FileChecksum returns the hex SHA-256 of the file at path
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SetChecksums records the checksums of the artifacts of c, relative to dir, and the running gnark version
+func (c *CircuitManifest) SetChecksums(dir string) error {
+	var err error
+	if c.Checksums.CCS, err = FileChecksum(filepath.Join(dir, c.CCS)); err != nil {
+		return err
+	}
+	if c.Checksums.PK, err = FileChecksum(filepath.Join(dir, c.PK)); err != nil {
+		return err
+	}
+	if c.Checksums.VK, err = FileChecksum(filepath.Join(dir, c.VK)); err != nil {
+		return err
+	}
+	c.GnarkVersion = GnarkVersion()
+	return nil
+}
+
+// VerifyArtifacts checks the artifacts of c, relative to dir, before they are loaded to prove: the
+// constraint system and proving key must have the recorded checksums, and so must the verifying key if
+// it is there. It also refuses artifacts serialized by another gnark version. Entries of manifests
+// written before the checksums were recorded are accepted as is.
+func (c *CircuitManifest) VerifyArtifacts(dir string) error {
+	if err := c.checkGnarkVersion(); err != nil {
+		return err
+	}
+	if err := c.checkArtifact(dir, "constraint system", c.CCS, c.Checksums.CCS, false); err != nil {
+		return err
+	}
+	if err := c.checkArtifact(dir, "proving key", c.PK, c.Checksums.PK, false); err != nil {
+		return err
+	}
+	return c.checkArtifact(dir, "verifying key", c.VK, c.Checksums.VK, true)
+}
+
+// VerifyVerifyingKey checks the verifying key of c, relative to dir, before it is loaded without the
+// proving key: it must have the recorded checksum
+func (c *CircuitManifest) VerifyVerifyingKey(dir string) error {
+	if err := c.checkGnarkVersion(); err != nil {
+		return err
+	}
+	return c.checkArtifact(dir, "verifying key", c.VK, c.Checksums.VK, false)
+}
+
+// checkGnarkVersion refuses artifacts serialized by another gnark version than the running one
+func (c *CircuitManifest) checkGnarkVersion() error {
+	if running := GnarkVersion(); c.GnarkVersion != "" && running != "" && c.GnarkVersion != running {
+		return fmt.Errorf("circuit %s: artifacts built with gnark %s, running %s", c.Name, c.GnarkVersion, running)
+	}
+	return nil
+}
+
+// checkArtifact checks that file, relative to dir, has the checksum want, if recorded. An optional
+// file may be missing.
+func (c *CircuitManifest) checkArtifact(dir, kind, file, want string, optional bool) error {
+	if want == "" {
+		return nil
+	}
+	got, err := FileChecksum(filepath.Join(dir, file))
+	if optional && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("circuit %s: %w", c.Name, err)
+	}
+	if got != want {
+		return fmt.Errorf("circuit %s: %s %s does not match the manifest (sha256 %s, expected %s)", c.Name, kind, file, got, want)
+	}
+	return nil
+}
+
+// CurveID returns the gnark curve identifier of the circuit
+func (c *CircuitManifest) CurveID() (ecc.ID, error) {
+	return ecc.IDFromString(c.Curve)
+}
+
+// ArtifactManifest lists the compiled circuits of a build directory
+type ArtifactManifest struct {
+	// Network, Preset and Fork are the chain the circuits were built for, see CheckTarget. They are
+	// empty in the manifests of older builds, and Network in those built without -network.
+	Network  string            `json:"network,omitempty"`
+	Preset   string            `json:"preset,omitempty"`
+	Fork     string            `json:"fork,omitempty"`
+	Circuits []CircuitManifest `json:"circuits"`
+}
+
+// CheckTarget fails if the circuits of the manifest were built for another network, preset or fork
+// than the given ones. The fork fixes the next_sync_committee branch the circuits verify.
+func (m *ArtifactManifest) CheckTarget(network string, preset Preset, fork string) error {
+	if m.Network != "" && network != "" && !strings.EqualFold(m.Network, network) {
+		return fmt.Errorf("the artifacts were built for %s, not %s", m.Network, network)
+	}
+	if m.Preset != "" && m.Preset != preset.String() {
+		return fmt.Errorf("the artifacts were built for the %s preset, not %s", m.Preset, preset)
+	}
+	if m.Fork != "" && !strings.EqualFold(m.Fork, fork) {
+		return fmt.Errorf("the artifacts were built for %s, not %s", m.Fork, fork)
+	}
+	return nil
+}
+
+// LoadArtifactManifest reads the manifest at path
+func LoadArtifactManifest(path string) (*ArtifactManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseArtifactManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseArtifactManifest parses a manifest and checks the backend and curve of its circuits
+func ParseArtifactManifest(data []byte) (*ArtifactManifest, error) {
+	var m ArtifactManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for i := range m.Circuits {
+		if _, err := ParseProofBackend(string(m.Circuits[i].Backend)); err != nil {
+			return nil, fmt.Errorf("circuit %s: %w", m.Circuits[i].Name, err)
+		}
+		if _, err := m.Circuits[i].CurveID(); err != nil {
+			return nil, fmt.Errorf("circuit %s: %w", m.Circuits[i].Name, err)
+		}
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to path
+func (m *ArtifactManifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Circuit returns the entry of the named circuit
+func (m *ArtifactManifest) Circuit(name string) (*CircuitManifest, error) {
+	for i := range m.Circuits {
+		if m.Circuits[i].Name == name {
+			return &m.Circuits[i], nil
+		}
+	}
+	return nil, fmt.Errorf("circuit %s not found in manifest", name)
+}
+
+// Set adds the entry of a circuit, or replaces the one with the same name
+func (m *ArtifactManifest) Set(c CircuitManifest) {
+	for i := range m.Circuits {
+		if m.Circuits[i].Name == c.Name {
+			m.Circuits[i] = c
+			return
+		}
+	}
+	m.Circuits = append(m.Circuits, c)
+}
@@ -0,0 +1,121 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".build", ManifestFileName)
+
+	m := &ArtifactManifest{}
+	m.Set(CircuitManifest{Name: "Eth2ScUpdateCircuit", Backend: BackendGroth16, Curve: ecc.BN254.String(), Verifier: VerifierSolidity})
+	m.Set(CircuitManifest{Name: "Eth2ScAggregationCircuit", Backend: BackendGroth16, Curve: ecc.BN254.String(), Verifier: VerifierSolidity})
+	m.Set(CircuitManifest{Name: "Eth2ScUpdateCircuit", Backend: BackendPlonk, Curve: ecc.BN254.String(), Verifier: VerifierSolidity})
+	require.Len(t, m.Circuits, 2)
+	require.NoError(t, m.Save(path))
+
+	loaded, err := LoadArtifactManifest(path)
+	require.NoError(t, err)
+	c, err := loaded.Circuit("Eth2ScUpdateCircuit")
+	require.NoError(t, err)
+	require.Equal(t, BackendPlonk, c.Backend)
+	require.Equal(t, backend.PLONK, c.Backend.ID())
+	curve, err := c.CurveID()
+	require.NoError(t, err)
+	require.Equal(t, ecc.BN254, curve)
+
+	_, err = loaded.Circuit("Unknown")
+	require.Error(t, err)
+
+	// the network, preset and fork recorded by setup_circuit.go
+	require.NoError(t, loaded.CheckTarget("holesky", PresetMinimal, "electra"))
+	loaded.Network, loaded.Preset, loaded.Fork = "sepolia", "mainnet", "fulu"
+	require.NoError(t, loaded.CheckTarget("Sepolia", PresetMainnet, "fulu"))
+	require.NoError(t, loaded.CheckTarget("", PresetMainnet, "fulu"))
+	require.Error(t, loaded.CheckTarget("holesky", PresetMainnet, "fulu"))
+	require.Error(t, loaded.CheckTarget("sepolia", PresetMinimal, "fulu"))
+	require.Error(t, loaded.CheckTarget("sepolia", PresetMainnet, "electra"))
+
+	m.Set(CircuitManifest{Name: "Broken", Backend: "stark", Curve: ecc.BN254.String()})
+	require.NoError(t, m.Save(path))
+	_, err = LoadArtifactManifest(path)
+	require.Error(t, err)
+}
+
+func TestCircuitManifestChecksums(t *testing.T) {
+	dir := t.TempDir()
+	c := CircuitManifest{Name: "Eth2ScUpdateCircuit", CCS: "c.ccs", PK: "c.pk", VK: "c.vk"}
+	for _, file := range []string{c.CCS, c.PK, c.VK} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(file), 0644))
+	}
+	// entries without checksums are accepted as is
+	require.NoError(t, c.VerifyArtifacts(dir))
+
+	require.NoError(t, c.SetChecksums(dir))
+	require.Len(t, c.Checksums.PK, 64)
+	require.Equal(t, GnarkVersion(), c.GnarkVersion)
+	require.NoError(t, c.VerifyArtifacts(dir))
+
+	// the verifying key is optional on a prover
+	require.NoError(t, os.Remove(filepath.Join(dir, c.VK)))
+	require.NoError(t, c.VerifyArtifacts(dir))
+
+	// a proving key of another setup
+	require.NoError(t, os.WriteFile(filepath.Join(dir, c.PK), []byte("other"), 0644))
+	require.Error(t, c.VerifyArtifacts(dir))
+	require.NoError(t, os.Remove(filepath.Join(dir, c.PK)))
+	require.Error(t, c.VerifyArtifacts(dir))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, c.PK), []byte(c.PK), 0644))
+	require.NoError(t, c.VerifyArtifacts(dir))
+
+	// artifacts of another gnark version, when the running one is known
+	c.GnarkVersion = "v0.0.1"
+	if GnarkVersion() != "" {
+		require.Error(t, c.VerifyArtifacts(dir))
+	}
+}
+
+func TestParseProofBackend(t *testing.T) {
+	for s, want := range map[string]ProofBackend{"": BackendGroth16, "groth16": BackendGroth16, "PLONK": BackendPlonk} {
+		b, err := ParseProofBackend(s)
+		require.NoError(t, err)
+		require.Equal(t, want, b)
+	}
+	_, err := ParseProofBackend("fflonk")
+	require.Error(t, err)
+}
+
+func TestCreateProofDataFor(t *testing.T) {
+	proofSolidity := make([]byte, 8*32+4+4*32)
+	for i := range proofSolidity {
+		proofSolidity[i] = byte(i)
+	}
+
+	data, err := CreateProofDataFor(BackendGroth16, proofSolidity)
+	require.NoError(t, err)
+	groth16Data := data.(*ProofData)
+	require.Empty(t, groth16Data.Backend)
+	require.Len(t, groth16Data.Proof, 8)
+	require.Equal(t, HexBytes(proofSolidity[8*32+4:9*32+4]), groth16Data.Commitments[0])
+
+	data, err = CreateProofDataFor(BackendPlonk, proofSolidity)
+	require.NoError(t, err)
+	require.Equal(t, HexBytes(proofSolidity), data.(*PlonkProofData).Proof)
+
+	// a proof without commitments
+	data, err = CreateProofDataFor(BackendGroth16, proofSolidity[:8*32])
+	require.NoError(t, err)
+	require.Len(t, data.(*ProofData).Proof, 8)
+	require.Empty(t, data.(*ProofData).Commitments)
+
+	_, err = CreateProofDataFor(BackendGroth16, proofSolidity[:100])
+	require.Error(t, err)
+	_, err = CreateProofDataFor(BackendGroth16, proofSolidity[:8*32+4])
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DomainSyncCommittee is DOMAIN_SYNC_COMMITTEE, the domain type of the sync committee signatures
+var DomainSyncCommittee = [4]byte{0x07, 0x00, 0x00, 0x00}
+
+// Network holds the parameters of a beacon chain the light client follows: its preset, which fixes
+// the circuit shape, what its signing domains are computed from, and the beacon API the relayer
+// fetches from by default.
+type Network struct {
+	Name   string
+	Preset Preset
+	// SlotDuration is SECONDS_PER_SLOT
+	SlotDuration          time.Duration
+	GenesisValidatorsRoot [32]byte
+	// ForkVersions maps the lower case fork names to their fork versions
+	ForkVersions map[string][4]byte
+	// BeaconAPI is the public beacon node used when no endpoint is configured, empty if there is none
+	BeaconAPI string
+}
+
+var (
+	// NetworkMainnet is Ethereum mainnet
+	NetworkMainnet = Network{
+		Name:                  "mainnet",
+		Preset:                PresetMainnet,
+		SlotDuration:          12 * time.Second,
+		BeaconAPI:             "https://lodestar-mainnet.chainsafe.io/",
+		GenesisValidatorsRoot: mustRoot("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		ForkVersions: map[string][4]byte{
+			"altair":    {0x01, 0x00, 0x00, 0x00},
+			"bellatrix": {0x02, 0x00, 0x00, 0x00},
+			"capella":   {0x03, 0x00, 0x00, 0x00},
+			"deneb":     {0x04, 0x00, 0x00, 0x00},
+			"electra":   {0x05, 0x00, 0x00, 0x00},
+			"fulu":      {0x06, 0x00, 0x00, 0x00},
+		},
+	}
+
+	// NetworkSepolia is the Sepolia testnet, whose Fulu domain is the default domain of the circuits
+	// and whose beacon API the relayer fetches from when neither a network nor an endpoint is configured
+	NetworkSepolia = Network{
+		Name:                  "sepolia",
+		Preset:                PresetMainnet,
+		SlotDuration:          12 * time.Second,
+		BeaconAPI:             "https://lodestar-sepolia.chainsafe.io/",
+		GenesisValidatorsRoot: mustRoot("0xd8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078"),
+		ForkVersions: map[string][4]byte{
+			"altair":    {0x90, 0x00, 0x00, 0x70},
+			"bellatrix": {0x90, 0x00, 0x00, 0x71},
+			"capella":   {0x90, 0x00, 0x00, 0x72},
+			"deneb":     {0x90, 0x00, 0x00, 0x73},
+			"electra":   {0x90, 0x00, 0x00, 0x74},
+			"fulu":      {0x90, 0x00, 0x00, 0x75},
+		},
+	}
+
+	// NetworkHolesky is the Holesky testnet
+	NetworkHolesky = Network{
+		Name:                  "holesky",
+		Preset:                PresetMainnet,
+		SlotDuration:          12 * time.Second,
+		BeaconAPI:             "https://lodestar-holesky.chainsafe.io/",
+		GenesisValidatorsRoot: mustRoot("0x9143aa7c615a7f7115e2b6aac319c03529df8242ae705fba9df39b79c59fa8b1"),
+		ForkVersions: map[string][4]byte{
+			"altair":    {0x02, 0x01, 0x70, 0x00},
+			"bellatrix": {0x03, 0x01, 0x70, 0x00},
+			"capella":   {0x04, 0x01, 0x70, 0x00},
+			"deneb":     {0x05, 0x01, 0x70, 0x00},
+			"electra":   {0x06, 0x01, 0x70, 0x00},
+			"fulu":      {0x07, 0x01, 0x70, 0x00},
+		},
+	}
+
+	// NetworkDevnet is a local minimal-preset devnet (e.g. kurtosis). Its genesis validators root and
+	// fork version differ per devnet, see WithGenesis.
+	NetworkDevnet = Network{
+		Name:         "devnet",
+		Preset:       PresetMinimal,
+		SlotDuration: 6 * time.Second,
+	}
+
+	// NetworkGnosis is Gnosis Chain (GBC), 5 seconds slots with the gnosis preset. Gnosis has not
+	// scheduled fulu, its version is added once the Gnosis config specifies one.
+	NetworkGnosis = Network{
+		Name:                  "gnosis",
+		Preset:                PresetGnosis,
+		SlotDuration:          5 * time.Second,
+		GenesisValidatorsRoot: mustRoot("0xf5dcb5564e829aab27264b9becd5dfaa017085611224cb3036f573368dbb9d47"),
+		ForkVersions: map[string][4]byte{
+			"altair":    {0x01, 0x00, 0x00, 0x64},
+			"bellatrix": {0x02, 0x00, 0x00, 0x64},
+			"capella":   {0x03, 0x00, 0x00, 0x64},
+			"deneb":     {0x04, 0x00, 0x00, 0x64},
+			"electra":   {0x05, 0x00, 0x00, 0x64},
+		},
+	}
+)
+
+// mustRoot decodes the hex of a 32 bytes root
+func mustRoot(s string) [32]byte {
+	b, err := HexToBytes(s)
+	if err != nil || len(b) != 32 {
+		panic(fmt.Errorf("invalid root %q", s))
+	}
+	return [32]byte(b)
+}
+
+// NetworkByName returns the known network of the given name
+func NetworkByName(name string) (*Network, error) {
+	for _, n := range []*Network{&NetworkMainnet, &NetworkSepolia, &NetworkHolesky, &NetworkGnosis, &NetworkDevnet} {
+		if strings.EqualFold(n.Name, name) {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown network %q", name)
+}
+
+// SyncCommitteeDomain returns the sync committee signing domain of the network at the given fork,
+// the value of the public Domain input
+func (n *Network) SyncCommitteeDomain(fork string) ([32]byte, error) {
+	if n.GenesisValidatorsRoot == ([32]byte{}) {
+		return [32]byte{}, fmt.Errorf("the genesis validators root of %s is not known", n.Name)
+	}
+	version, ok := n.ForkVersions[strings.ToLower(fork)]
+	if !ok {
+		return [32]byte{}, fmt.Errorf("unknown fork %q of %s", fork, n.Name)
+	}
+	return ComputeDomain(DomainSyncCommittee[:], version[:], n.GenesisValidatorsRoot[:])
+}
+
+// WithGenesis returns a copy of the network with the given genesis validators root, unless zero, and
+// version of fork, unless zero: the parameters of a devnet, or of a chain forking at a new version
+func (n Network) WithGenesis(root [32]byte, fork string, version [4]byte) *Network {
+	if root != ([32]byte{}) {
+		n.GenesisValidatorsRoot = root
+	}
+	if version != ([4]byte{}) {
+		versions := make(map[string][4]byte, len(n.ForkVersions)+1)
+		for name, v := range n.ForkVersions {
+			versions[name] = v
+		}
+		versions[strings.ToLower(fork)] = version
+		n.ForkVersions = versions
+	}
+	return &n
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork(t *testing.T) {
+	// the default domain of the circuits
+	domain, err := NetworkSepolia.SyncCommitteeDomain("fulu")
+	require.NoError(t, err)
+	require.Equal(t, "07000000f52c15272cff99835cd05aa522af469210b5b2c8807e372b6b9ca539", HexBytes(domain[:]).String())
+
+	gnosis, err := NetworkByName("Gnosis")
+	require.NoError(t, err)
+	require.Equal(t, PresetGnosis, gnosis.Preset)
+	require.Equal(t, 512, gnosis.Preset.SyncCommitteeSize())
+	require.Equal(t, uint64(1), gnosis.Preset.Period(16*512))
+	require.Equal(t, uint64(1), gnosis.Preset.Epoch(16))
+	require.Equal(t, uint64(16*512), gnosis.Preset.FirstSlot(1))
+	require.Equal(t, 5*time.Second, gnosis.SlotDuration)
+
+	electra, err := gnosis.SyncCommitteeDomain("electra")
+	require.NoError(t, err)
+	expected, err := ComputeDomain(DomainSyncCommittee[:], []byte{0x05, 0x00, 0x00, 0x64}, gnosis.GenesisValidatorsRoot[:])
+	require.NoError(t, err)
+	require.Equal(t, expected, electra)
+	require.NotEqual(t, domain, electra)
+
+	_, err = gnosis.SyncCommitteeDomain("fulu")
+	require.Error(t, err)
+	_, err = gnosis.SyncCommitteeDomain("phase0")
+	require.Error(t, err)
+	_, err = NetworkByName("goerli")
+	require.Error(t, err)
+
+	holesky, err := NetworkByName("holesky")
+	require.NoError(t, err)
+	fulu, err := holesky.SyncCommitteeDomain("fulu")
+	require.NoError(t, err)
+	expected, err = ComputeDomain(DomainSyncCommittee[:], []byte{0x07, 0x01, 0x70, 0x00}, holesky.GenesisValidatorsRoot[:])
+	require.NoError(t, err)
+	require.Equal(t, expected, fulu)
+
+	// a devnet has the domain of its genesis
+	devnet, err := NetworkByName("devnet")
+	require.NoError(t, err)
+	require.Equal(t, PresetMinimal, devnet.Preset)
+	_, err = devnet.SyncCommitteeDomain("fulu")
+	require.Error(t, err)
+	root := [32]byte{0x01}
+	configured := devnet.WithGenesis(root, "Fulu", [4]byte{0x60, 0x00, 0x00, 0x38})
+	require.Empty(t, NetworkDevnet.ForkVersions)
+	domain, err = configured.SyncCommitteeDomain("fulu")
+	require.NoError(t, err)
+	expected, err = ComputeDomain(DomainSyncCommittee[:], []byte{0x60, 0x00, 0x00, 0x38}, root[:])
+	require.NoError(t, err)
+	require.Equal(t, expected, domain)
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// PackedWordBytes is the size of the words packed into one public input: 128 bits always fit a BN254 scalar
+const PackedWordBytes = 16
+
+// HashedPreimageSize is the size of PackedPublicInputs.HashedPreimage
+const HashedPreimageSize = 4*32 + 3*8 + 64
+
+// PackedNbPublicInputs is the number of public inputs of Eth2ScUpdatePackedCircuit, against one per byte
+// (131) for Eth2ScUpdateCircuit
+const PackedNbPublicInputs = 15
+
+// PackedPublicInputs are the public values of Eth2ScUpdatePackedCircuit.
+//
+// Every 32 bytes value is split in two big-endian 128 bits words (hi, lo), i.e.
+// uint256(value) >> 128 and uint128(value) in Solidity, and the 64 bytes of the SSZ
+// sync_committee_bits Bitvector[512] in four such words, zero padded for the 32 bits of the minimal preset. Encode returns them in the order of the
+// public inputs of the circuit.
+type PackedPublicInputs struct {
+	ScPubKeysHash   [32]byte
+	NextScRoot      [32]byte
+	Period          uint64
+	Domain          [32]byte
+	ExecBlockHash   [32]byte
+	ExecBlockNumber uint64
+	AttestedSlot    uint64
+	ScBits          [64]byte // sync_committee_bits, bit i is ScBits[i/8] >> (i%8) & 1
+}
+
+// PackWords splits b, whose length is a multiple of PackedWordBytes, into big-endian 128 bits words
+func PackWords(b []byte) ([]*big.Int, error) {
+	if len(b)%PackedWordBytes != 0 {
+		return nil, fmt.Errorf("%d bytes are not a whole number of %d bytes words", len(b), PackedWordBytes)
+	}
+	words := make([]*big.Int, len(b)/PackedWordBytes)
+	for i := range words {
+		words[i] = new(big.Int).SetBytes(b[i*PackedWordBytes : (i+1)*PackedWordBytes])
+	}
+	return words, nil
+}
+
+// SyncCommitteeBitsBytes is the inverse of ParseSyncCommitteeBits, the SSZ serialization of the 512 bits
+func SyncCommitteeBitsBytes(bits []bool) [64]byte {
+	var b [64]byte
+	for i := 0; i < len(bits) && i < 512; i++ {
+		if bits[i] {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+// Encode returns the PackedNbPublicInputs public inputs of Eth2ScUpdatePackedCircuit
+func (p *PackedPublicInputs) Encode() []*big.Int {
+	out := make([]*big.Int, 0, PackedNbPublicInputs)
+	pack := func(b []byte) {
+		words, _ := PackWords(b) // whole words by construction
+		out = append(out, words...)
+	}
+	pack(p.ScPubKeysHash[:])
+	pack(p.NextScRoot[:])
+	out = append(out, new(big.Int).SetUint64(p.Period))
+	pack(p.Domain[:])
+	pack(p.ExecBlockHash[:])
+	out = append(out, new(big.Int).SetUint64(p.ExecBlockNumber), new(big.Int).SetUint64(p.AttestedSlot))
+	pack(p.ScBits[:])
+	return out
+}
+
+// ParticipantCount returns the number of bits set in ScBits, for the 2/3 participation check of the
+// verifier contract
+func (p *PackedPublicInputs) ParticipantCount() int {
+	n := 0
+	for _, b := range p.ScBits {
+		for ; b != 0; b &= b - 1 {
+			n++
+		}
+	}
+	return n
+}
+
+// HashedPreimage returns the public values hashed by Eth2ScUpdateHashedCircuit, which is
+// abi.encodePacked(ScPubKeysHash, NextScRoot, uint64(Period), Domain, ExecBlockHash,
+// uint64(ExecBlockNumber), uint64(AttestedSlot), ScBits) in Solidity
+func (p *PackedPublicInputs) HashedPreimage() []byte {
+	b := make([]byte, 0, HashedPreimageSize)
+	b = append(b, p.ScPubKeysHash[:]...)
+	b = append(b, p.NextScRoot[:]...)
+	b = binary.BigEndian.AppendUint64(b, p.Period)
+	b = append(b, p.Domain[:]...)
+	b = append(b, p.ExecBlockHash[:]...)
+	b = binary.BigEndian.AppendUint64(b, p.ExecBlockNumber)
+	b = binary.BigEndian.AppendUint64(b, p.AttestedSlot)
+	b = append(b, p.ScBits[:]...)
+	return b
+}
+
+// InputsHash returns the only public input of Eth2ScUpdateHashedCircuit, sha256(HashedPreimage()) with its
+// 3 most significant bits cleared to fit a BN254 scalar:
+// uint256(sha256(abi.encodePacked(...))) & ((1 << 253) - 1) in Solidity
+func (p *PackedPublicInputs) InputsHash() *big.Int {
+	digest := sha256.Sum256(p.HashedPreimage())
+	digest[0] &= 0x1f
+	return new(big.Int).SetBytes(digest[:])
+}
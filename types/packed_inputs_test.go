@@ -0,0 +1,59 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackedPublicInputs(t *testing.T) {
+	p := &PackedPublicInputs{Period: 1105, ExecBlockNumber: 22_000_000, AttestedSlot: 1105*8192 + 7}
+	for i := 0; i < 32; i++ {
+		p.ScPubKeysHash[i] = byte(i)
+		p.NextScRoot[i] = byte(0x80 + i)
+		p.Domain[i] = 0xff
+		p.ExecBlockHash[i] = byte(255 - i)
+	}
+	bits := make([]bool, 512)
+	for i := range bits {
+		bits[i] = i%3 != 0
+	}
+	p.ScBits = SyncCommitteeBitsBytes(bits)
+	require.Equal(t, bits, ParseSyncCommitteeBits(p.ScBits[:]))
+	require.Equal(t, 341, p.ParticipantCount())
+
+	inputs := p.Encode()
+	require.Len(t, inputs, PackedNbPublicInputs)
+	hex := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(s, 16)
+		require.True(t, ok)
+		return v
+	}
+	require.Equal(t, hex("000102030405060708090a0b0c0d0e0f"), inputs[0])
+	require.Equal(t, hex("101112131415161718191a1b1c1d1e1f"), inputs[1])
+	require.Equal(t, hex("808182838485868788898a8b8c8d8e8f"), inputs[2])
+	require.Equal(t, big.NewInt(1105), inputs[4])
+	require.Equal(t, hex("ffffffffffffffffffffffffffffffff"), inputs[5])
+	require.Equal(t, hex("efeeedecebeae9e8e7e6e5e4e3e2e1e0"), inputs[8])
+	require.Equal(t, big.NewInt(22_000_000), inputs[9])
+	require.Equal(t, big.NewInt(1105*8192+7), inputs[10])
+	// bits 0, 3, 6 .. clear: 0b10110110, 0b01101101, 0b11011011, ..
+	require.Equal(t, hex("b66ddbb66ddbb66ddbb66ddbb66ddbb6"), inputs[11])
+	for _, v := range inputs {
+		require.LessOrEqual(t, v.BitLen(), 128)
+	}
+
+	preimage := p.HashedPreimage()
+	require.Len(t, preimage, HashedPreimageSize)
+	require.Equal(t, p.ScPubKeysHash[:], preimage[:32])
+	require.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0x04, 0x51}, preimage[64:72])
+	require.Equal(t, p.ScBits[:], preimage[HashedPreimageSize-64:])
+	h := p.InputsHash()
+	require.LessOrEqual(t, h.BitLen(), 253)
+	p.AttestedSlot++
+	require.NotEqual(t, h, p.InputsHash())
+
+	_, err := PackWords(make([]byte, 31))
+	require.Error(t, err)
+}
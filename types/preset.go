@@ -0,0 +1,114 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/kysee/zk-chains/types/slots"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// Preset is the consensus preset of the chain the circuits are compiled for. It fixes the size of
+// the sync committee and the length of a sync committee period.
+type Preset uint8
+
+const (
+	// PresetMainnet is the preset of mainnet and of the public testnets
+	PresetMainnet Preset = iota
+	// PresetMinimal is the preset of the minimal-spec devnets (e.g. kurtosis): 32 members and periods of 8 epochs of 8 slots
+	PresetMinimal
+	// PresetGnosis is the preset of Gnosis Chain and Chiado: 512 members and periods of 512 epochs of 16 slots
+	PresetGnosis
+)
+
+func (p Preset) String() string {
+	switch p {
+	case PresetMainnet:
+		return "mainnet"
+	case PresetMinimal:
+		return "minimal"
+	case PresetGnosis:
+		return "gnosis"
+	default:
+		return fmt.Sprintf("Preset(%d)", uint8(p))
+	}
+}
+
+// ParsePreset parses the name returned by Preset.String.
+func ParsePreset(s string) (Preset, error) {
+	switch s {
+	case "", "mainnet":
+		return PresetMainnet, nil
+	case "minimal":
+		return PresetMinimal, nil
+	case "gnosis":
+		return PresetGnosis, nil
+	default:
+		return 0, fmt.Errorf("unknown preset: %q", s)
+	}
+}
+
+// SyncCommitteeSize is SYNC_COMMITTEE_SIZE
+func (p Preset) SyncCommitteeSize() int {
+	if p == PresetMinimal {
+		return 32
+	}
+	return 512
+}
+
+// SlotsPerEpochLog2 is log2(SLOTS_PER_EPOCH): 32 slots for mainnet, 8 for minimal and 16 for gnosis
+func (p Preset) SlotsPerEpochLog2() int {
+	switch p {
+	case PresetMinimal:
+		return 3
+	case PresetGnosis:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// SlotsPerPeriodLog2 is log2(SLOTS_PER_EPOCH * EPOCHS_PER_SYNC_COMMITTEE_PERIOD):
+// log2(32 * 256) for mainnet, log2(8 * 8) for minimal and log2(16 * 512) for gnosis
+func (p Preset) SlotsPerPeriodLog2() int {
+	if p == PresetMinimal {
+		return 6
+	}
+	return 13
+}
+
+// gnosisSpec is the mainnet spec with the slots and periods of Gnosis Chain, which zrnt does not ship
+var gnosisSpec = func() *common.Spec {
+	spec := *configs.Mainnet
+	spec.SLOTS_PER_EPOCH = 16
+	spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD = 512
+	spec.SECONDS_PER_SLOT = 5
+	return &spec
+}()
+
+// Spec returns the zrnt spec of the preset, whose lengths the slots package computes with
+func (p Preset) Spec() *common.Spec {
+	switch p {
+	case PresetMinimal:
+		return configs.Minimal
+	case PresetGnosis:
+		return gnosisSpec
+	default:
+		return configs.Mainnet
+	}
+}
+
+// Epoch returns the epoch of slot
+func (p Preset) Epoch(slot uint64) uint64 {
+	return slots.SlotToEpoch(p.Spec(), slot)
+}
+
+// Period returns the sync committee period of slot
+func (p Preset) Period(slot uint64) uint64 {
+	return slots.SlotToPeriod(p.Spec(), slot)
+}
+
+// FirstSlot returns the first slot of period
+func (p Preset) FirstSlot(period uint64) uint64 {
+	return slots.PeriodStartSlot(p.Spec(), period)
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreset(t *testing.T) {
+	require.Equal(t, 512, PresetMainnet.SyncCommitteeSize())
+	require.Equal(t, 32, PresetMinimal.SyncCommitteeSize())
+	require.Equal(t, uint64(1105), PresetMainnet.Period(9052234))
+	require.Equal(t, uint64(1), PresetMinimal.Period(64))
+	require.Equal(t, uint64(0), PresetMinimal.Period(63))
+
+	// the spec of each preset agrees with the lengths the circuits are compiled for
+	for _, p := range []Preset{PresetMainnet, PresetMinimal, PresetGnosis} {
+		spec := p.Spec()
+		require.Equal(t, uint64(1)<<p.SlotsPerEpochLog2(), uint64(spec.SLOTS_PER_EPOCH), p)
+		require.Equal(t, uint64(1)<<p.SlotsPerPeriodLog2(), uint64(spec.SLOTS_PER_EPOCH)*uint64(spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD), p)
+		require.Equal(t, uint64(p.SyncCommitteeSize()), uint64(spec.SYNC_COMMITTEE_SIZE), p)
+	}
+	require.Equal(t, uint64(5), uint64(PresetGnosis.Spec().SECONDS_PER_SLOT))
+
+	for _, p := range []Preset{PresetMainnet, PresetMinimal, PresetGnosis} {
+		parsed, err := ParsePreset(p.String())
+		require.NoError(t, err)
+		require.Equal(t, p, parsed)
+	}
+	_, err := ParsePreset("holesky")
+	require.Error(t, err)
+}
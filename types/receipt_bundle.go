@@ -0,0 +1,28 @@
+package types
+
+// TxStatusBundle is everything needed to prove that a transaction succeeded within a gas budget:
+// the header committing to the receipts root, the proof of the receipt and, for the gas used, the
+// proof of the receipt of the previous transaction of the block, along with the decoded fields.
+type TxStatusBundle struct {
+	BlockHash    HexBytes `json:"blockHash"`
+	BlockNumber  uint64   `json:"blockNumber"`
+	Header       HexBytes `json:"header"` // RLP encoded header, keccak256(Header) == BlockHash
+	ReceiptsRoot HexBytes `json:"receiptsRoot"`
+
+	TxHash       HexBytes   `json:"txHash"`
+	TxIndex      uint64     `json:"txIndex"`
+	Receipt      HexBytes   `json:"receipt"`      // consensus encoding of the receipt, the value of its leaf
+	ReceiptProof []HexBytes `json:"receiptProof"` // nodes from the receipts root to the leaf at rlp(TxIndex)
+
+	// The receipt of transaction TxIndex-1, absent for the first transaction of the block
+	PrevReceipt      HexBytes   `json:"prevReceipt,omitempty"`
+	PrevReceiptProof []HexBytes `json:"prevReceiptProof,omitempty"`
+
+	// Decoded fields: GasUsed is CumulativeGasUsed - PrevCumulativeGasUsed
+	Status                uint64 `json:"status"`
+	CumulativeGasUsed     uint64 `json:"cumulativeGasUsed"`
+	PrevCumulativeGasUsed uint64 `json:"prevCumulativeGasUsed"`
+	GasUsed               uint64 `json:"gasUsed"`
+	// MaxGas is the budget the transaction is proven to stay within
+	MaxGas uint64 `json:"maxGas"`
+}
@@ -0,0 +1,69 @@
+// Package slots converts between the slots, epochs and sync committee periods of a beacon chain, and
+// estimates when the update of a period can be proven. The lengths are those of the zrnt spec given,
+// see types.Preset.Spec for the one of each preset.
+package slots
+
+import (
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// FinalityEpochs is how many epochs the first epoch of a period takes to be finalized, once the
+// chain finalizes normally
+const FinalityEpochs = 2
+
+// SlotsPerEpoch is SLOTS_PER_EPOCH
+func SlotsPerEpoch(spec *common.Spec) uint64 {
+	return uint64(spec.SLOTS_PER_EPOCH)
+}
+
+// SlotsPerPeriod is SLOTS_PER_EPOCH * EPOCHS_PER_SYNC_COMMITTEE_PERIOD: 8192 on mainnet
+func SlotsPerPeriod(spec *common.Spec) uint64 {
+	return uint64(spec.SLOTS_PER_EPOCH) * uint64(spec.EPOCHS_PER_SYNC_COMMITTEE_PERIOD)
+}
+
+// SlotToEpoch returns the epoch of slot
+func SlotToEpoch(spec *common.Spec, slot uint64) uint64 {
+	return slot / SlotsPerEpoch(spec)
+}
+
+// SlotToPeriod returns the sync committee period of slot
+func SlotToPeriod(spec *common.Spec, slot uint64) uint64 {
+	return slot / SlotsPerPeriod(spec)
+}
+
+// EpochStartSlot returns the first slot of epoch
+func EpochStartSlot(spec *common.Spec, epoch uint64) uint64 {
+	return epoch * SlotsPerEpoch(spec)
+}
+
+// PeriodStartSlot returns the first slot of period
+func PeriodStartSlot(spec *common.Spec, period uint64) uint64 {
+	return period * SlotsPerPeriod(spec)
+}
+
+// PeriodFinalizedSlot returns the slot at which the first epoch of period is expected to be
+// finalized, and the finalized update of period to be available
+func PeriodFinalizedSlot(spec *common.Spec, period uint64) uint64 {
+	return PeriodStartSlot(spec, period) + EpochStartSlot(spec, FinalityEpochs)
+}
+
+// SlotDuration is SECONDS_PER_SLOT
+func SlotDuration(spec *common.Spec) time.Duration {
+	return time.Duration(spec.SECONDS_PER_SLOT) * time.Second
+}
+
+// FinalizationDelay returns how long after the slot head the first epoch of period is expected to be
+// finalized (see PeriodFinalizedSlot), 0 if it was reached. Slots last slotDuration, or SECONDS_PER_SLOT
+// if it is not positive.
+func FinalizationDelay(spec *common.Spec, head, period uint64, slotDuration time.Duration) time.Duration {
+	finalized := PeriodFinalizedSlot(spec, period)
+	if head >= finalized {
+		return 0
+	}
+	if slotDuration <= 0 {
+		slotDuration = SlotDuration(spec)
+	}
+	return time.Duration(finalized-head) * slotDuration
+}
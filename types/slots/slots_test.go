@@ -0,0 +1,31 @@
+package slots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlots(t *testing.T) {
+	mainnet, minimal := configs.Mainnet, configs.Minimal
+	require.Equal(t, uint64(8192), SlotsPerPeriod(mainnet))
+	require.Equal(t, uint64(64), SlotsPerPeriod(minimal))
+
+	// the attested slot of the update of period 1105 in data/
+	require.Equal(t, uint64(1105), SlotToPeriod(mainnet, 9052234))
+	require.Equal(t, uint64(282882), SlotToEpoch(mainnet, 9052234))
+	require.Equal(t, uint64(9052160), PeriodStartSlot(mainnet, 1105))
+	require.Equal(t, uint64(9052160), EpochStartSlot(mainnet, 282880))
+	require.Equal(t, uint64(1), SlotToPeriod(minimal, 64))
+	require.Equal(t, uint64(0), SlotToPeriod(minimal, 63))
+
+	// the update of period 1106 is expected 2 epochs after its first slot, 9060352
+	require.Equal(t, uint64(9060416), PeriodFinalizedSlot(mainnet, 1106))
+	require.Equal(t, 416*12*time.Second, FinalizationDelay(mainnet, 9_060_000, 1106, 0))
+	require.Equal(t, 416*5*time.Second, FinalizationDelay(mainnet, 9_060_000, 1106, 5*time.Second))
+	require.Zero(t, FinalizationDelay(mainnet, 9_060_416, 1106, 0))
+	require.Equal(t, 6*time.Second, SlotDuration(minimal))
+	require.Equal(t, uint64(64+16), PeriodFinalizedSlot(minimal, 1))
+}
@@ -0,0 +1,181 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// SSZProof is a Merkle proof for a single node of an SSZ hash tree.
+type SSZProof struct {
+	GIndex GIndex            // generalized index of the leaf
+	Leaf   zrntcommon.Root   // root of the node at GIndex
+	Branch []zrntcommon.Root // sibling roots, ordered from the leaf up to the root
+}
+
+// GenerateSSZProof walks the tree from root down to the node at the given
+// generalized index and returns the leaf with its branch.
+//
+// The branch is ordered bottom-up, as in the consensus spec
+// (e.g. next_sync_committee_branch), so branch[0] is the sibling of the leaf.
+func GenerateSSZProof(root tree.Node, gindex GIndex, hFn tree.HashFn) (*SSZProof, error) {
+	if !gindex.IsValid() {
+		return nil, fmt.Errorf("invalid generalized index: %d", gindex)
+	}
+
+	depth := gindex.Depth()
+	path := gindex.PathBits()
+	branch := make([]zrntcommon.Root, depth)
+
+	node := root
+	for level := depth - 1; level >= 0; level-- {
+		left, err := node.Left()
+		if err != nil {
+			return nil, fmt.Errorf("gindex %d: failed to navigate at depth %d: %w", gindex, depth-level, err)
+		}
+		right, err := node.Right()
+		if err != nil {
+			return nil, fmt.Errorf("gindex %d: failed to navigate at depth %d: %w", gindex, depth-level, err)
+		}
+
+		// bit 1 means the path goes to the right child, the sibling is on the left
+		if path[level] == 1 {
+			branch[level] = left.MerkleRoot(hFn)
+			node = right
+		} else {
+			branch[level] = right.MerkleRoot(hFn)
+			node = left
+		}
+	}
+
+	return &SSZProof{
+		GIndex: gindex,
+		Leaf:   node.MerkleRoot(hFn),
+		Branch: branch,
+	}, nil
+}
+
+// GenerateSSZProofFromView generates the proof of the node at gindex in the tree backing the view.
+func GenerateSSZProofFromView(v view.View, gindex GIndex) (*SSZProof, error) {
+	return GenerateSSZProof(v.Backing(), gindex, tree.GetHashFn())
+}
+
+// ViewFromSpecObj re-decodes an SSZ object (e.g. a BeaconBlockBody parsed from JSON)
+// into a tree-backed view of the given type, so that proofs can be generated from it.
+func ViewFromSpecObj(spec *zrntcommon.Spec, typ view.TypeDef, obj zrntcommon.SpecObj) (view.View, error) {
+	var buf bytes.Buffer
+	if err := obj.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		return nil, fmt.Errorf("failed to serialize object: %w", err)
+	}
+	return ViewFromSSZ(typ, buf.Bytes())
+}
+
+// ViewFromSSZ decodes SSZ bytes into a tree-backed view of the given type.
+func ViewFromSSZ(typ view.TypeDef, data []byte) (view.View, error) {
+	v, err := typ.Deserialize(codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize SSZ: %w", err)
+	}
+	return v, nil
+}
+
+// BeaconStateProof generates a proof for the node at gindex in an SSZ-encoded (Electra) BeaconState.
+func BeaconStateProof(spec *zrntcommon.Spec, stateSSZ []byte, gindex GIndex) (*SSZProof, error) {
+	v, err := ViewFromSSZ(electra.BeaconStateType(spec), stateSSZ)
+	if err != nil {
+		return nil, fmt.Errorf("beacon state: %w", err)
+	}
+	return GenerateSSZProofFromView(v, gindex)
+}
+
+// BeaconBlockBodyProof generates a proof for the node at gindex in the given BeaconBlockBody.
+//
+// The body tree is assembled from the field roots of the struct rather than from
+// electra.BeaconBlockBodyType, whose attester_slashings limit still follows phase0
+// and yields a different body root. The execution_payload subtree is expanded so
+// gindices pointing inside the payload (e.g. receipts_root) resolve as well.
+func BeaconBlockBodyProof(spec *zrntcommon.Spec, body *electra.BeaconBlockBody, gindex GIndex) (*SSZProof, error) {
+	hFn := tree.GetHashFn()
+
+	payload, err := ViewFromSpecObj(spec, deneb.ExecutionPayloadType(spec), &body.ExecutionPayload)
+	if err != nil {
+		return nil, fmt.Errorf("execution payload: %w", err)
+	}
+
+	fields := []tree.Node{
+		rootNode(body.RandaoReveal.HashTreeRoot(hFn)),
+		rootNode(body.Eth1Data.HashTreeRoot(hFn)),
+		rootNode(body.Graffiti),
+		rootNode(body.ProposerSlashings.HashTreeRoot(spec, hFn)),
+		rootNode(body.AttesterSlashings.HashTreeRoot(spec, hFn)),
+		rootNode(body.Attestations.HashTreeRoot(spec, hFn)),
+		rootNode(body.Deposits.HashTreeRoot(spec, hFn)),
+		rootNode(body.VoluntaryExits.HashTreeRoot(spec, hFn)),
+		rootNode(body.SyncAggregate.HashTreeRoot(spec, hFn)),
+		payload.Backing(),
+		rootNode(body.BLSToExecutionChanges.HashTreeRoot(spec, hFn)),
+		rootNode(body.BlobKZGCommitments.HashTreeRoot(spec, hFn)),
+		rootNode(body.ExecutionRequests.HashTreeRoot(spec, hFn)),
+	}
+	root, err := tree.SubtreeFillToContents(fields, tree.CoverDepth(uint64(len(fields))))
+	if err != nil {
+		return nil, fmt.Errorf("beacon block body: %w", err)
+	}
+	return GenerateSSZProof(root, gindex, hFn)
+}
+
+func rootNode(r zrntcommon.Root) tree.Node {
+	return &r
+}
+
+// BeaconBlockHeaderProof generates a proof for the node at gindex in the given BeaconBlockHeader.
+func BeaconBlockHeaderProof(header *zrntcommon.BeaconBlockHeader, gindex GIndex) (*SSZProof, error) {
+	return GenerateSSZProofFromView(header.View(), gindex)
+}
+
+// Verify checks the proof against the given root.
+func (p *SSZProof) Verify(root zrntcommon.Root) bool {
+	return VerifySSZBranch(root, p.Leaf, p.Branch, p.GIndex)
+}
+
+// ComputeSSZBranchRoot computes the root implied by a leaf at gindex and its bottom-up branch.
+// The branch length must equal the depth of gindex.
+func ComputeSSZBranchRoot(leaf zrntcommon.Root, branch []zrntcommon.Root, gindex GIndex) (zrntcommon.Root, error) {
+	if !gindex.IsValid() {
+		return zrntcommon.Root{}, fmt.Errorf("invalid generalized index: %d", gindex)
+	}
+	if depth := gindex.Depth(); len(branch) != depth {
+		return zrntcommon.Root{}, fmt.Errorf("branch length %d does not match depth %d of gindex %d", len(branch), depth, gindex)
+	}
+
+	hFn := tree.GetHashFn()
+	path := gindex.PathBits()
+	node := leaf
+	for i, sibling := range branch {
+		if path[i] == 1 {
+			// Current node is the right child, sibling is on the left
+			node = hFn(sibling, node)
+		} else {
+			// Current node is the left child, sibling is on the right
+			node = hFn(node, sibling)
+		}
+	}
+	return node, nil
+}
+
+// VerifySSZBranch verifies that leaf is the node at gindex of the tree with the given root.
+// This is is_valid_merkle_branch of the consensus spec, with depth and index derived from gindex:
+// depth = floorlog2(gindex), index = gindex % 2^depth.
+func VerifySSZBranch(root, leaf zrntcommon.Root, branch []zrntcommon.Root, gindex GIndex) bool {
+	computed, err := ComputeSSZBranchRoot(leaf, branch, gindex)
+	if err != nil {
+		return false
+	}
+	return computed == root
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSSZProof_BeaconBlockHeader(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	header := update.Data.AttestedHeader.Beacon
+	headerRoot := header.HashTreeRoot(tree.GetHashFn())
+
+	// BeaconBlockHeader has 5 fields -> depth 3, state_root is field 3 -> gindex 8 + 3 = 11
+	proof, err := BeaconBlockHeaderProof(&header, 11)
+	require.NoError(t, err)
+	require.Len(t, proof.Branch, 3)
+	require.Equal(t, header.StateRoot, proof.Leaf)
+	require.True(t, proof.Verify(headerRoot))
+
+	// body_root is field 4 -> gindex 12
+	proof, err = BeaconBlockHeaderProof(&header, 12)
+	require.NoError(t, err)
+	require.Equal(t, header.BodyRoot, proof.Leaf)
+	require.True(t, proof.Verify(headerRoot))
+}
+
+func TestGenerateSSZProof_BeaconBlockBody(t *testing.T) {
+	spec := configs.Mainnet
+	var body electra.BeaconBlockBody
+	body.Graffiti[0] = 0x42
+	body.SyncAggregate.SyncCommitteeBits = make(altair.SyncCommitteeBits, spec.SYNC_COMMITTEE_SIZE/8)
+	body.ExecutionPayload.ReceiptsRoot[0] = 0x01
+	bodyRoot := body.HashTreeRoot(spec, tree.GetHashFn())
+
+	// execution_payload is field 9 of the 13 (16 padded) body fields -> gindex 16 + 9 = 25
+	proof, err := BeaconBlockBodyProof(spec, &body, ExecutionPayloadGIndex)
+	require.NoError(t, err)
+	require.Len(t, proof.Branch, 4)
+	require.Equal(t, body.ExecutionPayload.HashTreeRoot(spec, tree.GetHashFn()), proof.Leaf)
+	require.True(t, proof.Verify(bodyRoot))
+
+	// a node deeper than the field level: execution_payload.receipts_root (field 3 of 17 -> 32 + 3)
+	deep, err := ConcatGIndices(ExecutionPayloadGIndex, 35)
+	require.NoError(t, err)
+	proof, err = BeaconBlockBodyProof(spec, &body, deep)
+	require.NoError(t, err)
+	require.Len(t, proof.Branch, 9)
+	require.Equal(t, body.ExecutionPayload.ReceiptsRoot, proof.Leaf)
+	require.True(t, proof.Verify(bodyRoot))
+
+	_, err = GenerateSSZProof(nil, 0, tree.GetHashFn())
+	require.Error(t, err)
+}
+
+func TestVerifySSZBranch_NextSyncCommittee(t *testing.T) {
+	updateFile, err := os.ReadFile(filepath.Join(rootDir, "data/sc-update-1105.json"))
+	require.NoError(t, err, "Failed to read light client update file")
+	var update LightClientUpdate
+	require.NoError(t, json.Unmarshal(updateFile, &update))
+
+	// next_sync_committee is at gindex 87 of the Electra/Fulu BeaconState
+	leaf := update.Data.NextSyncCommittee.HashTreeRoot(configs.Mainnet, tree.GetHashFn())
+	branch := update.Data.NextSyncCommitteeBranch[:]
+	stateRoot := update.Data.AttestedHeader.Beacon.StateRoot
+	require.True(t, VerifySSZBranch(stateRoot, leaf, branch, NextSyncCommitteeGIndexElectra))
+
+	// wrong gindex, wrong leaf, wrong root and truncated branches must fail
+	require.False(t, VerifySSZBranch(stateRoot, leaf, branch, 86))
+	require.False(t, VerifySSZBranch(stateRoot, zrntcommon.Root{}, branch, 87))
+	require.False(t, VerifySSZBranch(update.Data.AttestedHeader.Beacon.BodyRoot, leaf, branch, 87))
+	require.False(t, VerifySSZBranch(stateRoot, leaf, branch[:5], 87))
+	require.False(t, VerifySSZBranch(stateRoot, leaf, branch, 0))
+}
+
+func TestVerifySSZBranch_HandBuiltTree(t *testing.T) {
+	hFn := tree.GetHashFn()
+	var leaves [8]zrntcommon.Root
+	for i := range leaves {
+		leaves[i][0] = byte(i + 1)
+	}
+	// depth 3 tree built by hand
+	l1 := [4]zrntcommon.Root{hFn(leaves[0], leaves[1]), hFn(leaves[2], leaves[3]), hFn(leaves[4], leaves[5]), hFn(leaves[6], leaves[7])}
+	l2 := [2]zrntcommon.Root{hFn(l1[0], l1[1]), hFn(l1[2], l1[3])}
+	root := hFn(l2[0], l2[1])
+
+	// leaf 5 -> gindex 8 + 5 = 13, branch [leaf4, l1[3], l2[0]]
+	require.True(t, VerifySSZBranch(root, leaves[5], []zrntcommon.Root{leaves[4], l1[3], l2[0]}, 13))
+	// inner node l1[1] -> gindex 4 + 1 = 5, branch [l1[0], l2[1]]
+	require.True(t, VerifySSZBranch(root, l1[1], []zrntcommon.Root{l1[0], l2[1]}, 5))
+	// the root itself is gindex 1 with an empty branch
+	require.True(t, VerifySSZBranch(root, root, nil, 1))
+	require.False(t, VerifySSZBranch(root, leaves[5], []zrntcommon.Root{l1[3], leaves[4], l2[0]}, 13))
+}
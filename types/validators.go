@@ -0,0 +1,171 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+const (
+	// ValidatorRegistryDepth is log2(VALIDATOR_REGISTRY_LIMIT), the depth of the validators list data
+	ValidatorRegistryDepth = 40
+	// BalancesDepth is the depth of the balances list data, 4 balances being packed in each chunk
+	BalancesDepth = ValidatorRegistryDepth - 2
+)
+
+// BeaconState.validators and balances fields (fields 11 and 12), balances always follows validators
+const (
+	ValidatorsGIndexAltair  GIndex = 32 + 11 // Altair .. Deneb, 32 fields or less
+	ValidatorsGIndexElectra GIndex = 64 + 11 // Electra, Fulu, more than 32 fields
+)
+
+// ValidatorsGIndexForFork returns the generalized index of validators in the BeaconState of the given fork
+func ValidatorsGIndexForFork(fork string) (GIndex, error) {
+	gindex, err := BlockRootsGIndexForFork(fork)
+	if err != nil {
+		return 0, err
+	}
+	if gindex == BlockRootsGIndexAltair {
+		return ValidatorsGIndexAltair, nil
+	}
+	return ValidatorsGIndexElectra, nil
+}
+
+// ValidatorGIndex returns the generalized index, in the BeaconState, of validators[index]: the list data
+// (left of the length mix-in) and the validator.
+func ValidatorGIndex(validatorsGIndex GIndex, index uint64) (GIndex, error) {
+	validator, err := NewGIndex(ValidatorRegistryDepth, index)
+	if err != nil {
+		return 0, err
+	}
+	return ConcatGIndices(validatorsGIndex, 2, validator)
+}
+
+// BalanceGIndex returns the generalized index, in the BeaconState, of the chunk of balances holding
+// balances[index], at byte offset 8 * (index % 4) of the chunk.
+func BalanceGIndex(validatorsGIndex GIndex, index uint64) (GIndex, error) {
+	chunk, err := NewGIndex(BalancesDepth, index/4)
+	if err != nil {
+		return 0, err
+	}
+	return ConcatGIndices(validatorsGIndex+1, 2, chunk)
+}
+
+// ValidatorProof is a validator of a BeaconState and its balance, with their proofs.
+type ValidatorProof struct {
+	Index     uint64
+	Validator phase0.Validator
+	Balance   uint64
+	// ValidatorProof proves hash_tree_root(Validator), BalanceProof the chunk holding Balance
+	ValidatorProof *SSZProof
+	BalanceProof   *SSZProof
+}
+
+// BeaconStateValidatorProof generates the proofs of validators[index] and balances[index] in an
+// SSZ-encoded (Electra) BeaconState.
+func BeaconStateValidatorProof(spec *zrntcommon.Spec, stateSSZ []byte, index uint64) (*ValidatorProof, error) {
+	v, err := ViewFromSSZ(electra.BeaconStateType(spec), stateSSZ)
+	if err != nil {
+		return nil, fmt.Errorf("beacon state: %w", err)
+	}
+	state, err := electra.AsBeaconStateView(v, nil)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	if valid, err := validators.IsValidIndex(zrntcommon.ValidatorIndex(index)); err != nil || !valid {
+		return nil, fmt.Errorf("no validator %d in the state", index)
+	}
+	validator, err := validators.Validator(zrntcommon.ValidatorIndex(index))
+	if err != nil {
+		return nil, err
+	}
+	p := &ValidatorProof{Index: index}
+	if err := readValidator(validator, &p.Validator); err != nil {
+		return nil, fmt.Errorf("validator %d: %w", index, err)
+	}
+	balances, err := state.Balances()
+	if err != nil {
+		return nil, err
+	}
+	balance, err := balances.GetBalance(zrntcommon.ValidatorIndex(index))
+	if err != nil {
+		return nil, err
+	}
+	p.Balance = uint64(balance)
+
+	gindex, err := ValidatorGIndex(ValidatorsGIndexElectra, index)
+	if err != nil {
+		return nil, err
+	}
+	if p.ValidatorProof, err = GenerateSSZProofFromView(v, gindex); err != nil {
+		return nil, err
+	}
+	if gindex, err = BalanceGIndex(ValidatorsGIndexElectra, index); err != nil {
+		return nil, err
+	}
+	if p.BalanceProof, err = GenerateSSZProofFromView(v, gindex); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func readValidator(v zrntcommon.Validator, dst *phase0.Validator) (err error) {
+	if dst.Pubkey, err = v.Pubkey(); err != nil {
+		return err
+	}
+	if dst.WithdrawalCredentials, err = v.WithdrawalCredentials(); err != nil {
+		return err
+	}
+	if dst.EffectiveBalance, err = v.EffectiveBalance(); err != nil {
+		return err
+	}
+	if dst.Slashed, err = v.Slashed(); err != nil {
+		return err
+	}
+	if dst.ActivationEligibilityEpoch, err = v.ActivationEligibilityEpoch(); err != nil {
+		return err
+	}
+	if dst.ActivationEpoch, err = v.ActivationEpoch(); err != nil {
+		return err
+	}
+	if dst.ExitEpoch, err = v.ExitEpoch(); err != nil {
+		return err
+	}
+	dst.WithdrawableEpoch, err = v.WithdrawableEpoch()
+	return err
+}
+
+// Verify checks that the validator and its balance are the ones at Index in the state with the given
+// root, whose validators field is at validatorsGIndex.
+func (p *ValidatorProof) Verify(stateRoot zrntcommon.Root, validatorsGIndex GIndex) error {
+	gindex, err := ValidatorGIndex(validatorsGIndex, p.Index)
+	if err != nil {
+		return err
+	}
+	if p.ValidatorProof.Leaf != p.Validator.HashTreeRoot(tree.GetHashFn()) {
+		return fmt.Errorf("validator %d does not match its proof", p.Index)
+	}
+	if !VerifySSZBranch(stateRoot, p.ValidatorProof.Leaf, p.ValidatorProof.Branch, gindex) {
+		return fmt.Errorf("validator %d is not in validators of state %v", p.Index, stateRoot)
+	}
+
+	if gindex, err = BalanceGIndex(validatorsGIndex, p.Index); err != nil {
+		return err
+	}
+	offset := 8 * (p.Index % 4)
+	if binary.LittleEndian.Uint64(p.BalanceProof.Leaf[offset:offset+8]) != p.Balance {
+		return fmt.Errorf("balance of validator %d does not match its proof", p.Index)
+	}
+	if !VerifySSZBranch(stateRoot, p.BalanceProof.Leaf, p.BalanceProof.Branch, gindex) {
+		return fmt.Errorf("balance of validator %d is not in balances of state %v", p.Index, stateRoot)
+	}
+	return nil
+}
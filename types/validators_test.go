@@ -0,0 +1,55 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeaconStateValidatorProof(t *testing.T) {
+	spec := configs.Mainnet
+	state, err := electra.AsBeaconStateView(electra.BeaconStateType(spec).New(), nil)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		pubkey := zrntcommon.BLSPubkey{0x80, byte(i)}
+		require.NoError(t, state.AddValidator(spec, pubkey, zrntcommon.Root{0x01, byte(i)}, zrntcommon.Gwei(32_000_000_000+i)))
+	}
+	validators, err := state.Validators()
+	require.NoError(t, err)
+	validator, err := validators.Validator(5)
+	require.NoError(t, err)
+	require.NoError(t, validator.SetExitEpoch(400_000))
+	stateRoot := state.HashTreeRoot(tree.GetHashFn())
+	var stateSSZ bytes.Buffer
+	require.NoError(t, state.Serialize(codec.NewEncodingWriter(&stateSSZ)))
+
+	p, err := BeaconStateValidatorProof(spec, stateSSZ.Bytes(), 5)
+	require.NoError(t, err)
+	require.Equal(t, zrntcommon.BLSPubkey{0x80, 5}, p.Validator.Pubkey)
+	require.Equal(t, zrntcommon.Epoch(400_000), p.Validator.ExitEpoch)
+	require.Equal(t, uint64(32_000_000_005), p.Balance)
+	require.Equal(t, 1+ValidatorRegistryDepth+6, p.ValidatorProof.GIndex.Depth())
+	require.Equal(t, 1+BalancesDepth+6, p.BalanceProof.GIndex.Depth())
+	require.NoError(t, p.Verify(stateRoot, ValidatorsGIndexElectra))
+	require.Error(t, p.Verify(stateRoot, ValidatorsGIndexAltair))
+
+	// the balance is the second one of its chunk
+	p.Balance++
+	require.Error(t, p.Verify(stateRoot, ValidatorsGIndexElectra))
+	p.Balance--
+	p.Validator.Slashed = true
+	require.Error(t, p.Verify(stateRoot, ValidatorsGIndexElectra))
+
+	_, err = BeaconStateValidatorProof(spec, stateSSZ.Bytes(), 7)
+	require.Error(t, err)
+
+	g, err := ValidatorsGIndexForFork("deneb")
+	require.NoError(t, err)
+	require.Equal(t, ValidatorsGIndexAltair, g)
+}
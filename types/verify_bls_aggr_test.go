@@ -9,7 +9,9 @@ import (
 	"testing"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/kysee/zk-chains/types/slots"
 	zrntcommon "github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
 	"github.com/protolambda/ztyp/tree"
 	"github.com/stretchr/testify/require"
 )
@@ -109,7 +111,7 @@ func TestVerifySyncAggregate(t *testing.T) {
 	require.NoError(t, err, "Failed to parse sc-update-1104.json")
 	// At slot 1105, current sync committee
 	syncCommittee := update1104.Data.NextSyncCommittee
-	period := uint64(update1104.Data.AttestedHeader.Beacon.Slot / 8192)
+	period := slots.SlotToPeriod(configs.Mainnet, uint64(update1104.Data.AttestedHeader.Beacon.Slot))
 	t.Logf("Loaded light client update (period %d, curr_sync_committee at period %d)",
 		period, period+1)
 
@@ -120,7 +122,7 @@ func TestVerifySyncAggregate(t *testing.T) {
 	var update LightClientUpdate
 	err = json.Unmarshal(updateFile, &update)
 	require.NoError(t, err, "Failed to parse light client update JSON")
-	t.Logf("Loaded light client update (period %d, slot %s)", update.Data.AttestedHeader.Beacon.Slot/8192, update.Data.AttestedHeader.Beacon.Slot)
+	t.Logf("Loaded light client update (period %d, slot %s)", slots.SlotToPeriod(configs.Mainnet, uint64(update.Data.AttestedHeader.Beacon.Slot)), update.Data.AttestedHeader.Beacon.Slot)
 
 	// Verify sync aggregate
 	err = verifySyncAggregate(&syncCommittee, &update)
@@ -4,19 +4,66 @@ pragma solidity ^0.8.0;
 import "hardhat/console.sol";
 import "./Eth2ScUpdateVerifier.sol";
 
+// gnark PLONK Solidity verifier
+interface IPlonkVerifier {
+    function Verify(bytes calldata proof, uint256[] calldata public_inputs) external view returns (bool);
+}
+
+// gnark Groth16 Solidity verifier of a circuit compiled with -public-state-root, whose public inputs
+// end with the 32 bytes of the attested state root
+interface IStateRootVerifier {
+    function verifyProof(
+        uint256[8] calldata proof,
+        uint256[2] calldata commitments,
+        uint256[2] calldata commitmentPok,
+        uint256[163] calldata input
+    ) external view;
+}
+
 contract Eth2LightClient {
     uint256 public lastPeriod;
+    // attested slot of the last accepted update, every update must attest a later slot
+    uint256 public lastSlot;
     mapping(uint256 => bytes32) public scPubkeysHashes;
+    // execution block hash of the attested header of every accepted update, by execution block number
+    mapping(uint256 => bytes32) public executionBlockHashes;
+    // state root of the attested header of every accepted update, by attested slot, for circuits
+    // compiled with -public-state-root (see the *WithStateRoot functions)
+    mapping(uint256 => bytes32) public stateRoots;
     Eth2ScUpdateVerifier public verifier;
+    // true if the circuit commits to the full 48-byte compressed pubkeys (sc-hash-mode "full")
+    bool public immutable fullPubKeysHash;
+    // sync committee signing domain of the tracked network and fork, a public input of the circuit
+    bytes32 public immutable domain;
 
-    // Beacon chain constants
-    uint256 constant SLOTS_PER_EPOCH = 32;
-    uint256 constant EPOCHS_PER_SYNC_COMMITTEE_PERIOD = 256;
+    // Beacon chain constants of the preset the circuit was compiled for (SLOTS_PER_EPOCH,
+    // EPOCHS_PER_SYNC_COMMITTEE_PERIOD, SYNC_COMMITTEE_SIZE): 32, 256 and 512 on mainnet, 8, 8 and 32
+    // on minimal, 16, 512 and 512 on gnosis
+    uint256 public immutable slotsPerEpoch;
+    uint256 public immutable epochsPerSyncCommitteePeriod;
+    uint256 public immutable syncCommitteeSize;
 
-    constructor(uint256 _initialPeriod, bytes32 _initialScPubkeysHash, address _verifierAddress) {
+    constructor(
+        uint256 _initialPeriod,
+        bytes32 _initialScPubkeysHash,
+        address _verifierAddress,
+        bool _fullPubKeysHash,
+        bytes32 _domain,
+        uint256 _slotsPerEpoch,
+        uint256 _epochsPerSyncCommitteePeriod,
+        uint256 _syncCommitteeSize
+    ) {
+        require(_slotsPerEpoch > 0 && _epochsPerSyncCommitteePeriod > 0, "Invalid period length");
+        // the pubkeys are merkleized as a vector without padding
+        require(_syncCommitteeSize > 1 && (_syncCommitteeSize & (_syncCommitteeSize - 1)) == 0, "Sync committee size must be a power of two");
         lastPeriod = _initialPeriod;
         scPubkeysHashes[lastPeriod] = _initialScPubkeysHash;
         verifier = Eth2ScUpdateVerifier(_verifierAddress);
+        fullPubKeysHash = _fullPubKeysHash;
+        domain = _domain;
+        slotsPerEpoch = _slotsPerEpoch;
+        epochsPerSyncCommitteePeriod = _epochsPerSyncCommitteePeriod;
+        syncCommitteeSize = _syncCommitteeSize;
     }
 
     function updateSyncCommittee (
@@ -24,22 +71,117 @@ contract Eth2LightClient {
         uint256[2] calldata commitments,
         uint256[2] calldata commitmentPok,
         uint256 slot,
-        bytes calldata nextSc
+        bytes calldata nextSc,
+        bytes32 executionBlockHash,
+        uint256 executionBlockNumber
+    ) external {
+        uint256 _period = _checkPeriod(slot, nextSc);
+        uint256[131] memory input = _publicInputs(_period, slot, nextSc, executionBlockHash, executionBlockNumber);
+
+        // Call the verifier with [0,0] for commitments and commitmentPok
+        verifier.verifyProof(proof,commitments, commitmentPok, input);
+
+        _setNextSyncCommittee(_period, slot, nextSc);
+        executionBlockHashes[executionBlockNumber] = executionBlockHash;
+    }
+
+    // Same as updateSyncCommittee, for a circuit built with the PLONK backend (see .build/manifest.json).
+    // The verifier address must then hold a gnark PLONK verifier.
+    function updateSyncCommitteePlonk (
+        bytes calldata proof,
+        uint256 slot,
+        bytes calldata nextSc,
+        bytes32 executionBlockHash,
+        uint256 executionBlockNumber
     ) external {
+        uint256 _period = _checkPeriod(slot, nextSc);
+        uint256[131] memory fixedInput = _publicInputs(_period, slot, nextSc, executionBlockHash, executionBlockNumber);
+        uint256[] memory input = new uint256[](131);
+        for (uint256 i = 0; i < 131; i++) {
+            input[i] = fixedInput[i];
+        }
+
+        require(IPlonkVerifier(address(verifier)).Verify(proof, input), "Invalid proof");
+
+        _setNextSyncCommittee(_period, slot, nextSc);
+        executionBlockHashes[executionBlockNumber] = executionBlockHash;
+    }
+
+    // Same as updateSyncCommittee, for a circuit compiled with -public-state-root: the proof also binds
+    // stateRoot to the attested header, and it is stored for consumers verifying SSZ proofs against it.
+    // The verifier address must then hold the verifier of that circuit.
+    function updateSyncCommitteeWithStateRoot (
+        uint256[8] calldata proof,
+        uint256[2] calldata commitments,
+        uint256[2] calldata commitmentPok,
+        uint256 slot,
+        bytes calldata nextSc,
+        bytes32 executionBlockHash,
+        uint256 executionBlockNumber,
+        bytes32 stateRoot
+    ) external {
+        uint256 _period = _checkPeriod(slot, nextSc);
+        uint256[163] memory input = _stateRootInputs(_publicInputs(_period, slot, nextSc, executionBlockHash, executionBlockNumber), stateRoot);
+
+        IStateRootVerifier(address(verifier)).verifyProof(proof, commitments, commitmentPok, input);
+
+        _setNextSyncCommittee(_period, slot, nextSc);
+        executionBlockHashes[executionBlockNumber] = executionBlockHash;
+        stateRoots[slot] = stateRoot;
+    }
+
+    // Same as updateSyncCommitteeWithStateRoot, for the PLONK backend
+    function updateSyncCommitteePlonkWithStateRoot (
+        bytes calldata proof,
+        uint256 slot,
+        bytes calldata nextSc,
+        bytes32 executionBlockHash,
+        uint256 executionBlockNumber,
+        bytes32 stateRoot
+    ) external {
+        uint256 _period = _checkPeriod(slot, nextSc);
+        uint256[163] memory fixedInput = _stateRootInputs(_publicInputs(_period, slot, nextSc, executionBlockHash, executionBlockNumber), stateRoot);
+        uint256[] memory input = new uint256[](163);
+        for (uint256 i = 0; i < 163; i++) {
+            input[i] = fixedInput[i];
+        }
+
+        require(IPlonkVerifier(address(verifier)).Verify(proof, input), "Invalid proof");
+
+        _setNextSyncCommittee(_period, slot, nextSc);
+        executionBlockHashes[executionBlockNumber] = executionBlockHash;
+        stateRoots[slot] = stateRoot;
+    }
+
+    function _checkPeriod(uint256 slot, bytes calldata nextSc) internal view returns (uint256) {
         // Validate inputs
-        require(nextSc.length == 24624, "Invalid nextSc length"); // 513 * 48 bytes
+        require(nextSc.length == (syncCommitteeSize + 1) * 48, "Invalid nextSc length"); // pubkeys and aggregate pubkey
+        require(slot > lastSlot, "Slot must increase");
 
         // Compute and validate period
-        uint256 _period = slot / (SLOTS_PER_EPOCH * EPOCHS_PER_SYNC_COMMITTEE_PERIOD);
+        uint256 _period = slot / (slotsPerEpoch * epochsPerSyncCommitteePeriod);
         require(_period == lastPeriod, "Period must be same");
+        return _period;
+    }
 
+    function _publicInputs(
+        uint256 _period,
+        uint256 slot,
+        bytes calldata nextSc,
+        bytes32 executionBlockHash,
+        uint256 executionBlockNumber
+    ) internal view returns (uint256[131] memory input) {
         // Compute nextSyncCommitteeRoot using SSZ (for proof verification)
         bytes32 nextScRoot = _scRoot(nextSc);
 
         // Prepare public inputs for the verifier
         // input[0..32] = scPubkeysHash (current sync committee)
-        // input[33..64] = NextSyncCommitteeRoot (32 bytes)
-        uint256[64] memory input;
+        // input[32..63] = NextSyncCommitteeRoot (32 bytes)
+        // input[64] = period of the attested header, constrained in-circuit to slot / (slotsPerEpoch * epochsPerSyncCommitteePeriod)
+        // input[65..96] = signing domain (32 bytes)
+        // input[97..128] = execution block hash of the attested header (32 bytes)
+        // input[129] = execution block number of the attested header
+        // input[130] = slot of the attested header, constrained in-circuit to the signed header slot
         bytes32 currScPubKeyHash = scPubkeysHashes[lastPeriod];
 
         // input[0] is the current sync committee commitment (syncCommitteeHash)
@@ -52,28 +194,57 @@ contract Eth2LightClient {
             input[i + 32] = uint256(uint8(nextScRoot[i]));
         }
 
-        // Call the verifier with [0,0] for commitments and commitmentPok
-        verifier.verifyProof(proof,commitments, commitmentPok, input);
+        // the proof is only valid for the period of the signed header
+        input[64] = _period;
+
+        // the signature must have been made over this network's domain
+        for (uint256 i = 0; i < 32; i++) {
+            input[i + 65] = uint256(uint8(domain[i]));
+        }
+
+        // the execution block hash is proven to be committed to by the signed header
+        for (uint256 i = 0; i < 32; i++) {
+            input[i + 97] = uint256(uint8(executionBlockHash[i]));
+        }
+        input[129] = executionBlockNumber;
+
+        // the proof is bound to the attested slot, so an older update cannot be replayed
+        input[130] = slot;
+    }
 
+    // _stateRootInputs appends the 32 bytes of stateRoot to the public inputs of the default layout:
+    // input[131..162] = state root of the attested header, constrained in-circuit to the signed header's
+    function _stateRootInputs(uint256[131] memory base, bytes32 stateRoot) internal pure returns (uint256[163] memory input) {
+        for (uint256 i = 0; i < 131; i++) {
+            input[i] = base[i];
+        }
+        for (uint256 i = 0; i < 32; i++) {
+            input[i + 131] = uint256(uint8(stateRoot[i]));
+        }
+    }
+
+    function _setNextSyncCommittee(uint256 _period, uint256 slot, bytes calldata nextSc) internal {
         // If verification succeeds, compute and store hash of nextSc's public keys
         lastPeriod = _period + 1;
-        scPubkeysHashes[lastPeriod] = _pubKeysHash(nextSc);
+        lastSlot = slot;
+        scPubkeysHashes[lastPeriod] = fullPubKeysHash ? _pubKeysHashFull(nextSc) : _pubKeysHash(nextSc);
     }
 
-    function _scRoot(bytes memory syncCommitteeData) internal pure returns (bytes32) {
+    function _scRoot(bytes memory syncCommitteeData) internal view returns (bytes32) {
         // SSZ Merkleization for SyncCommittee Container:
         // struct SyncCommittee {
-        //     pubkeys: Vector[BLSPubkey, 512]  // 512 * 48 = 24576 bytes
-        //     aggregate_pubkey: BLSPubkey      // 48 bytes
+        //     pubkeys: Vector[BLSPubkey, syncCommitteeSize]  // syncCommitteeSize * 48 bytes
+        //     aggregate_pubkey: BLSPubkey                    // 48 bytes
         // }
-        // Total: 24576 + 48 = 24624 bytes
+        // Total: (syncCommitteeSize + 1) * 48 bytes, 24624 on mainnet
         // Container HashTreeRoot = hash(pubkeysRoot, aggregatePubkeyRoot)
 
-        require(syncCommitteeData.length == 24624, "Invalid sync committee data length");
+        uint256 n = syncCommitteeSize;
+        require(syncCommitteeData.length == (n + 1) * 48, "Invalid sync committee data length");
 
-        // Part 1: Compute pubkeys root (512 pubkeys, bytes 0-24575)
-        bytes32[512] memory leaves;
-        for (uint256 i = 0; i < 512; i++) {
+        // Part 1: Compute pubkeys root (n pubkeys, bytes 0 .. n * 48 - 1)
+        bytes32[] memory nodes = new bytes32[](n);
+        for (uint256 i = 0; i < n; i++) {
             uint256 offset = i * 48;
             bytes32 chunk0;
             bytes32 chunk1;
@@ -82,50 +253,24 @@ contract Eth2LightClient {
                 let data := mload(add(add(syncCommitteeData, 32), add(offset, 32)))
                 chunk1 := and(data, 0xffffffffffffffffffffffffffffffff00000000000000000000000000000000)
             }
-            leaves[i] = sha256(abi.encodePacked(chunk0, chunk1));
+            nodes[i] = sha256(abi.encodePacked(chunk0, chunk1));
         }
 
-        // Build Merkle tree from 512 leaves
-        bytes32[256] memory level1;
-        for (uint256 i = 0; i < 256; i++) {
-            level1[i] = sha256(abi.encodePacked(leaves[i * 2], leaves[i * 2 + 1]));
-        }
-        bytes32[128] memory level2;
-        for (uint256 i = 0; i < 128; i++) {
-            level2[i] = sha256(abi.encodePacked(level1[i * 2], level1[i * 2 + 1]));
-        }
-        bytes32[64] memory level3;
-        for (uint256 i = 0; i < 64; i++) {
-            level3[i] = sha256(abi.encodePacked(level2[i * 2], level2[i * 2 + 1]));
-        }
-        bytes32[32] memory level4;
-        for (uint256 i = 0; i < 32; i++) {
-            level4[i] = sha256(abi.encodePacked(level3[i * 2], level3[i * 2 + 1]));
-        }
-        bytes32[16] memory level5;
-        for (uint256 i = 0; i < 16; i++) {
-            level5[i] = sha256(abi.encodePacked(level4[i * 2], level4[i * 2 + 1]));
-        }
-        bytes32[8] memory level6;
-        for (uint256 i = 0; i < 8; i++) {
-            level6[i] = sha256(abi.encodePacked(level5[i * 2], level5[i * 2 + 1]));
-        }
-        bytes32[4] memory level7;
-        for (uint256 i = 0; i < 4; i++) {
-            level7[i] = sha256(abi.encodePacked(level6[i * 2], level6[i * 2 + 1]));
-        }
-        bytes32[2] memory level8;
-        for (uint256 i = 0; i < 2; i++) {
-            level8[i] = sha256(abi.encodePacked(level7[i * 2], level7[i * 2 + 1]));
+        // Build the Merkle tree of the n leaves in place, n being a power of two
+        for (uint256 width = n / 2; width > 0; width /= 2) {
+            for (uint256 i = 0; i < width; i++) {
+                nodes[i] = sha256(abi.encodePacked(nodes[i * 2], nodes[i * 2 + 1]));
+            }
         }
-        bytes32 pubkeysRoot = sha256(abi.encodePacked(level8[0], level8[1]));
+        bytes32 pubkeysRoot = nodes[0];
 
-        // Part 2: Compute aggregate_pubkey root (48 bytes at offset 24576)
+        // Part 2: Compute aggregate_pubkey root (48 bytes at offset n * 48)
+        uint256 aggOffset = n * 48;
         bytes32 aggChunk0;
         bytes32 aggChunk1;
         assembly {
-            aggChunk0 := mload(add(add(syncCommitteeData, 32), 24576))
-            let data := mload(add(add(syncCommitteeData, 32), 24608))
+            aggChunk0 := mload(add(add(syncCommitteeData, 32), aggOffset))
+            let data := mload(add(add(syncCommitteeData, 32), add(aggOffset, 32)))
             aggChunk1 := and(data, 0xffffffffffffffffffffffffffffffff00000000000000000000000000000000)
         }
         bytes32 aggregatePubkeyRoot = sha256(abi.encodePacked(aggChunk0, aggChunk1));
@@ -134,9 +279,9 @@ contract Eth2LightClient {
         return sha256(abi.encodePacked(pubkeysRoot, aggregatePubkeyRoot));
     }
 
-    function _pubKeysHash(bytes calldata pubKeys) internal pure returns (bytes32) {
-        require(pubKeys.length >= 512, "pubKeys length must be more than 512");
-        uint256 numPubkeys = 512;
+    function _pubKeysHash(bytes calldata pubKeys) internal view returns (bytes32) {
+        uint256 numPubkeys = syncCommitteeSize;
+        require(pubKeys.length >= numPubkeys * 48, "pubKeys shorter than the sync committee");
 
         bytes memory allLimbs = new bytes(numPubkeys * 16);
         for (uint256 i = 0; i < numPubkeys; i++) {
@@ -151,13 +296,20 @@ contract Eth2LightClient {
         }
         return sha256(allLimbs);
     }
+    function _pubKeysHashFull(bytes calldata pubKeys) internal view returns (bytes32) {
+        uint256 length = syncCommitteeSize * 48;
+        require(pubKeys.length >= length, "pubKeys shorter than the sync committee");
+        // the compressed pubkeys are laid out back to back, ahead of the aggregate pubkey
+        return sha256(pubKeys[0:length]);
+    }
+
     // Test function for _pubKeysSha2
-    function testPubKeysHash(bytes calldata data) public pure returns (bytes32) {
+    function testPubKeysHash(bytes calldata data) public view returns (bytes32) {
         return _pubKeysHash(data);
     }
 
     // Test function for _scRoot
-    function testScRoot(bytes calldata syncCommitteeData) public pure returns (bytes32) {
+    function testScRoot(bytes calldata syncCommitteeData) public view returns (bytes32) {
         return _scRoot(syncCommitteeData);
     }
 }
\ No newline at end of file
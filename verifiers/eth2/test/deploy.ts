@@ -20,6 +20,15 @@ const provider = new ethers.JsonRpcProvider(rpcUrl);
 const wallet = new ethers.Wallet(privateKey, provider);
 const managedWallet = new NonceManager(wallet);
 
+// sync committee signing domain, must match circuits.DOMAIN / the relayer's --domain
+const SC_DOMAIN = "0x07000000f52c15272cff99835cd05aa522af469210b5b2c8807e372b6b9ca539";
+
+// preset of the circuit (SLOTS_PER_EPOCH, EPOCHS_PER_SYNC_COMMITTEE_PERIOD, SYNC_COMMITTEE_SIZE), must match
+// the relayer's --preset: mainnet is 32, 256, 512 and minimal 8, 8, 32
+const SLOTS_PER_EPOCH = 32n;
+const EPOCHS_PER_SYNC_COMMITTEE_PERIOD = 256n;
+const SYNC_COMMITTEE_SIZE = 512n;
+
 async function deploy() {
 	console.log("Network URL:", rpcUrl);
 	console.log("Using account:", wallet.address);
@@ -55,7 +64,7 @@ async function deploy() {
 
 	// Deploy Eth2LightClient.sol
 	const scUpdate0 = loadSyncCommitteeUpdateData(`${projectRoot()}/data/sc-update-1104.json`);
-	const initialPeriod = 1n + BigInt(scUpdate0.data.attested_header.beacon.slot) / 8192n;
+	const initialPeriod = 1n + BigInt(scUpdate0.data.attested_header.beacon.slot) / (SLOTS_PER_EPOCH * EPOCHS_PER_SYNC_COMMITTEE_PERIOD);
 	//expected "0x8bd26c003d619dc6aa13e4c7b31d01910a87f43da84070e6cbdd4d45a91da3f3";
 	const initialScPubkeysHash = scPubKeysHash(scUpdate0.data.next_sync_committee);
 
@@ -72,7 +81,12 @@ async function deploy() {
 	const lightClient0: any = await LightClientFactory.deploy(
 		initialPeriod,
 		initialScPubkeysHash,
-		scUpdateVerifierAddress
+		scUpdateVerifierAddress,
+		false, // truncated sc-hash-mode
+		SC_DOMAIN,
+		SLOTS_PER_EPOCH,
+		EPOCHS_PER_SYNC_COMMITTEE_PERIOD,
+		SYNC_COMMITTEE_SIZE
 	);
 	await lightClient0.waitForDeployment();
 	const lightClientAddress = await lightClient0.getAddress();
@@ -102,6 +116,8 @@ async function testLightClientUpdate(lightClientAddress: string) {
     // Test testScRoot
     const scUpdate = loadSyncCommitteeUpdateData(`${projectRoot()}/data/sc-update-1105.json`);
     const slot = scUpdate.data.attested_header.beacon.slot;
+    const executionBlockHash = scUpdate.data.attested_header.execution.block_hash;
+    const executionBlockNumber = scUpdate.data.attested_header.execution.block_number;
     const nextSc = scUpdate.data.next_sync_committee;
     const szNextSc = syncCommitteeToBytes(nextSc);
     console.log("szNextSc.pubkes (+aggreagte):", szNextSc.length / 48);
@@ -119,21 +135,26 @@ async function testLightClientUpdate(lightClientAddress: string) {
 
     // Test updateSyncCommittee
     const proofData = loadProofData(`${projectRoot()}/data/proof-data.json`)
-    try {
-        const estimatedGas = await lightClient.updateSyncCommittee.estimateGas(
+    // pick the light client entry point matching the proof system of the circuit
+    const updateSyncCommittee = proofData.backend === "plonk"
+        ? (overrides: object) => lightClient.updateSyncCommitteePlonk(proofData.proof, slot, szNextSc, executionBlockHash, executionBlockNumber, overrides)
+        : (overrides: object) => lightClient.updateSyncCommittee(
             proofData.proof, proofData.commitments, proofData.commitmentPok,
-            slot, szNextSc,
-            {gasLimit: 30000000});
-        console.log("updateSyncCommittee - Estimated gas needed:", estimatedGas.toString());
+            slot, szNextSc, executionBlockHash, executionBlockNumber, overrides);
+    const estimateUpdateSyncCommittee = proofData.backend === "plonk"
+        ? (overrides: object) => lightClient.updateSyncCommitteePlonk.estimateGas(proofData.proof, slot, szNextSc, executionBlockHash, executionBlockNumber, overrides)
+        : (overrides: object) => lightClient.updateSyncCommittee.estimateGas(
+            proofData.proof, proofData.commitments, proofData.commitmentPok,
+            slot, szNextSc, executionBlockHash, executionBlockNumber, overrides);
+    try {
+        const estimatedGas = await estimateUpdateSyncCommittee({gasLimit: 30000000});
+        console.log(`updateSyncCommittee (${proofData.backend}) - Estimated gas needed:`, estimatedGas.toString());
     } catch (err) {
         console.error("estimateGas failed:", err);
         process.exit(0);
     }
 
-    const tx = await lightClient.updateSyncCommittee(
-        proofData.proof, proofData.commitments, proofData.commitmentPok,
-        slot, szNextSc,
-        {gasLimit: 30000000});
+    const tx = await updateSyncCommittee({gasLimit: 30000000});
     const receipt = await tx.wait();
     console.log("typeof gasUsed:", typeof receipt.gasUsed);
     console.log("updateSyncCommittee - gasUsed:", receipt.gasUsed,`(${Number(receipt.gasUsed) / 1_000_000}M)`);
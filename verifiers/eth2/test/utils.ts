@@ -175,20 +175,37 @@ export function syncCommitteeToBytes(sc: SyncCommittee): Uint8Array {
 }
 
 export interface ProofData {
+    backend: "groth16";
     proof: string[];
     commitments: string[];
     commitmentPok: string[];
 }
 
+export interface PlonkProofData {
+    backend: "plonk";
+    proof: string;
+}
+
 /**
- * Load proof data from JSON file
+ * Load proof data from JSON file, in the encoding of the backend recorded by the relayer
+ * (files without a backend are Groth16)
  * @param dataPath Path to proof-data.json file
- * @returns ProofData object containing proof, commitments, and commitmentPok
+ * @returns ProofData (proof, commitments, and commitmentPok) or PlonkProofData (proof bytes)
  */
-export function loadProofData(dataPath: string): ProofData {
+export function loadProofData(dataPath: string): ProofData | PlonkProofData {
     const fileContent = fs.readFileSync(dataPath, 'utf8');
     const jsonData = JSON.parse(fileContent);
 
+    if (jsonData.backend === "plonk") {
+        if (typeof jsonData.proof !== "string") {
+            throw new Error('Invalid proof-data.json: plonk proof must be a hex string');
+        }
+        return { backend: "plonk", proof: jsonData.proof };
+    }
+    if (jsonData.backend !== undefined && jsonData.backend !== "groth16") {
+        throw new Error(`Invalid proof-data.json: unknown backend ${jsonData.backend}`);
+    }
+
     if (!jsonData.proof || !Array.isArray(jsonData.proof)) {
         throw new Error('Invalid proof-data.json: proof must be an array');
     }
@@ -214,6 +231,7 @@ export function loadProofData(dataPath: string): ProofData {
     }
 
     return {
+        backend: "groth16",
         proof: jsonData.proof,
         commitments: jsonData.commitments,
         commitmentPok: jsonData.commitmentPok